@@ -0,0 +1,340 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+Admin is an admin HTTP API for the deliveries stored in [Handler.Store], similar to the "Recent Deliveries" UI of GitHub, and for the internal state of the [Handler] (queue and deduplication).
+
+Endpoints:
+  - GET /deliveries lists the deliveries, without their payload. The query parameters "event", "repository", "status", "since", "until" (RFC 3339) and "limit" filter them, see [StoreFilter]. The query parameter "order" sorts them: "oldest" (default) or "newest" first, so "limit" keeps the newest.
+  - GET /deliveries/{id} returns a delivery, without its payload.
+  - GET /deliveries/{id}/payload returns the raw payload of a delivery.
+  - DELETE /deliveries/{id} deletes a delivery.
+  - POST /deliveries/{id}/redeliver runs a delivery through [Handler.Delivery] (or [Handler.Sink]) again, see [Handler.Redeliver]. It requeues it if [Handler.Async] is set. It allows to requeue the failed deliveries ("status=failed"), that are the dead letters of the handler.
+  - GET /queue lists the IDs of the deliveries queued and running in [Handler.Async] and [Handler.AsyncLanes], see [AsyncPool.Queued] and [AsyncPool.Running].
+  - GET /dedup lists the delivery IDs seen by [Handler.Dedup], if it implements [DedupLister].
+  - DELETE /dedup/{id} forgets a delivery ID seen by [Handler.Dedup], so the next delivery with this ID is processed (see [DedupStore.Forget]). It purges (or force-expires) a duplicate entry.
+  - GET /maintenance returns the [MaintenanceStatus] of [Handler.Maintenance].
+  - PUT /maintenance enables the maintenance mode, with the optional "reason" query parameter, and DELETE /maintenance disables it (see [Maintenance]).
+
+It has its own mux, so it can be served on another address or path than the webhook (e.g. with [http.StripPrefix]).
+
+The GET requests require the [AdminRoleReader] role, and the other requests (redeliver, delete, forget, maintenance) the [AdminRoleOperator] role, see [AdminAuthorizer].
+Without Authorizer, it doesn't authenticate the requests, so it must not be exposed publicly.
+
+Fields:
+  - Handler is the handler (required). If its Store, Async, Dedup or Maintenance is nil, the related requests are rejected with a 404 response.
+  - Authorizer authorizes the requests (optional).
+  - Operator returns the operator of a redelivery, recorded in its [Lineage] (optional, default: the name of the [AdminPrincipal]).
+  - Error is called if an error happened (optional).
+
+It must be created with [NewAdmin].
+*/
+type Admin struct {
+	Handler    *Handler
+	Authorizer AdminAuthorizer
+	Operator   func(req *http.Request) string
+	Error      func(ctx context.Context, err error, req *http.Request)
+
+	mux *http.ServeMux
+}
+
+// NewAdmin creates a new [Admin].
+func NewAdmin(h *Handler) *Admin {
+	a := &Admin{
+		Handler: h,
+		mux:     http.NewServeMux(),
+	}
+	a.handle("GET /deliveries", AdminRoleReader, a.requireStore(a.list))
+	a.handle("GET /deliveries/{id}", AdminRoleReader, a.requireStore(a.get))
+	a.handle("GET /deliveries/{id}/payload", AdminRoleReader, a.requireStore(a.getPayload))
+	a.handle("DELETE /deliveries/{id}", AdminRoleOperator, a.requireStore(a.delete))
+	a.handle("POST /deliveries/{id}/redeliver", AdminRoleOperator, a.requireStore(a.redeliver))
+	a.handle("GET /queue", AdminRoleReader, a.queue)
+	a.handle("GET /dedup", AdminRoleReader, a.listDedup)
+	a.handle("DELETE /dedup/{id}", AdminRoleOperator, a.forgetDedup)
+	a.handle("GET /maintenance", AdminRoleReader, a.requireMaintenance(a.getMaintenance))
+	a.handle("PUT /maintenance", AdminRoleOperator, a.requireMaintenance(a.enableMaintenance))
+	a.handle("DELETE /maintenance", AdminRoleOperator, a.requireMaintenance(a.disableMaintenance))
+	return a
+}
+
+// handle registers a handler that requires the role.
+func (a *Admin) handle(pattern string, role AdminRole, f http.HandlerFunc) {
+	a.mux.HandleFunc(pattern, func(w http.ResponseWriter, req *http.Request) {
+		AuthorizeAdmin(w, req, a.Authorizer, role, f)
+	})
+}
+
+func (a *Admin) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	a.mux.ServeHTTP(w, req)
+}
+
+func (a *Admin) requireStore(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if a.Handler.Store == nil {
+			http.Error(w, "store not configured", http.StatusNotFound)
+			return
+		}
+		f(w, req)
+	}
+}
+
+// adminDelivery is the JSON representation of a [StoredDelivery], without its payload.
+type adminDelivery struct {
+	DeliveryID             string         `json:"delivery_id"`
+	Event                  string         `json:"event"`
+	Repository             string         `json:"repository,omitempty"`
+	HookID                 string         `json:"hook_id,omitempty"`
+	InstallationTargetType string         `json:"installation_target_type,omitempty"`
+	InstallationTargetID   string         `json:"installation_target_id,omitempty"`
+	UserAgent              string         `json:"user_agent,omitempty"`
+	Lineage                *Lineage       `json:"lineage,omitempty"`
+	SignatureHeaders       []string       `json:"signature_headers,omitempty"`
+	HeaderInterpretations  []string       `json:"header_interpretations,omitempty"`
+	Status                 DeliveryStatus `json:"status"`
+	Error                  string         `json:"error,omitempty"`
+	ReceivedAt             time.Time      `json:"received_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+}
+
+func newAdminDelivery(d *StoredDelivery) *adminDelivery {
+	return &adminDelivery{
+		DeliveryID:             d.DeliveryID,
+		Event:                  d.Event,
+		Repository:             d.Repository,
+		HookID:                 d.HookID,
+		InstallationTargetType: d.InstallationTargetType,
+		InstallationTargetID:   d.InstallationTargetID,
+		UserAgent:              d.UserAgent,
+		Lineage:                d.Lineage,
+		SignatureHeaders:       d.SignatureHeaders,
+		HeaderInterpretations:  d.HeaderInterpretations,
+		Status:                 d.Status,
+		Error:                  d.Error,
+		ReceivedAt:             d.ReceivedAt,
+		UpdatedAt:              d.UpdatedAt,
+	}
+}
+
+func (a *Admin) list(w http.ResponseWriter, req *http.Request) {
+	filter, err := parseStoreFilter(req)
+	if err != nil {
+		a.handleError(w, req, http.StatusBadRequest, err)
+		return
+	}
+	ds, err := a.Handler.Store.List(req.Context(), filter)
+	if err != nil {
+		a.handleError(w, req, http.StatusInternalServerError, fmt.Errorf("store: %w", err))
+		return
+	}
+	v := struct {
+		Deliveries []*adminDelivery `json:"deliveries"`
+	}{
+		Deliveries: make([]*adminDelivery, len(ds)),
+	}
+	for i, d := range ds {
+		v.Deliveries[i] = newAdminDelivery(d)
+	}
+	writeAdminJSON(w, http.StatusOK, v)
+}
+
+func parseStoreFilter(req *http.Request) (StoreFilter, error) {
+	q := req.URL.Query()
+	filter := StoreFilter{
+		Event:      q.Get("event"),
+		Repository: q.Get("repository"),
+		Status:     DeliveryStatus(q.Get("status")),
+	}
+	var err error
+	for name, t := range map[string]*time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		s := q.Get(name)
+		if s == "" {
+			continue
+		}
+		*t, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return StoreFilter{}, fmt.Errorf("invalid %q parameter: %w", name, err)
+		}
+	}
+	switch s := q.Get("order"); s {
+	case "", "oldest":
+	case "newest":
+		filter.Newest = true
+	default:
+		return StoreFilter{}, fmt.Errorf("invalid %q parameter: %q", "order", s)
+	}
+	if s := q.Get("limit"); s != "" {
+		filter.Limit, err = strconv.Atoi(s)
+		if err != nil {
+			return StoreFilter{}, fmt.Errorf("invalid %q parameter: %w", "limit", err)
+		}
+	}
+	return filter, nil
+}
+
+func (a *Admin) get(w http.ResponseWriter, req *http.Request) {
+	d, ok := a.getDelivery(w, req)
+	if !ok {
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, newAdminDelivery(d))
+}
+
+func (a *Admin) getPayload(w http.ResponseWriter, req *http.Request) {
+	d, ok := a.getDelivery(w, req)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(d.RawPayload)
+}
+
+func (a *Admin) getDelivery(w http.ResponseWriter, req *http.Request) (*StoredDelivery, bool) {
+	d, err := a.Handler.Store.Get(req.Context(), req.PathValue("id"))
+	if err != nil {
+		a.handleStoreError(w, req, err)
+		return nil, false
+	}
+	return d, true
+}
+
+func (a *Admin) delete(w http.ResponseWriter, req *http.Request) {
+	err := a.Handler.Store.Delete(req.Context(), req.PathValue("id"))
+	if err != nil {
+		a.handleStoreError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Admin) redeliver(w http.ResponseWriter, req *http.Request) {
+	statusCode, err := a.Handler.Redeliver(req.Context(), req.PathValue("id"), AdminOperator(req, a.Operator))
+	if err != nil {
+		if errors.Is(err, ErrStoredDeliveryNotFound) {
+			a.handleError(w, req, http.StatusNotFound, err)
+			return
+		}
+		a.handleError(w, req, http.StatusBadGateway, err)
+		return
+	}
+	v := struct {
+		StatusCode int `json:"status_code"`
+	}{
+		StatusCode: statusCode,
+	}
+	writeAdminJSON(w, http.StatusOK, v)
+}
+
+func (a *Admin) queue(w http.ResponseWriter, req *http.Request) {
+	ps := a.Handler.asyncPools()
+	if len(ps) == 0 {
+		http.Error(w, "async not configured", http.StatusNotFound)
+		return
+	}
+	v := struct {
+		Queued  []string `json:"queued"`
+		Running []string `json:"running"`
+	}{
+		Queued:  []string{},
+		Running: []string{},
+	}
+	for _, p := range ps {
+		v.Queued = append(v.Queued, p.Queued()...)
+		v.Running = append(v.Running, p.Running()...)
+	}
+	writeAdminJSON(w, http.StatusOK, v)
+}
+
+func (a *Admin) listDedup(w http.ResponseWriter, req *http.Request) {
+	if a.Handler.Dedup == nil {
+		http.Error(w, "dedup not configured", http.StatusNotFound)
+		return
+	}
+	l, ok := a.Handler.Dedup.(DedupLister)
+	if !ok {
+		http.Error(w, "dedup store can't be listed", http.StatusNotImplemented)
+		return
+	}
+	es, err := l.ListSeen(req.Context())
+	if err != nil {
+		a.handleError(w, req, http.StatusInternalServerError, fmt.Errorf("dedup: %w", err))
+		return
+	}
+	v := struct {
+		Deliveries []*DedupEntry `json:"deliveries"`
+	}{
+		Deliveries: es,
+	}
+	writeAdminJSON(w, http.StatusOK, v)
+}
+
+func (a *Admin) forgetDedup(w http.ResponseWriter, req *http.Request) {
+	if a.Handler.Dedup == nil {
+		http.Error(w, "dedup not configured", http.StatusNotFound)
+		return
+	}
+	err := a.Handler.Dedup.Forget(req.Context(), req.PathValue("id"))
+	if err != nil {
+		a.handleError(w, req, http.StatusInternalServerError, fmt.Errorf("dedup: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Admin) handleStoreError(w http.ResponseWriter, req *http.Request, err error) {
+	err = fmt.Errorf("store: %w", err)
+	if errors.Is(err, ErrStoredDeliveryNotFound) {
+		a.handleError(w, req, http.StatusNotFound, err)
+		return
+	}
+	a.handleError(w, req, http.StatusInternalServerError, err)
+}
+
+func (a *Admin) handleError(w http.ResponseWriter, req *http.Request, statusCode int, err error) {
+	http.Error(w, err.Error(), statusCode)
+	if a.Error != nil {
+		a.Error(req.Context(), err, req)
+	}
+}
+
+func (a *Admin) requireMaintenance(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if a.Handler.Maintenance == nil {
+			http.Error(w, "maintenance not configured", http.StatusNotFound)
+			return
+		}
+		f(w, req)
+	}
+}
+
+func (a *Admin) getMaintenance(w http.ResponseWriter, req *http.Request) {
+	writeAdminJSON(w, http.StatusOK, a.Handler.Maintenance.Status())
+}
+
+func (a *Admin) enableMaintenance(w http.ResponseWriter, req *http.Request) {
+	a.Handler.Maintenance.Enable(req.URL.Query().Get("reason"))
+	writeAdminJSON(w, http.StatusOK, a.Handler.Maintenance.Status())
+}
+
+func (a *Admin) disableMaintenance(w http.ResponseWriter, req *http.Request) {
+	a.Handler.Maintenance.Disable()
+	writeAdminJSON(w, http.StatusOK, a.Handler.Maintenance.Status())
+}
+
+func writeAdminJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
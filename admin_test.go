@@ -0,0 +1,305 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+var testAdminTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testNewAdmin(t *testing.T, delivery DeliveryHandler) (*Admin, *MemoryStore) {
+	t.Helper()
+	s := NewMemoryStore(10)
+	for i, id := range []string{"1", "2", "3"} {
+		event := "push"
+		if id == "2" {
+			event = "issues"
+		}
+		err := s.Save(context.Background(), &StoredDelivery{
+			DeliveryMetadata: DeliveryMetadata{
+				Event:      event,
+				DeliveryID: id,
+				ReceivedAt: testAdminTime.Add(time.Duration(i) * time.Minute),
+			},
+			Repository: "pierrre/githubhook",
+			RawPayload: testRawPayload,
+			Status:     DeliveryStatusSucceeded,
+		})
+		assert.NoError(t, err)
+	}
+	a := NewAdmin(&Handler{
+		Delivery: delivery,
+		Store:    s,
+	})
+	return a, s
+}
+
+func testAdminRequest(t *testing.T, a *Admin, method string, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminList(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	for _, tc := range []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "All",
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "Event",
+			query:    "?event=push",
+			expected: []string{"1", "3"},
+		},
+		{
+			name:     "TimeRange",
+			query:    "?since=2024-01-01T00:01:00Z&until=2024-01-01T00:02:00Z",
+			expected: []string{"2"},
+		},
+		{
+			name:     "Limit",
+			query:    "?repository=pierrre/githubhook&status=succeeded&limit=2",
+			expected: []string{"1", "2"},
+		},
+		{
+			name:     "Newest",
+			query:    "?order=newest&limit=2",
+			expected: []string{"3", "2"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := testAdminRequest(t, a, http.MethodGet, "/deliveries"+tc.query)
+			assert.Equal(t, w.Code, http.StatusOK)
+			var v struct {
+				Deliveries []struct {
+					DeliveryID string `json:"delivery_id"`
+				} `json:"deliveries"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &v)
+			assert.NoError(t, err)
+			ids := make([]string, len(v.Deliveries))
+			for i, d := range v.Deliveries {
+				ids[i] = d.DeliveryID
+			}
+			assert.SliceEqual(t, ids, tc.expected)
+		})
+	}
+}
+
+func TestAdminListInvalidParameter(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	for _, query := range []string{"?since=invalid", "?limit=invalid", "?order=invalid"} {
+		w := testAdminRequest(t, a, http.MethodGet, "/deliveries"+query)
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminListStoreError(t *testing.T) {
+	a := NewAdmin(&Handler{
+		Store: &testErrorStore{},
+	})
+	var adminErr error
+	a.Error = func(ctx context.Context, err error, req *http.Request) {
+		adminErr = err
+	}
+	w := testAdminRequest(t, a, http.MethodGet, "/deliveries")
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+	assert.Error(t, adminErr)
+}
+
+func TestAdminGet(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	w := testAdminRequest(t, a, http.MethodGet, "/deliveries/2")
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v adminDelivery
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, v.DeliveryID, "2")
+	assert.Equal(t, v.Event, "issues")
+	assert.Equal(t, v.Repository, "pierrre/githubhook")
+	assert.Equal(t, v.Status, DeliveryStatusSucceeded)
+}
+
+func TestAdminGetNotFound(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	w := testAdminRequest(t, a, http.MethodGet, "/deliveries/unknown")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestAdminGetPayload(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	w := testAdminRequest(t, a, http.MethodGet, "/deliveries/1/payload")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+	b, err := io.ReadAll(w.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), string(testRawPayload))
+}
+
+func TestAdminDelete(t *testing.T) {
+	a, s := testNewAdmin(t, nil)
+	w := testAdminRequest(t, a, http.MethodDelete, "/deliveries/1")
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	_, err := s.Get(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrStoredDeliveryNotFound)
+	w = testAdminRequest(t, a, http.MethodDelete, "/deliveries/1")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestAdminRedeliver(t *testing.T) {
+	var md *DeliveryMetadata
+	a, _ := testNewAdmin(t, func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+		md = m
+		return nil
+	})
+	a.Operator = func(req *http.Request) string {
+		return "operator"
+	}
+	w := testAdminRequest(t, a, http.MethodPost, "/deliveries/1/redeliver")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "{\"status_code\":200}\n")
+	assert.Equal(t, md.DeliveryID, "1")
+	assert.Equal(t, md.Lineage.Operator, "operator")
+	assert.Equal(t, md.Lineage.Reason, "redeliver")
+}
+
+func TestAdminRedeliverError(t *testing.T) {
+	a, _ := testNewAdmin(t, func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+		return errors.New("error")
+	})
+	w := testAdminRequest(t, a, http.MethodPost, "/deliveries/1/redeliver")
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	w = testAdminRequest(t, a, http.MethodPost, "/deliveries/unknown/redeliver")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestAdminMethodNotAllowed(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	w := testAdminRequest(t, a, http.MethodPost, "/deliveries/1")
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+}
+
+func TestAdminNoStore(t *testing.T) {
+	a := NewAdmin(new(Handler))
+	w := testAdminRequest(t, a, http.MethodGet, "/deliveries")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestAdminQueue(t *testing.T) {
+	p := NewAsyncPool(1, 10)
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	err := p.SubmitDelivery("1", func() {
+		close(started)
+		<-unblock
+	})
+	assert.NoError(t, err)
+	<-started
+	err = p.SubmitDelivery("2", func() {})
+	assert.NoError(t, err)
+	a := NewAdmin(&Handler{
+		Async: p,
+	})
+	w := testAdminRequest(t, a, http.MethodGet, "/queue")
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v struct {
+		Queued  []string `json:"queued"`
+		Running []string `json:"running"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, v.Queued, []string{"2"})
+	assert.SliceEqual(t, v.Running, []string{"1"})
+	close(unblock)
+	err = p.Shutdown(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestAdminDedup(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(10, 0)
+	for _, id := range []string{"1", "2"} {
+		_, err := s.MarkSeen(ctx, id)
+		assert.NoError(t, err)
+	}
+	a := NewAdmin(&Handler{
+		Dedup: s,
+	})
+	w := testAdminRequest(t, a, http.MethodDelete, "/dedup/1")
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	w = testAdminRequest(t, a, http.MethodGet, "/dedup")
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v struct {
+		Deliveries []*DedupEntry `json:"deliveries"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.SliceLen(t, v.Deliveries, 1)
+	assert.Equal(t, v.Deliveries[0].DeliveryID, "2")
+	seen, err := s.MarkSeen(ctx, "1")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+}
+
+type testErrorDedupStore struct{}
+
+func (s *testErrorDedupStore) MarkSeen(ctx context.Context, deliveryID string) (bool, error) {
+	return false, errors.New("error")
+}
+
+func (s *testErrorDedupStore) Forget(ctx context.Context, deliveryID string) error {
+	return errors.New("error")
+}
+
+func TestAdminDedupError(t *testing.T) {
+	a := NewAdmin(&Handler{
+		Dedup: &testErrorDedupStore{},
+	})
+	w := testAdminRequest(t, a, http.MethodGet, "/dedup")
+	assert.Equal(t, w.Code, http.StatusNotImplemented)
+	w = testAdminRequest(t, a, http.MethodDelete, "/dedup/1")
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestAdminNotConfigured(t *testing.T) {
+	a := NewAdmin(new(Handler))
+	for _, target := range []string{"/queue", "/dedup", "/maintenance"} {
+		w := testAdminRequest(t, a, http.MethodGet, target)
+		assert.Equal(t, w.Code, http.StatusNotFound)
+	}
+	w := testAdminRequest(t, a, http.MethodDelete, "/dedup/1")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestAdminMaintenance(t *testing.T) {
+	a := NewAdmin(&Handler{
+		Maintenance: new(Maintenance),
+	})
+	w := testAdminRequest(t, a, http.MethodPut, "/maintenance?reason=migration")
+	assert.Equal(t, w.Code, http.StatusOK)
+	var status MaintenanceStatus
+	err := json.Unmarshal(w.Body.Bytes(), &status)
+	assert.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, status.Reason, "migration")
+	w = testAdminRequest(t, a, http.MethodGet, "/maintenance")
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = testAdminRequest(t, a, http.MethodDelete, "/maintenance")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.False(t, a.Handler.Maintenance.Status().Enabled)
+}
@@ -0,0 +1,173 @@
+package githubhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AdminRole is the role of a user of the admin tools ([Admin] and the dashboard package).
+//
+// The zero value has no access.
+type AdminRole int
+
+// Admin roles.
+const (
+	// AdminRoleReader allows to inspect the deliveries and the internal state.
+	AdminRoleReader AdminRole = iota + 1
+	// AdminRoleOperator allows to inspect, and to run the operations: redeliver, delete, purge...
+	AdminRoleOperator
+)
+
+func (r AdminRole) String() string {
+	switch r {
+	case AdminRoleReader:
+		return "reader"
+	case AdminRoleOperator:
+		return "operator"
+	}
+	return "none"
+}
+
+/*
+AdminPrincipal is a user of the admin tools, authenticated by an [AdminAuthorizer].
+
+Fields:
+  - Name identifies the user. It's recorded as the operator of the redeliveries (see [Lineage]).
+  - Role is the role of the user.
+*/
+type AdminPrincipal struct {
+	Name string
+	Role AdminRole
+}
+
+// ErrAdminUnauthenticated is returned by [AdminAuthorizer] if the request doesn't have credentials.
+var ErrAdminUnauthenticated = errors.New("unauthenticated")
+
+// AdminAuthorizer authenticates the requests of the admin tools, and returns their principal.
+//
+// The request is rejected with a 401 response if it returns an error, or with a 403 response if the role of the principal is not sufficient.
+// It allows to grant the inspection access broadly, while the operations stay restricted.
+type AdminAuthorizer interface {
+	Authorize(req *http.Request) (*AdminPrincipal, error)
+}
+
+// AdminAuthorizerFunc is an [AdminAuthorizer] function.
+//
+// It allows to trust the identity set by an authenticating proxy (e.g. a header).
+type AdminAuthorizerFunc func(req *http.Request) (*AdminPrincipal, error)
+
+// Authorize implements [AdminAuthorizer].
+func (f AdminAuthorizerFunc) Authorize(req *http.Request) (*AdminPrincipal, error) {
+	return f(req)
+}
+
+// StaticTokenAuthorizer returns an [AdminAuthorizer] that authenticates the requests with a static bearer token ("Authorization: Bearer TOKEN").
+//
+// The tokens map the tokens to their principal.
+// The tokens are compared in constant time.
+func StaticTokenAuthorizer(tokens map[string]*AdminPrincipal) AdminAuthorizer {
+	return AdminAuthorizerFunc(func(req *http.Request) (*AdminPrincipal, error) {
+		token, ok := bearerToken(req)
+		if !ok {
+			return nil, ErrAdminUnauthenticated
+		}
+		var principal *AdminPrincipal
+		for t, p := range tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				principal = p
+			}
+		}
+		if principal == nil {
+			return nil, errors.New("invalid token")
+		}
+		return principal, nil
+	})
+}
+
+/*
+ClaimsAuthorizer returns an [AdminAuthorizer] that authenticates the requests with a bearer token, whose claims are returned by verify (e.g. an OIDC ID token verified by an OIDC library).
+
+The name of the principal is the "sub" claim.
+The role is read from the roleClaim claim (e.g. "groups"), that is a string or a list of strings: each value is mapped to a role by roles.
+If there are several roles, the highest is kept.
+*/
+func ClaimsAuthorizer(verify func(ctx context.Context, rawToken string) (claims map[string]any, err error), roleClaim string, roles map[string]AdminRole) AdminAuthorizer {
+	return AdminAuthorizerFunc(func(req *http.Request) (*AdminPrincipal, error) {
+		token, ok := bearerToken(req)
+		if !ok {
+			return nil, ErrAdminUnauthenticated
+		}
+		claims, err := verify(req.Context(), token)
+		if err != nil {
+			return nil, fmt.Errorf("verify token: %w", err)
+		}
+		p := new(AdminPrincipal)
+		p.Name, _ = claims["sub"].(string)
+		var values []any
+		switch v := claims[roleClaim].(type) {
+		case string:
+			values = []any{v}
+		case []any:
+			values = v
+		}
+		for _, v := range values {
+			s, _ := v.(string)
+			p.Role = max(p.Role, roles[s])
+		}
+		return p, nil
+	})
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+type adminPrincipalContextKey struct{}
+
+// AuthorizeAdmin authorizes the request with the authorizer, and calls next if the principal has the role.
+//
+// The principal is available with [ContextAdminPrincipal].
+// If the authorizer is nil, the request is authorized (without principal).
+// It's used by [Admin] and the dashboard package.
+func AuthorizeAdmin(w http.ResponseWriter, req *http.Request, authorizer AdminAuthorizer, role AdminRole, next http.HandlerFunc) {
+	if authorizer == nil {
+		next(w, req)
+		return
+	}
+	p, err := authorizer.Authorize(req)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, fmt.Sprintf("unauthorized: %v", err), http.StatusUnauthorized)
+		return
+	}
+	if p.Role < role {
+		http.Error(w, fmt.Sprintf("forbidden: %s role required", role), http.StatusForbidden)
+		return
+	}
+	next(w, req.WithContext(context.WithValue(req.Context(), adminPrincipalContextKey{}, p)))
+}
+
+// ContextAdminPrincipal returns the principal of the request authorized by [AuthorizeAdmin], or nil.
+func ContextAdminPrincipal(ctx context.Context) *AdminPrincipal {
+	p, _ := ctx.Value(adminPrincipalContextKey{}).(*AdminPrincipal)
+	return p
+}
+
+// AdminOperator returns the operator of an operation, recorded in its [Lineage]: the result of operator if it's not nil, or the name of the principal of the request.
+func AdminOperator(req *http.Request, operator func(req *http.Request) string) string {
+	if operator != nil {
+		return operator(req)
+	}
+	if p := ContextAdminPrincipal(req.Context()); p != nil {
+		return p.Name
+	}
+	return ""
+}
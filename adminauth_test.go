@@ -0,0 +1,173 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func testAdminAuthRequest(t *testing.T, a *Admin, method string, target string, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAuthorizer(t *testing.T) {
+	var md *DeliveryMetadata
+	a, _ := testNewAdmin(t, func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+		md = m
+		return nil
+	})
+	a.Authorizer = StaticTokenAuthorizer(map[string]*AdminPrincipal{
+		"reader-token":   {Name: "reader", Role: AdminRoleReader},
+		"operator-token": {Name: "operator", Role: AdminRoleOperator},
+	})
+	for _, tc := range []struct {
+		name     string
+		method   string
+		target   string
+		token    string
+		expected int
+	}{
+		{
+			name:     "Unauthenticated",
+			method:   http.MethodGet,
+			target:   "/deliveries",
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "InvalidToken",
+			method:   http.MethodGet,
+			target:   "/deliveries",
+			token:    "invalid",
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "ReaderList",
+			method:   http.MethodGet,
+			target:   "/deliveries",
+			token:    "reader-token",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "ReaderRedeliver",
+			method:   http.MethodPost,
+			target:   "/deliveries/1/redeliver",
+			token:    "reader-token",
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "ReaderDelete",
+			method:   http.MethodDelete,
+			target:   "/deliveries/1",
+			token:    "reader-token",
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "OperatorList",
+			method:   http.MethodGet,
+			target:   "/deliveries",
+			token:    "operator-token",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "OperatorRedeliver",
+			method:   http.MethodPost,
+			target:   "/deliveries/1/redeliver",
+			token:    "operator-token",
+			expected: http.StatusOK,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := testAdminAuthRequest(t, a, tc.method, tc.target, tc.token)
+			assert.Equal(t, w.Code, tc.expected)
+		})
+	}
+	assert.Equal(t, md.Lineage.Operator, "operator")
+}
+
+func TestAdminAuthorizerUnauthorizedHeader(t *testing.T) {
+	a, _ := testNewAdmin(t, nil)
+	a.Authorizer = StaticTokenAuthorizer(nil)
+	w := testAdminAuthRequest(t, a, http.MethodGet, "/deliveries", "")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+}
+
+func TestClaimsAuthorizer(t *testing.T) {
+	verify := func(ctx context.Context, rawToken string) (map[string]any, error) {
+		switch rawToken {
+		case "reader":
+			return map[string]any{"sub": "alice", "groups": "viewers"}, nil
+		case "operator":
+			return map[string]any{"sub": "bob", "groups": []any{"viewers", "oncall", 1}}, nil
+		case "none":
+			return map[string]any{"sub": "carol"}, nil
+		}
+		return nil, errors.New("invalid token")
+	}
+	a := ClaimsAuthorizer(verify, "groups", map[string]AdminRole{
+		"viewers": AdminRoleReader,
+		"oncall":  AdminRoleOperator,
+	})
+	for _, tc := range []struct {
+		token        string
+		expectedName string
+		expectedRole AdminRole
+	}{
+		{
+			token:        "reader",
+			expectedName: "alice",
+			expectedRole: AdminRoleReader,
+		},
+		{
+			token:        "operator",
+			expectedName: "bob",
+			expectedRole: AdminRoleOperator,
+		},
+		{
+			token:        "none",
+			expectedName: "carol",
+		},
+	} {
+		t.Run(tc.token, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			p, err := a.Authorize(req)
+			assert.NoError(t, err)
+			assert.Equal(t, p.Name, tc.expectedName)
+			assert.Equal(t, p.Role, tc.expectedRole)
+		})
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := a.Authorize(req)
+	assert.ErrorIs(t, err, ErrAdminUnauthenticated)
+	req.Header.Set("Authorization", "Bearer invalid")
+	_, err = a.Authorize(req)
+	assert.Error(t, err)
+}
+
+func TestAdminRoleString(t *testing.T) {
+	assert.Equal(t, AdminRoleReader.String(), "reader")
+	assert.Equal(t, AdminRoleOperator.String(), "operator")
+	assert.Equal(t, AdminRole(0).String(), "none")
+}
+
+func TestAdminOperator(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, AdminOperator(req, nil), "")
+	req = req.WithContext(context.WithValue(req.Context(), adminPrincipalContextKey{}, &AdminPrincipal{Name: "principal"}))
+	assert.Equal(t, AdminOperator(req, nil), "principal")
+	assert.Equal(t, AdminOperator(req, func(req *http.Request) string {
+		return "operator"
+	}), "operator")
+}
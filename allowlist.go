@@ -0,0 +1,283 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSourceNotAllowed is returned if the source address of a request is not allowed by [Handler.SourceAllowlist].
+var ErrSourceNotAllowed = errors.New("source address not allowed")
+
+/*
+SourceAllowlist rejects requests whose source address is not allowed, with a 403 response.
+
+The source address is the remote address of the request.
+If it's a trusted proxy, the source address is the last address of the X-Forwarded-For header that is not a trusted proxy.
+
+Fields:
+  - Prefixes provides the allowed prefixes (required), e.g. [GitHubMetaHooks] or [StaticPrefixes].
+  - TrustedProxies are the prefixes of the trusted proxies (e.g. load balancers). If it's empty, X-Forwarded-For is ignored.
+*/
+type SourceAllowlist struct {
+	Prefixes       PrefixProvider
+	TrustedProxies []netip.Prefix
+}
+
+// SourceAddr returns the source address of a request.
+func (a *SourceAllowlist) SourceAddr(req *http.Request) (netip.Addr, bool) {
+	return a.sourceAddr(req.RemoteAddr, req.Header.Values)
+}
+
+func (a *SourceAllowlist) sourceAddr(remoteAddr string, headerValues func(name string) []string) (netip.Addr, bool) {
+	addr, ok := parseRemoteAddr(remoteAddr)
+	if !ok || !a.isTrustedProxy(addr) {
+		return addr, ok
+	}
+	forwarded := strings.Split(strings.Join(headerValues("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		fa, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = fa.Unmap()
+		if !a.isTrustedProxy(addr) {
+			break
+		}
+	}
+	return addr, true
+}
+
+func (a *SourceAllowlist) isTrustedProxy(addr netip.Addr) bool {
+	return containsAddr(a.TrustedProxies, addr)
+}
+
+func (a *SourceAllowlist) check(in *inbound) error {
+	prefixes, err := a.Prefixes.Prefixes(in.ctx)
+	if err != nil {
+		return fmt.Errorf("source allowlist: %w", err)
+	}
+	addr, ok := a.sourceAddr(in.remoteAddr, in.headerValues)
+	if !ok || !containsAddr(prefixes, addr) {
+		return &RequestError{
+			StatusCode: http.StatusForbidden,
+			Message:    "source address not allowed",
+			Err:        fmt.Errorf("%w: %s", ErrSourceNotAllowed, addr),
+		}
+	}
+	return nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool {
+		return p.Contains(addr)
+	})
+}
+
+func (h *Handler) checkSourceAllowlist(in *inbound) error {
+	if h.SourceAllowlist == nil {
+		return nil
+	}
+	return h.SourceAllowlist.check(in)
+}
+
+// PrefixProvider provides IP prefixes.
+type PrefixProvider interface {
+	Prefixes(ctx context.Context) ([]netip.Prefix, error)
+}
+
+// StaticPrefixes is a [PrefixProvider] that always returns the same prefixes.
+type StaticPrefixes []netip.Prefix
+
+// Prefixes implements [PrefixProvider].
+func (p StaticPrefixes) Prefixes(ctx context.Context) ([]netip.Prefix, error) {
+	return p, nil
+}
+
+// DefaultGitHubMetaURL is the default value of [GitHubMetaHooks.URL].
+const DefaultGitHubMetaURL = "https://api.github.com/meta"
+
+// DefaultGitHubMetaRefreshInterval is the default value of [GitHubMetaHooks.RefreshInterval].
+const DefaultGitHubMetaRefreshInterval = time.Hour
+
+// DefaultGitHubMetaTimeout is the default value of [GitHubMetaHooks.Timeout].
+const DefaultGitHubMetaTimeout = 10 * time.Second
+
+/*
+GitHubMetaHooks is a [PrefixProvider] that returns the prefixes used by GitHub to send webhooks, from the "hooks" field of the GitHub meta API.
+
+The prefixes are cached, and refreshed in the background when they are older than RefreshInterval, so the requests are never blocked by a refresh once the prefixes are cached.
+Only the first call waits for the prefixes, and the concurrent calls share the same request to the API.
+If the refresh fails, the cached prefixes are still used (and the refresh is retried after a minute at most), and the error is returned only if there are no cached prefixes.
+
+Fields (all are optional):
+  - URL is the URL of the meta API (default: [DefaultGitHubMetaURL]), e.g. for GitHub Enterprise Server.
+  - Client is the HTTP client (default: [http.DefaultClient]).
+  - RefreshInterval is the refresh interval (default: [DefaultGitHubMetaRefreshInterval]).
+  - Timeout is the timeout of a request to the API (default: [DefaultGitHubMetaTimeout]).
+
+The zero value is ready to use.
+*/
+type GitHubMetaHooks struct {
+	URL             string
+	Client          *http.Client
+	RefreshInterval time.Duration
+	Timeout         time.Duration
+
+	mu         sync.Mutex
+	prefixes   []netip.Prefix
+	fetchedAt  time.Time
+	retryAt    time.Time
+	refreshing *metaRefresh
+	now        func() time.Time
+}
+
+// metaRefresh is a refresh in progress.
+type metaRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// Prefixes implements [PrefixProvider].
+func (m *GitHubMetaHooks) Prefixes(ctx context.Context) ([]netip.Prefix, error) {
+	m.mu.Lock()
+	if m.prefixes != nil {
+		now := m.getNow()
+		if now.Sub(m.fetchedAt) >= m.getRefreshInterval() && !now.Before(m.retryAt) {
+			m.startRefresh(ctx)
+		}
+		prefixes := m.prefixes
+		m.mu.Unlock()
+		return prefixes, nil
+	}
+	r := m.startRefresh(ctx)
+	m.mu.Unlock()
+	err := r.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.prefixes, nil
+}
+
+// Refresh fetches the prefixes from the meta API, and waits for the result.
+//
+// It can be called at startup, to fail early if the API can't be reached.
+func (m *GitHubMetaHooks) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	r := m.startRefresh(ctx)
+	m.mu.Unlock()
+	return r.wait(ctx)
+}
+
+// startRefresh starts a refresh in the background, or returns the refresh in progress.
+//
+// The lock must be held.
+// The refresh is shared, so it's not canceled by the context of the caller.
+func (m *GitHubMetaHooks) startRefresh(ctx context.Context) *metaRefresh {
+	if m.refreshing != nil {
+		return m.refreshing
+	}
+	r := &metaRefresh{
+		done: make(chan struct{}),
+	}
+	m.refreshing = r
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.getTimeout())
+	go func() {
+		defer cancel()
+		prefixes, err := m.fetch(ctx)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		now := m.getNow()
+		if err != nil {
+			r.err = fmt.Errorf("GitHub meta: %w", err)
+			m.retryAt = now.Add(min(m.getRefreshInterval(), time.Minute))
+		} else {
+			m.prefixes = prefixes
+			m.fetchedAt = now
+		}
+		m.refreshing = nil
+		close(r.done)
+	}()
+	return r
+}
+
+func (r *metaRefresh) wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return fmt.Errorf("GitHub meta: %w", ctx.Err())
+	}
+}
+
+func (m *GitHubMetaHooks) fetch(ctx context.Context) ([]netip.Prefix, error) {
+	u := m.URL
+	if u == "" {
+		u = DefaultGitHubMetaURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	var meta struct {
+		Hooks []string `json:"hooks"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&meta)
+	if err != nil {
+		return nil, fmt.Errorf("JSON decode: %w", err)
+	}
+	prefixes := make([]netip.Prefix, 0, len(meta.Hooks))
+	for _, s := range meta.Hooks {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse prefix: %w", err)
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
+
+func (m *GitHubMetaHooks) getRefreshInterval() time.Duration {
+	if m.RefreshInterval > 0 {
+		return m.RefreshInterval
+	}
+	return DefaultGitHubMetaRefreshInterval
+}
+
+func (m *GitHubMetaHooks) getTimeout() time.Duration {
+	if m.Timeout > 0 {
+		return m.Timeout
+	}
+	return DefaultGitHubMetaTimeout
+}
+
+func (m *GitHubMetaHooks) getNow() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,255 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerSourceAllowlist(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   []string
+		trustedProxies []netip.Prefix
+		statusCode     int
+	}{
+		{
+			name:       "Allowed",
+			remoteAddr: "192.30.252.1:1234",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "NotAllowed",
+			remoteAddr: "10.0.0.1:1234",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:         "ForwardedForIgnored",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: []string{"192.30.252.1"},
+			statusCode:   http.StatusForbidden,
+		},
+		{
+			name:           "TrustedProxy",
+			remoteAddr:     "10.0.0.1:1234",
+			forwardedFor:   []string{"1.2.3.4, 192.30.252.1", "10.0.0.2"},
+			trustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			statusCode:     http.StatusOK,
+		},
+		{
+			name:           "TrustedProxySpoofed",
+			remoteAddr:     "10.0.0.1:1234",
+			forwardedFor:   []string{"192.30.252.1, 1.2.3.4"},
+			trustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			statusCode:     http.StatusForbidden,
+		},
+		{
+			name:           "TrustedProxyInvalidForwardedFor",
+			remoteAddr:     "10.0.0.1:1234",
+			forwardedFor:   []string{"invalid"},
+			trustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			statusCode:     http.StatusForbidden,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var handledErr error
+			h := &Handler{
+				SourceAllowlist: &SourceAllowlist{
+					Prefixes:       StaticPrefixes{netip.MustParsePrefix("192.30.252.0/22")},
+					TrustedProxies: tc.trustedProxies,
+				},
+				Error: func(ctx context.Context, err error, req *http.Request) {
+					handledErr = err
+				},
+			}
+			req, err := new(Signer).NewRequest(ctx, "/", "push", "test", testRawPayload)
+			assert.NoError(t, err)
+			req.RemoteAddr = tc.remoteAddr
+			for _, v := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, tc.statusCode)
+			if tc.statusCode == http.StatusForbidden {
+				assert.ErrorIs(t, handledErr, ErrSourceNotAllowed)
+			}
+		})
+	}
+}
+
+type testPrefixProviderError struct{}
+
+func (testPrefixProviderError) Prefixes(ctx context.Context) ([]netip.Prefix, error) {
+	return nil, errors.New("error")
+}
+
+func TestHandlerSourceAllowlistError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		SourceAllowlist: &SourceAllowlist{
+			Prefixes: testPrefixProviderError{},
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestGitHubMetaHooks(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	requests := 0
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"hooks":["192.30.252.0/22","2a0a:a440::/29"]}`))
+	}))
+	defer srv.Close()
+	getRequests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := &GitHubMetaHooks{
+		URL: srv.URL,
+		now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+	}
+	prefixes, err := m.Prefixes(ctx)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, prefixes, []netip.Prefix{netip.MustParsePrefix("192.30.252.0/22"), netip.MustParsePrefix("2a0a:a440::/29")})
+	_, err = m.Prefixes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, getRequests(), 1)
+	mu.Lock()
+	now = now.Add(DefaultGitHubMetaRefreshInterval)
+	fail = true
+	mu.Unlock()
+	prefixes, err = m.Prefixes(ctx) // The cached prefixes are returned, and refreshed in the background.
+	assert.NoError(t, err)
+	assert.SliceLen(t, prefixes, 2)
+	testWaitFor(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.refreshing == nil
+	})
+	assert.Equal(t, getRequests(), 2)
+	prefixes, err = m.Prefixes(ctx) // The refresh is not retried immediately.
+	assert.NoError(t, err)
+	assert.SliceLen(t, prefixes, 2)
+	assert.Equal(t, getRequests(), 2)
+	err = m.Refresh(ctx)
+	assert.Error(t, err)
+}
+
+func TestGitHubMetaHooksConcurrent(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int64
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		<-unblock
+		_, _ = w.Write([]byte(`{"hooks":["192.30.252.0/22"]}`))
+	}))
+	defer srv.Close()
+	m := &GitHubMetaHooks{
+		URL: srv.URL,
+	}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prefixes, err := m.Prefixes(ctx)
+			assert.NoError(t, err)
+			assert.SliceLen(t, prefixes, 1)
+		}()
+	}
+	testWaitFor(t, func() bool {
+		return requests.Load() == 1
+	})
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := m.Prefixes(canceledCtx) // The caller doesn't wait after its context is canceled.
+	assert.ErrorIs(t, err, context.Canceled)
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, requests.Load(), int64(1))
+}
+
+func TestGitHubMetaHooksTimeout(t *testing.T) {
+	ctx := context.Background()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-unblock:
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	m := &GitHubMetaHooks{
+		URL:     srv.URL,
+		Timeout: time.Millisecond,
+	}
+	_, err := m.Prefixes(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGitHubMetaHooksDefault(t *testing.T) {
+	m := new(GitHubMetaHooks)
+	assert.Equal(t, m.getRefreshInterval(), DefaultGitHubMetaRefreshInterval)
+	assert.Equal(t, m.getTimeout(), DefaultGitHubMetaTimeout)
+}
+
+func TestGitHubMetaHooksError(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{
+			name: "JSON",
+			body: "invalid",
+		},
+		{
+			name: "Prefix",
+			body: `{"hooks":["invalid"]}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+			m := &GitHubMetaHooks{
+				URL: srv.URL,
+			}
+			_, err := m.Prefixes(ctx)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package githubhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+/*
+Annotations are key/value annotations of a delivery, see [DeliveryMetadata.Annotations].
+
+They allow the stages of the pipeline (filters, enrichers, [Router] handlers, sinks...) to communicate, without global state, e.g. "rule" = "deploy" or "enriched" = "pull_request_files".
+They are safe for concurrent use (e.g. by the sinks of [Fanout]), and are encoded in JSON as an object.
+
+A nil value is empty, and [Annotations.Set] panics.
+*/
+type Annotations struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// Set sets the value of a key.
+func (a *Annotations) Set(key string, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.m == nil {
+		a.m = make(map[string]string)
+	}
+	a.m[key] = value
+}
+
+// Get returns the value of a key, and true if it's defined.
+func (a *Annotations) Get(key string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	v, ok := a.m[key]
+	return v, ok
+}
+
+// Delete deletes a key.
+func (a *Annotations) Delete(key string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.m, key)
+}
+
+// All returns a copy of the annotations.
+func (a *Annotations) All() map[string]string {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return maps.Clone(a.m)
+}
+
+// Clone returns a copy of the annotations, that is not shared.
+func (a *Annotations) Clone() *Annotations {
+	if a == nil {
+		return nil
+	}
+	return &Annotations{
+		m: a.All(),
+	}
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (a *Annotations) MarshalJSON() ([]byte, error) {
+	m := a.All()
+	if m == nil {
+		m = map[string]string{}
+	}
+	return json.Marshal(m) //nolint:wrapcheck // Not needed.
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (a *Annotations) UnmarshalJSON(b []byte) error {
+	var m map[string]string
+	err := json.Unmarshal(b, &m)
+	if err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.m = m
+	return nil
+}
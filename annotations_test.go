@@ -0,0 +1,79 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestAnnotations(t *testing.T) {
+	a := new(Annotations)
+	_, ok := a.Get("rule")
+	assert.False(t, ok)
+	a.Set("rule", "deploy")
+	v, ok := a.Get("rule")
+	assert.True(t, ok)
+	assert.Equal(t, v, "deploy")
+	c := a.Clone()
+	a.Delete("rule")
+	_, ok = a.Get("rule")
+	assert.False(t, ok)
+	assert.MapEqual(t, c.All(), map[string]string{"rule": "deploy"})
+}
+
+func TestAnnotationsNil(t *testing.T) {
+	var a *Annotations
+	_, ok := a.Get("rule")
+	assert.False(t, ok)
+	a.Delete("rule")
+	assert.MapEmpty(t, a.All())
+	assert.Zero(t, a.Clone())
+}
+
+func TestAnnotationsJSON(t *testing.T) {
+	a := new(Annotations)
+	a.Set("rule", "deploy")
+	b, err := json.Marshal(a)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"rule":"deploy"}`)
+	u := new(Annotations)
+	err = json.Unmarshal(b, u)
+	assert.NoError(t, err)
+	assert.MapEqual(t, u.All(), a.All())
+	err = json.Unmarshal([]byte(`"invalid"`), u)
+	assert.Error(t, err)
+}
+
+func TestHandlerAnnotations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	var rule string
+	h := &Handler{
+		Sink: Filtered(SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+			rule, _ = d.Annotations.Get("rule")
+			d.Annotations.Set("sent", "true")
+			return nil
+		}), func(ctx context.Context, md *DeliveryMetadata, payload any) bool {
+			md.Annotations.Set("rule", "deploy")
+			return true
+		}),
+		Store: store,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, rule, "deploy")
+	sd, err := store.Get(ctx, req.Header.Get("X-GitHub-Delivery"))
+	assert.NoError(t, err)
+	assert.MapEqual(t, sd.Annotations.All(), map[string]string{"rule": "deploy", "sent": "true"})
+}
@@ -0,0 +1,329 @@
+package githubhook
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrAsyncPoolClosed is returned (wrapped in a [RequestError]) if the [AsyncPool] has been shut down.
+	ErrAsyncPoolClosed = errors.New("async pool closed")
+	// ErrAsyncQueueFull is returned (wrapped in a [RequestError]) if the queue of the [AsyncPool] is full.
+	ErrAsyncQueueFull = errors.New("async queue full")
+)
+
+/*
+AsyncPool processes deliveries asynchronously, with a bounded pool of workers.
+
+It allows to acknowledge deliveries before GitHub's timeout (10 seconds), even if [Handler.Delivery] is slow.
+It is used by [Handler.Async] and [Handler.AsyncLanes], and must be created with [NewAsyncPool].
+
+If the queue is full, deliveries are rejected with a 503 response, so GitHub can redeliver them.
+Errors returned by [Handler.Delivery] are reported to [Handler.Error], with a nil request.
+The context of the request is passed to [Handler.Delivery], without its cancellation.
+*/
+type AsyncPool struct {
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *asyncTask
+	wg     sync.WaitGroup
+
+	stateMu      sync.Mutex
+	seq          int
+	pending      map[*asyncTask]struct{}
+	running      map[*asyncTask]struct{}
+	processed    int
+	shuttingDown bool
+	abandoned    bool
+	drained      []string
+}
+
+// asyncTask is a function queued in an [AsyncPool], with the ID of its delivery (empty if it's not a delivery).
+type asyncTask struct {
+	seq        int
+	deliveryID string
+	f          func()
+}
+
+// NewAsyncPool creates a new [AsyncPool] and starts its workers.
+//
+// workers is the number of concurrent deliveries (minimum 1), and queueSize the number of pending deliveries.
+// If queueSize is 0, deliveries are accepted only if a worker is idle.
+func NewAsyncPool(workers int, queueSize int) *AsyncPool {
+	workers = max(workers, 1)
+	queueSize = max(queueSize, 0)
+	p := &AsyncPool{
+		queue:   make(chan *asyncTask, queueSize),
+		pending: make(map[*asyncTask]struct{}),
+		running: make(map[*asyncTask]struct{}),
+	}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *AsyncPool) work() {
+	defer p.wg.Done()
+	for t := range p.queue {
+		if !p.start(t) {
+			continue
+		}
+		t.f()
+		p.done(t)
+	}
+}
+
+// start marks the task as running, or returns false if it has been abandoned by [AsyncPool.ShutdownWithReport].
+func (p *AsyncPool) start(t *asyncTask) bool {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	if p.abandoned {
+		return false
+	}
+	delete(p.pending, t)
+	p.running[t] = struct{}{}
+	return true
+}
+
+func (p *AsyncPool) done(t *asyncTask) {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	delete(p.running, t)
+	switch {
+	case p.abandoned:
+	case p.shuttingDown:
+		if t.deliveryID != "" {
+			p.drained = append(p.drained, t.deliveryID)
+		}
+	default:
+		p.processed++
+	}
+}
+
+// Submit queues a function, executed by a worker.
+//
+// It returns an error (a [RequestError] with a 503 status) wrapping [ErrAsyncPoolClosed] if the pool has been shut down, or [ErrAsyncQueueFull] if the queue is full.
+// It allows other packages (e.g. sinks) to reuse the pool for their asynchronous work.
+func (p *AsyncPool) Submit(f func()) error {
+	return p.submit("", f)
+}
+
+// SubmitDelivery is like [AsyncPool.Submit], for a function that processes a delivery.
+//
+// The delivery ID is reported in the [ShutdownReport].
+func (p *AsyncPool) SubmitDelivery(deliveryID string, f func()) error {
+	return p.submit(deliveryID, f)
+}
+
+func (p *AsyncPool) submit(deliveryID string, f func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return newAsyncPoolError(ErrAsyncPoolClosed)
+	}
+	p.stateMu.Lock()
+	p.seq++
+	t := &asyncTask{
+		seq:        p.seq,
+		deliveryID: deliveryID,
+		f:          f,
+	}
+	p.pending[t] = struct{}{}
+	p.stateMu.Unlock()
+	select {
+	case p.queue <- t:
+		return nil
+	default:
+		p.stateMu.Lock()
+		delete(p.pending, t)
+		p.stateMu.Unlock()
+		return newAsyncPoolError(ErrAsyncQueueFull)
+	}
+}
+
+func newAsyncPoolError(err error) error {
+	return &RequestError{
+		StatusCode: http.StatusServiceUnavailable,
+		Message:    err.Error(),
+		Err:        err,
+	}
+}
+
+// Queued returns the IDs of the queued deliveries, that are not processed yet, in the submission order.
+//
+// It allows to inspect the queue, e.g. with the [Admin] API.
+func (p *AsyncPool) Queued() []string {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	return asyncTaskDeliveryIDs(maps.Keys(p.pending))
+}
+
+// Running returns the IDs of the deliveries that are being processed by the workers, in the submission order.
+func (p *AsyncPool) Running() []string {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	return asyncTaskDeliveryIDs(maps.Keys(p.running))
+}
+
+// asyncTaskDeliveryIDs returns the delivery IDs of the tasks, sorted in the submission order.
+func asyncTaskDeliveryIDs(tasks iter.Seq[*asyncTask]) []string {
+	ts := slices.SortedFunc(tasks, func(a, b *asyncTask) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	ids := make([]string, 0, len(ts))
+	for _, t := range ts {
+		if t.deliveryID != "" {
+			ids = append(ids, t.deliveryID)
+		}
+	}
+	return ids
+}
+
+// Shutdown stops accepting new deliveries, and waits until the pending deliveries are processed.
+//
+// It returns the context error if the context is done before.
+// See [AsyncPool.ShutdownWithReport] to know which deliveries have been abandoned.
+func (p *AsyncPool) Shutdown(ctx context.Context) error {
+	_, err := p.ShutdownWithReport(ctx)
+	return err
+}
+
+/*
+ShutdownWithReport is like [AsyncPool.Shutdown], and returns the report of the shutdown.
+
+If the context is done before the pending deliveries are processed, the queued deliveries are abandoned: they are not processed.
+The running deliveries are also reported as abandoned, but they can't be interrupted, so they may be processed later.
+The report is returned with the context error.
+*/
+func (p *AsyncPool) ShutdownWithReport(ctx context.Context) (*ShutdownReport, error) {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		p.stateMu.Lock()
+		p.shuttingDown = true
+		p.stateMu.Unlock()
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return p.report(), nil
+	case <-ctx.Done():
+		return p.abandon(), fmt.Errorf("async pool shutdown: %w", ctx.Err())
+	}
+}
+
+func (p *AsyncPool) report() *ShutdownReport {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	return p.newReport()
+}
+
+// abandon abandons the pending and running tasks, and returns the report.
+func (p *AsyncPool) abandon() *ShutdownReport {
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	p.abandoned = true
+	r := p.newReport()
+	ts := slices.Collect(maps.Keys(p.pending))
+	ts = slices.AppendSeq(ts, maps.Keys(p.running))
+	r.Abandoned = asyncTaskDeliveryIDs(slices.Values(ts))
+	return r
+}
+
+func (p *AsyncPool) newReport() *ShutdownReport {
+	return &ShutdownReport{
+		Processed: p.processed,
+		Drained:   slices.Clone(p.drained),
+	}
+}
+
+/*
+ShutdownReport is the report of the shutdown of an [AsyncPool], returned by [AsyncPool.ShutdownWithReport].
+
+It allows the operators to know which deliveries must be redelivered after an unclean shutdown (e.g. a rollout with a too short grace period).
+It can be encoded in JSON (e.g. to be logged), and saved to a [Store] with [ShutdownReport.Save].
+
+The deliveries are identified by their delivery ID.
+The functions submitted with [AsyncPool.Submit] don't have a delivery ID, so they are only counted in Processed.
+
+Fields:
+  - Processed is the number of functions processed before the shutdown.
+  - Drained are the deliveries that were pending when the shutdown started, and have been processed before the end of the shutdown.
+  - Abandoned are the deliveries that were not processed (or were still running) when the context of the shutdown was done. They should be redelivered.
+*/
+type ShutdownReport struct {
+	Processed int      `json:"processed"`
+	Drained   []string `json:"drained"`
+	Abandoned []string `json:"abandoned"`
+}
+
+// ErrDeliveryAbandoned is the error of the deliveries abandoned by the shutdown of an [AsyncPool], saved by [ShutdownReport.Save].
+var ErrDeliveryAbandoned = errors.New("delivery abandoned at shutdown")
+
+// Save marks the abandoned deliveries as failed in the store, with the [ErrDeliveryAbandoned] error, so they can be listed and redelivered (e.g. with the admin API).
+//
+// The deliveries that are not in the store are ignored.
+func (r *ShutdownReport) Save(ctx context.Context, s Store) error {
+	for _, id := range r.Abandoned {
+		d, err := s.Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrStoredDeliveryNotFound) {
+				continue
+			}
+			return fmt.Errorf("shutdown report: %w", err)
+		}
+		d.Status = DeliveryStatusFailed
+		d.Error = ErrDeliveryAbandoned.Error()
+		d.UpdatedAt = time.Now()
+		err = s.Save(ctx, d)
+		if err != nil {
+			return fmt.Errorf("shutdown report: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) deliverAsync(ctx context.Context, p *AsyncPool, md *DeliveryMetadata, rawPayload []byte, payload any) error {
+	ctx = context.WithoutCancel(ctx)
+	h.addInflight()
+	err := p.submit(md.DeliveryID, func() {
+		defer h.doneInflight()
+		err := h.deliverAsyncTask(ctx, md, rawPayload, payload)
+		h.updateStoredDelivery(ctx, md, rawPayload, err)
+		if err == nil {
+			return
+		}
+		err = fmt.Errorf("delivery: %w", err)
+		if forgetErr := h.forgetDuplicate(ctx, md); forgetErr != nil {
+			err = errors.Join(err, forgetErr)
+		}
+		if h.Error != nil {
+			h.Error(ctx, err, nil)
+		}
+	})
+	if err != nil {
+		h.doneInflight()
+	}
+	return err
+}
+
+func (h *Handler) deliverAsyncTask(ctx context.Context, md *DeliveryMetadata, rawPayload []byte, payload any) (err error) {
+	defer recoverPanic(&err)
+	return h.callDelivery(ctx, md, rawPayload, payload)
+}
@@ -0,0 +1,257 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerAsync(t *testing.T) {
+	ctx := context.Background()
+	var count atomic.Int64
+	p := NewAsyncPool(2, 10)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			count.Add(1)
+			return nil
+		},
+		Async: p,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	for range 5 {
+		req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		testExpectResponseStatus(t, resp, http.StatusAccepted)
+	}
+	err := p.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, count.Load(), 5)
+}
+
+func TestHandlerAsyncQueueFull(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	p := NewAsyncPool(1, 1)
+	var once sync.Once
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			once.Do(func() {
+				close(started)
+			})
+			<-unblock
+			return nil
+		},
+		Async: p,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusAccepted)
+	<-started
+	req = testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusAccepted)
+	req = testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusServiceUnavailable)
+	close(unblock)
+	err = p.Shutdown(ctx)
+	assert.NoError(t, err)
+}
+
+func TestHandlerAsyncClosed(t *testing.T) {
+	ctx := context.Background()
+	p := NewAsyncPool(1, 1)
+	err := p.Shutdown(ctx)
+	assert.NoError(t, err)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+		Async: p,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusServiceUnavailable)
+}
+
+func TestHandlerAsyncError(t *testing.T) {
+	ctx := context.Background()
+	p := NewAsyncPool(1, 1)
+	errDelivery := errors.New("error")
+	var reportedErr error
+	var reportedReq *http.Request
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errDelivery
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			reportedErr = err
+			reportedReq = req
+		},
+		Async: p,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusAccepted)
+	err = p.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, reportedErr, errDelivery)
+	assert.Zero(t, reportedReq)
+}
+
+func TestAsyncPoolShutdownTimeout(t *testing.T) {
+	ctx := context.Background()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	p := NewAsyncPool(1, 1)
+	err := p.submit("", func() {
+		<-unblock
+	})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = p.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAsyncPoolSubmit(t *testing.T) {
+	ctx := context.Background()
+	p := NewAsyncPool(1, 1)
+	done := make(chan struct{})
+	err := p.Submit(func() {
+		close(done)
+	})
+	assert.NoError(t, err)
+	<-done
+	err = p.Shutdown(ctx)
+	assert.NoError(t, err)
+	err = p.Submit(func() {})
+	assert.ErrorIs(t, err, ErrAsyncPoolClosed)
+}
+
+func TestAsyncPoolShutdownWithReport(t *testing.T) {
+	ctx := context.Background()
+	p := NewAsyncPool(1, 10)
+	done := make(chan struct{})
+	err := p.SubmitDelivery("1", func() {
+		close(done)
+	})
+	assert.NoError(t, err)
+	<-done
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	err = p.SubmitDelivery("2", func() {
+		close(started)
+		<-unblock
+	})
+	assert.NoError(t, err)
+	<-started
+	err = p.SubmitDelivery("3", func() {})
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(unblock)
+	}()
+	r, err := p.ShutdownWithReport(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Processed, 1)
+	assert.SliceEqual(t, r.Drained, []string{"2", "3"})
+	assert.SliceEmpty(t, r.Abandoned)
+}
+
+func TestAsyncPoolShutdownWithReportAbandoned(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	for _, id := range []string{"1", "2"} {
+		err := s.Save(ctx, &StoredDelivery{
+			DeliveryMetadata: DeliveryMetadata{Event: "push", DeliveryID: id},
+			Status:           DeliveryStatusPending,
+		})
+		assert.NoError(t, err)
+	}
+	p := NewAsyncPool(1, 10)
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	err := p.SubmitDelivery("1", func() {
+		close(started)
+		<-unblock
+	})
+	assert.NoError(t, err)
+	<-started
+	var called atomic.Bool
+	err = p.SubmitDelivery("2", func() {
+		called.Store(true)
+	})
+	assert.NoError(t, err)
+	err = p.SubmitDelivery("3", func() {})
+	assert.NoError(t, err)
+	shutdownCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	r, err := p.ShutdownWithReport(shutdownCtx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.SliceEqual(t, r.Abandoned, []string{"1", "2", "3"})
+	close(unblock)
+	p.wg.Wait()
+	assert.False(t, called.Load())
+	err = r.Save(ctx, s)
+	assert.NoError(t, err)
+	for _, id := range []string{"1", "2"} {
+		d, err := s.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, d.Status, DeliveryStatusFailed)
+		assert.Equal(t, d.Error, ErrDeliveryAbandoned.Error())
+	}
+}
+
+func TestAsyncPoolQueuedRunning(t *testing.T) {
+	ctx := context.Background()
+	p := NewAsyncPool(1, 10)
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	err := p.SubmitDelivery("1", func() {
+		close(started)
+		<-unblock
+	})
+	assert.NoError(t, err)
+	<-started
+	for _, id := range []string{"2", "3"} {
+		err = p.SubmitDelivery(id, func() {})
+		assert.NoError(t, err)
+	}
+	err = p.Submit(func() {})
+	assert.NoError(t, err)
+	assert.SliceEqual(t, p.Queued(), []string{"2", "3"})
+	assert.SliceEqual(t, p.Running(), []string{"1"})
+	close(unblock)
+	err = p.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, p.Queued())
+	assert.SliceEmpty(t, p.Running())
+}
@@ -0,0 +1,55 @@
+package githubhook
+
+import (
+	"slices"
+)
+
+/*
+AsyncLane routes the deliveries to a dedicated [AsyncPool] (a lane), instead of [Handler.Async].
+
+It allows to process the slow deliveries (e.g. large push events) in a slow lane, so the small latency-sensitive deliveries aren't queued behind them.
+A delivery matches the lane if its event is one of Events, and if the size of its raw payload is at least MinPayloadSize.
+
+Fields:
+  - Events are the events routed to the lane (optional). If it's empty, all events match.
+  - MinPayloadSize is the minimum size of the raw payload in bytes (optional). If it's 0, all sizes match.
+  - Pool is the pool of the lane (required).
+*/
+type AsyncLane struct {
+	Events         []string
+	MinPayloadSize int
+	Pool           *AsyncPool
+}
+
+func (l *AsyncLane) match(md *DeliveryMetadata, rawPayload []byte) bool {
+	if len(l.Events) > 0 && !slices.Contains(l.Events, md.Event) {
+		return false
+	}
+	return len(rawPayload) >= l.MinPayloadSize
+}
+
+// getAsyncPool returns the pool of the first lane of [Handler.AsyncLanes] matching the delivery, or [Handler.Async].
+//
+// It returns nil if the delivery must be processed synchronously.
+func (h *Handler) getAsyncPool(md *DeliveryMetadata, rawPayload []byte) *AsyncPool {
+	for _, l := range h.AsyncLanes {
+		if l.match(md, rawPayload) {
+			return l.Pool
+		}
+	}
+	return h.Async
+}
+
+// asyncPools returns [Handler.Async] and the pools of [Handler.AsyncLanes], without duplicates.
+func (h *Handler) asyncPools() []*AsyncPool {
+	var ps []*AsyncPool
+	if h.Async != nil {
+		ps = append(ps, h.Async)
+	}
+	for _, l := range h.AsyncLanes {
+		if l.Pool != nil && !slices.Contains(ps, l.Pool) {
+			ps = append(ps, l.Pool)
+		}
+	}
+	return ps
+}
@@ -0,0 +1,98 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerAsyncLanes(t *testing.T) {
+	ctx := context.Background()
+	fast := NewAsyncPool(1, 10)
+	slow := NewAsyncPool(1, 10)
+	issues := NewAsyncPool(1, 10)
+	var mu sync.Mutex
+	var events []string
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, md.Event)
+			return nil
+		},
+		Async: fast,
+		AsyncLanes: []*AsyncLane{
+			{Events: []string{"push"}, MinPayloadSize: 100, Pool: slow},
+			{Events: []string{"issues"}, Pool: issues},
+		},
+	}
+	largePayload := []byte(`{"ref":"refs/heads/main","padding":"` + string(bytes.Repeat([]byte("a"), 100)) + `"}`)
+	for _, tc := range []struct {
+		event      string
+		rawPayload []byte
+	}{
+		{event: "push", rawPayload: testRawPayload},
+		{event: "push", rawPayload: largePayload},
+		{event: "issues", rawPayload: testRawPayload},
+		{event: "issues", rawPayload: largePayload},
+		{event: "release", rawPayload: largePayload},
+	} {
+		req, err := new(Signer).NewRequest(ctx, "/", tc.event, "", tc.rawPayload)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusAccepted)
+	}
+	for _, tc := range []struct {
+		pool      *AsyncPool
+		processed int
+	}{
+		{pool: fast, processed: 2},
+		{pool: slow, processed: 1},
+		{pool: issues, processed: 2},
+	} {
+		r, err := tc.pool.ShutdownWithReport(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, r.Processed+len(r.Drained), tc.processed)
+	}
+	assert.SliceLen(t, events, 5)
+}
+
+func TestHandlerAsyncLanesWithoutAsync(t *testing.T) {
+	ctx := context.Background()
+	slow := NewAsyncPool(1, 10)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+		AsyncLanes: []*AsyncLane{
+			{MinPayloadSize: 100, Pool: slow},
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	err = slow.Shutdown(ctx)
+	assert.NoError(t, err)
+}
+
+func TestAdminQueueAsyncLanes(t *testing.T) {
+	p := NewAsyncPool(1, 10)
+	err := p.SubmitDelivery("1", func() {})
+	assert.NoError(t, err)
+	err = p.Shutdown(context.Background())
+	assert.NoError(t, err)
+	a := NewAdmin(&Handler{
+		AsyncLanes: []*AsyncLane{{Pool: p}},
+	})
+	w := testAdminRequest(t, a, http.MethodGet, "/queue")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), `{"queued":[],"running":[]}`+"\n")
+}
@@ -0,0 +1,85 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+)
+
+var (
+	// ErrBodyReadTimeout is returned (wrapped in a [RequestError]) if the request body can't be read before the deadline.
+	ErrBodyReadTimeout = errors.New("body read timeout")
+	// ErrBodyReadAborted is returned (wrapped in a [RequestError]) if the client aborted the request while the body was read.
+	ErrBodyReadAborted = errors.New("body read aborted")
+	// ErrBodyTooLarge is returned (wrapped in a [RequestError]) if the request body is larger than [Handler.MaxBodySize].
+	ErrBodyTooLarge = errors.New("body too large")
+)
+
+// DefaultMaxBodySize is the default value of [Handler.MaxBodySize].
+//
+// It is the maximum payload size of GitHub (25 MB).
+const DefaultMaxBodySize = 25 << 20
+
+func (h *Handler) getMaxBodySize() int64 {
+	if h.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return h.MaxBodySize
+}
+
+func limitBody(req *http.Request, limit int64) {
+	if limit > 0 {
+		req.Body = http.MaxBytesReader(nil, req.Body, limit)
+	}
+}
+
+// checkBodySize checks the size of a body that has already been read by the HTTP server.
+func checkBodySize(b []byte, limit int64) error {
+	if limit > 0 && int64(len(b)) > limit {
+		return wrapBodyReadError(&http.MaxBytesError{Limit: limit})
+	}
+	return nil
+}
+
+// wrapBodyReadError distinguishes network problems from other errors.
+func wrapBodyReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &RequestError{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Message:    ErrBodyTooLarge.Error(),
+			Err:        fmt.Errorf("%w: %w", ErrBodyTooLarge, err),
+		}
+	case isTimeoutError(err):
+		return &RequestError{
+			StatusCode: http.StatusRequestTimeout,
+			Message:    ErrBodyReadTimeout.Error(),
+			Err:        fmt.Errorf("%w: %w", ErrBodyReadTimeout, err),
+		}
+	case isAbortedError(err):
+		return &RequestError{
+			StatusCode: http.StatusBadRequest,
+			Message:    ErrBodyReadAborted.Error(),
+			Err:        fmt.Errorf("%w: %w", ErrBodyReadAborted, err),
+		}
+	default:
+		return fmt.Errorf("read body: %w", err)
+	}
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isAbortedError(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.Canceled) || errors.Is(err, net.ErrClosed)
+}
@@ -0,0 +1,120 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+type testErrorReader struct {
+	err error
+}
+
+func (r *testErrorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestHandlerBodyReadError(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		err        error
+		statusCode int
+		expected   error
+	}{
+		{
+			name:       "Timeout",
+			err:        os.ErrDeadlineExceeded,
+			statusCode: http.StatusRequestTimeout,
+			expected:   ErrBodyReadTimeout,
+		},
+		{
+			name:       "Aborted",
+			err:        io.ErrUnexpectedEOF,
+			statusCode: http.StatusBadRequest,
+			expected:   ErrBodyReadAborted,
+		},
+		{
+			name:       "Other",
+			err:        errors.New("error"),
+			statusCode: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var handledErr error
+			h := &Handler{
+				Error: func(ctx context.Context, err error, req *http.Request) {
+					handledErr = err
+				},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", &testErrorReader{err: tc.err})
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-GitHub-Event", "push")
+			req.Header.Set("X-GitHub-Delivery", "test")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, tc.statusCode)
+			assert.ErrorIs(t, handledErr, tc.err)
+			if tc.expected != nil {
+				assert.ErrorIs(t, handledErr, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHandlerMaxBodySize(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"foo":"bar"}`,
+		},
+		{
+			name:        "Form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        url.Values{"payload": {`{"foo":"bar"}`}}.Encode(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var handledErr error
+			h := &Handler{
+				MaxBodySize: 5,
+				Error: func(ctx context.Context, err error, req *http.Request) {
+					handledErr = err
+				},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("X-GitHub-Event", "push")
+			req.Header.Set("X-GitHub-Delivery", "test")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+			assert.ErrorIs(t, handledErr, ErrBodyTooLarge)
+		})
+	}
+}
+
+func TestHandlerMaxBodySizeUnlimited(t *testing.T) {
+	h := &Handler{
+		MaxBodySize: -1,
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"foo":"bar"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+}
@@ -0,0 +1,75 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// BusAllEvents is the topic of a [Bus] that receives all events.
+const BusAllEvents = "*"
+
+// Bus is an in-process publish/subscribe bus for deliveries.
+//
+// Topics are event names.
+// [Bus.Publish] can be used as [Handler.Delivery], so multiple application components can subscribe independently.
+// Subscribers are called synchronously, in subscription order.
+//
+// The zero value is ready to use.
+type Bus struct {
+	mu            sync.RWMutex
+	nextID        int
+	subscriptions map[string][]busSubscription
+}
+
+type busSubscription struct {
+	id      int
+	handler DeliveryHandler
+}
+
+// Subscribe subscribes a [DeliveryHandler] to an event (or [BusAllEvents]).
+//
+// It returns a function that unsubscribes it.
+func (b *Bus) Subscribe(event string, h DeliveryHandler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[string][]busSubscription)
+	}
+	id := b.nextID
+	b.nextID++
+	b.subscriptions[event] = append(b.subscriptions[event], busSubscription{
+		id:      id,
+		handler: h,
+	})
+	return func() {
+		b.unsubscribe(event, id)
+	}
+}
+
+func (b *Bus) unsubscribe(event string, id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[event] = slices.DeleteFunc(slices.Clone(b.subscriptions[event]), func(s busSubscription) bool {
+		return s.id == id
+	})
+}
+
+// Publish publishes a delivery to the subscribers of its event and of [BusAllEvents].
+//
+// It implements [DeliveryHandler].
+// All subscribers are called, even if one of them returns an error, and the errors are joined.
+func (b *Bus) Publish(ctx context.Context, md *DeliveryMetadata, payload any) error {
+	b.mu.RLock()
+	subs := slices.Concat(b.subscriptions[md.Event], b.subscriptions[BusAllEvents])
+	b.mu.RUnlock()
+	var errs []error
+	for _, s := range subs {
+		err := s.handler(ctx, md, payload)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
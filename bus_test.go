@@ -0,0 +1,56 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	bus := new(Bus)
+	var pushCount, allCount int
+	bus.Subscribe("push", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		pushCount++
+		return nil
+	})
+	bus.Subscribe(BusAllEvents, func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		allCount++
+		return nil
+	})
+	bus.Subscribe("issues", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		t.Fatal("unexpected call")
+		return nil
+	})
+	h := &Handler{
+		Delivery: bus.Publish,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, pushCount, 1)
+	assert.Equal(t, allCount, 1)
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+	bus := new(Bus)
+	count := 0
+	unsubscribe := bus.Subscribe("push", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		count++
+		return nil
+	})
+	assert.NoError(t, bus.Publish(ctx, &DeliveryMetadata{Event: "push"}, nil))
+	unsubscribe()
+	assert.NoError(t, bus.Publish(ctx, &DeliveryMetadata{Event: "push"}, nil))
+	assert.Equal(t, count, 1)
+}
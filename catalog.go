@@ -0,0 +1,100 @@
+package githubhook
+
+import (
+	"maps"
+	"slices"
+)
+
+// eventCatalog is the catalog of known events and their actions.
+// The value is nil if the event has no action.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads.
+var eventCatalog = map[string][]string{
+	"branch_protection_configuration": {"disabled", "enabled"},
+	"branch_protection_rule":          {"created", "deleted", "edited"},
+	"check_run":                       {"completed", "created", "requested_action", "rerequested"},
+	"check_suite":                     {"completed", "requested", "rerequested"},
+	"code_scanning_alert":             {"appeared_in_branch", "closed_by_user", "created", "fixed", "reopened", "reopened_by_user"},
+	"commit_comment":                  {"created"},
+	"create":                          nil,
+	"delete":                          nil,
+	"dependabot_alert":                {"auto_dismissed", "auto_reopened", "created", "dismissed", "fixed", "reintroduced", "reopened"},
+	"deploy_key":                      {"created", "deleted"},
+	"deployment":                      {"created"},
+	"deployment_protection_rule":      {"requested"},
+	"deployment_review":               {"approved", "rejected", "requested"},
+	"deployment_status":               {"created"},
+	"discussion":                      {"answered", "category_changed", "closed", "created", "deleted", "edited", "labeled", "locked", "pinned", "reopened", "transferred", "unanswered", "unlabeled", "unlocked", "unpinned"},
+	"discussion_comment":              {"created", "deleted", "edited"},
+	"fork":                            nil,
+	"github_app_authorization":        {"revoked"},
+	"gollum":                          nil,
+	"installation":                    {"created", "deleted", "new_permissions_accepted", "suspend", "unsuspend"},
+	"installation_repositories":       {"added", "removed"},
+	"installation_target":             {"renamed"},
+	"issue_comment":                   {"created", "deleted", "edited"},
+	"issues":                          {"assigned", "closed", "deleted", "demilestoned", "edited", "labeled", "locked", "milestoned", "opened", "pinned", "reopened", "transferred", "unassigned", "unlabeled", "unlocked", "unpinned"},
+	"label":                           {"created", "deleted", "edited"},
+	"marketplace_purchase":            {"cancelled", "changed", "pending_change", "pending_change_cancelled", "purchased"},
+	"member":                          {"added", "edited", "removed"},
+	"membership":                      {"added", "removed"},
+	"merge_group":                     {"checks_requested", "destroyed"},
+	"meta":                            {"deleted"},
+	"milestone":                       {"closed", "created", "deleted", "edited", "opened"},
+	"org_block":                       {"blocked", "unblocked"},
+	"organization":                    {"deleted", "member_added", "member_invited", "member_removed", "renamed"},
+	"package":                         {"published", "updated"},
+	"page_build":                      nil,
+	"ping":                            nil,
+	"project":                         {"closed", "created", "deleted", "edited", "reopened"},
+	"project_card":                    {"converted", "created", "deleted", "edited", "moved"},
+	"project_column":                  {"created", "deleted", "edited", "moved"},
+	"projects_v2_item":                {"archived", "converted", "created", "deleted", "edited", "reordered", "restored"},
+	"public":                          nil,
+	"pull_request":                    {"assigned", "auto_merge_disabled", "auto_merge_enabled", "closed", "converted_to_draft", "demilestoned", "dequeued", "edited", "enqueued", "labeled", "locked", "milestoned", "opened", "ready_for_review", "reopened", "review_request_removed", "review_requested", "synchronize", "unassigned", "unlabeled", "unlocked"},
+	"pull_request_review":             {"dismissed", "edited", "submitted"},
+	"pull_request_review_comment":     {"created", "deleted", "edited"},
+	"pull_request_review_thread":      {"resolved", "unresolved"},
+	"push":                            nil,
+	"registry_package":                {"published", "updated"},
+	"release":                         {"created", "deleted", "edited", "prereleased", "published", "released", "unpublished"},
+	"repository":                      {"archived", "created", "deleted", "edited", "privatized", "publicized", "renamed", "transferred", "unarchived"},
+	"repository_dispatch":             nil,
+	"repository_import":               nil,
+	"repository_vulnerability_alert":  {"create", "dismiss", "reopen", "resolve"},
+	"secret_scanning_alert":           {"created", "reopened", "resolved", "revoked", "validated"},
+	"security_advisory":               {"published", "updated", "withdrawn"},
+	"security_and_analysis":           nil,
+	"sponsorship":                     {"cancelled", "created", "edited", "pending_cancellation", "pending_tier_change", "tier_changed"},
+	"star":                            {"created", "deleted"},
+	"status":                          nil,
+	"team":                            {"added_to_repository", "created", "deleted", "edited", "removed_from_repository"},
+	"team_add":                        nil,
+	"watch":                           {"started"},
+	"workflow_dispatch":               nil,
+	"workflow_job":                    {"completed", "in_progress", "queued", "waiting"},
+	"workflow_run":                    {"completed", "in_progress", "requested"},
+}
+
+// Events returns the sorted names of all known events.
+func Events() []string {
+	return slices.Sorted(maps.Keys(eventCatalog))
+}
+
+// Actions returns the sorted known actions of an event.
+//
+// It returns nil if the event has no action, or is unknown.
+func Actions(event string) []string {
+	return slices.Clone(eventCatalog[event])
+}
+
+// IsKnownEvent returns true if the event is known.
+func IsKnownEvent(event string) bool {
+	_, ok := eventCatalog[event]
+	return ok
+}
+
+// IsKnownAction returns true if the action is known for the event.
+func IsKnownAction(event string, action string) bool {
+	return slices.Contains(eventCatalog[event], action)
+}
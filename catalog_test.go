@@ -0,0 +1,36 @@
+package githubhook
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestEvents(t *testing.T) {
+	events := Events()
+	assert.True(t, slices.IsSorted(events))
+	assert.True(t, slices.Contains(events, "push"))
+	assert.True(t, slices.Contains(events, "pull_request"))
+}
+
+func TestActions(t *testing.T) {
+	for _, event := range Events() {
+		actions := Actions(event)
+		assert.True(t, slices.IsSorted(actions))
+	}
+	assert.True(t, slices.Contains(Actions("pull_request"), "synchronize"))
+	assert.SliceEmpty(t, Actions("push"))
+	assert.SliceEmpty(t, Actions("unknown"))
+}
+
+func TestIsKnownEvent(t *testing.T) {
+	assert.True(t, IsKnownEvent("push"))
+	assert.False(t, IsKnownEvent("unknown"))
+}
+
+func TestIsKnownAction(t *testing.T) {
+	assert.True(t, IsKnownAction("issues", "opened"))
+	assert.False(t, IsKnownAction("issues", "unknown"))
+	assert.False(t, IsKnownAction("push", "opened"))
+}
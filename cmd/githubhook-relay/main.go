@@ -0,0 +1,88 @@
+// Command githubhook-relay relays GitHub webhook deliveries from a public relay channel to a local endpoint, for development.
+//
+// It connects outbound to a relay channel that streams the deliveries as Server-Sent Events (e.g. https://smee.io), so the local endpoint doesn't have to be exposed publicly (no need for ngrok).
+// The deliveries are verified with the webhook secret, and forwarded to the target with the original headers (see [forward]).
+// The invalid deliveries are logged and dropped.
+//
+// Usage:
+//
+//	GITHUB_WEBHOOK_SECRET=secret githubhook-relay -source https://smee.io/channel -target http://localhost:8080/webhook
+//
+// The relay channel must forward the payload without re-encoding it, otherwise the signature can't be verified.
+// The GitHub CLI forwarding API ("gh webhook forward") is not supported.
+//
+// It reconnects automatically when the connection is lost, and stops on SIGINT and SIGTERM.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/forward"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stderr)
+	stop()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("githubhook-relay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	source := fs.String("source", "", "URL of the relay channel (required)")
+	target := fs.String("target", "", "URL of the local endpoint (required)")
+	secret := fs.String("secret", os.Getenv("GITHUB_WEBHOOK_SECRET"), "webhook secret (default: $GITHUB_WEBHOOK_SECRET)")
+	targetSecret := fs.String("target-secret", "", "re-sign the deliveries with another secret for the target")
+	retries := fs.Int("retries", 0, "number of retries after a failed forward")
+	reconnectDelay := fs.Duration("reconnect-delay", DefaultReconnectDelay, "delay before reconnecting to the relay channel")
+	verbose := fs.Bool("v", false, "verbose logs")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	switch {
+	case *source == "":
+		return errors.New("flags: missing source")
+	case *target == "":
+		return errors.New("flags: missing target")
+	case *secret == "":
+		return errors.New("flags: missing secret")
+	}
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	r := &relay{
+		Source: *source,
+		Handler: &githubhook.Handler{
+			Secret: *secret,
+		},
+		Forwarder: &forward.Forwarder{
+			Destinations: []forward.Destination{{
+				URL:     *target,
+				Retries: *retries,
+				Secret:  *targetSecret,
+			}},
+		},
+		ReconnectDelay: *reconnectDelay,
+		Logger:         slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})),
+	}
+	return r.run(ctx)
+}
+
+// DefaultReconnectDelay is the default delay before reconnecting to the relay channel.
+const DefaultReconnectDelay = 5 * time.Second
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := run(ctx, []string{"-source", "http://127.0.0.1:0", "-target", "http://127.0.0.1:0", "-secret", "secret", "-v"}, io.Discard)
+	assert.NoError(t, err)
+}
+
+func TestRunError(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "")
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "Flags",
+			args: []string{"-invalid"},
+		},
+		{
+			name: "Source",
+			args: []string{"-target", "http://localhost", "-secret", "secret"},
+		},
+		{
+			name: "Target",
+			args: []string{"-source", "http://localhost", "-secret", "secret"},
+		},
+		{
+			name: "Secret",
+			args: []string{"-source", "http://localhost", "-target", "http://localhost"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(context.Background(), tc.args, io.Discard)
+			assert.Error(t, err)
+		})
+	}
+}
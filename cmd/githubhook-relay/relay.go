@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/forward"
+)
+
+// relay relays the deliveries from a relay channel (Server-Sent Events) to the forwarder.
+//
+// Each message contains a delivery, encoded as a JSON object with the request headers (lowercase names) and the payload in the "body" field, like smee.io.
+type relay struct {
+	Source         string
+	Client         *http.Client
+	Handler        *githubhook.Handler
+	Forwarder      *forward.Forwarder
+	ReconnectDelay time.Duration
+	Logger         *slog.Logger
+}
+
+// run relays the deliveries until the context is canceled, and reconnects when the connection is lost.
+func (r *relay) run(ctx context.Context) error {
+	for {
+		err := r.stream(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		r.Logger.WarnContext(ctx, "connection lost", "error", err, "reconnect_delay", r.ReconnectDelay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.ReconnectDelay):
+		}
+	}
+}
+
+// stream connects to the relay channel, and relays the deliveries until the connection is closed.
+func (r *relay) stream(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.Source, nil)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Not needed.
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("connect: unexpected response status %d", resp.StatusCode)
+	}
+	r.Logger.InfoContext(ctx, "connected", "source", r.Source)
+	return readEvents(resp.Body, func(ev *sseEvent) {
+		r.handleEvent(ctx, ev)
+	})
+}
+
+func (r *relay) handleEvent(ctx context.Context, ev *sseEvent) {
+	switch ev.name {
+	case "", "message":
+	case "ready", "ping":
+		r.Logger.DebugContext(ctx, "relay event", "event", ev.name)
+		return
+	default:
+		r.Logger.DebugContext(ctx, "unknown relay event", "event", ev.name)
+		return
+	}
+	header, rawPayload, err := decodeMessage(ev.data)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "invalid message", "error", err)
+		return
+	}
+	err = r.relayDelivery(ctx, header, rawPayload)
+	attrs := []any{
+		"event", header.Get("X-GitHub-Event"),
+		"delivery_id", header.Get("X-GitHub-Delivery"),
+	}
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "relay delivery", append(attrs, "error", err)...)
+		return
+	}
+	r.Logger.InfoContext(ctx, "delivery relayed", attrs...)
+}
+
+// relayDelivery verifies a delivery, and forwards it with the "relay" lineage.
+func (r *relay) relayDelivery(ctx context.Context, header http.Header, rawPayload []byte) error {
+	event := header.Get("X-GitHub-Event")
+	deliveryID := header.Get("X-GitHub-Delivery")
+	if event == "" || deliveryID == "" {
+		return errors.New("missing event or delivery ID")
+	}
+	err := r.Handler.VerifySignature(ctx, event, rawPayload, header.Get)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	md := githubhook.NewDeliveryMetadata(event, deliveryID, header.Get)
+	githubhook.NextLineage(md, "", "relay").SetHeader(header)
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+	err = r.Forwarder.Forward(ctx, header, rawPayload)
+	if err != nil {
+		return fmt.Errorf("forward: %w", err)
+	}
+	return nil
+}
+
+// decodeMessage decodes the headers and the raw payload of a delivery from a relay message.
+//
+// The string fields are the headers, and the "body" field is the payload.
+// The payload is kept as is, so the signature can be verified.
+func decodeMessage(data []byte) (http.Header, []byte, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("JSON: %w", err)
+	}
+	rawPayload, ok := fields["body"]
+	if !ok {
+		return nil, nil, errors.New("missing body")
+	}
+	header := make(http.Header)
+	for k, v := range fields {
+		var s string
+		if k == "body" || json.Unmarshal(v, &s) != nil {
+			continue
+		}
+		header.Set(k, s)
+	}
+	return header, rawPayload, nil
+}
+
+// sseEvent is a Server-Sent Event.
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+// readEvents reads the Server-Sent Events from r, and calls handle for each event.
+//
+// It returns when r is closed or fails.
+func readEvents(r io.Reader, handle func(ev *sseEvent)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), int(githubhook.DefaultMaxBodySize)*2)
+	ev := new(sseEvent)
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if data != nil {
+				ev.data = []byte(strings.Join(data, "\n"))
+				handle(ev)
+			}
+			ev = new(sseEvent)
+			data = nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // Comment.
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	err := sc.Err()
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	return errors.New("connection closed")
+}
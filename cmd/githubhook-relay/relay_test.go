@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/forward"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main"}`)
+
+func testMessage(t *testing.T, secret string, deliveryID string) string {
+	t.Helper()
+	header, err := (&githubhook.Signer{Secret: secret}).Header("push", deliveryID, testRawPayload)
+	assert.NoError(t, err)
+	fields := map[string]any{
+		"body":      json.RawMessage(testRawPayload),
+		"query":     map[string]any{},
+		"timestamp": 1234,
+	}
+	for k := range header {
+		fields[strings.ToLower(k)] = header.Get(k)
+	}
+	b, err := json.Marshal(fields)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+type testTarget struct {
+	*httptest.Server
+	mu      sync.Mutex
+	headers []http.Header
+	bodies  []string
+}
+
+func newTestTarget(t *testing.T) *testTarget {
+	t.Helper()
+	tt := new(testTarget)
+	tt.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		tt.mu.Lock()
+		defer tt.mu.Unlock()
+		tt.headers = append(tt.headers, req.Header)
+		tt.bodies = append(tt.bodies, string(b))
+	}))
+	t.Cleanup(tt.Close)
+	return tt
+}
+
+func (tt *testTarget) get() ([]http.Header, []string) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.headers, tt.bodies
+}
+
+func newTestSource(t *testing.T, events string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, events)
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target := newTestTarget(t)
+	source := newTestSource(t, "event: ready\ndata: {}\n\n"+
+		": comment\n\n"+
+		"data: "+testMessage(t, "secret", "1")+"\n\n"+
+		"data: "+testMessage(t, "invalid", "2")+"\n\n"+
+		"data: invalid\n\n"+
+		"event: ping\ndata: {}\n\n")
+	r := &relay{
+		Source:    source.URL,
+		Handler:   &githubhook.Handler{Secret: "secret"},
+		Forwarder: &forward.Forwarder{Destinations: []forward.Destination{{URL: target.URL}}},
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	done := make(chan error)
+	go func() {
+		done <- r.run(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		headers, _ := target.get()
+		if len(headers) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	err := <-done
+	assert.NoError(t, err)
+	headers, bodies := target.get()
+	assert.SliceLen(t, headers, 1)
+	assert.Equal(t, headers[0].Get("X-GitHub-Event"), "push")
+	assert.Equal(t, headers[0].Get("X-GitHub-Delivery"), "1")
+	assert.Equal(t, headers[0].Get(githubhook.LineageReasonHeader), "relay")
+	assert.NoError(t, githubhook.ValidateSignature([]byte(bodies[0]), headers[0].Get("X-Hub-Signature-256"), []byte("secret")))
+	assert.SliceEqual(t, bodies, []string{string(testRawPayload)})
+}
+
+func TestRelayReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var connections int
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		connections++
+		if connections >= 3 {
+			cancel()
+		}
+		if connections == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer source.Close()
+	r := &relay{
+		Source:         source.URL,
+		Handler:        &githubhook.Handler{Secret: "secret"},
+		Forwarder:      &forward.Forwarder{},
+		ReconnectDelay: time.Millisecond,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	err := r.run(ctx)
+	assert.NoError(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, connections, 3)
+}
+
+func TestRelayDeliveryError(t *testing.T) {
+	ctx := context.Background()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer target.Close()
+	r := &relay{
+		Handler:   &githubhook.Handler{Secret: "secret"},
+		Forwarder: &forward.Forwarder{Destinations: []forward.Destination{{URL: target.URL}}},
+	}
+	header, rawPayload, err := decodeMessage([]byte(testMessage(t, "secret", "1")))
+	assert.NoError(t, err)
+	err = r.relayDelivery(ctx, header, rawPayload)
+	assert.ErrorContains(t, err, "forward")
+	err = r.relayDelivery(ctx, make(http.Header), rawPayload)
+	assert.ErrorContains(t, err, "missing event or delivery ID")
+}
+
+func TestDecodeMessageError(t *testing.T) {
+	for _, data := range []string{
+		`invalid`,
+		`{"x-github-event": "push"}`,
+	} {
+		_, _, err := decodeMessage([]byte(data))
+		assert.Error(t, err)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	var events []string
+	err := readEvents(strings.NewReader("event: a\ndata: 1\ndata: 2\n\n: comment\n\ndata:3\nid: 1\n\n"), func(ev *sseEvent) {
+		events = append(events, fmt.Sprintf("%s=%s", ev.name, ev.data))
+	})
+	assert.ErrorContains(t, err, "connection closed")
+	assert.SliceEqual(t, events, []string{"a=1\n2", "=3"})
+}
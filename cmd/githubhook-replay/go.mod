@@ -0,0 +1,17 @@
+module github.com/pierrre/githubhook/cmd/githubhook-replay
+
+go 1.23.0
+
+require (
+	github.com/pierrre/assert v0.6.0
+	github.com/pierrre/githubhook v0.1.0
+	github.com/pierrre/githubhook/githubhookbolt v0.1.0
+	go.etcd.io/bbolt v1.4.0
+)
+
+require (
+	github.com/pierrre/compare v1.4.13 // indirect
+	github.com/pierrre/go-libs v0.10.3 // indirect
+	github.com/pierrre/pretty v0.8.1 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+)
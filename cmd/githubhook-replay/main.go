@@ -0,0 +1,167 @@
+// Command githubhook-replay replays saved GitHub webhook deliveries to a target handler, or reports their traffic.
+//
+// The deliveries are re-signed with the secret, and sent with the GitHub headers and a lineage with the "replay" reason (see [githubhook.Lineage]).
+// It's useful to reproduce production issues locally, and for load testing.
+//
+// The deliveries are read from:
+//   - raw JSON payload files, if the event is set with -event
+//   - JSON files of a [githubhook.Record] otherwise, e.g. the objects archived to S3 by the githubhookaws package
+//   - JSONL files of [githubhook.Record] (".jsonl" extension), one per line
+//   - a bbolt database of [githubhookbolt.Store] (-bolt), filtered with -filter-event, -repository, -status, -since, -until and -limit
+//
+// Usage:
+//
+//	GITHUB_WEBHOOK_SECRET=secret githubhook-replay -target http://localhost:8080/webhook -event push payload.json
+//	githubhook-replay -target http://localhost:8080/webhook -secret secret -bolt deliveries.db -filter-event push -since 2024-01-02T15:04:05Z
+//	githubhook-replay -target http://localhost:8080/webhook -secret secret -count 1000 -concurrency 10 -new-id deliveries.jsonl
+//	githubhook-replay -target http://localhost:8080/webhook -secret secret -ordered -speed 10 -bolt deliveries.db -repository pierrre/githubhook
+//
+// With -ordered, the deliveries are replayed in their original order per repository (by received time), e.g. to debug the consumers that depend on the order like deployment pipelines.
+// The deliveries of a repository are sent one at a time, and the repositories are replayed concurrently.
+// With -speed, the original intervals between the deliveries are respected, divided by the speed factor (e.g. 10 is 10 times faster).
+// The replay is paused and resumed with the SIGUSR1 signal.
+//
+// It exits with an error if a delivery is not accepted by the target (response status not 2xx).
+//
+// With -report, the deliveries are not replayed: the traffic report of the deliveries (events, actions and repositories, with their volume and an example payload) is written to stdout, in Markdown or JSON (see [githubhook.TrafficReport]).
+// It's useful for pruning the events of the webhook subscriptions and the filters:
+//
+//	githubhook-replay -report markdown -bolt deliveries.db -since 2024-01-01T00:00:00Z > traffic.md
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("githubhook-replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	target := fs.String("target", "", "URL of the target handler (required)")
+	secret := fs.String("secret", os.Getenv("GITHUB_WEBHOOK_SECRET"), "webhook secret used to sign the deliveries (default: $GITHUB_WEBHOOK_SECRET)")
+	event := fs.String("event", "", "event of the raw payload files (default: the files contain records)")
+	boltPath := fs.String("bolt", "", "bbolt database of stored deliveries")
+	var filter githubhook.StoreFilter
+	fs.StringVar(&filter.Event, "filter-event", "", "replay the stored deliveries of this event")
+	fs.StringVar(&filter.Repository, "repository", "", "replay the stored deliveries of this repository")
+	status := fs.String("status", "", "replay the stored deliveries with this status")
+	since := fs.String("since", "", "replay the stored deliveries received at or after this time (RFC 3339)")
+	until := fs.String("until", "", "replay the stored deliveries received before this time (RFC 3339)")
+	fs.IntVar(&filter.Limit, "limit", 0, "maximum number of stored deliveries")
+	report := fs.String("report", "", `write the traffic report of the deliveries to stdout instead of replaying them: "markdown" or "json"`)
+	r := new(replayer)
+	fs.StringVar(&r.Operator, "operator", os.Getenv("USER"), "operator of the replay, in the lineage")
+	fs.BoolVar(&r.NewDeliveryID, "new-id", false, "generate new delivery IDs, e.g. to bypass the deduplication")
+	fs.IntVar(&r.Count, "count", 1, "number of times each delivery is sent")
+	fs.IntVar(&r.Concurrency, "concurrency", 1, "number of concurrent requests")
+	fs.DurationVar(&r.Timeout, "timeout", 10*time.Second, "timeout of each request")
+	fs.BoolVar(&r.Ordered, "ordered", false, "replay the deliveries in their original order per repository (pause and resume with SIGUSR1)")
+	fs.Float64Var(&r.Speed, "speed", 0, "with -ordered, respect the original intervals between the deliveries, divided by this factor (default: no delay)")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	r.Target = *target
+	r.Secret = *secret
+	filter.Status = githubhook.DeliveryStatus(*status)
+	filter.Since, err = parseTime(*since)
+	if err != nil {
+		return fmt.Errorf("flags: since: %w", err)
+	}
+	filter.Until, err = parseTime(*until)
+	if err != nil {
+		return fmt.Errorf("flags: until: %w", err)
+	}
+	switch {
+	case *report != "" && *report != "markdown" && *report != "json":
+		return fmt.Errorf("flags: invalid report format %q", *report)
+	case r.Target == "" && *report == "":
+		return errors.New("flags: missing target")
+	case r.Secret == "" && *report == "":
+		return errors.New("flags: missing secret")
+	case *boltPath == "" && fs.NArg() == 0:
+		return errors.New("flags: missing deliveries: files or bolt")
+	case r.Count < 1 || r.Concurrency < 1:
+		return errors.New("flags: count and concurrency must be positive")
+	case r.Ordered && r.Count > 1:
+		return errors.New("flags: count can't be used with ordered")
+	case r.Speed < 0 || (r.Speed > 0 && !r.Ordered):
+		return errors.New("flags: speed must be positive, and requires ordered")
+	}
+	recs, err := loadFiles(fs.Args(), *event)
+	if err != nil {
+		return err
+	}
+	if *boltPath != "" {
+		var stored []*githubhook.Record
+		stored, err = loadBolt(ctx, *boltPath, filter)
+		if err != nil {
+			return err
+		}
+		recs = append(recs, stored...)
+	}
+	if *report != "" {
+		return writeReport(stdout, recs, *report)
+	}
+	r.Logger = slog.New(slog.NewTextHandler(stderr, nil))
+	if r.Ordered {
+		r.Pause = new(pauser)
+		sigusr1 := make(chan os.Signal, 1)
+		signal.Notify(sigusr1, syscall.SIGUSR1)
+		defer signal.Stop(sigusr1)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go r.Pause.toggleOnSignal(ctx, sigusr1, r.Logger)
+	}
+	return r.replay(ctx, recs)
+}
+
+// writeReport writes the traffic report of the deliveries, in Markdown or JSON.
+func writeReport(w io.Writer, recs []*githubhook.Record, format string) error {
+	report := new(githubhook.TrafficReport)
+	for _, rec := range recs {
+		report.Add(rec.Event, rec.ReceivedAt, rec.Payload)
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		err := enc.Encode(report)
+		if err != nil {
+			return fmt.Errorf("report: %w", err)
+		}
+		return nil
+	}
+	return report.WriteMarkdown(w) //nolint:wrapcheck // The error is already prefixed.
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return t, nil
+}
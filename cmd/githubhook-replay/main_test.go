@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	target := newTestTarget(t, "secret")
+	payload := testWriteFile(t, "payload.json", string(testRawPayload))
+	err := run(ctx, []string{"-target", target.URL, "-secret", "secret", "-event", "push", "-count", "2", payload}, io.Discard, io.Discard)
+	assert.NoError(t, err)
+	err = run(ctx, []string{"-target", target.URL, "-secret", "secret", "-ordered", "-speed", "1000000", "-bolt", testBolt(t), "-filter-event", "issues", "-since", "2024-01-02T15:04:00Z", "-until", "2024-01-02T16:00:00Z"}, io.Discard, io.Discard)
+	assert.NoError(t, err)
+	ds := target.getDeliveries()
+	assert.SliceLen(t, ds, 3)
+	assert.Equal(t, ds[2].Event, "issues")
+	assert.Equal(t, ds[2].DeliveryID, "2")
+}
+
+func TestRunReport(t *testing.T) {
+	ctx := context.Background()
+	var stdout bytes.Buffer
+	err := run(ctx, []string{"-report", "markdown", "-bolt", testBolt(t)}, &stdout, io.Discard)
+	assert.NoError(t, err)
+	assert.StringContains(t, stdout.String(), "# Webhook traffic")
+	assert.StringContains(t, stdout.String(), "## issues")
+	stdout.Reset()
+	payload := testWriteFile(t, "payload.json", string(testRawPayload))
+	err = run(ctx, []string{"-report", "json", "-event", "push", payload}, &stdout, io.Discard)
+	assert.NoError(t, err)
+	var report githubhook.TrafficReport
+	err = json.Unmarshal(stdout.Bytes(), &report)
+	assert.NoError(t, err)
+	assert.Equal(t, report.Deliveries, 1)
+	assert.Equal(t, report.Events[0].Event, "push")
+}
+
+func TestRunError(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "")
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "Flags",
+			args: []string{"-invalid"},
+		},
+		{
+			name: "Since",
+			args: []string{"-since", "invalid"},
+		},
+		{
+			name: "Until",
+			args: []string{"-until", "invalid"},
+		},
+		{
+			name: "Target",
+			args: []string{"-secret", "secret", "payload.json"},
+		},
+		{
+			name: "Secret",
+			args: []string{"-target", "http://localhost", "payload.json"},
+		},
+		{
+			name: "Deliveries",
+			args: []string{"-target", "http://localhost", "-secret", "secret"},
+		},
+		{
+			name: "Count",
+			args: []string{"-target", "http://localhost", "-secret", "secret", "-count", "0", "payload.json"},
+		},
+		{
+			name: "OrderedCount",
+			args: []string{"-target", "http://localhost", "-secret", "secret", "-ordered", "-count", "2", "payload.json"},
+		},
+		{
+			name: "Speed",
+			args: []string{"-target", "http://localhost", "-secret", "secret", "-speed", "10", "payload.json"},
+		},
+		{
+			name: "Report",
+			args: []string{"-report", "invalid", "payload.json"},
+		},
+		{
+			name: "Files",
+			args: []string{"-target", "http://localhost", "-secret", "secret", "missing.json"},
+		},
+		{
+			name: "Bolt",
+			args: []string{"-target", "http://localhost", "-secret", "secret", "-bolt", "/missing/deliveries.db"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(context.Background(), tc.args, io.Discard, io.Discard)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+// replayOrdered sends the deliveries in their original order per repository, and logs a summary.
+//
+// The deliveries are sorted by received time (the deliveries without received time keep their order).
+// The deliveries of a repository are sent one at a time, so a delivery is sent after the previous one is completed.
+// The repositories are replayed concurrently, with Concurrency concurrent requests.
+// If Speed is positive, the original intervals between the deliveries are respected, divided by Speed.
+// The replay is suspended while Pause is paused.
+func (r *replayer) replayOrdered(ctx context.Context, recs []*githubhook.Record) error {
+	recs = slices.Clone(recs)
+	slices.SortStableFunc(recs, func(a, b *githubhook.Record) int {
+		return a.ReceivedAt.Compare(b.ReceivedAt)
+	})
+	var repos []string
+	byRepo := make(map[string][]*githubhook.Record)
+	for _, rec := range recs {
+		repo := githubhook.RepositoryFullName(json.RawMessage(rec.Payload))
+		if _, ok := byRepo[repo]; !ok {
+			repos = append(repos, repo)
+		}
+		byRepo[repo] = append(byRepo[repo], rec)
+	}
+	var first time.Time
+	for _, rec := range recs {
+		if !rec.ReceivedAt.IsZero() {
+			first = rec.ReceivedAt
+			break
+		}
+	}
+	pause := r.Pause
+	if pause == nil {
+		pause = new(pauser)
+	}
+	clock := pause.clock()
+	st := newReplayStats()
+	sem := make(chan struct{}, r.Concurrency)
+	var wg sync.WaitGroup
+	for _, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, rec := range byRepo[repo] {
+				var at time.Duration
+				if r.Speed > 0 && !rec.ReceivedAt.IsZero() {
+					at = time.Duration(float64(rec.ReceivedAt.Sub(first)) / r.Speed)
+				}
+				if !clock.wait(ctx, at) {
+					return
+				}
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				r.sendRecord(ctx, rec, st)
+				<-sem
+			}
+		}()
+	}
+	wg.Wait()
+	return r.summary(ctx, st)
+}
+
+// pauser pauses and resumes a replay.
+//
+// The zero value is not paused.
+type pauser struct {
+	mu       sync.Mutex
+	paused   bool
+	pausedAt time.Time
+	offset   time.Duration // Total duration of the past pauses.
+	resumed  chan struct{}
+}
+
+// toggle pauses the replay if it's running, or resumes it if it's paused, and returns true if it's paused.
+func (p *pauser) toggle() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		p.paused = false
+		p.offset += time.Since(p.pausedAt)
+		close(p.resumed)
+		return false
+	}
+	p.paused = true
+	p.pausedAt = time.Now()
+	p.resumed = make(chan struct{})
+	return true
+}
+
+// state returns the channel closed when the replay is resumed (nil if it's not paused), and the total duration of the pauses.
+func (p *pauser) state() (resumed <-chan struct{}, offset time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return p.resumed, p.offset + time.Since(p.pausedAt)
+	}
+	return nil, p.offset
+}
+
+// clock returns a [replayClock] started now.
+func (p *pauser) clock() *replayClock {
+	_, offset := p.state()
+	return &replayClock{
+		pauser: p,
+		start:  time.Now(),
+		offset: offset,
+	}
+}
+
+// replayClock is the clock of a replay, which is stopped while it's paused.
+type replayClock struct {
+	pauser *pauser
+	start  time.Time
+	offset time.Duration
+}
+
+// wait waits until the replay is not paused, and its elapsed time is at least at.
+//
+// It returns false if the context is canceled.
+func (c *replayClock) wait(ctx context.Context, at time.Duration) bool {
+	for {
+		resumed, offset := c.pauser.state()
+		if resumed != nil {
+			select {
+			case <-resumed:
+				continue
+			case <-ctx.Done():
+				return false
+			}
+		}
+		d := at - (time.Since(c.start) - (offset - c.offset))
+		if d <= 0 {
+			return ctx.Err() == nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		}
+	}
+}
+
+// toggleOnSignal toggles the pauser when a signal is received, until the context is canceled.
+func (p *pauser) toggleOnSignal(ctx context.Context, sigs <-chan os.Signal, logger *slog.Logger) {
+	for {
+		select {
+		case <-sigs:
+		case <-ctx.Done():
+			return
+		}
+		if p.toggle() {
+			logger.InfoContext(ctx, "replay paused")
+		} else {
+			logger.InfoContext(ctx, "replay resumed")
+		}
+	}
+}
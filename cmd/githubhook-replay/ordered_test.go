@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func testOrderedRecord(repo string, id string, receivedAt time.Time) *githubhook.Record {
+	return &githubhook.Record{
+		Event:      "push",
+		DeliveryID: id,
+		Payload:    fmt.Appendf(nil, `{"repository":{"full_name":%q}}`, repo),
+		ReceivedAt: receivedAt,
+	}
+}
+
+func TestReplayOrdered(t *testing.T) {
+	ctx := context.Background()
+	target := newTestTarget(t, "secret")
+	r := newTestReplayer(target.URL)
+	r.Ordered = true
+	r.Speed = 10
+	r.Concurrency = 2
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := time.Now()
+	err := r.replay(ctx, []*githubhook.Record{
+		testOrderedRecord("a/a", "a3", t0.Add(300*time.Millisecond)),
+		testOrderedRecord("b/b", "b2", t0.Add(200*time.Millisecond)),
+		testOrderedRecord("a/a", "a1", t0),
+		testOrderedRecord("b/b", "b1", t0.Add(100*time.Millisecond)),
+		testOrderedRecord("a/a", "a2", t0.Add(100*time.Millisecond)),
+	})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	byRepo := make(map[string][]string)
+	for _, d := range target.getDeliveries() {
+		repo := githubhook.RepositoryFullName(d.Payload)
+		byRepo[repo] = append(byRepo[repo], d.DeliveryID)
+	}
+	assert.SliceEqual(t, byRepo["a/a"], []string{"a1", "a2", "a3"})
+	assert.SliceEqual(t, byRepo["b/b"], []string{"b1", "b2"})
+}
+
+func TestReplayOrderedCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := newTestReplayer("http://127.0.0.1:0")
+	r.Ordered = true
+	err := r.replay(ctx, []*githubhook.Record{
+		testOrderedRecord("a/a", "a1", time.Time{}),
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPauser(t *testing.T) {
+	ctx := context.Background()
+	p := new(pauser)
+	c := p.clock()
+	assert.True(t, c.wait(ctx, 0))
+	assert.True(t, p.toggle())
+	done := make(chan bool)
+	go func() {
+		done <- c.wait(ctx, 0)
+	}()
+	select {
+	case <-done:
+		t.Fatal("not paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.False(t, p.toggle())
+	assert.True(t, <-done)
+	// The paused duration is not counted in the elapsed time.
+	_, offset := p.state()
+	assert.GreaterOrEqual(t, offset, 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, c.wait(ctx, time.Hour))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+// replayer sends the deliveries to the target.
+type replayer struct {
+	Target        string
+	Secret        string
+	Operator      string
+	NewDeliveryID bool
+	Count         int
+	Concurrency   int
+	Timeout       time.Duration
+	Ordered       bool
+	Speed         float64
+	Pause         *pauser
+	Client        *http.Client
+	Logger        *slog.Logger
+}
+
+// replay sends each delivery Count times, with Concurrency concurrent requests, and logs a summary.
+//
+// It returns an error if a delivery is not accepted.
+func (r *replayer) replay(ctx context.Context, recs []*githubhook.Record) error {
+	if r.Ordered {
+		return r.replayOrdered(ctx, recs)
+	}
+	jobs := make(chan *githubhook.Record)
+	go func() {
+		defer close(jobs)
+		for range r.Count {
+			for _, rec := range recs {
+				select {
+				case jobs <- rec:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	st := newReplayStats()
+	var wg sync.WaitGroup
+	for range r.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rec := range jobs {
+				r.sendRecord(ctx, rec, st)
+			}
+		}()
+	}
+	wg.Wait()
+	return r.summary(ctx, st)
+}
+
+// sendRecord sends a delivery, logs the error, and adds it to the stats.
+func (r *replayer) sendRecord(ctx context.Context, rec *githubhook.Record, st *replayStats) {
+	start := time.Now()
+	err := r.send(ctx, rec)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "replay", "event", rec.Event, "delivery_id", rec.DeliveryID, "error", err)
+	}
+	st.add(duration, err)
+}
+
+// replayStats are the stats of a replay.
+type replayStats struct {
+	start time.Time
+
+	mu          sync.Mutex
+	sent        int
+	failed      int
+	total       time.Duration
+	maxDuration time.Duration
+}
+
+func newReplayStats() *replayStats {
+	return &replayStats{
+		start: time.Now(),
+	}
+}
+
+func (st *replayStats) add(duration time.Duration, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sent++
+	if err != nil {
+		st.failed++
+	}
+	st.total += duration
+	st.maxDuration = max(st.maxDuration, duration)
+}
+
+// summary logs the summary of the replay, and returns an error if it's canceled or if a delivery failed.
+func (r *replayer) summary(ctx context.Context, st *replayStats) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	elapsed := time.Since(st.start)
+	attrs := []any{
+		"sent", st.sent,
+		"failed", st.failed,
+		"elapsed", elapsed,
+	}
+	if st.sent > 0 {
+		attrs = append(attrs,
+			"rate", float64(st.sent)/elapsed.Seconds(),
+			"average_duration", st.total/time.Duration(st.sent),
+			"max_duration", st.maxDuration,
+		)
+	}
+	r.Logger.InfoContext(ctx, "replay summary", attrs...)
+	err := ctx.Err()
+	if err != nil {
+		return err //nolint:wrapcheck // Not needed.
+	}
+	if st.failed > 0 {
+		return fmt.Errorf("%d/%d deliveries failed", st.failed, st.sent)
+	}
+	return nil
+}
+
+// send re-signs a delivery and sends it to the target.
+func (r *replayer) send(ctx context.Context, rec *githubhook.Record) error {
+	s := &githubhook.Signer{
+		Secret: r.Secret,
+	}
+	if rec.DeliveryID != "" || rec.Lineage != nil {
+		s.Lineage = githubhook.NextLineage(&githubhook.DeliveryMetadata{
+			DeliveryID: rec.DeliveryID,
+			Lineage:    rec.Lineage,
+		}, r.Operator, "replay")
+	}
+	deliveryID := rec.DeliveryID
+	if r.NewDeliveryID {
+		deliveryID = ""
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	req, err := s.NewRequest(ctx, r.Target, rec.Event, deliveryID, rec.Payload)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	for k, v := range rec.Headers {
+		// The recorded signatures are replaced by the new ones.
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), "X-Hub-Signature") {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return nil
+}
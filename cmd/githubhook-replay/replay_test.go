@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main"}`)
+
+type testTarget struct {
+	*httptest.Server
+	mu         sync.Mutex
+	deliveries []*githubhook.VerifiedDelivery
+}
+
+// newTestTarget creates a target that verifies the deliveries with the secret.
+func newTestTarget(t *testing.T, secret string) *testTarget {
+	t.Helper()
+	tt := new(testTarget)
+	h := &githubhook.Handler{
+		Secret: secret,
+	}
+	tt.Server = httptest.NewServer(h.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		d, _ := githubhook.VerifiedDeliveryFromContext(req.Context())
+		tt.mu.Lock()
+		defer tt.mu.Unlock()
+		tt.deliveries = append(tt.deliveries, d)
+	})))
+	t.Cleanup(tt.Close)
+	return tt
+}
+
+func (tt *testTarget) getDeliveries() []*githubhook.VerifiedDelivery {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.deliveries
+}
+
+func newTestReplayer(target string) *replayer {
+	return &replayer{
+		Target:      target,
+		Secret:      "secret",
+		Operator:    "alice",
+		Count:       1,
+		Concurrency: 1,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	target := newTestTarget(t, "secret")
+	r := newTestReplayer(target.URL)
+	err := r.replay(ctx, []*githubhook.Record{
+		{
+			Event:      "push",
+			DeliveryID: "1",
+			Headers: map[string]string{
+				"X-GitHub-Hook-ID":    "123",
+				"X-Hub-Signature-256": "sha256=invalid",
+			},
+			Payload: testRawPayload,
+		},
+		{
+			Event:   "issues",
+			Payload: testRawPayload,
+		},
+	})
+	assert.NoError(t, err)
+	ds := target.getDeliveries()
+	assert.SliceLen(t, ds, 2)
+	assert.Equal(t, ds[0].Event, "push")
+	assert.Equal(t, ds[0].DeliveryID, "1")
+	assert.Equal(t, ds[0].HookID, "123")
+	assert.DeepEqual(t, ds[0].Lineage, &githubhook.Lineage{
+		OriginalDeliveryID: "1",
+		Generation:         1,
+		Operator:           "alice",
+		Reason:             "replay",
+	})
+	assert.Equal(t, string(ds[0].RawPayload), string(testRawPayload))
+	assert.Equal(t, ds[1].Event, "issues")
+	assert.NotZero(t, ds[1].DeliveryID)
+	assert.Zero(t, ds[1].Lineage)
+}
+
+func TestReplayLoad(t *testing.T) {
+	ctx := context.Background()
+	target := newTestTarget(t, "secret")
+	r := newTestReplayer(target.URL)
+	r.Count = 10
+	r.Concurrency = 4
+	r.NewDeliveryID = true
+	err := r.replay(ctx, []*githubhook.Record{
+		{
+			Event:      "push",
+			DeliveryID: "1",
+			Lineage: &githubhook.Lineage{
+				OriginalDeliveryID: "0",
+				Generation:         1,
+			},
+			Payload: testRawPayload,
+		},
+	})
+	assert.NoError(t, err)
+	ds := target.getDeliveries()
+	assert.SliceLen(t, ds, 10)
+	ids := make(map[string]bool)
+	for _, d := range ds {
+		ids[d.DeliveryID] = true
+		assert.Equal(t, d.Lineage.OriginalDeliveryID, "0")
+		assert.Equal(t, d.Lineage.Generation, 2)
+	}
+	assert.MapLen(t, ids, 10)
+}
+
+func TestReplayError(t *testing.T) {
+	ctx := context.Background()
+	target := newTestTarget(t, "other")
+	r := newTestReplayer(target.URL)
+	err := r.replay(ctx, []*githubhook.Record{
+		{
+			Event:   "push",
+			Payload: testRawPayload,
+		},
+	})
+	assert.ErrorContains(t, err, "1/1 deliveries failed")
+	r.Target = "http://127.0.0.1:0"
+	err = r.send(ctx, &githubhook.Record{Event: "push", Payload: testRawPayload})
+	assert.ErrorContains(t, err, "send")
+}
+
+func TestReplayCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := newTestReplayer("http://127.0.0.1:0")
+	err := r.replay(ctx, []*githubhook.Record{
+		{
+			Event:   "push",
+			Payload: testRawPayload,
+		},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/githubhookbolt"
+	bolt "go.etcd.io/bbolt"
+)
+
+// loadFiles loads the deliveries from files.
+//
+// The ".jsonl" files contain a [githubhook.Record] per line.
+// The other files contain a raw payload if event is not empty, or a [githubhook.Record] otherwise.
+func loadFiles(names []string, event string) ([]*githubhook.Record, error) {
+	var recs []*githubhook.Record
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		var fileRecs []*githubhook.Record
+		switch {
+		case filepath.Ext(name) == ".jsonl":
+			fileRecs, err = decodeRecords(b)
+		case event != "":
+			fileRecs = []*githubhook.Record{{
+				Event:   event,
+				Payload: bytes.TrimSpace(b),
+			}}
+		default:
+			var rec *githubhook.Record
+			rec, err = decodeRecord(b)
+			fileRecs = []*githubhook.Record{rec}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %w", name, err)
+		}
+		recs = append(recs, fileRecs...)
+	}
+	return recs, nil
+}
+
+func decodeRecords(b []byte) ([]*githubhook.Record, error) {
+	var recs []*githubhook.Record
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	line := 0
+	for sc.Scan() {
+		line++
+		l := bytes.TrimSpace(sc.Bytes())
+		if len(l) == 0 {
+			continue
+		}
+		rec, err := decodeRecord(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func decodeRecord(b []byte) (*githubhook.Record, error) {
+	rec := new(githubhook.Record)
+	err := json.Unmarshal(b, rec)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal: %w", err)
+	}
+	if rec.Event == "" {
+		return nil, errors.New("missing event")
+	}
+	return rec, nil
+}
+
+// loadBolt loads the stored deliveries from a bbolt database of [githubhookbolt.Store].
+//
+// The database must not be opened by another process (e.g. the handler).
+func loadBolt(ctx context.Context, path string, filter githubhook.StoreFilter) ([]*githubhook.Record, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bbolt: open: %w", err)
+	}
+	defer db.Close() //nolint:errcheck // Not needed.
+	s, err := githubhookbolt.New(db, 0)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // The error is already prefixed.
+	}
+	sds, err := s.List(ctx, filter)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // The error is already prefixed.
+	}
+	recs := make([]*githubhook.Record, 0, len(sds))
+	for _, sd := range sds {
+		recs = append(recs, storedDeliveryRecord(sd))
+	}
+	return recs, nil
+}
+
+func storedDeliveryRecord(sd *githubhook.StoredDelivery) *githubhook.Record {
+	headers := make(map[string]string)
+	for k, v := range map[string]string{
+		"X-GitHub-Hook-ID":                       sd.HookID,
+		"X-GitHub-Hook-Installation-Target-Type": sd.InstallationTargetType,
+		"X-GitHub-Hook-Installation-Target-ID":   sd.InstallationTargetID,
+		"User-Agent":                             sd.UserAgent,
+	} {
+		if v != "" {
+			headers[k] = v
+		}
+	}
+	return &githubhook.Record{
+		Event:      sd.Event,
+		DeliveryID: sd.DeliveryID,
+		Headers:    headers,
+		Payload:    sd.RawPayload,
+		Lineage:    sd.Lineage,
+		ReceivedAt: sd.ReceivedAt,
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/githubhookbolt"
+	bolt "go.etcd.io/bbolt"
+)
+
+func testWriteFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	name = filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(name, []byte(content), 0o600)
+	assert.NoError(t, err)
+	return name
+}
+
+func TestLoadFiles(t *testing.T) {
+	payload := testWriteFile(t, "payload.json", string(testRawPayload)+"\n")
+	recs, err := loadFiles([]string{payload}, "push")
+	assert.NoError(t, err)
+	assert.SliceLen(t, recs, 1)
+	assert.Equal(t, recs[0].Event, "push")
+	assert.Equal(t, string(recs[0].Payload), string(testRawPayload))
+	record := testWriteFile(t, "record.json", `{"event":"issues","delivery_id":"1","payload":{}}`)
+	records := testWriteFile(t, "records.jsonl", `{"event":"push","delivery_id":"2","payload":{}}`+"\n\n"+`{"event":"release","payload":{}}`+"\n")
+	recs, err = loadFiles([]string{record, records}, "")
+	assert.NoError(t, err)
+	assert.SliceLen(t, recs, 3)
+	assert.Equal(t, recs[0].DeliveryID, "1")
+	assert.Equal(t, recs[1].DeliveryID, "2")
+	assert.Equal(t, recs[2].Event, "release")
+}
+
+func TestLoadFilesError(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "JSON",
+			file:    "record.json",
+			content: `invalid`,
+		},
+		{
+			name:    "MissingEvent",
+			file:    "record.json",
+			content: `{"payload":{}}`,
+		},
+		{
+			name:    "JSONL",
+			file:    "records.jsonl",
+			content: `{"event":"push","payload":{}}` + "\ninvalid\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadFiles([]string{testWriteFile(t, tc.file, tc.content)}, "")
+			assert.Error(t, err)
+		})
+	}
+	_, err := loadFiles([]string{filepath.Join(t.TempDir(), "missing.json")}, "push")
+	assert.Error(t, err)
+}
+
+func testBolt(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "deliveries.db")
+	db, err := bolt.Open(path, 0o600, nil)
+	assert.NoError(t, err)
+	defer db.Close() //nolint:errcheck // Test.
+	s, err := githubhookbolt.New(db, 0)
+	assert.NoError(t, err)
+	for i, event := range []string{"push", "issues", "push"} {
+		err = s.Save(ctx, &githubhook.StoredDelivery{
+			DeliveryMetadata: githubhook.DeliveryMetadata{
+				Event:      event,
+				DeliveryID: string(rune('1' + i)),
+				HookID:     "123",
+				ReceivedAt: time.Date(2024, 1, 2, 15, 4, i, 0, time.UTC),
+			},
+			RawPayload: testRawPayload,
+			Status:     githubhook.DeliveryStatusPending,
+		})
+		assert.NoError(t, err)
+	}
+	return path
+}
+
+func TestLoadBolt(t *testing.T) {
+	ctx := context.Background()
+	recs, err := loadBolt(ctx, testBolt(t), githubhook.StoreFilter{Event: "push"})
+	assert.NoError(t, err)
+	assert.SliceLen(t, recs, 2)
+	assert.Equal(t, recs[0].DeliveryID, "1")
+	assert.Equal(t, recs[0].Headers["X-GitHub-Hook-ID"], "123")
+	assert.Equal(t, string(recs[0].Payload), string(testRawPayload))
+	assert.Equal(t, recs[1].DeliveryID, "3")
+}
+
+func TestLoadBoltError(t *testing.T) {
+	_, err := loadBolt(context.Background(), filepath.Join(t.TempDir(), "missing", "deliveries.db"), githubhook.StoreFilter{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+config is the configuration file of githubhookd (JSON, or YAML if the extension is ".yaml" or ".yml").
+
+The secrets can reference environment variables (e.g. "env:GITHUB_WEBHOOK_SECRET"), files (e.g. "file:/run/secrets/webhook") or Vault secrets (e.g. "vault:secret/githubhookd#webhook_secret"), or be encrypted with age (e.g. "age:YWdlLWVuY3J5cHRpb24u..."), so they don't have to be written in clear in the file (see [secretResolver]).
+They are resolved when the file is loaded.
+*/
+type config struct {
+	// Listen is the address of the webhook server (default: ":8080").
+	Listen string `json:"listen"`
+	// Path is the path of the webhook (default: "/webhook").
+	Path string `json:"path"`
+	// AdminListen is the address of the admin server, that serves the metrics and the health check (optional).
+	// If it's empty, they are served by the webhook server.
+	AdminListen string `json:"admin_listen"`
+	// ReusePort binds the listeners with SO_REUSEPORT (optional, Unix only), so a new process can bind the same addresses before the current one shuts down, e.g. during an upgrade.
+	// It's not needed for the handover on SIGUSR2, that passes the listeners to the new process.
+	ReusePort bool `json:"reuse_port"`
+	// Secrets are the accepted webhook secrets (required). The first one is the main secret.
+	Secrets []string `json:"secrets"`
+	// InternalSignature verifies the internal signature of a trusted proxy, instead of GitHub's signature (optional).
+	InternalSignature *internalSignatureConfig `json:"internal_signature"`
+	// AgeIdentityFile is the path of the age identity file, that decrypts the "age:" secrets (optional).
+	// The environment variables are expanded.
+	AgeIdentityFile string `json:"age_identity_file"`
+	// Vault is the configuration of the Vault server, that resolves the "vault:" secrets (optional).
+	Vault vaultConfig `json:"vault"`
+	// Events are the accepted events (optional). The other events are acknowledged, but not forwarded.
+	Events []string `json:"events"`
+	// MaxBodySize is the maximum size of the request body (default: [githubhook.DefaultMaxBodySize]).
+	MaxBodySize int64 `json:"max_body_size"`
+	// Forward are the forward targets.
+	// If there is no target, the deliveries are only logged.
+	Forward []forwardConfig `json:"forward"`
+	// Plugins are the external processors, filters and sinks (see [githubhookplugin]).
+	// They are started with the daemon, and are not reloaded.
+	Plugins []pluginConfig `json:"plugins"`
+	// Timeouts are the timeouts of the servers.
+	Timeouts timeoutsConfig `json:"timeouts"`
+	// LogLevel is the log level: "debug", "info", "warn" or "error" (default: "info").
+	LogLevel string `json:"log_level"`
+}
+
+/*
+internalSignatureConfig is the configuration of the internal signature of a trusted proxy, that verifies GitHub's signature and re-signs the deliveries (see [githubhook.SignatureScheme]).
+
+The signature is the hex encoded HMAC-SHA256 of the payload.
+The main secret is still used to sign the forwarded deliveries.
+*/
+type internalSignatureConfig struct {
+	// Header is the header of the signature (required).
+	Header string `json:"header"`
+	// Prefix is the prefix of the signature (optional), e.g. "sha256=".
+	Prefix string `json:"prefix"`
+	// Secrets are the accepted internal secrets (required).
+	Secrets []string `json:"secrets"`
+}
+
+// signatureScheme returns the signature scheme of the internal signature.
+func (c *internalSignatureConfig) signatureScheme() *githubhook.SignatureScheme {
+	return &githubhook.SignatureScheme{
+		Header:         c.Header,
+		Prefix:         c.Prefix,
+		SecretProvider: githubhook.StaticSecrets(c.Secrets),
+	}
+}
+
+// forwardConfig is the configuration of a forward target.
+type forwardConfig struct {
+	// URL is the URL of the target (required).
+	URL string `json:"url"`
+	// Secret re-signs the deliveries with another secret (optional, default: the main secret).
+	Secret string `json:"secret"`
+	// Events are the events forwarded to this target (optional, default: all).
+	Events []string `json:"events"`
+	// Timeout is the timeout of each attempt.
+	Timeout duration `json:"timeout"`
+	// Retries is the number of retries after a failed attempt.
+	Retries int `json:"retries"`
+	// RetryDelay is the delay between attempts.
+	RetryDelay duration `json:"retry_delay"`
+	// MaxLatency is the maximum latency of the contract test (optional).
+	MaxLatency duration `json:"max_latency"`
+	// Fields transforms the payload, by keeping only these fields (optional, see [githubhook.SelectFields]).
+	Fields []string `json:"fields"`
+	// Template transforms the payload with a template, whose output is the new JSON payload (optional, see [githubhook.TemplateTransform]).
+	// It can't be combined with Fields.
+	Template string `json:"template"`
+}
+
+// transform returns the payload transformation of the target, or nil.
+func (f *forwardConfig) transform() (githubhook.PayloadTransform, error) {
+	switch {
+	case len(f.Fields) > 0 && f.Template != "":
+		return nil, errors.New("fields and template can't be combined")
+	case len(f.Fields) > 0:
+		return githubhook.SelectFields(f.Fields...), nil
+	case f.Template != "":
+		return githubhook.TemplateTransform(f.Template) //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return nil, nil //nolint:nilnil // No transformation.
+}
+
+// pluginConfig is the configuration of a plugin.
+type pluginConfig struct {
+	// Path is the path of the plugin binary (required).
+	Path string `json:"path"`
+	// Args are the arguments of the plugin binary (optional).
+	Args []string `json:"args"`
+	// Events are the events sent to this plugin (optional, default: all).
+	// The other events are not filtered by this plugin.
+	Events []string `json:"events"`
+}
+
+// timeoutsConfig is the configuration of the timeouts of the servers.
+type timeoutsConfig struct {
+	// ReadHeader is the timeout for reading the request headers (default: 5s).
+	ReadHeader duration `json:"read_header"`
+	// Read is the timeout for reading the request (default: 10s).
+	Read duration `json:"read"`
+	// Write is the timeout for writing the response (default: 30s).
+	Write duration `json:"write"`
+	// Idle is the timeout of idle keep-alive connections (default: 60s).
+	Idle duration `json:"idle"`
+	// Shutdown is the timeout of the graceful shutdown (default: 30s).
+	Shutdown duration `json:"shutdown"`
+	// Handover is the timeout of the handover: the new process must be ready before it (default: 1m).
+	Handover duration `json:"handover"`
+}
+
+// duration is a [time.Duration] encoded as a string in JSON (e.g. "10s").
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return fmt.Errorf("duration: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("duration: %w", err)
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) orDefault(def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return time.Duration(d)
+}
+
+// loadConfig reads, decodes and validates the configuration file.
+func loadConfig(name string) (*config, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml":
+		b, err = yaml.YAMLToJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: YAML decode: %w", name, err)
+		}
+	}
+	cfg, err := parseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(b []byte) (*config, error) {
+	cfg := new(config)
+	err := json.Unmarshal(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("JSON decode: %w", err)
+	}
+	err = cfg.resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = ":8080"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/webhook"
+	}
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *config) resolveSecrets() error {
+	r := newSecretResolver(cfg)
+	var err error
+	for i, s := range cfg.Secrets {
+		cfg.Secrets[i], err = r.resolve(s)
+		if err != nil {
+			return fmt.Errorf("secret %d: %w", i, err)
+		}
+	}
+	if cfg.InternalSignature != nil {
+		for i, s := range cfg.InternalSignature.Secrets {
+			cfg.InternalSignature.Secrets[i], err = r.resolve(s)
+			if err != nil {
+				return fmt.Errorf("internal signature: secret %d: %w", i, err)
+			}
+		}
+	}
+	for i := range cfg.Forward {
+		cfg.Forward[i].Secret, err = r.resolve(cfg.Forward[i].Secret)
+		if err != nil {
+			return fmt.Errorf("forward %d: secret: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (cfg *config) validate() error {
+	if len(cfg.Secrets) == 0 || cfg.Secrets[0] == "" {
+		return errors.New("missing secret")
+	}
+	if is := cfg.InternalSignature; is != nil {
+		if is.Header == "" {
+			return errors.New("internal signature: missing header")
+		}
+		if len(is.Secrets) == 0 || is.Secrets[0] == "" {
+			return errors.New("internal signature: missing secret")
+		}
+	}
+	for i, f := range cfg.Forward {
+		if f.URL == "" {
+			return fmt.Errorf("forward %d: missing URL", i)
+		}
+		_, err := f.transform()
+		if err != nil {
+			return fmt.Errorf("forward %d: %w", i, err)
+		}
+	}
+	for i, p := range cfg.Plugins {
+		if p.Path == "" {
+			return fmt.Errorf("plugin %d: missing path", i)
+		}
+	}
+	_, err := cfg.logLevel()
+	return err
+}
+
+func (cfg *config) logLevel() (slog.Level, error) {
+	var l slog.Level
+	if cfg.LogLevel == "" {
+		return l, nil
+	}
+	err := l.UnmarshalText([]byte(cfg.LogLevel))
+	if err != nil {
+		return l, fmt.Errorf("log level: %w", err)
+	}
+	return l, nil
+}
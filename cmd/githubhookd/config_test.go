@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("TEST_SECRET", "secret")
+	cfg, err := parseConfig([]byte(`{
+	"secrets": ["env:TEST_SECRET", "old"],
+	"events": ["push"],
+	"forward": [{"url": "http://localhost/webhook", "secret": "env:TEST_SECRET", "timeout": "5s", "retries": 2, "fields": ["repository.full_name", "ref"]}],
+	"timeouts": {"shutdown": "1m"},
+	"log_level": "debug"
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Listen, ":8080")
+	assert.Equal(t, cfg.Path, "/webhook")
+	assert.SliceEqual(t, cfg.Secrets, []string{"secret", "old"})
+	assert.SliceEqual(t, cfg.Events, []string{"push"})
+	assert.SliceLen(t, cfg.Forward, 1)
+	assert.Equal(t, cfg.Forward[0].Secret, "secret")
+	assert.Equal(t, time.Duration(cfg.Forward[0].Timeout), 5*time.Second)
+	assert.Equal(t, cfg.Forward[0].Retries, 2)
+	assert.SliceEqual(t, cfg.Forward[0].Fields, []string{"repository.full_name", "ref"})
+	assert.Equal(t, cfg.Timeouts.Shutdown.orDefault(time.Second), time.Minute)
+	assert.Equal(t, cfg.Timeouts.Read.orDefault(time.Second), time.Second)
+	level, err := cfg.logLevel()
+	assert.NoError(t, err)
+	assert.Equal(t, level, slog.LevelDebug)
+}
+
+func TestParseConfigInternalSignature(t *testing.T) {
+	t.Setenv("TEST_INTERNAL_SECRET", "internal")
+	cfg, err := parseConfig([]byte(`{
+	"secrets": ["secret"],
+	"internal_signature": {"header": "X-Internal-Signature", "prefix": "sha256=", "secrets": ["env:TEST_INTERNAL_SECRET"]}
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.InternalSignature.Header, "X-Internal-Signature")
+	assert.SliceEqual(t, cfg.InternalSignature.Secrets, []string{"internal"})
+}
+
+func TestParseConfigError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		json string
+	}{
+		{
+			name: "InvalidJSON",
+			json: `{`,
+		},
+		{
+			name: "MissingSecret",
+			json: `{}`,
+		},
+		{
+			name: "InternalSignatureMissingHeader",
+			json: `{"secrets": ["secret"], "internal_signature": {"secrets": ["internal"]}}`,
+		},
+		{
+			name: "InternalSignatureMissingSecret",
+			json: `{"secrets": ["secret"], "internal_signature": {"header": "X-Internal-Signature"}}`,
+		},
+		{
+			name: "EmptySecret",
+			json: `{"secrets": ["env:TEST_UNDEFINED_SECRET"]}`,
+		},
+		{
+			name: "MissingForwardURL",
+			json: `{"secrets": ["secret"], "forward": [{}]}`,
+		},
+		{
+			name: "ForwardFieldsAndTemplate",
+			json: `{"secrets": ["secret"], "forward": [{"url": "http://localhost", "fields": ["ref"], "template": "{}"}]}`,
+		},
+		{
+			name: "ForwardInvalidTemplate",
+			json: `{"secrets": ["secret"], "forward": [{"url": "http://localhost", "template": "{{"}]}`,
+		},
+		{
+			name: "MissingPluginPath",
+			json: `{"secrets": ["secret"], "plugins": [{}]}`,
+		},
+		{
+			name: "InvalidDuration",
+			json: `{"secrets": ["secret"], "timeouts": {"read": "invalid"}}`,
+		},
+		{
+			name: "InvalidDurationType",
+			json: `{"secrets": ["secret"], "timeouts": {"read": 1}}`,
+		},
+		{
+			name: "InvalidLogLevel",
+			json: `{"secrets": ["secret"], "log_level": "invalid"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseConfig([]byte(tc.json))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{"secrets": ["secret"]}`), 0o600)
+	assert.NoError(t, err)
+	cfg, err := loadConfig(name)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, cfg.Secrets, []string{"secret"})
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	t.Setenv("TEST_SECRET", "secret")
+	name := filepath.Join(t.TempDir(), "githubhookd.yaml")
+	err := os.WriteFile(name, []byte(`listen: ":9000"
+secrets:
+  - env:TEST_SECRET
+forward:
+  - url: http://localhost/webhook
+    timeout: 5s
+    retries: 2
+`), 0o600)
+	assert.NoError(t, err)
+	cfg, err := loadConfig(name)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Listen, ":9000")
+	assert.SliceEqual(t, cfg.Secrets, []string{"secret"})
+	assert.Equal(t, cfg.Forward[0].URL, "http://localhost/webhook")
+	assert.Equal(t, cfg.Forward[0].Timeout, duration(5*time.Second))
+	assert.Equal(t, cfg.Forward[0].Retries, 2)
+}
+
+func TestLoadConfigYAMLError(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "githubhookd.yml")
+	err := os.WriteFile(name, []byte("secrets: [\n"), 0o600)
+	assert.NoError(t, err)
+	_, err = loadConfig(name)
+	assert.ErrorContains(t, err, "YAML decode")
+}
+
+func TestLoadConfigNotFound(t *testing.T) {
+	_, err := loadConfig(filepath.Join(t.TempDir(), "githubhookd.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
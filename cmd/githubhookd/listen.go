@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// listenFDsEnv is the environment variable that passes the number of listeners to the new process of a handover (see [handover]).
+// The file descriptor 3 is the readiness pipe, and the listeners follow in order: the webhook server, then the admin server.
+const listenFDsEnv = "GITHUBHOOKD_LISTEN_FDS"
+
+// inheritance is the state inherited from the previous process of a handover.
+type inheritance struct {
+	listeners []net.Listener
+	ready     *os.File
+}
+
+// take returns the next inherited listener, or nil.
+func (inh *inheritance) take() net.Listener {
+	if inh == nil || len(inh.listeners) == 0 {
+		return nil
+	}
+	ln := inh.listeners[0]
+	inh.listeners = inh.listeners[1:]
+	return ln
+}
+
+// notifyReady notifies the previous process that the new process is ready, so it can shut down.
+func (inh *inheritance) notifyReady() {
+	if inh == nil || inh.ready == nil {
+		return
+	}
+	_, _ = io.WriteString(inh.ready, "ready\n")
+	_ = inh.ready.Close()
+	inh.ready = nil
+}
+
+// close closes the listeners that have not been taken, and the readiness pipe.
+// If the new process exits before it's ready, the previous process keeps serving.
+func (inh *inheritance) close() {
+	if inh == nil {
+		return
+	}
+	for _, ln := range inh.listeners {
+		_ = ln.Close()
+	}
+	inh.listeners = nil
+	if inh.ready != nil {
+		_ = inh.ready.Close()
+		inh.ready = nil
+	}
+}
+
+// listen returns the listeners of the webhook server, and of the admin server (nil if admin_listen is not set).
+//
+// The listeners inherited from the previous process of a handover are used first.
+// The other listeners are bound to the configured addresses, with SO_REUSEPORT if reuse_port is set.
+func listen(ctx context.Context, cfg *config, inh *inheritance) (ln net.Listener, adminLn net.Listener, err error) {
+	var lc net.ListenConfig
+	if cfg.ReusePort {
+		lc.Control = reusePortControl
+	}
+	ln = inh.take()
+	if ln == nil {
+		ln, err = lc.Listen(ctx, "tcp", cfg.Listen)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listen: %w", err)
+		}
+	}
+	if cfg.AdminListen == "" {
+		return ln, nil, nil
+	}
+	adminLn = inh.take()
+	if adminLn == nil {
+		adminLn, err = lc.Listen(ctx, "tcp", cfg.AdminListen)
+		if err != nil {
+			_ = ln.Close()
+			return nil, nil, fmt.Errorf("admin listen: %w", err)
+		}
+	}
+	return ln, adminLn, nil
+}
+
+// runHandovers hands the listeners over to a new process (see [handover]) when a signal is received, until the context is canceled.
+//
+// Once the new process is ready, cancel is called, so the current process shuts down gracefully.
+// If the handover fails, the current process keeps serving.
+func runHandovers(ctx context.Context, cancel context.CancelFunc, signals <-chan os.Signal, newCmd func() *exec.Cmd, lns []net.Listener, timeout time.Duration, logger *slog.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		}
+		logger.InfoContext(ctx, "handover started")
+		err := handover(ctx, newCmd(), lns, timeout)
+		if err != nil {
+			logger.ErrorContext(ctx, "handover failed", "error", err)
+			continue
+		}
+		logger.InfoContext(ctx, "handover done")
+		cancel()
+		return
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+var errHandoverUnsupported = errors.New("not supported on this platform")
+
+func reusePortControl(network string, address string, c syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT: not supported on this platform")
+}
+
+// notifyHandover does nothing: the handover is not supported on this platform.
+func notifyHandover(c chan<- os.Signal) {}
+
+func inherit() (*inheritance, error) {
+	return nil, nil //nolint:nilnil // The handover is not supported on this platform.
+}
+
+func handover(ctx context.Context, cmd *exec.Cmd, lns []net.Listener, timeout time.Duration) error {
+	return errHandoverUnsupported
+}
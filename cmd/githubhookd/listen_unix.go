@@ -0,0 +1,143 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePortControl sets SO_REUSEPORT on the socket, so a new process can bind the same address before the current one shuts down.
+func reusePortControl(network string, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1) //nolint:gosec // The file descriptor fits in an int.
+	})
+	if err != nil {
+		return fmt.Errorf("control: %w", err)
+	}
+	if sockErr != nil {
+		return fmt.Errorf("SO_REUSEPORT: %w", sockErr)
+	}
+	return nil
+}
+
+// notifyHandover relays the handover signal (SIGUSR2) to c.
+func notifyHandover(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR2)
+}
+
+// inherit returns the state inherited from the previous process of a handover, or nil if the process was not started by a handover.
+//
+// The inherited file descriptors are closed on exec, so they are not leaked to the plugins.
+func inherit() (*inheritance, error) {
+	v := os.Getenv(listenFDsEnv)
+	if v == "" {
+		return nil, nil //nolint:nilnil // Not started by a handover.
+	}
+	_ = os.Unsetenv(listenFDsEnv)
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("inherit: invalid %s %q", listenFDsEnv, v)
+	}
+	syscall.CloseOnExec(3)
+	inh := &inheritance{
+		ready: os.NewFile(3, "ready"),
+	}
+	for i := range n {
+		f := os.NewFile(uintptr(4+i), "listener") //nolint:gosec // The number of listeners is small.
+		ln, err := net.FileListener(f)
+		_ = f.Close() // The listener has its own file descriptor.
+		if err != nil {
+			inh.close()
+			return nil, fmt.Errorf("inherit: listener %d: %w", i, err)
+		}
+		inh.listeners = append(inh.listeners, ln)
+	}
+	return inh, nil
+}
+
+/*
+handover starts a new process of the daemon (e.g. after the binary has been upgraded) with the listeners, and waits until it's ready.
+
+The listeners are passed as file descriptors, so they remain open during the switchover: the new process is ready when its sink is started and it serves, then the current process can shut down gracefully.
+The pending connections are accepted by the new process, so no delivery is dropped.
+If the new process exits or isn't ready before the timeout, it's killed and an error is returned.
+*/
+func handover(ctx context.Context, cmd *exec.Cmd, lns []net.Listener, timeout time.Duration) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("handover: pipe: %w", err)
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	files := []*os.File{w}
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, ln := range lns {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("handover: listener %s: unsupported type %T", ln.Addr(), ln)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return fmt.Errorf("handover: listener %s: %w", ln.Addr(), err)
+		}
+		files = append(files, f)
+	}
+	cmd.ExtraFiles = files
+	cmd.Env = append(cmd.Environ(), listenFDsEnv+"="+strconv.Itoa(len(lns)))
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("handover: start: %w", err)
+	}
+	for _, f := range files {
+		_ = f.Close() // The new process has its own file descriptors, so the pipe is closed if it exits.
+	}
+	files = nil
+	err = waitReady(ctx, r, timeout)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("handover: %w", err)
+	}
+	go func() {
+		_ = cmd.Wait() // The new process outlives the current one, but it's reaped if it exits before.
+	}()
+	return nil
+}
+
+// waitReady waits until the new process writes to the readiness pipe.
+func waitReady(ctx context.Context, r *os.File, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	err := r.SetReadDeadline(deadline)
+	if err != nil {
+		return fmt.Errorf("set deadline: %w", err)
+	}
+	b := make([]byte, 1)
+	_, err = r.Read(b)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return errors.New("new process not ready before the timeout")
+		}
+		return fmt.Errorf("new process exited before it was ready: %w", err)
+	}
+	return nil
+}
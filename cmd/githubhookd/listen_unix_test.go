@@ -0,0 +1,126 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestListenReusePort(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config{
+		Listen:    "127.0.0.1:0",
+		ReusePort: true,
+	}
+	ln1, _, err := listen(ctx, cfg, nil)
+	assert.NoError(t, err)
+	defer ln1.Close() //nolint:errcheck // Test.
+	cfg.Listen = ln1.Addr().String()
+	ln2, _, err := listen(ctx, cfg, nil)
+	assert.NoError(t, err)
+	defer ln2.Close() //nolint:errcheck // Test.
+	cfg.ReusePort = false
+	_, _, err = listen(ctx, cfg, nil)
+	assert.ErrorContains(t, err, "address already in use")
+}
+
+func TestListenInherited(t *testing.T) {
+	ctx := context.Background()
+	inherited := testListen(t)
+	inh := &inheritance{
+		listeners: []net.Listener{inherited},
+	}
+	cfg := &config{
+		Listen:      "127.0.0.1:0",
+		AdminListen: "127.0.0.1:0",
+	}
+	ln, adminLn, err := listen(ctx, cfg, inh)
+	assert.NoError(t, err)
+	defer ln.Close()      //nolint:errcheck // Test.
+	defer adminLn.Close() //nolint:errcheck // Test.
+	assert.Equal(t, ln, inherited)
+	assert.NotEqual(t, adminLn.Addr().String(), inherited.Addr().String())
+}
+
+// TestHandoverChild is the new process of the handover tests.
+// It's skipped if it's not started by a handover test.
+func TestHandoverChild(t *testing.T) {
+	mode := os.Getenv("GITHUBHOOKD_TEST_HANDOVER")
+	if mode == "" {
+		t.Skip("not started by a handover test")
+	}
+	inh, err := inherit()
+	assert.NoError(t, err)
+	defer inh.close()
+	if mode == "exit" {
+		return
+	}
+	ln := inh.take()
+	done := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.WriteString(w, "new")
+			close(done)
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	inh.notifyReady()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+	}
+	_ = srv.Shutdown(context.Background())
+}
+
+func testHandoverCommand(mode string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHandoverChild$") //nolint:gosec // Test.
+	cmd.Env = append(os.Environ(), "GITHUBHOOKD_TEST_HANDOVER="+mode)
+	return cmd
+}
+
+func TestHandover(t *testing.T) {
+	ln := testListen(t)
+	err := handover(context.Background(), testHandoverCommand("ready"), []net.Listener{ln}, 10*time.Second)
+	assert.NoError(t, err)
+	// The current process shuts down, the new process serves the connections.
+	_ = ln.Close()
+	resp, err := http.Get("http://" + ln.Addr().String()) //nolint:noctx // Test.
+	assert.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), "new")
+}
+
+func TestHandoverExit(t *testing.T) {
+	ln := testListen(t)
+	defer ln.Close() //nolint:errcheck // Test.
+	err := handover(context.Background(), testHandoverCommand("exit"), []net.Listener{ln}, 10*time.Second)
+	assert.ErrorContains(t, err, "exited before it was ready")
+}
+
+func TestHandoverTimeout(t *testing.T) {
+	ln := testListen(t)
+	defer ln.Close() //nolint:errcheck // Test.
+	err := handover(context.Background(), exec.Command("sleep", "10"), []net.Listener{ln}, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "not ready before the timeout")
+}
+
+func TestHandoverStartError(t *testing.T) {
+	ln := testListen(t)
+	defer ln.Close() //nolint:errcheck // Test.
+	err := handover(context.Background(), exec.Command("/nonexistent"), []net.Listener{ln}, time.Second)
+	assert.ErrorContains(t, err, "start")
+}
@@ -0,0 +1,553 @@
+// Command githubhookd is a standalone GitHub webhook receiver.
+//
+// It verifies the deliveries, and forwards them to downstream endpoints (see [forward]).
+// It's configured with a JSON file (or a YAML file with the same fields, if the extension is ".yaml" or ".yml"):
+//
+//	{
+//		"listen": ":8080",
+//		"path": "/webhook",
+//		"admin_listen": "127.0.0.1:9090",
+//		"secrets": ["env:GITHUB_WEBHOOK_SECRET"],
+//		"age_identity_file": "/etc/githubhookd/age.key",
+//		"events": ["push", "pull_request"],
+//		"forward": [
+//			{"url": "http://ci.internal/webhook", "events": ["push"], "retries": 2},
+//			{"url": "http://notify.internal/webhook", "events": ["push"], "fields": ["repository.full_name", "ref", "after"]},
+//			{"url": "http://bot.internal/webhook", "secret": "file:${CREDENTIALS_DIRECTORY}/bot", "timeout": "5s", "max_latency": "500ms"},
+//			{"url": "http://chat.internal/webhook", "secret": "age:YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOS..."}
+//		],
+//		"plugins": [
+//			{"path": "/usr/local/lib/githubhookd/audit", "args": ["-verbose"], "events": ["push"]}
+//		],
+//		"timeouts": {"shutdown": "30s"},
+//		"log_level": "info"
+//	}
+//
+// The secrets can be written as environment variable references ("env:NAME"), file references ("file:PATH"), Vault KV version 2 references ("vault:MOUNT/PATH#KEY", with the "vault" field or the VAULT_ADDR and VAULT_TOKEN environment variables), or age encrypted blobs encoded in base64 ("age:BASE64"), decrypted with the identities of "age_identity_file".
+// So the configuration file can be committed without leaking the secrets.
+// The other secret managers are supported through the files written by their agents (e.g. Kubernetes secrets, systemd credentials).
+// The sops files are not supported: the secrets can be encrypted with age instead.
+//
+// Behind a trusted proxy that verifies GitHub's signature and re-signs the deliveries with an internal secret, the "internal_signature" field verifies the internal signature instead of GitHub's signature (see [githubhook.SignatureScheme]), e.g. {"header": "X-Internal-Signature", "prefix": "sha256=", "secrets": ["vault:secret/ingress#signing_key"]}.
+// Its secrets are resolved like the other secrets.
+//
+// The forwarded deliveries are signed with the secret of the target, or with the main secret (the first one) if the target doesn't have a secret.
+// The payload of a target can be transformed before it's signed, by keeping only some fields ("fields", see [githubhook.SelectFields]), or with a template ("template", see [githubhook.TemplateTransform]), e.g. to slim down the push events for a notification service.
+//
+// The plugins are external binaries that process, filter or sink the deliveries (see [githubhookplugin]).
+// They are started with the daemon, and stopped when it exits.
+// The filter plugins are applied in order, before the forward targets and the sink plugins.
+//
+// Usage:
+//
+//	githubhookd -config githubhookd.json [-watch] [-check-targets] [-self-test] [-from-file FILE] [-sqlite FILE]
+//
+// With the -check-targets flag, it runs the contract test of the forward targets at startup, and exits if one of them fails: a signed synthetic "ping" delivery is sent to each target, and its response status and latency ("max_latency") are checked (see [forward.Forwarder.Check]).
+// Combined with the -self-test flag, it validates the targets on demand, without listening.
+//
+// With the -self-test flag, it validates the configuration end to end and exits, without listening: a signed synthetic "ping" delivery is sent through the handler (see [githubhook.Handler.SelfTest]).
+// It can be used before a deployment, or as a container startup check.
+//
+// With the -from-file flag, it processes the deliveries of a JSONL file of [githubhook.Record]s (or stdin with "-") and exits, without listening: they are verified and forwarded like the received deliveries (see [githubhook.Handler.ProcessRecords]).
+// It stops at the first delivery that is not accepted.
+// It can be used to process recorded or archived deliveries (e.g. by the S3 archiver of the githubhookaws package) offline.
+//
+// Before serving, the sink is started (see [githubhook.Handler.Start]): the connections to the forward targets are established, and it exits if a target can't be reached.
+//
+// The configuration is reloaded on SIGHUP, and when the file changes with the -watch flag, without dropping the in-flight deliveries.
+// The new sink is started before it replaces the current one, and the reload fails if it can't be started.
+// The previous sink is closed when its in-flight deliveries are done (see [githubhook.Handler.Close]), and the current one at shutdown.
+// Only the secrets, the events, the forward targets and the max body size are reloaded, the other fields (including the plugins) require a restart.
+//
+// With the -sqlite flag, it runs in the all-in-one SQLite mode (see [githubhooksqlite]), for small deployments without external dependencies: the deliveries are archived, deduplicated and queued in the SQLite file, and processed by a pool of workers.
+// The pending deliveries of the previous process (e.g. after a crash) are recovered at startup, and the deliveries are deleted after 30 days.
+// The admin UI (the dashboard on "/admin/", and the admin API on "/admin/api/") is served by the admin server only, without authentication, so admin_listen must be a private address.
+// The mode is not reloaded.
+//
+// The metrics (Prometheus) are served on "/metrics", and the health check on "/healthz", by the admin server (or the webhook server if admin_listen is not set).
+// It logs to stderr (JSON), and shuts down gracefully on SIGINT and SIGTERM: the in-flight deliveries are completed.
+//
+// It can be upgraded in place without dropping deliveries.
+// On SIGUSR2, it hands the listeners over to a new process of the daemon (e.g. after the binary has been replaced), started with the same arguments: the current process shuts down gracefully once the new one has started its sink and serves, and keeps serving if the new one fails ("timeouts.handover").
+// Alternatively, with "reuse_port", the listeners are bound with SO_REUSEPORT, so a process manager can start the new process alongside the current one before stopping it.
+// Both are only supported on Unix.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/forward"
+	"github.com/pierrre/githubhook/githubhookplugin"
+	"github.com/pierrre/githubhook/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stderr)
+	stop()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) error {
+	fs := flag.NewFlagSet("githubhookd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "githubhookd.json", "configuration file")
+	watch := fs.Bool("watch", false, "reload the configuration file when it changes")
+	checkTargets := fs.Bool("check-targets", false, "check the forward targets with synthetic deliveries before starting")
+	selfTest := fs.Bool("self-test", false, "validate the configuration with a synthetic delivery, and exit")
+	fromFile := fs.String("from-file", "", `process the deliveries of a JSONL file of records ("-" for stdin), and exit`)
+	sqliteFile := fs.String("sqlite", "", "archive, deduplicate and queue the deliveries in a SQLite file, and serve the admin UI")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	inh, err := inherit()
+	if err != nil {
+		return err
+	}
+	defer inh.close()
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	level, _ := cfg.logLevel() // The level is validated by loadConfig.
+	logger := slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: level}))
+	if *checkTargets {
+		err = runCheckTargets(ctx, cfg, logger)
+		if err != nil {
+			return err
+		}
+	}
+	plugins, err := startPlugins(ctx, cfg, stderr)
+	if err != nil {
+		return err
+	}
+	defer stopPlugins(plugins)
+	if *selfTest {
+		return runSelfTest(ctx, cfg, logger, plugins)
+	}
+	if *fromFile != "" {
+		return runFromFile(ctx, cfg, logger, plugins, *fromFile, stdin)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+	reloads, err := watchConfig(ctx, *configFile, sighup, *watch, logger)
+	if err != nil {
+		return err
+	}
+	var sm *sqliteMode
+	if *sqliteFile != "" {
+		sm, err = openSQLiteMode(ctx, *sqliteFile, logger)
+		if err != nil {
+			return err
+		}
+		defer sm.close()
+	}
+	ln, adminLn, err := listen(ctx, cfg, inh)
+	if err != nil {
+		return err
+	}
+	lns := []net.Listener{ln}
+	if adminLn != nil {
+		lns = append(lns, adminLn)
+	}
+	handovers := make(chan os.Signal, 1)
+	notifyHandover(handovers)
+	defer signal.Stop(handovers)
+	go runHandovers(ctx, cancel, handovers, func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0], args...) //nolint:gosec // The new process is the daemon itself.
+		cmd.Stdin = stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = stderr
+		return cmd
+	}, lns, cfg.Timeouts.Handover.orDefault(time.Minute), logger)
+	return serve(ctx, cfg, logger, ln, adminLn, reloads, plugins, sm, inh.notifyReady)
+}
+
+// startPlugins starts the plugins of the configuration.
+//
+// Their logs are written to stderr.
+func startPlugins(ctx context.Context, cfg *config, stderr io.Writer) ([]*githubhookplugin.Client, error) {
+	plugins := make([]*githubhookplugin.Client, 0, len(cfg.Plugins))
+	for _, p := range cfg.Plugins {
+		c, err := githubhookplugin.Open(ctx, exec.Command(p.Path, p.Args...), stderr) //nolint:gosec // The command is configured by the operator.
+		if err != nil {
+			stopPlugins(plugins)
+			return nil, err //nolint:wrapcheck // The error is already wrapped.
+		}
+		plugins = append(plugins, c)
+	}
+	return plugins, nil
+}
+
+func stopPlugins(plugins []*githubhookplugin.Client) {
+	for _, c := range plugins {
+		c.Close()
+	}
+}
+
+// runCheckTargets runs the contract test of the forward targets, and logs the results.
+func runCheckTargets(ctx context.Context, cfg *config, logger *slog.Logger) error {
+	f := &forward.Forwarder{
+		Destinations: make([]forward.Destination, len(cfg.Forward)),
+	}
+	for i, fc := range cfg.Forward {
+		f.Destinations[i] = newDestination(cfg, fc)
+	}
+	results, err := f.Check(ctx)
+	for _, res := range results {
+		attrs := []any{
+			"url", res.URL,
+			"status_code", res.StatusCode,
+			"latency", res.Latency,
+		}
+		if res.Err != nil {
+			logger.ErrorContext(ctx, "forward target check failed", append(attrs, "error", res.Err)...)
+			continue
+		}
+		logger.InfoContext(ctx, "forward target check passed", attrs...)
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // The error is already wrapped.
+	}
+	return nil
+}
+
+// runSelfTest creates the handler from the configuration, and sends a synthetic delivery through it.
+func runSelfTest(ctx context.Context, cfg *config, logger *slog.Logger, plugins []*githubhookplugin.Client) error {
+	h, err := newHandler(cfg, logger, nil, plugins, nil)
+	if err != nil {
+		return fmt.Errorf("handler: %w", err)
+	}
+	err = startHandler(ctx, h, logger)
+	if err != nil {
+		return err
+	}
+	err = h.SelfTest(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck // The error is already wrapped.
+	}
+	logger.InfoContext(ctx, "self-test passed")
+	return nil
+}
+
+// runFromFile creates the handler from the configuration, and processes the records of a file (or stdin if the name is "-").
+func runFromFile(ctx context.Context, cfg *config, logger *slog.Logger, plugins []*githubhookplugin.Client, name string, stdin io.Reader) error {
+	h, err := newHandler(cfg, logger, nil, plugins, nil)
+	if err != nil {
+		return fmt.Errorf("handler: %w", err)
+	}
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("from file: %w", err)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		r = f
+	}
+	err = h.ProcessRecords(ctx, r)
+	if err != nil {
+		return fmt.Errorf("from file: %w", err)
+	}
+	logger.InfoContext(ctx, "deliveries processed", "file", name)
+	return nil
+}
+
+// serve serves the webhook (and the admin endpoints) until the context is canceled, then shuts down the servers gracefully.
+//
+// If adminLn is nil, the admin endpoints are served by the webhook server.
+// The handler is replaced when a configuration is received from reloads.
+// The plugins are the started plugins of the configuration, and sm is the SQLite mode (optional).
+// ready is called once the sink is started and the servers are serving (optional).
+func serve(ctx context.Context, cfg *config, logger *slog.Logger, ln net.Listener, adminLn net.Listener, reloads <-chan *config, plugins []*githubhookplugin.Client, sm *sqliteMode, ready func()) error {
+	reg := prometheus.NewRegistry()
+	observer := metrics.New()
+	reg.MustRegister(
+		observer,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	h, err := newHandler(cfg, logger, observer, plugins, sm)
+	if err != nil {
+		return fmt.Errorf("handler: %w", err)
+	}
+	err = startHandler(ctx, h, logger)
+	if err != nil {
+		return err
+	}
+	if sm != nil {
+		sm.start(ctx, h, logger)
+	}
+	mux := http.NewServeMux()
+	rh := githubhook.NewReloadableHandler(h)
+	mux.Handle(cfg.Path, rh)
+	adminMux := mux
+	if adminLn != nil {
+		adminMux = http.NewServeMux()
+	}
+	adminMux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	adminMux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	if sm != nil {
+		if adminLn != nil {
+			adminMux.Handle("/admin/", http.StripPrefix("/admin", sm.adminHandler(rh, logger)))
+		} else {
+			logger.WarnContext(ctx, "the SQLite admin UI is not served, because admin_listen is not set")
+		}
+	}
+	listeners := map[net.Listener]*http.Server{
+		ln: newServer(cfg, mux, logger),
+	}
+	if adminLn != nil {
+		listeners[adminLn] = newServer(cfg, adminMux, logger)
+	}
+	errCh := make(chan error, len(listeners))
+	for l, srv := range listeners {
+		logger.InfoContext(ctx, "listening", "address", l.Addr().String())
+		go func() {
+			errCh <- srv.Serve(l)
+		}()
+	}
+	if ready != nil {
+		ready()
+	}
+	appliedCfg := cfg
+	err = waitServe(ctx, errCh, func(newCfg *config) {
+		appliedCfg = reloadConfig(ctx, rh, appliedCfg, newCfg, logger, observer, plugins, sm)
+	}, reloads)
+	logger.InfoContext(ctx, "shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Timeouts.Shutdown.orDefault(30*time.Second))
+	defer cancel()
+	errs := []error{err}
+	for _, srv := range listeners {
+		shutdownErr := srv.Shutdown(shutdownCtx)
+		if shutdownErr != nil {
+			errs = append(errs, fmt.Errorf("shutdown: %w", shutdownErr))
+		}
+	}
+	closeErr := rh.Handler().Close(shutdownCtx)
+	if closeErr != nil {
+		errs = append(errs, fmt.Errorf("handler: %w", closeErr))
+	}
+	if sm != nil {
+		errs = append(errs, sm.shutdown(shutdownCtx))
+	}
+	return errors.Join(errs...)
+}
+
+// waitServe waits until the context is canceled or a server fails, and reloads the configuration in the meantime.
+func waitServe(ctx context.Context, errCh <-chan error, reload func(*config), reloads <-chan *config) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return fmt.Errorf("serve: %w", err)
+		case newCfg := <-reloads:
+			reload(newCfg)
+		}
+	}
+}
+
+func newServer(cfg *config, h http.Handler, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader.orDefault(5 * time.Second),
+		ReadTimeout:       cfg.Timeouts.Read.orDefault(10 * time.Second),
+		WriteTimeout:      cfg.Timeouts.Write.orDefault(30 * time.Second),
+		IdleTimeout:       cfg.Timeouts.Idle.orDefault(60 * time.Second),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+}
+
+// startHandler starts the sink of the handler (see [githubhook.Handler.Start]), so the connections to the forward targets are established before the deliveries are received.
+func startHandler(ctx context.Context, h *githubhook.Handler, logger *slog.Logger) error {
+	start := time.Now()
+	err := h.Start(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck // The error is already wrapped.
+	}
+	logger.InfoContext(ctx, "sink started", "duration", time.Since(start))
+	return nil
+}
+
+// newHandler creates the webhook handler from the configuration, with the started plugins, and the SQLite mode (optional).
+func newHandler(cfg *config, logger *slog.Logger, observer githubhook.Observer, plugins []*githubhookplugin.Client, sm *sqliteMode) (*githubhook.Handler, error) {
+	sink, err := newSink(cfg, logger, plugins)
+	if err != nil {
+		return nil, err
+	}
+	opts := []githubhook.Option{
+		githubhook.WithSink(sink),
+		githubhook.WithAckPing(),
+		githubhook.WithSecurityHeaders(),
+		githubhook.WithObserver(observer),
+		githubhook.WithError(func(ctx context.Context, err error, req *http.Request) {
+			attrs := []any{"error", err}
+			if req != nil {
+				attrs = append(attrs,
+					"remote_address", req.RemoteAddr,
+					"event", req.Header.Get("X-GitHub-Event"),
+					"delivery_id", req.Header.Get("X-GitHub-Delivery"),
+				)
+			}
+			logger.ErrorContext(ctx, "webhook error", attrs...)
+		}),
+	}
+	if cfg.InternalSignature != nil {
+		opts = append(opts, githubhook.WithSignatureScheme(cfg.InternalSignature.signatureScheme()))
+	} else {
+		opts = append(opts, githubhook.WithSecret(cfg.Secrets[0]), githubhook.WithSecrets(cfg.Secrets[1:]...))
+	}
+	if cfg.MaxBodySize != 0 {
+		opts = append(opts, githubhook.WithMaxBodySize(cfg.MaxBodySize))
+	}
+	if sm != nil {
+		opts = append(opts, sm.option())
+	}
+	h, err := githubhook.NewHandler(opts...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return h, nil
+}
+
+// newDestination returns the destination of a forward target.
+//
+// The target is signed with the main secret if it doesn't have a secret.
+func newDestination(cfg *config, f forwardConfig) forward.Destination {
+	secret := f.Secret
+	if secret == "" {
+		secret = cfg.Secrets[0]
+	}
+	return forward.Destination{
+		URL:        f.URL,
+		Timeout:    time.Duration(f.Timeout),
+		Retries:    f.Retries,
+		RetryDelay: time.Duration(f.RetryDelay),
+		Secret:     secret,
+		MaxLatency: time.Duration(f.MaxLatency),
+	}
+}
+
+// newSink creates the sink of the forward targets and the sink plugins, filtered by the accepted events and the filter plugins, and logs the deliveries.
+//
+// The deliveries are always signed: the targets without secret receive the deliveries signed with the main secret.
+// The payload transformation of a target is applied before the signature.
+func newSink(cfg *config, logger *slog.Logger, plugins []*githubhookplugin.Client) (githubhook.Sink, error) {
+	sinks := make([]githubhook.Sink, 0, len(cfg.Forward)+len(plugins))
+	for i, f := range cfg.Forward {
+		var s githubhook.Sink = &forward.Forwarder{
+			Destinations: []forward.Destination{newDestination(cfg, f)},
+		}
+		transform, err := f.transform()
+		if err != nil {
+			return nil, fmt.Errorf("forward %d: %w", i, err)
+		}
+		if transform != nil {
+			s = githubhook.Transformed(s, transform)
+		}
+		sinks = append(sinks, filterEvents(s, f.Events))
+	}
+	for i, c := range plugins {
+		if c.IsSink() {
+			sinks = append(sinks, filterEvents(c, cfg.Plugins[i].Events))
+		}
+	}
+	sink := githubhook.Fanout(sinks...)
+	for i := len(plugins) - 1; i >= 0; i-- {
+		if c := plugins[i]; c.IsFilter() {
+			sink = filterPlugin(c, cfg.Plugins[i].Events, sink)
+		}
+	}
+	return &startSink{
+		Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			attrs := []any{
+				"event", d.Event,
+				"delivery_id", d.DeliveryID,
+			}
+			if len(cfg.Events) > 0 && !githubhook.EventIs(cfg.Events...)(ctx, &d.DeliveryMetadata, d.Payload) {
+				logger.DebugContext(ctx, "delivery ignored", attrs...)
+				return nil
+			}
+			start := time.Now()
+			err := sink.Send(ctx, d)
+			attrs = append(attrs, "duration", time.Since(start))
+			if err != nil {
+				// The error is logged by the handler.
+				logger.WarnContext(ctx, "delivery failed", attrs...)
+				return err
+			}
+			logger.InfoContext(ctx, "delivery", attrs...)
+			return nil
+		}),
+		start: sink,
+	}, nil
+}
+
+// filterEvents returns a sink that sends the deliveries of the events to the sink, or all deliveries if events is empty.
+func filterEvents(s githubhook.Sink, events []string) githubhook.Sink {
+	if len(events) == 0 {
+		return s
+	}
+	return githubhook.Filtered(s, githubhook.EventIs(events...))
+}
+
+// filterPlugin returns a sink that sends the deliveries of the events to the sink if they match the filter plugin.
+// The deliveries of the other events are sent without filtering.
+func filterPlugin(c *githubhookplugin.Client, events []string, s githubhook.Sink) githubhook.Sink {
+	filtered := c.Filtered(s)
+	if len(events) == 0 {
+		return filtered
+	}
+	match := githubhook.EventIs(events...)
+	return &startSink{
+		Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			if match(ctx, &d.DeliveryMetadata, d.Payload) {
+				return filtered.Send(ctx, d)
+			}
+			return s.Send(ctx, d)
+		}),
+		start: filtered, // It starts and closes s.
+	}
+}
+
+// startSink is a [githubhook.Sink] that starts and closes another sink, e.g. the sink that it wraps.
+type startSink struct {
+	githubhook.Sink
+	start githubhook.Sink
+}
+
+func (s *startSink) Start(ctx context.Context) error {
+	return githubhook.StartSink(ctx, s.start) //nolint:wrapcheck // The error is wrapped by the sink.
+}
+
+func (s *startSink) Close(ctx context.Context) error {
+	return githubhook.CloseSink(ctx, s.start) //nolint:wrapcheck // The error is wrapped by the sink.
+}
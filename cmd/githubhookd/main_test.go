@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/forward"
+	"github.com/pierrre/githubhook/githubhookplugin"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main"}`)
+
+// testPluginEnv is the environment variable that runs the test binary as a plugin, see TestMain.
+const testPluginEnv = "GITHUBHOOKD_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		serveTestPlugin(os.Args[1:])
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// serveTestPlugin serves a plugin that filters out the "issues" events, and forwards the deliveries to the URL of the first argument, signed with "secret".
+func serveTestPlugin(args []string) {
+	githubhookplugin.Serve(&githubhookplugin.Plugin{
+		Sink: &forward.Forwarder{
+			Destinations: []forward.Destination{{
+				URL:    args[0],
+				Secret: "secret",
+			}},
+		},
+		Filter: func(ctx context.Context, d *githubhook.VerifiedDelivery) (bool, error) {
+			return d.Event != "issues", nil
+		},
+	})
+}
+
+type testTarget struct {
+	*httptest.Server
+	mu       sync.Mutex
+	events   []string
+	payloads []string
+}
+
+// newTestTarget creates a target that checks the signature of every forwarded delivery with the secret.
+// The HEAD requests of the sink start are ignored.
+func newTestTarget(t *testing.T, secret string) *testTarget {
+	t.Helper()
+	tt := new(testTarget)
+	tt.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			return
+		}
+		event := req.Header.Get("X-GitHub-Event")
+		rawPayload, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.NotZero(t, req.Header.Get("X-Hub-Signature-256"))
+		err = (&githubhook.Handler{Secret: secret}).VerifySignature(req.Context(), event, rawPayload, req.Header.Get)
+		assert.NoError(t, err)
+		tt.mu.Lock()
+		defer tt.mu.Unlock()
+		tt.events = append(tt.events, event)
+		tt.payloads = append(tt.payloads, string(rawPayload))
+	}))
+	t.Cleanup(tt.Close)
+	return tt
+}
+
+func (tt *testTarget) getEvents() []string {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.events
+}
+
+func (tt *testTarget) getPayloads() []string {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.payloads
+}
+
+func testListen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	return ln
+}
+
+func testSend(ctx context.Context, t *testing.T, url string, secret string, event string) *http.Response {
+	t.Helper()
+	req, err := (&githubhook.Signer{Secret: secret}).NewRequest(ctx, url, event, "test", testRawPayload)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp
+}
+
+func TestServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	all := newTestTarget(t, "secret")
+	push := newTestTarget(t, "push")
+	slim := newTestTarget(t, "secret")
+	cfg := &config{
+		Path:    "/webhook",
+		Secrets: []string{"secret", "old"},
+		Events:  []string{"push", "issues"},
+		Forward: []forwardConfig{
+			{URL: all.URL},
+			{URL: push.URL, Secret: "push", Events: []string{"push"}},
+			{URL: slim.URL, Events: []string{"push"}, Template: `{"event": {{json .Event}}, "ref": {{json .Payload.ref}}}`},
+		},
+	}
+	ln := testListen(t)
+	adminLn := testListen(t)
+	done := make(chan error)
+	go func() {
+		done <- serve(ctx, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), ln, adminLn, nil, nil, nil, nil)
+	}()
+	url := "http://" + ln.Addr().String() + "/webhook"
+	resp := testSend(ctx, t, url, "secret", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	resp = testSend(ctx, t, url, "old", "issues")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	resp = testSend(ctx, t, url, "secret", "release")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	resp = testSend(ctx, t, url, "invalid", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	assert.SliceEqual(t, all.getEvents(), []string{"push", "issues"})
+	assert.SliceEqual(t, push.getEvents(), []string{"push"})
+	assert.SliceEqual(t, slim.getPayloads(), []string{`{"event": "push", "ref": "refs/heads/main"}`})
+	resp, err := http.Get("http://" + adminLn.Addr().String() + "/metrics") //nolint:noctx // Test.
+	assert.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.NoError(t, err)
+	assert.StringContains(t, string(b), "githubhook_requests_total")
+	resp, err = http.Get("http://" + adminLn.Addr().String() + "/healthz") //nolint:noctx // Test.
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	cancel()
+	err = <-done
+	assert.NoError(t, err)
+}
+
+func TestServeForwardError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer target.Close()
+	cfg := &config{
+		Path:    "/webhook",
+		Secrets: []string{"secret"},
+		Forward: []forwardConfig{{URL: target.URL}},
+	}
+	ln := testListen(t)
+	logs := new(strings.Builder)
+	var logsMu sync.Mutex
+	logger := slog.New(slog.NewTextHandler(&lockedWriter{w: logs, mu: &logsMu}, nil))
+	done := make(chan error)
+	go func() {
+		done <- serve(ctx, cfg, logger, ln, nil, nil, nil, nil, nil)
+	}()
+	resp := testSend(ctx, t, "http://"+ln.Addr().String()+"/webhook", "secret", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+	resp, err := http.Get("http://" + ln.Addr().String() + "/healthz") //nolint:noctx // Test.
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	cancel()
+	err = <-done
+	assert.NoError(t, err)
+	logsMu.Lock()
+	defer logsMu.Unlock()
+	assert.StringContains(t, logs.String(), "webhook error")
+}
+
+func TestNewHandlerInternalSignature(t *testing.T) {
+	ctx := context.Background()
+	target := newTestTarget(t, "secret")
+	cfg := &config{
+		Secrets: []string{"secret"},
+		InternalSignature: &internalSignatureConfig{
+			Header:  "X-Internal-Signature",
+			Prefix:  "sha256=",
+			Secrets: []string{"internal"},
+		},
+		Forward: []forwardConfig{{URL: target.URL}},
+	}
+	h, err := newHandler(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil)
+	assert.NoError(t, err)
+	s := &githubhook.Signer{
+		Secret: "internal",
+		Scheme: cfg.InternalSignature.signatureScheme(),
+	}
+	req, err := s.NewRequest(ctx, "/", "push", "1", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	req, err = (&githubhook.Signer{Secret: "secret"}).NewRequest(ctx, "/", "push", "2", testRawPayload)
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.SliceEqual(t, target.getEvents(), []string{"push"})
+}
+
+func TestServeStartError(t *testing.T) {
+	target := httptest.NewServer(http.NotFoundHandler())
+	target.Close()
+	cfg := &config{
+		Path:    "/webhook",
+		Secrets: []string{"secret"},
+		Forward: []forwardConfig{{URL: target.URL}},
+	}
+	err := serve(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), testListen(t), nil, nil, nil, nil, nil)
+	assert.ErrorContains(t, err, "start sink")
+}
+
+type lockedWriter struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p) //nolint:wrapcheck // Test.
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	name := filepath.Join(t.TempDir(), "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{"listen": "127.0.0.1:0", "admin_listen": "127.0.0.1:0", "secrets": ["secret"]}`), 0o600)
+	assert.NoError(t, err)
+	cancel()
+	err = run(ctx, []string{"-config", name}, nil, io.Discard)
+	assert.NoError(t, err)
+}
+
+func TestRunSelfTest(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{"listen": "invalid", "secrets": ["secret"]}`), 0o600)
+	assert.NoError(t, err)
+	var stderr bytes.Buffer
+	err = run(context.Background(), []string{"-config", name, "-self-test"}, nil, &stderr)
+	assert.NoError(t, err)
+	assert.StringContains(t, stderr.String(), "self-test passed")
+}
+
+func TestRunCheckTargets(t *testing.T) {
+	target := newTestTarget(t, "secret")
+	name := filepath.Join(t.TempDir(), "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{"listen": "invalid", "secrets": ["secret"], "forward": [{"url": "`+target.URL+`", "max_latency": "1m"}]}`), 0o600)
+	assert.NoError(t, err)
+	var stderr bytes.Buffer
+	err = run(context.Background(), []string{"-config", name, "-check-targets", "-self-test"}, nil, &stderr)
+	assert.NoError(t, err)
+	assert.StringContains(t, stderr.String(), "forward target check passed")
+	assert.SliceEqual(t, target.getEvents(), []string{"ping"})
+}
+
+func TestRunFromFile(t *testing.T) {
+	target := newTestTarget(t, "secret")
+	dir := t.TempDir()
+	name := filepath.Join(dir, "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{"listen": "invalid", "secrets": ["secret"], "forward": [{"url": "`+target.URL+`"}]}`), 0o600)
+	assert.NoError(t, err)
+	records := `{"event": "push", "delivery_id": "1", "payload": {}}
+{"event": "issues", "delivery_id": "2", "payload": {}}
+`
+	recordsName := filepath.Join(dir, "records.jsonl")
+	err = os.WriteFile(recordsName, []byte(records), 0o600)
+	assert.NoError(t, err)
+	err = run(context.Background(), []string{"-config", name, "-from-file", recordsName}, nil, io.Discard)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, target.getEvents(), []string{"push", "issues"})
+	err = run(context.Background(), []string{"-config", name, "-from-file", "-"}, strings.NewReader(records), io.Discard)
+	assert.NoError(t, err)
+	assert.SliceLen(t, target.getEvents(), 4)
+}
+
+func TestRunPlugins(t *testing.T) {
+	t.Setenv(testPluginEnv, "1")
+	target := newTestTarget(t, "secret")
+	pluginTarget := newTestTarget(t, "secret")
+	dir := t.TempDir()
+	name := filepath.Join(dir, "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{
+	"listen": "invalid",
+	"secrets": ["secret"],
+	"forward": [{"url": "`+target.URL+`"}],
+	"plugins": [{"path": "`+os.Args[0]+`", "args": ["`+pluginTarget.URL+`"], "events": ["push", "issues"]}]
+}`), 0o600)
+	assert.NoError(t, err)
+	records := `{"event": "push", "delivery_id": "1", "payload": {}}
+{"event": "issues", "delivery_id": "2", "payload": {}}
+{"event": "release", "delivery_id": "3", "payload": {}}
+`
+	err = run(context.Background(), []string{"-config", name, "-from-file", "-"}, strings.NewReader(records), io.Discard)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, target.getEvents(), []string{"push", "release"})
+	assert.SliceEqual(t, pluginTarget.getEvents(), []string{"push"})
+}
+
+func TestRunError(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		args   []string
+	}{
+		{
+			name: "Flags",
+			args: []string{"-invalid"},
+		},
+		{
+			name:   "Config",
+			config: `{}`,
+		},
+		{
+			name:   "Listen",
+			config: `{"listen": "invalid", "secrets": ["secret"]}`,
+		},
+		{
+			name:   "SelfTest",
+			config: `{"secrets": ["secret"], "max_body_size": 1}`,
+			args:   []string{"-self-test"},
+		},
+		{
+			name:   "CheckTargets",
+			config: `{"listen": "127.0.0.1:0", "secrets": ["secret"], "forward": [{"url": "http://127.0.0.1:1"}]}`,
+			args:   []string{"-check-targets"},
+		},
+		{
+			name:   "FromFileNotFound",
+			config: `{"secrets": ["secret"]}`,
+			args:   []string{"-from-file", "not_found.jsonl"},
+		},
+		{
+			name:   "FromFileInvalid",
+			config: `{"secrets": ["secret"]}`,
+			args:   []string{"-from-file", "-"},
+		},
+		{
+			name:   "Plugin",
+			config: `{"secrets": ["secret"], "plugins": [{"path": "/nonexistent/plugin"}]}`,
+		},
+		{
+			name:   "AdminListen",
+			config: `{"listen": "127.0.0.1:0", "admin_listen": "invalid", "secrets": ["secret"]}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			args := tc.args
+			if tc.config != "" {
+				name := filepath.Join(t.TempDir(), "githubhookd.json")
+				err := os.WriteFile(name, []byte(tc.config), 0o600)
+				assert.NoError(t, err)
+				args = append([]string{"-config", name}, args...)
+			}
+			err := run(context.Background(), args, strings.NewReader("invalid"), io.Discard)
+			assert.Error(t, err)
+		})
+	}
+}
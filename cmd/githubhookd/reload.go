@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/githubhookplugin"
+)
+
+// watchConfig reloads the configuration file when a signal is received (SIGHUP), and when the file changes if watch is true.
+//
+// It returns the channel of the reloaded configurations.
+// Invalid configurations are logged and skipped.
+// It stops when the context is canceled.
+func watchConfig(ctx context.Context, name string, signals <-chan os.Signal, watch bool, logger *slog.Logger) (<-chan *config, error) {
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	var watcher *fsnotify.Watcher
+	if watch {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			return nil, fmt.Errorf("watch: %w", err)
+		}
+		// The directory is watched, because editors and deployment tools often replace the file instead of writing it.
+		err = watcher.Add(filepath.Dir(name))
+		if err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("watch: %w", err)
+		}
+		fileEvents = watcher.Events
+		fileErrors = watcher.Errors
+	}
+	reloads := make(chan *config)
+	go func() {
+		if watcher != nil {
+			defer func() {
+				_ = watcher.Close()
+			}()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				logger.InfoContext(ctx, "reloading configuration", "signal", sig.String())
+			case ev := <-fileEvents:
+				if filepath.Clean(ev.Name) != filepath.Clean(name) || !ev.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				logger.InfoContext(ctx, "reloading configuration", "file_event", ev.Op.String())
+			case err := <-fileErrors:
+				logger.ErrorContext(ctx, "watch configuration", "error", err)
+				continue
+			}
+			cfg, err := loadConfig(name)
+			if err != nil {
+				logger.ErrorContext(ctx, "reload configuration", "error", err)
+				continue
+			}
+			select {
+			case reloads <- cfg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return reloads, nil
+}
+
+// reloadConfig replaces the handler with a new one created from the new configuration.
+//
+// Only the handler configuration (secrets, events, forward targets and max body size) is reloaded.
+// The other fields require a restart.
+// cfg is the last applied configuration, plugins are its started plugins, and sm is the SQLite mode (optional).
+// The previous handler is closed in the background, when its requests in progress are done.
+// It returns the new applied configuration: the reloaded fields of newCfg and the other fields of cfg, or cfg if the reload fails or if nothing changed.
+func reloadConfig(ctx context.Context, rh *githubhook.ReloadableHandler, cfg *config, newCfg *config, logger *slog.Logger, observer githubhook.Observer, plugins []*githubhookplugin.Client, sm *sqliteMode) *config {
+	if newCfg.Listen != cfg.Listen || newCfg.AdminListen != cfg.AdminListen || newCfg.Path != cfg.Path || newCfg.Timeouts != cfg.Timeouts || newCfg.LogLevel != cfg.LogLevel || !reflect.DeepEqual(newCfg.Plugins, cfg.Plugins) {
+		logger.WarnContext(ctx, "the listen addresses, path, timeouts, log level and plugins are not reloaded, restart to apply them")
+	}
+	applied := *newCfg
+	applied.Listen = cfg.Listen
+	applied.AdminListen = cfg.AdminListen
+	applied.Path = cfg.Path
+	applied.Timeouts = cfg.Timeouts
+	applied.LogLevel = cfg.LogLevel
+	applied.Plugins = cfg.Plugins
+	if reflect.DeepEqual(&applied, cfg) {
+		logger.InfoContext(ctx, "configuration unchanged")
+		return cfg
+	}
+	h, err := newHandler(&applied, logger, observer, plugins, sm)
+	if err != nil {
+		logger.ErrorContext(ctx, "reload configuration", "error", err)
+		return cfg
+	}
+	err = startHandler(ctx, h, logger)
+	if err != nil {
+		logger.ErrorContext(ctx, "reload configuration", "error", err)
+		return cfg
+	}
+	old := rh.Reload(h)
+	logger.InfoContext(ctx, "configuration reloaded")
+	go closeHandler(ctx, old, applied.Timeouts.Shutdown.orDefault(30*time.Second), logger)
+	return &applied
+}
+
+// closeHandler closes the handler replaced by a reload (see [githubhook.Handler.Close]), after its requests in progress, with a timeout.
+//
+// The shared components (e.g. the SQLite mode) are not closed.
+func closeHandler(ctx context.Context, h *githubhook.Handler, timeout time.Duration, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	err := h.Close(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "close previous handler", "error", err)
+		return
+	}
+	logger.DebugContext(ctx, "previous handler closed")
+}
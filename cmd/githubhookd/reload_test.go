@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestServeReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &config{
+		Path:    "/webhook",
+		Secrets: []string{"secret1"},
+	}
+	ln := testListen(t)
+	reloads := make(chan *config)
+	done := make(chan error)
+	go func() {
+		done <- serve(ctx, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), ln, nil, reloads, nil, nil, nil)
+	}()
+	url := "http://" + ln.Addr().String() + "/webhook"
+	resp := testSend(ctx, t, url, "secret1", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	reloads <- &config{
+		Listen:  ":1234",
+		Path:    "/webhook",
+		Secrets: []string{"secret2"},
+	}
+	resp = testSend(ctx, t, url, "secret1", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	resp = testSend(ctx, t, url, "secret2", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	cancel()
+	err := <-done
+	assert.NoError(t, err)
+}
+
+func TestReloadConfig(t *testing.T) {
+	ctx := context.Background()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	h, err := newHandler(&config{Secrets: []string{"secret1"}}, logger, nil, nil, nil)
+	assert.NoError(t, err)
+	rh := githubhook.NewReloadableHandler(h)
+	cfg := &config{
+		Listen:  ":8080",
+		Path:    "/webhook",
+		Secrets: []string{"secret1"},
+	}
+	newCfg := &config{
+		Listen:  ":1234",
+		Path:    "/webhook",
+		Secrets: []string{"secret2"},
+	}
+	applied := reloadConfig(ctx, rh, cfg, newCfg, logger, nil, nil, nil)
+	assert.StringContains(t, logs.String(), "not reloaded")
+	assert.StringContains(t, logs.String(), "configuration reloaded")
+	assert.Equal(t, applied.Listen, ":8080")
+	assert.SliceEqual(t, applied.Secrets, []string{"secret2"})
+	logs.Reset()
+	applied = reloadConfig(ctx, rh, applied, newCfg, logger, nil, nil, nil)
+	assert.StringContains(t, logs.String(), "configuration unchanged")
+	logs.Reset()
+	invalidCfg := &config{
+		Listen:  ":8080",
+		Path:    "/webhook",
+		Secrets: []string{""},
+	}
+	previous := applied
+	applied = reloadConfig(ctx, rh, applied, invalidCfg, logger, nil, nil, nil)
+	assert.StringContains(t, logs.String(), "reload configuration")
+	assert.Equal(t, applied, previous)
+	logs.Reset()
+	target := httptest.NewServer(http.NotFoundHandler())
+	target.Close()
+	unreachableCfg := &config{
+		Listen:  ":8080",
+		Path:    "/webhook",
+		Secrets: []string{"secret2"},
+		Forward: []forwardConfig{{URL: target.URL}},
+	}
+	applied = reloadConfig(ctx, rh, applied, unreachableCfg, logger, nil, nil, nil)
+	assert.StringContains(t, logs.String(), "start sink")
+	assert.Equal(t, applied, previous)
+}
+
+type testCloseSink struct {
+	githubhook.Sink
+	closed chan struct{}
+	err    error
+}
+
+func (s *testCloseSink) Close(ctx context.Context) error {
+	close(s.closed)
+	return s.err
+}
+
+func TestCloseHandler(t *testing.T) {
+	ctx := context.Background()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s := &testCloseSink{Sink: githubhook.SinkFunc(nil), closed: make(chan struct{})}
+	closeHandler(ctx, &githubhook.Handler{Sink: s}, time.Second, logger)
+	<-s.closed
+	assert.StringContains(t, logs.String(), "previous handler closed")
+	logs.Reset()
+	s = &testCloseSink{Sink: githubhook.SinkFunc(nil), closed: make(chan struct{}), err: errors.New("error")}
+	closeHandler(ctx, &githubhook.Handler{Sink: s}, time.Second, logger)
+	assert.StringContains(t, logs.String(), "close previous handler")
+	assert.StringContains(t, logs.String(), "close sink: error")
+}
+
+func TestStartSinkClose(t *testing.T) {
+	s := &testCloseSink{Sink: githubhook.SinkFunc(nil), closed: make(chan struct{})}
+	err := githubhook.CloseSink(context.Background(), &startSink{Sink: githubhook.SinkFunc(nil), start: s})
+	assert.NoError(t, err)
+	<-s.closed
+}
+
+func TestWatchConfig(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	name := filepath.Join(t.TempDir(), "githubhookd.json")
+	writeConfig := func(secret string) {
+		t.Helper()
+		err := os.WriteFile(name, []byte(`{"secrets": ["`+secret+`"]}`), 0o600)
+		assert.NoError(t, err)
+	}
+	writeConfig("secret1")
+	signals := make(chan os.Signal)
+	reloads, err := watchConfig(ctx, name, signals, true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+	signals <- syscall.SIGHUP
+	cfg := testReceiveConfig(t, reloads)
+	assert.SliceEqual(t, cfg.Secrets, []string{"secret1"})
+	writeConfig("secret2")
+	cfg = testReceiveConfig(t, reloads)
+	assert.SliceEqual(t, cfg.Secrets, []string{"secret2"})
+}
+
+func TestWatchConfigInvalid(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	name := filepath.Join(t.TempDir(), "githubhookd.json")
+	err := os.WriteFile(name, []byte(`{}`), 0o600)
+	assert.NoError(t, err)
+	signals := make(chan os.Signal)
+	reloads, err := watchConfig(ctx, name, signals, false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+	signals <- syscall.SIGHUP
+	select {
+	case <-reloads:
+		t.Fatal("unexpected reload")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchConfigError(t *testing.T) {
+	_, err := watchConfig(context.Background(), filepath.Join(t.TempDir(), "missing", "githubhookd.json"), nil, true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Error(t, err)
+}
+
+func testReceiveConfig(t *testing.T, reloads <-chan *config) *config {
+	t.Helper()
+	select {
+	case cfg := <-reloads:
+		return cfg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+		return nil
+	}
+}
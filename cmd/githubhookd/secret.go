@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+)
+
+/*
+secretResolver resolves the secret references of the configuration, so the configuration file can be committed without leaking the secrets.
+
+A secret is either:
+  - "env:NAME": the value of the environment variable. It's an error if the variable is unset or empty.
+  - "file:PATH": the content of the file, without the trailing new lines (e.g. a Kubernetes or systemd credential). The environment variables of the path are expanded.
+  - "age:BASE64": an [age] encrypted blob, encoded in base64 (e.g. "age -r RECIPIENT | base64 -w0"). It's decrypted with the identities of the "age_identity_file" field.
+  - "vault:MOUNT/PATH#KEY": the key of a secret of a [Vault] KV version 2 secrets engine, e.g. "vault:secret/githubhookd#webhook_secret". The server is configured by the "vault" field.
+  - a literal value, that is used as is (e.g. it can contain "$").
+
+The sops files are not supported: the secrets can be encrypted with age instead.
+
+[age]: https://age-encryption.org
+[Vault]: https://developer.hashicorp.com/vault/docs/secrets/kv/kv-v2
+*/
+type secretResolver struct {
+	sources map[string]secretSource
+}
+
+// secretSource resolves the secret references of a prefix (e.g. "vault:").
+type secretSource interface {
+	resolveSecret(ref string) (string, error)
+}
+
+func newSecretResolver(cfg *config) *secretResolver {
+	return &secretResolver{
+		sources: map[string]secretSource{
+			"env":  envSecretSource{},
+			"file": fileSecretSource{},
+			"age": &ageSecretSource{
+				identityFile: os.ExpandEnv(cfg.AgeIdentityFile),
+			},
+			"vault": &vaultSecretSource{
+				config: cfg.Vault,
+			},
+		},
+	}
+}
+
+func (r *secretResolver) resolve(s string) (string, error) {
+	prefix, ref, ok := strings.Cut(s, ":")
+	if !ok {
+		return s, nil
+	}
+	src, ok := r.sources[prefix]
+	if !ok {
+		return s, nil
+	}
+	v, err := src.resolveSecret(ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", prefix, err)
+	}
+	return v, nil
+}
+
+// envSecretSource resolves the "env:" references.
+type envSecretSource struct{}
+
+func (envSecretSource) resolveSecret(name string) (string, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return "", fmt.Errorf("variable %q is unset or empty", name)
+	}
+	return v, nil
+}
+
+// fileSecretSource resolves the "file:" references.
+type fileSecretSource struct{}
+
+func (fileSecretSource) resolveSecret(name string) (string, error) {
+	b, err := os.ReadFile(os.ExpandEnv(name))
+	if err != nil {
+		return "", err //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// ageSecretSource resolves the "age:" references.
+type ageSecretSource struct {
+	identityFile string
+	identities   []age.Identity
+}
+
+func (s *ageSecretSource) resolveSecret(ref string) (string, error) {
+	identities, err := s.getIdentities()
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ref))
+	if err != nil {
+		return "", fmt.Errorf("base64 decode: %w", err)
+	}
+	dr, err := age.Decrypt(bytes.NewReader(b), identities...)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	b, err = io.ReadAll(dr)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// getIdentities returns the identities of the identity file, that is read once.
+func (s *ageSecretSource) getIdentities() ([]age.Identity, error) {
+	if s.identities != nil {
+		return s.identities, nil
+	}
+	if s.identityFile == "" {
+		return nil, errors.New("missing identity file")
+	}
+	f, err := os.Open(s.identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("identity file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // Not needed.
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("identity file: %w", err)
+	}
+	s.identities = identities
+	return identities, nil
+}
+
+// vaultConfig is the configuration of the Vault server, that resolves the "vault:" secrets.
+type vaultConfig struct {
+	// Addr is the address of the server (default: the VAULT_ADDR environment variable).
+	Addr string `json:"addr"`
+	// TokenFile is the path of the file containing the token (default: the VAULT_TOKEN environment variable).
+	// The environment variables are expanded.
+	TokenFile string `json:"token_file"`
+	// Timeout is the timeout of each request (default: 10s).
+	Timeout duration `json:"timeout"`
+}
+
+// vaultSecretSource resolves the "vault:" references, with the KV version 2 API.
+type vaultSecretSource struct {
+	config vaultConfig
+	client *http.Client
+}
+
+func (s *vaultSecretSource) resolveSecret(ref string) (string, error) {
+	p, key, ok := strings.Cut(ref, "#")
+	mount, p, ok2 := strings.Cut(p, "/")
+	if !ok || !ok2 || key == "" || mount == "" || p == "" {
+		return "", fmt.Errorf("invalid reference %q: expected MOUNT/PATH#KEY", ref)
+	}
+	addr := s.config.Addr
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if addr == "" {
+		return "", errors.New("missing address")
+	}
+	token, err := s.getToken()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.JoinPath(addr, "v1", mount, "data", p)
+	if err != nil {
+		return "", fmt.Errorf("URL: %w", err)
+	}
+	timeout := s.config.Timeout.orDefault(10 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Not needed.
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: unexpected status %d", p, resp.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return "", fmt.Errorf("JSON decode: %w", err)
+	}
+	v, ok := body.Data.Data[key].(string)
+	if !ok || v == "" {
+		return "", fmt.Errorf("%s: missing key %q", p, key)
+	}
+	return v, nil
+}
+
+func (s *vaultSecretSource) getToken() (string, error) {
+	if s.config.TokenFile == "" {
+		token := os.Getenv("VAULT_TOKEN")
+		if token == "" {
+			return "", errors.New("missing token")
+		}
+		return token, nil
+	}
+	b, err := os.ReadFile(os.ExpandEnv(s.config.TokenFile))
+	if err != nil {
+		return "", fmt.Errorf("token file: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
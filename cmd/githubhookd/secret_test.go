@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/pierrre/assert"
+)
+
+func TestParseConfigSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TEST_SECRET_DIR", dir)
+	err := os.WriteFile(filepath.Join(dir, "secret"), []byte("file-secret\n"), 0o600)
+	assert.NoError(t, err)
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	identityFile := filepath.Join(dir, "age.key")
+	err = os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0o600)
+	assert.NoError(t, err)
+	encrypted := testAgeEncrypt(t, identity.Recipient(), "age-secret")
+	cfg, err := parseConfig(fmt.Appendf(nil, `{
+	"secrets": ["file:${TEST_SECRET_DIR}/secret", %q],
+	"age_identity_file": %q,
+	"forward": [{"url": "http://localhost/webhook", "secret": %q}]
+}`, encrypted, identityFile, encrypted))
+	assert.NoError(t, err)
+	assert.SliceEqual(t, cfg.Secrets, []string{"file-secret", "age-secret"})
+	assert.Equal(t, cfg.Forward[0].Secret, "age-secret")
+}
+
+func TestParseConfigSecretLiteral(t *testing.T) {
+	t.Setenv("TEST_SECRET", "env-secret")
+	cfg, err := parseConfig([]byte(`{
+	"secrets": ["pa$$word${TEST_SECRET}", "env:TEST_SECRET"],
+	"forward": [{"url": "http://localhost/webhook", "secret": "$TEST_SECRET"}]
+}`))
+	assert.NoError(t, err)
+	assert.SliceEqual(t, cfg.Secrets, []string{"pa$$word${TEST_SECRET}", "env-secret"})
+	assert.Equal(t, cfg.Forward[0].Secret, "$TEST_SECRET")
+}
+
+func TestParseConfigSecretReferencesError(t *testing.T) {
+	t.Setenv("TEST_EMPTY_SECRET", "")
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	identityFile := filepath.Join(dir, "age.key")
+	err = os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0o600)
+	assert.NoError(t, err)
+	invalidIdentityFile := filepath.Join(dir, "invalid.key")
+	err = os.WriteFile(invalidIdentityFile, []byte("invalid\n"), 0o600)
+	assert.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	encrypted := testAgeEncrypt(t, other.Recipient(), "age-secret")
+	for _, tc := range []struct {
+		name string
+		json string
+	}{
+		{
+			name: "FileNotFound",
+			json: fmt.Sprintf(`{"secrets": [%q]}`, "file:"+filepath.Join(dir, "missing")),
+		},
+		{
+			name: "AgeMissingIdentityFile",
+			json: fmt.Sprintf(`{"secrets": [%q]}`, encrypted),
+		},
+		{
+			name: "AgeIdentityFileNotFound",
+			json: fmt.Sprintf(`{"secrets": [%q], "age_identity_file": %q}`, encrypted, filepath.Join(dir, "missing.key")),
+		},
+		{
+			name: "AgeInvalidIdentityFile",
+			json: fmt.Sprintf(`{"secrets": [%q], "age_identity_file": %q}`, encrypted, invalidIdentityFile),
+		},
+		{
+			name: "AgeInvalidBase64",
+			json: fmt.Sprintf(`{"secrets": ["age:invalid!"], "age_identity_file": %q}`, identityFile),
+		},
+		{
+			name: "AgeWrongIdentity",
+			json: fmt.Sprintf(`{"secrets": [%q], "age_identity_file": %q}`, encrypted, identityFile),
+		},
+		{
+			name: "EnvUnset",
+			json: `{"secrets": ["env:TEST_UNSET_SECRET"]}`,
+		},
+		{
+			name: "EnvEmpty",
+			json: `{"secrets": ["env:TEST_EMPTY_SECRET"]}`,
+		},
+		{
+			name: "ForwardEnvUnset",
+			json: `{"secrets": ["secret"], "forward": [{"url": "http://localhost/webhook", "secret": "env:TEST_UNSET_SECRET"}]}`,
+		},
+		{
+			name: "Forward",
+			json: fmt.Sprintf(`{"secrets": ["secret"], "forward": [{"url": "http://localhost/webhook", "secret": %q}]}`, "file:"+filepath.Join(dir, "missing")),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseConfig([]byte(tc.json))
+			assert.Error(t, err)
+			assert.ErrorContains(t, err, "secret")
+		})
+	}
+}
+
+func testAgeEncrypt(tb testing.TB, recipient age.Recipient, secret string) string {
+	tb.Helper()
+	b := new(bytes.Buffer)
+	w, err := age.Encrypt(b, recipient)
+	assert.NoError(tb, err)
+	_, err = io.WriteString(w, secret)
+	assert.NoError(tb, err)
+	err = w.Close()
+	assert.NoError(tb, err)
+	return "age:" + base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+func TestParseConfigSecretVault(t *testing.T) {
+	srv := newTestVault(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("token\n"), 0o600)
+	assert.NoError(t, err)
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+	cfg, err := parseConfig([]byte(`{
+	"secrets": ["vault:secret/githubhookd#webhook_secret"],
+	"forward": [{"url": "http://localhost/webhook", "secret": "vault:secret/githubhookd/bot#secret"}]
+}`))
+	assert.NoError(t, err)
+	assert.SliceEqual(t, cfg.Secrets, []string{"vault-secret"})
+	assert.Equal(t, cfg.Forward[0].Secret, "vault-bot-secret")
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	cfg, err = parseConfig(fmt.Appendf(nil, `{
+	"secrets": ["vault:secret/githubhookd#webhook_secret"],
+	"vault": {"addr": %q, "token_file": %q}
+}`, srv.URL, tokenFile))
+	assert.NoError(t, err)
+	assert.SliceEqual(t, cfg.Secrets, []string{"vault-secret"})
+}
+
+func TestParseConfigSecretVaultError(t *testing.T) {
+	srv := newTestVault(t)
+	for _, tc := range []struct {
+		name   string
+		secret string
+		addr   string
+		token  string
+	}{
+		{
+			name:   "InvalidReference",
+			secret: "vault:githubhookd",
+			addr:   srv.URL,
+			token:  "token",
+		},
+		{
+			name:   "MissingAddress",
+			secret: "vault:secret/githubhookd#webhook_secret",
+			token:  "token",
+		},
+		{
+			name:   "MissingToken",
+			secret: "vault:secret/githubhookd#webhook_secret",
+			addr:   srv.URL,
+		},
+		{
+			name:   "Forbidden",
+			secret: "vault:secret/githubhookd#webhook_secret",
+			addr:   srv.URL,
+			token:  "invalid",
+		},
+		{
+			name:   "NotFound",
+			secret: "vault:secret/missing#webhook_secret",
+			addr:   srv.URL,
+			token:  "token",
+		},
+		{
+			name:   "MissingKey",
+			secret: "vault:secret/githubhookd#missing",
+			addr:   srv.URL,
+			token:  "token",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("VAULT_ADDR", tc.addr)
+			t.Setenv("VAULT_TOKEN", tc.token)
+			_, err := parseConfig(fmt.Appendf(nil, `{"secrets": [%q]}`, tc.secret))
+			assert.ErrorContains(t, err, "secret 0: vault:")
+		})
+	}
+}
+
+// newTestVault returns a Vault server with a KV version 2 secrets engine mounted on "secret", that accepts the token "token".
+func newTestVault(tb testing.TB) *httptest.Server {
+	tb.Helper()
+	secrets := map[string]map[string]any{
+		"/v1/secret/data/githubhookd": {
+			"webhook_secret": "vault-secret",
+		},
+		"/v1/secret/data/githubhookd/bot": {
+			"secret": "vault-bot-secret",
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		data, ok := secrets[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data": data,
+			},
+		})
+	}))
+	tb.Cleanup(srv.Close)
+	return srv
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/githubhooksqlite"
+)
+
+// sqliteMode is the all-in-one SQLite mode of the -sqlite flag (see [githubhooksqlite]).
+//
+// The database and the queue are shared by the reloaded handlers.
+type sqliteMode struct {
+	db   *githubhooksqlite.DB
+	pool *githubhook.AsyncPool
+}
+
+// sqliteWorkers and sqliteQueueSize are the number of workers and the queue size of the durable queue.
+const (
+	sqliteWorkers   = 4
+	sqliteQueueSize = 1000
+)
+
+func openSQLiteMode(ctx context.Context, name string, logger *slog.Logger) (*sqliteMode, error) {
+	db, err := githubhooksqlite.Open(ctx, name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // The error is already wrapped.
+	}
+	db.Error = func(ctx context.Context, err error) {
+		logger.ErrorContext(ctx, "SQLite sweep", "error", err)
+	}
+	return &sqliteMode{
+		db:   db,
+		pool: githubhook.NewAsyncPool(sqliteWorkers, sqliteQueueSize),
+	}, nil
+}
+
+// option returns the option that wires the database and the queue onto a handler.
+func (m *sqliteMode) option() githubhook.Option {
+	return m.db.Option(m.pool)
+}
+
+// start recovers the pending deliveries of the previous process with the handler, and starts the sweeps.
+func (m *sqliteMode) start(ctx context.Context, h *githubhook.Handler, logger *slog.Logger) {
+	n, err := m.db.Recover(ctx, h)
+	if err != nil {
+		logger.ErrorContext(ctx, "SQLite recover", "error", err)
+	}
+	if n > 0 {
+		logger.InfoContext(ctx, "SQLite pending deliveries recovered", "count", n)
+	}
+	go m.db.RunSweeps(ctx, time.Hour)
+}
+
+// adminHandler returns the admin UI.
+//
+// Its handler shares the database and the queue, and redelivers with the sink of the current handler, so it's not replaced by the reloads.
+func (m *sqliteMode) adminHandler(rh *githubhook.ReloadableHandler, logger *slog.Logger) http.Handler {
+	h := &githubhook.Handler{
+		Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			return rh.Handler().Sink.Send(ctx, d)
+		}),
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			logger.ErrorContext(ctx, "admin error", "error", err)
+		},
+	}
+	m.option()(h)
+	return githubhooksqlite.NewAdminHandler(h, nil)
+}
+
+// shutdown waits until the queued deliveries are processed, or the context is done.
+//
+// The deliveries that are not processed remain pending, so they are recovered at the next start.
+func (m *sqliteMode) shutdown(ctx context.Context) error {
+	err := m.pool.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("SQLite queue: %w", err)
+	}
+	return nil
+}
+
+// close closes the database.
+func (m *sqliteMode) close() {
+	_ = m.db.Close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestServeSQLite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target := newTestTarget(t, "secret")
+	cfg := &config{
+		Path:    "/webhook",
+		Secrets: []string{"secret"},
+		Forward: []forwardConfig{{URL: target.URL}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sm, err := openSQLiteMode(ctx, filepath.Join(t.TempDir(), "githubhookd.db"), logger)
+	assert.NoError(t, err)
+	defer sm.close()
+	ln := testListen(t)
+	adminLn := testListen(t)
+	done := make(chan error)
+	go func() {
+		done <- serve(ctx, cfg, logger, ln, adminLn, nil, nil, sm, nil)
+	}()
+	resp := testSend(ctx, t, "http://"+ln.Addr().String()+"/webhook", "secret", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusAccepted)
+	resp = testSend(ctx, t, "http://"+ln.Addr().String()+"/webhook", "secret", "push")
+	assert.Equal(t, resp.StatusCode, http.StatusOK) // Duplicate.
+	cancel()
+	err = <-done
+	assert.NoError(t, err)
+	assert.SliceEqual(t, target.getEvents(), []string{"push"})
+	d, err := sm.db.Store.Get(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, githubhook.DeliveryStatusSucceeded)
+}
+
+func TestSQLiteModeAdminHandler(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sm, err := openSQLiteMode(ctx, filepath.Join(t.TempDir(), "githubhookd.db"), logger)
+	assert.NoError(t, err)
+	defer sm.close()
+	var mu sync.Mutex
+	var sent []string
+	h := &githubhook.Handler{
+		Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, d.DeliveryID)
+			return nil
+		}),
+	}
+	sm.option()(h)
+	req, err := new(githubhook.Signer).NewRequest(ctx, "/", "push", "1", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	rh := githubhook.NewReloadableHandler(h)
+	ah := http.StripPrefix("/admin", sm.adminHandler(rh, logger))
+	w = httptest.NewRecorder()
+	ah.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/api/deliveries", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v struct {
+		Deliveries []struct {
+			DeliveryID string `json:"delivery_id"`
+		} `json:"deliveries"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.SliceLen(t, v.Deliveries, 1)
+	w = httptest.NewRecorder()
+	ah.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = httptest.NewRecorder()
+	ah.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/api/deliveries/1/redeliver", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	err = sm.shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, sent, []string{"1", "1"})
+}
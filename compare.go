@@ -0,0 +1,264 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+CompareResult is the outcome of a [http.Handler] for a request.
+
+Fields:
+  - StatusCode, Header and Body are the response.
+  - Verified is true if the delivery has been verified (source, signature and accepted event).
+  - Event is the event of the verified delivery.
+  - Payload is the decoded payload (see [Handler.DecodePayload]).
+  - Err is the error of the request (see [Observer]), or the panic of the candidate handler.
+  - DeliveryErr is the error returned by [Handler.Delivery] (or [Handler.Sink]). The asynchronous deliveries ([Handler.Async]) are not recorded.
+
+The outcome of the pipeline (Verified, Event, Payload, Err and DeliveryErr) is only recorded for a [Handler], it's empty for the other handlers.
+*/
+type CompareResult struct {
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	Verified    bool
+	Event       string
+	Payload     any
+	Err         error
+	DeliveryErr error
+}
+
+// Equal returns true if the outcomes are equal (see [CompareResult.Diff]).
+func (r *CompareResult) Equal(other *CompareResult) bool {
+	return len(r.Diff(other)) == 0
+}
+
+// Diff returns the names of the fields whose values are different, e.g. "StatusCode" or "DeliveryErr".
+//
+// Headers are not compared, because they often contain values that depend on time.
+// Errors are compared by message.
+func (r *CompareResult) Diff(other *CompareResult) []string {
+	var diff []string
+	add := func(name string, equal bool) {
+		if !equal {
+			diff = append(diff, name)
+		}
+	}
+	add("StatusCode", r.StatusCode == other.StatusCode)
+	add("Body", bytes.Equal(r.Body, other.Body))
+	add("Verified", r.Verified == other.Verified)
+	add("Event", r.Event == other.Event)
+	add("Payload", reflect.DeepEqual(r.Payload, other.Payload))
+	add("Err", errorMessage(r.Err) == errorMessage(other.Err))
+	add("DeliveryErr", errorMessage(r.DeliveryErr) == errorMessage(other.DeliveryErr))
+	return diff
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Compare default values.
+const (
+	// DefaultCompareMaxCandidates is the default value of [Compare.MaxCandidates].
+	DefaultCompareMaxCandidates = 10
+	// DefaultCompareCandidateTimeout is the default value of [Compare.CandidateTimeout].
+	DefaultCompareCandidateTimeout = 30 * time.Second
+)
+
+/*
+Compare returns a [http.Handler] that feeds every request to a current and a candidate [http.Handler].
+
+The response of the current handler is returned to the client.
+The candidate handler is called asynchronously with a copy of the request, and its response is discarded.
+The request body is limited to the [Handler.MaxBodySize] of the current handler (or [DefaultMaxBodySize] if it's not a [Handler]).
+
+Fields (all are optional):
+  - Result is called with the outcomes of both handlers.
+  - Mismatch is called if the outcomes are not equal (see [CompareResult.Diff]). A panic of the candidate handler is a mismatch.
+  - Error is called if the request body can't be read.
+  - MaxCandidates is the maximum number of candidate requests running concurrently (default: [DefaultCompareMaxCandidates]). When it's reached, the comparison is dropped (see [Compare.Dropped]).
+  - CandidateTimeout is the timeout of the candidate request (default: [DefaultCompareCandidateTimeout]). Its context is detached from the cancellation of the client request.
+*/
+type Compare struct {
+	Current          http.Handler
+	Candidate        http.Handler
+	Result           func(req *http.Request, current, candidate *CompareResult)
+	Mismatch         func(req *http.Request, current, candidate *CompareResult)
+	Error            func(err error, req *http.Request)
+	MaxCandidates    int
+	CandidateTimeout time.Duration
+
+	semOnce sync.Once
+	sem     chan struct{}
+	dropped atomic.Int64
+}
+
+func (c *Compare) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	limitBody(req, c.getMaxBodySize())
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		c.handleError(w, req, wrapBodyReadError(err))
+		return
+	}
+	currentReq, currentRec := newCompareRequest(req.Context(), req, body)
+	currentW := httptest.NewRecorder()
+	c.Current.ServeHTTP(currentW, currentReq)
+	current := newCompareResult(currentW, currentRec)
+	writeCompareResult(w, current)
+	if !c.acquire() {
+		c.dropped.Add(1)
+		return
+	}
+	candidateReq, candidateRec := newCompareRequest(context.WithoutCancel(req.Context()), req, body)
+	go c.runCandidate(candidateReq, candidateRec, current)
+}
+
+// Dropped returns the number of comparisons dropped because [Compare.MaxCandidates] was reached.
+func (c *Compare) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+func (c *Compare) getMaxBodySize() int64 {
+	if h, ok := c.Current.(*Handler); ok {
+		return h.getMaxBodySize()
+	}
+	return DefaultMaxBodySize
+}
+
+func (c *Compare) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	if c.Error != nil {
+		c.Error(err, req)
+	}
+	statusCode := http.StatusInternalServerError
+	message := http.StatusText(statusCode)
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		statusCode = reqErr.StatusCode
+		message = reqErr.Message
+	}
+	http.Error(w, message, statusCode)
+}
+
+func (c *Compare) acquire() bool {
+	c.semOnce.Do(func() {
+		n := c.MaxCandidates
+		if n <= 0 {
+			n = DefaultCompareMaxCandidates
+		}
+		c.sem = make(chan struct{}, n)
+	})
+	select {
+	case c.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Compare) runCandidate(req *http.Request, rec *compareRecorder, current *CompareResult) {
+	defer func() {
+		<-c.sem
+	}()
+	timeout := c.CandidateTimeout
+	if timeout <= 0 {
+		timeout = DefaultCompareCandidateTimeout
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+	candidate := c.serveCandidate(req, rec)
+	if c.Result != nil {
+		c.Result(req, current, candidate)
+	}
+	if c.Mismatch != nil && !current.Equal(candidate) {
+		c.Mismatch(req, current, candidate)
+	}
+}
+
+// serveCandidate calls the candidate handler, and returns a result with a 500 status code if it panics.
+func (c *Compare) serveCandidate(req *http.Request, rec *compareRecorder) (res *CompareResult) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		res = newCompareResult(httptest.NewRecorder(), rec)
+		res.StatusCode = http.StatusInternalServerError
+		res.Err = &PanicError{
+			Value: r,
+			Stack: debug.Stack(),
+		}
+	}()
+	w := httptest.NewRecorder()
+	c.Candidate.ServeHTTP(w, req)
+	return newCompareResult(w, rec)
+}
+
+// newCompareRequest returns a copy of the request with the body, and a recorder of the outcome of the pipeline.
+func newCompareRequest(ctx context.Context, req *http.Request, body []byte) (*http.Request, *compareRecorder) {
+	rec := new(compareRecorder)
+	req = req.Clone(context.WithValue(ctx, compareRecorderContextKey{}, rec))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return req, rec
+}
+
+func newCompareResult(w *httptest.ResponseRecorder, rec *compareRecorder) *CompareResult {
+	res := rec.result()
+	res.StatusCode = w.Code
+	res.Header = w.Header()
+	res.Body = w.Body.Bytes()
+	return res
+}
+
+func writeCompareResult(w http.ResponseWriter, res *CompareResult) {
+	header := w.Header()
+	for k, vs := range res.Header {
+		header[k] = vs
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = w.Write(res.Body)
+}
+
+type compareRecorderContextKey struct{}
+
+// compareRecorder records the outcome of the pipeline of a [Handler] for [Compare].
+type compareRecorder struct {
+	mu  sync.Mutex
+	res CompareResult
+}
+
+func (rec *compareRecorder) record(f func(res *CompareResult)) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	f(&rec.res)
+}
+
+func (rec *compareRecorder) result() *CompareResult {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	res := rec.res
+	return &res
+}
+
+// recordCompare records the outcome of the pipeline, if the request is handled by [Compare].
+func recordCompare(ctx context.Context, f func(res *CompareResult)) {
+	rec, ok := ctx.Value(compareRecorderContextKey{}).(*compareRecorder)
+	if ok {
+		rec.record(f)
+	}
+}
@@ -0,0 +1,204 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	type results struct {
+		current   *CompareResult
+		candidate *CompareResult
+	}
+	mismatchCh := make(chan results, 1)
+	c := &Compare{
+		Current: &Handler{},
+		Candidate: &Handler{
+			Secret: "foobar",
+		},
+		Mismatch: func(req *http.Request, current, candidate *CompareResult) {
+			mismatchCh <- results{current: current, candidate: candidate}
+		},
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	res := <-mismatchCh
+	assert.Equal(t, res.current.StatusCode, http.StatusOK)
+	assert.Equal(t, res.candidate.StatusCode, http.StatusBadRequest)
+}
+
+func TestCompareEqual(t *testing.T) {
+	ctx := context.Background()
+	resultCh := make(chan bool, 1)
+	c := &Compare{
+		Current:   &Handler{},
+		Candidate: &Handler{},
+		Result: func(req *http.Request, current, candidate *CompareResult) {
+			resultCh <- current.Equal(candidate)
+		},
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.True(t, <-resultCh)
+}
+
+func TestCompareOutcome(t *testing.T) {
+	ctx := context.Background()
+	type results struct {
+		current   *CompareResult
+		candidate *CompareResult
+	}
+	mismatchCh := make(chan results, 1)
+	c := &Compare{
+		Current: &Handler{},
+		Candidate: &Handler{
+			DecodePayload: func(ctx context.Context, event string, rawPayload []byte) (any, error) {
+				return "candidate", nil
+			},
+		},
+		Mismatch: func(req *http.Request, current, candidate *CompareResult) {
+			mismatchCh <- results{current: current, candidate: candidate}
+		},
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	res := <-mismatchCh
+	assert.True(t, res.current.Verified)
+	assert.Equal(t, res.current.Event, "push")
+	assert.NotZero(t, res.current.Payload)
+	assert.SliceEqual(t, res.current.Diff(res.candidate), []string{"Payload"})
+}
+
+func TestCompareOutcomeDeliveryError(t *testing.T) {
+	current := &CompareResult{StatusCode: http.StatusOK}
+	candidate := &CompareResult{StatusCode: http.StatusOK, Err: errors.New("error"), DeliveryErr: errors.New("error")}
+	assert.SliceEqual(t, current.Diff(candidate), []string{"Err", "DeliveryErr"})
+	assert.SliceEmpty(t, candidate.Diff(&CompareResult{StatusCode: http.StatusOK, Err: errors.New("error"), DeliveryErr: errors.New("error")}))
+}
+
+func TestCompareCandidatePanic(t *testing.T) {
+	ctx := context.Background()
+	mismatchCh := make(chan *CompareResult, 1)
+	c := &Compare{
+		Current: &Handler{},
+		Candidate: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			panic("error")
+		}),
+		Mismatch: func(req *http.Request, current, candidate *CompareResult) {
+			mismatchCh <- candidate
+		},
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	candidate := <-mismatchCh
+	assert.Equal(t, candidate.StatusCode, http.StatusInternalServerError)
+	var panicErr *PanicError
+	assert.ErrorAs(t, candidate.Err, &panicErr)
+	assert.Equal(t, panicErr.Value, any("error"))
+}
+
+func TestCompareCandidateContext(t *testing.T) {
+	ctx := context.Background()
+	errCh := make(chan error, 1)
+	c := &Compare{
+		Current: &Handler{},
+		Candidate: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+			errCh <- req.Context().Err()
+		}),
+		CandidateTimeout: 10 * time.Millisecond,
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	assert.ErrorIs(t, <-errCh, context.DeadlineExceeded)
+}
+
+func TestCompareDropped(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	c := &Compare{
+		Current: &Handler{},
+		Candidate: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-release
+			close(done)
+		}),
+		MaxCandidates: 1,
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	for range 2 {
+		req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		testExpectResponseStatusOK(t, resp)
+	}
+	assert.Equal(t, c.Dropped(), int64(1))
+	close(release)
+	<-done
+}
+
+func TestCompareErrorBodyTooLarge(t *testing.T) {
+	ctx := context.Background()
+	var handledErr error
+	c := &Compare{
+		Current: &Handler{
+			MaxBodySize: 5,
+		},
+		Candidate: &Handler{},
+		Error: func(err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusRequestEntityTooLarge)
+	assert.ErrorIs(t, handledErr, ErrBodyTooLarge)
+}
@@ -0,0 +1,49 @@
+package githubhook
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrConcurrencyLimit is returned (wrapped in a [RequestError]) if a request is rejected by [Handler.ConcurrencyLimiter].
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// ConcurrencyLimiter limits the number of requests handled concurrently by a [Handler].
+//
+// Requests beyond the limit are rejected immediately with a 503 response, instead of waiting.
+// It prevents a slow [Handler.Delivery] from exhausting the server goroutines and memory under load.
+//
+// It must be created with [NewConcurrencyLimiter].
+type ConcurrencyLimiter struct {
+	limit    int64
+	inFlight atomic.Int64
+}
+
+// NewConcurrencyLimiter creates a new [ConcurrencyLimiter] with the maximum number of concurrent requests.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		limit: int64(limit),
+	}
+}
+
+// InFlight returns the number of requests currently handled.
+func (l *ConcurrencyLimiter) InFlight() int {
+	return int(l.inFlight.Load())
+}
+
+func (l *ConcurrencyLimiter) acquire() error {
+	if l.inFlight.Add(1) > l.limit {
+		l.inFlight.Add(-1)
+		return &RequestError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "too many concurrent requests",
+			Err:        ErrConcurrencyLimit,
+		}
+	}
+	return nil
+}
+
+func (l *ConcurrencyLimiter) release() {
+	l.inFlight.Add(-1)
+}
@@ -0,0 +1,48 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerConcurrencyLimiter(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	var handledErr error
+	l := NewConcurrencyLimiter(1)
+	h := &Handler{
+		ConcurrencyLimiter: l,
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			close(started)
+			<-unblock
+			return nil
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	done := make(chan int)
+	go func() {
+		req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		done <- w.Code
+	}()
+	<-started
+	assert.Equal(t, l.InFlight(), 1)
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusServiceUnavailable)
+	assert.ErrorIs(t, handledErr, ErrConcurrencyLimit)
+	close(unblock)
+	assert.Equal(t, <-done, http.StatusOK)
+	assert.Equal(t, l.InFlight(), 0)
+}
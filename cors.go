@@ -0,0 +1,71 @@
+package githubhook
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+CORS is the CORS configuration of a [Handler].
+
+Fields:
+  - AllowedOrigins is the list of allowed origins. "*" allows all origins.
+  - AllowedHeaders is the list of allowed request headers. If it's empty, the headers sent by GitHub are allowed.
+  - MaxAge is the duration during which a preflight response can be cached. If it's zero, the header is not sent.
+*/
+type CORS struct {
+	AllowedOrigins []string
+	AllowedHeaders []string
+	MaxAge         time.Duration
+}
+
+var corsDefaultAllowedHeaders = []string{
+	"Content-Type",
+	"X-GitHub-Event",
+	"X-GitHub-Delivery",
+	"X-Hub-Signature",
+	"X-Hub-Signature-256",
+}
+
+func (c *CORS) isOriginAllowed(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, "*") || slices.Contains(c.AllowedOrigins, origin)
+}
+
+func (c *CORS) allowedHeaders() []string {
+	if len(c.AllowedHeaders) > 0 {
+		return c.AllowedHeaders
+	}
+	return corsDefaultAllowedHeaders
+}
+
+// handleCORS sets the CORS headers and handles preflight requests.
+// It returns true if the response has been written.
+func (h *Handler) handleCORS(w http.ResponseWriter, req *http.Request) bool {
+	if h.CORS == nil {
+		return false
+	}
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if !h.CORS.isOriginAllowed(origin) {
+		return false
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	header.Set("Access-Control-Allow-Methods", http.MethodPost)
+	header.Set("Access-Control-Allow-Headers", strings.Join(h.CORS.allowedHeaders(), ", "))
+	if h.CORS.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(h.CORS.MaxAge.Seconds())))
+	}
+	h.writeResponseHeader(w, http.StatusNoContent)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,78 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		CORS: &CORS{
+			AllowedOrigins: []string{"http://localhost:8080"},
+			MaxAge:         time.Hour,
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, srv.URL, http.NoBody)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "http://localhost:8080")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusNoContent)
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "http://localhost:8080")
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Methods"), http.MethodPost)
+	assert.Equal(t, resp.Header.Get("Access-Control-Max-Age"), "3600")
+}
+
+func TestCORSRequest(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		CORS: &CORS{
+			AllowedOrigins: []string{"*"},
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("Origin", "http://localhost:8080")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "http://localhost:8080")
+}
+
+func TestCORSOriginNotAllowed(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		CORS: &CORS{
+			AllowedOrigins: []string{"http://localhost:8080"},
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req, err := http.NewRequestWithContext(ctx, http.MethodOptions, srv.URL, http.NoBody)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusMethodNotAllowed)
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "")
+}
@@ -0,0 +1,145 @@
+package githubhook
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Cost stages.
+const (
+	// CostStageDecode is the decoding of the payload.
+	CostStageDecode = "decode"
+	// CostStageDelivery is the call to [Handler.Delivery] (or [Handler.Sink]), including the filters and handlers composed in it.
+	CostStageDelivery = "delivery"
+)
+
+// CostTenant returns the tenant of a delivery for [Costs], e.g. the installation target or the repository owner.
+//
+// The number of tenants should be bounded, because the costs are kept for each tenant.
+type CostTenant func(md *DeliveryMetadata) string
+
+/*
+Costs accounts the processing time of the deliveries, per event, tenant and stage, so operators can attribute the processing cost and tune the filters of the most expensive event streams.
+
+The [Handler] accounts the [CostStageDecode] and [CostStageDelivery] stages.
+Custom stages (e.g. a filter or a handler composed in [Handler.Delivery]) can be accounted with [Costs.Handler].
+
+The processing time is the wall time: Go doesn't expose the CPU time of a goroutine.
+
+It must be created with [NewCosts].
+
+It implements [http.Handler], and serves the costs as JSON (e.g. for an admin endpoint).
+The github.com/pierrre/githubhook/metrics package provides a Prometheus collector.
+*/
+type Costs struct {
+	tenant CostTenant
+
+	mu      sync.Mutex
+	entries map[costKey]*Cost
+	now     func() time.Time
+}
+
+type costKey struct {
+	event  string
+	tenant string
+	stage  string
+}
+
+// NewCosts creates a new [Costs].
+//
+// tenant returns the tenant of a delivery.
+// If it's nil, the tenant is empty.
+func NewCosts(tenant CostTenant) *Costs {
+	return &Costs{
+		tenant:  tenant,
+		entries: make(map[costKey]*Cost),
+		now:     time.Now,
+	}
+}
+
+// Record records the processing time of a delivery for a stage.
+func (c *Costs) Record(md *DeliveryMetadata, stage string, duration time.Duration) {
+	var tenant string
+	if c.tenant != nil {
+		tenant = c.tenant(md)
+	}
+	k := costKey{
+		event:  md.Event,
+		tenant: tenant,
+		stage:  stage,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		e = &Cost{
+			Event:  k.event,
+			Tenant: k.tenant,
+			Stage:  k.stage,
+		}
+		c.entries[k] = e
+	}
+	e.Count++
+	e.Total += duration
+	e.Max = max(e.Max, duration)
+}
+
+// start starts the measure of a stage, and returns a function that records it.
+func (c *Costs) start(md *DeliveryMetadata, stage string) (stop func()) {
+	start := c.now()
+	return func() {
+		c.Record(md, stage, c.now().Sub(start))
+	}
+}
+
+// Handler returns a [DeliveryHandler] that accounts the processing time of the given [DeliveryHandler] for a stage.
+func (c *Costs) Handler(stage string, h DeliveryHandler) DeliveryHandler {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		defer c.start(md, stage)()
+		return h(ctx, md, payload)
+	}
+}
+
+// Costs returns the costs, sorted by decreasing total processing time.
+func (c *Costs) Costs() []Cost {
+	c.mu.Lock()
+	costs := make([]Cost, 0, len(c.entries))
+	for _, e := range c.entries {
+		costs = append(costs, *e)
+	}
+	c.mu.Unlock()
+	slices.SortFunc(costs, func(a, b Cost) int {
+		return cmp.Or(
+			cmp.Compare(b.Total, a.Total),
+			cmp.Compare(a.Event, b.Event),
+			cmp.Compare(a.Tenant, b.Tenant),
+			cmp.Compare(a.Stage, b.Stage),
+		)
+	})
+	return costs
+}
+
+func (c *Costs) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	v := struct {
+		Costs []Cost `json:"costs"`
+	}{
+		Costs: c.Costs(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Cost is the accounted processing time of an event, a tenant and a stage.
+type Cost struct {
+	Event  string        `json:"event"`
+	Tenant string        `json:"tenant"`
+	Stage  string        `json:"stage"`
+	Count  int           `json:"count"`
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+}
@@ -0,0 +1,99 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func testNewCosts(tenant CostTenant) *Costs {
+	c := NewCosts(tenant)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	return c
+}
+
+func TestCosts(t *testing.T) {
+	ctx := context.Background()
+	c := testNewCosts(func(md *DeliveryMetadata) string {
+		return md.HookID
+	})
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+		Costs: c,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	for range 2 {
+		req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+		req.Header.Set("X-GitHub-Hook-ID", "123")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		testExpectResponseStatusOK(t, resp)
+	}
+	costs := c.Costs()
+	assert.SliceLen(t, costs, 2)
+	for _, cost := range costs {
+		assert.Equal(t, cost.Event, "push")
+		assert.Equal(t, cost.Tenant, "123")
+		assert.Equal(t, cost.Count, 2)
+		assert.Equal(t, cost.Total, 2*time.Millisecond)
+		assert.Equal(t, cost.Max, time.Millisecond)
+	}
+}
+
+func TestCostsHandler(t *testing.T) {
+	ctx := context.Background()
+	c := testNewCosts(nil)
+	h := c.Handler("filter", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		return nil
+	})
+	md := &DeliveryMetadata{
+		Event: "push",
+	}
+	err := h(ctx, md, nil)
+	assert.NoError(t, err)
+	c.Record(md, CostStageDelivery, 10*time.Millisecond)
+	costs := c.Costs()
+	assert.SliceLen(t, costs, 2)
+	assert.Equal(t, costs[0], Cost{
+		Event: "push",
+		Stage: CostStageDelivery,
+		Count: 1,
+		Total: 10 * time.Millisecond,
+		Max:   10 * time.Millisecond,
+	})
+	assert.Equal(t, costs[1], Cost{
+		Event: "push",
+		Stage: "filter",
+		Count: 1,
+		Total: time.Millisecond,
+		Max:   time.Millisecond,
+	})
+}
+
+func TestCostsServeHTTP(t *testing.T) {
+	c := NewCosts(nil)
+	c.Record(&DeliveryMetadata{Event: "push"}, CostStageDecode, time.Millisecond)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	c.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v struct {
+		Costs []Cost `json:"costs"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.SliceLen(t, v.Costs, 1)
+}
@@ -0,0 +1,290 @@
+// Package dashboard provides an embedded web dashboard for inspecting the deliveries stored in [githubhook.Handler.Store].
+//
+// It shows the recent deliveries, their headers, their pretty-printed payload, their verification result and their processing outcome, and allows to replay them:
+//
+//	mux.Handle("/dashboard/", http.StripPrefix("/dashboard", dashboard.New(h)))
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/pierrre/githubhook"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// DefaultLimit is the default number of deliveries shown by [Dashboard].
+const DefaultLimit = 100
+
+/*
+Dashboard is an embedded web dashboard for the deliveries stored in [githubhook.Handler.Store].
+
+Pages:
+  - / lists the most recent deliveries (newest first). The query parameters "event", "repository" and "status" filter them.
+  - /deliveries/{id} shows a delivery.
+  - POST /deliveries/{id}/replay replays a delivery, see [githubhook.Handler.Redeliver].
+
+The verification result is shown from [githubhook.DeliveryMetadata.SignatureHeaders]: the deliveries accepted without signature verification (no secret, or [githubhook.Handler.TrustedSources]) are shown as not verified.
+
+The replay form is protected against cross-site request forgery with a token, derived from a random key generated by [New].
+
+It uses relative links, so it can be served under any path prefix (e.g. with [http.StripPrefix]).
+
+The pages require the [githubhook.AdminRoleReader] role, and the replay the [githubhook.AdminRoleOperator] role (the replay form is hidden for the readers), see [githubhook.AdminAuthorizer].
+Without Authorizer, it doesn't authenticate the requests, so it must not be exposed publicly.
+
+Fields:
+  - Handler is the handler (required). If its Store is nil, all requests are rejected with a 404 response.
+  - Limit is the maximum number of deliveries listed (default: [DefaultLimit]).
+  - Authorizer authorizes the requests (optional).
+  - Operator returns the operator of a replay, recorded in its [githubhook.Lineage] (optional, default: the name of the [githubhook.AdminPrincipal]).
+  - Error is called if an error happened (optional).
+
+It must be created with [New].
+*/
+type Dashboard struct {
+	Handler    *githubhook.Handler
+	Limit      int
+	Authorizer githubhook.AdminAuthorizer
+	Operator   func(req *http.Request) string
+	Error      func(ctx context.Context, err error, req *http.Request)
+
+	mux     *http.ServeMux
+	csrfKey []byte
+}
+
+// New creates a new [Dashboard].
+func New(h *githubhook.Handler) *Dashboard {
+	d := &Dashboard{
+		Handler: h,
+		mux:     http.NewServeMux(),
+		csrfKey: make([]byte, 32),
+	}
+	_, _ = rand.Read(d.csrfKey) // It never returns an error.
+	d.handle("GET /{$}", githubhook.AdminRoleReader, d.list)
+	d.handle("GET /deliveries/{id}", githubhook.AdminRoleReader, d.get)
+	d.handle("POST /deliveries/{id}/replay", githubhook.AdminRoleOperator, d.replay)
+	return d
+}
+
+// handle registers a handler that requires the role.
+func (d *Dashboard) handle(pattern string, role githubhook.AdminRole, f http.HandlerFunc) {
+	d.mux.HandleFunc(pattern, func(w http.ResponseWriter, req *http.Request) {
+		githubhook.AuthorizeAdmin(w, req, d.Authorizer, role, f)
+	})
+}
+
+// canReplay returns true if the principal of the request can replay the deliveries.
+func (d *Dashboard) canReplay(req *http.Request) bool {
+	if d.Authorizer == nil {
+		return true
+	}
+	p := githubhook.ContextAdminPrincipal(req.Context())
+	return p != nil && p.Role >= githubhook.AdminRoleOperator
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if d.Handler.Store == nil {
+		http.Error(w, "store not configured", http.StatusNotFound)
+		return
+	}
+	d.mux.ServeHTTP(w, req)
+}
+
+type listPage struct {
+	Filter     githubhook.StoreFilter
+	Statuses   []githubhook.DeliveryStatus
+	Deliveries []*githubhook.StoredDelivery
+}
+
+func (d *Dashboard) list(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	limit := d.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	filter := githubhook.StoreFilter{
+		Event:      q.Get("event"),
+		Repository: q.Get("repository"),
+		Status:     githubhook.DeliveryStatus(q.Get("status")),
+		Limit:      limit,
+		Newest:     true,
+	}
+	ds, err := d.Handler.Store.List(req.Context(), filter)
+	if err != nil {
+		d.handleError(w, req, http.StatusInternalServerError, fmt.Errorf("store: %w", err))
+		return
+	}
+	d.render(w, req, http.StatusOK, "list.html", &listPage{
+		Filter: filter,
+		Statuses: []githubhook.DeliveryStatus{
+			githubhook.DeliveryStatusPending,
+			githubhook.DeliveryStatusSucceeded,
+			githubhook.DeliveryStatusFailed,
+		},
+		Deliveries: ds,
+	})
+}
+
+type deliveryPage struct {
+	Delivery    *githubhook.StoredDelivery
+	CanReplay   bool
+	CSRFToken   string
+	Headers     []header
+	Payload     string
+	Replayed    string
+	ReplayError string
+}
+
+type header struct {
+	Name  string
+	Value string
+}
+
+func (d *Dashboard) get(w http.ResponseWriter, req *http.Request) {
+	sd, ok := d.getDelivery(w, req)
+	if !ok {
+		return
+	}
+	d.renderDelivery(w, req, http.StatusOK, &deliveryPage{
+		Delivery: sd,
+		Replayed: req.URL.Query().Get("replayed"),
+	})
+}
+
+func (d *Dashboard) replay(w http.ResponseWriter, req *http.Request) {
+	id := req.PathValue("id")
+	if !hmac.Equal([]byte(req.PostFormValue("csrf_token")), []byte(d.csrfToken(id))) {
+		d.handleError(w, req, http.StatusForbidden, errors.New("invalid CSRF token"))
+		return
+	}
+	statusCode, err := d.Handler.Redeliver(req.Context(), id, githubhook.AdminOperator(req, d.Operator))
+	if err != nil {
+		if errors.Is(err, githubhook.ErrStoredDeliveryNotFound) {
+			d.handleError(w, req, http.StatusNotFound, err)
+			return
+		}
+		if d.Error != nil {
+			d.Error(req.Context(), err, req)
+		}
+		sd, ok := d.getDelivery(w, req)
+		if !ok {
+			return
+		}
+		d.renderDelivery(w, req, http.StatusBadGateway, &deliveryPage{
+			Delivery:    sd,
+			ReplayError: err.Error(),
+		})
+		return
+	}
+	// The location is relative to ".../deliveries/{id}/replay", so it works under any path prefix.
+	w.Header().Set("Location", "../"+url.PathEscape(id)+"?replayed="+strconv.Itoa(statusCode))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+func (d *Dashboard) getDelivery(w http.ResponseWriter, req *http.Request) (*githubhook.StoredDelivery, bool) {
+	sd, err := d.Handler.Store.Get(req.Context(), req.PathValue("id"))
+	if err != nil {
+		err = fmt.Errorf("store: %w", err)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, githubhook.ErrStoredDeliveryNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		d.handleError(w, req, statusCode, err)
+		return nil, false
+	}
+	return sd, true
+}
+
+// csrfToken returns the CSRF token of the replay form of a delivery.
+func (d *Dashboard) csrfToken(deliveryID string) string {
+	mac := hmac.New(sha256.New, d.csrfKey)
+	_, _ = mac.Write([]byte(deliveryID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dashboard) renderDelivery(w http.ResponseWriter, req *http.Request, statusCode int, p *deliveryPage) {
+	p.CanReplay = d.canReplay(req)
+	p.CSRFToken = d.csrfToken(p.Delivery.DeliveryID)
+	p.Headers = deliveryHeaders(&p.Delivery.DeliveryMetadata)
+	p.Payload = prettyPayload(p.Delivery.RawPayload)
+	d.render(w, req, statusCode, "delivery.html", p)
+}
+
+// deliveryHeaders returns the request headers of a delivery, from its metadata.
+//
+// The names are not canonicalized, so they are shown as sent by GitHub.
+func deliveryHeaders(md *githubhook.DeliveryMetadata) []header {
+	var hs []header
+	for _, h := range []header{
+		{"X-GitHub-Event", md.Event},
+		{"X-GitHub-Delivery", md.DeliveryID},
+		{"X-GitHub-Hook-ID", md.HookID},
+		{"X-GitHub-Hook-Installation-Target-Type", md.InstallationTargetType},
+		{"X-GitHub-Hook-Installation-Target-ID", md.InstallationTargetID},
+		{"User-Agent", md.UserAgent},
+	} {
+		if h.Value != "" {
+			hs = append(hs, h)
+		}
+	}
+	if md.Lineage != nil {
+		hd := make(http.Header)
+		md.Lineage.SetHeader(hd)
+		names := slices.Sorted(maps.Keys(hd))
+		for _, name := range names {
+			hs = append(hs, header{
+				Name:  name,
+				Value: hd.Get(name),
+			})
+		}
+	}
+	return hs
+}
+
+// prettyPayload returns the indented JSON payload, or the raw payload if it's not valid JSON.
+func prettyPayload(rawPayload []byte) string {
+	buf := new(bytes.Buffer)
+	err := json.Indent(buf, rawPayload, "", "  ")
+	if err != nil {
+		return string(rawPayload)
+	}
+	return buf.String()
+}
+
+func (d *Dashboard) render(w http.ResponseWriter, req *http.Request, statusCode int, name string, data any) {
+	buf := new(bytes.Buffer)
+	err := templates.ExecuteTemplate(buf, name, data)
+	if err != nil {
+		d.handleError(w, req, http.StatusInternalServerError, fmt.Errorf("template %q: %w", name, err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (d *Dashboard) handleError(w http.ResponseWriter, req *http.Request, statusCode int, err error) {
+	http.Error(w, err.Error(), statusCode)
+	if d.Error != nil {
+		d.Error(req.Context(), err, req)
+	}
+}
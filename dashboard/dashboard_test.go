@@ -0,0 +1,217 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main","repository":{"full_name":"pierrre/githubhook"}}`)
+
+func testNewDashboard(t *testing.T, delivery githubhook.DeliveryHandler) *Dashboard {
+	t.Helper()
+	s := githubhook.NewMemoryStore(10)
+	for i, id := range []string{"1", "2", "3"} {
+		err := s.Save(context.Background(), &githubhook.StoredDelivery{
+			DeliveryMetadata: githubhook.DeliveryMetadata{
+				Event:            "push",
+				DeliveryID:       id,
+				HookID:           "123",
+				ReceivedAt:       time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC),
+				SignatureHeaders: []string{"X-Hub-Signature-256"},
+			},
+			Repository: "pierrre/githubhook",
+			RawPayload: testRawPayload,
+			Status:     githubhook.DeliveryStatusFailed,
+			Error:      "test error",
+		})
+		assert.NoError(t, err)
+	}
+	return New(&githubhook.Handler{
+		Delivery: delivery,
+		Store:    s,
+	})
+}
+
+func testRequest(t *testing.T, d *Dashboard, method string, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	return w
+}
+
+func testReplayRequest(t *testing.T, d *Dashboard, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	form := url.Values{"csrf_token": {d.csrfToken(id)}}
+	req := httptest.NewRequest(http.MethodPost, "/deliveries/"+id+"/replay", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	return w
+}
+
+func TestList(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	d.Limit = 2
+	w := testRequest(t, d, http.MethodGet, "/?status=failed")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	body := w.Body.String()
+	assert.StringContains(t, body, `href="deliveries/3"`)
+	assert.StringContains(t, body, `href="deliveries/2"`)
+	assert.StringNotContains(t, body, `href="deliveries/1"`)
+	assert.Less(t, strings.Index(body, `href="deliveries/3"`), strings.Index(body, `href="deliveries/2"`))
+	assert.StringContains(t, body, `<option value="failed" selected>`)
+}
+
+func TestListEmpty(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	w := testRequest(t, d, http.MethodGet, "/?event=issues")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.StringContains(t, w.Body.String(), "No deliveries.")
+}
+
+func TestGet(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	w := testRequest(t, d, http.MethodGet, "/deliveries/1")
+	assert.Equal(t, w.Code, http.StatusOK)
+	body := w.Body.String()
+	assert.StringContains(t, body, "X-GitHub-Hook-ID")
+	assert.StringContains(t, body, `<span class="status verified">verified</span> with <span class="mono">X-Hub-Signature-256</span>`)
+	assert.StringContains(t, body, "test error")
+	assert.StringContains(t, body, "\n  &#34;ref&#34;: &#34;refs/heads/main&#34;,\n")
+	assert.StringContains(t, body, `action="1/replay"`)
+	assert.StringContains(t, body, `name="csrf_token" value="`+d.csrfToken("1")+`"`)
+}
+
+func TestGetNotVerified(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	err := d.Handler.Store.Save(context.Background(), &githubhook.StoredDelivery{
+		DeliveryMetadata: githubhook.DeliveryMetadata{
+			Event:                 "push",
+			DeliveryID:            "trusted",
+			HeaderInterpretations: []string{"interpretation"},
+		},
+		RawPayload: testRawPayload,
+	})
+	assert.NoError(t, err)
+	w := testRequest(t, d, http.MethodGet, "/deliveries/trusted")
+	assert.Equal(t, w.Code, http.StatusOK)
+	body := w.Body.String()
+	assert.StringContains(t, body, `<span class="status unverified">not verified</span>`)
+	assert.StringNotContains(t, body, `<span class="status verified">`)
+	assert.StringContains(t, body, "interpretation")
+}
+
+func TestGetNotFound(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	w := testRequest(t, d, http.MethodGet, "/deliveries/unknown")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestReplay(t *testing.T) {
+	var md *githubhook.DeliveryMetadata
+	d := testNewDashboard(t, func(ctx context.Context, m *githubhook.DeliveryMetadata, payload any) error {
+		md = m
+		return nil
+	})
+	d.Operator = func(req *http.Request) string {
+		return "operator"
+	}
+	w := testReplayRequest(t, d, "1")
+	assert.Equal(t, w.Code, http.StatusSeeOther)
+	loc, err := url.Parse("http://example.com/dashboard/deliveries/1/replay")
+	assert.NoError(t, err)
+	loc, err = loc.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, loc.String(), "http://example.com/dashboard/deliveries/1?replayed=200")
+	assert.Equal(t, md.Lineage.Operator, "operator")
+	w = testRequest(t, d, http.MethodGet, "/deliveries/1?replayed=200")
+	body := w.Body.String()
+	assert.StringContains(t, body, "Replayed (status 200).")
+	assert.StringContains(t, body, "X-Githubhook-Original-Delivery")
+	assert.StringContains(t, body, "succeeded")
+}
+
+func TestReplayError(t *testing.T) {
+	var dashboardErr error
+	d := testNewDashboard(t, func(ctx context.Context, m *githubhook.DeliveryMetadata, payload any) error {
+		return errors.New("replay error")
+	})
+	d.Error = func(ctx context.Context, err error, req *http.Request) {
+		dashboardErr = err
+	}
+	w := testReplayRequest(t, d, "1")
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	assert.StringContains(t, w.Body.String(), "Replay failed: delivery: replay error")
+	assert.Error(t, dashboardErr)
+}
+
+func TestReplayNotFound(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	w := testReplayRequest(t, d, "unknown")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestReplayCSRF(t *testing.T) {
+	deliveryCalled := false
+	d := testNewDashboard(t, func(ctx context.Context, m *githubhook.DeliveryMetadata, payload any) error {
+		deliveryCalled = true
+		return nil
+	})
+	w := testRequest(t, d, http.MethodPost, "/deliveries/1/replay")
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	assert.False(t, deliveryCalled)
+	form := url.Values{"csrf_token": {d.csrfToken("2")}}
+	req := httptest.NewRequest(http.MethodPost, "/deliveries/1/replay", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	assert.False(t, deliveryCalled)
+}
+
+func TestNoStore(t *testing.T) {
+	d := New(new(githubhook.Handler))
+	w := testRequest(t, d, http.MethodGet, "/")
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func TestPrettyPayloadInvalid(t *testing.T) {
+	assert.Equal(t, prettyPayload([]byte("invalid")), "invalid")
+}
+
+func TestAuthorizer(t *testing.T) {
+	d := testNewDashboard(t, nil)
+	d.Authorizer = githubhook.AdminAuthorizerFunc(func(req *http.Request) (*githubhook.AdminPrincipal, error) {
+		return &githubhook.AdminPrincipal{
+			Name: "reader",
+			Role: githubhook.AdminRoleReader,
+		}, nil
+	})
+	w := testRequest(t, d, http.MethodGet, "/deliveries/1")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.StringNotContains(t, w.Body.String(), "Replay")
+	w = testReplayRequest(t, d, "1")
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	d.Authorizer = githubhook.AdminAuthorizerFunc(func(req *http.Request) (*githubhook.AdminPrincipal, error) {
+		return &githubhook.AdminPrincipal{
+			Name: "operator",
+			Role: githubhook.AdminRoleOperator,
+		}, nil
+	})
+	w = testRequest(t, d, http.MethodGet, "/deliveries/1")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.StringContains(t, w.Body.String(), "Replay")
+	w = testReplayRequest(t, d, "1")
+	assert.Equal(t, w.Code, http.StatusSeeOther)
+}
@@ -0,0 +1,183 @@
+package githubhook
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DedupStore stores the IDs of the received deliveries, in order to detect duplicates.
+//
+// GitHub can send the same delivery several times (manual redelivery, retries).
+// See [Handler.Dedup].
+type DedupStore interface {
+	// MarkSeen marks the delivery ID as seen, and returns true if it was already seen.
+	// It must be atomic.
+	MarkSeen(ctx context.Context, deliveryID string) (seen bool, err error)
+	// Forget forgets a delivery ID, so the next delivery with this ID is processed.
+	Forget(ctx context.Context, deliveryID string) error
+}
+
+// DedupEntry is a delivery ID stored in a [DedupStore], see [DedupLister].
+type DedupEntry struct {
+	DeliveryID string    `json:"delivery_id"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// DedupLister is implemented by the [DedupStore] that can list their delivery IDs.
+//
+// It allows to inspect the store, e.g. with the [Admin] API.
+type DedupLister interface {
+	// ListSeen returns the seen delivery IDs that are not expired.
+	ListSeen(ctx context.Context) ([]*DedupEntry, error)
+}
+
+// DuplicatePolicy defines the response to a duplicate delivery, see [Handler.Dedup].
+//
+// Different redelivery strategies of the senders need different signals.
+type DuplicatePolicy int
+
+const (
+	// DuplicateAck acknowledges the duplicates with a 200 response, like a processed delivery (idempotent).
+	DuplicateAck DuplicatePolicy = iota
+	// DuplicateConflict rejects the duplicates with a 409 response, and the "duplicate delivery" body.
+	DuplicateConflict
+	// DuplicateAccepted acknowledges the duplicates with a 202 response, and the "duplicate delivery" body.
+	DuplicateAccepted
+)
+
+// duplicateResponseBody is the response body of the duplicates, with [DuplicateConflict] and [DuplicateAccepted].
+const duplicateResponseBody = "duplicate delivery\n"
+
+func (p DuplicatePolicy) statusCode() int {
+	switch p {
+	case DuplicateConflict:
+		return http.StatusConflict
+	case DuplicateAccepted:
+		return http.StatusAccepted
+	}
+	return http.StatusOK
+}
+
+// isDuplicate returns true if the delivery has already been received.
+func (h *Handler) isDuplicate(ctx context.Context, md *DeliveryMetadata) (bool, error) {
+	if h.Dedup == nil {
+		return false, nil
+	}
+	seen, err := h.Dedup.MarkSeen(ctx, md.DeliveryID)
+	if err != nil {
+		return false, fmt.Errorf("dedup: %w", err)
+	}
+	return seen, nil
+}
+
+// forgetDuplicate forgets a delivery that failed, so it can be redelivered.
+func (h *Handler) forgetDuplicate(ctx context.Context, md *DeliveryMetadata) error {
+	if h.Dedup == nil {
+		return nil
+	}
+	err := h.Dedup.Forget(ctx, md.DeliveryID)
+	if err != nil {
+		return fmt.Errorf("dedup: %w", err)
+	}
+	return nil
+}
+
+/*
+MemoryDedupStore is an in-memory [DedupStore].
+
+It keeps a bounded number of delivery IDs (the least recently seen are evicted), for a limited duration.
+It must be created with [NewMemoryDedupStore].
+*/
+type MemoryDedupStore struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type memoryDedupEntry struct {
+	deliveryID string
+	expiration time.Time
+}
+
+// NewMemoryDedupStore creates a new [MemoryDedupStore].
+//
+// size is the maximum number of delivery IDs (minimum 1).
+// If ttl is 0, delivery IDs are only evicted when the store is full.
+func NewMemoryDedupStore(size int, ttl time.Duration) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		size:    max(size, 1),
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// MarkSeen implements [DedupStore].
+func (s *MemoryDedupStore) MarkSeen(ctx context.Context, deliveryID string) (bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[deliveryID]; ok {
+		e := el.Value.(*memoryDedupEntry) //nolint:forcetypeassert // The list only contains this type.
+		if s.ttl <= 0 || now.Before(e.expiration) {
+			s.lru.MoveToFront(el)
+			return true, nil
+		}
+		s.remove(el)
+	}
+	e := &memoryDedupEntry{
+		deliveryID: deliveryID,
+	}
+	if s.ttl > 0 {
+		e.expiration = now.Add(s.ttl)
+	}
+	s.entries[deliveryID] = s.lru.PushFront(e)
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+	return false, nil
+}
+
+// Forget implements [DedupStore].
+func (s *MemoryDedupStore) Forget(ctx context.Context, deliveryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[deliveryID]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+// ListSeen implements [DedupLister].
+//
+// The delivery IDs are sorted from the most recently seen to the least recently seen.
+// The expiration is zero if the store has no TTL.
+func (s *MemoryDedupStore) ListSeen(ctx context.Context) ([]*DedupEntry, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	es := make([]*DedupEntry, 0, s.lru.Len())
+	for el := s.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*memoryDedupEntry) //nolint:forcetypeassert // The list only contains this type.
+		if s.ttl > 0 && !now.Before(e.expiration) {
+			continue
+		}
+		es = append(es, &DedupEntry{
+			DeliveryID: e.deliveryID,
+			Expiration: e.expiration,
+		})
+	}
+	return es, nil
+}
+
+func (s *MemoryDedupStore) remove(el *list.Element) {
+	e := s.lru.Remove(el).(*memoryDedupEntry) //nolint:forcetypeassert // The list only contains this type.
+	delete(s.entries, e.deliveryID)
+}
@@ -0,0 +1,184 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerDedup(t *testing.T) {
+	ctx := context.Background()
+	count := 0
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			count++
+			return nil
+		},
+		Dedup: NewMemoryDedupStore(10, 0),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	deliveryID := req.Header.Get("X-GitHub-Delivery")
+	for range 2 {
+		req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		testExpectResponseStatusOK(t, resp)
+	}
+	assert.Equal(t, count, 1)
+}
+
+func TestHandlerDuplicatePolicy(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name         string
+		policy       DuplicatePolicy
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Ack",
+			policy:       DuplicateAck,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Conflict",
+			policy:       DuplicateConflict,
+			expectedCode: http.StatusConflict,
+			expectedBody: "duplicate delivery\n",
+		},
+		{
+			name:         "Accepted",
+			policy:       DuplicateAccepted,
+			expectedCode: http.StatusAccepted,
+			expectedBody: "duplicate delivery\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var errorCalled bool
+			h := &Handler{
+				Dedup:           NewMemoryDedupStore(10, 0),
+				DuplicatePolicy: tc.policy,
+				Error: func(ctx context.Context, err error, req *http.Request) {
+					errorCalled = true
+				},
+			}
+			req, err := new(Signer).NewRequest(ctx, "/", "push", "test", testRawPayload)
+			assert.NoError(t, err)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, http.StatusOK)
+			assert.Equal(t, w.Body.String(), "")
+			req, err = new(Signer).NewRequest(ctx, "/", "push", "test", testRawPayload)
+			assert.NoError(t, err)
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, tc.expectedCode)
+			assert.Equal(t, w.Body.String(), tc.expectedBody)
+			assert.False(t, errorCalled)
+			statusCode, err := h.Process(ctx, &DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+			assert.NoError(t, err)
+			assert.Equal(t, statusCode, tc.expectedCode)
+		})
+	}
+}
+
+func TestHandlerDedupDeliveryError(t *testing.T) {
+	ctx := context.Background()
+	count := 0
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			count++
+			if count == 1 {
+				return errors.New("error")
+			}
+			return nil
+		},
+		Dedup: NewMemoryDedupStore(10, 0),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	deliveryID := req.Header.Get("X-GitHub-Delivery")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusInternalServerError)
+	req = testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("X-GitHub-Delivery", deliveryID)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, count, 2)
+}
+
+func TestMemoryDedupStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(2, 0)
+	for _, id := range []string{"a", "b", "c"} {
+		seen, err := s.MarkSeen(ctx, id)
+		assert.NoError(t, err)
+		assert.False(t, seen)
+	}
+	seen, err := s.MarkSeen(ctx, "c")
+	assert.NoError(t, err)
+	assert.True(t, seen)
+	seen, err = s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+}
+
+func TestMemoryDedupStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(10, time.Nanosecond)
+	seen, err := s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	time.Sleep(time.Millisecond)
+	seen, err = s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+}
+
+func TestMemoryDedupStoreForget(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(10, 0)
+	_, err := s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	err = s.Forget(ctx, "a")
+	assert.NoError(t, err)
+	seen, err := s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+}
+
+func TestMemoryDedupStoreListSeen(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(10, time.Hour)
+	for _, id := range []string{"a", "b"} {
+		_, err := s.MarkSeen(ctx, id)
+		assert.NoError(t, err)
+	}
+	es, err := s.ListSeen(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, es, 2)
+	assert.Equal(t, es[0].DeliveryID, "b")
+	assert.Equal(t, es[1].DeliveryID, "a")
+	assert.False(t, es[0].Expiration.IsZero())
+	s = NewMemoryDedupStore(10, time.Nanosecond)
+	_, err = s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	es, err = s.ListSeen(ctx)
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, es)
+}
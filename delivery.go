@@ -0,0 +1,70 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// DeliveryHandler handles a delivery.
+//
+// If it returns an error, the delivery is rejected (see [Handler.Delivery]).
+type DeliveryHandler func(ctx context.Context, md *DeliveryMetadata, payload any) error
+
+// DeliveryMiddleware wraps a [DeliveryHandler].
+type DeliveryMiddleware func(next DeliveryHandler) DeliveryHandler
+
+// DeliveryPredicate returns true if a delivery matches.
+type DeliveryPredicate func(ctx context.Context, md *DeliveryMetadata, payload any) bool
+
+// Chain returns a [DeliveryMiddleware] that applies the middlewares in order.
+//
+// The first middleware is the outermost.
+func Chain(middlewares ...DeliveryMiddleware) DeliveryMiddleware {
+	return func(next DeliveryHandler) DeliveryHandler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// Tee returns a [DeliveryHandler] that calls all handlers in order.
+//
+// All handlers are called, even if one of them returns an error, and the errors are joined.
+func Tee(handlers ...DeliveryHandler) DeliveryHandler {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		var errs []error
+		for _, h := range handlers {
+			err := h(ctx, md, payload)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// If returns a [DeliveryHandler] that calls the handler only if the predicate matches.
+func If(predicate DeliveryPredicate, h DeliveryHandler) DeliveryHandler {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		if predicate(ctx, md, payload) {
+			return h(ctx, md, payload)
+		}
+		return nil
+	}
+}
+
+// Filter returns a [DeliveryMiddleware] that calls the next handler only if the predicate matches.
+func Filter(predicate DeliveryPredicate) DeliveryMiddleware {
+	return func(next DeliveryHandler) DeliveryHandler {
+		return If(predicate, next)
+	}
+}
+
+// EventIs returns a [DeliveryPredicate] that matches deliveries of the given events.
+func EventIs(events ...string) DeliveryPredicate {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) bool {
+		return slices.Contains(events, md.Event)
+	}
+}
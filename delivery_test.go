@@ -0,0 +1,81 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	newMiddleware := func(name string) DeliveryMiddleware {
+		return func(next DeliveryHandler) DeliveryHandler {
+			return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+				calls = append(calls, name)
+				return next(ctx, md, payload)
+			}
+		}
+	}
+	h := Chain(newMiddleware("a"), newMiddleware("b"))(func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push"}, nil))
+	assert.SliceEqual(t, calls, []string{"a", "b", "handler"})
+}
+
+func TestTee(t *testing.T) {
+	ctx := context.Background()
+	count := 0
+	inc := func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		count++
+		return nil
+	}
+	assert.NoError(t, Tee(inc, inc, inc)(ctx, &DeliveryMetadata{Event: "push"}, nil))
+	assert.Equal(t, count, 3)
+}
+
+func TestTeeError(t *testing.T) {
+	ctx := context.Background()
+	count := 0
+	errA := errors.New("a")
+	errB := errors.New("b")
+	newHandler := func(err error) DeliveryHandler {
+		return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			count++
+			return err
+		}
+	}
+	err := Tee(newHandler(errA), newHandler(nil), newHandler(errB))(ctx, &DeliveryMetadata{Event: "push"}, nil)
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errB)
+	assert.Equal(t, count, 3)
+}
+
+func TestIf(t *testing.T) {
+	ctx := context.Background()
+	count := 0
+	h := If(EventIs("push"), func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		count++
+		return nil
+	})
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push"}, nil))
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "issues"}, nil))
+	assert.Equal(t, count, 1)
+}
+
+func TestFilter(t *testing.T) {
+	ctx := context.Background()
+	count := 0
+	h := Filter(EventIs("push", "issues"))(func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		count++
+		return nil
+	})
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push"}, nil))
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "issues"}, nil))
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "release"}, nil))
+	assert.Equal(t, count, 2)
+}
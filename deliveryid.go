@@ -0,0 +1,62 @@
+package githubhook
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// DeliveryIDGenerator generates the delivery ID of a synthetic delivery (e.g. self-test, replay, test sender).
+//
+// See [RandomDeliveryID], [TimeOrderedDeliveryID] and [ContentDeliveryID].
+type DeliveryIDGenerator func(event string, rawPayload []byte) (string, error)
+
+// NewDeliveryID returns a new random delivery ID (a version 4 UUID, like GitHub).
+func NewDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("random delivery ID: %w", err)
+	}
+	return formatUUID(b, 4), nil
+}
+
+// RandomDeliveryID is a [DeliveryIDGenerator] that returns a random version 4 UUID (see [NewDeliveryID]).
+func RandomDeliveryID(event string, rawPayload []byte) (string, error) {
+	return NewDeliveryID()
+}
+
+// TimeOrderedDeliveryID is a [DeliveryIDGenerator] that returns a version 7 UUID, so delivery IDs are sorted by creation time.
+func TimeOrderedDeliveryID(event string, rawPayload []byte) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b[6:])
+	if err != nil {
+		return "", fmt.Errorf("random delivery ID: %w", err)
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli())) //nolint:gosec // The time is positive.
+	copy(b[:6], ms[2:])
+	return formatUUID(b, 7), nil
+}
+
+// ContentDeliveryID is a [DeliveryIDGenerator] that returns a version 8 UUID derived from the SHA-256 hash of the event and payload.
+//
+// The same delivery always has the same ID, so replays are traceable and deduplicated.
+func ContentDeliveryID(event string, rawPayload []byte) (string, error) {
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(event))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write(rawPayload)
+	return formatUUID(hash.Sum(nil)[:16], 8), nil
+}
+
+// formatUUID sets the version and variant bits of a 16 bytes UUID, and formats it.
+func formatUUID(b []byte, version byte) string {
+	b[6] = (b[6] & 0x0f) | version<<4
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
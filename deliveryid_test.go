@@ -0,0 +1,63 @@
+package githubhook
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func testUUIDRegexp(version string) *regexp.Regexp {
+	return regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-` + version + `[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+}
+
+func TestNewDeliveryID(t *testing.T) {
+	id, err := NewDeliveryID()
+	assert.NoError(t, err)
+	assert.RegexpMatch(t, testUUIDRegexp("4"), id)
+}
+
+func TestRandomDeliveryID(t *testing.T) {
+	id1, err := RandomDeliveryID("push", testRawPayload)
+	assert.NoError(t, err)
+	id2, err := RandomDeliveryID("push", testRawPayload)
+	assert.NoError(t, err)
+	assert.RegexpMatch(t, testUUIDRegexp("4"), id1)
+	assert.NotEqual(t, id1, id2)
+}
+
+func TestTimeOrderedDeliveryID(t *testing.T) {
+	id1, err := TimeOrderedDeliveryID("push", testRawPayload)
+	assert.NoError(t, err)
+	assert.RegexpMatch(t, testUUIDRegexp("7"), id1)
+}
+
+func TestContentDeliveryID(t *testing.T) {
+	id1, err := ContentDeliveryID("push", testRawPayload)
+	assert.NoError(t, err)
+	id2, err := ContentDeliveryID("push", testRawPayload)
+	assert.NoError(t, err)
+	id3, err := ContentDeliveryID("issues", testRawPayload)
+	assert.NoError(t, err)
+	assert.RegexpMatch(t, testUUIDRegexp("8"), id1)
+	assert.Equal(t, id1, id2)
+	assert.NotEqual(t, id1, id3)
+}
+
+func TestHandlerGenerateDeliveryID(t *testing.T) {
+	ctx := context.Background()
+	var deliveryID string
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryID = md.DeliveryID
+			return nil
+		},
+		GenerateDeliveryID: ContentDeliveryID,
+	}
+	err := h.SelfTest(ctx)
+	assert.NoError(t, err)
+	expected, err := ContentDeliveryID("ping", selfTestRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, deliveryID, expected)
+}
@@ -0,0 +1,165 @@
+package githubhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PayloadDifferenceType is the type of a [PayloadDifference].
+type PayloadDifferenceType string
+
+// PayloadDifferenceType values.
+const (
+	PayloadDifferenceAdded   PayloadDifferenceType = "added"
+	PayloadDifferenceRemoved PayloadDifferenceType = "removed"
+	PayloadDifferenceChanged PayloadDifferenceType = "changed"
+)
+
+// PayloadDifference is a difference between 2 payloads.
+//
+// Path is the location of the value, e.g. "pull_request.labels[0].name".
+// Old is nil if the value was added, New is nil if the value was removed.
+type PayloadDifference struct {
+	Type PayloadDifferenceType
+	Path string
+	Old  any
+	New  any
+}
+
+func (d PayloadDifference) String() string {
+	switch d.Type {
+	case PayloadDifferenceAdded:
+		return fmt.Sprintf("+ %s: %v", d.Path, d.New)
+	case PayloadDifferenceRemoved:
+		return fmt.Sprintf("- %s: %v", d.Path, d.Old)
+	default:
+		return fmt.Sprintf("~ %s: %v => %v", d.Path, d.Old, d.New)
+	}
+}
+
+/*
+DiffPayloadsOptions are the options of [DiffPayloads].
+
+Fields (all are optional):
+  - IncludeTimestamps disables the suppression of timestamps. By default, the values of keys ending with "_at" or named "timestamp" are ignored.
+  - Ignore is called for each path, and the path is ignored if it returns true.
+*/
+type DiffPayloadsOptions struct {
+	IncludeTimestamps bool
+	Ignore            func(path string) bool
+}
+
+// DiffPayloads returns the differences between 2 raw JSON payloads (e.g. consecutive "pull_request" deliveries).
+//
+// The differences are sorted by path.
+// The options can be nil.
+func DiffPayloads(oldRawPayload, newRawPayload []byte, opts *DiffPayloadsOptions) ([]PayloadDifference, error) {
+	if opts == nil {
+		opts = new(DiffPayloadsOptions)
+	}
+	var oldPayload, newPayload any
+	err := json.Unmarshal(oldRawPayload, &oldPayload)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal old: %w", err)
+	}
+	err = json.Unmarshal(newRawPayload, &newPayload)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal new: %w", err)
+	}
+	var diffs []PayloadDifference
+	diffPayloadValues(opts, "", oldPayload, newPayload, &diffs)
+	return diffs, nil
+}
+
+func diffPayloadValues(opts *DiffPayloadsOptions, path string, oldV, newV any, diffs *[]PayloadDifference) {
+	if opts.Ignore != nil && opts.Ignore(path) {
+		return
+	}
+	switch oldT := oldV.(type) {
+	case map[string]any:
+		if newT, ok := newV.(map[string]any); ok {
+			diffPayloadMaps(opts, path, oldT, newT, diffs)
+			return
+		}
+	case []any:
+		if newT, ok := newV.([]any); ok {
+			diffPayloadSlices(opts, path, oldT, newT, diffs)
+			return
+		}
+	}
+	if !reflect.DeepEqual(oldV, newV) {
+		*diffs = append(*diffs, PayloadDifference{
+			Type: PayloadDifferenceChanged,
+			Path: path,
+			Old:  oldV,
+			New:  newV,
+		})
+	}
+}
+
+func diffPayloadMaps(opts *DiffPayloadsOptions, path string, oldM, newM map[string]any, diffs *[]PayloadDifference) {
+	keys := slices.Sorted(maps.Keys(oldM))
+	for k := range newM {
+		if _, ok := oldM[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if !opts.IncludeTimestamps && isPayloadTimestampKey(k) {
+			continue
+		}
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		oldV, oldOK := oldM[k]
+		newV, newOK := newM[k]
+		diffPayloadEntry(opts, p, oldV, oldOK, newV, newOK, diffs)
+	}
+}
+
+func diffPayloadSlices(opts *DiffPayloadsOptions, path string, oldS, newS []any, diffs *[]PayloadDifference) {
+	for i := range max(len(oldS), len(newS)) {
+		p := path + "[" + strconv.Itoa(i) + "]"
+		var oldV, newV any
+		oldOK := i < len(oldS)
+		if oldOK {
+			oldV = oldS[i]
+		}
+		newOK := i < len(newS)
+		if newOK {
+			newV = newS[i]
+		}
+		diffPayloadEntry(opts, p, oldV, oldOK, newV, newOK, diffs)
+	}
+}
+
+func diffPayloadEntry(opts *DiffPayloadsOptions, path string, oldV any, oldOK bool, newV any, newOK bool, diffs *[]PayloadDifference) {
+	switch {
+	case oldOK && newOK:
+		diffPayloadValues(opts, path, oldV, newV, diffs)
+	case opts.Ignore != nil && opts.Ignore(path):
+	case oldOK:
+		*diffs = append(*diffs, PayloadDifference{
+			Type: PayloadDifferenceRemoved,
+			Path: path,
+			Old:  oldV,
+		})
+	default:
+		*diffs = append(*diffs, PayloadDifference{
+			Type: PayloadDifferenceAdded,
+			Path: path,
+			New:  newV,
+		})
+	}
+}
+
+func isPayloadTimestampKey(k string) bool {
+	return strings.HasSuffix(k, "_at") || k == "timestamp"
+}
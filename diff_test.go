@@ -0,0 +1,48 @@
+package githubhook
+
+import (
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestDiffPayloads(t *testing.T) {
+	oldRawPayload := []byte(`{
+		"action": "synchronize",
+		"pull_request": {"updated_at": "2024-01-01T00:00:00Z", "head": {"sha": "a"}, "labels": [{"name": "bug"}], "draft": true}
+	}`)
+	newRawPayload := []byte(`{
+		"action": "synchronize",
+		"pull_request": {"updated_at": "2024-01-02T00:00:00Z", "head": {"sha": "b"}, "labels": [{"name": "bug"}, {"name": "ci"}], "merged": false}
+	}`)
+	diffs, err := DiffPayloads(oldRawPayload, newRawPayload, nil)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, diffs, []PayloadDifference{
+		{Type: PayloadDifferenceRemoved, Path: "pull_request.draft", Old: true},
+		{Type: PayloadDifferenceChanged, Path: "pull_request.head.sha", Old: "a", New: "b"},
+		{Type: PayloadDifferenceAdded, Path: "pull_request.labels[1]", New: map[string]any{"name": "ci"}},
+		{Type: PayloadDifferenceAdded, Path: "pull_request.merged", New: false},
+	})
+}
+
+func TestDiffPayloadsOptions(t *testing.T) {
+	oldRawPayload := []byte(`{"updated_at": "2024-01-01T00:00:00Z", "sha": "a"}`)
+	newRawPayload := []byte(`{"updated_at": "2024-01-02T00:00:00Z", "sha": "b"}`)
+	diffs, err := DiffPayloads(oldRawPayload, newRawPayload, &DiffPayloadsOptions{
+		IncludeTimestamps: true,
+		Ignore: func(path string) bool {
+			return path == "sha"
+		},
+	})
+	assert.NoError(t, err)
+	assert.SliceLen(t, diffs, 1)
+	assert.Equal(t, diffs[0].Path, "updated_at")
+	assert.Equal(t, diffs[0].String(), "~ updated_at: 2024-01-01T00:00:00Z => 2024-01-02T00:00:00Z")
+}
+
+func TestDiffPayloadsError(t *testing.T) {
+	_, err := DiffPayloads([]byte("not json"), []byte("{}"), nil)
+	assert.Error(t, err)
+	_, err = DiffPayloads([]byte("{}"), []byte("not json"), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package githubhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/*
+Envelope is a normalized view of a payload, common to all event types.
+
+Fields are empty if they are not available in the payload:
+  - Event is the event name.
+  - Action is the action (e.g. "opened").
+  - Repository is the repository full name (e.g. "octocat/Hello-World").
+  - Owner is the repository owner, or the organization login.
+  - Sender is the login of the user who triggered the event.
+  - InstallationID is the GitHub App installation ID.
+  - ResourceURL is the HTML URL of the main resource (e.g. the pull request), or the repository.
+*/
+type Envelope struct {
+	Event          string
+	Action         string
+	Repository     string
+	Owner          string
+	Sender         string
+	InstallationID int64
+	ResourceURL    string
+}
+
+type envelopeAccount struct {
+	Login string `json:"login"`
+}
+
+type envelopePayload struct {
+	Action     string `json:"action"`
+	Repository *struct {
+		FullName string           `json:"full_name"`
+		HTMLURL  string           `json:"html_url"`
+		Owner    *envelopeAccount `json:"owner"`
+	} `json:"repository"`
+	Organization *envelopeAccount `json:"organization"`
+	Sender       *envelopeAccount `json:"sender"`
+	Installation *struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+	Compare string `json:"compare"`
+}
+
+// envelopeResourceKeys are the payload keys of the main resources, sorted by priority.
+var envelopeResourceKeys = []string{
+	"pull_request",
+	"issue",
+	"comment",
+	"review",
+	"release",
+	"discussion",
+	"workflow_run",
+	"workflow_job",
+	"check_run",
+	"check_suite",
+	"deployment",
+	"deployment_status",
+	"alert",
+	"package",
+}
+
+// ParseEnvelope extracts an [Envelope] from a raw JSON payload.
+func ParseEnvelope(event string, rawPayload []byte) (*Envelope, error) {
+	var p envelopePayload
+	err := json.Unmarshal(rawPayload, &p)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal: %w", err)
+	}
+	env := &Envelope{
+		Event:  event,
+		Action: p.Action,
+	}
+	if p.Repository != nil {
+		env.Repository = p.Repository.FullName
+		env.ResourceURL = p.Repository.HTMLURL
+		if p.Repository.Owner != nil {
+			env.Owner = p.Repository.Owner.Login
+		}
+	}
+	if env.Owner == "" && p.Organization != nil {
+		env.Owner = p.Organization.Login
+	}
+	if p.Sender != nil {
+		env.Sender = p.Sender.Login
+	}
+	if p.Installation != nil {
+		env.InstallationID = p.Installation.ID
+	}
+	if p.Compare != "" {
+		env.ResourceURL = p.Compare
+	}
+	resourceURL, err := getEnvelopeResourceURL(rawPayload)
+	if err != nil {
+		return nil, err
+	}
+	if resourceURL != "" {
+		env.ResourceURL = resourceURL
+	}
+	return env, nil
+}
+
+func getEnvelopeResourceURL(rawPayload []byte) (string, error) {
+	var m map[string]json.RawMessage
+	err := json.Unmarshal(rawPayload, &m)
+	if err != nil {
+		return "", fmt.Errorf("JSON unmarshal: %w", err)
+	}
+	for _, k := range envelopeResourceKeys {
+		v, ok := m[k]
+		if !ok {
+			continue
+		}
+		var r struct {
+			HTMLURL string `json:"html_url"`
+		}
+		err = json.Unmarshal(v, &r)
+		if err != nil || r.HTMLURL == "" {
+			continue
+		}
+		return r.HTMLURL, nil
+	}
+	return "", nil
+}
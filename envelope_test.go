@@ -0,0 +1,49 @@
+package githubhook
+
+import (
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestParseEnvelope(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "opened",
+		"pull_request": {"html_url": "https://github.com/octocat/Hello-World/pull/1"},
+		"repository": {"full_name": "octocat/Hello-World", "html_url": "https://github.com/octocat/Hello-World", "owner": {"login": "octocat"}},
+		"sender": {"login": "monalisa"},
+		"installation": {"id": 123}
+	}`)
+	env, err := ParseEnvelope("pull_request", rawPayload)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, env, &Envelope{
+		Event:          "pull_request",
+		Action:         "opened",
+		Repository:     "octocat/Hello-World",
+		Owner:          "octocat",
+		Sender:         "monalisa",
+		InstallationID: 123,
+		ResourceURL:    "https://github.com/octocat/Hello-World/pull/1",
+	})
+}
+
+func TestParseEnvelopePush(t *testing.T) {
+	rawPayload := []byte(`{
+		"compare": "https://github.com/octocat/Hello-World/compare/a...b",
+		"repository": {"full_name": "octocat/Hello-World", "html_url": "https://github.com/octocat/Hello-World"},
+		"organization": {"login": "github"}
+	}`)
+	env, err := ParseEnvelope("push", rawPayload)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, env, &Envelope{
+		Event:       "push",
+		Repository:  "octocat/Hello-World",
+		Owner:       "github",
+		ResourceURL: "https://github.com/octocat/Hello-World/compare/a...b",
+	})
+}
+
+func TestParseEnvelopeError(t *testing.T) {
+	_, err := ParseEnvelope("push", []byte("not json"))
+	assert.Error(t, err)
+}
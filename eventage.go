@@ -0,0 +1,112 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pierrre/githubhook/events"
+)
+
+// PayloadTime returns the time of the event, from the timestamps of the payload.
+//
+// It supports the payloads of the [events] package that carry a timestamp (e.g. the push time of the repository, the update of a pull request).
+// It returns false if the payload doesn't carry a timestamp.
+//
+// The time of a push is the "pushed_at" time of the repository, not the time of the head commit, which can be much older than the push (e.g. a branch pushed long after it was committed).
+func PayloadTime(payload any) (time.Time, bool) {
+	var t time.Time
+	switch p := payload.(type) {
+	case *events.PushEvent:
+		if p.Repository != nil {
+			t = p.Repository.PushedAt.Time
+		}
+	case *events.PullRequestEvent:
+		if p.PullRequest != nil {
+			t = p.PullRequest.UpdatedAt.Time
+		}
+	case *events.PullRequestReviewEvent:
+		if p.Review != nil {
+			t = p.Review.SubmittedAt.Time
+		}
+	case *events.IssuesEvent:
+		if p.Issue != nil {
+			t = p.Issue.UpdatedAt.Time
+		}
+	case *events.IssueCommentEvent:
+		if p.Comment != nil {
+			t = p.Comment.UpdatedAt.Time
+		}
+	case *events.ReleaseEvent:
+		if p.Release != nil {
+			t = p.Release.CreatedAt.Time
+			if p.Release.PublishedAt != nil {
+				t = p.Release.PublishedAt.Time
+			}
+		}
+	case *events.StarEvent:
+		if p.StarredAt != nil {
+			t = p.StarredAt.Time
+		}
+	case *events.StatusEvent:
+		t = p.UpdatedAt.Time
+	case *events.WorkflowRunEvent:
+		if p.WorkflowRun != nil {
+			t = p.WorkflowRun.UpdatedAt.Time
+		}
+	case *events.WorkflowJobEvent:
+		if p.WorkflowJob != nil {
+			t = p.WorkflowJob.StartedAt.Time
+			if p.WorkflowJob.CompletedAt != nil {
+				t = p.WorkflowJob.CompletedAt.Time
+			}
+		}
+	case *events.CheckRunEvent:
+		if p.CheckRun != nil {
+			t = p.CheckRun.StartedAt.Time
+			if p.CheckRun.CompletedAt != nil {
+				t = p.CheckRun.CompletedAt.Time
+			}
+		}
+	case *events.CheckSuiteEvent:
+		if p.CheckSuite != nil {
+			t = p.CheckSuite.UpdatedAt.Time
+		}
+	}
+	return t, !t.IsZero()
+}
+
+// ErrStaleEvent is returned by [MaxEventAge] for the stale events, if there is no stale handler.
+var ErrStaleEvent = errors.New("stale event")
+
+// MaxEventAge returns a [DeliveryMiddleware] that guards against stale events (e.g. arriving via a late redelivery), to prevent them from triggering actions like deploys.
+//
+// The age of an event is the duration between [PayloadTime] and [DeliveryMetadata.ReceivedAt].
+// Deliveries older than maxAge are passed to the stale handler instead of the next handler.
+// If stale is nil, an error wrapping [ErrStaleEvent] is returned, so the stale deliveries are not dropped silently.
+// The stale handler can flag the delivery (e.g. log it), drop it (return nil), or call the next handler anyway.
+//
+// Deliveries without timestamp are always passed to the next handler.
+func MaxEventAge(maxAge time.Duration, stale DeliveryHandler) DeliveryMiddleware {
+	return func(next DeliveryHandler) DeliveryHandler {
+		return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			t, ok := PayloadTime(payload)
+			if !ok {
+				return next(ctx, md, payload)
+			}
+			receivedAt := md.ReceivedAt
+			if receivedAt.IsZero() {
+				receivedAt = time.Now()
+			}
+			age := receivedAt.Sub(t)
+			if age <= maxAge {
+				return next(ctx, md, payload)
+			}
+			if stale != nil {
+				return stale(ctx, md, payload)
+			}
+			return fmt.Errorf("%w: age %s > %s", ErrStaleEvent, age, maxAge)
+		}
+	}
+}
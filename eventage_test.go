@@ -0,0 +1,143 @@
+package githubhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestPayloadTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := events.Timestamp{Time: now}
+	for _, tc := range []struct {
+		name     string
+		payload  any
+		expected time.Time
+	}{
+		{
+			name: "Push",
+			payload: &events.PushEvent{
+				Common: events.Common{
+					Repository: &events.Repository{PushedAt: ts},
+				},
+				HeadCommit: &events.PushCommit{Timestamp: events.Timestamp{Time: now.Add(-24 * time.Hour)}},
+			},
+			expected: now,
+		},
+		{
+			name:    "PushWithoutRepository",
+			payload: &events.PushEvent{},
+		},
+		{
+			name: "PullRequest",
+			payload: &events.PullRequestEvent{
+				PullRequest: &events.PullRequest{UpdatedAt: ts},
+			},
+			expected: now,
+		},
+		{
+			name: "ReleasePublished",
+			payload: &events.ReleaseEvent{
+				Release: &events.Release{
+					CreatedAt:   events.Timestamp{Time: now.Add(-time.Hour)},
+					PublishedAt: &ts,
+				},
+			},
+			expected: now,
+		},
+		{
+			name: "CheckRunCompleted",
+			payload: &events.CheckRunEvent{
+				CheckRun: &events.CheckRun{
+					StartedAt:   events.Timestamp{Time: now.Add(-time.Hour)},
+					CompletedAt: &ts,
+				},
+			},
+			expected: now,
+		},
+		{
+			name:    "Unsupported",
+			payload: &events.PingEvent{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tm, ok := PayloadTime(tc.payload)
+			assert.Equal(t, ok, !tc.expected.IsZero())
+			assert.Equal(t, tm, tc.expected)
+		})
+	}
+}
+
+func TestMaxEventAge(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPayload := func(age time.Duration) any {
+		return &events.PullRequestEvent{
+			PullRequest: &events.PullRequest{
+				UpdatedAt: events.Timestamp{Time: now.Add(-age)},
+			},
+		}
+	}
+	md := &DeliveryMetadata{
+		Event:      "pull_request",
+		ReceivedAt: now,
+	}
+	for _, tc := range []struct {
+		name          string
+		payload       any
+		stale         bool
+		expectedNext  int
+		expectedStale int
+		expectedErr   error
+	}{
+		{
+			name:         "Fresh",
+			payload:      newPayload(time.Minute),
+			stale:        true,
+			expectedNext: 1,
+		},
+		{
+			name:          "Stale",
+			payload:       newPayload(2 * time.Hour),
+			stale:         true,
+			expectedStale: 1,
+		},
+		{
+			name:        "StaleError",
+			payload:     newPayload(2 * time.Hour),
+			expectedErr: ErrStaleEvent,
+		},
+		{
+			name:         "WithoutTimestamp",
+			payload:      &events.PingEvent{},
+			stale:        true,
+			expectedNext: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var nextCalls, staleCalls int
+			var stale DeliveryHandler
+			if tc.stale {
+				stale = func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+					staleCalls++
+					return nil
+				}
+			}
+			h := MaxEventAge(time.Hour, stale)(func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+				nextCalls++
+				return nil
+			})
+			err := h(ctx, md, tc.payload)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, nextCalls, tc.expectedNext)
+			assert.Equal(t, staleCalls, tc.expectedStale)
+		})
+	}
+}
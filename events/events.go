@@ -0,0 +1,99 @@
+// Package events provides Go types for the common GitHub webhook payloads.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Event names of the known payload types.
+const (
+	NameCheckRun            = "check_run"
+	NameCheckSuite          = "check_suite"
+	NameCodeScanningAlert   = "code_scanning_alert"
+	NameCreate              = "create"
+	NameDelete              = "delete"
+	NameFork                = "fork"
+	NameIssueComment        = "issue_comment"
+	NameIssues              = "issues"
+	NameMarketplacePurchase = "marketplace_purchase"
+	NamePing                = "ping"
+	NamePullRequest         = "pull_request"
+	NamePullRequestReview   = "pull_request_review"
+	NamePush                = "push"
+	NameRelease             = "release"
+	NameRepository          = "repository"
+	NameSecretScanningAlert = "secret_scanning_alert"
+	NameSecurityAdvisory    = "security_advisory"
+	NameSponsorship         = "sponsorship"
+	NameStar                = "star"
+	NameStatus              = "status"
+	NameWorkflowJob         = "workflow_job"
+	NameWorkflowRun         = "workflow_run"
+)
+
+var newPayloadFuncs = map[string]func() any{
+	NameCheckRun:            func() any { return new(CheckRunEvent) },
+	NameCheckSuite:          func() any { return new(CheckSuiteEvent) },
+	NameCodeScanningAlert:   func() any { return new(CodeScanningAlertEvent) },
+	NameCreate:              func() any { return new(CreateEvent) },
+	NameDelete:              func() any { return new(DeleteEvent) },
+	NameFork:                func() any { return new(ForkEvent) },
+	NameIssueComment:        func() any { return new(IssueCommentEvent) },
+	NameIssues:              func() any { return new(IssuesEvent) },
+	NameMarketplacePurchase: func() any { return new(MarketplacePurchaseEvent) },
+	NamePing:                func() any { return new(PingEvent) },
+	NamePullRequest:         func() any { return new(PullRequestEvent) },
+	NamePullRequestReview:   func() any { return new(PullRequestReviewEvent) },
+	NamePush:                func() any { return new(PushEvent) },
+	NameRelease:             func() any { return new(ReleaseEvent) },
+	NameRepository:          func() any { return new(RepositoryEvent) },
+	NameSecretScanningAlert: func() any { return new(SecretScanningAlertEvent) },
+	NameSecurityAdvisory:    func() any { return new(SecurityAdvisoryEvent) },
+	NameSponsorship:         func() any { return new(SponsorshipEvent) },
+	NameStar:                func() any { return new(StarEvent) },
+	NameStatus:              func() any { return new(StatusEvent) },
+	NameWorkflowJob:         func() any { return new(WorkflowJobEvent) },
+	NameWorkflowRun:         func() any { return new(WorkflowRunEvent) },
+}
+
+// New returns a pointer to a new payload value for the event (e.g. *[PushEvent] for "push").
+//
+// It returns nil if the event is not known.
+func New(event string) any {
+	f, ok := newPayloadFuncs[event]
+	if !ok {
+		return nil
+	}
+	return f()
+}
+
+// Known returns the sorted names of the events that have a payload type.
+func Known() []string {
+	return slices.Sorted(maps.Keys(newPayloadFuncs))
+}
+
+// Decode decodes a raw JSON payload.
+//
+// If the event is known, it returns a pointer to the matching type (see [New]).
+// Otherwise it returns the generic decoded value (usually a map[string]any).
+func Decode(event string, rawPayload []byte) (any, error) {
+	payload := New(event)
+	if payload == nil {
+		var v any
+		err := json.Unmarshal(rawPayload, &v)
+		if err != nil {
+			return nil, fmt.Errorf("JSON unmarshal: %w", err)
+		}
+		return v, nil
+	}
+	err := json.Unmarshal(rawPayload, payload)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal %T: %w", payload, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,196 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestDecodePush(t *testing.T) {
+	rawPayload := []byte(`{
+		"ref": "refs/heads/main",
+		"after": "abc",
+		"repository": {"full_name": "octocat/Hello-World", "created_at": 1700000000, "pushed_at": 1700000001},
+		"head_commit": {"id": "abc", "timestamp": "2024-01-01T00:00:00Z"},
+		"sender": {"login": "octocat"}
+	}`)
+	payload, err := Decode("push", rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*PushEvent](t, payload)
+	assert.Equal(t, ev.Ref, "refs/heads/main")
+	assert.Equal(t, ev.Repository.FullName, "octocat/Hello-World")
+	assert.True(t, ev.Repository.CreatedAt.Equal(time.Unix(1700000000, 0)))
+	assert.True(t, ev.HeadCommit.Timestamp.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, ev.Sender.Login, "octocat")
+}
+
+func TestDecodePullRequest(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "opened",
+		"number": 1,
+		"pull_request": {"number": 1, "head": {"sha": "abc"}, "created_at": "2024-01-01T00:00:00Z", "merged_at": null}
+	}`)
+	payload, err := Decode("pull_request", rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*PullRequestEvent](t, payload)
+	assert.Equal(t, ev.Action, "opened")
+	assert.Equal(t, ev.PullRequest.Head.SHA, "abc")
+	assert.Zero(t, ev.PullRequest.MergedAt)
+}
+
+func TestDecodeSponsorship(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "created",
+		"sponsorship": {
+			"node_id": "MDExOlNwb25zb3JzaGlwMQ==",
+			"created_at": "2019-12-20T19:24:46+00:00",
+			"sponsorable": {"login": "octocat"},
+			"sponsor": {"login": "monalisa"},
+			"privacy_level": "public",
+			"tier": {"name": "$5 a month", "monthly_price_in_cents": 500, "monthly_price_in_dollars": 5}
+		},
+		"sender": {"login": "monalisa"}
+	}`)
+	payload, err := Decode(NameSponsorship, rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*SponsorshipEvent](t, payload)
+	assert.Equal(t, ev.Action, "created")
+	assert.Equal(t, ev.Sponsorship.Sponsor.Login, "monalisa")
+	assert.Equal(t, ev.Sponsorship.Tier.MonthlyPriceInCents, 500)
+	assert.Zero(t, ev.EffectiveDate)
+}
+
+func TestDecodeMarketplacePurchase(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "purchased",
+		"effective_date": "2017-10-25T00:00:00+00:00",
+		"marketplace_purchase": {
+			"account": {"type": "Organization", "id": 18404719, "login": "octocat"},
+			"billing_cycle": "monthly",
+			"unit_count": 1,
+			"on_free_trial": false,
+			"free_trial_ends_on": null,
+			"next_billing_date": "2017-11-05T00:00:00+00:00",
+			"plan": {"id": 435, "name": "Basic Plan", "monthly_price_in_cents": 1000, "price_model": "per-unit", "bullets": ["Is Basic"]}
+		},
+		"sender": {"login": "octocat"}
+	}`)
+	payload, err := Decode(NameMarketplacePurchase, rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*MarketplacePurchaseEvent](t, payload)
+	assert.Equal(t, ev.Action, "purchased")
+	assert.True(t, ev.EffectiveDate.Equal(time.Date(2017, 10, 25, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, ev.MarketplacePurchase.Account.Login, "octocat")
+	assert.Equal(t, ev.MarketplacePurchase.Plan.Name, "Basic Plan")
+	assert.Zero(t, ev.MarketplacePurchase.FreeTrialEndsOn)
+	assert.Zero(t, ev.PreviousMarketplacePurchase)
+}
+
+func TestDecodeSecurityAdvisory(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "published",
+		"security_advisory": {
+			"ghsa_id": "GHSA-rf4j-j272-fj86",
+			"cve_id": "CVE-2018-6188",
+			"summary": "Moderate severity vulnerability",
+			"severity": "moderate",
+			"identifiers": [{"type": "GHSA", "value": "GHSA-rf4j-j272-fj86"}],
+			"references": [{"url": "https://nvd.nist.gov/vuln/detail/CVE-2018-6188"}],
+			"published_at": "2018-10-03T21:13:54Z",
+			"updated_at": "2018-10-03T21:13:54Z",
+			"withdrawn_at": null,
+			"vulnerabilities": [{
+				"package": {"ecosystem": "pip", "name": "django"},
+				"severity": "moderate",
+				"vulnerable_version_range": ">= 2.0.0, < 2.0.2",
+				"first_patched_version": {"identifier": "2.0.2"}
+			}]
+		}
+	}`)
+	payload, err := Decode(NameSecurityAdvisory, rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*SecurityAdvisoryEvent](t, payload)
+	assert.Equal(t, ev.SecurityAdvisory.GHSAID, "GHSA-rf4j-j272-fj86")
+	assert.SliceLen(t, ev.SecurityAdvisory.Vulnerabilities, 1)
+	assert.Equal(t, ev.SecurityAdvisory.Vulnerabilities[0].Package.Name, "django")
+	assert.Equal(t, ev.SecurityAdvisory.Vulnerabilities[0].FirstPatchedVersion.Identifier, "2.0.2")
+	assert.Zero(t, ev.SecurityAdvisory.WithdrawnAt)
+}
+
+func TestDecodeCodeScanningAlert(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "created",
+		"alert": {
+			"number": 42,
+			"created_at": "2020-11-06T18:18:32Z",
+			"state": "open",
+			"rule": {"id": "js/unused-local-variable", "severity": "note", "tags": ["maintainability"]},
+			"tool": {"name": "CodeQL", "version": "2.4.0"},
+			"most_recent_instance": {"ref": "refs/heads/main", "commit_sha": "abc", "location": {"path": "main.js", "start_line": 3}}
+		},
+		"ref": "refs/heads/main",
+		"commit_oid": "abc",
+		"repository": {"full_name": "octocat/Hello-World"}
+	}`)
+	payload, err := Decode(NameCodeScanningAlert, rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*CodeScanningAlertEvent](t, payload)
+	assert.Equal(t, ev.Alert.Number, 42)
+	assert.Equal(t, ev.Alert.Rule.ID, "js/unused-local-variable")
+	assert.Equal(t, ev.Alert.Tool.Name, "CodeQL")
+	assert.Equal(t, ev.Alert.MostRecentInstance.Location.StartLine, 3)
+	assert.Equal(t, ev.CommitOID, "abc")
+	assert.Equal(t, ev.Repository.FullName, "octocat/Hello-World")
+}
+
+func TestDecodeSecretScanningAlert(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "resolved",
+		"alert": {
+			"number": 7,
+			"created_at": "2022-06-28T11:52:41Z",
+			"state": "resolved",
+			"secret_type": "github_personal_access_token",
+			"secret_type_display_name": "GitHub Personal Access Token",
+			"resolution": "revoked",
+			"resolved_by": {"login": "octocat"},
+			"resolved_at": "2022-06-29T11:52:41Z",
+			"push_protection_bypassed": false
+		}
+	}`)
+	payload, err := Decode(NameSecretScanningAlert, rawPayload)
+	assert.NoError(t, err)
+	ev, _ := assert.Type[*SecretScanningAlertEvent](t, payload)
+	assert.Equal(t, ev.Alert.SecretType, "github_personal_access_token")
+	assert.Equal(t, ev.Alert.Resolution, "revoked")
+	assert.Equal(t, ev.Alert.ResolvedBy.Login, "octocat")
+	assert.True(t, ev.Alert.ResolvedAt.Equal(time.Date(2022, 6, 29, 11, 52, 41, 0, time.UTC)))
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	payload, err := Decode("unknown", []byte(`{"foo":"bar"}`))
+	assert.NoError(t, err)
+	assert.DeepEqual[any](t, payload, map[string]any{"foo": "bar"})
+}
+
+func TestDecodeError(t *testing.T) {
+	_, err := Decode("push", []byte("not json"))
+	assert.Error(t, err)
+	_, err = Decode("unknown", []byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestDecodeErrorTimestamp(t *testing.T) {
+	_, err := Decode("push", []byte(`{"head_commit": {"timestamp": "invalid"}}`))
+	assert.Error(t, err)
+	_, err = Decode("push", []byte(`{"repository": {"created_at": true}}`))
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	for _, event := range Known() {
+		assert.NotZero(t, New(event))
+	}
+	assert.Zero(t, New("unknown"))
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+/*
+FieldDiff is the difference between the fields of a raw payload and the fields of its payload type.
+
+Fields:
+  - Unknown are the fields of the payload that are not in the type, so they are lost when it's decoded.
+  - Missing are the fields of the type that are expected but not in the payload: the fields that are not pointers and don't have the "omitempty" option, in the objects that are present.
+
+The fields are identified by their path, e.g. "pull_request.head.sha", or "commits[].id" for the elements of an array.
+They are sorted and unique.
+*/
+type FieldDiff struct {
+	Unknown []string
+	Missing []string
+}
+
+// CompareFields compares the fields of a raw payload with the fields of the payload type of the event.
+//
+// It returns nil if the event is not known (see [New]).
+func CompareFields(event string, rawPayload []byte) (*FieldDiff, error) {
+	payload := New(event)
+	if payload == nil {
+		return nil, nil //nolint:nilnil // The event is not known.
+	}
+	var v any
+	err := json.Unmarshal(rawPayload, &v)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshal: %w", err)
+	}
+	c := &fieldComparer{
+		unknown: make(map[string]bool),
+		missing: make(map[string]bool),
+	}
+	c.compare("", v, reflect.TypeOf(payload))
+	return &FieldDiff{
+		Unknown: slices.Sorted(maps.Keys(c.unknown)),
+		Missing: slices.Sorted(maps.Keys(c.missing)),
+	}, nil
+}
+
+type fieldComparer struct {
+	unknown map[string]bool
+	missing map[string]bool
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	rawMessageType      = reflect.TypeFor[json.RawMessage]()
+)
+
+func (c *fieldComparer) compare(path string, v any, typ reflect.Type) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == rawMessageType || reflect.PointerTo(typ).Implements(jsonUnmarshalerType) {
+		// The value is decoded by the type itself.
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		if typ.Kind() != reflect.Struct {
+			return
+		}
+		c.compareObject(path, v, typ)
+	case []any:
+		if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+			return
+		}
+		for _, e := range v {
+			c.compare(path+"[]", e, typ.Elem())
+		}
+	}
+}
+
+func (c *fieldComparer) compareObject(path string, v map[string]any, typ reflect.Type) {
+	fields := structFields(typ)
+	for name, fv := range v {
+		f, ok := fields[name]
+		if !ok {
+			c.unknown[joinFieldPath(path, name)] = true
+			continue
+		}
+		c.compare(joinFieldPath(path, name), fv, f.typ)
+	}
+	for name, f := range fields {
+		if _, ok := v[name]; !ok && f.expected {
+			c.missing[joinFieldPath(path, name)] = true
+		}
+	}
+}
+
+type structField struct {
+	typ      reflect.Type
+	expected bool
+}
+
+// structFields returns the JSON fields of a struct type, including the fields of the embedded structs.
+func structFields(typ reflect.Type) map[string]structField {
+	fields := make(map[string]structField)
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for n, ef := range structFields(f.Type) {
+				fields[n] = ef
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = structField{
+			typ:      f.Type,
+			expected: f.Type.Kind() != reflect.Pointer && !slices.Contains(strings.Split(opts, ","), "omitempty"),
+		}
+	}
+	return fields
+}
+
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
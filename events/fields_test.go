@@ -0,0 +1,46 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestCompareFields(t *testing.T) {
+	rawPayload := []byte(`{
+		"action": "opened",
+		"number": 1,
+		"new_field": true,
+		"pull_request": {"number": 1, "head": {"sha": "abc", "new_head_field": 1}, "created_at": "2024-01-01T00:00:00Z", "merged_at": null},
+		"sender": {"login": "octocat"}
+	}`)
+	d, err := CompareFields(NamePullRequest, rawPayload)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, d.Unknown, []string{"new_field", "pull_request.head.new_head_field"})
+	assert.SliceContains(t, d.Missing, "pull_request.title")
+	assert.SliceContains(t, d.Missing, "pull_request.head.ref")
+	assert.SliceContains(t, d.Missing, "sender.id")
+	assert.SliceNotContains(t, d.Missing, "before")
+	assert.SliceNotContains(t, d.Missing, "repository")
+	assert.SliceNotContains(t, d.Missing, "pull_request.created_at")
+}
+
+func TestCompareFieldsArray(t *testing.T) {
+	rawPayload := []byte(`{"commits": [{"id": "abc", "new_field": 1}, {"id": "def", "new_field": 2}], "head_commit": null}`)
+	d, err := CompareFields(NamePush, rawPayload)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, d.Unknown, []string{"commits[].new_field"})
+	assert.SliceContains(t, d.Missing, "commits[].message")
+	assert.SliceContains(t, d.Missing, "ref")
+}
+
+func TestCompareFieldsUnknownEvent(t *testing.T) {
+	d, err := CompareFields("unknown", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Zero(t, d)
+}
+
+func TestCompareFieldsError(t *testing.T) {
+	_, err := CompareFields(NamePush, []byte(`invalid`))
+	assert.Error(t, err)
+}
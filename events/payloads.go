@@ -0,0 +1,221 @@
+package events
+
+// Common contains the fields that are common to most payloads.
+type Common struct {
+	Repository   *Repository   `json:"repository"`
+	Organization *Organization `json:"organization"`
+	Sender       *User         `json:"sender"`
+	Installation *Installation `json:"installation"`
+}
+
+// PingEvent is the payload of the "ping" event.
+type PingEvent struct {
+	Common
+	Zen    string `json:"zen"`
+	HookID int64  `json:"hook_id"`
+	Hook   *Hook  `json:"hook"`
+}
+
+// PushEvent is the payload of the "push" event.
+type PushEvent struct {
+	Common
+	Ref        string       `json:"ref"`
+	Before     string       `json:"before"`
+	After      string       `json:"after"`
+	BaseRef    string       `json:"base_ref"`
+	Created    bool         `json:"created"`
+	Deleted    bool         `json:"deleted"`
+	Forced     bool         `json:"forced"`
+	Compare    string       `json:"compare"`
+	Commits    []PushCommit `json:"commits"`
+	HeadCommit *PushCommit  `json:"head_commit"`
+	Pusher     CommitAuthor `json:"pusher"`
+}
+
+// CreateEvent is the payload of the "create" event.
+type CreateEvent struct {
+	Common
+	Ref          string `json:"ref"`
+	RefType      string `json:"ref_type"`
+	MasterBranch string `json:"master_branch"`
+	Description  string `json:"description"`
+	PusherType   string `json:"pusher_type"`
+}
+
+// DeleteEvent is the payload of the "delete" event.
+type DeleteEvent struct {
+	Common
+	Ref        string `json:"ref"`
+	RefType    string `json:"ref_type"`
+	PusherType string `json:"pusher_type"`
+}
+
+// ForkEvent is the payload of the "fork" event.
+type ForkEvent struct {
+	Common
+	Forkee *Repository `json:"forkee"`
+}
+
+// PullRequestEvent is the payload of the "pull_request" event.
+type PullRequestEvent struct {
+	Common
+	Action            string       `json:"action"`
+	Number            int          `json:"number"`
+	PullRequest       *PullRequest `json:"pull_request"`
+	Before            string       `json:"before,omitempty"`
+	After             string       `json:"after,omitempty"`
+	Label             *Label       `json:"label,omitempty"`
+	Assignee          *User        `json:"assignee,omitempty"`
+	RequestedReviewer *User        `json:"requested_reviewer,omitempty"`
+}
+
+// PullRequestReviewEvent is the payload of the "pull_request_review" event.
+type PullRequestReviewEvent struct {
+	Common
+	Action      string       `json:"action"`
+	Review      *Review      `json:"review"`
+	PullRequest *PullRequest `json:"pull_request"`
+}
+
+// IssuesEvent is the payload of the "issues" event.
+type IssuesEvent struct {
+	Common
+	Action   string `json:"action"`
+	Issue    *Issue `json:"issue"`
+	Label    *Label `json:"label,omitempty"`
+	Assignee *User  `json:"assignee,omitempty"`
+}
+
+// IssueCommentEvent is the payload of the "issue_comment" event.
+type IssueCommentEvent struct {
+	Common
+	Action  string   `json:"action"`
+	Issue   *Issue   `json:"issue"`
+	Comment *Comment `json:"comment"`
+}
+
+// ReleaseEvent is the payload of the "release" event.
+type ReleaseEvent struct {
+	Common
+	Action  string   `json:"action"`
+	Release *Release `json:"release"`
+}
+
+// StarEvent is the payload of the "star" event.
+type StarEvent struct {
+	Common
+	Action    string     `json:"action"`
+	StarredAt *Timestamp `json:"starred_at"`
+}
+
+// StatusEvent is the payload of the "status" event.
+type StatusEvent struct {
+	Common
+	ID          int64     `json:"id"`
+	SHA         string    `json:"sha"`
+	Name        string    `json:"name"`
+	State       string    `json:"state"`
+	Context     string    `json:"context"`
+	Description string    `json:"description"`
+	TargetURL   string    `json:"target_url"`
+	CreatedAt   Timestamp `json:"created_at"`
+	UpdatedAt   Timestamp `json:"updated_at"`
+}
+
+// WorkflowRunEvent is the payload of the "workflow_run" event.
+type WorkflowRunEvent struct {
+	Common
+	Action      string       `json:"action"`
+	WorkflowRun *WorkflowRun `json:"workflow_run"`
+	Workflow    *Workflow    `json:"workflow"`
+}
+
+// WorkflowJobEvent is the payload of the "workflow_job" event.
+type WorkflowJobEvent struct {
+	Common
+	Action      string       `json:"action"`
+	WorkflowJob *WorkflowJob `json:"workflow_job"`
+}
+
+// CheckRunEvent is the payload of the "check_run" event.
+type CheckRunEvent struct {
+	Common
+	Action   string    `json:"action"`
+	CheckRun *CheckRun `json:"check_run"`
+}
+
+// CheckSuiteEvent is the payload of the "check_suite" event.
+type CheckSuiteEvent struct {
+	Common
+	Action     string      `json:"action"`
+	CheckSuite *CheckSuite `json:"check_suite"`
+}
+
+// RepositoryEvent is the payload of the "repository" event.
+type RepositoryEvent struct {
+	Common
+	Action  string             `json:"action"`
+	Changes *RepositoryChanges `json:"changes,omitempty"`
+}
+
+// RepositoryChanges are the changes of a [RepositoryEvent] ("renamed" and "transferred" actions).
+type RepositoryChanges struct {
+	Repository *struct {
+		Name *struct {
+			From string `json:"from"`
+		} `json:"name"`
+	} `json:"repository,omitempty"`
+	Owner *struct {
+		From struct {
+			User         *User         `json:"user"`
+			Organization *Organization `json:"organization"`
+		} `json:"from"`
+	} `json:"owner,omitempty"`
+}
+
+// SponsorshipEvent is the payload of the "sponsorship" event.
+type SponsorshipEvent struct {
+	Common
+	Action        string       `json:"action"`
+	Sponsorship   *Sponsorship `json:"sponsorship"`
+	EffectiveDate *Timestamp   `json:"effective_date,omitempty"`
+}
+
+// MarketplacePurchaseEvent is the payload of the "marketplace_purchase" event.
+type MarketplacePurchaseEvent struct {
+	Common
+	Action                      string               `json:"action"`
+	EffectiveDate               Timestamp            `json:"effective_date"`
+	MarketplacePurchase         *MarketplacePurchase `json:"marketplace_purchase"`
+	PreviousMarketplacePurchase *MarketplacePurchase `json:"previous_marketplace_purchase,omitempty"`
+}
+
+// SecurityAdvisoryEvent is the payload of the "security_advisory" event.
+type SecurityAdvisoryEvent struct {
+	Common
+	Action           string            `json:"action"`
+	SecurityAdvisory *SecurityAdvisory `json:"security_advisory"`
+}
+
+// CodeScanningAlertEvent is the payload of the "code_scanning_alert" event.
+type CodeScanningAlertEvent struct {
+	Common
+	Action    string             `json:"action"`
+	Alert     *CodeScanningAlert `json:"alert"`
+	Ref       string             `json:"ref"`
+	CommitOID string             `json:"commit_oid"`
+}
+
+// SecretScanningAlertEvent is the payload of the "secret_scanning_alert" event.
+type SecretScanningAlertEvent struct {
+	Common
+	Action string               `json:"action"`
+	Alert  *SecretScanningAlert `json:"alert"`
+}
+
+// GetCommon returns the common fields.
+//
+// It allows to access them for any payload type, with an interface.
+func (c *Common) GetCommon() *Common {
+	return c
+}
@@ -0,0 +1,516 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp is a time that can be decoded from a RFC 3339 string or a Unix timestamp.
+//
+// GitHub uses both formats, sometimes for the same field (e.g. "created_at" in the repository of a push payload).
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		err := json.Unmarshal(b, &t.Time)
+		if err != nil {
+			return fmt.Errorf("time: %w", err)
+		}
+		return nil
+	}
+	sec, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("unix timestamp: %w", err)
+	}
+	t.Time = time.Unix(sec, 0).UTC()
+	return nil
+}
+
+// User is a GitHub user, bot or organization account.
+type User struct {
+	ID        int64  `json:"id"`
+	NodeID    string `json:"node_id"`
+	Login     string `json:"login"`
+	Name      string `json:"name,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Type      string `json:"type"`
+	SiteAdmin bool   `json:"site_admin"`
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+	URL       string `json:"url"`
+}
+
+// Organization is a GitHub organization.
+type Organization struct {
+	ID          int64  `json:"id"`
+	NodeID      string `json:"node_id"`
+	Login       string `json:"login"`
+	Description string `json:"description"`
+	AvatarURL   string `json:"avatar_url"`
+	URL         string `json:"url"`
+}
+
+// Installation is a GitHub App installation.
+type Installation struct {
+	ID     int64  `json:"id"`
+	NodeID string `json:"node_id"`
+}
+
+// Repository is a GitHub repository.
+type Repository struct {
+	ID            int64     `json:"id"`
+	NodeID        string    `json:"node_id"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	Owner         User      `json:"owner"`
+	Private       bool      `json:"private"`
+	Fork          bool      `json:"fork"`
+	Archived      bool      `json:"archived"`
+	Disabled      bool      `json:"disabled"`
+	Visibility    string    `json:"visibility"`
+	Description   string    `json:"description"`
+	DefaultBranch string    `json:"default_branch"`
+	Topics        []string  `json:"topics"`
+	HTMLURL       string    `json:"html_url"`
+	URL           string    `json:"url"`
+	CloneURL      string    `json:"clone_url"`
+	SSHURL        string    `json:"ssh_url"`
+	CreatedAt     Timestamp `json:"created_at"`
+	UpdatedAt     Timestamp `json:"updated_at"`
+	PushedAt      Timestamp `json:"pushed_at"`
+}
+
+// Label is an issue or pull request label.
+type Label struct {
+	ID          int64  `json:"id"`
+	NodeID      string `json:"node_id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// Milestone is an issue or pull request milestone.
+type Milestone struct {
+	ID     int64  `json:"id"`
+	NodeID string `json:"node_id"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+}
+
+// Issue is a GitHub issue.
+//
+// PullRequest is not nil if the issue is a pull request.
+type Issue struct {
+	ID          int64        `json:"id"`
+	NodeID      string       `json:"node_id"`
+	Number      int          `json:"number"`
+	Title       string       `json:"title"`
+	Body        string       `json:"body"`
+	State       string       `json:"state"`
+	StateReason string       `json:"state_reason"`
+	Locked      bool         `json:"locked"`
+	User        User         `json:"user"`
+	Labels      []Label      `json:"labels"`
+	Assignees   []User       `json:"assignees"`
+	Milestone   *Milestone   `json:"milestone"`
+	Comments    int          `json:"comments"`
+	PullRequest *IssuePRLink `json:"pull_request"`
+	HTMLURL     string       `json:"html_url"`
+	URL         string       `json:"url"`
+	CreatedAt   Timestamp    `json:"created_at"`
+	UpdatedAt   Timestamp    `json:"updated_at"`
+	ClosedAt    *Timestamp   `json:"closed_at"`
+}
+
+// IssuePRLink links an [Issue] to its pull request.
+type IssuePRLink struct {
+	URL     string `json:"url"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Comment is an issue, pull request or commit comment.
+type Comment struct {
+	ID        int64     `json:"id"`
+	NodeID    string    `json:"node_id"`
+	Body      string    `json:"body"`
+	User      User      `json:"user"`
+	HTMLURL   string    `json:"html_url"`
+	URL       string    `json:"url"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+}
+
+// Branch is a reference to a branch of a [PullRequest].
+type Branch struct {
+	Label string      `json:"label"`
+	Ref   string      `json:"ref"`
+	SHA   string      `json:"sha"`
+	User  User        `json:"user"`
+	Repo  *Repository `json:"repo"`
+}
+
+// PullRequest is a GitHub pull request.
+type PullRequest struct {
+	ID                 int64      `json:"id"`
+	NodeID             string     `json:"node_id"`
+	Number             int        `json:"number"`
+	Title              string     `json:"title"`
+	Body               string     `json:"body"`
+	State              string     `json:"state"`
+	Locked             bool       `json:"locked"`
+	Draft              bool       `json:"draft"`
+	Merged             bool       `json:"merged"`
+	Mergeable          *bool      `json:"mergeable"`
+	MergeCommitSHA     string     `json:"merge_commit_sha"`
+	MergedBy           *User      `json:"merged_by"`
+	User               User       `json:"user"`
+	Labels             []Label    `json:"labels"`
+	Assignees          []User     `json:"assignees"`
+	RequestedReviewers []User     `json:"requested_reviewers"`
+	Milestone          *Milestone `json:"milestone"`
+	Head               Branch     `json:"head"`
+	Base               Branch     `json:"base"`
+	Commits            int        `json:"commits"`
+	Additions          int        `json:"additions"`
+	Deletions          int        `json:"deletions"`
+	ChangedFiles       int        `json:"changed_files"`
+	HTMLURL            string     `json:"html_url"`
+	URL                string     `json:"url"`
+	DiffURL            string     `json:"diff_url"`
+	CreatedAt          Timestamp  `json:"created_at"`
+	UpdatedAt          Timestamp  `json:"updated_at"`
+	ClosedAt           *Timestamp `json:"closed_at"`
+	MergedAt           *Timestamp `json:"merged_at"`
+}
+
+// Review is a pull request review.
+type Review struct {
+	ID          int64     `json:"id"`
+	NodeID      string    `json:"node_id"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	CommitID    string    `json:"commit_id"`
+	User        User      `json:"user"`
+	HTMLURL     string    `json:"html_url"`
+	SubmittedAt Timestamp `json:"submitted_at"`
+}
+
+// CommitAuthor is the author or committer of a [PushCommit].
+type CommitAuthor struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+}
+
+// PushCommit is a commit of a [PushEvent].
+type PushCommit struct {
+	ID        string       `json:"id"`
+	TreeID    string       `json:"tree_id"`
+	Distinct  bool         `json:"distinct"`
+	Message   string       `json:"message"`
+	Timestamp Timestamp    `json:"timestamp"`
+	URL       string       `json:"url"`
+	Author    CommitAuthor `json:"author"`
+	Committer CommitAuthor `json:"committer"`
+	Added     []string     `json:"added"`
+	Removed   []string     `json:"removed"`
+	Modified  []string     `json:"modified"`
+}
+
+// Release is a GitHub release.
+type Release struct {
+	ID              int64      `json:"id"`
+	NodeID          string     `json:"node_id"`
+	TagName         string     `json:"tag_name"`
+	TargetCommitish string     `json:"target_commitish"`
+	Name            string     `json:"name"`
+	Body            string     `json:"body"`
+	Draft           bool       `json:"draft"`
+	Prerelease      bool       `json:"prerelease"`
+	Author          User       `json:"author"`
+	HTMLURL         string     `json:"html_url"`
+	URL             string     `json:"url"`
+	TarballURL      string     `json:"tarball_url"`
+	ZipballURL      string     `json:"zipball_url"`
+	CreatedAt       Timestamp  `json:"created_at"`
+	PublishedAt     *Timestamp `json:"published_at"`
+}
+
+// WorkflowRun is a GitHub Actions workflow run.
+type WorkflowRun struct {
+	ID           int64       `json:"id"`
+	NodeID       string      `json:"node_id"`
+	Name         string      `json:"name"`
+	DisplayTitle string      `json:"display_title"`
+	Event        string      `json:"event"`
+	Status       string      `json:"status"`
+	Conclusion   string      `json:"conclusion"`
+	WorkflowID   int64       `json:"workflow_id"`
+	RunNumber    int         `json:"run_number"`
+	RunAttempt   int         `json:"run_attempt"`
+	HeadBranch   string      `json:"head_branch"`
+	HeadSHA      string      `json:"head_sha"`
+	Path         string      `json:"path"`
+	Actor        User        `json:"actor"`
+	Repository   *Repository `json:"repository"`
+	HTMLURL      string      `json:"html_url"`
+	URL          string      `json:"url"`
+	CreatedAt    Timestamp   `json:"created_at"`
+	UpdatedAt    Timestamp   `json:"updated_at"`
+	RunStartedAt Timestamp   `json:"run_started_at"`
+}
+
+// Workflow is a GitHub Actions workflow.
+type Workflow struct {
+	ID      int64  `json:"id"`
+	NodeID  string `json:"node_id"`
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	URL     string `json:"url"`
+}
+
+// WorkflowJob is a GitHub Actions workflow job.
+type WorkflowJob struct {
+	ID           int64      `json:"id"`
+	NodeID       string     `json:"node_id"`
+	RunID        int64      `json:"run_id"`
+	RunAttempt   int        `json:"run_attempt"`
+	Name         string     `json:"name"`
+	WorkflowName string     `json:"workflow_name"`
+	Status       string     `json:"status"`
+	Conclusion   string     `json:"conclusion"`
+	HeadBranch   string     `json:"head_branch"`
+	HeadSHA      string     `json:"head_sha"`
+	Labels       []string   `json:"labels"`
+	RunnerName   string     `json:"runner_name"`
+	HTMLURL      string     `json:"html_url"`
+	URL          string     `json:"url"`
+	CreatedAt    Timestamp  `json:"created_at"`
+	StartedAt    Timestamp  `json:"started_at"`
+	CompletedAt  *Timestamp `json:"completed_at"`
+}
+
+// CheckSuite is a check suite.
+type CheckSuite struct {
+	ID         int64     `json:"id"`
+	NodeID     string    `json:"node_id"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSHA    string    `json:"head_sha"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion"`
+	URL        string    `json:"url"`
+	CreatedAt  Timestamp `json:"created_at"`
+	UpdatedAt  Timestamp `json:"updated_at"`
+}
+
+// CheckRun is a check run.
+type CheckRun struct {
+	ID          int64       `json:"id"`
+	NodeID      string      `json:"node_id"`
+	Name        string      `json:"name"`
+	HeadSHA     string      `json:"head_sha"`
+	ExternalID  string      `json:"external_id"`
+	Status      string      `json:"status"`
+	Conclusion  string      `json:"conclusion"`
+	CheckSuite  *CheckSuite `json:"check_suite"`
+	HTMLURL     string      `json:"html_url"`
+	DetailsURL  string      `json:"details_url"`
+	URL         string      `json:"url"`
+	StartedAt   Timestamp   `json:"started_at"`
+	CompletedAt *Timestamp  `json:"completed_at"`
+}
+
+// Hook is a webhook configuration.
+type Hook struct {
+	ID     int64      `json:"id"`
+	Type   string     `json:"type"`
+	Name   string     `json:"name"`
+	Active bool       `json:"active"`
+	Events []string   `json:"events"`
+	Config HookConfig `json:"config"`
+	AppID  int64      `json:"app_id,omitempty"`
+}
+
+// HookConfig is the configuration of a [Hook].
+type HookConfig struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	InsecureSSL string `json:"insecure_ssl"`
+}
+
+// Sponsorship is a GitHub Sponsors sponsorship.
+type Sponsorship struct {
+	NodeID       string           `json:"node_id"`
+	CreatedAt    Timestamp        `json:"created_at"`
+	Sponsorable  *User            `json:"sponsorable"`
+	Sponsor      *User            `json:"sponsor"`
+	PrivacyLevel string           `json:"privacy_level"`
+	Tier         *SponsorshipTier `json:"tier"`
+}
+
+// SponsorshipTier is the tier of a [Sponsorship].
+type SponsorshipTier struct {
+	NodeID                string    `json:"node_id"`
+	CreatedAt             Timestamp `json:"created_at"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description"`
+	MonthlyPriceInCents   int       `json:"monthly_price_in_cents"`
+	MonthlyPriceInDollars int       `json:"monthly_price_in_dollars"`
+	IsOneTime             bool      `json:"is_one_time"`
+	IsCustomAmount        bool      `json:"is_custom_amount"`
+}
+
+// MarketplacePurchase is a GitHub Marketplace purchase.
+type MarketplacePurchase struct {
+	Account         MarketplaceAccount `json:"account"`
+	BillingCycle    string             `json:"billing_cycle"`
+	UnitCount       int                `json:"unit_count"`
+	OnFreeTrial     bool               `json:"on_free_trial"`
+	FreeTrialEndsOn *Timestamp         `json:"free_trial_ends_on"`
+	NextBillingDate *Timestamp         `json:"next_billing_date"`
+	Plan            MarketplacePlan    `json:"plan"`
+}
+
+// MarketplaceAccount is the account of a [MarketplacePurchase].
+type MarketplaceAccount struct {
+	ID                       int64  `json:"id"`
+	NodeID                   string `json:"node_id"`
+	Login                    string `json:"login"`
+	Type                     string `json:"type"`
+	OrganizationBillingEmail string `json:"organization_billing_email"`
+}
+
+// MarketplacePlan is the plan of a [MarketplacePurchase].
+type MarketplacePlan struct {
+	ID                  int64    `json:"id"`
+	Name                string   `json:"name"`
+	Description         string   `json:"description"`
+	MonthlyPriceInCents int      `json:"monthly_price_in_cents"`
+	YearlyPriceInCents  int      `json:"yearly_price_in_cents"`
+	PriceModel          string   `json:"price_model"`
+	HasFreeTrial        bool     `json:"has_free_trial"`
+	UnitName            string   `json:"unit_name"`
+	Bullets             []string `json:"bullets"`
+}
+
+// SecurityAdvisory is a GitHub security advisory.
+type SecurityAdvisory struct {
+	GHSAID          string                          `json:"ghsa_id"`
+	CVEID           string                          `json:"cve_id"`
+	Summary         string                          `json:"summary"`
+	Description     string                          `json:"description"`
+	Severity        string                          `json:"severity"`
+	Identifiers     []SecurityAdvisoryIdentifier    `json:"identifiers"`
+	References      []SecurityAdvisoryReference     `json:"references"`
+	Vulnerabilities []SecurityAdvisoryVulnerability `json:"vulnerabilities"`
+	PublishedAt     Timestamp                       `json:"published_at"`
+	UpdatedAt       Timestamp                       `json:"updated_at"`
+	WithdrawnAt     *Timestamp                      `json:"withdrawn_at"`
+}
+
+// SecurityAdvisoryIdentifier is an identifier of a [SecurityAdvisory] (e.g. GHSA or CVE).
+type SecurityAdvisoryIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// SecurityAdvisoryReference is a reference of a [SecurityAdvisory].
+type SecurityAdvisoryReference struct {
+	URL string `json:"url"`
+}
+
+// SecurityAdvisoryVulnerability is a vulnerable package of a [SecurityAdvisory].
+type SecurityAdvisoryVulnerability struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Severity               string `json:"severity"`
+	VulnerableVersionRange string `json:"vulnerable_version_range"`
+	FirstPatchedVersion    *struct {
+		Identifier string `json:"identifier"`
+	} `json:"first_patched_version"`
+}
+
+// CodeScanningAlert is a code scanning alert.
+type CodeScanningAlert struct {
+	Number             int                        `json:"number"`
+	State              string                     `json:"state"`
+	Rule               CodeScanningRule           `json:"rule"`
+	Tool               CodeScanningTool           `json:"tool"`
+	MostRecentInstance *CodeScanningAlertInstance `json:"most_recent_instance"`
+	DismissedBy        *User                      `json:"dismissed_by"`
+	DismissedReason    string                     `json:"dismissed_reason"`
+	DismissedAt        *Timestamp                 `json:"dismissed_at"`
+	FixedAt            *Timestamp                 `json:"fixed_at"`
+	HTMLURL            string                     `json:"html_url"`
+	URL                string                     `json:"url"`
+	CreatedAt          Timestamp                  `json:"created_at"`
+	UpdatedAt          *Timestamp                 `json:"updated_at"`
+}
+
+// CodeScanningRule is the rule of a [CodeScanningAlert].
+type CodeScanningRule struct {
+	ID                    string   `json:"id"`
+	Name                  string   `json:"name"`
+	Description           string   `json:"description"`
+	Severity              string   `json:"severity"`
+	SecuritySeverityLevel string   `json:"security_severity_level"`
+	Tags                  []string `json:"tags"`
+}
+
+// CodeScanningTool is the tool of a [CodeScanningAlert].
+type CodeScanningTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	GUID    string `json:"guid"`
+}
+
+// CodeScanningAlertInstance is an instance of a [CodeScanningAlert].
+type CodeScanningAlertInstance struct {
+	Ref         string `json:"ref"`
+	AnalysisKey string `json:"analysis_key"`
+	Category    string `json:"category"`
+	Environment string `json:"environment"`
+	State       string `json:"state"`
+	CommitSHA   string `json:"commit_sha"`
+	Location    struct {
+		Path        string `json:"path"`
+		StartLine   int    `json:"start_line"`
+		EndLine     int    `json:"end_line"`
+		StartColumn int    `json:"start_column"`
+		EndColumn   int    `json:"end_column"`
+	} `json:"location"`
+}
+
+// SecretScanningAlert is a secret scanning alert.
+type SecretScanningAlert struct {
+	Number                 int        `json:"number"`
+	State                  string     `json:"state"`
+	SecretType             string     `json:"secret_type"`
+	SecretTypeDisplayName  string     `json:"secret_type_display_name"`
+	Validity               string     `json:"validity"`
+	Resolution             string     `json:"resolution"`
+	ResolvedBy             *User      `json:"resolved_by"`
+	ResolvedAt             *Timestamp `json:"resolved_at"`
+	ResolutionComment      string     `json:"resolution_comment"`
+	PushProtectionBypassed bool       `json:"push_protection_bypassed"`
+	HTMLURL                string     `json:"html_url"`
+	URL                    string     `json:"url"`
+	LocationsURL           string     `json:"locations_url"`
+	CreatedAt              Timestamp  `json:"created_at"`
+	UpdatedAt              *Timestamp `json:"updated_at"`
+}
@@ -0,0 +1,62 @@
+// Package fixtures provides sample GitHub webhook payloads, embedded in the binary.
+//
+// There is at least one fixture for each event of the catalog (see [githubhook.Events]), so each event known by the [events] package.
+// The payloads follow the examples of GitHub's documentation (https://docs.github.com/en/webhooks/webhook-events-and-payloads), with the same placeholder values (e.g. "octocat/Hello-World").
+// A fixture is identified by its name, which is "<event>.<action>" (e.g. "pull_request.opened"), or "<event>" for events without action (e.g. "push").
+//
+// It can be used to write table-driven tests, or to replay deliveries.
+package fixtures
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+//go:embed payloads/*.json
+var payloads embed.FS
+
+// ErrNotFound is returned by [Load] if the fixture doesn't exist.
+var ErrNotFound = errors.New("fixture not found")
+
+// Load returns the raw JSON payload of the fixture.
+func Load(name string) ([]byte, error) {
+	b, err := payloads.ReadFile(path.Join("payloads", name+".json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+		}
+		return nil, fmt.Errorf("read %q: %w", name, err)
+	}
+	return b, nil
+}
+
+// MustLoad is like [Load], but panics on error.
+func MustLoad(name string) []byte {
+	b, err := Load(name)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Names returns the sorted names of all fixtures.
+func Names() []string {
+	entries, _ := payloads.ReadDir("payloads") // The directory is embedded, so it can't fail.
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Event returns the event name of a fixture name (e.g. "pull_request" for "pull_request.opened").
+func Event(name string) string {
+	event, _, _ := strings.Cut(name, ".")
+	return event
+}
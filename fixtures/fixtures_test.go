@@ -0,0 +1,78 @@
+package fixtures
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestLoad(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			raw, err := Load(name)
+			assert.NoError(t, err)
+			payload, err := events.Decode(Event(name), raw)
+			assert.NoError(t, err)
+			assert.NotZero(t, payload)
+		})
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	_, err := Load("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMustLoad(t *testing.T) {
+	raw := MustLoad("pull_request.opened")
+	payload, err := events.Decode(events.NamePullRequest, raw)
+	assert.NoError(t, err)
+	pr, _ := assert.Type[*events.PullRequestEvent](t, payload)
+	assert.Equal(t, pr.Action, "opened")
+}
+
+func TestMustLoadPanic(t *testing.T) {
+	assert.Panics(t, func() {
+		MustLoad("unknown")
+	})
+}
+
+func TestNamesKnownEvents(t *testing.T) {
+	names := Names()
+	for _, event := range events.Known() {
+		ok := slices.ContainsFunc(names, func(name string) bool {
+			return Event(name) == event
+		})
+		assert.True(t, ok, assert.Messagef("no fixture for event %q", event))
+	}
+}
+
+func TestNamesCatalogEvents(t *testing.T) {
+	names := Names()
+	for _, event := range githubhook.Events() {
+		ok := slices.ContainsFunc(names, func(name string) bool {
+			return Event(name) == event
+		})
+		assert.True(t, ok, assert.Messagef("no fixture for event %q", event))
+	}
+}
+
+func TestNamesCatalogActions(t *testing.T) {
+	for _, name := range Names() {
+		event, action, ok := strings.Cut(name, ".")
+		if !ok {
+			assert.SliceEmpty(t, githubhook.Actions(event), assert.Messagef("no action for fixture %q", name))
+			continue
+		}
+		assert.True(t, githubhook.IsKnownAction(event, action), assert.Messagef("unknown action for fixture %q", name))
+	}
+}
+
+func TestEvent(t *testing.T) {
+	assert.Equal(t, Event("pull_request.opened"), "pull_request")
+	assert.Equal(t, Event("push"), "push")
+}
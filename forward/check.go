@@ -0,0 +1,77 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+var checkRawPayload = []byte(`{"zen":"githubhook contract test"}`)
+
+/*
+CheckResult is the result of the contract test of a destination, see [Forwarder.Check].
+
+Fields:
+  - URL is the URL of the destination.
+  - StatusCode is the response status, or 0 if there is no response.
+  - Latency is the duration of the request.
+  - Err is the reason of the failure, or nil if the destination passed.
+*/
+type CheckResult struct {
+	URL        string
+	StatusCode int
+	Latency    time.Duration
+	Err        error
+}
+
+/*
+Check runs the contract test of the destinations, so a broken downstream configuration is detected before real deliveries are lost, e.g. at startup or on demand.
+
+It sends a synthetic "ping" delivery to each destination, signed with [Destination.Secret] (unsigned if it's empty), once and without retries.
+A destination passes if the response status is 2xx, and the latency is not greater than [Destination.MaxLatency] (if it's defined).
+The delivery ID is prefixed with "contract-test-", so the destinations can recognize it.
+
+It returns the results of all destinations, and the joined errors of the destinations that failed.
+*/
+func (f *Forwarder) Check(ctx context.Context) ([]*CheckResult, error) {
+	id, err := githubhook.NewDeliveryID()
+	if err != nil {
+		return nil, fmt.Errorf("check: %w", err)
+	}
+	header, err := new(githubhook.Signer).Header("ping", "contract-test-"+id, checkRawPayload)
+	if err != nil {
+		return nil, fmt.Errorf("check: header: %w", err)
+	}
+	results := make([]*CheckResult, len(f.Destinations))
+	var errs []error
+	for i, dst := range f.Destinations {
+		res := f.checkDestination(ctx, dst, header)
+		results[i] = res
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("check: destination %s: %w", dst.URL, res.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (f *Forwarder) checkDestination(ctx context.Context, dst Destination, header http.Header) *CheckResult {
+	res := &CheckResult{
+		URL: dst.URL,
+	}
+	header, err := getDestinationHeader(dst, header, checkRawPayload)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	start := time.Now()
+	res.StatusCode, res.Err = f.do(ctx, dst, header, checkRawPayload)
+	res.Latency = time.Since(start)
+	if res.Err == nil && dst.MaxLatency > 0 && res.Latency > dst.MaxLatency {
+		res.Err = fmt.Errorf("latency %s greater than %s", res.Latency, dst.MaxLatency)
+	}
+	return res
+}
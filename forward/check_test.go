@@ -0,0 +1,71 @@
+package forward
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestForwarderCheck(t *testing.T) {
+	var deliveryID string
+	downstream := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryID = md.DeliveryID
+			return nil
+		},
+	}
+	srv := httptest.NewServer(downstream)
+	defer srv.Close()
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: srv.URL, Secret: "foobar", MaxLatency: time.Minute},
+		},
+	}
+	results, err := f.Check(context.Background())
+	assert.NoError(t, err)
+	assert.SliceLen(t, results, 1)
+	assert.Equal(t, results[0].URL, srv.URL)
+	assert.Equal(t, results[0].StatusCode, http.StatusOK)
+	assert.NotZero(t, results[0].Latency)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, strings.HasPrefix(deliveryID, "contract-test-"))
+}
+
+func TestForwarderCheckError(t *testing.T) {
+	downstream := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			return nil
+		},
+	}
+	srv := httptest.NewServer(downstream)
+	defer srv.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer slow.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: srv.URL, Secret: "invalid"},
+			{URL: slow.URL, MaxLatency: time.Millisecond},
+			{URL: unreachable.URL},
+		},
+	}
+	results, err := f.Check(context.Background())
+	assert.ErrorContains(t, err, "check: destination "+srv.URL+": unexpected response status 400")
+	assert.ErrorContains(t, err, "check: destination "+slow.URL+": latency")
+	assert.ErrorContains(t, err, "check: destination "+unreachable.URL+": do request")
+	assert.SliceLen(t, results, 3)
+	assert.Equal(t, results[0].StatusCode, http.StatusBadRequest)
+	assert.Equal(t, results[1].StatusCode, http.StatusOK)
+	assert.Zero(t, results[2].StatusCode)
+}
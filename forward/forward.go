@@ -0,0 +1,292 @@
+// Package forward provides a forwarder of verified GitHub webhook deliveries to downstream endpoints.
+//
+// It turns a [githubhook.Handler] into a verification gateway fronting multiple internal consumers:
+//
+//	http.Handle("/webhook", h.Middleware(&forward.Forwarder{Destinations: destinations}))
+package forward
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+// DefaultTimeout is the default value of [Destination.Timeout].
+const DefaultTimeout = 10 * time.Second
+
+// DefaultRetryDelay is the default value of [Destination.RetryDelay].
+const DefaultRetryDelay = time.Second
+
+/*
+Destination is a downstream endpoint.
+
+Fields:
+  - URL is the URL of the endpoint (required).
+  - Timeout is the timeout of each attempt (default: [DefaultTimeout]).
+  - Retries is the number of retries after a failed attempt (network error, 429 or 5xx response).
+  - RetryDelay is the delay between attempts (default: [DefaultRetryDelay]).
+  - Secret re-signs the delivery with another secret (see [githubhook.Signer]). If it's empty, the original signature headers are forwarded.
+  - MaxLatency is the maximum latency of the contract test (optional), see [Forwarder.Check].
+*/
+type Destination struct {
+	URL        string
+	Timeout    time.Duration
+	Retries    int
+	RetryDelay time.Duration
+	Secret     string
+	MaxLatency time.Duration
+}
+
+/*
+Forwarder is a [http.Handler] that forwards verified deliveries to downstream endpoints concurrently, with the original headers preserved.
+The forwarded deliveries have a [githubhook.Lineage] with the "forward" reason.
+
+It must be wrapped by [githubhook.Handler.Middleware], which verifies the deliveries.
+The forwarded body is the raw JSON payload, so the original signature is only valid for JSON deliveries: form encoded deliveries must be re-signed with [Destination.Secret].
+If all destinations accept the delivery, the response status is 200.
+Otherwise, it's 502, so GitHub can redeliver it.
+
+Fields:
+  - Destinations are the downstream endpoints.
+  - Client is the HTTP client (default: [http.DefaultClient]).
+  - Error is called if the delivery can't be forwarded to a destination.
+*/
+type Forwarder struct {
+	Destinations []Destination
+	Client       *http.Client
+	Error        func(ctx context.Context, err error, req *http.Request)
+}
+
+func (f *Forwarder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// The original headers are forwarded, so the delivery is sent with the request headers instead of [Forwarder.Send].
+	s := githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+		header := req.Header.Clone()
+		githubhook.NextLineage(&d.DeliveryMetadata, "", "forward").SetHeader(header)
+		return f.Forward(ctx, header, d.RawPayload)
+	})
+	githubhook.SinkHTTPHandler(s, f.Error).ServeHTTP(w, req)
+}
+
+// Send forwards a verified delivery to all destinations.
+//
+// It implements [githubhook.Sink].
+// The original headers are not available, so the headers are rebuilt from the metadata, and the delivery is signed only for the destinations with a [Destination.Secret].
+func (f *Forwarder) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	s := &githubhook.Signer{
+		UserAgent: d.UserAgent,
+		Lineage:   githubhook.NextLineage(&d.DeliveryMetadata, "", "forward"),
+	}
+	header, err := s.Header(d.Event, d.DeliveryID, d.RawPayload)
+	if err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+	for k, v := range map[string]string{
+		"X-GitHub-Hook-ID":                       d.HookID,
+		"X-GitHub-Hook-Installation-Target-Type": d.InstallationTargetType,
+		"X-GitHub-Hook-Installation-Target-ID":   d.InstallationTargetID,
+	} {
+		if v != "" {
+			header.Set(k, v)
+		}
+	}
+	return f.Forward(ctx, header, d.RawPayload)
+}
+
+// Forward forwards a delivery to all destinations concurrently, with the original headers.
+//
+// It returns the joined errors of the destinations that failed.
+func (f *Forwarder) Forward(ctx context.Context, header http.Header, rawPayload []byte) error {
+	// The destinations are sent with [githubhook.Fanout], which recovers the panics.
+	sinks := make([]githubhook.Sink, len(f.Destinations))
+	for i, dst := range f.Destinations {
+		sinks[i] = githubhook.SinkFunc(func(ctx context.Context, _ *githubhook.VerifiedDelivery) error {
+			err := f.forwardDestination(ctx, dst, header, rawPayload)
+			if err != nil {
+				return fmt.Errorf("destination %s: %w", dst.URL, err)
+			}
+			return nil
+		})
+	}
+	return githubhook.Fanout(sinks...).Send(ctx, nil) //nolint:wrapcheck // The errors are wrapped by the sinks.
+}
+
+// Start pre-establishes the connections to the destinations, so the first delivery doesn't pay the connection and TLS handshake.
+//
+// It implements [githubhook.SinkStarter].
+// It sends a HEAD request to each destination concurrently: any response is accepted, and the connection is kept by the client for the deliveries.
+// It returns the joined errors of the destinations that can't be reached (e.g. DNS or TLS failure).
+// [Forwarder.Check] should be used to validate the destinations with a delivery.
+func (f *Forwarder) Start(ctx context.Context) error {
+	sinks := make([]githubhook.Sink, len(f.Destinations))
+	for i, dst := range f.Destinations {
+		sinks[i] = githubhook.SinkFunc(func(ctx context.Context, _ *githubhook.VerifiedDelivery) error {
+			err := f.connect(ctx, dst)
+			if err != nil {
+				return fmt.Errorf("destination %s: %w", dst.URL, err)
+			}
+			return nil
+		})
+	}
+	return githubhook.Fanout(sinks...).Send(ctx, nil) //nolint:wrapcheck // The errors are wrapped by the sinks.
+}
+
+// connect sends a HEAD request to the destination, and discards the response.
+func (f *Forwarder) connect(ctx context.Context, dst Destination) error {
+	timeout := dst.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx = withConnectionTrace(ctx, dst.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, dst.URL, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	resp, err := f.getClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (f *Forwarder) forwardDestination(ctx context.Context, dst Destination, header http.Header, rawPayload []byte) error {
+	header, err := getDestinationHeader(dst, header, rawPayload)
+	if err != nil {
+		return err
+	}
+	retryDelay := dst.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = DefaultRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = f.send(ctx, dst, header, rawPayload)
+		if err == nil || !retry || attempt >= dst.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func getDestinationHeader(dst Destination, header http.Header, rawPayload []byte) (http.Header, error) {
+	if dst.Secret != "" {
+		s := &githubhook.Signer{
+			Secret:    dst.Secret,
+			UserAgent: header.Get("User-Agent"),
+		}
+		signed, err := s.Header(header.Get("X-GitHub-Event"), header.Get("X-GitHub-Delivery"), rawPayload)
+		if err != nil {
+			return nil, fmt.Errorf("sign: %w", err)
+		}
+		header = mergeHeader(header, signed)
+	}
+	return forwardedHeader(header), nil
+}
+
+func mergeHeader(header http.Header, signed http.Header) http.Header {
+	header = header.Clone()
+	header.Del("X-Hub-Signature")
+	for k, v := range signed {
+		header[k] = v
+	}
+	return header
+}
+
+// forwardedHeader returns the headers of the delivery: GitHub headers, signatures, lineage, User-Agent and Content-Type.
+func forwardedHeader(header http.Header) http.Header {
+	fwd := make(http.Header)
+	for k, v := range header {
+		ck := http.CanonicalHeaderKey(k)
+		if strings.HasPrefix(ck, "X-Github-") || strings.HasPrefix(ck, "X-Hub-Signature") || strings.HasPrefix(ck, "X-Githubhook-") || ck == "User-Agent" || ck == "Content-Type" {
+			fwd[ck] = v
+		}
+	}
+	return fwd
+}
+
+// send sends the delivery once, and returns true if it can be retried.
+func (f *Forwarder) send(ctx context.Context, dst Destination, header http.Header, rawPayload []byte) (retry bool, err error) {
+	statusCode, err := f.do(ctx, dst, header, rawPayload)
+	if err == nil {
+		return false, nil
+	}
+	retry = statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+	return retry, err
+}
+
+// do sends the delivery once, and returns the response status (0 if there is no response).
+//
+// It returns an error if the response status is not 2xx.
+// The delivery is traced with the [githubhook.SinkTrace] of the context.
+func (f *Forwarder) do(ctx context.Context, dst Destination, header http.Header, rawPayload []byte) (statusCode int, err error) {
+	timeout := dst.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx = withConnectionTrace(ctx, dst.URL)
+	err = githubhook.TracePublish(ctx, dst.URL, header.Get("X-GitHub-Delivery"), func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, dst.URL, bytes.NewReader(rawPayload))
+		if err != nil {
+			return fmt.Errorf("new request: %w", err)
+		}
+		req.Header = header.Clone()
+		resp, err := f.getClient().Do(req)
+		if err != nil {
+			return fmt.Errorf("do request: %w", err)
+		}
+		_ = resp.Body.Close()
+		statusCode = resp.StatusCode
+		if statusCode < 200 || statusCode >= 300 {
+			return fmt.Errorf("unexpected response status %d", statusCode)
+		}
+		return nil
+	})
+	return statusCode, err
+}
+
+// withConnectionTrace returns a context with a [httptrace.ClientTrace], that calls the connection hooks of the [githubhook.SinkTrace] of the context.
+func withConnectionTrace(ctx context.Context, target string) context.Context {
+	trace := githubhook.ContextSinkTrace(ctx)
+	if trace == nil || (trace.Connect == nil && trace.Connected == nil) {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			if trace.Connect != nil {
+				trace.Connect(target)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if trace.Connected != nil {
+				trace.Connected(target, nil)
+			}
+		},
+		ConnectDone: func(network string, addr string, err error) {
+			if err != nil && trace.Connected != nil {
+				trace.Connected(target, err)
+			}
+		},
+	})
+}
+
+func (f *Forwarder) getClient() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,264 @@
+package forward
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main"}`)
+
+func testNewRequest(t *testing.T, secret string) *http.Request {
+	t.Helper()
+	s := &githubhook.Signer{
+		Secret: secret,
+	}
+	req, err := s.NewRequest(context.Background(), "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	req.Header.Set("X-Other", "other")
+	return req
+}
+
+func TestForwarder(t *testing.T) {
+	var delivered atomic.Int64
+	downstream := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			assert.Equal(t, md.DeliveryID, "test")
+			delivered.Add(1)
+			return nil
+		},
+	}
+	var header http.Header
+	srv1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		header = req.Header
+		downstream.ServeHTTP(w, req)
+	}))
+	defer srv1.Close()
+	srv2 := httptest.NewServer(downstream)
+	defer srv2.Close()
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: srv1.URL},
+			{URL: srv2.URL},
+		},
+	}
+	w := httptest.NewRecorder()
+	h.Middleware(f).ServeHTTP(w, testNewRequest(t, "foobar"))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, delivered.Load(), int64(2))
+	assert.Equal(t, header.Get("X-GitHub-Event"), "push")
+	assert.NotZero(t, header.Get("X-Hub-Signature-256"))
+	assert.Zero(t, header.Get("X-Other"))
+	assert.Equal(t, header.Get(githubhook.LineageOriginalDeliveryHeader), "test")
+	assert.Equal(t, header.Get(githubhook.LineageReasonHeader), "forward")
+}
+
+func TestForwarderSecret(t *testing.T) {
+	deliveryCalled := false
+	downstream := &githubhook.Handler{
+		Secret: "internal",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	srv := httptest.NewServer(downstream)
+	defer srv.Close()
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: srv.URL, Secret: "internal"},
+		},
+	}
+	w := httptest.NewRecorder()
+	h.Middleware(f).ServeHTTP(w, testNewRequest(t, "foobar"))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func TestForwarderRetry(t *testing.T) {
+	var attempts atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.BytesEqual(t, body, testRawPayload)
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: srv.URL, Retries: 2, RetryDelay: time.Millisecond},
+		},
+	}
+	err := f.Forward(context.Background(), http.Header{}, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, attempts.Load(), int64(3))
+}
+
+func TestForwarderError(t *testing.T) {
+	var attempts atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	var handledErr error
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: srv.URL, Retries: 2, RetryDelay: time.Millisecond},
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	w := httptest.NewRecorder()
+	h.Middleware(f).ServeHTTP(w, testNewRequest(t, "foobar"))
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	assert.Error(t, handledErr)
+	assert.Equal(t, attempts.Load(), int64(1))
+}
+
+func TestForwarderPanic(t *testing.T) {
+	f := &Forwarder{
+		Destinations: []Destination{
+			{URL: "http://example.com"},
+		},
+		Client: &http.Client{
+			Transport: testRoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				panic("test")
+			}),
+		},
+	}
+	err := f.Forward(context.Background(), make(http.Header), testRawPayload)
+	var panicErr *githubhook.PanicError
+	assert.ErrorAs(t, err, &panicErr)
+}
+
+type testRoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f testRoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestForwarderWithoutMiddleware(t *testing.T) {
+	f := new(Forwarder)
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, testNewRequest(t, ""))
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestForwarderSink(t *testing.T) {
+	var md *githubhook.DeliveryMetadata
+	downstream := &githubhook.Handler{
+		Secret: "internal",
+		Delivery: func(ctx context.Context, m *githubhook.DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	srv := httptest.NewServer(downstream)
+	defer srv.Close()
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Sink: &Forwarder{
+			Destinations: []Destination{
+				{URL: srv.URL, Secret: "internal"},
+			},
+		},
+	}
+	req := testNewRequest(t, "foobar")
+	req.Header.Set("X-GitHub-Hook-ID", "123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.NotZero(t, md)
+	assert.Equal(t, md.DeliveryID, "test")
+	assert.Equal(t, md.HookID, "123")
+	assert.Equal(t, md.Lineage.Reason, "forward")
+}
+
+func TestForwarderSinkTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	var mu sync.Mutex
+	var calls []string
+	ctx := githubhook.WithSinkTrace(context.Background(), &githubhook.SinkTrace{
+		Connect: func(target string) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "connect "+target)
+		},
+		Connected: func(target string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "connected "+target)
+		},
+		Publish: func(target string, deliveryID string) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "publish "+target+" "+deliveryID)
+		},
+		Ack: func(target string, deliveryID string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "ack "+target+" "+err.Error())
+		},
+	})
+	f := &Forwarder{
+		Destinations: []Destination{{URL: srv.URL}},
+	}
+	err := f.Forward(ctx, testNewRequest(t, "").Header, testRawPayload)
+	assert.Error(t, err)
+	assert.SliceEqual(t, calls, []string{
+		"publish " + srv.URL + " test",
+		"connect " + srv.URL,
+		"connected " + srv.URL,
+		"ack " + srv.URL + " unexpected response status 500",
+	})
+}
+
+func TestForwarderStart(t *testing.T) {
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, req.Method, http.MethodHead)
+		n.Add(1)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer srv.Close()
+	f := &Forwarder{
+		Destinations: []Destination{{URL: srv.URL}, {URL: srv.URL + "/other"}},
+	}
+	err := githubhook.StartSink(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Equal(t, n.Load(), int64(2))
+}
+
+func TestForwarderStartError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	f := &Forwarder{
+		Destinations: []Destination{{URL: srv.URL}},
+	}
+	err := f.Start(context.Background())
+	assert.ErrorContains(t, err, "destination "+srv.URL)
+}
@@ -2,76 +2,306 @@
 package githubhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1" //nolint:gosec // Github uses SHA1.
+	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/pierrre/githubhook/events"
 )
 
 /*
 Handler is a [http.Handler] for GitHub webhook.
 
+It should be created with [NewHandler], which validates the configuration.
+
 It supports both JSON and form content types.
+It supports both SHA-256 (X-Hub-Signature-256) and SHA-1 (X-Hub-Signature) signatures.
 
 Fields (all are optional):
   - Secret is the secret defined in GitHub webhook.
-  - DecodePayload is called to decode payload. If it's not defined, JSON unmarshal is used.
-  - Delivery is called if a valid delivery is received.
+  - Secrets are additional secrets accepted, e.g. during secret rotation. A signature is valid if it matches Secret or any of them.
+  - SecretProvider returns the secret for each request (e.g. per tenant). It is mutually exclusive with Secret.
+  - SignatureScheme verifies the internal signature of a trusted proxy that re-signs the deliveries, instead of GitHub's signature. It is mutually exclusive with Secret, Secrets and SecretProvider.
+  - DecodePayload is called to decode payload. If it's not defined, [events.Decode] is used: known events are decoded to their type (e.g. *[events.PushEvent]), other events to a map[string]any.
+  - Delivery is called if a valid delivery is received, with its [DeliveryMetadata]. See [Chain], [Tee] and [If] to compose handlers.
+    If it returns an error, the response status is 500 (or the status of the [RequestError]), so GitHub can redeliver it.
   - Error is called if an error happened.
+  - SecurityHeaders enables standard security headers on all responses.
+  - ResponseModifier is called before the response is written, with its headers and status code.
+  - CORS enables CORS handling, including preflight requests.
+  - SignaturePolicy defines how signatures are verified if both X-Hub-Signature-256 and X-Hub-Signature are present.
+  - TrustedSources skips the signature verification for requests coming from trusted sources.
+  - Quarantine stores the deliveries that fail one of the SoftChecks, instead of processing them.
+  - SoftChecks are the checks applied to verified deliveries if Quarantine is defined.
+  - LenientHeaders enables tolerant header parsing, for requests modified by proxies (see below).
+  - Async enables asynchronous processing: verified deliveries are queued in the pool, and acknowledged with a 202 response.
+  - AsyncLanes route the deliveries to dedicated pools by event or payload size (e.g. large push events to a slow lane), instead of Async. The first matching lane is used.
+  - Dedup enables deduplication: a delivery ID is delivered at most once, duplicates are answered according to DuplicatePolicy. Failed deliveries are forgotten, so they can be redelivered.
+  - DuplicatePolicy defines the response to duplicates (default: [DuplicateAck], a 200 response).
+  - MaxBodySize is the maximum size of the request body (default: [DefaultMaxBodySize]). Larger requests are rejected with a 413 response. If it's negative, the size is not limited.
+  - Ping is called for "ping" deliveries (sent when a webhook is created), instead of Delivery.
+  - AckPing acknowledges "ping" deliveries without calling Delivery.
+  - GenerateDeliveryID generates the delivery ID of synthetic deliveries ([Handler.SelfTest], and [Record]s without delivery ID). By default, the IDs are random.
+  - Observer observes the requests and deliveries, e.g. to collect metrics.
+  - RateLimiters limit the rate of verified deliveries per source (e.g. remote address or repository). Deliveries exceeding a limit are rejected with a 429 response.
+  - SourceAllowlist rejects requests whose source address is not allowed (e.g. not from GitHub), before reading the body. It is applied even to TrustedSources.
+  - ConcurrencyLimiter limits the number of requests handled concurrently. Requests beyond the limit are rejected with a 503 response.
+  - RejectForm rejects form encoded deliveries with a 415 response and a helpful message, to enforce the JSON content type recommended by GitHub. See [MigrateFormHook] to switch the existing webhooks to JSON.
+  - Costs accounts the processing time of the deliveries (decoding and Delivery or Sink), per event and tenant.
+  - Sink is called if a valid delivery is received, with the [VerifiedDelivery], instead of Delivery. It is mutually exclusive with Delivery. See [Fanout], [Fallback] and [Filtered] to compose sinks, and [Handler.Start] to start it before the deliveries are received.
+  - Store persists the verified deliveries (except duplicates) with their processing status, e.g. for replay, audit and admin tooling. If a delivery can't be stored, the response status is 500.
+  - Maintenance pauses the processing of the verified deliveries while the maintenance mode is enabled: they are stored and acknowledged with a 202 response, and resumed when it's disabled.
+  - Quota accounts the verified deliveries per repository per time window, and logs, alerts or throttles the repositories exceeding their quota.
+
+All callbacks receive the context of the request.
+If a callback panics, the panic is recovered and reported to Error as a [PanicError], and the response status is 500.
+
+By default, headers are parsed strictly.
+In lenient mode:
+  - header names are matched case-insensitively, and "_" is equivalent to "-"
+  - header values are trimmed
+  - the Content-Type header can have parameters, case variations, and duplicate (but equivalent) values
+
+The headers interpreted differently than in strict mode are recorded in [DeliveryMetadata.HeaderInterpretations].
 */
 type Handler struct {
-	Secret        string
-	DecodePayload func(event string, rawPayload []byte) (any, error)
-	Delivery      func(event string, deliveryID string, payload any)
-	Error         func(err error, req *http.Request)
+	Secret             string
+	Secrets            []string
+	SecretProvider     SecretProvider
+	SignatureScheme    *SignatureScheme
+	DecodePayload      func(ctx context.Context, event string, rawPayload []byte) (any, error)
+	Delivery           DeliveryHandler
+	Error              func(ctx context.Context, err error, req *http.Request)
+	SecurityHeaders    bool
+	ResponseModifier   func(header http.Header, statusCode int)
+	CORS               *CORS
+	SignaturePolicy    SignaturePolicy
+	TrustedSources     []TrustedSource
+	Quarantine         *Quarantine
+	SoftChecks         []SoftCheck
+	LenientHeaders     bool
+	Async              *AsyncPool
+	AsyncLanes         []*AsyncLane
+	Dedup              DedupStore
+	DuplicatePolicy    DuplicatePolicy
+	MaxBodySize        int64
+	Ping               func(ctx context.Context, md *DeliveryMetadata, ping *events.PingEvent) error
+	AckPing            bool
+	GenerateDeliveryID DeliveryIDGenerator
+	Observer           Observer
+	RateLimiters       []*RateLimiter
+	SourceAllowlist    *SourceAllowlist
+	ConcurrencyLimiter *ConcurrencyLimiter
+	RejectForm         bool
+	Costs              *Costs
+	Sink               Sink
+	Store              Store
+	Quota              *Quota
+	Maintenance        *Maintenance
+
+	withoutSecret bool
+	acceptEvent   func(event string) error
+	routeResponse func(event string) *RouteResponse
+	inflight      int32 // Updated atomically, so the handler can be copied before it's used, see [Handler.Close].
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	err := h.handleRequest(req)
-	if err != nil {
-		h.handleError(err, w, req)
+	if h.handleCORS(w, req) {
 		return
 	}
+	h.serve(w, newRequestInbound(req))
+}
+
+// serve handles a request, writes the response, and observes it.
+func (h *Handler) serve(w http.ResponseWriter, in *inbound) {
+	h.addInflight()
+	defer h.doneInflight()
+	md, statusCode, duplicate, err := h.handleRequest(in)
+	rr := h.getRouteResponse(md)
+	switch {
+	case err != nil:
+		statusCode = h.handleError(err, w, in, rr != nil && rr.SanitizeErrors)
+	case duplicate:
+		h.writeResponseHeader(w, statusCode)
+		if h.DuplicatePolicy != DuplicateAck {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(statusCode)
+			_, _ = io.WriteString(w, duplicateResponseBody)
+		} else {
+			w.WriteHeader(statusCode)
+		}
+	case rr != nil:
+		statusCode = h.writeRouteResponse(w, in, md, statusCode, rr)
+	default:
+		h.writeResponseHeader(w, statusCode)
+		w.WriteHeader(statusCode)
+	}
+	h.observeRequest(in.ctx, md, statusCode, err)
 }
 
-func (h *Handler) handleRequest(req *http.Request) error {
-	err := checkHTTPMethod(req)
+// handleRequest handles a request, and returns the metadata of the delivery if it has been verified.
+func (h *Handler) handleRequest(in *inbound) (md *DeliveryMetadata, statusCode int, duplicate bool, err error) {
+	defer recoverPanic(&err)
+	if h.ConcurrencyLimiter != nil {
+		err = h.ConcurrencyLimiter.acquire()
+		if err != nil {
+			return nil, 0, false, err
+		}
+		defer h.ConcurrencyLimiter.release()
+	}
+	md, rawPayload, err := h.verifyRequest(in)
 	if err != nil {
-		return err
+		return nil, 0, false, err
 	}
-	event, err := requireHeader("X-GitHub-Event", req)
+	err = h.checkRateLimiters(in, md, rawPayload)
 	if err != nil {
-		return err
+		return md, 0, false, err
 	}
-	deliveryID, err := requireHeader("X-GitHub-Delivery", req)
+	err = h.checkQuota(in.ctx, md, rawPayload)
 	if err != nil {
-		return err
+		return md, 0, false, err
+	}
+	rawPayload = in.retainPayload(rawPayload)
+	if h.quarantine(md, rawPayload, in) {
+		return md, http.StatusOK, false, nil
 	}
-	rawPayload, err := getRawPayload(req)
+	statusCode, duplicate, err = h.process(in.ctx, md, rawPayload)
+	return md, statusCode, duplicate, err
+}
+
+// process deduplicates, stores and delivers a verified delivery.
+// It returns duplicate=true if the delivery is a duplicate, with the status code of [Handler.DuplicatePolicy].
+func (h *Handler) process(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (statusCode int, duplicate bool, err error) {
+	duplicate, err = h.isDuplicate(ctx, md)
 	if err != nil {
-		return err
+		return 0, false, err
 	}
-	err = h.checkSignature(rawPayload, req)
+	if duplicate {
+		return h.DuplicatePolicy.statusCode(), true, nil
+	}
+	err = h.storeDelivery(ctx, md, rawPayload)
 	if err != nil {
-		return err
+		if forgetErr := h.forgetDuplicate(ctx, md); forgetErr != nil {
+			err = errors.Join(err, forgetErr)
+		}
+		return 0, false, err
 	}
-	payload, err := h.decodePayload(event, rawPayload)
+	statusCode, err = h.deliver(ctx, md, rawPayload)
 	if err != nil {
-		return err
+		h.updateStoredDelivery(ctx, md, rawPayload, err)
+		if forgetErr := h.forgetDuplicate(ctx, md); forgetErr != nil {
+			err = errors.Join(err, forgetErr)
+		}
+		return 0, false, err
 	}
-	if h.Delivery != nil {
-		h.Delivery(event, deliveryID, payload)
+	if statusCode != http.StatusAccepted {
+		h.updateStoredDelivery(ctx, md, rawPayload, nil)
 	}
-	return nil
+	return statusCode, false, nil
+}
+
+// deliver decodes the payload and calls the delivery handler, or holds the delivery in [Handler.Maintenance].
+//
+// It returns the response status code: 200 if the delivery has been processed, or 202 if it has been queued in [Handler.Async] (or [Handler.AsyncLanes]) or held.
+func (h *Handler) deliver(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (int, error) {
+	held, err := h.hold(ctx, md, rawPayload)
+	if err != nil {
+		return 0, err
+	}
+	if held {
+		return http.StatusAccepted, nil
+	}
+	return h.deliverNow(ctx, md, rawPayload)
+}
+
+// deliverNow decodes the payload and calls the delivery handler.
+func (h *Handler) deliverNow(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (int, error) {
+	if md.Event == events.NamePing && (h.Ping != nil || h.AckPing) {
+		return h.handlePing(ctx, md, rawPayload)
+	}
+	payload, err := h.decodePayload(ctx, md, rawPayload)
+	if err != nil {
+		return 0, err
+	}
+	if h.Delivery == nil && h.Sink == nil {
+		return http.StatusOK, nil
+	}
+	if pool := h.getAsyncPool(md, rawPayload); pool != nil {
+		err = h.deliverAsync(ctx, pool, md, rawPayload, payload)
+		if err != nil {
+			return 0, err
+		}
+		return http.StatusAccepted, nil
+	}
+	err = h.callDelivery(ctx, md, rawPayload, payload)
+	recordCompare(ctx, func(res *CompareResult) {
+		res.DeliveryErr = err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("delivery: %w", err)
+	}
+	return http.StatusOK, nil
 }
 
-func checkHTTPMethod(req *http.Request) error {
-	if method := req.Method; method != "POST" {
+// verifyRequest checks the source of the request, parses it, verifies its signature, and checks that the event is accepted.
+func (h *Handler) verifyRequest(in *inbound) (*DeliveryMetadata, []byte, error) {
+	err := h.checkSourceAllowlist(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	event, deliveryID, rawPayload, err := h.parseRequest(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	signatureHeaders, err := h.checkSignature(in.ctx, event, rawPayload, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.acceptEvent != nil {
+		err = h.acceptEvent(event)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	md := h.newDeliveryMetadata(event, deliveryID, in)
+	md.SignatureHeaders = signatureHeaders
+	if h.LenientHeaders {
+		md.HeaderInterpretations = getLenientHeaderInterpretations(in.request().Header)
+	}
+	return md, rawPayload, nil
+}
+
+// parseRequest checks the method and headers of the request, and returns the event, the delivery ID and the raw payload.
+func (h *Handler) parseRequest(in *inbound) (event string, deliveryID string, rawPayload []byte, err error) {
+	err = checkHTTPMethod(in.method)
+	if err != nil {
+		return "", "", nil, err
+	}
+	event, err = h.requireHeader("X-GitHub-Event", in)
+	if err != nil {
+		return "", "", nil, err
+	}
+	deliveryID, err = h.requireHeader("X-GitHub-Delivery", in)
+	if err != nil {
+		return "", "", nil, err
+	}
+	rawPayload, err = h.getRawPayload(in)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return event, deliveryID, rawPayload, nil
+}
+
+func checkHTTPMethod(method string) error {
+	if method != "POST" {
 		return &RequestError{
 			StatusCode: http.StatusMethodNotAllowed,
 			Message:    "method not allowed: " + method,
@@ -80,16 +310,23 @@ func checkHTTPMethod(req *http.Request) error {
 	return nil
 }
 
-func getRawPayload(req *http.Request) ([]byte, error) {
-	switch t := req.Header.Get("Content-Type"); t {
+func (h *Handler) getRawPayload(in *inbound) ([]byte, error) {
+	t, err := h.getContentType(in)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
 	case "application/json":
-		b, err := io.ReadAll(req.Body)
+		return in.readBody(h.getMaxBodySize())
+	case "application/x-www-form-urlencoded":
+		if h.RejectForm {
+			return nil, newFormRejectedError()
+		}
+		form, err := in.readForm(h.getMaxBodySize())
 		if err != nil {
-			return nil, fmt.Errorf("read body: %w", err)
+			return nil, err
 		}
-		return b, nil
-	case "application/x-www-form-urlencoded":
-		return []byte(req.PostFormValue("payload")), nil
+		return []byte(form.Get("payload")), nil
 	default:
 		return nil, &RequestError{
 			StatusCode: http.StatusBadRequest,
@@ -98,8 +335,30 @@ func getRawPayload(req *http.Request) ([]byte, error) {
 	}
 }
 
-func requireHeader(name string, req *http.Request) (string, error) {
-	hd := req.Header.Get(name)
+func newFormRejectedError() error {
+	return &RequestError{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Message:    "form content type is not supported: configure the webhook content type as application/json",
+		Err:        ErrFormRejected,
+	}
+}
+
+func (h *Handler) getContentType(in *inbound) (string, error) {
+	if h.LenientHeaders {
+		return getLenientContentType(in.request().Header)
+	}
+	return in.header("Content-Type"), nil
+}
+
+func (h *Handler) getHeader(name string, in *inbound) string {
+	if h.LenientHeaders {
+		return getLenientHeader(in.request().Header, name)
+	}
+	return in.header(name)
+}
+
+func (h *Handler) requireHeader(name string, in *inbound) (string, error) {
+	hd := h.getHeader(name, in)
 	if hd == "" {
 		return "", &RequestError{
 			StatusCode: http.StatusBadRequest,
@@ -109,82 +368,227 @@ func requireHeader(name string, req *http.Request) (string, error) {
 	return hd, nil
 }
 
-func (h *Handler) checkSignature(rawPayload []byte, req *http.Request) error {
-	if h.Secret == "" {
-		return nil
+// SignaturePolicy defines how signatures are verified when a request contains several signature headers.
+type SignaturePolicy int
+
+const (
+	// SignaturePolicyAll verifies all signatures, and rejects the request if any of them is invalid.
+	SignaturePolicyAll SignaturePolicy = iota
+	// SignaturePolicyStrongest verifies only the strongest signature.
+	SignaturePolicyStrongest
+)
+
+type signatureScheme struct {
+	header string
+	prefix string
+	hash   func() hash.Hash
+}
+
+// getSignatureHeaders returns the signature headers of the schemes, for the error messages.
+func getSignatureHeaders(schemes []signatureScheme) string {
+	headers := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		headers[i] = scheme.header
 	}
-	signature, err := requireHeader("X-Hub-Signature", req)
-	if err != nil {
-		return err
+	return strings.Join(headers, " or ")
+}
+
+// signatureSchemes is sorted from the strongest to the weakest.
+var signatureSchemes = []signatureScheme{
+	{
+		header: "X-Hub-Signature-256",
+		prefix: "sha256=",
+		hash:   sha256.New,
+	},
+	{
+		header: "X-Hub-Signature",
+		prefix: "sha1=",
+		hash:   sha1.New,
+	},
+}
+
+// checkSignature verifies the signature of a request, and returns the verified signature headers.
+func (h *Handler) checkSignature(ctx context.Context, event string, rawPayload []byte, in *inbound) ([]string, error) {
+	if h.isTrustedSource(in) {
+		return nil, nil
 	}
-	err = h.checkSignaturePayload(rawPayload, signature)
+	return h.verifySignature(ctx, in, event, rawPayload, func(name string) string {
+		return h.getHeader(name, in)
+	})
+}
+
+// verifySignature verifies the signature headers returned by the header function, and returns the verified headers.
+// The request can be nil.
+func (h *Handler) verifySignature(ctx context.Context, in *inbound, event string, rawPayload []byte, header func(name string) string) ([]string, error) {
+	var req *http.Request
+	if in != nil && (h.SecretProvider != nil || h.SignatureScheme != nil) {
+		req = in.request()
+	}
+	secrets, err := h.getCandidateSecrets(ctx, req, event)
 	if err != nil {
-		return &RequestError{
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		if h.SecretProvider == nil && len(h.Secrets) == 0 && h.SignatureScheme == nil {
+			// No secret is configured (see WithoutSecret).
+			return nil, nil
+		}
+		// A configured provider that returns no secret must not accept unsigned deliveries.
+		return nil, &RequestError{
+			StatusCode: http.StatusForbidden,
+			Message:    "no secret",
+			Err:        ErrNoSecret,
+		}
+	}
+	schemes := h.getSignatureSchemes()
+	var checked []string
+	for _, scheme := range schemes {
+		signature := header(scheme.header)
+		if signature == "" {
+			continue
+		}
+		err := checkSignaturePayload(secrets, rawPayload, signature, scheme)
+		if err != nil {
+			return nil, &RequestError{
+				StatusCode: http.StatusBadRequest,
+				Message:    fmt.Sprintf("invalid header %s: %s", scheme.header, err),
+				Err:        fmt.Errorf("%w: %w", ErrInvalidSignature, err),
+			}
+		}
+		checked = append(checked, scheme.header)
+		if h.SignaturePolicy == SignaturePolicyStrongest {
+			break
+		}
+	}
+	if len(checked) == 0 {
+		return nil, &RequestError{
 			StatusCode: http.StatusBadRequest,
-			Message:    fmt.Sprintf("invalid header X-Hub-Signature: %s", err),
+			Message:    "missing header: " + getSignatureHeaders(schemes),
+			Err:        ErrInvalidSignature,
 		}
 	}
-	return nil
+	return checked, nil
 }
 
-func (h *Handler) checkSignaturePayload(rawPayload []byte, signature string) error {
-	if !strings.HasPrefix(signature, "sha1=") {
+func checkSignaturePayload(secrets [][]byte, rawPayload []byte, signature string, scheme signatureScheme) error {
+	if !strings.HasPrefix(signature, scheme.prefix) {
 		return errors.New("format")
 	}
-	signature = strings.TrimPrefix(signature, "sha1=")
+	signature = strings.TrimPrefix(signature, scheme.prefix)
 	requestMAC, err := hex.DecodeString(signature)
 	if err != nil {
 		return fmt.Errorf("decode hex: %w", err)
 	}
-	hash := hmac.New(sha1.New, []byte(h.Secret))
-	_, _ = hash.Write(rawPayload)
-	expectedMAC := hash.Sum(nil)
-	if !hmac.Equal(requestMAC, expectedMAC) {
-		return errors.New("doesn't match secret")
+	for _, secret := range secrets {
+		mac := hmac.New(scheme.hash, secret)
+		_, _ = mac.Write(rawPayload)
+		expectedMAC := mac.Sum(nil)
+		if hmac.Equal(requestMAC, expectedMAC) {
+			return nil
+		}
 	}
-	return nil
+	return errors.New("doesn't match secret")
 }
 
-func (h *Handler) decodePayload(event string, rawPayload []byte) (any, error) {
+func (h *Handler) decodePayload(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (any, error) {
+	if h.Costs != nil {
+		defer h.Costs.start(md, CostStageDecode)()
+	}
 	var payload any
 	var err error
 	if h.DecodePayload != nil {
-		payload, err = h.DecodePayload(event, rawPayload)
+		payload, err = h.DecodePayload(ctx, md.Event, rawPayload)
 	} else {
-		err = json.Unmarshal(rawPayload, &payload)
+		payload, err = events.Decode(md.Event, rawPayload)
 	}
 	if err != nil {
-		return nil, &RequestError{
-			StatusCode: http.StatusBadRequest,
-			Message:    fmt.Sprintf("payload decode error: %s", err),
-		}
+		return nil, newPayloadDecodeError(err)
 	}
+	recordCompare(ctx, func(res *CompareResult) {
+		res.Payload = payload
+	})
 	return payload, nil
 }
 
-func (h *Handler) handleError(err error, w http.ResponseWriter, req *http.Request) {
-	var statusCode int
+// handleError writes the error response, and calls [Handler.Error].
+// If sanitize is true, the message of the response is the status text, see [RouteResponse.SanitizeErrors].
+func (h *Handler) handleError(err error, w http.ResponseWriter, in *inbound, sanitize bool) (statusCode int) {
 	var message string
 	var reqErr *RequestError
 	if errors.As(err, &reqErr) {
 		statusCode = reqErr.StatusCode
 		message = reqErr.Message
+		for k, v := range reqErr.Header {
+			w.Header()[k] = v
+		}
 	} else {
 		statusCode = http.StatusInternalServerError
 		message = http.StatusText(statusCode)
 	}
+	if sanitize {
+		message = http.StatusText(statusCode)
+	}
+	h.writeResponseHeader(w, statusCode)
 	http.Error(w, message, statusCode)
 	if h.Error != nil {
-		h.Error(err, req)
+		h.Error(in.ctx, err, in.request())
+	}
+	return statusCode
+}
+
+var securityHeaders = map[string]string{
+	"Cache-Control":             "no-store",
+	"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+	"Referrer-Policy":           "no-referrer",
+	"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	"X-Content-Type-Options":    "nosniff",
+	"X-Frame-Options":           "DENY",
+}
+
+func (h *Handler) writeResponseHeader(w http.ResponseWriter, statusCode int) {
+	header := w.Header()
+	if h.SecurityHeaders {
+		for k, v := range securityHeaders {
+			header.Set(k, v)
+		}
+	}
+	if h.ResponseModifier != nil {
+		h.ResponseModifier(header, statusCode)
 	}
 }
 
+func newPayloadDecodeError(err error) error {
+	return &RequestError{
+		StatusCode: http.StatusBadRequest,
+		Message:    fmt.Sprintf("payload decode error: %s", err),
+		Err:        fmt.Errorf("%w: %w", ErrPayloadDecode, err),
+	}
+}
+
+var (
+	// ErrInvalidSignature is returned (wrapped in a [RequestError]) if the signature is missing or invalid.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrPayloadDecode is returned (wrapped in a [RequestError]) if the payload can't be decoded.
+	ErrPayloadDecode = errors.New("payload decode")
+	// ErrFormRejected is returned (wrapped in a [RequestError]) if a form encoded delivery is rejected by [Handler.RejectForm].
+	ErrFormRejected = errors.New("form content type rejected")
+)
+
 // RequestError represents a request error.
+//
+// Err is the optional underlying error.
+// Header contains optional additional response headers (e.g. Retry-After).
 type RequestError struct {
 	StatusCode int
 	Message    string
+	Err        error
+	Header     http.Header
 }
 
 func (err *RequestError) Error() string {
 	return fmt.Sprintf("request error %d: %s", err.StatusCode, err.Message)
 }
+
+func (err *RequestError) Unwrap() error {
+	return err.Err
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1" //nolint:gosec // Github uses SHA1.
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook/events"
 )
 
 var testRawPayload = []byte(`{"foo":"bar"}`)
@@ -53,6 +55,27 @@ func TestHandlerForm(t *testing.T) {
 	testExpectResponseStatusOK(t, resp)
 }
 
+func TestHandlerRejectForm(t *testing.T) {
+	var handledErr error
+	h := &Handler{
+		RejectForm: true,
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	form := make(url.Values)
+	form.Set("payload", string(testRawPayload))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusUnsupportedMediaType)
+	assert.StringContains(t, w.Body.String(), "application/json")
+	assert.ErrorIs(t, handledErr, ErrFormRejected)
+}
+
 func TestHandlerSecret(t *testing.T) {
 	ctx := context.Background()
 	h := &Handler{
@@ -69,12 +92,103 @@ func TestHandlerSecret(t *testing.T) {
 	testExpectResponseStatusOK(t, resp)
 }
 
+func TestHandlerSecretSHA256(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	testSignRequestSHA256(req, h.Secret, testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+}
+
+func TestHandlerSecretSignaturePolicyStrongest(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret:          "foobar",
+		SignaturePolicy: SignaturePolicyStrongest,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	testSignRequest(req, "wrong", testRawPayload)
+	testSignRequestSHA256(req, h.Secret, testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+}
+
+func TestHandlerSignatureHeaders(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		configure func(h *Handler)
+		expected  []string
+	}{
+		{
+			name:     "All",
+			expected: []string{"X-Hub-Signature-256", "X-Hub-Signature"},
+		},
+		{
+			name: "Strongest",
+			configure: func(h *Handler) {
+				h.SignaturePolicy = SignaturePolicyStrongest
+			},
+			expected: []string{"X-Hub-Signature-256"},
+		},
+		{
+			name: "TrustedSource",
+			configure: func(h *Handler) {
+				h.TrustedSources = []TrustedSource{TrustedSourceFunc(func(req *http.Request) bool {
+					return true
+				})}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var md *DeliveryMetadata
+			h := &Handler{
+				Secret: "foobar",
+				Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+					md = m
+					return nil
+				},
+			}
+			if tc.configure != nil {
+				tc.configure(h)
+			}
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			req := testNewJSONRequest(ctx, t, srv, h.Secret, testRawPayload)
+			testSignRequestSHA256(req, h.Secret, testRawPayload)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			testExpectResponseStatusOK(t, resp)
+			assert.SliceEqual(t, md.SignatureHeaders, tc.expected)
+		})
+	}
+}
+
 func TestHandlerDelivery(t *testing.T) {
 	ctx := context.Background()
 	deliveryCalled := false
 	h := &Handler{
-		Delivery: func(event string, deliveryId string, payload any) {
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
 			deliveryCalled = true
+			return nil
 		},
 	}
 	srv := httptest.NewServer(h)
@@ -89,11 +203,33 @@ func TestHandlerDelivery(t *testing.T) {
 	assert.True(t, deliveryCalled)
 }
 
+func TestHandlerDecodePayloadDefault(t *testing.T) {
+	ctx := context.Background()
+	var deliveryPayload any
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryPayload = payload
+			return nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", []byte(`{"ref":"refs/heads/main"}`))
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	ev, _ := assert.Type[*events.PushEvent](t, deliveryPayload)
+	assert.Equal(t, ev.Ref, "refs/heads/main")
+}
+
 func TestHandlerDecodePayload(t *testing.T) {
 	ctx := context.Background()
 	decodePayloadCalled := false
 	h := &Handler{
-		DecodePayload: func(event string, rawPayload []byte) (any, error) {
+		DecodePayload: func(ctx context.Context, event string, rawPayload []byte) (any, error) {
 			decodePayloadCalled = true
 			return string(rawPayload), nil
 		},
@@ -114,7 +250,7 @@ func TestHandlerError(t *testing.T) {
 	ctx := context.Background()
 	errorCalled := false
 	h := &Handler{
-		Error: func(err error, req *http.Request) {
+		Error: func(ctx context.Context, err error, req *http.Request) {
 			errorCalled = true
 		},
 	}
@@ -131,6 +267,47 @@ func TestHandlerError(t *testing.T) {
 	assert.True(t, errorCalled)
 }
 
+func TestHandlerSecurityHeaders(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		SecurityHeaders: true,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, resp.Header.Get("X-Frame-Options"), "DENY")
+	assert.Equal(t, resp.Header.Get("Cache-Control"), "no-store")
+}
+
+func TestHandlerResponseModifier(t *testing.T) {
+	ctx := context.Background()
+	var statusCode int
+	h := &Handler{
+		ResponseModifier: func(header http.Header, sc int) {
+			statusCode = sc
+			header.Set("X-Test", "test")
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, http.NoBody)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusMethodNotAllowed)
+	assert.Equal(t, statusCode, http.StatusMethodNotAllowed)
+	assert.Equal(t, resp.Header.Get("X-Test"), "test")
+}
+
 func TestHandlerErrorMethod(t *testing.T) {
 	ctx := context.Background()
 	h := &Handler{}
@@ -259,6 +436,24 @@ func TestHandlerErrorHeaderSignatureSecret(t *testing.T) {
 	testExpectResponseStatus(t, resp, http.StatusBadRequest)
 }
 
+func TestHandlerErrorHeaderSignatureMismatch(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	testSignRequest(req, "wrong", testRawPayload)
+	testSignRequestSHA256(req, h.Secret, testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
+
 func TestHandlerErrorDecodePayload(t *testing.T) {
 	ctx := context.Background()
 	h := &Handler{}
@@ -274,13 +469,52 @@ func TestHandlerErrorDecodePayload(t *testing.T) {
 	testExpectResponseStatus(t, resp, http.StatusBadRequest)
 }
 
+func TestHandlerErrorDelivery(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errors.New("error")
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusInternalServerError)
+}
+
+func TestHandlerErrorDeliveryRequestError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return &RequestError{
+				StatusCode: http.StatusServiceUnavailable,
+				Message:    "unavailable",
+			}
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusServiceUnavailable)
+}
+
 func TestHandlerErrorInternal(t *testing.T) {
 	ctx := context.Background()
 	w := httptest.NewRecorder()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost", http.NoBody)
 	assert.NoError(t, err)
 	h := &Handler{}
-	h.handleError(errors.New("internal error"), w, req)
+	h.handleError(errors.New("internal error"), w, newRequestInbound(req), false)
 	assert.Equal(t, w.Code, http.StatusInternalServerError)
 }
 
@@ -321,6 +555,15 @@ func testSignRequest(req *http.Request, secret string, rawPayload []byte) {
 	req.Header.Set("X-Hub-Signature", signature)
 }
 
+func testSignRequestSHA256(req *http.Request, secret string, rawPayload []byte) {
+	hash := hmac.New(sha256.New, []byte(secret))
+	_, _ = hash.Write(rawPayload)
+	mac := hash.Sum(nil)
+	signature := hex.EncodeToString(mac)
+	signature = "sha256=" + signature
+	req.Header.Set("X-Hub-Signature-256", signature)
+}
+
 func testGetRandomDeliveryID(t *testing.T) string {
 	t.Helper()
 	buf := make([]byte, 16)
@@ -342,3 +585,29 @@ func testExpectResponseStatus(t *testing.T, resp *http.Response, statusCode int)
 	t.Helper()
 	assert.Equal(t, statusCode, resp.StatusCode)
 }
+
+type testContextKey struct{}
+
+func TestHandlerContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), testContextKey{}, "value")
+	var decodeValue, deliveryValue any
+	h := &Handler{
+		DecodePayload: func(ctx context.Context, event string, rawPayload []byte) (any, error) {
+			decodeValue = ctx.Value(testContextKey{})
+			return nil, nil
+		},
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryValue = ctx.Value(testContextKey{})
+			return nil
+		},
+	}
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(testRawPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal[any](t, decodeValue, "value")
+	assert.Equal[any](t, deliveryValue, "value")
+}
@@ -0,0 +1,86 @@
+// Package githubhookaws provides [Amazon SQS] and [Amazon SNS] sinks, and an [Amazon S3] archiver, for verified GitHub webhook deliveries, with the [AWS SDK for Go v2].
+//
+// The deliveries are sent to a queue with [SQS], or published to a topic with [SNS]:
+//
+//	http.Handle("/webhook", h.Middleware(&githubhookaws.SQS{Client: sqs.NewFromConfig(cfg), QueueURL: queueURL}))
+//
+// The message body is the raw JSON payload.
+// The message attributes are the event name, the delivery ID, the repository full name (if the payload has a repository), and the lineage (see [githubhook.Lineage]).
+//
+// If the queue or the topic is FIFO (the name has the ".fifo" suffix), the message group ID is the repository full name (or the event name if the payload doesn't have a repository), so the deliveries of a repository are ordered.
+// The message deduplication ID is the delivery ID, so redeliveries are deduplicated.
+//
+// The raw payloads can be archived to an S3 (or S3-compatible) bucket with [Archiver].
+//
+// [Amazon SQS]: https://aws.amazon.com/sqs/
+// [Amazon SNS]: https://aws.amazon.com/sns/
+// [Amazon S3]: https://aws.amazon.com/s3/
+// [AWS SDK for Go v2]: https://github.com/aws/aws-sdk-go-v2
+package githubhookaws
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pierrre/githubhook"
+)
+
+// Message attribute names.
+const (
+	// AttributeEvent is the name of the event name attribute.
+	AttributeEvent = "X-GitHub-Event"
+	// AttributeDeliveryID is the name of the delivery ID attribute.
+	AttributeDeliveryID = "X-GitHub-Delivery"
+	// AttributeRepository is the name of the repository full name attribute.
+	AttributeRepository = "Repository"
+)
+
+const fifoSuffix = ".fifo"
+
+type message struct {
+	body            string
+	attributes      map[string]string
+	groupID         string
+	deduplicationID string
+}
+
+func newMessage(md *githubhook.DeliveryMetadata, rawPayload []byte, fifo bool) *message {
+	attributes := map[string]string{
+		AttributeEvent:      md.Event,
+		AttributeDeliveryID: md.DeliveryID,
+	}
+	repository := githubhook.RepositoryFullName(json.RawMessage(rawPayload))
+	if repository != "" {
+		attributes[AttributeRepository] = repository
+	}
+	if md.Lineage != nil {
+		header := make(http.Header)
+		md.Lineage.SetHeader(header)
+		for k := range header {
+			attributes[k] = header.Get(k)
+		}
+	}
+	// Attribute values must not be empty.
+	for k, v := range attributes {
+		if v == "" {
+			delete(attributes, k)
+		}
+	}
+	msg := &message{
+		body:       string(rawPayload),
+		attributes: attributes,
+	}
+	if fifo {
+		msg.groupID = repository
+		if msg.groupID == "" {
+			msg.groupID = md.Event
+		}
+		msg.deduplicationID = md.DeliveryID
+	}
+	return msg
+}
+
+func isFIFO(s string) bool {
+	return strings.HasSuffix(s, fifoSuffix)
+}
@@ -0,0 +1,69 @@
+package githubhookaws
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main","repository":{"full_name":"pierrre/githubhook"}}`)
+
+func testNewRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := new(githubhook.Signer).NewRequest(context.Background(), "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	return req
+}
+
+func TestNewMessage(t *testing.T) {
+	md := &githubhook.DeliveryMetadata{
+		Event:      "push",
+		DeliveryID: "test",
+	}
+	msg := newMessage(md, testRawPayload, false)
+	assert.Equal(t, msg.body, string(testRawPayload))
+	assert.MapEqual(t, msg.attributes, map[string]string{
+		AttributeEvent:      "push",
+		AttributeDeliveryID: "test",
+		AttributeRepository: "pierrre/githubhook",
+	})
+	assert.Zero(t, msg.groupID)
+	assert.Zero(t, msg.deduplicationID)
+}
+
+func TestNewMessageFIFO(t *testing.T) {
+	md := &githubhook.DeliveryMetadata{
+		Event:      "push",
+		DeliveryID: "test",
+	}
+	msg := newMessage(md, testRawPayload, true)
+	assert.Equal(t, msg.groupID, "pierrre/githubhook")
+	assert.Equal(t, msg.deduplicationID, "test")
+}
+
+func TestNewMessageFIFONoRepository(t *testing.T) {
+	md := &githubhook.DeliveryMetadata{
+		Event:      "ping",
+		DeliveryID: "test",
+	}
+	msg := newMessage(md, []byte(`{}`), true)
+	assert.Equal(t, msg.groupID, "ping")
+	_, ok := msg.attributes[AttributeRepository]
+	assert.False(t, ok)
+}
+
+func TestNewMessageLineage(t *testing.T) {
+	md := &githubhook.DeliveryMetadata{
+		Event:      "push",
+		DeliveryID: "test",
+	}
+	md.Lineage = githubhook.NextLineage(md, "", "replay")
+	msg := newMessage(md, testRawPayload, false)
+	assert.Equal(t, msg.attributes[githubhook.LineageOriginalDeliveryHeader], "test")
+	assert.Equal(t, msg.attributes[githubhook.LineageReasonHeader], "replay")
+	_, ok := msg.attributes[githubhook.LineageOperatorHeader]
+	assert.False(t, ok)
+}
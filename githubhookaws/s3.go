@@ -0,0 +1,244 @@
+package githubhookaws
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pierrre/githubhook"
+)
+
+// S3Client writes objects to S3 buckets.
+//
+// It's implemented by [s3.Client], including for S3-compatible storages (e.g. MinIO, with a custom endpoint).
+type S3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+var (
+	// ErrArchiverClosed is reported to [Archiver.Error] if the [Archiver] has been shut down.
+	// It's [githubhook.ErrAsyncPoolClosed], because the archiver uses a [githubhook.AsyncPool].
+	ErrArchiverClosed = githubhook.ErrAsyncPoolClosed
+	// ErrArchiverQueueFull is reported to [Archiver.Error] if the queue of the [Archiver] is full.
+	// It's [githubhook.ErrAsyncQueueFull], because the archiver uses a [githubhook.AsyncPool].
+	ErrArchiverQueueFull = githubhook.ErrAsyncQueueFull
+)
+
+/*
+Archiver writes verified raw payloads to an S3 (or S3-compatible) bucket, for long-term audit and offline analysis.
+
+Each delivery is written to an object with the key "<owner>/<repo>/<event>/<delivery-id>.json" (without "<owner>/<repo>/" if the payload doesn't have a repository).
+The segments of the key are escaped, so a delivery ID or a repository sent by a client can't write outside of Prefix (see [Archiver.ObjectKey]).
+The object is a JSON [githubhook.Record], with the headers of the delivery, so it can be processed again with [githubhook.Handler.ProcessRecords].
+
+The deliveries are archived asynchronously, by a [githubhook.AsyncPool], so the archival never delays the HTTP response nor fails the delivery.
+If the queue is full, or if an object can't be written, the error is reported to Error, and the delivery is not archived.
+
+It can be used as:
+  - a [githubhook.Sink], e.g. combined with another sink with [githubhook.Fanout].
+  - a [githubhook.DeliveryHandler] with [Archiver.Handle].
+
+Fields (must be set before the archiver is used):
+  - Prefix is the prefix of the object keys (optional).
+  - Gzip compresses the objects with gzip. The objects have the "gzip" content encoding, so they are decompressed transparently by HTTP clients.
+  - Error is called if a delivery can't be archived (optional).
+
+It must be created with [NewArchiver], and shut down with [Archiver.Shutdown] (or [Archiver.Close], e.g. by [githubhook.Handler.Close]).
+*/
+type Archiver struct {
+	Prefix string
+	Gzip   bool
+	Error  func(md *githubhook.DeliveryMetadata, err error)
+
+	client S3Client
+	bucket string
+	pool   *githubhook.AsyncPool
+}
+
+// NewArchiver creates a new [Archiver] and starts its workers.
+//
+// workers is the number of concurrent writes (minimum 1), and queueSize the number of pending deliveries.
+func NewArchiver(client S3Client, bucket string, workers int, queueSize int) *Archiver {
+	return &Archiver{
+		client: client,
+		bucket: bucket,
+		pool:   githubhook.NewAsyncPool(workers, queueSize),
+	}
+}
+
+// Handle archives a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+// If the payload is a [json.RawMessage] or a []byte, it's archived as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are not archived.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func (a *Archiver) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	return githubhook.SinkDeliveryHandler(a)(ctx, md, payload)
+}
+
+// Send archives a verified delivery.
+//
+// It implements [githubhook.Sink].
+func (a *Archiver) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	return a.Archive(ctx, &d.DeliveryMetadata, d.RawPayload)
+}
+
+// Archive queues a delivery with its raw JSON payload, and returns immediately.
+//
+// It always returns nil: the errors are reported to [Archiver.Error].
+func (a *Archiver) Archive(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	ctx = context.WithoutCancel(ctx)
+	err := a.pool.SubmitDelivery(md.DeliveryID, func() {
+		err := a.PutObject(ctx, md, rawPayload)
+		if err != nil {
+			a.handleError(md, err)
+		}
+	})
+	if err != nil {
+		a.handleError(md, err)
+	}
+	return nil
+}
+
+func (a *Archiver) handleError(md *githubhook.DeliveryMetadata, err error) {
+	if a.Error != nil {
+		a.Error(md, fmt.Errorf("S3: archive %q: %w", md.DeliveryID, err))
+	}
+}
+
+// Start validates the bucket and the credentials, before the deliveries are archived.
+//
+// It implements [githubhook.SinkStarter].
+// If the client implements HeadBucket (e.g. [s3.Client]), the bucket is requested, so an unknown bucket or a denied access is detected, instead of being reported to Error for each delivery.
+// It also pre-establishes the connection of the client.
+func (a *Archiver) Start(ctx context.Context) error {
+	c, ok := a.client.(interface {
+		HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	})
+	if !ok {
+		return nil
+	}
+	_, err := c.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(a.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("S3: head bucket: %w", err)
+	}
+	return nil
+}
+
+// PutObject writes a delivery with its raw JSON payload, synchronously.
+func (a *Archiver) PutObject(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	body, err := newArchiveBody(md, rawPayload, a.Gzip)
+	if err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(a.ObjectKey(md, rawPayload)),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+		Metadata: map[string]string{
+			"received-at": md.ReceivedAt.UTC().Format(time.RFC3339Nano),
+		},
+	}
+	if a.Gzip {
+		input.ContentEncoding = aws.String("gzip")
+	}
+	err = githubhook.TracePublish(ctx, a.bucket+"/"+*input.Key, md.DeliveryID, func() error {
+		_, err := a.client.PutObject(ctx, input)
+		return err //nolint:wrapcheck // The error is wrapped below.
+	})
+	if err != nil {
+		return fmt.Errorf("S3: put object: %w", err)
+	}
+	return nil
+}
+
+// ObjectKey returns the key of the object of a delivery.
+//
+// The delivery ID, the event and the segments of the repository are escaped with [url.PathEscape] (and the "." and ".." segments with "%2E"), because they are sent by the client.
+func (a *Archiver) ObjectKey(md *githubhook.DeliveryMetadata, rawPayload []byte) string {
+	segments := []string{a.Prefix}
+	if repo := githubhook.RepositoryFullName(json.RawMessage(rawPayload)); repo != "" {
+		for _, s := range strings.Split(repo, "/") {
+			segments = append(segments, escapeKeySegment(s))
+		}
+	}
+	segments = append(segments, escapeKeySegment(md.Event), escapeKeySegment(md.DeliveryID)+".json")
+	return path.Join(segments...)
+}
+
+func escapeKeySegment(s string) string {
+	s = url.PathEscape(s)
+	if strings.Trim(s, ".") == "" {
+		return strings.Repeat("%2E", len(s))
+	}
+	return s
+}
+
+func newArchiveBody(md *githubhook.DeliveryMetadata, rawPayload []byte, gz bool) ([]byte, error) {
+	headers := make(map[string]string)
+	for k, v := range map[string]string{
+		"X-GitHub-Hook-ID":                       md.HookID,
+		"X-GitHub-Hook-Installation-Target-Type": md.InstallationTargetType,
+		"X-GitHub-Hook-Installation-Target-ID":   md.InstallationTargetID,
+		"User-Agent":                             md.UserAgent,
+	} {
+		if v != "" {
+			headers[k] = v
+		}
+	}
+	b, err := json.Marshal(&githubhook.Record{
+		Event:      md.Event,
+		DeliveryID: md.DeliveryID,
+		Headers:    headers,
+		Payload:    rawPayload,
+		Lineage:    md.Lineage,
+		ReceivedAt: md.ReceivedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JSON encode: %w", err)
+	}
+	if !gz {
+		return b, nil
+	}
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	_, _ = w.Write(b) // A bytes.Buffer doesn't return an error.
+	_ = w.Close()
+	return buf.Bytes(), nil
+}
+
+// Shutdown stops accepting new deliveries, and waits until the pending deliveries are archived.
+//
+// It returns the context error if the context is done before.
+func (a *Archiver) Shutdown(ctx context.Context) error {
+	_, err := a.ShutdownWithReport(ctx)
+	return err
+}
+
+// Close shuts down the archiver with [Archiver.Shutdown], because it owns its workers.
+//
+// It implements [githubhook.SinkCloser], so an archiver shared by several handlers (e.g. across [githubhook.ReloadableHandler.Reload]) must be wrapped by a sink that doesn't close it.
+func (a *Archiver) Close(ctx context.Context) error {
+	return a.Shutdown(ctx)
+}
+
+// ShutdownWithReport is like [Archiver.Shutdown], and returns the report of the shutdown, with the deliveries that have not been archived (see [githubhook.AsyncPool.ShutdownWithReport]).
+func (a *Archiver) ShutdownWithReport(ctx context.Context) (*githubhook.ShutdownReport, error) {
+	r, err := a.pool.ShutdownWithReport(ctx)
+	if err != nil {
+		return r, fmt.Errorf("S3: archiver: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,256 @@
+package githubhookaws
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+type testS3Client struct {
+	mu     sync.Mutex
+	inputs []*s3.PutObjectInput
+	bodies [][]byte
+	bucket string
+	err    error
+	block  chan struct{}
+}
+
+func (c *testS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if c.block != nil {
+		<-c.block
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Test.
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inputs = append(c.inputs, params)
+	c.bodies = append(c.bodies, body)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (c *testS3Client) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bucket = *params.Bucket
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func TestArchiver(t *testing.T) {
+	ctx := context.Background()
+	c := &testS3Client{}
+	a := NewArchiver(c, "bucket", 1, 10)
+	a.Prefix = "github"
+	md := &githubhook.DeliveryMetadata{
+		Event:      "push",
+		DeliveryID: "test",
+		HookID:     "123",
+		ReceivedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	err := a.Send(ctx, &githubhook.VerifiedDelivery{DeliveryMetadata: *md, RawPayload: testRawPayload})
+	assert.NoError(t, err)
+	err = a.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, c.inputs, 1)
+	input := c.inputs[0]
+	assert.Equal(t, aws.ToString(input.Bucket), "bucket")
+	assert.Equal(t, aws.ToString(input.Key), "github/pierrre/githubhook/push/test.json")
+	assert.Equal(t, aws.ToString(input.ContentType), "application/json")
+	assert.Zero(t, input.ContentEncoding)
+	assert.Equal(t, input.Metadata["received-at"], "2024-01-01T00:00:00Z")
+	var r githubhook.Record
+	err = json.Unmarshal(c.bodies[0], &r)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Event, "push")
+	assert.Equal(t, r.DeliveryID, "test")
+	assert.Equal(t, r.Headers["X-GitHub-Hook-ID"], "123")
+	assert.Equal(t, string(r.Payload), string(testRawPayload))
+}
+
+func TestArchiverStart(t *testing.T) {
+	c := &testS3Client{}
+	a := NewArchiver(c, "bucket", 1, 1)
+	defer a.Shutdown(context.Background()) //nolint:errcheck // Not needed.
+	err := githubhook.StartSink(context.Background(), a)
+	assert.NoError(t, err)
+	assert.Equal(t, c.bucket, "bucket")
+	c.err = errors.New("error")
+	err = a.Start(context.Background())
+	assert.ErrorContains(t, err, "S3: head bucket: error")
+}
+
+func TestArchiverObjectKey(t *testing.T) {
+	a := NewArchiver(&testS3Client{}, "bucket", 1, 0)
+	a.Prefix = "github"
+	defer func() {
+		_ = a.Shutdown(context.Background())
+	}()
+	for _, tc := range []struct {
+		name       string
+		event      string
+		deliveryID string
+		rawPayload string
+		expected   string
+	}{
+		{
+			name:       "Repository",
+			event:      "push",
+			deliveryID: "test",
+			rawPayload: `{"repository":{"full_name":"pierrre/githubhook"}}`,
+			expected:   "github/pierrre/githubhook/push/test.json",
+		},
+		{
+			name:       "NoRepository",
+			event:      "ping",
+			deliveryID: "test",
+			rawPayload: `{}`,
+			expected:   "github/ping/test.json",
+		},
+		{
+			name:       "DeliveryIDTraversal",
+			event:      "push",
+			deliveryID: "../../../other/x",
+			rawPayload: `{}`,
+			expected:   "github/push/..%2F..%2F..%2Fother%2Fx.json",
+		},
+		{
+			name:       "RepositoryTraversal",
+			event:      "..",
+			deliveryID: "test",
+			rawPayload: `{"repository":{"full_name":"../.."}}`,
+			expected:   "github/%2E%2E/%2E%2E/%2E%2E/test.json",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key := a.ObjectKey(&githubhook.DeliveryMetadata{Event: tc.event, DeliveryID: tc.deliveryID}, []byte(tc.rawPayload))
+			assert.Equal(t, key, tc.expected)
+		})
+	}
+}
+
+func TestArchiverGzip(t *testing.T) {
+	ctx := context.Background()
+	c := &testS3Client{}
+	a := NewArchiver(c, "bucket", 1, 10)
+	a.Gzip = true
+	err := a.Handle(ctx, &githubhook.DeliveryMetadata{Event: "ping", DeliveryID: "test"}, json.RawMessage(`{"zen":"test"}`))
+	assert.NoError(t, err)
+	err = a.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, c.inputs, 1)
+	assert.Equal(t, aws.ToString(c.inputs[0].Key), "ping/test.json")
+	assert.Equal(t, aws.ToString(c.inputs[0].ContentEncoding), "gzip")
+	zr, err := gzip.NewReader(bytes.NewReader(c.bodies[0]))
+	assert.NoError(t, err)
+	var r githubhook.Record
+	err = json.NewDecoder(zr).Decode(&r)
+	assert.NoError(t, err)
+	assert.Equal(t, string(r.Payload), `{"zen":"test"}`)
+}
+
+func TestArchiverError(t *testing.T) {
+	ctx := context.Background()
+	c := &testS3Client{
+		err: errors.New("error"),
+	}
+	a := NewArchiver(c, "bucket", 1, 10)
+	var archiveErr error
+	a.Error = func(md *githubhook.DeliveryMetadata, err error) {
+		archiveErr = err
+	}
+	err := a.Archive(ctx, &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	err = a.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.Error(t, archiveErr)
+}
+
+func TestArchiverQueueFull(t *testing.T) {
+	ctx := context.Background()
+	c := &testS3Client{
+		block: make(chan struct{}),
+	}
+	a := NewArchiver(c, "bucket", 1, 0)
+	var mu sync.Mutex
+	var errs []error
+	a.Error = func(md *githubhook.DeliveryMetadata, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	md := &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}
+	// The worker may not be idle yet, so the deliveries are sent until one is rejected.
+	for {
+		err := a.Archive(ctx, md, testRawPayload)
+		assert.NoError(t, err)
+		mu.Lock()
+		n := len(errs)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(c.block)
+	err := a.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, errs[0], ErrArchiverQueueFull)
+}
+
+func TestArchiverClosed(t *testing.T) {
+	ctx := context.Background()
+	a := NewArchiver(&testS3Client{}, "bucket", 1, 10)
+	var archiveErr error
+	a.Error = func(md *githubhook.DeliveryMetadata, err error) {
+		archiveErr = err
+	}
+	err := a.Shutdown(ctx)
+	assert.NoError(t, err)
+	err = a.Archive(ctx, &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, archiveErr, ErrArchiverClosed)
+}
+
+func TestArchiverClose(t *testing.T) {
+	ctx := context.Background()
+	c := &testS3Client{}
+	a := NewArchiver(c, "bucket", 1, 10)
+	err := a.Send(ctx, &githubhook.VerifiedDelivery{DeliveryMetadata: githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, RawPayload: testRawPayload})
+	assert.NoError(t, err)
+	err = githubhook.CloseSink(ctx, a)
+	assert.NoError(t, err)
+	assert.SliceLen(t, c.inputs, 1)
+}
+
+func TestArchiverShutdownContext(t *testing.T) {
+	c := &testS3Client{
+		block: make(chan struct{}),
+	}
+	defer close(c.block)
+	a := NewArchiver(c, "bucket", 1, 10)
+	err := a.Archive(context.Background(), &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, err := a.ShutdownWithReport(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.SliceEqual(t, r.Abandoned, []string{"test"})
+}
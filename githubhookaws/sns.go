@@ -0,0 +1,113 @@
+package githubhookaws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/pierrre/githubhook"
+)
+
+// SNSClient publishes messages to SNS topics.
+//
+// It's implemented by [sns.Client].
+type SNSClient interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+/*
+SNS publishes verified deliveries to an SNS topic.
+
+[SNS.Publish] returns after the message is stored by SNS, so the HTTP response reflects the success or the failure, and GitHub can redeliver the delivery if it fails.
+
+It can be used as:
+  - a [http.Handler] wrapped by [githubhook.Handler.Middleware]: the response status is 200 if the delivery is published, 502 otherwise.
+  - a [githubhook.DeliveryHandler] with [SNS.Handle].
+
+Fields:
+  - Client is the SNS client (required).
+  - TopicARN is the ARN of the topic (required).
+  - Error is called if a delivery can't be published by [SNS.ServeHTTP].
+*/
+type SNS struct {
+	Client   SNSClient
+	TopicARN string
+	Error    func(ctx context.Context, err error, req *http.Request)
+}
+
+func (s *SNS) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	githubhook.SinkHTTPHandler(s, s.Error).ServeHTTP(w, req)
+}
+
+// Handle publishes a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+// If the payload is a [json.RawMessage] or a []byte, it's published as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are not published.
+// [SNS.ServeHTTP] should be preferred, because it publishes the raw payload.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func (s *SNS) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	return githubhook.SinkDeliveryHandler(s)(ctx, md, payload)
+}
+
+// Send publishes a verified delivery.
+//
+// It implements [githubhook.Sink].
+func (s *SNS) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	return s.Publish(ctx, &d.DeliveryMetadata, d.RawPayload)
+}
+
+// Start validates the topic and the credentials, before the deliveries are published.
+//
+// It implements [githubhook.SinkStarter].
+// If the client implements GetTopicAttributes (e.g. [sns.Client]), the attributes of the topic are requested, so an unknown topic or a denied access is detected.
+// It also pre-establishes the connection of the client.
+func (s *SNS) Start(ctx context.Context) error {
+	c, ok := s.Client.(interface {
+		GetTopicAttributes(ctx context.Context, params *sns.GetTopicAttributesInput, optFns ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error)
+	})
+	if !ok {
+		return nil
+	}
+	_, err := c.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{
+		TopicArn: aws.String(s.TopicARN),
+	})
+	if err != nil {
+		return fmt.Errorf("SNS: get topic attributes: %w", err)
+	}
+	return nil
+}
+
+// Publish publishes a delivery with its raw JSON payload.
+func (s *SNS) Publish(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	msg := newMessage(md, rawPayload, isFIFO(s.TopicARN))
+	input := &sns.PublishInput{
+		TopicArn:          aws.String(s.TopicARN),
+		Message:           aws.String(msg.body),
+		MessageAttributes: make(map[string]snstypes.MessageAttributeValue, len(msg.attributes)),
+	}
+	for k, v := range msg.attributes {
+		input.MessageAttributes[k] = snstypes.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+	if msg.groupID != "" {
+		input.MessageGroupId = aws.String(msg.groupID)
+	}
+	if msg.deduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(msg.deduplicationID)
+	}
+	err := githubhook.TracePublish(ctx, s.TopicARN, md.DeliveryID, func() error {
+		_, err := s.Client.Publish(ctx, input)
+		return err //nolint:wrapcheck // The error is wrapped below.
+	})
+	if err != nil {
+		return fmt.Errorf("SNS: publish: %w", err)
+	}
+	return nil
+}
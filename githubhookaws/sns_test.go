@@ -0,0 +1,101 @@
+package githubhookaws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+type testSNSClient struct {
+	inputs   []*sns.PublishInput
+	topicARN string
+	err      error
+}
+
+func (c *testSNSClient) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	c.inputs = append(c.inputs, params)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sns.PublishOutput{}, nil
+}
+
+func (c *testSNSClient) GetTopicAttributes(ctx context.Context, params *sns.GetTopicAttributesInput, optFns ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error) {
+	c.topicARN = *params.TopicArn
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sns.GetTopicAttributesOutput{}, nil
+}
+
+func TestSNS(t *testing.T) {
+	c := &testSNSClient{}
+	s := &SNS{
+		Client:   c,
+		TopicARN: "arn:aws:sns:us-east-1:123456789012:github",
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(s).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, c.inputs, 1)
+	input := c.inputs[0]
+	assert.Equal(t, aws.ToString(input.TopicArn), s.TopicARN)
+	assert.Equal(t, aws.ToString(input.Message), string(testRawPayload))
+	assert.Equal(t, aws.ToString(input.MessageAttributes[AttributeEvent].StringValue), "push")
+	assert.Zero(t, input.MessageGroupId)
+}
+
+func TestSNSFIFO(t *testing.T) {
+	c := &testSNSClient{}
+	s := &SNS{
+		Client:   c,
+		TopicARN: "arn:aws:sns:us-east-1:123456789012:github.fifo",
+	}
+	err := s.Publish(context.Background(), &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, aws.ToString(c.inputs[0].MessageGroupId), "pierrre/githubhook")
+	assert.Equal(t, aws.ToString(c.inputs[0].MessageDeduplicationId), "test")
+}
+
+func TestSNSError(t *testing.T) {
+	c := &testSNSClient{
+		err: errors.New("error"),
+	}
+	s := &SNS{
+		Client: c,
+	}
+	err := s.Handle(context.Background(), &githubhook.DeliveryMetadata{Event: "push"}, []byte(testRawPayload))
+	assert.Error(t, err)
+}
+
+func TestSNSStart(t *testing.T) {
+	c := &testSNSClient{}
+	s := &SNS{
+		Client:   c,
+		TopicARN: "arn:aws:sns:us-east-1:123456789012:github",
+	}
+	err := githubhook.StartSink(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Equal(t, c.topicARN, s.TopicARN)
+	c.err = errors.New("error")
+	err = s.Start(context.Background())
+	assert.ErrorContains(t, err, "SNS: get topic attributes: error")
+}
+
+func TestSNSSink(t *testing.T) {
+	c := &testSNSClient{}
+	h := &githubhook.Handler{
+		Sink: &SNS{Client: c},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, c.inputs, 1)
+}
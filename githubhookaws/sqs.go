@@ -0,0 +1,113 @@
+package githubhookaws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pierrre/githubhook"
+)
+
+// SQSClient sends messages to SQS queues.
+//
+// It's implemented by [sqs.Client].
+type SQSClient interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+/*
+SQS sends verified deliveries to an SQS queue.
+
+[SQS.SendMessage] returns after the message is stored by SQS, so the HTTP response reflects the success or the failure, and GitHub can redeliver the delivery if it fails.
+
+It can be used as:
+  - a [http.Handler] wrapped by [githubhook.Handler.Middleware]: the response status is 200 if the delivery is sent, 502 otherwise.
+  - a [githubhook.DeliveryHandler] with [SQS.Handle].
+
+Fields:
+  - Client is the SQS client (required).
+  - QueueURL is the URL of the queue (required).
+  - Error is called if a delivery can't be sent by [SQS.ServeHTTP].
+*/
+type SQS struct {
+	Client   SQSClient
+	QueueURL string
+	Error    func(ctx context.Context, err error, req *http.Request)
+}
+
+func (s *SQS) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	githubhook.SinkHTTPHandler(s, s.Error).ServeHTTP(w, req)
+}
+
+// Handle sends a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+// If the payload is a [json.RawMessage] or a []byte, it's sent as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are not sent.
+// [SQS.ServeHTTP] should be preferred, because it sends the raw payload.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func (s *SQS) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	return githubhook.SinkDeliveryHandler(s)(ctx, md, payload)
+}
+
+// Send sends a verified delivery.
+//
+// It implements [githubhook.Sink].
+func (s *SQS) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	return s.SendMessage(ctx, &d.DeliveryMetadata, d.RawPayload)
+}
+
+// Start validates the queue and the credentials, before the deliveries are sent.
+//
+// It implements [githubhook.SinkStarter].
+// If the client implements GetQueueAttributes (e.g. [sqs.Client]), the attributes of the queue are requested, so an unknown queue or a denied access is detected.
+// It also pre-establishes the connection of the client.
+func (s *SQS) Start(ctx context.Context) error {
+	c, ok := s.Client.(interface {
+		GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	})
+	if !ok {
+		return nil
+	}
+	_, err := c.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(s.QueueURL),
+	})
+	if err != nil {
+		return fmt.Errorf("SQS: get queue attributes: %w", err)
+	}
+	return nil
+}
+
+// SendMessage sends a delivery with its raw JSON payload.
+func (s *SQS) SendMessage(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	msg := newMessage(md, rawPayload, isFIFO(s.QueueURL))
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.QueueURL),
+		MessageBody:       aws.String(msg.body),
+		MessageAttributes: make(map[string]sqstypes.MessageAttributeValue, len(msg.attributes)),
+	}
+	for k, v := range msg.attributes {
+		input.MessageAttributes[k] = sqstypes.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+	if msg.groupID != "" {
+		input.MessageGroupId = aws.String(msg.groupID)
+	}
+	if msg.deduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(msg.deduplicationID)
+	}
+	err := githubhook.TracePublish(ctx, s.QueueURL, md.DeliveryID, func() error {
+		_, err := s.Client.SendMessage(ctx, input)
+		return err //nolint:wrapcheck // The error is wrapped below.
+	})
+	if err != nil {
+		return fmt.Errorf("SQS: send message: %w", err)
+	}
+	return nil
+}
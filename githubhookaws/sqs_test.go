@@ -0,0 +1,152 @@
+package githubhookaws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+type testSQSClient struct {
+	inputs   []*sqs.SendMessageInput
+	queueURL string
+	err      error
+}
+
+func (c *testSQSClient) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	c.inputs = append(c.inputs, params)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (c *testSQSClient) GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	c.queueURL = *params.QueueUrl
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sqs.GetQueueAttributesOutput{}, nil
+}
+
+func TestSQS(t *testing.T) {
+	c := &testSQSClient{}
+	s := &SQS{
+		Client:   c,
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/github",
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(s).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, c.inputs, 1)
+	input := c.inputs[0]
+	assert.Equal(t, aws.ToString(input.QueueUrl), s.QueueURL)
+	assert.Equal(t, aws.ToString(input.MessageBody), string(testRawPayload))
+	assert.Equal(t, aws.ToString(input.MessageAttributes[AttributeEvent].StringValue), "push")
+	assert.Equal(t, aws.ToString(input.MessageAttributes[AttributeDeliveryID].StringValue), "test")
+	assert.Equal(t, aws.ToString(input.MessageAttributes[AttributeRepository].StringValue), "pierrre/githubhook")
+	assert.Zero(t, input.MessageGroupId)
+	assert.Zero(t, input.MessageDeduplicationId)
+}
+
+func TestSQSSinkTrace(t *testing.T) {
+	c := &testSQSClient{
+		err: errors.New("error"),
+	}
+	s := &SQS{
+		Client:   c,
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/github",
+	}
+	var ackErr error
+	ctx := githubhook.WithSinkTrace(context.Background(), &githubhook.SinkTrace{
+		Ack: func(target string, deliveryID string, err error) {
+			assert.Equal(t, target, s.QueueURL)
+			assert.Equal(t, deliveryID, "test")
+			ackErr = err
+		},
+	})
+	err := s.SendMessage(ctx, &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.Error(t, err)
+	assert.Error(t, ackErr)
+}
+
+func TestSQSFIFO(t *testing.T) {
+	c := &testSQSClient{}
+	s := &SQS{
+		Client:   c,
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/github.fifo",
+	}
+	err := s.SendMessage(context.Background(), &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, aws.ToString(c.inputs[0].MessageGroupId), "pierrre/githubhook")
+	assert.Equal(t, aws.ToString(c.inputs[0].MessageDeduplicationId), "test")
+}
+
+func TestSQSError(t *testing.T) {
+	c := &testSQSClient{
+		err: errors.New("error"),
+	}
+	var sendErr error
+	s := &SQS{
+		Client: c,
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			sendErr = err
+		},
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(s).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	assert.Error(t, sendErr)
+}
+
+func TestSQSStart(t *testing.T) {
+	c := &testSQSClient{}
+	s := &SQS{
+		Client:   c,
+		QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/github",
+	}
+	err := githubhook.StartSink(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Equal(t, c.queueURL, s.QueueURL)
+	c.err = errors.New("error")
+	err = s.Start(context.Background())
+	assert.ErrorContains(t, err, "SQS: get queue attributes: error")
+}
+
+func TestSQSWithoutMiddleware(t *testing.T) {
+	s := &SQS{
+		Client: &testSQSClient{},
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestSQSHandle(t *testing.T) {
+	c := &testSQSClient{}
+	h := &githubhook.Handler{
+		Delivery: (&SQS{Client: c}).Handle,
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, c.inputs, 1)
+	assert.Equal(t, aws.ToString(c.inputs[0].MessageAttributes[AttributeRepository].StringValue), "pierrre/githubhook")
+}
+
+func TestSQSSink(t *testing.T) {
+	c := &testSQSClient{}
+	h := &githubhook.Handler{
+		Sink: &SQS{Client: c},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, c.inputs, 1)
+}
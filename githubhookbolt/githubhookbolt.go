@@ -0,0 +1,514 @@
+// Package githubhookbolt provides a [bbolt] [githubhook.Store], suitable for single-instance deployments that want durability without SQL.
+//
+//	db, err := bbolt.Open("githubhook.db", 0o600, nil)
+//	s, err := githubhookbolt.New(db, 30*24*time.Hour)
+//	go s.RunSweeps(ctx, time.Hour)
+//	h := &githubhook.Handler{Store: s}
+//
+// [bbolt]: https://github.com/etcd-io/bbolt
+package githubhookbolt
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketDeliveries = []byte("deliveries")
+	bucketIDs        = []byte("ids")
+	bucketPayloads   = []byte("payloads")
+)
+
+/*
+Store is a bbolt [githubhook.Store].
+
+The deliveries are stored in a bucket per event type, with keys ordered by received time, so listing an event type or a time range doesn't scan the other deliveries.
+An index bucket maps the delivery IDs to their event type and received time.
+The payloads are content-addressed (see [githubhook.PayloadDigest]): they are stored once in a payload bucket, with a reference count, and deleted with the last delivery that references them.
+So the redeliveries and the duplicate events don't store the same payload twice.
+
+Fields:
+  - Error is called if a sweep fails in [Store.RunSweeps] (optional).
+
+It must be created with [New].
+*/
+type Store struct {
+	Error func(ctx context.Context, err error)
+
+	db        *bolt.DB
+	retention time.Duration
+	now       func() time.Time
+}
+
+// New creates a new [Store], and creates its buckets if they don't exist.
+//
+// retention is the duration after which the deliveries are deleted by [Store.Sweep] (0 keeps them forever).
+// It doesn't close the database.
+func New(db *bolt.DB, retention time.Duration) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketDeliveries, bucketIDs, bucketPayloads} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return fmt.Errorf("create bucket %q: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bbolt: %w", err)
+	}
+	return &Store{
+		db:        db,
+		retention: retention,
+		now:       time.Now,
+	}, nil
+}
+
+// record is the stored value of a delivery.
+// The event type, the delivery ID and the received time are in the bucket name and the key.
+// The payload is in the payload bucket, identified by PayloadDigest, or in Payload for the deliveries stored by the previous versions.
+type record struct {
+	Repository             string              `json:"repository,omitempty"`
+	HookID                 string              `json:"hook_id,omitempty"`
+	InstallationTargetType string              `json:"installation_target_type,omitempty"`
+	InstallationTargetID   string              `json:"installation_target_id,omitempty"`
+	UserAgent              string              `json:"user_agent,omitempty"`
+	Lineage                *githubhook.Lineage `json:"lineage,omitempty"`
+	SignatureHeaders       []string            `json:"signature_headers,omitempty"`
+	HeaderInterpretations  []string            `json:"header_interpretations,omitempty"`
+	Annotations            map[string]string   `json:"annotations,omitempty"`
+	Payload                []byte              `json:"payload,omitempty"`
+	PayloadDigest          []byte              `json:"payload_digest,omitempty"`
+	Status                 string              `json:"status"`
+	Error                  string              `json:"error,omitempty"`
+	UpdatedAt              time.Time           `json:"updated_at"`
+}
+
+// deliveryKey returns the key of a delivery in its event bucket: the big-endian received time, followed by the delivery ID.
+func deliveryKey(receivedAt time.Time, deliveryID string) []byte {
+	k := timeKey(receivedAt)
+	return append(k, deliveryID...)
+}
+
+func timeKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 0, 8), uint64(t.UnixNano())) //nolint:gosec // Times before 1970 are not expected.
+}
+
+// indexValue returns the value of a delivery in the index bucket: the big-endian received time, followed by the event type.
+func indexValue(receivedAt time.Time, event string) []byte {
+	v := timeKey(receivedAt)
+	return append(v, event...)
+}
+
+// parseIndexValue returns the event bucket and the key of a delivery, from its index value.
+func parseIndexValue(deliveryID string, v []byte) (event []byte, key []byte, err error) {
+	if len(v) < 8 {
+		return nil, nil, errors.New("invalid index value")
+	}
+	key = append(slices.Clone(v[:8]), deliveryID...)
+	return v[8:], key, nil
+}
+
+// Save implements [githubhook.Store].
+func (s *Store) Save(ctx context.Context, d *githubhook.StoredDelivery) error {
+	digest := githubhook.PayloadDigest(d.RawPayload)
+	v, err := json.Marshal(&record{
+		Repository:             d.Repository,
+		HookID:                 d.HookID,
+		InstallationTargetType: d.InstallationTargetType,
+		InstallationTargetID:   d.InstallationTargetID,
+		UserAgent:              d.UserAgent,
+		Lineage:                d.Lineage,
+		SignatureHeaders:       d.SignatureHeaders,
+		HeaderInterpretations:  d.HeaderInterpretations,
+		Annotations:            d.Annotations.All(),
+		PayloadDigest:          digest[:],
+		Status:                 string(d.Status),
+		Error:                  d.Error,
+		UpdatedAt:              d.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt: JSON encode: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		// The event type or the received time of an existing delivery may be different, so its previous key is deleted.
+		err := deleteDelivery(tx, d.DeliveryID)
+		if err != nil && !errors.Is(err, githubhook.ErrStoredDeliveryNotFound) {
+			return err
+		}
+		err = acquirePayload(tx, digest[:], d.RawPayload)
+		if err != nil {
+			return err
+		}
+		eb, err := tx.Bucket(bucketDeliveries).CreateBucketIfNotExists([]byte(d.Event))
+		if err != nil {
+			return fmt.Errorf("create event bucket: %w", err)
+		}
+		err = eb.Put(deliveryKey(d.ReceivedAt, d.DeliveryID), v)
+		if err != nil {
+			return fmt.Errorf("put: %w", err)
+		}
+		err = tx.Bucket(bucketIDs).Put([]byte(d.DeliveryID), indexValue(d.ReceivedAt, d.Event))
+		if err != nil {
+			return fmt.Errorf("put index: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt: save: %w", err)
+	}
+	return nil
+}
+
+// Get implements [githubhook.Store].
+func (s *Store) Get(ctx context.Context, deliveryID string) (*githubhook.StoredDelivery, error) {
+	var d *githubhook.StoredDelivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		iv := tx.Bucket(bucketIDs).Get([]byte(deliveryID))
+		if iv == nil {
+			return fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+		}
+		event, key, err := parseIndexValue(deliveryID, iv)
+		if err != nil {
+			return err
+		}
+		eb := tx.Bucket(bucketDeliveries).Bucket(event)
+		if eb == nil {
+			return fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+		}
+		v := eb.Get(key)
+		if v == nil {
+			return fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+		}
+		d, err = decodeDelivery(tx, event, key, v)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bbolt: get: %w", err)
+	}
+	return d, nil
+}
+
+// List implements [githubhook.Store].
+func (s *Store) List(ctx context.Context, filter githubhook.StoreFilter) ([]*githubhook.StoredDelivery, error) {
+	var ds []*githubhook.StoredDelivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketDeliveries)
+		if filter.Event != "" {
+			eb := b.Bucket([]byte(filter.Event))
+			if eb == nil {
+				return nil
+			}
+			var err error
+			// The keys are sorted, so the scan can stop at the limit.
+			ds, err = listBucket(tx, []byte(filter.Event), eb, &filter)
+			return err
+		}
+		err := b.ForEachBucket(func(event []byte) error {
+			eds, err := listBucket(tx, event, b.Bucket(event), &filter)
+			if err != nil {
+				return err
+			}
+			ds = append(ds, eds...)
+			return nil
+		})
+		if err != nil {
+			return err //nolint:wrapcheck // The error is wrapped below.
+		}
+		slices.SortFunc(ds, func(a, b *githubhook.StoredDelivery) int {
+			c := a.ReceivedAt.Compare(b.ReceivedAt)
+			if c == 0 {
+				c = cmp.Compare(a.DeliveryID, b.DeliveryID)
+			}
+			if filter.Newest {
+				c = -c
+			}
+			return c
+		})
+		if filter.Limit > 0 && len(ds) > filter.Limit {
+			ds = ds[:filter.Limit]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bbolt: list: %w", err)
+	}
+	return ds, nil
+}
+
+// listBucket returns the deliveries of an event bucket matching the filter, in the time range [Since, Until).
+//
+// The bucket is scanned backward if [githubhook.StoreFilter.Newest] is set.
+func listBucket(tx *bolt.Tx, event []byte, eb *bolt.Bucket, filter *githubhook.StoreFilter) ([]*githubhook.StoredDelivery, error) {
+	var ds []*githubhook.StoredDelivery
+	var since, until []byte
+	if !filter.Since.IsZero() {
+		since = timeKey(filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		until = timeKey(filter.Until)
+	}
+	c := eb.Cursor()
+	var k, v []byte
+	next := c.Next
+	switch {
+	case filter.Newest:
+		next = c.Prev
+		if until == nil {
+			k, v = c.Last()
+		} else if k, _ = c.Seek(until); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+	case since == nil:
+		k, v = c.First()
+	default:
+		k, v = c.Seek(since)
+	}
+	for ; k != nil && (until == nil || bytes.Compare(k, until) < 0) && (since == nil || bytes.Compare(k, since) >= 0); k, v = next() {
+		d, err := decodeDelivery(tx, event, k, v)
+		if err != nil {
+			return nil, err
+		}
+		if !filter.Match(d) {
+			continue
+		}
+		ds = append(ds, d)
+		if filter.Limit > 0 && len(ds) >= filter.Limit {
+			break
+		}
+	}
+	return ds, nil
+}
+
+// Delete implements [githubhook.Store].
+func (s *Store) Delete(ctx context.Context, deliveryID string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return deleteDelivery(tx, deliveryID)
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt: delete: %w", err)
+	}
+	return nil
+}
+
+func deleteDelivery(tx *bolt.Tx, deliveryID string) error {
+	ib := tx.Bucket(bucketIDs)
+	iv := ib.Get([]byte(deliveryID))
+	if iv == nil {
+		return fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+	}
+	event, key, err := parseIndexValue(deliveryID, iv)
+	if err != nil {
+		return err
+	}
+	eb := tx.Bucket(bucketDeliveries).Bucket(event)
+	if eb != nil {
+		err = releaseRecordPayload(tx, eb.Get(key))
+		if err != nil {
+			return err
+		}
+		err = eb.Delete(key)
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+	}
+	err = ib.Delete([]byte(deliveryID))
+	if err != nil {
+		return fmt.Errorf("delete index: %w", err)
+	}
+	return nil
+}
+
+func decodeDelivery(tx *bolt.Tx, event []byte, key []byte, v []byte) (*githubhook.StoredDelivery, error) {
+	if len(key) < 8 {
+		return nil, errors.New("invalid key")
+	}
+	var r record
+	err := json.Unmarshal(v, &r)
+	if err != nil {
+		return nil, fmt.Errorf("JSON decode: %w", err)
+	}
+	if r.PayloadDigest != nil {
+		pv := tx.Bucket(bucketPayloads).Get(r.PayloadDigest)
+		if len(pv) < 8 {
+			return nil, fmt.Errorf("payload %x not found", r.PayloadDigest)
+		}
+		// The value is only valid during the transaction.
+		r.Payload = bytes.Clone(pv[8:])
+	}
+	return &githubhook.StoredDelivery{
+		DeliveryMetadata: githubhook.DeliveryMetadata{
+			Event:                  string(event),
+			DeliveryID:             string(key[8:]),
+			HookID:                 r.HookID,
+			InstallationTargetType: r.InstallationTargetType,
+			InstallationTargetID:   r.InstallationTargetID,
+			UserAgent:              r.UserAgent,
+			ReceivedAt:             time.Unix(0, int64(binary.BigEndian.Uint64(key[:8]))).UTC(), //nolint:gosec // The key is created from a time.
+			Lineage:                r.Lineage,
+			SignatureHeaders:       r.SignatureHeaders,
+			HeaderInterpretations:  r.HeaderInterpretations,
+			Annotations:            newAnnotations(r.Annotations),
+		},
+		Repository: r.Repository,
+		RawPayload: r.Payload,
+		Status:     githubhook.DeliveryStatus(r.Status),
+		Error:      r.Error,
+		UpdatedAt:  r.UpdatedAt,
+	}, nil
+}
+
+// Sweep deletes the deliveries received before the retention duration.
+//
+// It returns the number of deleted deliveries.
+// It does nothing if the retention is 0.
+func (s *Store) Sweep(ctx context.Context) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	before := timeKey(s.now().Add(-s.retention))
+	n := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketDeliveries)
+		ib := tx.Bucket(bucketIDs)
+		var empty [][]byte
+		err := b.ForEachBucket(func(event []byte) error {
+			eb := b.Bucket(event)
+			c := eb.Cursor()
+			// The keys are sorted by received time, so the expired deliveries are at the beginning.
+			for k, v := c.First(); k != nil && bytes.Compare(k, before) < 0; k, v = c.First() {
+				err := releaseRecordPayload(tx, v)
+				if err != nil {
+					return err
+				}
+				err = c.Delete()
+				if err != nil {
+					return fmt.Errorf("delete: %w", err)
+				}
+				err = ib.Delete(k[8:])
+				if err != nil {
+					return fmt.Errorf("delete index: %w", err)
+				}
+				n++
+			}
+			if k, _ := c.First(); k == nil {
+				empty = append(empty, slices.Clone(event))
+			}
+			return nil
+		})
+		if err != nil {
+			return err //nolint:wrapcheck // The error is wrapped below.
+		}
+		for _, event := range empty {
+			err = b.DeleteBucket(event)
+			if err != nil {
+				return fmt.Errorf("delete event bucket: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("bbolt: sweep: %w", err)
+	}
+	return n, nil
+}
+
+// acquirePayload stores a payload if it doesn't exist, and increments its reference count.
+//
+// The value of a payload is its big-endian reference count, followed by the raw payload.
+func acquirePayload(tx *bolt.Tx, digest []byte, rawPayload []byte) error {
+	pb := tx.Bucket(bucketPayloads)
+	var refs uint64
+	if pv := pb.Get(digest); len(pv) >= 8 {
+		refs = binary.BigEndian.Uint64(pv[:8])
+	}
+	pv := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(rawPayload)), refs+1)
+	pv = append(pv, rawPayload...)
+	err := pb.Put(digest, pv)
+	if err != nil {
+		return fmt.Errorf("put payload: %w", err)
+	}
+	return nil
+}
+
+// releaseRecordPayload decrements the reference count of the payload of a record, and deletes the payload if it's not referenced anymore.
+//
+// It does nothing if the record doesn't reference a payload (e.g. stored by a previous version).
+func releaseRecordPayload(tx *bolt.Tx, v []byte) error {
+	if v == nil {
+		return nil
+	}
+	var r struct {
+		PayloadDigest []byte `json:"payload_digest"`
+	}
+	err := json.Unmarshal(v, &r)
+	if err != nil {
+		return fmt.Errorf("JSON decode: %w", err)
+	}
+	if r.PayloadDigest == nil {
+		return nil
+	}
+	pb := tx.Bucket(bucketPayloads)
+	pv := pb.Get(r.PayloadDigest)
+	if len(pv) < 8 {
+		return nil
+	}
+	refs := binary.BigEndian.Uint64(pv[:8])
+	if refs <= 1 {
+		err = pb.Delete(r.PayloadDigest)
+		if err != nil {
+			return fmt.Errorf("delete payload: %w", err)
+		}
+		return nil
+	}
+	nv := binary.BigEndian.AppendUint64(make([]byte, 0, len(pv)), refs-1)
+	nv = append(nv, pv[8:]...)
+	err = pb.Put(r.PayloadDigest, nv)
+	if err != nil {
+		return fmt.Errorf("put payload: %w", err)
+	}
+	return nil
+}
+
+// RunSweeps calls [Store.Sweep] periodically, until the context is canceled.
+//
+// The errors are reported to [Store.Error].
+func (s *Store) RunSweeps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := s.Sweep(ctx)
+		if err != nil && s.Error != nil {
+			s.Error(ctx, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// newAnnotations returns the annotations of a record, or nil if it doesn't have any.
+func newAnnotations(m map[string]string) *githubhook.Annotations {
+	if len(m) == 0 {
+		return nil
+	}
+	a := new(githubhook.Annotations)
+	for k, v := range m {
+		a.Set(k, v)
+	}
+	return a
+}
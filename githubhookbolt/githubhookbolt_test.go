@@ -0,0 +1,310 @@
+package githubhookbolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	bolt "go.etcd.io/bbolt"
+)
+
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testNewStore(t *testing.T, retention time.Duration) *Store {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	s, err := New(db, retention)
+	assert.NoError(t, err)
+	return s
+}
+
+func testNewDelivery(event string, deliveryID string, receivedAt time.Time) *githubhook.StoredDelivery {
+	return &githubhook.StoredDelivery{
+		DeliveryMetadata: githubhook.DeliveryMetadata{
+			Event:      event,
+			DeliveryID: deliveryID,
+			HookID:     "123",
+			ReceivedAt: receivedAt,
+		},
+		Repository: "pierrre/githubhook",
+		RawPayload: []byte(`{"ref":"refs/heads/main"}`),
+		Status:     githubhook.DeliveryStatusPending,
+		UpdatedAt:  receivedAt,
+	}
+}
+
+func testDeliveryIDs(ds []*githubhook.StoredDelivery) []string {
+	ids := make([]string, len(ds))
+	for i, d := range ds {
+		ids[i] = d.DeliveryID
+	}
+	return ids
+}
+
+func TestSaveGet(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, 0)
+	d := testNewDelivery("push", "1", testTime)
+	d.Lineage = &githubhook.Lineage{OriginalDeliveryID: "original", Generation: 1}
+	d.SignatureHeaders = []string{"X-Hub-Signature-256", "X-Hub-Signature"}
+	d.HeaderInterpretations = []string{"interpretation"}
+	d.Annotations = new(githubhook.Annotations)
+	d.Annotations.Set("rule", "deploy")
+	err := s.Save(ctx, d)
+	assert.NoError(t, err)
+	got, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.DeepEqual(t, got, d)
+	d.Status = githubhook.DeliveryStatusFailed
+	d.Error = "error"
+	err = s.Save(ctx, d)
+	assert.NoError(t, err)
+	got, err = s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, got.Status, githubhook.DeliveryStatusFailed)
+	assert.Equal(t, got.Error, "error")
+}
+
+func TestSaveMove(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, 0)
+	err := s.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	err = s.Save(ctx, testNewDelivery("issues", "1", testTime.Add(time.Hour)))
+	assert.NoError(t, err)
+	got, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, got.Event, "issues")
+	ds, err := s.List(ctx, githubhook.StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 1)
+}
+
+func TestGetNotFound(t *testing.T) {
+	s := testNewStore(t, 0)
+	_, err := s.Get(context.Background(), "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, 0)
+	for _, d := range []*githubhook.StoredDelivery{
+		testNewDelivery("push", "3", testTime.Add(3*time.Minute)),
+		testNewDelivery("issues", "2", testTime.Add(2*time.Minute)),
+		testNewDelivery("push", "1", testTime.Add(1*time.Minute)),
+		testNewDelivery("issues", "4", testTime.Add(4*time.Minute)),
+		testNewDelivery("push", "5", testTime.Add(5*time.Minute)),
+	} {
+		err := s.Save(ctx, d)
+		assert.NoError(t, err)
+	}
+	for _, tc := range []struct {
+		name     string
+		filter   githubhook.StoreFilter
+		expected []string
+	}{
+		{
+			name:     "All",
+			expected: []string{"1", "2", "3", "4", "5"},
+		},
+		{
+			name:     "Event",
+			filter:   githubhook.StoreFilter{Event: "push"},
+			expected: []string{"1", "3", "5"},
+		},
+		{
+			name:     "EventLimit",
+			filter:   githubhook.StoreFilter{Event: "push", Limit: 2},
+			expected: []string{"1", "3"},
+		},
+		{
+			name:     "Limit",
+			filter:   githubhook.StoreFilter{Limit: 3},
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "Newest",
+			filter:   githubhook.StoreFilter{Limit: 3, Newest: true},
+			expected: []string{"5", "4", "3"},
+		},
+		{
+			name:     "EventNewest",
+			filter:   githubhook.StoreFilter{Event: "push", Limit: 2, Newest: true},
+			expected: []string{"5", "3"},
+		},
+		{
+			name:     "TimeRangeNewest",
+			filter:   githubhook.StoreFilter{Since: testTime.Add(2 * time.Minute), Until: testTime.Add(4 * time.Minute), Newest: true},
+			expected: []string{"3", "2"},
+		},
+		{
+			name:     "TimeRange",
+			filter:   githubhook.StoreFilter{Since: testTime.Add(2 * time.Minute), Until: testTime.Add(4 * time.Minute)},
+			expected: []string{"2", "3"},
+		},
+		{
+			name:     "EventNotFound",
+			filter:   githubhook.StoreFilter{Event: "ping"},
+			expected: []string{},
+		},
+		{
+			name:     "Status",
+			filter:   githubhook.StoreFilter{Status: githubhook.DeliveryStatusFailed},
+			expected: []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ds, err := s.List(ctx, tc.filter)
+			assert.NoError(t, err)
+			assert.SliceEqual(t, testDeliveryIDs(ds), tc.expected)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, 0)
+	err := s.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	err = s.Delete(ctx, "1")
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+	err = s.Delete(ctx, "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+}
+
+// testPayloadRefs returns the reference counts of the stored payloads, by hex digest.
+func testPayloadRefs(t *testing.T, s *Store) map[string]uint64 {
+	t.Helper()
+	refs := make(map[string]uint64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPayloads).ForEach(func(k, v []byte) error {
+			refs[hex.EncodeToString(k)] = binary.BigEndian.Uint64(v[:8])
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+	return refs
+}
+
+func TestPayloads(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, time.Hour)
+	s.now = func() time.Time {
+		return testTime.Add(2 * time.Hour)
+	}
+	digest := githubhook.PayloadDigest([]byte(`{"ref":"refs/heads/main"}`))
+	for _, d := range []*githubhook.StoredDelivery{
+		testNewDelivery("push", "1", testTime),
+		testNewDelivery("push", "2", testTime.Add(90*time.Minute)),
+		testNewDelivery("push", "3", testTime.Add(90*time.Minute)),
+	} {
+		err := s.Save(ctx, d)
+		assert.NoError(t, err)
+	}
+	err := s.Save(ctx, testNewDelivery("push", "3", testTime.Add(90*time.Minute))) // Update.
+	assert.NoError(t, err)
+	assert.MapEqual(t, testPayloadRefs(t, s), map[string]uint64{hex.EncodeToString(digest[:]): 3})
+	d, err := s.Get(ctx, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, string(d.RawPayload), `{"ref":"refs/heads/main"}`)
+	_, err = s.Sweep(ctx)
+	assert.NoError(t, err)
+	err = s.Delete(ctx, "2")
+	assert.NoError(t, err)
+	assert.MapEqual(t, testPayloadRefs(t, s), map[string]uint64{hex.EncodeToString(digest[:]): 1})
+	err = s.Delete(ctx, "3")
+	assert.NoError(t, err)
+	assert.MapEmpty(t, testPayloadRefs(t, s))
+}
+
+func TestPayloadInline(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, 0)
+	// The deliveries stored by the previous versions have the payload in the record.
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		eb, err := tx.Bucket(bucketDeliveries).CreateBucketIfNotExists([]byte("push"))
+		if err != nil {
+			return err //nolint:wrapcheck // Test.
+		}
+		err = eb.Put(deliveryKey(testTime, "1"), []byte(`{"payload":"e30=","status":"succeeded"}`))
+		if err != nil {
+			return err //nolint:wrapcheck // Test.
+		}
+		return tx.Bucket(bucketIDs).Put([]byte("1"), indexValue(testTime, "push")) //nolint:wrapcheck // Test.
+	})
+	assert.NoError(t, err)
+	d, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, string(d.RawPayload), `{}`)
+	err = s.Delete(ctx, "1")
+	assert.NoError(t, err)
+}
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, time.Hour)
+	s.now = func() time.Time {
+		return testTime.Add(2 * time.Hour)
+	}
+	for _, d := range []*githubhook.StoredDelivery{
+		testNewDelivery("push", "1", testTime),
+		testNewDelivery("issues", "2", testTime),
+		testNewDelivery("push", "3", testTime.Add(90*time.Minute)),
+	} {
+		err := s.Save(ctx, d)
+		assert.NoError(t, err)
+	}
+	n, err := s.Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 2)
+	ds, err := s.List(ctx, githubhook.StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceEqual(t, testDeliveryIDs(ds), []string{"3"})
+	_, err = s.Get(ctx, "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+}
+
+func TestSweepNoRetention(t *testing.T) {
+	ctx := context.Background()
+	s := testNewStore(t, 0)
+	err := s.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	n, err := s.Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+}
+
+func TestRunSweeps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := testNewStore(t, time.Hour)
+	err := s.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RunSweeps(ctx, time.Millisecond)
+	}()
+	for {
+		_, err = s.Get(ctx, "1")
+		if err != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+	cancel()
+	<-done
+}
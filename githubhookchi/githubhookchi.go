@@ -0,0 +1,48 @@
+// Package githubhookchi provides a [chi] adapter for [githubhook.Handler].
+//
+// The webhook is mounted with [Route], that runs the whole pipeline of the [githubhook.Handler]:
+//
+//	githubhookchi.Route(r, "/webhook", h)
+//
+// Or with [Middleware], that only verifies the deliveries, so they are handled by the next chi handler:
+//
+//	r.With(githubhookchi.Middleware(h)).Post("/webhook", func(w http.ResponseWriter, req *http.Request) {
+//		payload, _ := githubhookchi.Payload(req)
+//		...
+//	})
+//
+// chi doesn't consume the request body, so the signature can be verified without additional work.
+package githubhookchi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pierrre/githubhook"
+)
+
+// Route registers a [http.Handler] for POST requests on the pattern of a [chi.Router]: usually a [githubhook.Handler], that runs its whole pipeline, or a [githubhook.ReloadableHandler].
+func Route(r chi.Router, pattern string, h http.Handler) {
+	r.Method(http.MethodPost, pattern, h)
+}
+
+// Middleware returns a chi middleware that verifies the deliveries with the [githubhook.Handler], and calls the next handler with the verified delivery (see [githubhook.Handler.Middleware]).
+//
+// The verified delivery can be retrieved with [Delivery] and [Payload].
+func Middleware(h *githubhook.Handler) func(next http.Handler) http.Handler {
+	return h.Middleware
+}
+
+// Delivery returns the verified delivery of the request, injected by [Middleware] in the request context (see [githubhook.VerifiedDeliveryFromContext]).
+func Delivery(req *http.Request) (*githubhook.VerifiedDelivery, bool) {
+	return githubhook.VerifiedDeliveryFromContext(req.Context())
+}
+
+// Payload returns the decoded payload of the request, injected by [Middleware].
+func Payload(req *http.Request) (any, bool) {
+	d, ok := Delivery(req)
+	if !ok {
+		return nil, false
+	}
+	return d.Payload, true
+}
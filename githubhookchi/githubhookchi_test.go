@@ -0,0 +1,81 @@
+package githubhookchi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestRoute(t *testing.T) {
+	deliveryCalled := false
+	h := &githubhook.Handler{
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	r := chi.NewRouter()
+	Route(r, "/webhook", h)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader([]byte(`{"foo":"bar"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func TestMiddleware(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	var payload any
+	r := chi.NewRouter()
+	r.With(Middleware(h)).Post("/webhook", func(w http.ResponseWriter, req *http.Request) {
+		d, ok := Delivery(req)
+		assert.True(t, ok)
+		assert.Equal(t, d.Event, "push")
+		payload, _ = Payload(req)
+	})
+	s := &githubhook.Signer{
+		Secret: "foobar",
+	}
+	req, err := s.NewRequest(context.Background(), "/webhook", "push", "test", []byte(`{"ref":"refs/heads/main"}`))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	pe, _ := assert.Type[*events.PushEvent](t, payload)
+	assert.Equal(t, pe.Ref, "refs/heads/main")
+}
+
+func TestMiddlewareInvalidSignature(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	r := chi.NewRouter()
+	r.With(Middleware(h)).Post("/webhook", func(w http.ResponseWriter, req *http.Request) {
+		t.Fatal("handler called")
+	})
+	s := &githubhook.Signer{
+		Secret: "invalid",
+	}
+	req, err := s.NewRequest(context.Background(), "/webhook", "push", "test", []byte(`{}`))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestPayloadNotFound(t *testing.T) {
+	_, ok := Payload(httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.False(t, ok)
+}
@@ -0,0 +1,15 @@
+module github.com/pierrre/githubhook/githubhookchi
+
+go 1.23.0
+
+require (
+	github.com/go-chi/chi/v5 v5.1.0
+	github.com/pierrre/assert v0.6.0
+	github.com/pierrre/githubhook v0.1.0
+)
+
+require (
+	github.com/pierrre/compare v1.4.13 // indirect
+	github.com/pierrre/go-libs v0.10.3 // indirect
+	github.com/pierrre/pretty v0.8.1 // indirect
+)
@@ -0,0 +1,97 @@
+// Package githubhookecho provides an [echo] adapter for [githubhook.Handler].
+//
+// The webhook is mounted with [Handler], that runs the whole pipeline of the [githubhook.Handler]:
+//
+//	e.POST("/webhook", githubhookecho.Handler(h))
+//
+// Or with [Middleware], that only verifies the deliveries, so they are handled by the next echo handler:
+//
+//	e.POST("/webhook", func(c echo.Context) error {
+//		payload, _ := githubhookecho.Payload(c)
+//		...
+//	}, githubhookecho.Middleware(h))
+//
+// In both cases, if the request body was preserved with [PreserveBody], it is restored before the verification, so the signature can be verified even if another middleware consumed it.
+package githubhookecho
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pierrre/githubhook"
+)
+
+// BodyBytesKey is the [echo.Context] key where [PreserveBody] stores the request body.
+const BodyBytesKey = "githubhook/bodybytes"
+
+// Handler returns an [echo.HandlerFunc] for a [http.Handler]: usually a [githubhook.Handler], that runs its whole pipeline, or a [githubhook.ReloadableHandler].
+func Handler(h http.Handler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		restoreBody(c)
+		h.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// Middleware returns an [echo.MiddlewareFunc] that verifies the deliveries with the [githubhook.Handler], and calls the next handler with the verified delivery (see [githubhook.Handler.Middleware]).
+//
+// The verified delivery can be retrieved with [Delivery] and [Payload].
+// If the verification fails, the response is written and the next handler is not called.
+func Middleware(h *githubhook.Handler) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			restoreBody(c)
+			var err error
+			h.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				c.SetRequest(req)
+				err = next(c)
+			})).ServeHTTP(c.Response(), c.Request())
+			return err
+		}
+	}
+}
+
+// PreserveBody returns an [echo.MiddlewareFunc] that reads the request body and stores it in the [echo.Context] with [BodyBytesKey].
+//
+// It must be registered before any middleware that consumes the body.
+func PreserveBody() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return fmt.Errorf("read body: %w", err)
+			}
+			c.Set(BodyBytesKey, b)
+			req.Body = io.NopCloser(bytes.NewReader(b))
+			return next(c)
+		}
+	}
+}
+
+func restoreBody(c echo.Context) {
+	b, ok := c.Get(BodyBytesKey).([]byte)
+	if !ok {
+		return
+	}
+	req := c.Request()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.ContentLength = int64(len(b))
+}
+
+// Delivery returns the verified delivery, injected by [Middleware] in the request context (see [githubhook.VerifiedDeliveryFromContext]).
+func Delivery(c echo.Context) (*githubhook.VerifiedDelivery, bool) {
+	return githubhook.VerifiedDeliveryFromContext(c.Request().Context())
+}
+
+// Payload returns the decoded payload, injected by [Middleware].
+func Payload(c echo.Context) (any, bool) {
+	d, ok := Delivery(c)
+	if !ok {
+		return nil, false
+	}
+	return d.Payload, true
+}
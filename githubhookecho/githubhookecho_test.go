@@ -0,0 +1,112 @@
+package githubhookecho
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // Github uses SHA1.
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"foo":"bar"}`)
+
+func TestHandler(t *testing.T) {
+	deliveryCalled := false
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	e := echo.New()
+	e.POST("/", Handler(h))
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func TestHandlerPreserveBody(t *testing.T) {
+	deliveryCalled := false
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	e := echo.New()
+	e.Use(PreserveBody())
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			_, err := io.ReadAll(c.Request().Body)
+			assert.NoError(t, err)
+			return next(c)
+		}
+	})
+	e.POST("/", Handler(h))
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func testNewRequest(t *testing.T, secret string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(testRawPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	hash := hmac.New(sha1.New, []byte(secret))
+	_, _ = hash.Write(testRawPayload)
+	req.Header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(hash.Sum(nil)))
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	var payload any
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		d, ok := Delivery(c)
+		assert.True(t, ok)
+		assert.Equal(t, d.Event, "push")
+		payload, _ = Payload(c)
+		return c.NoContent(http.StatusNoContent)
+	}, Middleware(h))
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	assert.NotZero(t, payload)
+}
+
+func TestMiddlewareInvalidSignature(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		t.Fatal("handler called")
+		return nil
+	}, Middleware(h))
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, testNewRequest(t, "invalid"))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestPayloadNotFound(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	_, ok := Payload(c)
+	assert.False(t, ok)
+}
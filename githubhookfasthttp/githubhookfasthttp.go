@@ -0,0 +1,60 @@
+// Package githubhookfasthttp provides a [fasthttp] adapter for [githubhook.Handler].
+//
+// It reuses the complete pipeline of the handler, with [githubhook.Handler.HandleInbound].
+package githubhookfasthttp
+
+import (
+	"net/http"
+
+	"github.com/pierrre/githubhook"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+/*
+Handler returns a [fasthttp.RequestHandler] for a [githubhook.Handler].
+
+The request is handled natively by [githubhook.Handler.HandleInbound]: the signature is verified on the body of the request without copying it, and the body is only copied once the delivery has been verified.
+All the options of the handler are supported, except CORS (e.g. [githubhook.Handler.SourceAllowlist], [githubhook.Handler.RateLimiters], [githubhook.Handler.ConcurrencyLimiter], [githubhook.Handler.MaxBodySize] and the events rejected by [githubhook.Router]).
+The request is converted to a [http.Request] only for the options whose API depends on it (see [githubhook.Inbound]).
+
+The maximum body size is also limited by [fasthttp.Server.MaxRequestBodySize], before the handler is called.
+*/
+func Handler(h *githubhook.Handler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		resp := h.HandleInbound(newInbound(ctx))
+		for name, values := range resp.Header {
+			for _, v := range values {
+				ctx.Response.Header.Add(name, v)
+			}
+		}
+		ctx.SetStatusCode(resp.StatusCode)
+		ctx.SetBody(resp.Body)
+	}
+}
+
+func newInbound(ctx *fasthttp.RequestCtx) *githubhook.Inbound {
+	return &githubhook.Inbound{
+		Context:    ctx,
+		Method:     string(ctx.Method()),
+		RemoteAddr: ctx.RemoteAddr().String(),
+		Header: func(name string) string {
+			return string(ctx.Request.Header.Peek(name))
+		},
+		HeaderValues: func(name string) []string {
+			vs := ctx.Request.Header.PeekAll(name)
+			res := make([]string, len(vs))
+			for i, v := range vs {
+				res[i] = string(v)
+			}
+			return res
+		},
+		Body: ctx.PostBody(),
+		Request: func() *http.Request {
+			req := new(http.Request)
+			// The conversion only fails if the URI can't be parsed, which has already been done by fasthttp.
+			_ = fasthttpadaptor.ConvertRequest(ctx, req, true)
+			return req.WithContext(ctx)
+		},
+	}
+}
@@ -0,0 +1,221 @@
+package githubhookfasthttp
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+	"github.com/valyala/fasthttp"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main"}`)
+
+func testNewRequestCtx(t *testing.T, secret string) *fasthttp.RequestCtx {
+	t.Helper()
+	s := &githubhook.Signer{
+		Secret: secret,
+	}
+	header, err := s.Header("push", "test", testRawPayload)
+	assert.NoError(t, err)
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetMethod(http.MethodPost)
+	ctx.Request.SetRequestURI("/")
+	for name := range header {
+		ctx.Request.Header.Set(name, header.Get(name))
+	}
+	ctx.Request.SetBody(testRawPayload)
+	return ctx
+}
+
+func TestHandler(t *testing.T) {
+	var md *githubhook.DeliveryMetadata
+	var payload any
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, m *githubhook.DeliveryMetadata, p any) error {
+			md = m
+			payload = p
+			return nil
+		},
+	}
+	ctx := testNewRequestCtx(t, "foobar")
+	ctx.Request.Header.Set("X-GitHub-Hook-ID", "123")
+	Handler(h)(ctx)
+	assert.Equal(t, ctx.Response.StatusCode(), http.StatusOK)
+	assert.Equal(t, md.Event, "push")
+	assert.Equal(t, md.DeliveryID, "test")
+	assert.Equal(t, md.HookID, "123")
+	pe, _ := assert.Type[*events.PushEvent](t, payload)
+	assert.Equal(t, pe.Ref, "refs/heads/main")
+}
+
+func TestHandlerForm(t *testing.T) {
+	deliveryCalled := false
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	ctx := testNewRequestCtx(t, "foobar")
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	ctx.Request.SetBodyString(url.Values{"payload": {string(testRawPayload)}}.Encode())
+	Handler(h)(ctx)
+	assert.Equal(t, ctx.Response.StatusCode(), http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func TestHandlerRejectForm(t *testing.T) {
+	h := &githubhook.Handler{
+		RejectForm: true,
+	}
+	ctx := testNewRequestCtx(t, "")
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	ctx.Request.SetBodyString(url.Values{"payload": {string(testRawPayload)}}.Encode())
+	Handler(h)(ctx)
+	assert.Equal(t, ctx.Response.StatusCode(), http.StatusUnsupportedMediaType)
+}
+
+func TestHandlerAsync(t *testing.T) {
+	deliveries := make(chan []byte, 1)
+	release := make(chan struct{})
+	h := &githubhook.Handler{
+		Async: githubhook.NewAsyncPool(1, 1),
+		Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			<-release
+			deliveries <- d.RawPayload
+			return nil
+		}),
+	}
+	ctx := testNewRequestCtx(t, "")
+	Handler(h)(ctx)
+	assert.Equal(t, ctx.Response.StatusCode(), http.StatusAccepted)
+	// The body buffer is reused by the server after the handler returns.
+	copy(ctx.Request.Body(), "xxxxxxxx")
+	close(release)
+	select {
+	case rawPayload := <-deliveries:
+		assert.Equal(t, string(rawPayload), string(testRawPayload))
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		modify     func(ctx *fasthttp.RequestCtx)
+		statusCode int
+		expected   error
+	}{
+		{
+			name: "InvalidSignature",
+			modify: func(ctx *fasthttp.RequestCtx) {
+				ctx.Request.SetBodyString(`{}`)
+			},
+			statusCode: http.StatusBadRequest,
+			expected:   githubhook.ErrInvalidSignature,
+		},
+		{
+			name: "Method",
+			modify: func(ctx *fasthttp.RequestCtx) {
+				ctx.Request.Header.SetMethod(http.MethodGet)
+			},
+			statusCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "MissingEvent",
+			modify: func(ctx *fasthttp.RequestCtx) {
+				ctx.Request.Header.Del("X-GitHub-Event")
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "MissingDeliveryID",
+			modify: func(ctx *fasthttp.RequestCtx) {
+				ctx.Request.Header.Del("X-GitHub-Delivery")
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "ContentType",
+			modify: func(ctx *fasthttp.RequestCtx) {
+				ctx.Request.Header.SetContentType("text/plain")
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "MaxBodySize",
+			modify: func(ctx *fasthttp.RequestCtx) {
+				ctx.Request.SetBody(make([]byte, 2048))
+			},
+			statusCode: http.StatusRequestEntityTooLarge,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var handledErr error
+			var handledReq *http.Request
+			var observedStatusCode int
+			h := &githubhook.Handler{
+				Secret:      "foobar",
+				MaxBodySize: 1024,
+				Error: func(ctx context.Context, err error, req *http.Request) {
+					handledErr = err
+					handledReq = req
+				},
+				Observer: testObserver(func(statusCode int) {
+					observedStatusCode = statusCode
+				}),
+			}
+			ctx := testNewRequestCtx(t, "foobar")
+			tc.modify(ctx)
+			Handler(h)(ctx)
+			assert.Equal(t, ctx.Response.StatusCode(), tc.statusCode)
+			assert.Equal(t, observedStatusCode, tc.statusCode)
+			assert.Error(t, handledErr)
+			assert.NotZero(t, handledReq)
+			if tc.expected != nil {
+				assert.ErrorIs(t, handledErr, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHandlerRouterReject(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	r, err := githubhook.NewRouter(h)
+	assert.NoError(t, err)
+	r.Unregistered = githubhook.UnregisteredEventReject
+	ctx := testNewRequestCtx(t, "foobar")
+	Handler(h)(ctx)
+	assert.Equal(t, ctx.Response.StatusCode(), http.StatusBadRequest)
+}
+
+func TestHandlerSourceAllowlist(t *testing.T) {
+	h := &githubhook.Handler{
+		SourceAllowlist: &githubhook.SourceAllowlist{
+			Prefixes: githubhook.StaticPrefixes{netip.MustParsePrefix("192.0.2.0/24")},
+		},
+	}
+	ctx := testNewRequestCtx(t, "")
+	Handler(h)(ctx)
+	assert.Equal(t, ctx.Response.StatusCode(), http.StatusForbidden)
+}
+
+type testObserver func(statusCode int)
+
+func (o testObserver) ObserveRequest(ctx context.Context, event string, statusCode int, err error) {
+	o(statusCode)
+}
+
+func (o testObserver) ObserveDelivery(ctx context.Context, md *githubhook.DeliveryMetadata, duration time.Duration, err error) {
+}
@@ -0,0 +1,18 @@
+module github.com/pierrre/githubhook/githubhookfasthttp
+
+go 1.23.0
+
+require (
+	github.com/pierrre/assert v0.6.0
+	github.com/pierrre/githubhook v0.1.0
+	github.com/valyala/fasthttp v1.58.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/klauspost/compress v1.17.11 // indirect
+	github.com/pierrre/compare v1.4.13 // indirect
+	github.com/pierrre/go-libs v0.10.3 // indirect
+	github.com/pierrre/pretty v0.8.1 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+)
@@ -0,0 +1,84 @@
+// Package githubhookgin provides a [gin] adapter for [githubhook.Handler].
+//
+// The webhook is mounted with [Handler], that runs the whole pipeline of the [githubhook.Handler]:
+//
+//	r.POST("/webhook", githubhookgin.Handler(h))
+//
+// Or with [Middleware], that only verifies the deliveries, so they are handled by the next gin handlers:
+//
+//	r.POST("/webhook", githubhookgin.Middleware(h), func(c *gin.Context) {
+//		payload, _ := githubhookgin.Payload(c)
+//		...
+//	})
+//
+// In both cases, if the request body was already consumed with [gin.Context.ShouldBindBodyWith], it is restored before the verification, so the signature can be verified.
+package githubhookgin
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pierrre/githubhook"
+)
+
+// Handler returns a [gin.HandlerFunc] for a [http.Handler]: usually a [githubhook.Handler], that runs its whole pipeline, or a [githubhook.ReloadableHandler].
+//
+// The next handlers are not called.
+func Handler(h http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		restoreBody(c)
+		h.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+// Middleware returns a [gin.HandlerFunc] that verifies the deliveries with the [githubhook.Handler], and calls the next handlers with the verified delivery (see [githubhook.Handler.Middleware]).
+//
+// The verified delivery can be retrieved with [Delivery] and [Payload].
+// If the verification fails, the response is written and the next handlers are not called.
+func Middleware(h *githubhook.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		restoreBody(c)
+		called := false
+		h.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called = true
+			c.Request = req
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+func restoreBody(c *gin.Context) {
+	v, ok := c.Get(gin.BodyBytesKey)
+	if !ok {
+		return
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(b))
+	c.Request.ContentLength = int64(len(b))
+}
+
+// Delivery returns the verified delivery, injected by [Middleware] in the request context (see [githubhook.VerifiedDeliveryFromContext]).
+func Delivery(c *gin.Context) (*githubhook.VerifiedDelivery, bool) {
+	if c.Request == nil {
+		return nil, false
+	}
+	return githubhook.VerifiedDeliveryFromContext(c.Request.Context())
+}
+
+// Payload returns the decoded payload, injected by [Middleware].
+func Payload(c *gin.Context) (any, bool) {
+	d, ok := Delivery(c)
+	if !ok {
+		return nil, false
+	}
+	return d.Payload, true
+}
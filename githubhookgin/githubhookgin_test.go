@@ -0,0 +1,132 @@
+package githubhookgin
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // Github uses SHA1.
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"foo":"bar"}`)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestHandler(t *testing.T) {
+	deliveryCalled := false
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	r := gin.New()
+	r.POST("/", Handler(h), func(c *gin.Context) {
+		t.Fatal("next handler called")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func TestHandlerBodyConsumed(t *testing.T) {
+	deliveryCalled := false
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+	}
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		var v map[string]any
+		err := c.ShouldBindBodyWith(&v, binding.JSON)
+		assert.NoError(t, err)
+	}, Handler(h))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, deliveryCalled)
+}
+
+func testNewRequest(t *testing.T, secret string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(testRawPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	hash := hmac.New(sha1.New, []byte(secret))
+	_, _ = hash.Write(testRawPayload)
+	req.Header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(hash.Sum(nil)))
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	var payload any
+	r := gin.New()
+	r.POST("/", Middleware(h), func(c *gin.Context) {
+		d, ok := Delivery(c)
+		assert.True(t, ok)
+		assert.Equal(t, d.Event, "push")
+		payload, _ = Payload(c)
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	assert.NotZero(t, payload)
+}
+
+func TestMiddlewareBodyConsumed(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		var v map[string]any
+		err := c.ShouldBindBodyWith(&v, binding.JSON)
+		assert.NoError(t, err)
+	}, Middleware(h), func(c *gin.Context) {
+		_, ok := Delivery(c)
+		assert.True(t, ok)
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, testNewRequest(t, h.Secret))
+	assert.Equal(t, w.Code, http.StatusNoContent)
+}
+
+func TestMiddlewareInvalidSignature(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	r := gin.New()
+	r.POST("/", Middleware(h), func(c *gin.Context) {
+		t.Fatal("handler called")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, testNewRequest(t, "invalid"))
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestPayloadNotFound(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	_, ok := Payload(c)
+	assert.False(t, ok)
+}
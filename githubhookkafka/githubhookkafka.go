@@ -0,0 +1,174 @@
+// Package githubhookkafka provides a [Kafka] sink for verified GitHub webhook deliveries, with the [sarama] client.
+//
+// It writes the deliveries to a topic, keyed by the repository full name, so the deliveries of a repository are ordered in a partition:
+//
+//	http.Handle("/webhook", h.Middleware(&githubhookkafka.Producer{Topic: "github", SyncProducer: sp}))
+//
+// [Kafka]: https://kafka.apache.org
+package githubhookkafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/IBM/sarama"
+	"github.com/pierrre/githubhook"
+)
+
+// SyncProducer produces messages, and waits for the acknowledgement.
+//
+// It's implemented by [sarama.SyncProducer].
+type SyncProducer interface {
+	SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+}
+
+// AsyncProducer produces messages in the background.
+//
+// It's implemented by [sarama.AsyncProducer].
+type AsyncProducer interface {
+	Input() chan<- *sarama.ProducerMessage
+}
+
+// Client returns the metadata of the cluster.
+//
+// It's implemented by [sarama.Client], e.g. the client the producer is created with ([sarama.NewSyncProducerFromClient]).
+type Client interface {
+	Partitions(topic string) ([]int32, error)
+}
+
+/*
+Producer writes verified deliveries to a Kafka topic.
+
+If SyncProducer is set, the delivery is written synchronously, and [Producer.Produce] returns after it's acknowledged by Kafka.
+So the HTTP response reflects the success or the failure, and GitHub can redeliver it if the production fails.
+Otherwise, the delivery is written with AsyncProducer, and [Producer.Produce] returns once the message is queued.
+The production errors must be handled with [sarama.AsyncProducer.Errors].
+
+The message value is the raw JSON payload.
+The message key is the repository full name (see [githubhook.RepositoryFullName]), or no key if the payload doesn't have a repository.
+The message headers are X-GitHub-Event, X-GitHub-Delivery, and the lineage headers (see [githubhook.Lineage]).
+
+It can be used as:
+  - a [http.Handler] wrapped by [githubhook.Handler.Middleware]: the response status is 200 if the delivery is written, 502 otherwise.
+  - a [githubhook.DeliveryHandler] with [Producer.Handle].
+
+Fields:
+  - Topic is the topic (required).
+  - SyncProducer is the synchronous producer (required if AsyncProducer is not set).
+  - AsyncProducer is the asynchronous producer (optional).
+  - Client is the client of the producer, used by [Producer.Start] to validate the topic (optional).
+  - Error is called if a delivery can't be written by [Producer.ServeHTTP].
+*/
+type Producer struct {
+	Topic         string
+	SyncProducer  SyncProducer
+	AsyncProducer AsyncProducer
+	Client        Client
+	Error         func(ctx context.Context, err error, req *http.Request)
+}
+
+func (p *Producer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	githubhook.SinkHTTPHandler(p, p.Error).ServeHTTP(w, req)
+}
+
+// Handle writes a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+// If the payload is a [json.RawMessage] or a []byte, it's written as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are not written.
+// [Producer.ServeHTTP] should be preferred, because it writes the raw payload.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func (p *Producer) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	return githubhook.SinkDeliveryHandler(p)(ctx, md, payload)
+}
+
+// Send writes a verified delivery.
+//
+// It implements [githubhook.Sink].
+func (p *Producer) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	return p.Produce(ctx, &d.DeliveryMetadata, d.RawPayload)
+}
+
+// Produce writes a delivery with its raw JSON payload.
+func (p *Producer) Produce(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	msg := p.newMessage(md, rawPayload)
+	return githubhook.TracePublish(ctx, p.Topic, md.DeliveryID, func() error {
+		return p.produce(ctx, msg)
+	})
+}
+
+func (p *Producer) produce(ctx context.Context, msg *sarama.ProducerMessage) error {
+	if p.SyncProducer != nil {
+		_, _, err := p.SyncProducer.SendMessage(msg)
+		if err != nil {
+			return fmt.Errorf("Kafka: send message to topic %q: %w", p.Topic, err)
+		}
+		return nil
+	}
+	if p.AsyncProducer == nil {
+		return errors.New("Kafka: no producer")
+	}
+	select {
+	case p.AsyncProducer.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Kafka: queue message to topic %q: %w", p.Topic, ctx.Err())
+	}
+}
+
+// Start validates the producer, before the deliveries are written.
+//
+// It implements [githubhook.SinkStarter].
+// The sarama producers connect to the brokers when they are created, so it only checks that the topic exists, with the metadata of Client (if it's set).
+func (p *Producer) Start(ctx context.Context) error {
+	if p.SyncProducer == nil && p.AsyncProducer == nil {
+		return errors.New("Kafka: no producer")
+	}
+	if p.Client == nil {
+		return nil
+	}
+	_, err := p.Client.Partitions(p.Topic)
+	if err != nil {
+		return fmt.Errorf("Kafka: partitions of topic %q: %w", p.Topic, err)
+	}
+	return nil
+}
+
+func (p *Producer) newMessage(md *githubhook.DeliveryMetadata, rawPayload []byte) *sarama.ProducerMessage {
+	header := make(http.Header)
+	if md.Lineage != nil {
+		md.Lineage.SetHeader(header)
+	}
+	// Kafka headers are case sensitive, so the GitHub headers are not canonicalized.
+	headers := []sarama.RecordHeader{
+		newRecordHeader("X-GitHub-Event", md.Event),
+		newRecordHeader("X-GitHub-Delivery", md.DeliveryID),
+	}
+	for _, k := range slices.Sorted(maps.Keys(header)) {
+		headers = append(headers, newRecordHeader(k, header.Get(k)))
+	}
+	msg := &sarama.ProducerMessage{
+		Topic:    p.Topic,
+		Value:    sarama.ByteEncoder(rawPayload),
+		Headers:  headers,
+		Metadata: md,
+	}
+	key := githubhook.RepositoryFullName(json.RawMessage(rawPayload))
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	return msg
+}
+
+func newRecordHeader(key string, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(value),
+	}
+}
@@ -0,0 +1,248 @@
+package githubhookkafka
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main","repository":{"full_name":"pierrre/githubhook"}}`)
+
+type testSyncProducer struct {
+	msgs []*sarama.ProducerMessage
+	err  error
+}
+
+func (p *testSyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	p.msgs = append(p.msgs, msg)
+	return 0, int64(len(p.msgs)), p.err
+}
+
+type testAsyncProducer struct {
+	input chan *sarama.ProducerMessage
+}
+
+func (p *testAsyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.input
+}
+
+type testClient struct {
+	topics map[string][]int32
+}
+
+func (c *testClient) Partitions(topic string) ([]int32, error) {
+	partitions, ok := c.topics[topic]
+	if !ok {
+		return nil, sarama.ErrUnknownTopicOrPartition
+	}
+	return partitions, nil
+}
+
+func testNewRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := new(githubhook.Signer).NewRequest(context.Background(), "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	return req
+}
+
+func testHeader(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestProducerSync(t *testing.T) {
+	sp := &testSyncProducer{}
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: sp,
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, sp.msgs, 1)
+	msg := sp.msgs[0]
+	assert.Equal(t, msg.Topic, "github")
+	assert.Equal(t, msg.Key, sarama.Encoder(sarama.StringEncoder("pierrre/githubhook")))
+	value, err := msg.Value.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, string(value), string(testRawPayload))
+	assert.Equal(t, testHeader(msg, "X-GitHub-Event"), "push")
+	assert.Equal(t, testHeader(msg, "X-GitHub-Delivery"), "test")
+}
+
+func TestProducerSinkTrace(t *testing.T) {
+	sp := &testSyncProducer{}
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: sp,
+	}
+	var published, acked string
+	ctx := githubhook.WithSinkTrace(context.Background(), &githubhook.SinkTrace{
+		Publish: func(target string, deliveryID string) {
+			published = target + " " + deliveryID
+		},
+		Ack: func(target string, deliveryID string, err error) {
+			assert.NoError(t, err)
+			acked = target + " " + deliveryID
+		},
+	})
+	err := p.Produce(ctx, &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, published, "github test")
+	assert.Equal(t, acked, "github test")
+}
+
+func TestProducerSyncError(t *testing.T) {
+	sp := &testSyncProducer{
+		err: errors.New("error"),
+	}
+	var produceErr error
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: sp,
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			produceErr = err
+		},
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	assert.Error(t, produceErr)
+}
+
+func TestProducerAsync(t *testing.T) {
+	ap := &testAsyncProducer{
+		input: make(chan *sarama.ProducerMessage, 1),
+	}
+	p := &Producer{
+		Topic:         "github",
+		AsyncProducer: ap,
+	}
+	err := p.Produce(context.Background(), &githubhook.DeliveryMetadata{Event: "push", DeliveryID: "test"}, testRawPayload)
+	assert.NoError(t, err)
+	msg := <-ap.input
+	assert.Equal(t, testHeader(msg, "X-GitHub-Delivery"), "test")
+}
+
+func TestProducerAsyncContextCanceled(t *testing.T) {
+	ap := &testAsyncProducer{
+		input: make(chan *sarama.ProducerMessage),
+	}
+	p := &Producer{
+		Topic:         "github",
+		AsyncProducer: ap,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := p.Produce(ctx, &githubhook.DeliveryMetadata{Event: "push"}, testRawPayload)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestProducerNoProducer(t *testing.T) {
+	p := &Producer{
+		Topic: "github",
+	}
+	err := p.Produce(context.Background(), &githubhook.DeliveryMetadata{Event: "push"}, testRawPayload)
+	assert.Error(t, err)
+}
+
+func TestProducerStart(t *testing.T) {
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: &testSyncProducer{},
+		Client:       &testClient{topics: map[string][]int32{"github": {0, 1}}},
+	}
+	err := githubhook.StartSink(context.Background(), p)
+	assert.NoError(t, err)
+	p.Topic = "unknown"
+	err = p.Start(context.Background())
+	assert.ErrorIs(t, err, sarama.ErrUnknownTopicOrPartition)
+}
+
+func TestProducerStartWithoutClient(t *testing.T) {
+	p := &Producer{
+		Topic:         "github",
+		AsyncProducer: &testAsyncProducer{},
+	}
+	err := p.Start(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestProducerStartNoProducer(t *testing.T) {
+	p := &Producer{
+		Topic: "github",
+	}
+	err := p.Start(context.Background())
+	assert.Error(t, err)
+}
+
+func TestProducerWithoutMiddleware(t *testing.T) {
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: &testSyncProducer{},
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestProducerHandle(t *testing.T) {
+	sp := &testSyncProducer{}
+	h := &githubhook.Handler{
+		Delivery: (&Producer{Topic: "github", SyncProducer: sp}).Handle,
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, sp.msgs, 1)
+	assert.Equal(t, sp.msgs[0].Key, sarama.Encoder(sarama.StringEncoder("pierrre/githubhook")))
+}
+
+func TestProducerNoRepository(t *testing.T) {
+	sp := &testSyncProducer{}
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: sp,
+	}
+	err := p.Handle(context.Background(), &githubhook.DeliveryMetadata{Event: "ping"}, []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Zero(t, sp.msgs[0].Key)
+}
+
+func TestProducerLineage(t *testing.T) {
+	sp := &testSyncProducer{}
+	p := &Producer{
+		Topic:        "github",
+		SyncProducer: sp,
+	}
+	md := &githubhook.DeliveryMetadata{
+		Event:      "push",
+		DeliveryID: "test",
+	}
+	md.Lineage = githubhook.NextLineage(md, "", "replay")
+	err := p.Produce(context.Background(), md, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, testHeader(sp.msgs[0], githubhook.LineageOriginalDeliveryHeader), "test")
+	assert.Equal(t, testHeader(sp.msgs[0], githubhook.LineageReasonHeader), "replay")
+}
+
+func TestProducerSink(t *testing.T) {
+	sp := &testSyncProducer{}
+	h := &githubhook.Handler{
+		Sink: &Producer{Topic: "github", SyncProducer: sp},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, sp.msgs, 1)
+}
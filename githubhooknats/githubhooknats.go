@@ -0,0 +1,195 @@
+// Package githubhooknats provides a [NATS] sink for verified GitHub webhook deliveries, with optional [JetStream] persistence.
+//
+// It publishes the deliveries to subjects derived from the event name and the repository (e.g. "github.push.owner.repo"):
+//
+//	http.Handle("/webhook", h.Middleware(&githubhooknats.Publisher{Conn: nc}))
+//
+// [NATS]: https://nats.io
+// [JetStream]: https://docs.nats.io/nats-concepts/jetstream
+package githubhooknats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pierrre/githubhook"
+)
+
+// DefaultSubjectPrefix is the prefix of the subjects returned by [DefaultSubject].
+const DefaultSubjectPrefix = "github"
+
+// Conn publishes core NATS messages.
+//
+// It's implemented by [nats.Conn].
+type Conn interface {
+	PublishMsg(msg *nats.Msg) error
+}
+
+// JetStream publishes JetStream messages, and waits for the acknowledgement.
+//
+// It's implemented by [jetstream.JetStream].
+type JetStream interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+/*
+Publisher publishes verified deliveries to NATS.
+
+If JetStream is set, the delivery is published with JetStream, and [Publisher.Publish] returns after the delivery is persisted by the stream.
+So the delivery is not acknowledged to GitHub until it's persisted, and GitHub can redeliver it if the publication fails.
+The delivery ID is used as message ID (Nats-Msg-Id), so redeliveries are deduplicated by the stream.
+Otherwise, the delivery is published with Conn, without persistence guarantee.
+
+The message data is the raw JSON payload.
+The message headers are X-GitHub-Event, X-GitHub-Delivery, and the lineage headers (see [githubhook.Lineage]).
+
+It can be used as:
+  - a [http.Handler] wrapped by [githubhook.Handler.Middleware]: the response status is 200 if the delivery is published, 502 otherwise.
+  - a [githubhook.DeliveryHandler] with [Publisher.Handle].
+
+Fields:
+  - Conn is the core NATS connection (required if JetStream is not set).
+  - JetStream is the JetStream context (optional).
+  - Subject returns the subject of a delivery (default: [DefaultSubject]).
+  - Error is called if a delivery can't be published by [Publisher.ServeHTTP].
+*/
+type Publisher struct {
+	Conn      Conn
+	JetStream JetStream
+	Subject   func(md *githubhook.DeliveryMetadata, rawPayload []byte) string
+	Error     func(ctx context.Context, err error, req *http.Request)
+}
+
+func (p *Publisher) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	githubhook.SinkHTTPHandler(p, p.Error).ServeHTTP(w, req)
+}
+
+// Handle publishes a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+// If the payload is a [json.RawMessage] or a []byte, it's published as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are not published.
+// [Publisher.ServeHTTP] should be preferred, because it publishes the raw payload.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func (p *Publisher) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	return githubhook.SinkDeliveryHandler(p)(ctx, md, payload)
+}
+
+// Send publishes a verified delivery.
+//
+// It implements [githubhook.Sink].
+func (p *Publisher) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	return p.Publish(ctx, &d.DeliveryMetadata, d.RawPayload)
+}
+
+// Publish publishes a delivery with its raw JSON payload.
+func (p *Publisher) Publish(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	msg := p.newMsg(md, rawPayload)
+	return githubhook.TracePublish(ctx, msg.Subject, md.DeliveryID, func() error {
+		return p.publish(ctx, msg)
+	})
+}
+
+func (p *Publisher) publish(ctx context.Context, msg *nats.Msg) error {
+	if p.JetStream != nil {
+		_, err := p.JetStream.PublishMsg(ctx, msg)
+		if err != nil {
+			return fmt.Errorf("NATS JetStream: publish %q: %w", msg.Subject, err)
+		}
+		return nil
+	}
+	if p.Conn == nil {
+		return errors.New("NATS: no connection")
+	}
+	err := p.Conn.PublishMsg(msg)
+	if err != nil {
+		return fmt.Errorf("NATS: publish %q: %w", msg.Subject, err)
+	}
+	return nil
+}
+
+// Start validates the connection, before the deliveries are published.
+//
+// It implements [githubhook.SinkStarter].
+// If JetStream implements AccountInfo (e.g. [jetstream.JetStream]), the account information is requested, so a server without JetStream or an account without permission is detected.
+// Otherwise, if Conn implements FlushWithContext (e.g. [nats.Conn]), the connection is flushed with a round trip to the server.
+func (p *Publisher) Start(ctx context.Context) error {
+	if p.JetStream != nil {
+		js, ok := p.JetStream.(interface {
+			AccountInfo(ctx context.Context) (*jetstream.AccountInfo, error)
+		})
+		if !ok {
+			return nil
+		}
+		_, err := js.AccountInfo(ctx)
+		if err != nil {
+			return fmt.Errorf("NATS JetStream: account info: %w", err)
+		}
+		return nil
+	}
+	if p.Conn == nil {
+		return errors.New("NATS: no connection")
+	}
+	nc, ok := p.Conn.(interface {
+		FlushWithContext(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	err := nc.FlushWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("NATS: flush: %w", err)
+	}
+	return nil
+}
+
+func (p *Publisher) newMsg(md *githubhook.DeliveryMetadata, rawPayload []byte) *nats.Msg {
+	subject := p.Subject
+	if subject == nil {
+		subject = DefaultSubject
+	}
+	header := make(http.Header)
+	if md.Lineage != nil {
+		md.Lineage.SetHeader(header)
+	}
+	msg := &nats.Msg{
+		Subject: subject(md, rawPayload),
+		Header:  nats.Header(header),
+		Data:    rawPayload,
+	}
+	// NATS headers are case sensitive, so the GitHub headers are not canonicalized.
+	msg.Header.Set("X-GitHub-Event", md.Event)
+	msg.Header.Set("X-GitHub-Delivery", md.DeliveryID)
+	if md.DeliveryID != "" {
+		msg.Header.Set(jetstream.MsgIDHeader, md.DeliveryID)
+	}
+	return msg
+}
+
+// DefaultSubject returns the subject "github.<event>.<owner>.<repo>" of a delivery, or "github.<event>" if the payload doesn't have a repository.
+//
+// The tokens are sanitized: the characters that are not allowed in a NATS subject token ('.', '*', '>' and whitespace) are replaced with '_'.
+func DefaultSubject(md *githubhook.DeliveryMetadata, rawPayload []byte) string {
+	tokens := []string{DefaultSubjectPrefix, sanitizeToken(md.Event)}
+	owner, repo, ok := strings.Cut(githubhook.RepositoryFullName(json.RawMessage(rawPayload)), "/")
+	if ok {
+		tokens = append(tokens, sanitizeToken(owner), sanitizeToken(repo))
+	}
+	return strings.Join(tokens, ".")
+}
+
+var tokenReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_", "\t", "_", "\r", "_", "\n", "_")
+
+func sanitizeToken(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return tokenReplacer.Replace(s)
+}
@@ -0,0 +1,237 @@
+package githubhooknats
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main","repository":{"full_name":"pierrre/githubhook"}}`)
+
+type testConn struct {
+	msgs     []*nats.Msg
+	err      error
+	flushed  int
+	flushErr error
+}
+
+func (c *testConn) PublishMsg(msg *nats.Msg) error {
+	c.msgs = append(c.msgs, msg)
+	return c.err
+}
+
+func (c *testConn) FlushWithContext(ctx context.Context) error {
+	c.flushed++
+	return c.flushErr
+}
+
+type testJetStream struct {
+	msgs       []*nats.Msg
+	err        error
+	accountErr error
+}
+
+func (js *testJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.msgs = append(js.msgs, msg)
+	if js.err != nil {
+		return nil, js.err
+	}
+	return &jetstream.PubAck{Stream: "github"}, nil
+}
+
+func (js *testJetStream) AccountInfo(ctx context.Context) (*jetstream.AccountInfo, error) {
+	if js.accountErr != nil {
+		return nil, js.accountErr
+	}
+	return new(jetstream.AccountInfo), nil
+}
+
+func testNewRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := new(githubhook.Signer).NewRequest(context.Background(), "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	return req
+}
+
+func TestPublisherConn(t *testing.T) {
+	conn := &testConn{}
+	p := &Publisher{
+		Conn: conn,
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, conn.msgs, 1)
+	msg := conn.msgs[0]
+	assert.Equal(t, msg.Subject, "github.push.pierrre.githubhook")
+	assert.Equal(t, string(msg.Data), string(testRawPayload))
+	assert.Equal(t, msg.Header.Get("X-GitHub-Event"), "push")
+	assert.Equal(t, msg.Header.Get("X-GitHub-Delivery"), "test")
+	assert.Equal(t, msg.Header.Get(jetstream.MsgIDHeader), "test")
+}
+
+func TestPublisherJetStream(t *testing.T) {
+	conn := &testConn{}
+	js := &testJetStream{}
+	p := &Publisher{
+		Conn:      conn,
+		JetStream: js,
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceEmpty(t, conn.msgs)
+	assert.SliceLen(t, js.msgs, 1)
+}
+
+func TestPublisherJetStreamError(t *testing.T) {
+	js := &testJetStream{
+		err: errors.New("error"),
+	}
+	var publishErr error
+	p := &Publisher{
+		JetStream: js,
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			publishErr = err
+		},
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	assert.Error(t, publishErr)
+}
+
+func TestPublisherNoConn(t *testing.T) {
+	p := &Publisher{}
+	err := p.Publish(context.Background(), &githubhook.DeliveryMetadata{Event: "push"}, testRawPayload)
+	assert.Error(t, err)
+}
+
+func TestPublisherStartConn(t *testing.T) {
+	c := &testConn{}
+	p := &Publisher{Conn: c}
+	err := githubhook.StartSink(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, c.flushed, 1)
+	c.flushErr = errors.New("error")
+	err = p.Start(context.Background())
+	assert.ErrorContains(t, err, "NATS: flush: error")
+}
+
+func TestPublisherStartJetStream(t *testing.T) {
+	js := &testJetStream{}
+	p := &Publisher{JetStream: js}
+	err := p.Start(context.Background())
+	assert.NoError(t, err)
+	js.accountErr = errors.New("error")
+	err = p.Start(context.Background())
+	assert.ErrorContains(t, err, "NATS JetStream: account info: error")
+}
+
+func TestPublisherStartNoConn(t *testing.T) {
+	p := &Publisher{}
+	err := p.Start(context.Background())
+	assert.Error(t, err)
+}
+
+func TestPublisherWithoutMiddleware(t *testing.T) {
+	p := &Publisher{
+		Conn: &testConn{},
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestPublisherHandle(t *testing.T) {
+	conn := &testConn{}
+	h := &githubhook.Handler{
+		Delivery: (&Publisher{Conn: conn}).Handle,
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, conn.msgs, 1)
+	assert.Equal(t, conn.msgs[0].Subject, "github.push.pierrre.githubhook")
+}
+
+func TestPublisherHandleRaw(t *testing.T) {
+	conn := &testConn{}
+	p := &Publisher{
+		Conn: conn,
+	}
+	err := p.Handle(context.Background(), &githubhook.DeliveryMetadata{Event: "push"}, []byte(testRawPayload))
+	assert.NoError(t, err)
+	assert.Equal(t, string(conn.msgs[0].Data), string(testRawPayload))
+}
+
+func TestPublisherLineage(t *testing.T) {
+	conn := &testConn{}
+	p := &Publisher{
+		Conn: conn,
+	}
+	md := &githubhook.DeliveryMetadata{
+		Event:      "push",
+		DeliveryID: "test",
+	}
+	md.Lineage = githubhook.NextLineage(md, "", "replay")
+	err := p.Publish(context.Background(), md, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, conn.msgs[0].Header.Get(githubhook.LineageOriginalDeliveryHeader), "test")
+}
+
+func TestDefaultSubject(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		event      string
+		rawPayload string
+		expected   string
+	}{
+		{
+			name:       "Repository",
+			event:      events.NamePush,
+			rawPayload: `{"repository":{"full_name":"pierrre/githubhook"}}`,
+			expected:   "github.push.pierrre.githubhook",
+		},
+		{
+			name:       "NoRepository",
+			event:      events.NamePing,
+			rawPayload: `{}`,
+			expected:   "github.ping",
+		},
+		{
+			name:       "Sanitize",
+			event:      events.NamePush,
+			rawPayload: `{"repository":{"full_name":"pierrre/github.hook"}}`,
+			expected:   "github.push.pierrre.github_hook",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			md := &githubhook.DeliveryMetadata{
+				Event: tc.event,
+			}
+			s := DefaultSubject(md, []byte(tc.rawPayload))
+			assert.Equal(t, s, tc.expected)
+		})
+	}
+}
+
+func TestPublisherSink(t *testing.T) {
+	conn := &testConn{}
+	h := &githubhook.Handler{
+		Sink: &Publisher{Conn: conn},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, conn.msgs, 1)
+	assert.Equal(t, string(conn.msgs[0].Data), string(testRawPayload))
+}
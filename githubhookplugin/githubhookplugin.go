@@ -0,0 +1,394 @@
+/*
+Package githubhookplugin provides external delivery processors, loaded as plugins with [go-plugin] over gRPC.
+
+A plugin is a separate binary, that serves a [Plugin] with [Serve].
+It can be a sink or a processor (it receives the deliveries), a filter (it decides which deliveries are sent to the other sinks), or both.
+It's started by the receiver with [Open], which returns a [Client].
+
+It allows to extend a deployed receiver (e.g. githubhookd) without recompiling it.
+The plugin receives the verified deliveries, with their metadata and raw payload.
+
+[go-plugin]: https://github.com/hashicorp/go-plugin
+*/
+package githubhookplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// handshake is the handshake between the receiver and the plugins.
+// The protocol version must be incremented if the service changes in an incompatible way.
+var handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "GITHUBHOOK_PLUGIN",
+	MagicCookieValue: "githubhook",
+}
+
+const pluginName = "processor"
+
+/*
+Plugin is the implementation of a plugin.
+
+Fields (at least one is required):
+  - Sink receives the deliveries.
+  - Filter returns false if a delivery must not be sent to the other sinks.
+
+The payload of the delivery is decoded with [events.Decode].
+*/
+type Plugin struct {
+	Sink   githubhook.Sink
+	Filter func(ctx context.Context, d *githubhook.VerifiedDelivery) (bool, error)
+}
+
+// Serve serves a plugin.
+//
+// It must be called by the main function of the plugin binary, and doesn't return.
+func Serve(p *Plugin) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: handshake,
+		Plugins: plugin.PluginSet{
+			pluginName: &grpcPlugin{impl: p},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
+
+// Open starts a plugin binary with the command, and returns its [Client].
+//
+// The logs of the plugin are written to logs (optional).
+// The [Client] must be closed, in order to stop the plugin.
+func Open(ctx context.Context, cmd *exec.Cmd, logs io.Writer) (*Client, error) {
+	if logs == nil {
+		logs = io.Discard
+	}
+	pc := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: handshake,
+		Plugins: plugin.PluginSet{
+			pluginName: new(grpcPlugin),
+		},
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:       "plugin",
+			Output:     logs,
+			Level:      hclog.Info,
+			JSONFormat: true,
+		}),
+	})
+	c, err := newClient(ctx, pc)
+	if err != nil {
+		pc.Kill()
+		return nil, fmt.Errorf("plugin %s: %w", cmd.Path, err)
+	}
+	return c, nil
+}
+
+func newClient(ctx context.Context, pc *plugin.Client) (*Client, error) {
+	rpcClient, err := pc.Client()
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	raw, err := rpcClient.Dispense(pluginName)
+	if err != nil {
+		return nil, fmt.Errorf("dispense: %w", err)
+	}
+	c := &Client{
+		grpc: raw.(*grpcClient), //nolint:forcetypeassert // The type is defined by grpcPlugin.
+		kill: pc.Kill,
+	}
+	err = c.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+/*
+Client is the client of a plugin started by [Open].
+
+It implements [githubhook.Sink].
+*/
+type Client struct {
+	grpc   *grpcClient
+	info   infoMessage
+	kill   func()
+	closed bool
+}
+
+func (c *Client) init(ctx context.Context) error {
+	err := c.grpc.invoke(ctx, "Info", struct{}{}, &c.info)
+	if err != nil {
+		return fmt.Errorf("info: %w", err)
+	}
+	if !c.info.Sink && !c.info.Filter {
+		return errors.New("info: neither a sink nor a filter")
+	}
+	return nil
+}
+
+// IsSink returns true if the plugin is a sink ([Plugin.Sink]).
+func (c *Client) IsSink() bool {
+	return c.info.Sink
+}
+
+// IsFilter returns true if the plugin is a filter ([Plugin.Filter]).
+func (c *Client) IsFilter() bool {
+	return c.info.Filter
+}
+
+// Send implements [githubhook.Sink].
+//
+// It sends a delivery to [Plugin.Sink].
+// It does nothing if the plugin is not a sink.
+func (c *Client) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	if !c.info.Sink {
+		return nil
+	}
+	err := c.grpc.invoke(ctx, "Send", newDeliveryMessage(d), &struct{}{})
+	if err != nil {
+		return fmt.Errorf("plugin: %w", err)
+	}
+	return nil
+}
+
+// Match calls [Plugin.Filter], and returns true if the delivery must be sent to the other sinks.
+//
+// It returns true if the plugin is not a filter.
+func (c *Client) Match(ctx context.Context, d *githubhook.VerifiedDelivery) (bool, error) {
+	if !c.info.Filter {
+		return true, nil
+	}
+	var resp matchMessage
+	err := c.grpc.invoke(ctx, "Match", newDeliveryMessage(d), &resp)
+	if err != nil {
+		return false, fmt.Errorf("plugin: %w", err)
+	}
+	return resp.Match, nil
+}
+
+// Filtered returns a [githubhook.Sink] that sends the deliveries to the sink only if they match [Client.Match].
+//
+// It implements [githubhook.SinkStarter] and [githubhook.SinkCloser]: it starts and closes the sink.
+func (c *Client) Filtered(sink githubhook.Sink) githubhook.Sink {
+	return &filteredSink{
+		client: c,
+		sink:   sink,
+	}
+}
+
+type filteredSink struct {
+	client *Client
+	sink   githubhook.Sink
+}
+
+func (s *filteredSink) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	ok, err := s.client.Match(ctx, d)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return s.sink.Send(ctx, d)
+}
+
+func (s *filteredSink) Start(ctx context.Context) error {
+	return githubhook.StartSink(ctx, s.sink)
+}
+
+func (s *filteredSink) Close(ctx context.Context) error {
+	return githubhook.CloseSink(ctx, s.sink)
+}
+
+// Close stops the plugin.
+func (c *Client) Close() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.kill()
+}
+
+// grpcPlugin is the [plugin.GRPCPlugin] of the processor plugin.
+type grpcPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	impl *Plugin
+}
+
+func (p *grpcPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&serviceDesc, &server{impl: p.impl})
+	return nil
+}
+
+func (p *grpcPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, conn *grpc.ClientConn) (any, error) {
+	return &grpcClient{conn: conn}, nil
+}
+
+// The service is defined without protobuf: the messages are Go types, encoded in JSON by jsonCodec.
+
+const serviceName = "githubhookplugin.Processor"
+
+type infoMessage struct {
+	Sink   bool `json:"sink"`
+	Filter bool `json:"filter"`
+}
+
+type deliveryMessage struct {
+	Metadata   githubhook.DeliveryMetadata `json:"metadata"`
+	RawPayload []byte                      `json:"raw_payload"`
+}
+
+func newDeliveryMessage(d *githubhook.VerifiedDelivery) *deliveryMessage {
+	return &deliveryMessage{
+		Metadata:   d.DeliveryMetadata,
+		RawPayload: d.RawPayload,
+	}
+}
+
+func (m *deliveryMessage) delivery() (*githubhook.VerifiedDelivery, error) {
+	payload, err := events.Decode(m.Metadata.Event, m.RawPayload)
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	return &githubhook.VerifiedDelivery{
+		DeliveryMetadata: m.Metadata,
+		RawPayload:       m.RawPayload,
+		Payload:          payload,
+	}, nil
+}
+
+type matchMessage struct {
+	Match bool `json:"match"`
+}
+
+// processorServer is the interface of the service implementation, required by [grpc.ServiceDesc].
+type processorServer interface {
+	info(ctx context.Context, req *struct{}) (*infoMessage, error)
+	send(ctx context.Context, req *deliveryMessage) (*struct{}, error)
+	match(ctx context.Context, req *deliveryMessage) (*matchMessage, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*processorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Info",
+			Handler:    unaryHandler(processorServer.info),
+		},
+		{
+			MethodName: "Send",
+			Handler:    unaryHandler(processorServer.send),
+		},
+		{
+			MethodName: "Match",
+			Handler:    unaryHandler(processorServer.match),
+		},
+	},
+}
+
+func unaryHandler[Req any, Resp any](f func(processorServer, context.Context, *Req) (*Resp, error)) func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := new(Req)
+		err := dec(req)
+		if err != nil {
+			return nil, err
+		}
+		ps := srv.(processorServer) //nolint:forcetypeassert // The type is checked by RegisterService.
+		if interceptor == nil {
+			return f(ps, ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv}, func(ctx context.Context, req any) (any, error) {
+			return f(ps, ctx, req.(*Req)) //nolint:forcetypeassert // The request is passed by the interceptor.
+		})
+	}
+}
+
+type server struct {
+	impl *Plugin
+}
+
+func (s *server) info(ctx context.Context, req *struct{}) (*infoMessage, error) {
+	return &infoMessage{
+		Sink:   s.impl.Sink != nil,
+		Filter: s.impl.Filter != nil,
+	}, nil
+}
+
+func (s *server) send(ctx context.Context, req *deliveryMessage) (*struct{}, error) {
+	if s.impl.Sink == nil {
+		return nil, status.Error(codes.Unimplemented, "not a sink")
+	}
+	d, err := req.delivery()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = s.impl.Sink.Send(ctx, d)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	return &struct{}{}, nil
+}
+
+func (s *server) match(ctx context.Context, req *deliveryMessage) (*matchMessage, error) {
+	if s.impl.Filter == nil {
+		return nil, status.Error(codes.Unimplemented, "not a filter")
+	}
+	d, err := req.delivery()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ok, err := s.impl.Filter(ctx, d)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	return &matchMessage{Match: ok}, nil
+}
+
+type grpcClient struct {
+	conn *grpc.ClientConn
+}
+
+// invoke calls a method of the service, and returns the message of the error status.
+func (c *grpcClient) invoke(ctx context.Context, method string, req any, resp any) error {
+	err := c.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, resp, grpc.CallContentSubtype(jsonCodec{}.Name()))
+	if err != nil {
+		return errors.New(status.Convert(err).Message())
+	}
+	return nil
+}
+
+// jsonCodec is a [encoding.Codec] that encodes the messages in JSON.
+//
+// It's registered for the "githubhookplugin" content subtype, so it doesn't replace the protobuf codec used by the internal services of go-plugin.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v) //nolint:wrapcheck // Not needed.
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v) //nolint:wrapcheck // Not needed.
+}
+
+func (jsonCodec) Name() string {
+	return "githubhookplugin"
+}
+
+func init() { //nolint:gochecknoinits // The codec must be registered before the server and the client are created.
+	encoding.RegisterCodec(jsonCodec{})
+}
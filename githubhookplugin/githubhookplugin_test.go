@@ -0,0 +1,174 @@
+package githubhookplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main"}`)
+
+// testPluginEnv is the environment variable that runs the test binary as a plugin, see TestMain.
+const testPluginEnv = "GITHUBHOOKPLUGIN_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		Serve(newTestPlugin(nil))
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// newTestPlugin creates a plugin that accepts the "push" events, and fails for the "issues" events.
+func newTestPlugin(sent func(d *githubhook.VerifiedDelivery)) *Plugin {
+	return &Plugin{
+		Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			if d.Event == "issues" {
+				return errors.New("error")
+			}
+			if sent != nil {
+				sent(d)
+			}
+			return nil
+		}),
+		Filter: func(ctx context.Context, d *githubhook.VerifiedDelivery) (bool, error) {
+			if d.Event == "issues" {
+				return false, errors.New("error")
+			}
+			return d.Event == "push", nil
+		},
+	}
+}
+
+func testNewClient(t *testing.T, p *Plugin) *Client {
+	t.Helper()
+	rpcClient, _ := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{
+		pluginName: &grpcPlugin{impl: p},
+	})
+	raw, err := rpcClient.Dispense(pluginName)
+	assert.NoError(t, err)
+	c := &Client{
+		grpc: raw.(*grpcClient), //nolint:forcetypeassert // Test.
+		kill: func() {
+			_ = rpcClient.Close()
+		},
+	}
+	err = c.init(context.Background())
+	assert.NoError(t, err)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func testDelivery(event string) *githubhook.VerifiedDelivery {
+	return &githubhook.VerifiedDelivery{
+		DeliveryMetadata: githubhook.DeliveryMetadata{
+			Event:      event,
+			DeliveryID: "test",
+			Lineage:    &githubhook.Lineage{OriginalDeliveryID: "original", Generation: 1},
+		},
+		RawPayload: testRawPayload,
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	var sent *githubhook.VerifiedDelivery
+	c := testNewClient(t, newTestPlugin(func(d *githubhook.VerifiedDelivery) {
+		sent = d
+	}))
+	assert.True(t, c.IsSink())
+	assert.True(t, c.IsFilter())
+	err := c.Send(ctx, testDelivery("push"))
+	assert.NoError(t, err)
+	assert.Equal(t, sent.DeliveryID, "test")
+	assert.DeepEqual(t, sent.Lineage, &githubhook.Lineage{OriginalDeliveryID: "original", Generation: 1})
+	assert.Equal(t, string(sent.RawPayload), string(testRawPayload))
+	ev, _ := assert.Type[*events.PushEvent](t, sent.Payload)
+	assert.Equal(t, ev.Ref, "refs/heads/main")
+	err = c.Send(ctx, testDelivery("issues"))
+	assert.ErrorContains(t, err, "error")
+	ok, err := c.Match(ctx, testDelivery("push"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = c.Match(ctx, testDelivery("release"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, err = c.Match(ctx, testDelivery("issues"))
+	assert.Error(t, err)
+}
+
+func TestClientFiltered(t *testing.T) {
+	ctx := context.Background()
+	c := testNewClient(t, &Plugin{
+		Filter: newTestPlugin(nil).Filter,
+	})
+	assert.False(t, c.IsSink())
+	var sent []string
+	s := c.Filtered(githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+		sent = append(sent, d.Event)
+		return nil
+	}))
+	assert.NoError(t, s.Send(ctx, testDelivery("push")))
+	assert.NoError(t, s.Send(ctx, testDelivery("release")))
+	assert.Error(t, s.Send(ctx, testDelivery("issues")))
+	assert.SliceEqual(t, sent, []string{"push"})
+	assert.NoError(t, c.Send(ctx, testDelivery("push")))
+}
+
+func TestClientSinkOnly(t *testing.T) {
+	ctx := context.Background()
+	c := testNewClient(t, &Plugin{
+		Sink: newTestPlugin(nil).Sink,
+	})
+	ok, err := c.Match(ctx, testDelivery("release"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestClientInvalidPayload(t *testing.T) {
+	c := testNewClient(t, newTestPlugin(nil))
+	d := testDelivery("push")
+	d.RawPayload = []byte("invalid")
+	err := c.Send(context.Background(), d)
+	assert.ErrorContains(t, err, "decode payload")
+}
+
+func TestClientInitError(t *testing.T) {
+	rpcClient, _ := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{
+		pluginName: &grpcPlugin{impl: new(Plugin)},
+	})
+	raw, err := rpcClient.Dispense(pluginName)
+	assert.NoError(t, err)
+	c := &Client{
+		grpc: raw.(*grpcClient), //nolint:forcetypeassert // Test.
+	}
+	err = c.init(context.Background())
+	assert.Error(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	cmd := exec.Command(os.Args[0]) //nolint:gosec // Test.
+	cmd.Env = append(os.Environ(), testPluginEnv+"=1")
+	c, err := Open(ctx, cmd, nil)
+	assert.NoError(t, err)
+	defer c.Close()
+	err = c.Send(ctx, testDelivery("push"))
+	assert.NoError(t, err)
+	err = c.Send(ctx, testDelivery("issues"))
+	assert.ErrorContains(t, err, "error")
+	c.Close()
+	c.Close()
+}
+
+func TestOpenError(t *testing.T) {
+	_, err := Open(context.Background(), exec.Command("/nonexistent/plugin"), nil)
+	assert.Error(t, err)
+}
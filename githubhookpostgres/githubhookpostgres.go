@@ -0,0 +1,570 @@
+// Package githubhookpostgres provides a [PostgreSQL] [githubhook.Store], suitable for multi-instance deployments that share one delivery log.
+//
+// It uses [database/sql], so it works with any PostgreSQL driver (e.g. github.com/jackc/pgx/v5/stdlib):
+//
+//	s := githubhookpostgres.New(db, "githubhook_deliveries", 100, 10*time.Millisecond)
+//	defer s.Close()
+//	err := s.Migrate(ctx)
+//	h := &githubhook.Handler{Store: s}
+//
+// [PostgreSQL]: https://www.postgresql.org
+package githubhookpostgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+const columns = "delivery_id, event, repository, hook_id, installation_target_type, installation_target_id, user_agent, lineage, signature_headers, header_interpretations, annotations, payload, payload_digest, status, error, received_at, updated_at"
+
+var columnCount = strings.Count(columns, ",") + 1
+
+// selectColumns are the columns of a delivery, selected from the table (d) joined with the payload table (p).
+// The payload of the deliveries stored by the previous versions is in the table.
+const selectColumns = "d.delivery_id, d.event, d.repository, d.hook_id, d.installation_target_type, d.installation_target_id, d.user_agent, d.lineage, d.signature_headers, d.header_interpretations, d.annotations, COALESCE(p.payload, d.payload), d.status, d.error, d.received_at, d.updated_at"
+
+// DefaultInsertTimeout is the default value of [Store.InsertTimeout].
+const DefaultInsertTimeout = 10 * time.Second
+
+/*
+Store is a PostgreSQL [githubhook.Store].
+
+The deliveries are stored in a table, with indexes on the delivery ID (primary key), the event and the repository.
+The payloads are content-addressed (see [githubhook.PayloadDigest]): they are stored once in a payload table ("<table>_payloads"), in a BYTEA column, and the deliveries reference them by digest.
+So the redeliveries and the duplicate events don't store the same payload twice.
+The payload is stored byte for byte, so its original signature remains valid.
+The payloads are not deleted with the deliveries: the unreferenced payloads are deleted by [Store.SweepPayloads].
+
+Concurrent calls to [Store.Save] are batched in a single INSERT statement, so the throughput is not limited by the round trip to the database.
+[Store.Save] returns after its batch is inserted.
+If the INSERT statement of a batch fails, the deliveries are inserted individually, so an invalid delivery doesn't fail the others.
+The batches are not canceled by the context of the callers, so each INSERT statement is limited by InsertTimeout (default: [DefaultInsertTimeout]).
+
+It must be created with [New], and closed with [Store.Close].
+The fields must not be modified after the first call to [Store.Save].
+*/
+type Store struct {
+	InsertTimeout time.Duration
+
+	db         *sql.DB
+	table      string
+	maxBatch   int
+	batchDelay time.Duration
+
+	queue     chan *saveRequest
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+type saveRequest struct {
+	d   *githubhook.StoredDelivery
+	err chan error
+}
+
+// New creates a new [Store].
+//
+// table is the name of the table.
+// maxBatch is the maximum number of deliveries inserted by a statement.
+// batchDelay is the maximum duration to wait for other deliveries before inserting a batch.
+// If maxBatch <= 1, the deliveries are inserted individually.
+func New(db *sql.DB, table string, maxBatch int, batchDelay time.Duration) *Store {
+	s := &Store{
+		db:         db,
+		table:      table,
+		maxBatch:   maxBatch,
+		batchDelay: batchDelay,
+		closed:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if maxBatch > 1 {
+		s.queue = make(chan *saveRequest)
+		go s.runBatches()
+	} else {
+		close(s.done)
+	}
+	return s
+}
+
+// Close stops the batching, after the pending deliveries are inserted.
+//
+// It doesn't close the database.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	<-s.done
+	return nil
+}
+
+// Migrate creates the table, the payload table and their indexes, if they don't exist.
+//
+// It also converts the payload column of the tables created by the previous versions from JSONB to BYTEA.
+// The payloads already stored in these tables have been normalized by JSONB, so they are not converted back to the raw payloads.
+func (s *Store) Migrate(ctx context.Context) error {
+	table := quoteIdentifier(s.table)
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS ` + table + ` (
+	delivery_id TEXT PRIMARY KEY,
+	event TEXT NOT NULL,
+	repository TEXT NOT NULL,
+	hook_id TEXT NOT NULL,
+	installation_target_type TEXT NOT NULL,
+	installation_target_id TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	lineage JSONB,
+	signature_headers JSONB,
+	header_interpretations JSONB,
+	annotations JSONB,
+	payload BYTEA NOT NULL,
+	status TEXT NOT NULL,
+	error TEXT NOT NULL,
+	received_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+)`,
+		`ALTER TABLE ` + table + ` ADD COLUMN IF NOT EXISTS signature_headers JSONB`,
+		`ALTER TABLE ` + table + ` ADD COLUMN IF NOT EXISTS header_interpretations JSONB`,
+		`ALTER TABLE ` + table + ` ADD COLUMN IF NOT EXISTS annotations JSONB`,
+		`ALTER TABLE ` + table + ` ADD COLUMN IF NOT EXISTS payload_digest BYTEA`,
+		`CREATE TABLE IF NOT EXISTS ` + s.payloadTable() + ` (
+	digest BYTEA PRIMARY KEY,
+	payload BYTEA NOT NULL,
+	used_at TIMESTAMPTZ NOT NULL
+)`,
+		`DO $$
+BEGIN
+	IF (SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ` + quoteLiteral(s.table) + ` AND column_name = 'payload') = 'jsonb' THEN
+		ALTER TABLE ` + table + ` ALTER COLUMN payload TYPE BYTEA USING convert_to(payload::TEXT, 'UTF8');
+	END IF;
+END $$`,
+		`CREATE INDEX IF NOT EXISTS ` + quoteIdentifier(s.table+"_received_at_idx") + ` ON ` + table + ` (received_at)`,
+		`CREATE INDEX IF NOT EXISTS ` + quoteIdentifier(s.table+"_event_idx") + ` ON ` + table + ` (event, received_at)`,
+		`CREATE INDEX IF NOT EXISTS ` + quoteIdentifier(s.table+"_repository_idx") + ` ON ` + table + ` (repository, received_at)`,
+		`CREATE INDEX IF NOT EXISTS ` + quoteIdentifier(s.table+"_payload_digest_idx") + ` ON ` + table + ` (payload_digest)`,
+	} {
+		_, err := s.db.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("PostgreSQL: migrate: %w", err)
+		}
+	}
+	return nil
+}
+
+// Save implements [githubhook.Store].
+func (s *Store) Save(ctx context.Context, d *githubhook.StoredDelivery) error {
+	if s.queue == nil {
+		return s.insert(ctx, []*githubhook.StoredDelivery{d})
+	}
+	req := &saveRequest{
+		d:   d,
+		err: make(chan error, 1),
+	}
+	select {
+	case s.queue <- req:
+	case <-s.closed:
+		return errors.New("PostgreSQL: store closed")
+	case <-ctx.Done():
+		return fmt.Errorf("PostgreSQL: %w", ctx.Err())
+	}
+	select {
+	case err := <-req.err:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("PostgreSQL: %w", ctx.Err())
+	}
+}
+
+func (s *Store) runBatches() {
+	defer close(s.done)
+	for {
+		var first *saveRequest
+		select {
+		case first = <-s.queue:
+		case <-s.closed:
+			return
+		}
+		s.runBatch(s.collectBatch(first))
+	}
+}
+
+func (s *Store) collectBatch(first *saveRequest) []*saveRequest {
+	reqs := []*saveRequest{first}
+	timer := time.NewTimer(s.batchDelay)
+	defer timer.Stop()
+	for len(reqs) < s.maxBatch {
+		select {
+		case req := <-s.queue:
+			reqs = append(reqs, req)
+		case <-timer.C:
+			return reqs
+		case <-s.closed:
+			return reqs
+		}
+	}
+	return reqs
+}
+
+func (s *Store) runBatch(reqs []*saveRequest) {
+	// A statement can't insert the same delivery twice, so only the last save of a delivery is kept.
+	indexes := make(map[string]int, len(reqs))
+	var ds []*githubhook.StoredDelivery
+	for _, req := range reqs {
+		i, ok := indexes[req.d.DeliveryID]
+		if ok {
+			ds[i] = req.d
+			continue
+		}
+		indexes[req.d.DeliveryID] = len(ds)
+		ds = append(ds, req.d)
+	}
+	// The batch is shared by several callers, so it's not canceled by the context of one of them.
+	err := s.insertWithTimeout(ds)
+	if err != nil && len(ds) > 1 {
+		// A single invalid delivery fails the whole statement, so the deliveries are inserted individually.
+		errs := make(map[string]error, len(ds))
+		for _, d := range ds {
+			errs[d.DeliveryID] = s.insertWithTimeout([]*githubhook.StoredDelivery{d})
+		}
+		for _, req := range reqs {
+			req.err <- errs[req.d.DeliveryID]
+		}
+		return
+	}
+	for _, req := range reqs {
+		req.err <- err
+	}
+}
+
+func (s *Store) insertWithTimeout(ds []*githubhook.StoredDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.getInsertTimeout())
+	defer cancel()
+	return s.insert(ctx, ds)
+}
+
+// insert inserts the deliveries, and their payloads in the same statement.
+//
+// The used time of the existing payloads is updated, so they are not deleted by [Store.SweepPayloads].
+func (s *Store) insert(ctx context.Context, ds []*githubhook.StoredDelivery) error {
+	var b strings.Builder
+	b.WriteString(`WITH payloads AS (INSERT INTO ` + s.payloadTable() + ` (digest, payload, used_at) VALUES `)
+	args := make([]any, 0, len(ds)*(columnCount+2))
+	// A statement can't update the same payload twice.
+	digests := make(map[[sha256.Size]byte]bool, len(ds))
+	for _, d := range ds {
+		digest := githubhook.PayloadDigest(d.RawPayload)
+		if digests[digest] {
+			continue
+		}
+		if len(digests) > 0 {
+			b.WriteString(", ")
+		}
+		digests[digest] = true
+		b.WriteString("($" + strconv.Itoa(len(args)+1) + ", $" + strconv.Itoa(len(args)+2) + ", now())")
+		args = append(args, digest[:], nonNullBytes(d.RawPayload))
+	}
+	b.WriteString(` ON CONFLICT (digest) DO UPDATE SET used_at = EXCLUDED.used_at) `)
+	b.WriteString(`INSERT INTO ` + quoteIdentifier(s.table) + ` (` + columns + `) VALUES `)
+	for i, d := range ds {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range columnCount {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("$" + strconv.Itoa(len(args)+j+1))
+		}
+		b.WriteString(")")
+		dArgs, err := deliveryArgs(d)
+		if err != nil {
+			return err
+		}
+		args = append(args, dArgs...)
+	}
+	b.WriteString(` ON CONFLICT (delivery_id) DO UPDATE SET `)
+	for i, c := range strings.Split(columns, ", ")[1:] {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(c + " = EXCLUDED." + c)
+	}
+	_, err := s.db.ExecContext(ctx, b.String(), args...)
+	if err != nil {
+		return fmt.Errorf("PostgreSQL: insert: %w", err)
+	}
+	return nil
+}
+
+func deliveryArgs(d *githubhook.StoredDelivery) ([]any, error) {
+	// The JSON values are passed as strings, because some drivers send []byte as bytea.
+	// The payload is passed as []byte, because it's stored as bytea.
+	var lineage sql.NullString
+	if d.Lineage != nil {
+		b, err := json.Marshal(d.Lineage)
+		if err != nil {
+			return nil, fmt.Errorf("PostgreSQL: lineage JSON encode: %w", err)
+		}
+		lineage = sql.NullString{String: string(b), Valid: true}
+	}
+	signatureHeaders, err := stringsArg(d.SignatureHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: signature headers JSON encode: %w", err)
+	}
+	headerInterpretations, err := stringsArg(d.HeaderInterpretations)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: header interpretations JSON encode: %w", err)
+	}
+	var annotations sql.NullString
+	if m := d.Annotations.All(); len(m) > 0 {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("PostgreSQL: annotations JSON encode: %w", err)
+		}
+		annotations = sql.NullString{String: string(b), Valid: true}
+	}
+	digest := githubhook.PayloadDigest(d.RawPayload)
+	return []any{
+		d.DeliveryID,
+		d.Event,
+		d.Repository,
+		d.HookID,
+		d.InstallationTargetType,
+		d.InstallationTargetID,
+		d.UserAgent,
+		lineage,
+		signatureHeaders,
+		headerInterpretations,
+		annotations,
+		[]byte{}, // The payload is in the payload table.
+		digest[:],
+		string(d.Status),
+		d.Error,
+		d.ReceivedAt,
+		d.UpdatedAt,
+	}, nil
+}
+
+// Get implements [githubhook.Store].
+func (s *Store) Get(ctx context.Context, deliveryID string) (*githubhook.StoredDelivery, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+selectColumns+` FROM `+s.joinedTables()+` WHERE delivery_id = $1`, deliveryID)
+	d, err := scanDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// List implements [githubhook.Store].
+func (s *Store) List(ctx context.Context, filter githubhook.StoreFilter) ([]*githubhook.StoredDelivery, error) {
+	var conds []string
+	var args []any
+	addCond := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, cond+" $"+strconv.Itoa(len(args)))
+	}
+	if filter.Event != "" {
+		addCond("event =", filter.Event)
+	}
+	if filter.Repository != "" {
+		addCond("repository =", filter.Repository)
+	}
+	if filter.Status != "" {
+		addCond("status =", string(filter.Status))
+	}
+	if !filter.Since.IsZero() {
+		addCond("received_at >=", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		addCond("received_at <", filter.Until)
+	}
+	query := `SELECT ` + selectColumns + ` FROM ` + s.joinedTables()
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	if filter.Newest {
+		query += ` ORDER BY received_at DESC, delivery_id DESC`
+	} else {
+		query += ` ORDER BY received_at, delivery_id`
+	}
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: list: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var ds []*githubhook.StoredDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: list: %w", err)
+	}
+	return ds, nil
+}
+
+// Delete implements [githubhook.Store].
+func (s *Store) Delete(ctx context.Context, deliveryID string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM `+quoteIdentifier(s.table)+` WHERE delivery_id = $1`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("PostgreSQL: delete: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("PostgreSQL: delete: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+	}
+	return nil
+}
+
+// SweepPayloads deletes the payloads that are not referenced by a delivery, e.g. after [Store.Delete], and returns the number of deleted payloads.
+//
+// Only the payloads not used for the grace period are deleted, so a payload is not deleted while a delivery that references it is being inserted.
+func (s *Store) SweepPayloads(ctx context.Context, grace time.Duration) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM `+s.payloadTable()+` p WHERE used_at < $1 AND NOT EXISTS (SELECT 1 FROM `+quoteIdentifier(s.table)+` d WHERE d.payload_digest = p.digest)`, time.Now().Add(-grace))
+	if err != nil {
+		return 0, fmt.Errorf("PostgreSQL: sweep payloads: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("PostgreSQL: sweep payloads: %w", err)
+	}
+	return n, nil
+}
+
+func (s *Store) payloadTable() string {
+	return quoteIdentifier(s.table + "_payloads")
+}
+
+// joinedTables returns the table (d) joined with the payload table (p), for [selectColumns].
+func (s *Store) joinedTables() string {
+	return quoteIdentifier(s.table) + ` d LEFT JOIN ` + s.payloadTable() + ` p ON p.digest = d.payload_digest`
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(sc scanner) (*githubhook.StoredDelivery, error) {
+	d := new(githubhook.StoredDelivery)
+	var lineage, signatureHeaders, headerInterpretations, annotations []byte
+	var status string
+	err := sc.Scan(
+		&d.DeliveryID,
+		&d.Event,
+		&d.Repository,
+		&d.HookID,
+		&d.InstallationTargetType,
+		&d.InstallationTargetID,
+		&d.UserAgent,
+		&lineage,
+		&signatureHeaders,
+		&headerInterpretations,
+		&annotations,
+		&d.RawPayload,
+		&status,
+		&d.Error,
+		&d.ReceivedAt,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: scan: %w", err)
+	}
+	d.Status = githubhook.DeliveryStatus(status)
+	if lineage != nil {
+		d.Lineage = new(githubhook.Lineage)
+		err = json.Unmarshal(lineage, d.Lineage)
+		if err != nil {
+			return nil, fmt.Errorf("PostgreSQL: lineage JSON decode: %w", err)
+		}
+	}
+	d.SignatureHeaders, err = scanStrings(signatureHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: signature headers JSON decode: %w", err)
+	}
+	d.HeaderInterpretations, err = scanStrings(headerInterpretations)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: header interpretations JSON decode: %w", err)
+	}
+	if annotations != nil {
+		d.Annotations = new(githubhook.Annotations)
+		err = json.Unmarshal(annotations, d.Annotations)
+		if err != nil {
+			return nil, fmt.Errorf("PostgreSQL: annotations JSON decode: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// nonNullBytes returns b, or an empty slice if it's nil, because NULL is not allowed.
+func nonNullBytes(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
+
+// stringsArg returns the JSON array of a slice, or NULL if it's empty.
+func stringsArg(ss []string) (sql.NullString, error) {
+	if len(ss) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(ss)
+	if err != nil {
+		return sql.NullString{}, err //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// scanStrings decodes a JSON array, or returns nil if it's NULL.
+func scanStrings(b []byte) ([]string, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var ss []string
+	err := json.Unmarshal(b, &ss)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return ss, nil
+}
+
+func (s *Store) getInsertTimeout() time.Duration {
+	if s.InsertTimeout > 0 {
+		return s.InsertTimeout
+	}
+	return DefaultInsertTimeout
+}
+
+func quoteLiteral(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
+
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
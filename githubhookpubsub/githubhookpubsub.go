@@ -0,0 +1,175 @@
+// Package githubhookpubsub provides a [Google Cloud Pub/Sub] sink for verified GitHub webhook deliveries.
+//
+// It publishes the deliveries to a topic, with the repository full name as ordering key, so the deliveries of a repository are ordered:
+//
+//	topic := client.Topic("github")
+//	topic.EnableMessageOrdering = true
+//	http.Handle("/webhook", h.Middleware(&githubhookpubsub.Publisher{Topic: topic, Wait: true}))
+//
+// [Google Cloud Pub/Sub]: https://cloud.google.com/pubsub
+package githubhookpubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/pierrre/githubhook"
+)
+
+// Message attribute names.
+const (
+	// AttributeEvent is the name of the event name attribute.
+	AttributeEvent = "X-GitHub-Event"
+	// AttributeDeliveryID is the name of the delivery ID attribute.
+	AttributeDeliveryID = "X-GitHub-Delivery"
+	// AttributeRepository is the name of the repository full name attribute.
+	AttributeRepository = "Repository"
+)
+
+// Topic publishes messages.
+//
+// It's implemented by [pubsub.Topic].
+type Topic interface {
+	Publish(ctx context.Context, msg *pubsub.Message) *pubsub.PublishResult
+	ResumePublish(orderingKey string)
+}
+
+/*
+Publisher publishes verified deliveries to a Pub/Sub topic.
+
+The message data is the raw JSON payload.
+The message attributes are the event name, the delivery ID, the repository full name (if the payload has a repository), and the lineage (see [githubhook.Lineage]).
+The ordering key is the repository full name, or no key if the payload doesn't have a repository.
+It requires [pubsub.Topic.EnableMessageOrdering], and a subscription with message ordering enabled.
+If the publication of an ordered message fails, the publication is resumed for its ordering key, so the delivery can be redelivered.
+
+If Wait is true, [Publisher.Publish] returns after the publication is confirmed by the server.
+So the HTTP response reflects the success or the failure, and GitHub can redeliver the delivery if the publication fails.
+Otherwise, [Publisher.Publish] returns once the message is queued, and the publication errors are reported to PublishError.
+
+It can be used as:
+  - a [http.Handler] wrapped by [githubhook.Handler.Middleware]: the response status is 200 if the delivery is published, 502 otherwise.
+  - a [githubhook.DeliveryHandler] with [Publisher.Handle].
+
+Fields:
+  - Topic is the topic (required).
+  - Wait waits for the publication confirmation.
+  - Error is called if a delivery can't be published by [Publisher.ServeHTTP].
+  - PublishError is called if a publication fails, and Wait is false.
+*/
+type Publisher struct {
+	Topic        Topic
+	Wait         bool
+	Error        func(ctx context.Context, err error, req *http.Request)
+	PublishError func(md *githubhook.DeliveryMetadata, err error)
+}
+
+func (p *Publisher) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	githubhook.SinkHTTPHandler(p, p.Error).ServeHTTP(w, req)
+}
+
+// Handle publishes a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+// If the payload is a [json.RawMessage] or a []byte, it's published as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are not published.
+// [Publisher.ServeHTTP] should be preferred, because it publishes the raw payload.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func (p *Publisher) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	return githubhook.SinkDeliveryHandler(p)(ctx, md, payload)
+}
+
+// Send publishes a verified delivery.
+//
+// It implements [githubhook.Sink].
+func (p *Publisher) Send(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+	return p.Publish(ctx, &d.DeliveryMetadata, d.RawPayload)
+}
+
+// Publish publishes a delivery with its raw JSON payload.
+func (p *Publisher) Publish(ctx context.Context, md *githubhook.DeliveryMetadata, rawPayload []byte) error {
+	msg := newMessage(md, rawPayload)
+	return githubhook.TracePublish(ctx, topicName(p.Topic), md.DeliveryID, func() error {
+		res := p.Topic.Publish(ctx, msg)
+		if !p.Wait {
+			go p.waitAsync(md, msg, res)
+			return nil
+		}
+		return p.wait(ctx, msg, res)
+	})
+}
+
+// Start validates the topic and the credentials, before the deliveries are published.
+//
+// It implements [githubhook.SinkStarter].
+// If the topic implements Exists (e.g. [pubsub.Topic]), the existence of the topic is checked, so an unknown topic or a denied access is detected.
+// It also pre-establishes the connection of the client.
+func (p *Publisher) Start(ctx context.Context) error {
+	t, ok := p.Topic.(interface {
+		Exists(ctx context.Context) (bool, error)
+	})
+	if !ok {
+		return nil
+	}
+	exists, err := t.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("Pub/Sub: topic exists: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("Pub/Sub: topic %q not found", topicName(p.Topic))
+	}
+	return nil
+}
+
+// topicName returns the name of the topic for [githubhook.SinkTrace], if it implements [fmt.Stringer] (like [pubsub.Topic]).
+func topicName(t Topic) string {
+	if s, ok := t.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
+
+func (p *Publisher) wait(ctx context.Context, msg *pubsub.Message, res *pubsub.PublishResult) error {
+	_, err := res.Get(ctx)
+	if err != nil {
+		if msg.OrderingKey != "" {
+			p.Topic.ResumePublish(msg.OrderingKey)
+		}
+		return fmt.Errorf("Pub/Sub: publish: %w", err)
+	}
+	return nil
+}
+
+func (p *Publisher) waitAsync(md *githubhook.DeliveryMetadata, msg *pubsub.Message, res *pubsub.PublishResult) {
+	err := p.wait(context.Background(), msg, res)
+	if err != nil && p.PublishError != nil {
+		p.PublishError(md, err)
+	}
+}
+
+func newMessage(md *githubhook.DeliveryMetadata, rawPayload []byte) *pubsub.Message {
+	attributes := map[string]string{
+		AttributeEvent:      md.Event,
+		AttributeDeliveryID: md.DeliveryID,
+	}
+	repository := githubhook.RepositoryFullName(json.RawMessage(rawPayload))
+	if repository != "" {
+		attributes[AttributeRepository] = repository
+	}
+	if md.Lineage != nil {
+		header := make(http.Header)
+		md.Lineage.SetHeader(header)
+		for k := range header {
+			attributes[k] = header.Get(k)
+		}
+	}
+	return &pubsub.Message{
+		Data:        rawPayload,
+		Attributes:  attributes,
+		OrderingKey: repository,
+	}
+}
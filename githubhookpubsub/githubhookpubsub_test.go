@@ -0,0 +1,167 @@
+package githubhookpubsub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+var testRawPayload = []byte(`{"ref":"refs/heads/main","repository":{"full_name":"pierrre/githubhook"}}`)
+
+func testNewTopic(t *testing.T) (*pstest.Server, *pubsub.Topic) {
+	t.Helper()
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	client, err := pubsub.NewClient(ctx, "project",
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	topic, err := client.CreateTopic(ctx, "github")
+	assert.NoError(t, err)
+	topic.EnableMessageOrdering = true
+	t.Cleanup(topic.Stop)
+	return srv, topic
+}
+
+func testNewRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := new(githubhook.Signer).NewRequest(context.Background(), "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	return req
+}
+
+func TestPublisherWait(t *testing.T) {
+	srv, topic := testNewTopic(t)
+	p := &Publisher{
+		Topic: topic,
+		Wait:  true,
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	msgs := srv.Messages()
+	assert.SliceLen(t, msgs, 1)
+	msg := msgs[0]
+	assert.Equal(t, string(msg.Data), string(testRawPayload))
+	assert.Equal(t, msg.OrderingKey, "pierrre/githubhook")
+	assert.Equal(t, msg.Attributes[AttributeEvent], "push")
+	assert.Equal(t, msg.Attributes[AttributeDeliveryID], "test")
+	assert.Equal(t, msg.Attributes[AttributeRepository], "pierrre/githubhook")
+}
+
+func TestPublisherWaitError(t *testing.T) {
+	srv, topic := testNewTopic(t)
+	srv.SetAutoPublishResponse(false)
+	srv.AddPublishResponse(nil, status.Error(codes.InvalidArgument, "error"))
+	var publishErr error
+	p := &Publisher{
+		Topic: topic,
+		Wait:  true,
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			publishErr = err
+		},
+	}
+	w := httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusBadGateway)
+	assert.Error(t, publishErr)
+	// The publication is resumed, so the redelivery can be published.
+	srv.SetAutoPublishResponse(true)
+	w = httptest.NewRecorder()
+	new(githubhook.Handler).Middleware(p).ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestPublisherAsyncError(t *testing.T) {
+	srv, topic := testNewTopic(t)
+	srv.SetAutoPublishResponse(false)
+	srv.AddPublishResponse(nil, status.Error(codes.InvalidArgument, "error"))
+	errs := make(chan error, 1)
+	p := &Publisher{
+		Topic: topic,
+		PublishError: func(md *githubhook.DeliveryMetadata, err error) {
+			errs <- err
+		},
+	}
+	err := p.Publish(context.Background(), &githubhook.DeliveryMetadata{Event: "push"}, testRawPayload)
+	assert.NoError(t, err)
+	assert.Error(t, <-errs)
+}
+
+func TestPublisherStart(t *testing.T) {
+	ctx := context.Background()
+	_, topic := testNewTopic(t)
+	p := &Publisher{
+		Topic: topic,
+	}
+	err := githubhook.StartSink(ctx, p)
+	assert.NoError(t, err)
+	err = topic.Delete(ctx)
+	assert.NoError(t, err)
+	err = p.Start(ctx)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestPublisherWithoutMiddleware(t *testing.T) {
+	_, topic := testNewTopic(t)
+	p := &Publisher{
+		Topic: topic,
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestPublisherHandle(t *testing.T) {
+	srv, topic := testNewTopic(t)
+	h := &githubhook.Handler{
+		Delivery: (&Publisher{Topic: topic, Wait: true}).Handle,
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, srv.Messages(), 1)
+}
+
+func TestNewMessageNoRepository(t *testing.T) {
+	md := &githubhook.DeliveryMetadata{
+		Event:      "ping",
+		DeliveryID: "test",
+	}
+	md.Lineage = githubhook.NextLineage(md, "", "replay")
+	msg := newMessage(md, []byte(`{}`))
+	assert.Zero(t, msg.OrderingKey)
+	_, ok := msg.Attributes[AttributeRepository]
+	assert.False(t, ok)
+	assert.Equal(t, msg.Attributes[githubhook.LineageOriginalDeliveryHeader], "test")
+}
+
+func TestPublisherSink(t *testing.T) {
+	srv, topic := testNewTopic(t)
+	h := &githubhook.Handler{
+		Sink: &Publisher{Topic: topic, Wait: true},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, testNewRequest(t))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.SliceLen(t, srv.Messages(), 1)
+}
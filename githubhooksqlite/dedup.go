@@ -0,0 +1,102 @@
+package githubhooksqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+/*
+DedupStore is a SQLite [githubhook.DedupStore], that also implements [githubhook.DedupLister].
+
+The delivery IDs are stored in the "dedup" table, with their expiration.
+The expired delivery IDs are seen again, and deleted by [DedupStore.Sweep].
+
+It must be created with [NewDedupStore] (or [Open]).
+*/
+type DedupStore struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewDedupStore creates a new [DedupStore] on a database migrated by [Migrate].
+//
+// ttl is the duration during which a delivery ID is seen.
+// It doesn't close the database.
+func NewDedupStore(db *sql.DB, ttl time.Duration) *DedupStore {
+	return &DedupStore{
+		db:  db,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// MarkSeen implements [githubhook.DedupStore].
+func (s *DedupStore) MarkSeen(ctx context.Context, deliveryID string) (bool, error) {
+	now := s.now()
+	// The statement is atomic: an expired delivery ID is replaced, and an unexpired one is not modified.
+	res, err := s.db.ExecContext(ctx, `INSERT INTO dedup (delivery_id, expiration) VALUES (?, ?) ON CONFLICT (delivery_id) DO UPDATE SET expiration = excluded.expiration WHERE dedup.expiration <= ?`, deliveryID, now.Add(s.ttl).UnixNano(), now.UnixNano())
+	if err != nil {
+		return false, fmt.Errorf("SQLite: mark seen: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("SQLite: mark seen: %w", err)
+	}
+	return n == 0, nil
+}
+
+// Forget implements [githubhook.DedupStore].
+func (s *DedupStore) Forget(ctx context.Context, deliveryID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM dedup WHERE delivery_id = ?`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("SQLite: forget: %w", err)
+	}
+	return nil
+}
+
+// ListSeen implements [githubhook.DedupLister].
+//
+// The delivery IDs are sorted by expiration.
+func (s *DedupStore) ListSeen(ctx context.Context) ([]*githubhook.DedupEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT delivery_id, expiration FROM dedup WHERE expiration > ? ORDER BY expiration, delivery_id`, s.now().UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: list seen: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var es []*githubhook.DedupEntry
+	for rows.Next() {
+		e := new(githubhook.DedupEntry)
+		var expiration int64
+		err = rows.Scan(&e.DeliveryID, &expiration)
+		if err != nil {
+			return nil, fmt.Errorf("SQLite: list seen: scan: %w", err)
+		}
+		e.Expiration = time.Unix(0, expiration).UTC()
+		es = append(es, e)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: list seen: %w", err)
+	}
+	return es, nil
+}
+
+// Sweep deletes the expired delivery IDs, and returns their number.
+func (s *DedupStore) Sweep(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM dedup WHERE expiration <= ?`, s.now().UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("SQLite: sweep seen: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("SQLite: sweep seen: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,47 @@
+package githubhooksqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestDedupStore(t *testing.T) {
+	ctx := context.Background()
+	s := testOpen(t).Dedup
+	now := testTime
+	s.now = func() time.Time {
+		return now
+	}
+	seen, err := s.MarkSeen(ctx, "1")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	seen, err = s.MarkSeen(ctx, "1")
+	assert.NoError(t, err)
+	assert.True(t, seen)
+	es, err := s.ListSeen(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, es, 1)
+	assert.Equal(t, es[0].DeliveryID, "1")
+	assert.Equal(t, es[0].Expiration, testTime.Add(DefaultDedupTTL))
+	err = s.Forget(ctx, "1")
+	assert.NoError(t, err)
+	seen, err = s.MarkSeen(ctx, "1")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	now = now.Add(DefaultDedupTTL)
+	es, err = s.ListSeen(ctx)
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, es)
+	seen, err = s.MarkSeen(ctx, "1")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	_, err = s.MarkSeen(ctx, "2")
+	assert.NoError(t, err)
+	now = now.Add(DefaultDedupTTL)
+	n, err := s.Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(2))
+}
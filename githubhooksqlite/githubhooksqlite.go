@@ -0,0 +1,233 @@
+// Package githubhooksqlite provides an all-in-one SQLite mode for small deployments: the delivery archive ([githubhook.Store]), the deduplication ([githubhook.DedupStore]) and a durable queue share a single database file, with the admin UI on top of them.
+//
+// It uses the pure Go driver [modernc.org/sqlite], so it doesn't need cgo or an external service.
+//
+//	db, err := githubhooksqlite.Open(ctx, "githubhook.db")
+//	defer db.Close()
+//	go db.RunSweeps(ctx, time.Hour)
+//	pool := githubhook.NewAsyncPool(4, 1000)
+//	h, err := githubhook.NewHandler(githubhook.WithSecret(secret), githubhook.WithDelivery(f), db.Option(pool))
+//	_, err = db.Recover(ctx, h)
+//	mux.Handle("/webhook", h)
+//	adminMux.Handle("/admin/", http.StripPrefix("/admin", githubhooksqlite.NewAdminHandler(h, authorizer)))
+//
+// [modernc.org/sqlite]: https://pkg.go.dev/modernc.org/sqlite
+package githubhooksqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/dashboard"
+	_ "modernc.org/sqlite" // Register the "sqlite" driver.
+)
+
+// DB default values.
+const (
+	// DefaultRetention is the default value of [DB.Retention].
+	DefaultRetention = 30 * 24 * time.Hour
+	// DefaultDedupTTL is the duration during which a delivery ID is seen by the [DedupStore] of [Open].
+	DefaultDedupTTL = 24 * time.Hour
+)
+
+/*
+DB is the all-in-one SQLite database: the delivery archive (Store) and the deduplication (Dedup) are stored in the same file.
+
+The durable queue is the combination of the archive and a [githubhook.AsyncPool] (see [DB.Option]): the deliveries are stored with the pending status before they are queued, and the pending deliveries of a previous process (e.g. after a crash or an unclean shutdown) are queued again by [DB.Recover].
+So the deliveries are processed at least once.
+
+Fields:
+  - Store is the delivery archive.
+  - Dedup is the deduplication store.
+  - Retention is the duration after which the deliveries are deleted by [DB.RunSweeps] (default: [DefaultRetention]). If it's negative, they are kept forever.
+  - Error is called if a sweep fails in [DB.RunSweeps] (optional).
+
+It must be created with [Open], and closed with [DB.Close].
+*/
+type DB struct {
+	Store     *Store
+	Dedup     *DedupStore
+	Retention time.Duration
+	Error     func(ctx context.Context, err error)
+
+	db       *sql.DB
+	openedAt time.Time
+}
+
+// Open opens (or creates) the database file, and migrates it.
+//
+// The database uses the WAL journal mode, and a single connection, so the concurrent writes don't fail with "database is locked".
+func Open(ctx context.Context, name string) (*DB, error) {
+	sqlDB, err := sql.Open("sqlite", "file:"+name+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: open: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	err = Migrate(ctx, sqlDB)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return &DB{
+		Store:    NewStore(sqlDB),
+		Dedup:    NewDedupStore(sqlDB, DefaultDedupTTL),
+		db:       sqlDB,
+		openedAt: time.Now(),
+	}, nil
+}
+
+// Close closes the database.
+func (db *DB) Close() error {
+	err := db.db.Close()
+	if err != nil {
+		return fmt.Errorf("SQLite: close: %w", err)
+	}
+	return nil
+}
+
+// Migrate creates the tables and their indexes, if they don't exist.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS payloads (
+	digest BLOB PRIMARY KEY,
+	payload BLOB NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS deliveries (
+	delivery_id TEXT PRIMARY KEY,
+	event TEXT NOT NULL,
+	repository TEXT NOT NULL,
+	hook_id TEXT NOT NULL,
+	installation_target_type TEXT NOT NULL,
+	installation_target_id TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	lineage TEXT,
+	signature_headers TEXT,
+	header_interpretations TEXT,
+	annotations TEXT,
+	payload_digest BLOB NOT NULL REFERENCES payloads (digest),
+	status TEXT NOT NULL,
+	error TEXT NOT NULL,
+	received_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS deliveries_event ON deliveries (event, received_at)`,
+		`CREATE INDEX IF NOT EXISTS deliveries_repository ON deliveries (repository, received_at)`,
+		`CREATE INDEX IF NOT EXISTS deliveries_status ON deliveries (status, received_at)`,
+		`CREATE INDEX IF NOT EXISTS deliveries_received_at ON deliveries (received_at)`,
+		`CREATE INDEX IF NOT EXISTS deliveries_payload_digest ON deliveries (payload_digest)`,
+		`CREATE TABLE IF NOT EXISTS dedup (
+	delivery_id TEXT PRIMARY KEY,
+	expiration INTEGER NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS dedup_expiration ON dedup (expiration)`,
+	} {
+		_, err := db.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("SQLite: migrate: %w", err)
+		}
+	}
+	return nil
+}
+
+// Option returns a [githubhook.Option] that wires the database onto a handler: [githubhook.Handler.Store] and [githubhook.Handler.Dedup], and [githubhook.Handler.Async] if pool is not nil (the durable queue).
+//
+// Without pool, the deliveries are processed synchronously, and the pending deliveries are the ones interrupted by a crash.
+func (db *DB) Option(pool *githubhook.AsyncPool) githubhook.Option {
+	return func(h *githubhook.Handler) {
+		h.Store = db.Store
+		h.Dedup = db.Dedup
+		if pool != nil {
+			h.Async = pool
+		}
+	}
+}
+
+// RecoverOperator is the operator recorded in the [githubhook.Lineage] of the deliveries recovered by [DB.Recover].
+const RecoverOperator = "githubhooksqlite"
+
+/*
+Recover redelivers the pending deliveries received before the database was opened, i.e. by a previous process that stopped before processing them (see [githubhook.Handler.Redeliver]).
+They are queued again in [githubhook.Handler.Async], or delivered synchronously.
+
+It must be called once, after the handler is created.
+The deliveries received by the current process are not recovered, so they are not processed twice.
+The deliveries that can't be redelivered (e.g. the queue is full) remain pending, so they are recovered at the next start, or can be redelivered with the admin UI.
+
+It returns the number of redelivered deliveries, and the errors.
+*/
+func (db *DB) Recover(ctx context.Context, h *githubhook.Handler) (int, error) {
+	ds, err := db.Store.List(ctx, githubhook.StoreFilter{
+		Status: githubhook.DeliveryStatusPending,
+		Until:  db.openedAt,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("recover: %w", err)
+	}
+	n := 0
+	var errs []error
+	for _, d := range ds {
+		_, err = h.Redeliver(ctx, d.DeliveryID, RecoverOperator)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recover %s: %w", d.DeliveryID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+// Sweep deletes the deliveries received before [DB.Retention], and the expired delivery IDs of the deduplication.
+func (db *DB) Sweep(ctx context.Context) error {
+	retention := db.Retention
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+	_, err := db.Store.Sweep(ctx, retention)
+	if err != nil {
+		return err
+	}
+	_, err = db.Dedup.Sweep(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunSweeps calls [DB.Sweep] periodically, until the context is canceled.
+//
+// The errors are reported to [DB.Error].
+func (db *DB) RunSweeps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := db.Sweep(ctx)
+		if err != nil && db.Error != nil {
+			db.Error(ctx, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// NewAdminHandler returns the admin UI of a handler: the dashboard on "/" (see [dashboard.Dashboard]), and the admin API on "/api/" (see [githubhook.Admin]).
+//
+// The authorizer authorizes the requests (optional, see [githubhook.AdminAuthorizer]).
+// Without authorizer, it must not be exposed publicly.
+func NewAdminHandler(h *githubhook.Handler, authorizer githubhook.AdminAuthorizer) http.Handler {
+	d := dashboard.New(h)
+	d.Authorizer = authorizer
+	a := githubhook.NewAdmin(h)
+	a.Authorizer = authorizer
+	mux := http.NewServeMux()
+	mux.Handle("/", d)
+	mux.Handle("/api/", http.StripPrefix("/api", a))
+	return mux
+}
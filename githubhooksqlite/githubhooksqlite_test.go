@@ -0,0 +1,119 @@
+package githubhooksqlite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	db := testOpen(t)
+	pool := githubhook.NewAsyncPool(1, 10)
+	delivered := make(chan string, 10)
+	h, err := githubhook.NewHandler(
+		githubhook.WithSecret("secret"),
+		githubhook.WithDelivery(func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			delivered <- md.DeliveryID
+			return nil
+		}),
+		db.Option(pool),
+	)
+	assert.NoError(t, err)
+	for _, statusCode := range []int{http.StatusAccepted, http.StatusOK} {
+		req, err := (&githubhook.Signer{Secret: "secret"}).NewRequest(ctx, "/", "push", "1", []byte(`{"repository":{"full_name":"pierrre/githubhook"}}`))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, statusCode)
+	}
+	err = pool.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, <-delivered, "1")
+	assert.Equal(t, len(delivered), 0)
+	d, err := db.Store.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, githubhook.DeliveryStatusSucceeded)
+	assert.Equal(t, d.Repository, "pierrre/githubhook")
+}
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(ctx, name)
+	assert.NoError(t, err)
+	for _, d := range []*githubhook.StoredDelivery{
+		testNewDelivery("push", "1", testTime),
+		testNewDelivery("push", "2", testTime),
+	} {
+		err = db.Store.Save(ctx, d)
+		assert.NoError(t, err)
+	}
+	d := testNewDelivery("push", "3", testTime)
+	d.Status = githubhook.DeliveryStatusSucceeded
+	err = db.Store.Save(ctx, d)
+	assert.NoError(t, err)
+	err = db.Close()
+	assert.NoError(t, err)
+	db, err = Open(ctx, name)
+	assert.NoError(t, err)
+	defer db.Close() //nolint:errcheck // Test.
+	err = db.Store.Save(ctx, testNewDelivery("push", "4", time.Now()))
+	assert.NoError(t, err)
+	var delivered []string
+	h := &githubhook.Handler{
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			delivered = append(delivered, md.DeliveryID)
+			return nil
+		},
+	}
+	db.Option(nil)(h)
+	n, err := db.Recover(ctx, h)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 2)
+	assert.SliceEqual(t, delivered, []string{"1", "2"})
+	got, err := db.Store.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, got.Status, githubhook.DeliveryStatusSucceeded)
+	assert.Equal(t, got.Lineage.Operator, RecoverOperator)
+	got, err = db.Store.Get(ctx, "4")
+	assert.NoError(t, err)
+	assert.Equal(t, got.Status, githubhook.DeliveryStatusPending)
+}
+
+func TestSweepDB(t *testing.T) {
+	ctx := context.Background()
+	db := testOpen(t)
+	err := db.Store.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	_, err = db.Dedup.MarkSeen(ctx, "1")
+	assert.NoError(t, err)
+	err = db.Sweep(ctx)
+	assert.NoError(t, err)
+	_, err = db.Store.Get(ctx, "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+	es, err := db.Dedup.ListSeen(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, es, 1)
+}
+
+func TestNewAdminHandler(t *testing.T) {
+	ctx := context.Background()
+	db := testOpen(t)
+	err := db.Store.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	h := new(githubhook.Handler)
+	db.Option(nil)(h)
+	ah := NewAdminHandler(h, nil)
+	for _, target := range []string{"/", "/deliveries/1", "/api/deliveries", "/api/deliveries/1", "/api/dedup"} {
+		w := httptest.NewRecorder()
+		ah.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+}
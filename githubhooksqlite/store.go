@@ -0,0 +1,360 @@
+package githubhooksqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pierrre/githubhook"
+)
+
+// selectColumns are the columns of a delivery, selected from the deliveries table (d) joined with the payloads table (p).
+const selectColumns = "d.delivery_id, d.event, d.repository, d.hook_id, d.installation_target_type, d.installation_target_id, d.user_agent, d.lineage, d.signature_headers, d.header_interpretations, d.annotations, p.payload, d.status, d.error, d.received_at, d.updated_at"
+
+const joinedTables = "deliveries d JOIN payloads p ON p.digest = d.payload_digest"
+
+/*
+Store is a SQLite [githubhook.Store].
+
+The deliveries are stored in the "deliveries" table, with indexes on the event, the repository, the status and the received time.
+The payloads are content-addressed (see [githubhook.PayloadDigest]): they are stored once in the "payloads" table, and deleted with the last delivery that references them.
+The payload is stored byte for byte, so its original signature remains valid.
+The times are stored as Unix nanoseconds, so they are exact and sorted.
+
+It must be created with [NewStore] (or [Open]).
+*/
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewStore creates a new [Store] on a database migrated by [Migrate].
+//
+// It doesn't close the database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// Save implements [githubhook.Store].
+func (s *Store) Save(ctx context.Context, d *githubhook.StoredDelivery) error {
+	args, err := deliveryArgs(d)
+	if err != nil {
+		return err
+	}
+	digest := githubhook.PayloadDigest(d.RawPayload)
+	err = s.transaction(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO payloads (digest, payload) VALUES (?, ?) ON CONFLICT (digest) DO NOTHING`, digest[:], nonNullBytes(d.RawPayload))
+		if err != nil {
+			return fmt.Errorf("insert payload: %w", err)
+		}
+		// The previous payload of an existing delivery may be different.
+		previous, err := getPayloadDigest(ctx, tx, d.DeliveryID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO deliveries (delivery_id, event, repository, hook_id, installation_target_type, installation_target_id, user_agent, lineage, signature_headers, header_interpretations, annotations, payload_digest, status, error, received_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+		if err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+		return deleteUnreferencedPayload(ctx, tx, previous)
+	})
+	if err != nil {
+		return fmt.Errorf("SQLite: save: %w", err)
+	}
+	return nil
+}
+
+func deliveryArgs(d *githubhook.StoredDelivery) ([]any, error) {
+	lineage, err := jsonArg(d.Lineage, d.Lineage == nil)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: lineage JSON encode: %w", err)
+	}
+	signatureHeaders, err := jsonArg(d.SignatureHeaders, len(d.SignatureHeaders) == 0)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: signature headers JSON encode: %w", err)
+	}
+	headerInterpretations, err := jsonArg(d.HeaderInterpretations, len(d.HeaderInterpretations) == 0)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: header interpretations JSON encode: %w", err)
+	}
+	annotations := d.Annotations.All()
+	annotationsArg, err := jsonArg(annotations, len(annotations) == 0)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: annotations JSON encode: %w", err)
+	}
+	digest := githubhook.PayloadDigest(d.RawPayload)
+	return []any{
+		d.DeliveryID,
+		d.Event,
+		d.Repository,
+		d.HookID,
+		d.InstallationTargetType,
+		d.InstallationTargetID,
+		d.UserAgent,
+		lineage,
+		signatureHeaders,
+		headerInterpretations,
+		annotationsArg,
+		digest[:],
+		string(d.Status),
+		d.Error,
+		d.ReceivedAt.UnixNano(),
+		d.UpdatedAt.UnixNano(),
+	}, nil
+}
+
+// Get implements [githubhook.Store].
+func (s *Store) Get(ctx context.Context, deliveryID string) (*githubhook.StoredDelivery, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+selectColumns+` FROM `+joinedTables+` WHERE d.delivery_id = ?`, deliveryID)
+	d, err := scanDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// List implements [githubhook.Store].
+func (s *Store) List(ctx context.Context, filter githubhook.StoreFilter) ([]*githubhook.StoredDelivery, error) {
+	var conds []string
+	var args []any
+	addCond := func(cond string, arg any) {
+		conds = append(conds, cond+" ?")
+		args = append(args, arg)
+	}
+	if filter.Event != "" {
+		addCond("d.event =", filter.Event)
+	}
+	if filter.Repository != "" {
+		addCond("d.repository =", filter.Repository)
+	}
+	if filter.Status != "" {
+		addCond("d.status =", string(filter.Status))
+	}
+	if !filter.Since.IsZero() {
+		addCond("d.received_at >=", filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		addCond("d.received_at <", filter.Until.UnixNano())
+	}
+	query := `SELECT ` + selectColumns + ` FROM ` + joinedTables
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	if filter.Newest {
+		query += ` ORDER BY d.received_at DESC, d.delivery_id DESC`
+	} else {
+		query += ` ORDER BY d.received_at, d.delivery_id`
+	}
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: list: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var ds []*githubhook.StoredDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: list: %w", err)
+	}
+	return ds, nil
+}
+
+// Delete implements [githubhook.Store].
+func (s *Store) Delete(ctx context.Context, deliveryID string) error {
+	err := s.transaction(ctx, func(tx *sql.Tx) error {
+		digest, err := getPayloadDigest(ctx, tx, deliveryID)
+		if err != nil {
+			return err
+		}
+		if digest == nil {
+			return fmt.Errorf("%w: %s", githubhook.ErrStoredDeliveryNotFound, deliveryID)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM deliveries WHERE delivery_id = ?`, deliveryID)
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		return deleteUnreferencedPayload(ctx, tx, digest)
+	})
+	if err != nil {
+		return fmt.Errorf("SQLite: delete: %w", err)
+	}
+	return nil
+}
+
+// Sweep deletes the deliveries received before the retention duration, and their unreferenced payloads.
+//
+// It returns the number of deleted deliveries.
+// It does nothing if the retention is 0.
+func (s *Store) Sweep(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	var n int64
+	err := s.transaction(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM deliveries WHERE received_at < ?`, s.now().Add(-retention).UnixNano())
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		n, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM payloads WHERE NOT EXISTS (SELECT 1 FROM deliveries d WHERE d.payload_digest = payloads.digest)`)
+		if err != nil {
+			return fmt.Errorf("delete payloads: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("SQLite: sweep: %w", err)
+	}
+	return n, nil
+}
+
+func (s *Store) transaction(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	err = f(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// getPayloadDigest returns the payload digest of a delivery, or nil if it doesn't exist.
+func getPayloadDigest(ctx context.Context, tx *sql.Tx, deliveryID string) ([]byte, error) {
+	var digest []byte
+	err := tx.QueryRowContext(ctx, `SELECT payload_digest FROM deliveries WHERE delivery_id = ?`, deliveryID).Scan(&digest)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("select payload digest: %w", err)
+	}
+	return digest, nil
+}
+
+// deleteUnreferencedPayload deletes a payload if it's not referenced by a delivery.
+//
+// It does nothing if the digest is nil.
+func deleteUnreferencedPayload(ctx context.Context, tx *sql.Tx, digest []byte) error {
+	if digest == nil {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM payloads WHERE digest = ? AND NOT EXISTS (SELECT 1 FROM deliveries WHERE payload_digest = ?)`, digest, digest)
+	if err != nil {
+		return fmt.Errorf("delete payload: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(sc scanner) (*githubhook.StoredDelivery, error) {
+	d := new(githubhook.StoredDelivery)
+	var lineage, signatureHeaders, headerInterpretations, annotations sql.NullString
+	var status string
+	var receivedAt, updatedAt int64
+	err := sc.Scan(
+		&d.DeliveryID,
+		&d.Event,
+		&d.Repository,
+		&d.HookID,
+		&d.InstallationTargetType,
+		&d.InstallationTargetID,
+		&d.UserAgent,
+		&lineage,
+		&signatureHeaders,
+		&headerInterpretations,
+		&annotations,
+		&d.RawPayload,
+		&status,
+		&d.Error,
+		&receivedAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: scan: %w", err)
+	}
+	d.Status = githubhook.DeliveryStatus(status)
+	d.ReceivedAt = time.Unix(0, receivedAt).UTC()
+	d.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	if lineage.Valid {
+		d.Lineage = new(githubhook.Lineage)
+		err = json.Unmarshal([]byte(lineage.String), d.Lineage)
+		if err != nil {
+			return nil, fmt.Errorf("SQLite: lineage JSON decode: %w", err)
+		}
+	}
+	if signatureHeaders.Valid {
+		err = json.Unmarshal([]byte(signatureHeaders.String), &d.SignatureHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("SQLite: signature headers JSON decode: %w", err)
+		}
+	}
+	if headerInterpretations.Valid {
+		err = json.Unmarshal([]byte(headerInterpretations.String), &d.HeaderInterpretations)
+		if err != nil {
+			return nil, fmt.Errorf("SQLite: header interpretations JSON decode: %w", err)
+		}
+	}
+	if annotations.Valid {
+		d.Annotations = new(githubhook.Annotations)
+		err = json.Unmarshal([]byte(annotations.String), d.Annotations)
+		if err != nil {
+			return nil, fmt.Errorf("SQLite: annotations JSON decode: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// jsonArg returns the JSON encoding of a value, or NULL if it's empty.
+func jsonArg(v any, empty bool) (sql.NullString, error) {
+	if empty {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err //nolint:wrapcheck // The error is wrapped by the caller.
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// nonNullBytes returns b, or an empty slice if it's nil, because NULL is not allowed.
+func nonNullBytes(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,197 @@
+package githubhooksqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testOpen(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
+
+func testNewDelivery(event string, deliveryID string, receivedAt time.Time) *githubhook.StoredDelivery {
+	return &githubhook.StoredDelivery{
+		DeliveryMetadata: githubhook.DeliveryMetadata{
+			Event:      event,
+			DeliveryID: deliveryID,
+			HookID:     "123",
+			ReceivedAt: receivedAt,
+		},
+		Repository: "pierrre/githubhook",
+		RawPayload: []byte(`{"ref":"refs/heads/main"}`),
+		Status:     githubhook.DeliveryStatusPending,
+		UpdatedAt:  receivedAt,
+	}
+}
+
+func testDeliveryIDs(ds []*githubhook.StoredDelivery) []string {
+	ids := make([]string, len(ds))
+	for i, d := range ds {
+		ids[i] = d.DeliveryID
+	}
+	return ids
+}
+
+func TestSaveGet(t *testing.T) {
+	ctx := context.Background()
+	s := testOpen(t).Store
+	d := testNewDelivery("push", "1", testTime)
+	d.Lineage = &githubhook.Lineage{OriginalDeliveryID: "original", Generation: 1}
+	d.SignatureHeaders = []string{"X-Hub-Signature-256", "X-Hub-Signature"}
+	d.HeaderInterpretations = []string{"interpretation"}
+	d.Annotations = new(githubhook.Annotations)
+	d.Annotations.Set("rule", "deploy")
+	err := s.Save(ctx, d)
+	assert.NoError(t, err)
+	got, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.DeepEqual(t, got, d)
+	d.Status = githubhook.DeliveryStatusFailed
+	d.Error = "error"
+	err = s.Save(ctx, d)
+	assert.NoError(t, err)
+	got, err = s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, got.Status, githubhook.DeliveryStatusFailed)
+	assert.Equal(t, got.Error, "error")
+}
+
+func TestGetNotFound(t *testing.T) {
+	s := testOpen(t).Store
+	_, err := s.Get(context.Background(), "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	s := testOpen(t).Store
+	for _, d := range []*githubhook.StoredDelivery{
+		testNewDelivery("push", "3", testTime.Add(3*time.Minute)),
+		testNewDelivery("issues", "2", testTime.Add(2*time.Minute)),
+		testNewDelivery("push", "1", testTime.Add(1*time.Minute)),
+		testNewDelivery("issues", "4", testTime.Add(4*time.Minute)),
+		testNewDelivery("push", "5", testTime.Add(5*time.Minute)),
+	} {
+		err := s.Save(ctx, d)
+		assert.NoError(t, err)
+	}
+	for _, tc := range []struct {
+		name     string
+		filter   githubhook.StoreFilter
+		expected []string
+	}{
+		{
+			name:     "All",
+			expected: []string{"1", "2", "3", "4", "5"},
+		},
+		{
+			name:     "Event",
+			filter:   githubhook.StoreFilter{Event: "push", Limit: 2},
+			expected: []string{"1", "3"},
+		},
+		{
+			name:     "Newest",
+			filter:   githubhook.StoreFilter{Limit: 3, Newest: true},
+			expected: []string{"5", "4", "3"},
+		},
+		{
+			name:     "TimeRange",
+			filter:   githubhook.StoreFilter{Since: testTime.Add(2 * time.Minute), Until: testTime.Add(4 * time.Minute)},
+			expected: []string{"2", "3"},
+		},
+		{
+			name:     "Repository",
+			filter:   githubhook.StoreFilter{Repository: "other/repository"},
+			expected: []string{},
+		},
+		{
+			name:     "Status",
+			filter:   githubhook.StoreFilter{Status: githubhook.DeliveryStatusPending, Limit: 1},
+			expected: []string{"1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ds, err := s.List(ctx, tc.filter)
+			assert.NoError(t, err)
+			assert.SliceEqual(t, testDeliveryIDs(ds), tc.expected)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	s := testOpen(t).Store
+	err := s.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	err = s.Delete(ctx, "1")
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+	err = s.Delete(ctx, "1")
+	assert.ErrorIs(t, err, githubhook.ErrStoredDeliveryNotFound)
+}
+
+// testPayloadCount returns the number of stored payloads.
+func testPayloadCount(t *testing.T, s *Store) int {
+	t.Helper()
+	var n int
+	err := s.db.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM payloads`).Scan(&n)
+	assert.NoError(t, err)
+	return n
+}
+
+func TestPayloads(t *testing.T) {
+	ctx := context.Background()
+	s := testOpen(t).Store
+	for _, id := range []string{"1", "2"} {
+		err := s.Save(ctx, testNewDelivery("push", id, testTime))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, testPayloadCount(t, s), 1)
+	d := testNewDelivery("push", "2", testTime)
+	d.RawPayload = []byte(`{"ref":"refs/heads/other"}`)
+	err := s.Save(ctx, d)
+	assert.NoError(t, err)
+	assert.Equal(t, testPayloadCount(t, s), 2)
+	err = s.Delete(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, testPayloadCount(t, s), 1)
+	got, err := s.Get(ctx, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, string(got.RawPayload), string(d.RawPayload))
+}
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	s := testOpen(t).Store
+	s.now = func() time.Time {
+		return testTime.Add(48 * time.Hour)
+	}
+	err := s.Save(ctx, testNewDelivery("push", "1", testTime))
+	assert.NoError(t, err)
+	err = s.Save(ctx, testNewDelivery("push", "2", testTime.Add(47*time.Hour)))
+	assert.NoError(t, err)
+	n, err := s.Sweep(ctx, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+	ds, err := s.List(ctx, githubhook.StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceEqual(t, testDeliveryIDs(ds), []string{"2"})
+	assert.Equal(t, testPayloadCount(t, s), 1)
+	n, err = s.Sweep(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(0))
+}
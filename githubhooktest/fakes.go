@@ -0,0 +1,143 @@
+package githubhooktest
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/pierrre/githubhook"
+)
+
+/*
+DedupStore is an in-memory [githubhook.DedupStore], with introspection helpers.
+
+Err is the error returned by all methods (optional), in order to test error handling.
+
+The zero value is ready to use.
+*/
+type DedupStore struct {
+	Err error
+
+	mu        sync.Mutex
+	seen      map[string]bool
+	marked    []string
+	forgotten []string
+}
+
+// MarkSeen implements [githubhook.DedupStore].
+func (s *DedupStore) MarkSeen(ctx context.Context, deliveryID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return false, s.Err
+	}
+	s.marked = append(s.marked, deliveryID)
+	if s.seen[deliveryID] {
+		return true, nil
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	s.seen[deliveryID] = true
+	return false, nil
+}
+
+// Forget implements [githubhook.DedupStore].
+func (s *DedupStore) Forget(ctx context.Context, deliveryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.forgotten = append(s.forgotten, deliveryID)
+	delete(s.seen, deliveryID)
+	return nil
+}
+
+// Marked returns the delivery IDs passed to [DedupStore.MarkSeen], in order.
+func (s *DedupStore) Marked() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.marked)
+}
+
+// Forgotten returns the delivery IDs passed to [DedupStore.Forget], in order.
+func (s *DedupStore) Forgotten() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.forgotten)
+}
+
+/*
+SecretProvider is a [githubhook.SecretProvider] that returns a fixed secret, with introspection helpers.
+
+Err is the error returned by [SecretProvider.GetSecret] (optional), in order to test error handling.
+
+The zero value is ready to use, and returns an empty secret (so the requests are rejected, see [githubhook.ErrNoSecret]).
+*/
+type SecretProvider struct {
+	Secret string
+	Err    error
+
+	mu     sync.Mutex
+	events []string
+}
+
+// GetSecret implements [githubhook.SecretProvider].
+func (sp *SecretProvider) GetSecret(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.events = append(sp.events, event)
+	if sp.Err != nil {
+		return nil, sp.Err
+	}
+	return []byte(sp.Secret), nil
+}
+
+// Events returns the events of the requests passed to [SecretProvider.GetSecret], in order.
+func (sp *SecretProvider) Events() []string {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return slices.Clone(sp.events)
+}
+
+// Delivery is a delivery recorded by a [Recorder].
+type Delivery struct {
+	Metadata *githubhook.DeliveryMetadata
+	Payload  any
+}
+
+/*
+Recorder is a [githubhook.DeliveryHandler] that records the deliveries.
+
+Err is the error returned by [Recorder.Handle] (optional), in order to test error handling.
+
+The zero value is ready to use.
+*/
+type Recorder struct {
+	Err error
+
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// Handle records a delivery.
+//
+// It implements [githubhook.DeliveryHandler].
+func (r *Recorder) Handle(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, Delivery{
+		Metadata: md,
+		Payload:  payload,
+	})
+	return r.Err
+}
+
+// Deliveries returns the recorded deliveries, in order.
+func (r *Recorder) Deliveries() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.deliveries)
+}
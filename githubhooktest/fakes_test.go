@@ -0,0 +1,70 @@
+package githubhooktest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestFakes(t *testing.T) {
+	ctx := context.Background()
+	dedup := new(DedupStore)
+	sp := &SecretProvider{
+		Secret: "foobar",
+	}
+	rec := new(Recorder)
+	h := &githubhook.Handler{
+		SecretProvider: sp,
+		Dedup:          dedup,
+		Delivery:       rec.Handle,
+	}
+	s := &githubhook.Signer{
+		Secret: "foobar",
+	}
+	for range 2 {
+		req, err := s.NewRequest(ctx, "/", "push", "test", []byte(`{}`))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+	assert.SliceEqual(t, dedup.Marked(), []string{"test", "test"})
+	assert.SliceEmpty(t, dedup.Forgotten())
+	assert.SliceEqual(t, sp.Events(), []string{"push", "push"})
+	ds := rec.Deliveries()
+	assert.SliceLen(t, ds, 1)
+	assert.Equal(t, ds[0].Metadata.DeliveryID, "test")
+}
+
+func TestFakesError(t *testing.T) {
+	ctx := context.Background()
+	dedup := new(DedupStore)
+	rec := &Recorder{
+		Err: errors.New("error"),
+	}
+	h := &githubhook.Handler{
+		Dedup:    dedup,
+		Delivery: rec.Handle,
+	}
+	req, err := new(githubhook.Signer).NewRequest(ctx, "/", "push", "test", []byte(`{}`))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+	assert.SliceEqual(t, dedup.Forgotten(), []string{"test"})
+	dedup.Err = errors.New("error")
+	_, err = dedup.MarkSeen(ctx, "test")
+	assert.Error(t, err)
+	err = dedup.Forget(ctx, "test")
+	assert.Error(t, err)
+	sp := &SecretProvider{
+		Err: errors.New("error"),
+	}
+	_, err = sp.GetSecret(ctx, nil, "push")
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+// Package githubhooktest provides utilities for testing applications that use [githubhook].
+//
+// It provides helpers to create signed deliveries, in-memory fakes of the pluggable interfaces with introspection helpers, and a soak-test harness ([Soak]).
+// Like [httptest], the helpers panic on error.
+package githubhooktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pierrre/githubhook"
+)
+
+// NewRequest returns a new delivery request for an [http.Handler], signed with the secret (if it's not empty).
+//
+// The payload is either raw JSON ([]byte or [json.RawMessage]), or a value encoded to JSON.
+// The delivery ID is random.
+func NewRequest(event string, payload any, secret string) *http.Request {
+	rawPayload := encodePayload(payload)
+	s := &githubhook.Signer{
+		Secret: secret,
+	}
+	header, err := s.Header(event, "", rawPayload)
+	if err != nil {
+		panic(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(rawPayload))
+	req.Header = header
+	return req
+}
+
+// Sign signs a request with the secret.
+//
+// It reads the body, and replaces it with an equivalent one.
+func Sign(req *http.Request, secret string) {
+	rawPayload, err := io.ReadAll(req.Body)
+	if err != nil {
+		panic(fmt.Errorf("read body: %w", err))
+	}
+	req.Body = io.NopCloser(bytes.NewReader(rawPayload))
+	s := &githubhook.Signer{
+		Secret: secret,
+	}
+	header, err := s.Header(req.Header.Get("X-GitHub-Event"), req.Header.Get("X-GitHub-Delivery"), rawPayload)
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range []string{"X-Hub-Signature-256", "X-Hub-Signature"} {
+		req.Header.Set(name, header.Get(name))
+	}
+}
+
+// Serve serves a signed delivery (see [NewRequest]) with an [http.Handler], and returns the recorded response.
+func Serve(h http.Handler, event string, payload any, secret string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, NewRequest(event, payload, secret))
+	return w
+}
+
+func encodePayload(payload any) []byte {
+	switch p := payload.(type) {
+	case []byte:
+		return p
+	case json.RawMessage:
+		return p
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		panic(fmt.Errorf("JSON marshal: %w", err))
+	}
+	return b
+}
@@ -0,0 +1,55 @@
+package githubhooktest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestNewRequest(t *testing.T) {
+	rec := new(Recorder)
+	h := &githubhook.Handler{
+		Secret:   "foobar",
+		Delivery: rec.Handle,
+	}
+	req := NewRequest("push", map[string]any{"ref": "refs/heads/main"}, "foobar")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	ds := rec.Deliveries()
+	assert.SliceLen(t, ds, 1)
+	ev, _ := assert.Type[*events.PushEvent](t, ds[0].Payload)
+	assert.Equal(t, ev.Ref, "refs/heads/main")
+}
+
+func TestSign(t *testing.T) {
+	ctx := context.Background()
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader([]byte(`{}`)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	Sign(req, "foobar")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestServe(t *testing.T) {
+	h := &githubhook.Handler{
+		Secret: "foobar",
+	}
+	w := Serve(h, "push", []byte(`{}`), "foobar")
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = Serve(h, "push", []byte(`{}`), "invalid")
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
@@ -0,0 +1,294 @@
+package githubhooktest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pierrre/githubhook"
+	"github.com/pierrre/githubhook/events"
+	"github.com/pierrre/githubhook/fixtures"
+)
+
+// Soak invariant errors.
+var (
+	// ErrSoakLost is returned by [Soak.Run] if an accepted delivery was not delivered.
+	ErrSoakLost = errors.New("accepted deliveries were lost")
+	// ErrSoakDuplicated is returned by [Soak.Run] if a delivery was delivered more than once, with [githubhook.Handler.Dedup] enabled.
+	ErrSoakDuplicated = errors.New("deliveries were delivered more than once")
+	// ErrSoakUnbounded is returned by [Soak.Run] if the number of pending deliveries exceeded [Soak.MaxPending].
+	ErrSoakUnbounded = errors.New("pending deliveries exceeded the bound")
+)
+
+/*
+Soak runs the full pipeline of a [githubhook.Handler] under sustained synthetic load, and checks invariants.
+
+The invariants are:
+  - No delivery is lost: each accepted delivery (status 200 or 202) is delivered successfully.
+  - No duplicate side effect: if [githubhook.Handler.Dedup] is set, each delivery is delivered at most once, even if it's redelivered.
+  - The queue is bounded: if MaxPending is set, the number of accepted deliveries that are not delivered yet never exceeds it.
+
+Deliveries rejected with a 503 status (e.g. the queue of [githubhook.Handler.Async] is full) are not lost, because GitHub redelivers them.
+
+Fields:
+  - Handler is the tested handler (required). It's not modified, [Soak.Run] uses a copy with wrapped [githubhook.Handler.Delivery] (or [githubhook.Handler.Sink]) and [githubhook.Handler.Ping].
+  - Secret is the secret used to sign the deliveries.
+  - Deliveries is the number of unique deliveries (default: 1000).
+  - Concurrency is the number of concurrent requests (default: 10).
+  - RedeliverEvery sends every Nth delivery twice, with the same delivery ID (default: 0, disabled).
+  - MaxPending is the maximum number of pending deliveries (default: 0, not checked).
+  - Payload returns the event and the raw payload of the i-th delivery (default: cycle through the [fixtures]).
+
+Quarantined deliveries (see [githubhook.Handler.Quarantine]) are reported as lost.
+If [githubhook.Handler.Async] is set, [Soak.Run] shuts it down to wait for the pending deliveries, so it can't be reused.
+*/
+type Soak struct {
+	Handler        *githubhook.Handler
+	Secret         string
+	Deliveries     int
+	Concurrency    int
+	RedeliverEvery int
+	MaxPending     int
+	Payload        func(i int) (event string, rawPayload []byte)
+}
+
+// SoakReport is the report of [Soak.Run].
+type SoakReport struct {
+	// Sent is the number of sent requests, including redeliveries.
+	Sent int
+	// Accepted is the number of requests with a 200 or 202 status.
+	Accepted int
+	// Rejected is the number of requests with a 503 status.
+	Rejected int
+	// Failed is the number of requests with another status.
+	Failed int
+	// Delivered is the number of successful calls to [githubhook.Handler.Delivery] (or [githubhook.Handler.Sink] or [githubhook.Handler.Ping]).
+	Delivered int
+	// Lost is the number of accepted deliveries that were not delivered.
+	Lost int
+	// Duplicated is the number of deliveries that were delivered successfully more than once.
+	Duplicated int
+	// MaxPending is the maximum observed number of accepted deliveries that were not delivered yet.
+	MaxPending int
+}
+
+// Run runs the soak test.
+//
+// It returns the report, and an error if an invariant is violated.
+func (s *Soak) Run(ctx context.Context) (*SoakReport, error) {
+	st := newSoakState(s.Handler.Async != nil)
+	h := *s.Handler
+	if h.Sink != nil {
+		sink := h.Sink
+		h.Sink = githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+			err := sink.Send(ctx, d)
+			st.delivered(d.DeliveryID, st.async, err)
+			return err
+		})
+	} else {
+		delivery := h.Delivery
+		h.Delivery = func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			var err error
+			if delivery != nil {
+				err = delivery(ctx, md, payload)
+			}
+			st.delivered(md.DeliveryID, st.async, err)
+			return err
+		}
+	}
+	if h.Ping != nil {
+		ping := h.Ping
+		h.Ping = func(ctx context.Context, md *githubhook.DeliveryMetadata, payload *events.PingEvent) error {
+			err := ping(ctx, md, payload)
+			st.delivered(md.DeliveryID, false, err)
+			return err
+		}
+	}
+	err := s.send(ctx, &h, st)
+	if err != nil {
+		return nil, err
+	}
+	if h.Async != nil {
+		err = h.Async.Shutdown(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("async shutdown: %w", err)
+		}
+	}
+	r := st.report()
+	return r, s.check(r)
+}
+
+func (s *Soak) send(ctx context.Context, h *githubhook.Handler, st *soakState) error {
+	deliveries := s.Deliveries
+	if deliveries <= 0 {
+		deliveries = 1000
+	}
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+	indexes := make(chan int)
+	errs := make([]error, concurrency)
+	var wg sync.WaitGroup
+	for w := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if errs[w] != nil {
+					continue
+				}
+				errs[w] = s.sendDelivery(ctx, h, st, i)
+			}
+		}()
+	}
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	err := errors.Join(errs...)
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err //nolint:wrapcheck // The errors are already wrapped.
+}
+
+func (s *Soak) sendDelivery(ctx context.Context, h *githubhook.Handler, st *soakState, i int) error {
+	event, rawPayload := s.getPayload(i)
+	deliveryID := "soak-" + strconv.Itoa(i)
+	n := 1
+	if s.RedeliverEvery > 0 && i%s.RedeliverEvery == 0 {
+		n = 2
+	}
+	signer := &githubhook.Signer{
+		Secret: s.Secret,
+	}
+	for range n {
+		header, err := signer.Header(event, deliveryID, rawPayload)
+		if err != nil {
+			return fmt.Errorf("delivery %q: %w", deliveryID, err)
+		}
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(rawPayload))
+		req.Header = header
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		// A ping acknowledged by the handler is not delivered.
+		acked := event == events.NamePing && h.Ping == nil && h.AckPing
+		st.response(deliveryID, w.Code, acked)
+	}
+	return nil
+}
+
+func (s *Soak) getPayload(i int) (event string, rawPayload []byte) {
+	if s.Payload != nil {
+		return s.Payload(i)
+	}
+	names := fixtures.Names()
+	name := names[i%len(names)]
+	return fixtures.Event(name), fixtures.MustLoad(name)
+}
+
+func (s *Soak) check(r *SoakReport) error {
+	var errs []error
+	if r.Lost > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrSoakLost, r.Lost))
+	}
+	if s.Handler.Dedup != nil && r.Duplicated > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrSoakDuplicated, r.Duplicated))
+	}
+	if s.MaxPending > 0 && r.MaxPending > s.MaxPending {
+		errs = append(errs, fmt.Errorf("%w: %d > %d", ErrSoakUnbounded, r.MaxPending, s.MaxPending))
+	}
+	return errors.Join(errs...)
+}
+
+type soakState struct {
+	async      bool
+	mu         sync.Mutex
+	r          SoakReport
+	accepted   map[string]bool
+	deliveries map[string]int
+	pending    atomic.Int64
+	maxPending atomic.Int64
+}
+
+func newSoakState(async bool) *soakState {
+	return &soakState{
+		async:      async,
+		accepted:   make(map[string]bool),
+		deliveries: make(map[string]int),
+	}
+}
+
+func (st *soakState) delivered(deliveryID string, async bool, err error) {
+	if async {
+		st.updatePending(-1)
+	}
+	if err != nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.r.Delivered++
+	st.deliveries[deliveryID]++
+}
+
+func (st *soakState) response(deliveryID string, statusCode int, acked bool) {
+	if st.async && statusCode == http.StatusAccepted {
+		st.updatePending(1)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.r.Sent++
+	switch statusCode {
+	case http.StatusOK, http.StatusAccepted:
+		st.r.Accepted++
+		if !acked {
+			st.accepted[deliveryID] = true
+		}
+	case http.StatusServiceUnavailable:
+		st.r.Rejected++
+	default:
+		st.r.Failed++
+	}
+}
+
+// updatePending updates the number of pending deliveries, accepted asynchronously.
+// A delivery can start before its response is recorded, so the number can be transiently negative.
+func (st *soakState) updatePending(delta int64) {
+	pending := st.pending.Add(delta)
+	for {
+		m := st.maxPending.Load()
+		if pending <= m || st.maxPending.CompareAndSwap(m, pending) {
+			return
+		}
+	}
+}
+
+func (st *soakState) report() *SoakReport {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	r := st.r
+	for deliveryID := range st.accepted {
+		if st.deliveries[deliveryID] == 0 {
+			r.Lost++
+		}
+	}
+	for _, n := range st.deliveries {
+		if n > 1 {
+			r.Duplicated++
+		}
+	}
+	r.MaxPending = int(st.maxPending.Load())
+	return &r
+}
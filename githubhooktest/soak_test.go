@@ -0,0 +1,131 @@
+package githubhooktest
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+)
+
+func TestSoak(t *testing.T) {
+	ctx := context.Background()
+	s := &Soak{
+		Handler: &githubhook.Handler{
+			Secret: "foobar",
+		},
+		Secret:     "foobar",
+		Deliveries: 100,
+	}
+	r, err := s.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Sent, 100)
+	assert.Equal(t, r.Accepted, 100)
+	assert.Equal(t, r.Delivered, 100)
+	assert.Zero(t, r.Lost)
+}
+
+func TestSoakSink(t *testing.T) {
+	ctx := context.Background()
+	var sent atomic.Int64
+	s := &Soak{
+		Handler: &githubhook.Handler{
+			Secret: "foobar",
+			Sink: githubhook.SinkFunc(func(ctx context.Context, d *githubhook.VerifiedDelivery) error {
+				sent.Add(1)
+				return nil
+			}),
+		},
+		Secret:     "foobar",
+		Deliveries: 100,
+	}
+	r, err := s.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Delivered, 100)
+	assert.Equal(t, sent.Load(), int64(100))
+	assert.Zero(t, r.Lost)
+}
+
+func TestSoakAsyncDedup(t *testing.T) {
+	ctx := context.Background()
+	s := &Soak{
+		Handler: &githubhook.Handler{
+			Secret: "foobar",
+			Async:  githubhook.NewAsyncPool(4, 100),
+			Dedup:  githubhook.NewMemoryDedupStore(1000, time.Hour),
+		},
+		Secret:         "foobar",
+		Deliveries:     200,
+		RedeliverEvery: 3,
+		MaxPending:     104,
+	}
+	r, err := s.Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Sent, 267)
+	assert.Equal(t, r.Accepted+r.Rejected, 267)
+	assert.Zero(t, r.Lost)
+	assert.Zero(t, r.Duplicated)
+	assert.LessOrEqual(t, r.MaxPending, 104)
+}
+
+type testNoDedupStore struct{}
+
+func (testNoDedupStore) MarkSeen(ctx context.Context, deliveryID string) (bool, error) {
+	return false, nil
+}
+
+func (testNoDedupStore) Forget(ctx context.Context, deliveryID string) error {
+	return nil
+}
+
+func TestSoakErrorDuplicated(t *testing.T) {
+	ctx := context.Background()
+	s := &Soak{
+		Handler: &githubhook.Handler{
+			Dedup: testNoDedupStore{},
+		},
+		Deliveries:     10,
+		RedeliverEvery: 1,
+	}
+	r, err := s.Run(ctx)
+	assert.ErrorIs(t, err, ErrSoakDuplicated)
+	assert.Equal(t, r.Duplicated, 10)
+}
+
+func TestSoakErrorLost(t *testing.T) {
+	ctx := context.Background()
+	s := &Soak{
+		Handler: &githubhook.Handler{
+			Quarantine: new(githubhook.Quarantine),
+			SoftChecks: []githubhook.SoftCheck{
+				func(event string, rawPayload []byte, req *http.Request) string {
+					return "test"
+				},
+			},
+		},
+		Deliveries: 10,
+	}
+	r, err := s.Run(ctx)
+	assert.ErrorIs(t, err, ErrSoakLost)
+	assert.Equal(t, r.Lost, 10)
+}
+
+func TestSoakErrorUnbounded(t *testing.T) {
+	ctx := context.Background()
+	s := &Soak{
+		Handler: &githubhook.Handler{
+			Async: githubhook.NewAsyncPool(1, 100),
+			Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+				time.Sleep(time.Millisecond)
+				return nil
+			},
+		},
+		Deliveries: 50,
+		MaxPending: 1,
+	}
+	_, err := s.Run(ctx)
+	assert.ErrorIs(t, err, ErrSoakUnbounded)
+}
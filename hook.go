@@ -0,0 +1,160 @@
+package githubhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Content types of [HookConfig].
+const (
+	HookContentTypeJSON = "json"
+	HookContentTypeForm = "form"
+)
+
+/*
+HookConfig is the configuration of a webhook, returned and updated by the GitHub webhook configuration API.
+
+See https://docs.github.com/en/rest/repos/webhooks#get-a-webhook-configuration-for-a-repository.
+
+The empty fields are not updated by [HookClient.UpdateConfig].
+The secret is never returned by the API.
+*/
+type HookConfig struct {
+	URL         string `json:"url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	InsecureSSL string `json:"insecure_ssl,omitempty"`
+	Secret      string `json:"secret,omitempty"`
+}
+
+/*
+Hook is a webhook, returned by the GitHub webhook API.
+
+See https://docs.github.com/en/rest/repos/webhooks#get-a-repository-webhook.
+*/
+type Hook struct {
+	ID     int64      `json:"id"`
+	Name   string     `json:"name"`
+	Active bool       `json:"active"`
+	Events []string   `json:"events"`
+	Config HookConfig `json:"config"`
+}
+
+// HookUpdate is an update of a webhook, see [HookClient.UpdateHook].
+//
+// The nil fields are not updated.
+type HookUpdate struct {
+	Active *bool    `json:"active,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+/*
+HookClient is a client of the GitHub webhook management API, for a single webhook.
+
+Fields:
+  - URL is the URL of the webhook in the API (required), e.g. "https://api.github.com/repos/OWNER/REPO/hooks/HOOK_ID", "https://api.github.com/orgs/ORG/hooks/HOOK_ID" or "https://api.github.com/app/hook".
+  - Token is the token sent in the Authorization header (optional), e.g. a personal access token. A GitHub App must authenticate with a JWT, set by Client.
+  - Client is the HTTP client (default: [http.DefaultClient]).
+*/
+type HookClient struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// Config returns the configuration of the webhook.
+func (c *HookClient) Config(ctx context.Context) (*HookConfig, error) {
+	u, err := c.url("config")
+	if err != nil {
+		return nil, fmt.Errorf("hook config: %w", err)
+	}
+	cfg := new(HookConfig)
+	_, err = doGitHubAPI(ctx, c.Client, c.Token, http.MethodGet, u, nil, http.StatusOK, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("hook config: %w", err)
+	}
+	return cfg, nil
+}
+
+// UpdateConfig updates the non-empty fields of the configuration of the webhook, and returns the updated configuration.
+func (c *HookClient) UpdateConfig(ctx context.Context, cfg *HookConfig) (*HookConfig, error) {
+	u, err := c.url("config")
+	if err != nil {
+		return nil, fmt.Errorf("update hook config: %w", err)
+	}
+	updated := new(HookConfig)
+	_, err = doGitHubAPI(ctx, c.Client, c.Token, http.MethodPatch, u, cfg, http.StatusOK, updated)
+	if err != nil {
+		return nil, fmt.Errorf("update hook config: %w", err)
+	}
+	return updated, nil
+}
+
+// Hook returns the webhook.
+//
+// It's not supported by the webhook of a GitHub App, whose events are configured in the app settings.
+func (c *HookClient) Hook(ctx context.Context) (*Hook, error) {
+	u, err := c.url("")
+	if err != nil {
+		return nil, fmt.Errorf("hook: %w", err)
+	}
+	hook := new(Hook)
+	_, err = doGitHubAPI(ctx, c.Client, c.Token, http.MethodGet, u, nil, http.StatusOK, hook)
+	if err != nil {
+		return nil, fmt.Errorf("hook: %w", err)
+	}
+	return hook, nil
+}
+
+// UpdateHook updates the webhook, and returns the updated webhook.
+//
+// It's not supported by the webhook of a GitHub App.
+func (c *HookClient) UpdateHook(ctx context.Context, update *HookUpdate) (*Hook, error) {
+	u, err := c.url("")
+	if err != nil {
+		return nil, fmt.Errorf("update hook: %w", err)
+	}
+	hook := new(Hook)
+	_, err = doGitHubAPI(ctx, c.Client, c.Token, http.MethodPatch, u, update, http.StatusOK, hook)
+	if err != nil {
+		return nil, fmt.Errorf("update hook: %w", err)
+	}
+	return hook, nil
+}
+
+// url returns the URL of the webhook in the API, with the path suffix (optional).
+func (c *HookClient) url(suffix string) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("parse URL: %w", err)
+	}
+	if suffix != "" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + suffix
+	}
+	return u.String(), nil
+}
+
+/*
+MigrateFormHook switches the content type of a webhook from form to JSON, as recommended by GitHub, e.g. before enabling [Handler.RejectForm].
+
+It returns true if the webhook was configured with the form content type, and has been switched to JSON.
+The other fields of the configuration (including the secret) are not modified, so the webhook keeps working: [Handler] accepts both content types.
+*/
+func MigrateFormHook(ctx context.Context, c *HookClient) (bool, error) {
+	cfg, err := c.Config(ctx)
+	if err != nil {
+		return false, fmt.Errorf("migrate form hook: %w", err)
+	}
+	if cfg.ContentType != HookContentTypeForm {
+		return false, nil
+	}
+	_, err = c.UpdateConfig(ctx, &HookConfig{
+		ContentType: HookContentTypeJSON,
+	})
+	if err != nil {
+		return false, fmt.Errorf("migrate form hook: %w", err)
+	}
+	return true, nil
+}
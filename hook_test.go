@@ -0,0 +1,186 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+type testHookAPI struct {
+	*httptest.Server
+	mu      sync.Mutex
+	hook    Hook
+	updates []map[string]any
+}
+
+func newTestHookAPI(t *testing.T, cfg HookConfig) *testHookAPI {
+	t.Helper()
+	api := &testHookAPI{
+		hook: Hook{
+			ID:     1,
+			Name:   "web",
+			Active: true,
+			Events: []string{"push"},
+			Config: cfg,
+		},
+	}
+	mux := http.NewServeMux()
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			api.mu.Lock()
+			defer api.mu.Unlock()
+			h(w, req)
+		}
+	}
+	decodeUpdate := func(req *http.Request, v any) {
+		assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+		b, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		var update map[string]any
+		err = json.Unmarshal(b, &update)
+		assert.NoError(t, err)
+		api.updates = append(api.updates, update)
+		err = json.Unmarshal(b, v)
+		assert.NoError(t, err)
+	}
+	mux.HandleFunc("GET /hooks/1", authorized(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.hook)
+	}))
+	mux.HandleFunc("PATCH /hooks/1", authorized(func(w http.ResponseWriter, req *http.Request) {
+		var update HookUpdate
+		decodeUpdate(req, &update)
+		if update.Active != nil {
+			api.hook.Active = *update.Active
+		}
+		if update.Events != nil {
+			api.hook.Events = update.Events
+		}
+		_ = json.NewEncoder(w).Encode(api.hook)
+	}))
+	mux.HandleFunc("GET /hooks/1/config", authorized(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.hook.Config)
+	}))
+	mux.HandleFunc("PATCH /hooks/1/config", authorized(func(w http.ResponseWriter, req *http.Request) {
+		decodeUpdate(req, &api.hook.Config) // Only the fields of the update are modified.
+		_ = json.NewEncoder(w).Encode(api.hook.Config)
+	}))
+	api.Server = httptest.NewServer(mux)
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (api *testHookAPI) get() (Hook, []map[string]any) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.hook, api.updates
+}
+
+// set modifies the webhook, e.g. to simulate a change in the UI.
+func (api *testHookAPI) set(f func(hook *Hook)) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	f(&api.hook)
+}
+
+func newTestHookClient(api *testHookAPI) *HookClient {
+	return &HookClient{
+		URL:    api.URL + "/hooks/1",
+		Token:  "token",
+		Client: api.Client(),
+	}
+}
+
+func TestHookClientConfig(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeJSON, InsecureSSL: "0"})
+	c := newTestHookClient(api)
+	cfg, err := c.Config(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, *cfg, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeJSON, InsecureSSL: "0"})
+}
+
+func TestHookClientConfigError(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{})
+	c := newTestHookClient(api)
+	c.Token = ""
+	_, err := c.Config(context.Background())
+	assert.ErrorContains(t, err, "unexpected response status 401")
+}
+
+func TestHookClientURLError(t *testing.T) {
+	c := &HookClient{
+		URL: "\x00",
+	}
+	_, err := c.Config(context.Background())
+	assert.ErrorContains(t, err, "parse URL")
+	_, err = c.UpdateConfig(context.Background(), &HookConfig{})
+	assert.ErrorContains(t, err, "parse URL")
+	_, err = c.Hook(context.Background())
+	assert.ErrorContains(t, err, "parse URL")
+	_, err = c.UpdateHook(context.Background(), &HookUpdate{})
+	assert.ErrorContains(t, err, "parse URL")
+}
+
+func TestMigrateFormHook(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeForm, Secret: "********"})
+	c := newTestHookClient(api)
+	migrated, err := MigrateFormHook(context.Background(), c)
+	assert.NoError(t, err)
+	assert.True(t, migrated)
+	hook, update := api.get()
+	assert.Equal(t, hook.Config.ContentType, HookContentTypeJSON)
+	assert.Equal(t, hook.Config.Secret, "********")
+	// Only the content type is updated, so the secret is not overwritten by the masked value.
+	assert.SliceLen(t, update, 1)
+	assert.MapEqual(t, update[0], map[string]any{"content_type": "json"})
+	migrated, err = MigrateFormHook(context.Background(), c)
+	assert.NoError(t, err)
+	assert.False(t, migrated)
+	_, update = api.get()
+	assert.SliceLen(t, update, 1)
+}
+
+func TestMigrateFormHookError(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{})
+	c := newTestHookClient(api)
+	c.Token = ""
+	_, err := MigrateFormHook(context.Background(), c)
+	assert.ErrorContains(t, err, "migrate form hook")
+}
+
+func TestHookClientHook(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://example.com/webhook"})
+	c := newTestHookClient(api)
+	hook, err := c.Hook(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, hook.ID, 1)
+	assert.True(t, hook.Active)
+	assert.SliceEqual(t, hook.Events, []string{"push"})
+	assert.Equal(t, hook.Config.URL, "https://example.com/webhook")
+	active := false
+	hook, err = c.UpdateHook(context.Background(), &HookUpdate{Active: &active})
+	assert.NoError(t, err)
+	assert.False(t, hook.Active)
+	assert.SliceEqual(t, hook.Events, []string{"push"})
+	_, updates := api.get()
+	assert.MapEqual(t, updates[0], map[string]any{"active": false})
+}
+
+func TestHookClientHookError(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{})
+	c := newTestHookClient(api)
+	c.Token = ""
+	_, err := c.Hook(context.Background())
+	assert.ErrorContains(t, err, "hook: unexpected response status 401")
+	_, err = c.UpdateHook(context.Background(), &HookUpdate{})
+	assert.ErrorContains(t, err, "update hook: unexpected response status 401")
+}
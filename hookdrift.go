@@ -0,0 +1,216 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fields of [HookDrift].
+const (
+	HookDriftURL         = "url"
+	HookDriftContentType = "content_type"
+	HookDriftInsecureSSL = "insecure_ssl"
+	HookDriftActive      = "active"
+	HookDriftEvents      = "events"
+)
+
+// HookDrift is a difference between the declared configuration of a webhook and its live configuration, found by [HookDriftDetector].
+type HookDrift struct {
+	// Field is the drifted field, e.g. [HookDriftActive].
+	Field string
+	// Declared is the declared value. The events are sorted and joined with ",".
+	Declared string
+	// Live is the live value.
+	Live string
+}
+
+func (d HookDrift) String() string {
+	return fmt.Sprintf("%s: declared %q, live %q", d.Field, d.Declared, d.Live)
+}
+
+/*
+HookDriftDetector compares periodically the live configuration of a webhook (URL, content type, TLS verification, active flag and events) with its declared configuration, with the GitHub webhook API.
+It detects the changes made outside of the declared configuration, e.g. someone disabled the webhook in the UI.
+
+Fields:
+  - Client is the client of the webhook (required).
+  - Config is the declared configuration. The empty fields are not checked. The secret can't be checked (it's not returned by the API), but it's restored by a repair of the configuration.
+  - Events are the declared events (optional). If it's nil, the events are not checked. The order doesn't matter.
+  - SkipHook only checks the configuration, not the active flag and the events, e.g. for the webhook of a GitHub App (see [HookClient.Hook]). Otherwise, the webhook must be active.
+  - Repair updates the webhook with the declared configuration if a drift is found.
+  - Drift is called if a check finds drifts (optional), before the repair.
+  - Error is called if a check or a repair fails in [HookDriftDetector.Run] (optional).
+
+The result of the last check is available with [HookDriftDetector.Report], e.g. for metrics.
+
+The fields must not be modified after the first check.
+*/
+type HookDriftDetector struct {
+	Client   *HookClient
+	Config   HookConfig
+	Events   []string
+	SkipHook bool
+	Repair   bool
+	Drift    func(ctx context.Context, drifts []HookDrift)
+	Error    func(ctx context.Context, err error)
+
+	mu     sync.Mutex
+	report HookDriftReport
+}
+
+// HookDriftReport is the report of the last check of a [HookDriftDetector].
+type HookDriftReport struct {
+	// CheckedAt is the time of the last successful check, or zero.
+	CheckedAt time.Time
+	// Drifts are the drifts found by the last successful check.
+	Drifts []HookDrift
+	// Repairs is the number of successful repairs since the creation of the detector.
+	Repairs int
+}
+
+// Check checks the webhook once, and returns the drifts.
+//
+// If Repair is set, the drifted webhook is repaired, and an error is returned if the repair fails.
+func (d *HookDriftDetector) Check(ctx context.Context) ([]HookDrift, error) {
+	drifts, err := d.check(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("hook drift: %w", err)
+	}
+	d.mu.Lock()
+	d.report.CheckedAt = time.Now()
+	d.report.Drifts = drifts
+	d.mu.Unlock()
+	if len(drifts) == 0 {
+		return nil, nil
+	}
+	if d.Drift != nil {
+		d.Drift(ctx, drifts)
+	}
+	if !d.Repair {
+		return drifts, nil
+	}
+	err = d.repair(ctx, drifts)
+	if err != nil {
+		return drifts, fmt.Errorf("hook drift: repair: %w", err)
+	}
+	d.mu.Lock()
+	d.report.Repairs++
+	d.mu.Unlock()
+	return drifts, nil
+}
+
+// Run runs [HookDriftDetector.Check] periodically, until the context is canceled.
+//
+// The errors are reported to [HookDriftDetector.Error].
+func (d *HookDriftDetector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := d.Check(ctx)
+		if err != nil && ctx.Err() == nil && d.Error != nil {
+			d.Error(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report returns the report of the last check.
+func (d *HookDriftDetector) Report() HookDriftReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := d.report
+	r.Drifts = slices.Clone(r.Drifts)
+	return r
+}
+
+func (d *HookDriftDetector) check(ctx context.Context) ([]HookDrift, error) {
+	if d.Client == nil {
+		return nil, errors.New("client not configured")
+	}
+	var cfg *HookConfig
+	var drifts []HookDrift
+	if d.SkipHook {
+		var err error
+		cfg, err = d.Client.Config(ctx)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // The error is wrapped by the caller.
+		}
+	} else {
+		hook, err := d.Client.Hook(ctx)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // The error is wrapped by the caller.
+		}
+		cfg = &hook.Config
+		if !hook.Active {
+			drifts = append(drifts, HookDrift{Field: HookDriftActive, Declared: strconv.FormatBool(true), Live: strconv.FormatBool(false)})
+		}
+		if d.Events != nil {
+			declared, live := joinEvents(d.Events), joinEvents(hook.Events)
+			if declared != live {
+				drifts = append(drifts, HookDrift{Field: HookDriftEvents, Declared: declared, Live: live})
+			}
+		}
+	}
+	for _, f := range []struct {
+		field    string
+		declared string
+		live     string
+	}{
+		{HookDriftURL, d.Config.URL, cfg.URL},
+		{HookDriftContentType, d.Config.ContentType, cfg.ContentType},
+		{HookDriftInsecureSSL, d.Config.InsecureSSL, cfg.InsecureSSL},
+	} {
+		if f.declared != "" && f.declared != f.live {
+			drifts = append(drifts, HookDrift{Field: f.field, Declared: f.declared, Live: f.live})
+		}
+	}
+	return drifts, nil
+}
+
+// repair updates the drifted parts of the webhook with the declared configuration.
+func (d *HookDriftDetector) repair(ctx context.Context, drifts []HookDrift) error {
+	var repairHook, repairConfig bool
+	for _, drift := range drifts {
+		switch drift.Field {
+		case HookDriftActive, HookDriftEvents:
+			repairHook = true
+		default:
+			repairConfig = true
+		}
+	}
+	if repairConfig {
+		cfg := d.Config
+		_, err := d.Client.UpdateConfig(ctx, &cfg)
+		if err != nil {
+			return err //nolint:wrapcheck // The error is wrapped by the caller.
+		}
+	}
+	if repairHook {
+		active := true
+		_, err := d.Client.UpdateHook(ctx, &HookUpdate{
+			Active: &active,
+			Events: d.Events,
+		})
+		if err != nil {
+			return err //nolint:wrapcheck // The error is wrapped by the caller.
+		}
+	}
+	return nil
+}
+
+// joinEvents returns the sorted events, joined with ",".
+func joinEvents(events []string) string {
+	events = slices.Clone(events)
+	slices.Sort(events)
+	return strings.Join(slices.Compact(events), ",")
+}
@@ -0,0 +1,160 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func newTestHookDriftDetector(api *testHookAPI) *HookDriftDetector {
+	return &HookDriftDetector{
+		Client: newTestHookClient(api),
+		Config: HookConfig{
+			URL:         "https://example.com/webhook",
+			ContentType: HookContentTypeJSON,
+			InsecureSSL: "0",
+		},
+		Events: []string{"push", "pull_request"},
+	}
+}
+
+func TestHookDriftDetector(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeJSON, InsecureSSL: "0"})
+	api.set(func(hook *Hook) {
+		hook.Events = []string{"pull_request", "push"}
+	})
+	d := newTestHookDriftDetector(api)
+	var driftCalls []HookDrift
+	d.Drift = func(ctx context.Context, drifts []HookDrift) {
+		driftCalls = append(driftCalls, drifts...)
+	}
+	drifts, err := d.Check(context.Background())
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, drifts)
+	assert.SliceEmpty(t, driftCalls)
+	report := d.Report()
+	assert.False(t, report.CheckedAt.IsZero())
+	assert.SliceEmpty(t, report.Drifts)
+	// Someone disables the webhook and changes its events and content type in the UI.
+	api.set(func(hook *Hook) {
+		hook.Active = false
+		hook.Events = []string{"push"}
+		hook.Config.ContentType = HookContentTypeForm
+	})
+	expected := []HookDrift{
+		{Field: HookDriftActive, Declared: "true", Live: "false"},
+		{Field: HookDriftEvents, Declared: "pull_request,push", Live: "push"},
+		{Field: HookDriftContentType, Declared: "json", Live: "form"},
+	}
+	drifts, err = d.Check(context.Background())
+	assert.NoError(t, err)
+	assert.SliceEqual(t, drifts, expected)
+	assert.SliceEqual(t, driftCalls, expected)
+	report = d.Report()
+	assert.SliceEqual(t, report.Drifts, expected)
+	assert.Equal(t, report.Repairs, 0)
+	_, updates := api.get()
+	assert.SliceEmpty(t, updates)
+	assert.Equal(t, drifts[0].String(), `active: declared "true", live "false"`)
+}
+
+func TestHookDriftDetectorRepair(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://old.example.com/webhook", ContentType: HookContentTypeJSON})
+	api.set(func(hook *Hook) {
+		hook.Active = false
+	})
+	d := newTestHookDriftDetector(api)
+	d.Config.Secret = "secret"
+	d.Repair = true
+	drifts, err := d.Check(context.Background())
+	assert.NoError(t, err)
+	assert.SliceLen(t, drifts, 4)
+	hook, updates := api.get()
+	assert.True(t, hook.Active)
+	assert.SliceEqual(t, hook.Events, []string{"push", "pull_request"})
+	assert.Equal(t, hook.Config, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeJSON, InsecureSSL: "0", Secret: "secret"})
+	assert.SliceLen(t, updates, 2)
+	assert.Equal(t, d.Report().Repairs, 1)
+	drifts, err = d.Check(context.Background())
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, drifts)
+	assert.SliceEmpty(t, d.Report().Drifts)
+}
+
+func TestHookDriftDetectorSkipHook(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeForm, InsecureSSL: "0"})
+	api.set(func(hook *Hook) {
+		hook.Active = false
+	})
+	d := newTestHookDriftDetector(api)
+	d.SkipHook = true
+	d.Repair = true
+	drifts, err := d.Check(context.Background())
+	assert.NoError(t, err)
+	assert.SliceEqual(t, drifts, []HookDrift{{Field: HookDriftContentType, Declared: "json", Live: "form"}})
+	hook, updates := api.get()
+	assert.False(t, hook.Active)
+	assert.Equal(t, hook.Config.ContentType, HookContentTypeJSON)
+	assert.SliceLen(t, updates, 1)
+}
+
+func TestHookDriftDetectorError(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{})
+	d := newTestHookDriftDetector(api)
+	d.Client.Token = ""
+	_, err := d.Check(context.Background())
+	assert.ErrorContains(t, err, "hook drift: hook: unexpected response status 401")
+	d.SkipHook = true
+	_, err = d.Check(context.Background())
+	assert.ErrorContains(t, err, "hook drift: hook config: unexpected response status 401")
+	assert.True(t, d.Report().CheckedAt.IsZero())
+	d.Client = nil
+	_, err = d.Check(context.Background())
+	assert.ErrorContains(t, err, "client not configured")
+}
+
+func TestHookDriftDetectorRepairError(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{URL: "https://example.com/webhook", ContentType: HookContentTypeJSON, InsecureSSL: "0"})
+	api.set(func(hook *Hook) {
+		hook.Active = false
+	})
+	d := newTestHookDriftDetector(api)
+	d.Repair = true
+	d.Drift = func(ctx context.Context, drifts []HookDrift) {
+		d.Client.Token = "" // The repair is not authorized.
+	}
+	drifts, err := d.Check(context.Background())
+	assert.ErrorContains(t, err, "hook drift: repair: update hook")
+	assert.SliceLen(t, drifts, 2)
+	assert.Equal(t, d.Report().Repairs, 0)
+}
+
+func TestHookDriftDetectorRun(t *testing.T) {
+	api := newTestHookAPI(t, HookConfig{})
+	d := newTestHookDriftDetector(api)
+	d.Client.Token = ""
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errCount atomic.Int64
+	d.Error = func(ctx context.Context, err error) {
+		assert.Error(t, err)
+		if errCount.Add(1) == 2 {
+			cancel()
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx, time.Millisecond)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal(errors.New("timeout"))
+	}
+	assert.GreaterOrEqual(t, errCount.Load(), 2)
+}
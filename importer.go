@@ -0,0 +1,125 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+/*
+Importer imports the past deliveries of a webhook from the GitHub hook deliveries API, including their request headers and payload.
+
+It allows a new receiver to bootstrap the history of its [Store], or to backfill the processing of a missed period.
+The deliveries are imported from the oldest to the newest, once per GUID (the most recent attempt).
+The deliveries that are already in the store are skipped, so an import can be resumed.
+
+Fields:
+  - URL is the URL of the deliveries API of the webhook (required), see [Reconciler].
+  - Token is the token sent in the Authorization header (optional).
+  - Client is the HTTP client (default: [http.DefaultClient]).
+  - Store is the store where the deliveries are saved (required without Handler). Their status is [DeliveryStatusSucceeded] if the webhook answered with a 2xx status, [DeliveryStatusFailed] otherwise.
+  - Handler processes the deliveries instead (optional), like [ReconcileFetch] but with the "import" lineage. Store is ignored: the deliveries are stored in [Handler.Store] if it's defined, and the existing ones are skipped.
+  - Error is called for each delivery that can't be imported (optional). If it's nil, the import stops at the first error.
+
+The signature is not verified, because the payload is fetched from the authenticated API.
+The payload is encoded by the API, so it may not be byte-for-byte identical to the signed payload.
+*/
+type Importer struct {
+	URL     string
+	Token   string
+	Client  *http.Client
+	Store   Store
+	Handler *Handler
+	Error   func(ctx context.Context, d *HookDelivery, err error)
+}
+
+// Import imports the deliveries delivered between since and until, and returns the number of imported deliveries.
+func (im *Importer) Import(ctx context.Context, since time.Time, until time.Time) (int, error) {
+	store := im.getStore()
+	if store == nil && im.Handler == nil {
+		return 0, errors.New("import: store not configured")
+	}
+	api := im.api()
+	ds, err := api.list(ctx, since, until)
+	if err != nil {
+		return 0, fmt.Errorf("import: %w", err)
+	}
+	slices.Reverse(ds) // From the oldest to the newest.
+	var n int
+	for _, d := range ds {
+		imported, err := im.importDelivery(ctx, api, store, d)
+		if err != nil {
+			err = fmt.Errorf("import: delivery %s: %w", d.GUID, err)
+			if im.Error == nil {
+				return n, err
+			}
+			im.Error(ctx, d, err)
+			continue
+		}
+		if imported {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (im *Importer) importDelivery(ctx context.Context, api *hookDeliveriesAPI, store Store, d *HookDelivery) (bool, error) {
+	if store != nil {
+		_, err := store.Get(ctx, d.GUID)
+		if err == nil {
+			return false, nil
+		}
+		if !errors.Is(err, ErrStoredDeliveryNotFound) {
+			return false, fmt.Errorf("store: %w", err)
+		}
+	}
+	header, rawPayload, err := api.fetchRequest(ctx, d.ID)
+	if err != nil {
+		return false, err
+	}
+	md := NewDeliveryMetadata(d.Event, d.GUID, header.Get)
+	md.ReceivedAt = d.DeliveredAt
+	if im.Handler != nil {
+		md.Lineage = NextLineage(md, "", "import")
+		err = im.Handler.processFetched(ctx, md, header, rawPayload)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	sd := &StoredDelivery{
+		DeliveryMetadata: *md,
+		Repository:       RepositoryFullName(json.RawMessage(rawPayload)),
+		RawPayload:       rawPayload,
+		Status:           DeliveryStatusSucceeded,
+		UpdatedAt:        d.DeliveredAt,
+	}
+	if d.StatusCode < 200 || d.StatusCode >= 300 {
+		sd.Status = DeliveryStatusFailed
+		sd.Error = fmt.Sprintf("webhook response status %d: %s", d.StatusCode, d.Status)
+	}
+	err = store.Save(ctx, sd)
+	if err != nil {
+		return false, fmt.Errorf("store: %w", err)
+	}
+	return true, nil
+}
+
+func (im *Importer) getStore() Store {
+	if im.Handler != nil {
+		return im.Handler.Store
+	}
+	return im.Store
+}
+
+func (im *Importer) api() *hookDeliveriesAPI {
+	return &hookDeliveriesAPI{
+		url:    im.URL,
+		token:  im.Token,
+		client: im.Client,
+	}
+}
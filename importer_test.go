@@ -0,0 +1,118 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+// newTestImportAPI creates a fake deliveries API, with 3 deliveries (from the newest to the oldest):
+//   - 3: failed
+//   - 2: succeeded
+//   - 1: too old
+func newTestImportAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hooks/1/deliveries", func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode([]*HookDelivery{
+			{ID: 3, GUID: "guid-3", Event: "issues", DeliveredAt: testReconcileNow.Add(-1 * time.Minute), Status: "Internal Server Error", StatusCode: 500},
+			{ID: 2, GUID: "guid-2", Event: "push", DeliveredAt: testReconcileNow.Add(-2 * time.Minute), Status: "OK", StatusCode: 200},
+			{ID: 1, GUID: "guid-1", Event: "push", DeliveredAt: testReconcileNow.Add(-48 * time.Hour), StatusCode: 200},
+		})
+	})
+	mux.HandleFunc("GET /hooks/1/deliveries/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id := req.PathValue("id")
+		if id == "1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"request":{"headers":{"X-GitHub-Hook-ID":"1"},"payload":{"repository":{"full_name":"pierrre/githubhook"},"id":%s}}}`, id)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestImporter(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestImportAPI(t)
+	store := NewMemoryStore(10)
+	im := &Importer{
+		URL:   srv.URL + "/hooks/1/deliveries",
+		Store: store,
+	}
+	n, err := im.Import(ctx, testReconcileNow.Add(-time.Hour), testReconcileNow)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 2)
+	ds, err := store.List(ctx, StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 2)
+	d := ds[0]
+	assert.Equal(t, d.DeliveryID, "guid-2")
+	assert.Equal(t, d.Event, "push")
+	assert.Equal(t, d.HookID, "1")
+	assert.Equal(t, d.Repository, "pierrre/githubhook")
+	assert.Equal(t, d.Status, DeliveryStatusSucceeded)
+	assert.True(t, d.ReceivedAt.Equal(testReconcileNow.Add(-2*time.Minute)))
+	assert.Equal(t, string(d.RawPayload), `{"repository":{"full_name":"pierrre/githubhook"},"id":2}`)
+	d = ds[1]
+	assert.Equal(t, d.DeliveryID, "guid-3")
+	assert.Equal(t, d.Status, DeliveryStatusFailed)
+	assert.Equal(t, d.Error, "webhook response status 500: Internal Server Error")
+	n, err = im.Import(ctx, testReconcileNow.Add(-time.Hour), testReconcileNow)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+}
+
+func TestImporterHandler(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestImportAPI(t)
+	var delivered []*DeliveryMetadata
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			delivered = append(delivered, md)
+			return nil
+		},
+	}
+	im := &Importer{
+		URL:     srv.URL + "/hooks/1/deliveries",
+		Handler: h,
+	}
+	n, err := im.Import(ctx, testReconcileNow.Add(-time.Hour), testReconcileNow)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 2)
+	assert.SliceLen(t, delivered, 2)
+	assert.Equal(t, delivered[0].DeliveryID, "guid-2")
+	assert.Equal(t, delivered[0].Lineage.Reason, "import")
+	assert.Equal(t, delivered[1].DeliveryID, "guid-3")
+}
+
+func TestImporterError(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestImportAPI(t)
+	im := &Importer{
+		URL:   srv.URL + "/hooks/1/deliveries",
+		Store: NewMemoryStore(10),
+	}
+	var errs []string
+	im.Error = func(ctx context.Context, d *HookDelivery, err error) {
+		errs = append(errs, d.GUID)
+	}
+	n, err := im.Import(ctx, testReconcileNow.Add(-72*time.Hour), testReconcileNow)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 2)
+	assert.SliceEqual(t, errs, []string{"guid-1"})
+	im.Error = nil
+	_, err = im.Import(ctx, testReconcileNow.Add(-72*time.Hour), testReconcileNow)
+	assert.ErrorContains(t, err, "guid-1")
+	_, err = (&Importer{URL: srv.URL}).Import(ctx, time.Time{}, testReconcileNow)
+	assert.Error(t, err)
+	_, err = (&Importer{URL: srv.URL + "/invalid", Store: NewMemoryStore(10)}).Import(ctx, time.Time{}, testReconcileNow)
+	assert.Error(t, err)
+}
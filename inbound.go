@@ -0,0 +1,184 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+/*
+Inbound is a request received by an HTTP server other than [net/http], see [Handler.HandleInbound].
+
+Fields:
+  - Context is the context of the request (required).
+  - Method is the HTTP method.
+  - RemoteAddr is the network address of the client ("IP:port").
+  - Header returns the first value of a header (required).
+  - HeaderValues returns all the values of a header (optional). It's used for X-Forwarded-For by [Handler.SourceAllowlist]. If it's nil, only the first value is used.
+  - Body is the request body. It's not modified, and not retained after [Handler.HandleInbound] returns: it's copied once the delivery has been verified.
+  - Request converts the request to [http.Request] (required). It's called at most once, and only by the options whose API depends on [http.Request]: [Handler.TrustedSources], [Handler.SecretProvider], [Handler.RateLimiters], [Handler.SoftChecks], [Handler.LenientHeaders] and [Handler.Error].
+*/
+type Inbound struct {
+	Context      context.Context
+	Method       string
+	RemoteAddr   string
+	Header       func(name string) string
+	HeaderValues func(name string) []string
+	Body         []byte
+	Request      func() *http.Request
+}
+
+/*
+InboundResponse is the response to an [Inbound] request.
+
+Fields:
+  - StatusCode is the status code.
+  - Header contains the response headers (e.g. Content-Type, Retry-After, [Handler.SecurityHeaders]).
+  - Body is the response body.
+*/
+type InboundResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// HandleInbound handles a request received by an HTTP server other than [net/http], and returns the response to write.
+//
+// It runs the same pipeline as [Handler.ServeHTTP] (source allowlist, body size limit, signature verification, router, rate limiters, quarantine, deduplication, store, delivery, [Handler.Observer] and [Handler.Error]), except CORS.
+// The signature is verified on [Inbound.Body] without copying it.
+func (h *Handler) HandleInbound(in *Inbound) *InboundResponse {
+	w := &inboundResponseWriter{
+		resp: &InboundResponse{
+			Header: make(http.Header),
+		},
+	}
+	h.serve(w, newInbound(in))
+	if w.resp.StatusCode == 0 {
+		w.resp.StatusCode = http.StatusOK
+	}
+	w.resp.Body = w.body.Bytes()
+	return w.resp
+}
+
+// inbound is a request handled by the pipeline of [Handler], independent of the HTTP server.
+//
+// It's created from an [http.Request] by [Handler.ServeHTTP], or from an [Inbound] by [Handler.HandleInbound].
+type inbound struct {
+	ctx          context.Context
+	method       string
+	remoteAddr   string
+	header       func(name string) string
+	headerValues func(name string) []string
+	readBody     func(limit int64) ([]byte, error)
+	readForm     func(limit int64) (url.Values, error)
+	// borrowed is true if the body returned by readBody belongs to the HTTP server, and must be copied before it's retained.
+	borrowed  bool
+	req       *http.Request
+	toRequest func() *http.Request
+}
+
+func newRequestInbound(req *http.Request) *inbound {
+	return &inbound{
+		ctx:          req.Context(),
+		method:       req.Method,
+		remoteAddr:   req.RemoteAddr,
+		header:       req.Header.Get,
+		headerValues: req.Header.Values,
+		readBody: func(limit int64) ([]byte, error) {
+			limitBody(req, limit)
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, wrapBodyReadError(err)
+			}
+			return b, nil
+		},
+		readForm: func(limit int64) (url.Values, error) {
+			limitBody(req, limit)
+			err := req.ParseForm()
+			if err != nil {
+				return nil, wrapBodyReadError(err)
+			}
+			return req.PostForm, nil
+		},
+		req: req,
+	}
+}
+
+func newInbound(in *Inbound) *inbound {
+	headerValues := in.HeaderValues
+	if headerValues == nil {
+		headerValues = func(name string) []string {
+			if v := in.Header(name); v != "" {
+				return []string{v}
+			}
+			return nil
+		}
+	}
+	return &inbound{
+		ctx:          in.Context,
+		method:       in.Method,
+		remoteAddr:   in.RemoteAddr,
+		header:       in.Header,
+		headerValues: headerValues,
+		readBody: func(limit int64) ([]byte, error) {
+			err := checkBodySize(in.Body, limit)
+			if err != nil {
+				return nil, err
+			}
+			return in.Body, nil
+		},
+		readForm: func(limit int64) (url.Values, error) {
+			err := checkBodySize(in.Body, limit)
+			if err != nil {
+				return nil, err
+			}
+			vs, err := url.ParseQuery(string(in.Body))
+			if err != nil {
+				return nil, fmt.Errorf("parse form: %w", err)
+			}
+			return vs, nil
+		},
+		borrowed:  true,
+		toRequest: in.Request,
+	}
+}
+
+// request returns the request converted to [http.Request], for the options whose API depends on it.
+func (in *inbound) request() *http.Request {
+	if in.req == nil {
+		in.req = in.toRequest()
+	}
+	return in.req
+}
+
+// retainPayload returns a raw payload that can be retained after the request has been handled.
+func (in *inbound) retainPayload(rawPayload []byte) []byte {
+	if in.borrowed {
+		return bytes.Clone(rawPayload)
+	}
+	return rawPayload
+}
+
+// inboundResponseWriter is an [http.ResponseWriter] that records an [InboundResponse].
+type inboundResponseWriter struct {
+	resp *InboundResponse
+	body bytes.Buffer
+}
+
+func (w *inboundResponseWriter) Header() http.Header {
+	return w.resp.Header
+}
+
+func (w *inboundResponseWriter) WriteHeader(statusCode int) {
+	if w.resp.StatusCode == 0 {
+		w.resp.StatusCode = statusCode
+	}
+}
+
+func (w *inboundResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b) //nolint:wrapcheck // Not needed.
+}
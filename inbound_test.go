@@ -0,0 +1,145 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandleInbound(t *testing.T) {
+	var retained []byte
+	h := &Handler{
+		Secret: "foobar",
+		Sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+			retained = d.RawPayload
+			return nil
+		}),
+	}
+	in, converted := testNewInbound(t, "foobar", testRawPayload)
+	resp := h.HandleInbound(in)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, *converted, 0)
+	in.Body[0] = 'x'
+	assert.Equal(t, string(retained), string(testRawPayload))
+}
+
+func TestHandleInboundForm(t *testing.T) {
+	h := &Handler{
+		Secret: "foobar",
+	}
+	in, _ := testNewInbound(t, "foobar", testRawPayload)
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	in.Header = func(name string) string {
+		if v := header.Get(name); v != "" {
+			return v
+		}
+		return in.Request().Header.Get(name)
+	}
+	in.Body = []byte("payload=%7B%22foo%22%3A%22bar%22%7D")
+	resp := h.HandleInbound(in)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+}
+
+func TestHandleInboundErrorSignature(t *testing.T) {
+	var handledErr error
+	var handledReq *http.Request
+	h := &Handler{
+		Secret: "foobar",
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+			handledReq = req
+		},
+	}
+	in, converted := testNewInbound(t, "invalid", testRawPayload)
+	resp := h.HandleInbound(in)
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	assert.ErrorIs(t, handledErr, ErrInvalidSignature)
+	assert.NotZero(t, handledReq)
+	assert.Equal(t, *converted, 1)
+}
+
+func TestHandleInboundErrorMaxBodySize(t *testing.T) {
+	h := &Handler{
+		Secret:      "foobar",
+		MaxBodySize: 5,
+	}
+	in, _ := testNewInbound(t, "foobar", testRawPayload)
+	resp := h.HandleInbound(in)
+	assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+}
+
+func TestHandleInboundSourceAllowlist(t *testing.T) {
+	h := &Handler{
+		Secret: "foobar",
+		SourceAllowlist: &SourceAllowlist{
+			Prefixes:       StaticPrefixes{netip.MustParsePrefix("192.0.2.0/24")},
+			TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		},
+	}
+	for _, tc := range []struct {
+		name         string
+		forwardedFor []string
+		statusCode   int
+	}{
+		{
+			name:         "Allowed",
+			forwardedFor: []string{"198.51.100.1", "192.0.2.1"},
+			statusCode:   http.StatusOK,
+		},
+		{
+			name:         "Forbidden",
+			forwardedFor: []string{"192.0.2.1, 198.51.100.1"},
+			statusCode:   http.StatusForbidden,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			in, _ := testNewInbound(t, "foobar", testRawPayload)
+			in.RemoteAddr = "10.0.0.1:1234"
+			in.HeaderValues = func(name string) []string {
+				if name == "X-Forwarded-For" {
+					return tc.forwardedFor
+				}
+				return nil
+			}
+			resp := h.HandleInbound(in)
+			assert.Equal(t, resp.StatusCode, tc.statusCode)
+		})
+	}
+}
+
+func TestHandleInboundErrorPanic(t *testing.T) {
+	h := &Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic(errors.New("error"))
+		},
+	}
+	in, _ := testNewInbound(t, "foobar", testRawPayload)
+	resp := h.HandleInbound(in)
+	assert.Equal(t, resp.StatusCode, http.StatusInternalServerError)
+	assert.Equal(t, string(resp.Body), http.StatusText(http.StatusInternalServerError)+"\n")
+}
+
+// testNewInbound returns an [Inbound] signed with the secret, and the number of conversions to [http.Request].
+func testNewInbound(t *testing.T, secret string, rawPayload []byte) (*Inbound, *int) {
+	t.Helper()
+	ctx := context.Background()
+	req, err := (&Signer{Secret: secret}).NewRequest(ctx, "/", "push", testGetRandomDeliveryID(t), rawPayload)
+	assert.NoError(t, err)
+	converted := new(int)
+	return &Inbound{
+		Context:    ctx,
+		Method:     http.MethodPost,
+		RemoteAddr: "192.0.2.1:1234",
+		Header:     req.Header.Get,
+		Body:       append([]byte(nil), rawPayload...),
+		Request: func() *http.Request {
+			*converted++
+			return req
+		},
+	}, converted
+}
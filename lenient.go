@@ -0,0 +1,90 @@
+package githubhook
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+func getLenientHeader(header http.Header, name string) string {
+	for _, v := range getLenientHeaderValues(header, name) {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func getLenientHeaderValues(header http.Header, name string) []string {
+	vs := header.Values(name)
+	name = normalizeLenientHeaderName(name)
+	for k, kvs := range header {
+		if k != http.CanonicalHeaderKey(name) && normalizeLenientHeaderName(k) == name {
+			vs = append(vs, kvs...)
+		}
+	}
+	return vs
+}
+
+func normalizeLenientHeaderName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+func getLenientContentType(header http.Header) (string, error) {
+	var contentType string
+	for _, v := range getLenientHeaderValues(header, "Content-Type") {
+		for _, v := range strings.Split(v, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			mediaType, _, err := mime.ParseMediaType(v)
+			if err != nil {
+				return "", &RequestError{
+					StatusCode: http.StatusBadRequest,
+					Message:    "invalid content type: " + v,
+				}
+			}
+			if contentType != "" && contentType != mediaType {
+				return "", &RequestError{
+					StatusCode: http.StatusBadRequest,
+					Message:    "conflicting content types: " + contentType + ", " + mediaType,
+				}
+			}
+			contentType = mediaType
+		}
+	}
+	return contentType, nil
+}
+
+// lenientHeaderNames are the headers whose interpretation is recorded in [DeliveryMetadata.HeaderInterpretations].
+var lenientHeaderNames = []string{
+	"X-GitHub-Event",
+	"X-GitHub-Delivery",
+	"X-GitHub-Hook-ID",
+	"X-GitHub-Hook-Installation-Target-Type",
+	"X-GitHub-Hook-Installation-Target-ID",
+	"X-Hub-Signature-256",
+	"X-Hub-Signature",
+	"User-Agent",
+}
+
+// getLenientHeaderInterpretations returns the headers whose lenient value differs from the strict value, e.g. `Content-Type: "application/json; charset=utf-8" interpreted as "application/json"`.
+func getLenientHeaderInterpretations(header http.Header) []string {
+	var interpretations []string
+	add := func(name string, strict string, lenient string) {
+		if strict != lenient {
+			interpretations = append(interpretations, fmt.Sprintf("%s: %q interpreted as %q", name, strict, lenient))
+		}
+	}
+	contentType, err := getLenientContentType(header)
+	if err == nil {
+		add("Content-Type", strings.Join(getLenientHeaderValues(header, "Content-Type"), ", "), contentType)
+	}
+	for _, name := range lenientHeaderNames {
+		add(name, header.Get(name), getLenientHeader(header, name))
+	}
+	return interpretations
+}
@@ -0,0 +1,95 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerLenientHeaders(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		LenientHeaders: true,
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Del("Content-Type")
+	req.Header.Add("Content-Type", "Application/JSON; charset=utf-8")
+	req.Header.Add("Content-Type", "application/json")
+	event := req.Header.Get("X-GitHub-Event")
+	req.Header.Del("X-GitHub-Event")
+	req.Header["X_GitHub_Event"] = []string{" " + event + " "}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.SliceEqual(t, md.HeaderInterpretations, []string{
+		`Content-Type: "Application/JSON; charset=utf-8, application/json" interpreted as "application/json"`,
+		`X-GitHub-Event: "" interpreted as "push"`,
+	})
+}
+
+func TestHandlerLenientHeadersNoInterpretation(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		LenientHeaders: true,
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.SliceEmpty(t, md.HeaderInterpretations)
+}
+
+func TestHandlerLenientHeadersErrorConflictingContentTypes(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		LenientHeaders: true,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestHandlerStrictHeadersErrorContentTypeParameters(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
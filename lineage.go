@@ -0,0 +1,87 @@
+package githubhook
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Lineage headers.
+const (
+	LineageOriginalDeliveryHeader = "X-Githubhook-Original-Delivery"
+	LineageGenerationHeader       = "X-Githubhook-Generation"
+	LineageOperatorHeader         = "X-Githubhook-Operator"
+	LineageReasonHeader           = "X-Githubhook-Reason"
+)
+
+/*
+Lineage identifies a delivery that was internally generated from another one (replayed, forwarded or reprocessed), so audits can distinguish it from original GitHub traffic.
+
+It's transmitted with the lineage headers (e.g. [LineageOriginalDeliveryHeader]).
+The headers are not covered by the signature, so the lineage is informative and must not be used for authorization.
+
+Fields:
+  - OriginalDeliveryID is the ID of the original GitHub delivery.
+  - Generation is the number of times the original delivery was regenerated (1 for the first replay).
+  - Operator is the operator (person or system) that regenerated the delivery (optional).
+  - Reason is the reason of the regeneration (optional), e.g. "replay", "forward" or "quarantine release".
+*/
+type Lineage struct {
+	OriginalDeliveryID string `json:"original_delivery_id"`
+	Generation         int    `json:"generation"`
+	Operator           string `json:"operator,omitempty"`
+	Reason             string `json:"reason,omitempty"`
+}
+
+// NextLineage returns the lineage of a delivery regenerated from another one.
+//
+// The lineage of the delivery can be nil if it's an original GitHub delivery.
+func NextLineage(md *DeliveryMetadata, operator string, reason string) *Lineage {
+	if md.Lineage == nil {
+		return &Lineage{
+			OriginalDeliveryID: md.DeliveryID,
+			Generation:         1,
+			Operator:           operator,
+			Reason:             reason,
+		}
+	}
+	return &Lineage{
+		OriginalDeliveryID: md.Lineage.OriginalDeliveryID,
+		Generation:         md.Lineage.Generation + 1,
+		Operator:           operator,
+		Reason:             reason,
+	}
+}
+
+// SetHeader sets the lineage headers.
+func (l *Lineage) SetHeader(header http.Header) {
+	header.Set(LineageOriginalDeliveryHeader, l.OriginalDeliveryID)
+	header.Set(LineageGenerationHeader, strconv.Itoa(l.Generation))
+	setHeaderIfNotEmpty(header, LineageOperatorHeader, l.Operator)
+	setHeaderIfNotEmpty(header, LineageReasonHeader, l.Reason)
+}
+
+func setHeaderIfNotEmpty(header http.Header, name string, value string) {
+	if value != "" {
+		header.Set(name, value)
+	} else {
+		header.Del(name)
+	}
+}
+
+// parseLineage parses the lineage headers, and returns nil if they are missing or invalid.
+func parseLineage(header func(name string) string) *Lineage {
+	original := header(LineageOriginalDeliveryHeader)
+	if original == "" {
+		return nil
+	}
+	generation, err := strconv.Atoi(header(LineageGenerationHeader))
+	if err != nil || generation < 1 {
+		return nil
+	}
+	return &Lineage{
+		OriginalDeliveryID: original,
+		Generation:         generation,
+		Operator:           header(LineageOperatorHeader),
+		Reason:             header(LineageReasonHeader),
+	}
+}
@@ -0,0 +1,97 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestNextLineage(t *testing.T) {
+	md := &DeliveryMetadata{
+		DeliveryID: "original",
+	}
+	l := NextLineage(md, "alice", "replay")
+	assert.DeepEqual(t, l, &Lineage{
+		OriginalDeliveryID: "original",
+		Generation:         1,
+		Operator:           "alice",
+		Reason:             "replay",
+	})
+	md = &DeliveryMetadata{
+		DeliveryID: "replayed",
+		Lineage:    l,
+	}
+	l = NextLineage(md, "", "forward")
+	assert.DeepEqual(t, l, &Lineage{
+		OriginalDeliveryID: "original",
+		Generation:         2,
+		Reason:             "forward",
+	})
+}
+
+func TestHandlerLineage(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	s := &Signer{
+		Lineage: &Lineage{
+			OriginalDeliveryID: "original",
+			Generation:         1,
+			Operator:           "alice",
+			Reason:             "replay",
+		},
+	}
+	req, err := s.NewRequest(ctx, "/", "push", "replayed", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.DeepEqual(t, md.Lineage, s.Lineage)
+}
+
+func TestHandlerLineageInvalid(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	req.Header.Set(LineageOriginalDeliveryHeader, "original")
+	req.Header.Set(LineageGenerationHeader, "invalid")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Zero(t, md.Lineage)
+}
+
+func TestHandlerProcessRecordsLineage(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	r := strings.NewReader(`{"event":"push","delivery_id":"2","payload":{},"lineage":{"original_delivery_id":"1","generation":1,"reason":"replay"}}`)
+	err := h.ProcessRecords(ctx, r)
+	assert.NoError(t, err)
+	assert.DeepEqual(t, md.Lineage, &Lineage{
+		OriginalDeliveryID: "1",
+		Generation:         1,
+		Reason:             "replay",
+	})
+}
@@ -0,0 +1,231 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrMaintenanceFull is returned (wrapped in a [RequestError]) if the deliveries held by [Maintenance] reached [Maintenance.Size].
+var ErrMaintenanceFull = errors.New("maintenance full")
+
+// DefaultMaintenanceSize is the default value of [Maintenance.Size].
+const DefaultMaintenanceSize = 10000
+
+/*
+Maintenance pauses the processing of the deliveries, e.g. during the maintenance of a downstream system.
+
+While it's enabled, the deliveries are still verified, deduplicated and stored in [Handler.Store] (with the pending status), but they are held instead of being delivered, and they are acknowledged with a 202 response.
+When it's disabled, the held deliveries are resumed automatically in the background, in the order of reception: they are delivered with [Handler.Delivery] (or [Handler.Sink]), or queued in [Handler.Async], and their status is updated in [Handler.Store].
+The deliveries received while the held deliveries are resumed are held too, so the order is preserved.
+The errors of the resumed deliveries are reported to [Handler.Error], with a nil request.
+
+The held deliveries are kept in memory: they are lost if the process stops, but they can be redelivered from [Handler.Store] (see [Handler.Redeliver]).
+
+Fields:
+  - Size is the maximum number of held deliveries (default: [DefaultMaintenanceSize]). When it's reached, the deliveries are rejected with a 503 response, so GitHub can redeliver them. If it's negative, the number is not limited.
+
+It can be toggled with the [Admin] API.
+
+The zero value is ready to use (disabled).
+*/
+type Maintenance struct {
+	Size int
+
+	mu       sync.Mutex
+	enabled  bool
+	since    time.Time
+	reason   string
+	held     []*heldDelivery
+	resuming bool
+	resumed  *sync.Cond
+}
+
+// heldDelivery is a delivery held by [Maintenance], with the handler that resumes it.
+type heldDelivery struct {
+	ctx        context.Context
+	h          *Handler
+	md         *DeliveryMetadata
+	rawPayload []byte
+}
+
+/*
+MaintenanceStatus is the status of a [Maintenance].
+
+Fields:
+  - Enabled is true if the maintenance mode is enabled.
+  - Since is the time when it has been enabled.
+  - Reason is the reason given to [Maintenance.Enable].
+  - Held is the number of held deliveries, that are not resumed yet.
+*/
+type MaintenanceStatus struct {
+	Enabled bool      `json:"enabled"`
+	Since   time.Time `json:"since"`
+	Reason  string    `json:"reason,omitempty"`
+	Held    int       `json:"held"`
+}
+
+// Enable enables the maintenance mode, with an optional reason (e.g. "database migration").
+//
+// If it's already enabled, the reason is updated.
+func (m *Maintenance) Enable(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.enabled {
+		m.enabled = true
+		m.since = time.Now()
+	}
+	m.reason = reason
+}
+
+// Disable disables the maintenance mode, and resumes the held deliveries in the background (see [Maintenance.Wait]).
+func (m *Maintenance) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = false
+	m.since = time.Time{}
+	m.reason = ""
+	if len(m.held) > 0 && !m.resuming {
+		m.resuming = true
+		go m.resume()
+	}
+}
+
+// Status returns the status.
+func (m *Maintenance) Status() MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MaintenanceStatus{
+		Enabled: m.enabled,
+		Since:   m.since,
+		Reason:  m.reason,
+		Held:    len(m.held),
+	}
+}
+
+// Wait waits until the held deliveries are resumed, or the maintenance mode is enabled again, or the context is done.
+//
+// It allows to wait for the resumption before a shutdown.
+func (m *Maintenance) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for m.resuming && ctx.Err() == nil {
+			m.getResumed().Wait()
+		}
+	}()
+	stop := context.AfterFunc(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.getResumed().Broadcast()
+	})
+	defer stop()
+	<-done
+	err := ctx.Err()
+	if err != nil {
+		return fmt.Errorf("maintenance: %w", err)
+	}
+	return nil
+}
+
+// getResumed returns the condition signaled when the resumption is stopped. The lock must be held.
+func (m *Maintenance) getResumed() *sync.Cond {
+	if m.resumed == nil {
+		m.resumed = sync.NewCond(&m.mu)
+	}
+	return m.resumed
+}
+
+func (m *Maintenance) getSize() int {
+	if m.Size == 0 {
+		return DefaultMaintenanceSize
+	}
+	return m.Size
+}
+
+// hold holds the delivery if the maintenance mode is enabled, or if the held deliveries are being resumed.
+func (m *Maintenance) hold(ctx context.Context, h *Handler, md *DeliveryMetadata, rawPayload []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.enabled && len(m.held) == 0 {
+		return false, nil
+	}
+	size := m.getSize()
+	if size >= 0 && len(m.held) >= size {
+		return false, &RequestError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "maintenance: too many held deliveries",
+			Err:        ErrMaintenanceFull,
+		}
+	}
+	m.held = append(m.held, &heldDelivery{
+		ctx:        context.WithoutCancel(ctx),
+		h:          h,
+		md:         md,
+		rawPayload: rawPayload,
+	})
+	return true, nil
+}
+
+// resume resumes the held deliveries, until there are none or the maintenance mode is enabled again.
+func (m *Maintenance) resume() {
+	for {
+		d := m.next()
+		if d == nil {
+			return
+		}
+		d.h.resumeDelivery(d.ctx, d.md, d.rawPayload)
+	}
+}
+
+// next returns the next held delivery to resume, or nil and stops the resumption.
+func (m *Maintenance) next() *heldDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.enabled || len(m.held) == 0 {
+		m.resuming = false
+		m.getResumed().Broadcast()
+		return nil
+	}
+	d := m.held[0]
+	m.held[0] = nil
+	m.held = m.held[1:]
+	return d
+}
+
+// hold holds the delivery in [Handler.Maintenance], if the maintenance mode is enabled.
+func (h *Handler) hold(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (bool, error) {
+	if h.Maintenance == nil {
+		return false, nil
+	}
+	return h.Maintenance.hold(ctx, h, md, rawPayload)
+}
+
+// resumeDelivery delivers a delivery held by [Maintenance], and updates its status in [Handler.Store].
+func (h *Handler) resumeDelivery(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) {
+	statusCode, err := h.resumeDeliveryTask(ctx, md, rawPayload)
+	if statusCode == http.StatusAccepted {
+		return
+	}
+	h.updateStoredDelivery(ctx, md, rawPayload, err)
+	if err == nil {
+		return
+	}
+	err = fmt.Errorf("maintenance resume: %w", err)
+	if forgetErr := h.forgetDuplicate(ctx, md); forgetErr != nil {
+		err = errors.Join(err, forgetErr)
+	}
+	if h.Error != nil {
+		h.Error(ctx, err, nil)
+	}
+}
+
+func (h *Handler) resumeDeliveryTask(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (statusCode int, err error) {
+	defer recoverPanic(&err)
+	return h.deliverNow(ctx, md, rawPayload)
+}
@@ -0,0 +1,173 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestMaintenance(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var delivered []string
+	m := new(Maintenance)
+	s := NewMemoryStore(10)
+	h := &Handler{
+		Maintenance: m,
+		Store:       s,
+		Dedup:       NewMemoryDedupStore(10, time.Hour),
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delivered = append(delivered, md.DeliveryID)
+			return nil
+		},
+	}
+	m.Enable("migration")
+	status := m.Status()
+	assert.True(t, status.Enabled)
+	assert.Equal(t, status.Reason, "migration")
+	assert.NotZero(t, status.Since)
+	for _, id := range []string{"1", "2"} {
+		w := testServeMaintenance(t, h, id)
+		assert.Equal(t, w.Code, http.StatusAccepted)
+	}
+	w := testServeMaintenance(t, h, "1")
+	assert.Equal(t, w.Code, http.StatusOK) // Duplicate.
+	assert.Equal(t, m.Status().Held, 2)
+	assert.SliceEmpty(t, delivered)
+	d, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusPending)
+	m.Disable()
+	err = m.Wait(ctx)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, delivered, []string{"1", "2"})
+	assert.Equal(t, m.Status(), MaintenanceStatus{})
+	d, err = s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusSucceeded)
+	w = testServeMaintenance(t, h, "3")
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestMaintenanceResumeError(t *testing.T) {
+	ctx := context.Background()
+	var handledErr error
+	done := make(chan struct{})
+	m := new(Maintenance)
+	s := NewMemoryStore(10)
+	dedup := NewMemoryDedupStore(10, time.Hour)
+	h := &Handler{
+		Maintenance: m,
+		Store:       s,
+		Dedup:       dedup,
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic(errors.New("error"))
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+			close(done)
+		},
+	}
+	m.Enable("")
+	w := testServeMaintenance(t, h, "1")
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	m.Disable()
+	<-done
+	err := m.Wait(ctx)
+	assert.NoError(t, err)
+	var panicErr *PanicError
+	assert.ErrorAs(t, handledErr, &panicErr)
+	d, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusFailed)
+	entries, err := dedup.ListSeen(ctx)
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, entries)
+}
+
+func TestMaintenanceFull(t *testing.T) {
+	var handledErr error
+	h := &Handler{
+		Maintenance: &Maintenance{
+			Size: 1,
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	h.Maintenance.Enable("")
+	w := testServeMaintenance(t, h, "1")
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	w = testServeMaintenance(t, h, "2")
+	assert.Equal(t, w.Code, http.StatusServiceUnavailable)
+	assert.ErrorIs(t, handledErr, ErrMaintenanceFull)
+}
+
+func TestMaintenanceEnableAgain(t *testing.T) {
+	ctx := context.Background()
+	m := new(Maintenance)
+	release := make(chan struct{})
+	var delivered []string
+	h := &Handler{
+		Maintenance: m,
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			<-release
+			delivered = append(delivered, md.DeliveryID)
+			return nil
+		},
+	}
+	m.Enable("")
+	for _, id := range []string{"1", "2"} {
+		w := testServeMaintenance(t, h, id)
+		assert.Equal(t, w.Code, http.StatusAccepted)
+	}
+	m.Disable()
+	m.Enable("again")
+	close(release)
+	err := m.Wait(ctx)
+	assert.NoError(t, err)
+	assert.True(t, len(delivered) <= 1)
+	assert.Equal(t, m.Status().Held, 2-len(delivered))
+	m.Disable()
+	err = m.Wait(ctx)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, delivered, []string{"1", "2"})
+}
+
+func TestMaintenanceWaitContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := new(Maintenance)
+	release := make(chan struct{})
+	defer close(release)
+	h := &Handler{
+		Maintenance: m,
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			<-release
+			return nil
+		},
+	}
+	m.Enable("")
+	w := testServeMaintenance(t, h, "1")
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	m.Disable()
+	cancel()
+	err := m.Wait(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func testServeMaintenance(t *testing.T, h *Handler, deliveryID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := new(Signer).NewRequest(context.Background(), "/", "push", deliveryID, testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
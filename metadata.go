@@ -0,0 +1,60 @@
+package githubhook
+
+import (
+	"time"
+)
+
+/*
+DeliveryMetadata contains the metadata of a delivery, parsed from the request headers.
+
+Fields:
+  - Event is the event name (X-GitHub-Event).
+  - DeliveryID is the delivery GUID (X-GitHub-Delivery).
+  - HookID is the ID of the webhook (X-GitHub-Hook-ID).
+  - InstallationTargetType is the type of resource where the webhook was created (X-GitHub-Hook-Installation-Target-Type).
+  - InstallationTargetID is the ID of resource where the webhook was created (X-GitHub-Hook-Installation-Target-ID).
+  - UserAgent is the user agent (User-Agent), e.g. "GitHub-Hookshot/044aadd".
+  - ReceivedAt is the time when the request was received.
+  - Lineage is the lineage of a delivery regenerated from another one (lineage headers), or nil for an original GitHub delivery.
+  - SignatureHeaders are the signature headers verified by [Handler] (e.g. X-Hub-Signature-256), see [Handler.SignaturePolicy]. It's empty if the signature is not verified (no secret, or [Handler.TrustedSources]).
+  - HeaderInterpretations are the headers interpreted differently than in strict mode, with [Handler.LenientHeaders], for debugging (e.g. `Content-Type: "application/json; charset=utf-8" interpreted as "application/json"`).
+  - Annotations are the annotations written by the stages of the pipeline, for the next stages. They are shared by the copies of the metadata (e.g. in [VerifiedDelivery]), and stored with the delivery (see [Handler.Store]).
+
+Optional headers are empty if they are not present.
+*/
+type DeliveryMetadata struct {
+	Event                  string
+	DeliveryID             string
+	HookID                 string
+	InstallationTargetType string
+	InstallationTargetID   string
+	UserAgent              string
+	ReceivedAt             time.Time
+	Lineage                *Lineage
+	SignatureHeaders       []string
+	HeaderInterpretations  []string
+	Annotations            *Annotations
+}
+
+func (h *Handler) newDeliveryMetadata(event string, deliveryID string, in *inbound) *DeliveryMetadata {
+	return NewDeliveryMetadata(event, deliveryID, func(name string) string {
+		return h.getHeader(name, in)
+	})
+}
+
+// NewDeliveryMetadata creates a new [DeliveryMetadata] received now, with the optional headers returned by the header function.
+//
+// It allows to create the metadata without [http.Request], e.g. in an adapter for another HTTP server.
+func NewDeliveryMetadata(event string, deliveryID string, header func(name string) string) *DeliveryMetadata {
+	return &DeliveryMetadata{
+		Event:                  event,
+		DeliveryID:             deliveryID,
+		HookID:                 header("X-GitHub-Hook-ID"),
+		InstallationTargetType: header("X-GitHub-Hook-Installation-Target-Type"),
+		InstallationTargetID:   header("X-GitHub-Hook-Installation-Target-ID"),
+		UserAgent:              header("User-Agent"),
+		ReceivedAt:             time.Now(),
+		Lineage:                parseLineage(header),
+		Annotations:            new(Annotations),
+	}
+}
@@ -0,0 +1,42 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerDeliveryMetadata(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("X-GitHub-Hook-ID", "123")
+	req.Header.Set("X-GitHub-Hook-Installation-Target-Type", "repository")
+	req.Header.Set("X-GitHub-Hook-Installation-Target-ID", "456")
+	req.Header.Set("User-Agent", "GitHub-Hookshot/044aadd")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.NotZero(t, md)
+	assert.Equal(t, md.Event, "push")
+	assert.Equal(t, md.DeliveryID, req.Header.Get("X-GitHub-Delivery"))
+	assert.Equal(t, md.HookID, "123")
+	assert.Equal(t, md.InstallationTargetType, "repository")
+	assert.Equal(t, md.InstallationTargetID, "456")
+	assert.Equal(t, md.UserAgent, "GitHub-Hookshot/044aadd")
+	assert.NotZero(t, md.ReceivedAt)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	costSecondsDesc = prometheus.NewDesc(
+		"githubhook_processing_seconds_total",
+		"Processing time of the deliveries, by event, tenant and stage.",
+		[]string{"event", "tenant", "stage"}, nil,
+	)
+	costCountDesc = prometheus.NewDesc(
+		"githubhook_processing_total",
+		"Number of processed deliveries, by event, tenant and stage.",
+		[]string{"event", "tenant", "stage"}, nil,
+	)
+)
+
+/*
+CostCollector is a [prometheus.Collector] that exposes the processing costs accounted by [githubhook.Costs].
+
+Metrics:
+  - githubhook_processing_seconds_total: processing time of the deliveries, by event, tenant and stage
+  - githubhook_processing_total: processed deliveries, by event, tenant and stage
+
+It must be registered with a [prometheus.Registerer].
+*/
+type CostCollector struct {
+	Costs *githubhook.Costs
+}
+
+// Describe implements [prometheus.Collector].
+func (c *CostCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- costSecondsDesc
+	ch <- costCountDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *CostCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, cost := range c.Costs.Costs() {
+		ch <- prometheus.MustNewConstMetric(costSecondsDesc, prometheus.CounterValue, cost.Total.Seconds(), cost.Event, cost.Tenant, cost.Stage)
+		ch <- prometheus.MustNewConstMetric(costCountDesc, prometheus.CounterValue, float64(cost.Count), cost.Event, cost.Tenant, cost.Stage)
+	}
+}
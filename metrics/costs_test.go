@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCostCollector(t *testing.T) {
+	costs := githubhook.NewCosts(func(md *githubhook.DeliveryMetadata) string {
+		return md.HookID
+	})
+	md := &githubhook.DeliveryMetadata{
+		Event:  "push",
+		HookID: "123",
+	}
+	costs.Record(md, githubhook.CostStageDecode, 500*time.Millisecond)
+	costs.Record(md, githubhook.CostStageDecode, 500*time.Millisecond)
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(&CostCollector{Costs: costs})
+	assert.NoError(t, err)
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP githubhook_processing_seconds_total Processing time of the deliveries, by event, tenant and stage.
+# TYPE githubhook_processing_seconds_total counter
+githubhook_processing_seconds_total{event="push",stage="decode",tenant="123"} 1
+# HELP githubhook_processing_total Number of processed deliveries, by event, tenant and stage.
+# TYPE githubhook_processing_total counter
+githubhook_processing_total{event="push",stage="decode",tenant="123"} 2
+`))
+	assert.NoError(t, err)
+}
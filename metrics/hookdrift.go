@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	hookDriftedDesc = prometheus.NewDesc(
+		"githubhook_hook_drifted",
+		"1 if the last check of the webhook found a drift, 0 otherwise.",
+		nil, nil,
+	)
+	hookDriftDesc = prometheus.NewDesc(
+		"githubhook_hook_drift",
+		"Drifted fields of the webhook, found by the last check.",
+		[]string{"field"}, nil,
+	)
+	hookDriftRepairsDesc = prometheus.NewDesc(
+		"githubhook_hook_drift_repairs_total",
+		"Number of repairs of the webhook.",
+		nil, nil,
+	)
+	hookDriftLastCheckDesc = prometheus.NewDesc(
+		"githubhook_hook_drift_last_check_timestamp_seconds",
+		"Time of the last successful check of the webhook.",
+		nil, nil,
+	)
+)
+
+/*
+HookDriftCollector is a [prometheus.Collector] that exposes the report of a [githubhook.HookDriftDetector].
+
+Metrics:
+  - githubhook_hook_drifted: 1 if the last check found a drift, 0 otherwise
+  - githubhook_hook_drift: 1 for each drifted field, by field (e.g. "active")
+  - githubhook_hook_drift_repairs_total: number of repairs
+  - githubhook_hook_drift_last_check_timestamp_seconds: time of the last successful check (not exposed before the first check)
+
+It must be registered with a [prometheus.Registerer].
+*/
+type HookDriftCollector struct {
+	Detector *githubhook.HookDriftDetector
+}
+
+// Describe implements [prometheus.Collector].
+func (c *HookDriftCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hookDriftedDesc
+	ch <- hookDriftDesc
+	ch <- hookDriftRepairsDesc
+	ch <- hookDriftLastCheckDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *HookDriftCollector) Collect(ch chan<- prometheus.Metric) {
+	r := c.Detector.Report()
+	var drifted float64
+	if len(r.Drifts) > 0 {
+		drifted = 1
+	}
+	ch <- prometheus.MustNewConstMetric(hookDriftedDesc, prometheus.GaugeValue, drifted)
+	for _, d := range r.Drifts {
+		ch <- prometheus.MustNewConstMetric(hookDriftDesc, prometheus.GaugeValue, 1, d.Field)
+	}
+	ch <- prometheus.MustNewConstMetric(hookDriftRepairsDesc, prometheus.CounterValue, float64(r.Repairs))
+	if !r.CheckedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(hookDriftLastCheckDesc, prometheus.GaugeValue, float64(r.CheckedAt.UnixNano())/1e9)
+	}
+}
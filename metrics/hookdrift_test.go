@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHookDriftCollector(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"id":1,"active":false,"events":["push"],"config":{"url":"https://example.com/webhook","content_type":"form"}}`))
+	}))
+	defer api.Close()
+	d := &githubhook.HookDriftDetector{
+		Client: &githubhook.HookClient{
+			URL:    api.URL + "/hooks/1",
+			Client: api.Client(),
+		},
+		Config: githubhook.HookConfig{
+			ContentType: githubhook.HookContentTypeJSON,
+		},
+	}
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(&HookDriftCollector{Detector: d})
+	assert.NoError(t, err)
+	n, err := testutil.GatherAndCount(reg, "githubhook_hook_drift_last_check_timestamp_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+	_, err = d.Check(context.Background())
+	assert.NoError(t, err)
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP githubhook_hook_drift Drifted fields of the webhook, found by the last check.
+# TYPE githubhook_hook_drift gauge
+githubhook_hook_drift{field="active"} 1
+githubhook_hook_drift{field="content_type"} 1
+# HELP githubhook_hook_drift_repairs_total Number of repairs of the webhook.
+# TYPE githubhook_hook_drift_repairs_total counter
+githubhook_hook_drift_repairs_total 0
+# HELP githubhook_hook_drifted 1 if the last check of the webhook found a drift, 0 otherwise.
+# TYPE githubhook_hook_drifted gauge
+githubhook_hook_drifted 1
+`), "githubhook_hook_drift", "githubhook_hook_drift_repairs_total", "githubhook_hook_drifted")
+	assert.NoError(t, err)
+	n, err = testutil.GatherAndCount(reg, "githubhook_hook_drift_last_check_timestamp_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, n, 1)
+}
@@ -0,0 +1,115 @@
+// Package metrics provides a [Prometheus] [githubhook.Observer], and collectors of [githubhook.Costs], [githubhook.SLA], [githubhook.HookDriftDetector], [githubhook.Quarantine] and [githubhook.Quota].
+//
+// [Prometheus]: https://prometheus.io
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+Observer is a [githubhook.Observer] that collects Prometheus metrics.
+
+Metrics:
+  - githubhook_requests_total: received requests, by event and response status code
+  - githubhook_signature_failures_total: requests with a missing or invalid signature, by event
+  - githubhook_decode_failures_total: requests with a payload that can't be decoded, by event
+  - githubhook_body_read_aborted_total: requests aborted by the client while the body was read (see [githubhook.ErrBodyReadAborted])
+  - githubhook_delivery_duration_seconds: duration of [githubhook.Handler.Delivery], by event and result ("success" or "error")
+
+The event label is empty for the requests that have not been verified, and "other" for the events that are not in the event catalog (see [githubhook.IsKnownEvent]), so the number of series is bounded.
+
+It must be created with [New], and registered with a [prometheus.Registerer].
+*/
+type Observer struct {
+	requests          *prometheus.CounterVec
+	signatureFailures *prometheus.CounterVec
+	decodeFailures    *prometheus.CounterVec
+	bodyReadAborted   prometheus.Counter
+	deliveryDuration  *prometheus.HistogramVec
+}
+
+// New creates a new [Observer].
+func New() *Observer {
+	return &Observer{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "githubhook_requests_total",
+			Help: "Number of received requests, by event and response status code.",
+		}, []string{"event", "code"}),
+		signatureFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "githubhook_signature_failures_total",
+			Help: "Number of requests with a missing or invalid signature, by event.",
+		}, []string{"event"}),
+		decodeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "githubhook_decode_failures_total",
+			Help: "Number of requests with a payload that can't be decoded, by event.",
+		}, []string{"event"}),
+		bodyReadAborted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "githubhook_body_read_aborted_total",
+			Help: "Number of requests aborted by the client while the body was read.",
+		}),
+		deliveryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "githubhook_delivery_duration_seconds",
+			Help:    "Duration of the delivery callback, by event and result.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"event", "result"}),
+	}
+}
+
+// ObserveRequest implements [githubhook.Observer].
+func (o *Observer) ObserveRequest(ctx context.Context, event string, statusCode int, err error) {
+	event = eventLabel(event)
+	o.requests.WithLabelValues(event, strconv.Itoa(statusCode)).Inc()
+	if errors.Is(err, githubhook.ErrInvalidSignature) {
+		o.signatureFailures.WithLabelValues(event).Inc()
+	}
+	if errors.Is(err, githubhook.ErrPayloadDecode) {
+		o.decodeFailures.WithLabelValues(event).Inc()
+	}
+	if errors.Is(err, githubhook.ErrBodyReadAborted) {
+		o.bodyReadAborted.Inc()
+	}
+}
+
+// ObserveDelivery implements [githubhook.Observer].
+func (o *Observer) ObserveDelivery(ctx context.Context, md *githubhook.DeliveryMetadata, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	o.deliveryDuration.WithLabelValues(eventLabel(md.Event), result).Observe(duration.Seconds())
+}
+
+// otherEvent is the label of the events that are not in the event catalog.
+const otherEvent = "other"
+
+func eventLabel(event string) string {
+	if event == "" || githubhook.IsKnownEvent(event) {
+		return event
+	}
+	return otherEvent
+}
+
+// Describe implements [prometheus.Collector].
+func (o *Observer) Describe(ch chan<- *prometheus.Desc) {
+	o.requests.Describe(ch)
+	o.signatureFailures.Describe(ch)
+	o.decodeFailures.Describe(ch)
+	o.bodyReadAborted.Describe(ch)
+	o.deliveryDuration.Describe(ch)
+}
+
+// Collect implements [prometheus.Collector].
+func (o *Observer) Collect(ch chan<- prometheus.Metric) {
+	o.requests.Collect(ch)
+	o.signatureFailures.Collect(ch)
+	o.decodeFailures.Collect(ch)
+	o.bodyReadAborted.Collect(ch)
+	o.deliveryDuration.Collect(ch)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+	o := New()
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(o)
+	assert.NoError(t, err)
+	h := &githubhook.Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			return nil
+		},
+		Observer: o,
+	}
+	for _, tc := range []struct {
+		secret     string
+		event      string
+		rawPayload string
+	}{
+		{secret: "foobar", event: "push", rawPayload: `{"foo":"bar"}`},
+		{secret: "invalid", event: "push", rawPayload: `{"foo":"bar"}`},
+		{secret: "invalid", event: "random1", rawPayload: `{"foo":"bar"}`},
+		{secret: "foobar", event: "push", rawPayload: `invalid`},
+		{secret: "foobar", event: "random2", rawPayload: `{"foo":"bar"}`},
+		{secret: "foobar", event: "random3", rawPayload: `{"foo":"bar"}`},
+	} {
+		s := &githubhook.Signer{
+			Secret: tc.secret,
+		}
+		req, err := s.NewRequest(ctx, "/", tc.event, "", []byte(tc.rawPayload))
+		assert.NoError(t, err)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP githubhook_requests_total Number of received requests, by event and response status code.
+# TYPE githubhook_requests_total counter
+githubhook_requests_total{code="200",event="other"} 2
+githubhook_requests_total{code="200",event="push"} 1
+githubhook_requests_total{code="400",event=""} 2
+githubhook_requests_total{code="400",event="push"} 1
+# HELP githubhook_signature_failures_total Number of requests with a missing or invalid signature, by event.
+# TYPE githubhook_signature_failures_total counter
+githubhook_signature_failures_total{event=""} 2
+# HELP githubhook_decode_failures_total Number of requests with a payload that can't be decoded, by event.
+# TYPE githubhook_decode_failures_total counter
+githubhook_decode_failures_total{event="push"} 1
+`), "githubhook_requests_total", "githubhook_signature_failures_total", "githubhook_decode_failures_total")
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.CollectAndCount(o, "githubhook_delivery_duration_seconds"), 2)
+}
+
+func TestObserverBodyReadAborted(t *testing.T) {
+	o := New()
+	h := &githubhook.Handler{
+		Secret:   "foobar",
+		Observer: o,
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", iotest.ErrReader(io.ErrUnexpectedEOF))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Equal(t, testutil.ToFloat64(o.bodyReadAborted), 1)
+}
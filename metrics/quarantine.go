@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	quarantinedDesc = prometheus.NewDesc(
+		"githubhook_quarantined_deliveries",
+		"Number of quarantined deliveries.",
+		nil, nil,
+	)
+	quarantineDroppedDesc = prometheus.NewDesc(
+		"githubhook_quarantine_dropped_total",
+		"Number of deliveries evicted because the quarantine was full.",
+		nil, nil,
+	)
+)
+
+/*
+QuarantineCollector is a [prometheus.Collector] that exposes the state of a [githubhook.Quarantine].
+
+Metrics:
+  - githubhook_quarantined_deliveries: number of quarantined deliveries
+  - githubhook_quarantine_dropped_total: number of deliveries evicted because the quarantine was full (see [githubhook.Quarantine.Size])
+
+It must be registered with a [prometheus.Registerer].
+*/
+type QuarantineCollector struct {
+	Quarantine *githubhook.Quarantine
+}
+
+// Describe implements [prometheus.Collector].
+func (c *QuarantineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- quarantinedDesc
+	ch <- quarantineDroppedDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *QuarantineCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(quarantinedDesc, prometheus.GaugeValue, float64(c.Quarantine.Len()))
+	ch <- prometheus.MustNewConstMetric(quarantineDroppedDesc, prometheus.CounterValue, float64(c.Quarantine.Dropped()))
+}
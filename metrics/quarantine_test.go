@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQuarantineCollector(t *testing.T) {
+	q := &githubhook.Quarantine{
+		Size: 1,
+	}
+	h := &githubhook.Handler{
+		Quarantine: q,
+		SoftChecks: []githubhook.SoftCheck{
+			githubhook.CheckKnownEvent(),
+		},
+	}
+	for _, id := range []string{"1", "2"} {
+		req, err := new(githubhook.Signer).NewRequest(context.Background(), "/", "unknown", id, []byte(`{}`))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(&QuarantineCollector{Quarantine: q})
+	assert.NoError(t, err)
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP githubhook_quarantine_dropped_total Number of deliveries evicted because the quarantine was full.
+# TYPE githubhook_quarantine_dropped_total counter
+githubhook_quarantine_dropped_total 1
+# HELP githubhook_quarantined_deliveries Number of quarantined deliveries.
+# TYPE githubhook_quarantined_deliveries gauge
+githubhook_quarantined_deliveries 1
+`))
+	assert.NoError(t, err)
+}
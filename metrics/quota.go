@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	quotaDeliveriesDesc = prometheus.NewDesc(
+		"githubhook_quota_deliveries",
+		"Number of deliveries of the repository in the current quota window.",
+		[]string{"repository"}, nil,
+	)
+	quotaLimitDesc = prometheus.NewDesc(
+		"githubhook_quota_limit",
+		"Number of deliveries allowed for the repository per quota window (0 if not limited).",
+		[]string{"repository"}, nil,
+	)
+	quotaExceededDesc = prometheus.NewDesc(
+		"githubhook_quota_exceeded_total",
+		"Number of deliveries that exceeded the quota of their repository.",
+		nil, nil,
+	)
+)
+
+/*
+QuotaCollector is a [prometheus.Collector] that exposes the usage of a [githubhook.Quota].
+
+Metrics:
+  - githubhook_quota_deliveries: number of deliveries in the current window, by repository
+  - githubhook_quota_limit: quota of the repositories that have deliveries in the current window, by repository
+  - githubhook_quota_exceeded_total: number of deliveries that exceeded the quota
+
+The series of a repository are only exposed while it has deliveries in the current window, so the number of series is bounded by the number of active repositories.
+
+It must be registered with a [prometheus.Registerer].
+*/
+type QuotaCollector struct {
+	Quota *githubhook.Quota
+}
+
+// Describe implements [prometheus.Collector].
+func (c *QuotaCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- quotaDeliveriesDesc
+	ch <- quotaLimitDesc
+	ch <- quotaExceededDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *QuotaCollector) Collect(ch chan<- prometheus.Metric) {
+	_, usage := c.Quota.Usage()
+	for _, u := range usage {
+		ch <- prometheus.MustNewConstMetric(quotaDeliveriesDesc, prometheus.GaugeValue, float64(u.Count), u.Repository)
+		ch <- prometheus.MustNewConstMetric(quotaLimitDesc, prometheus.GaugeValue, float64(u.Limit), u.Repository)
+	}
+	ch <- prometheus.MustNewConstMetric(quotaExceededDesc, prometheus.CounterValue, float64(c.Quota.Exceeded()))
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQuotaCollector(t *testing.T) {
+	q := &githubhook.Quota{
+		Limit:  1,
+		Action: githubhook.QuotaActionThrottle,
+	}
+	h := &githubhook.Handler{
+		Quota: q,
+	}
+	for _, tc := range []struct {
+		repository string
+		statusCode int
+	}{
+		{"octocat/a", http.StatusOK},
+		{"octocat/a", http.StatusTooManyRequests},
+		{"octocat/b", http.StatusOK},
+	} {
+		req, err := new(githubhook.Signer).NewRequest(context.Background(), "/", "push", "", []byte(`{"repository":{"full_name":"`+tc.repository+`"}}`))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, tc.statusCode)
+	}
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(&QuotaCollector{Quota: q})
+	assert.NoError(t, err)
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP githubhook_quota_deliveries Number of deliveries of the repository in the current quota window.
+# TYPE githubhook_quota_deliveries gauge
+githubhook_quota_deliveries{repository="octocat/a"} 2
+githubhook_quota_deliveries{repository="octocat/b"} 1
+# HELP githubhook_quota_exceeded_total Number of deliveries that exceeded the quota of their repository.
+# TYPE githubhook_quota_exceeded_total counter
+githubhook_quota_exceeded_total 1
+# HELP githubhook_quota_limit Number of deliveries allowed for the repository per quota window (0 if not limited).
+# TYPE githubhook_quota_limit gauge
+githubhook_quota_limit{repository="octocat/a"} 1
+githubhook_quota_limit{repository="octocat/b"} 1
+`))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	slaComplianceDesc = prometheus.NewDesc(
+		"githubhook_sla_compliance_ratio",
+		"Ratio of compliant deliveries in the rolling window of the SLA.",
+		nil, nil,
+	)
+	slaObjectiveDesc = prometheus.NewDesc(
+		"githubhook_sla_objective_ratio",
+		"Objective of the SLA.",
+		nil, nil,
+	)
+	slaDeliveriesDesc = prometheus.NewDesc(
+		"githubhook_sla_deliveries",
+		"Number of deliveries in the rolling window of the SLA, by compliance.",
+		[]string{"compliant"}, nil,
+	)
+	slaBreachedDesc = prometheus.NewDesc(
+		"githubhook_sla_breached",
+		"1 if the SLA is breached, 0 otherwise.",
+		nil, nil,
+	)
+)
+
+/*
+SLACollector is a [prometheus.Collector] that exposes the compliance report of a [githubhook.SLA].
+
+Metrics:
+  - githubhook_sla_compliance_ratio: ratio of compliant deliveries in the rolling window
+  - githubhook_sla_objective_ratio: objective of the SLA
+  - githubhook_sla_deliveries: deliveries in the rolling window, by compliance ("true" or "false")
+  - githubhook_sla_breached: 1 if the SLA is breached, 0 otherwise
+
+It must be registered with a [prometheus.Registerer].
+*/
+type SLACollector struct {
+	SLA *githubhook.SLA
+}
+
+// Describe implements [prometheus.Collector].
+func (c *SLACollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- slaComplianceDesc
+	ch <- slaObjectiveDesc
+	ch <- slaDeliveriesDesc
+	ch <- slaBreachedDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *SLACollector) Collect(ch chan<- prometheus.Metric) {
+	r := c.SLA.Report()
+	ch <- prometheus.MustNewConstMetric(slaComplianceDesc, prometheus.GaugeValue, r.Compliance)
+	ch <- prometheus.MustNewConstMetric(slaObjectiveDesc, prometheus.GaugeValue, r.Objective)
+	ch <- prometheus.MustNewConstMetric(slaDeliveriesDesc, prometheus.GaugeValue, float64(r.Compliant), "true")
+	ch <- prometheus.MustNewConstMetric(slaDeliveriesDesc, prometheus.GaugeValue, float64(r.Total-r.Compliant), "false")
+	var breached float64
+	if r.Breached {
+		breached = 1
+	}
+	ch <- prometheus.MustNewConstMetric(slaBreachedDesc, prometheus.GaugeValue, breached)
+}
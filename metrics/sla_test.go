@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSLACollector(t *testing.T) {
+	ctx := context.Background()
+	sla := githubhook.NewSLA(0.9, time.Second, time.Minute)
+	sla.Record(ctx, 100*time.Millisecond, true)
+	sla.Record(ctx, 2*time.Second, true)
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(&SLACollector{SLA: sla})
+	assert.NoError(t, err)
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP githubhook_sla_breached 1 if the SLA is breached, 0 otherwise.
+# TYPE githubhook_sla_breached gauge
+githubhook_sla_breached 1
+# HELP githubhook_sla_compliance_ratio Ratio of compliant deliveries in the rolling window of the SLA.
+# TYPE githubhook_sla_compliance_ratio gauge
+githubhook_sla_compliance_ratio 0.5
+# HELP githubhook_sla_deliveries Number of deliveries in the rolling window of the SLA, by compliance.
+# TYPE githubhook_sla_deliveries gauge
+githubhook_sla_deliveries{compliant="false"} 1
+githubhook_sla_deliveries{compliant="true"} 1
+# HELP githubhook_sla_objective_ratio Objective of the SLA.
+# TYPE githubhook_sla_objective_ratio gauge
+githubhook_sla_objective_ratio 0.9
+`))
+	assert.NoError(t, err)
+}
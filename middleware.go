@@ -0,0 +1,70 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// VerifiedDelivery is a delivery verified by [Handler.Middleware].
+type VerifiedDelivery struct {
+	DeliveryMetadata
+	RawPayload []byte
+	Payload    any
+}
+
+type verifiedDeliveryContextKey struct{}
+
+// VerifiedDeliveryFromContext returns the [VerifiedDelivery] injected by [Handler.Middleware] in the request context.
+func VerifiedDeliveryFromContext(ctx context.Context) (*VerifiedDelivery, bool) {
+	d, ok := ctx.Value(verifiedDeliveryContextKey{}).(*VerifiedDelivery)
+	return d, ok
+}
+
+// Middleware returns an [http.Handler] that verifies the deliveries, and calls next with the verified delivery.
+//
+// It only uses the handler for authentication: it checks the method, the headers, the signature and the accepted events, and decodes the payload (see [Handler.DecodePayload]).
+// The rest of the pipeline (quarantine, deduplication, delivery) is not applied.
+//
+// The verified delivery is injected in the request context, see [VerifiedDeliveryFromContext].
+// The request body is replaced with the raw JSON payload, and the Content-Type header is set to "application/json" (it can be form encoded).
+//
+// Rejected requests are handled like [Handler.ServeHTTP] (response, [Handler.Error] and [Handler.Observer]).
+func (h *Handler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if h.handleCORS(w, req) {
+			return
+		}
+		in := newRequestInbound(req)
+		md, d, err := h.verifyMiddlewareRequest(in)
+		if err != nil {
+			statusCode := h.handleError(err, w, in, false)
+			h.observeRequest(in.ctx, md, statusCode, err)
+			return
+		}
+		req = req.WithContext(context.WithValue(req.Context(), verifiedDeliveryContextKey{}, d))
+		req.Body = io.NopCloser(bytes.NewReader(d.RawPayload))
+		req.ContentLength = int64(len(d.RawPayload))
+		req.Header.Set("Content-Type", "application/json")
+		next.ServeHTTP(w, req)
+	})
+}
+
+// verifyMiddlewareRequest verifies a request, and returns the metadata of the delivery if it has been verified.
+func (h *Handler) verifyMiddlewareRequest(in *inbound) (md *DeliveryMetadata, d *VerifiedDelivery, err error) {
+	defer recoverPanic(&err)
+	md, rawPayload, err := h.verifyRequest(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := h.decodePayload(in.ctx, md, rawPayload)
+	if err != nil {
+		return md, nil, err
+	}
+	return md, &VerifiedDelivery{
+		DeliveryMetadata: *md,
+		RawPayload:       rawPayload,
+		Payload:          payload,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package githubhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerMiddleware(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+	}
+	var called bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+		d, ok := VerifiedDeliveryFromContext(req.Context())
+		assert.True(t, ok)
+		assert.Equal(t, d.Event, "push")
+		assert.Equal(t, d.DeliveryID, "test")
+		assert.BytesEqual(t, d.RawPayload, testRawPayload)
+		assert.NotZero(t, d.Payload)
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.BytesEqual(t, body, testRawPayload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	req, err := (&Signer{Secret: "foobar"}).NewRequest(ctx, "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.Middleware(next).ServeHTTP(w, req)
+	assert.True(t, called)
+	assert.Equal(t, w.Code, http.StatusNoContent)
+}
+
+func TestHandlerMiddlewareForm(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.BytesEqual(t, body, testRawPayload)
+	})
+	header, err := (&Signer{Secret: "foobar"}).Header("push", "test", testRawPayload)
+	assert.NoError(t, err)
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(url.Values{"payload": {string(testRawPayload)}}.Encode()))
+	req.Header = header
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.Middleware(next).ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestHandlerMiddlewareInvalidSignature(t *testing.T) {
+	ctx := context.Background()
+	var handledErr error
+	h := &Handler{
+		Secret: "foobar",
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Fatal("next handler called")
+	})
+	req, err := (&Signer{Secret: "invalid"}).NewRequest(ctx, "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.Middleware(next).ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.ErrorIs(t, handledErr, ErrInvalidSignature)
+}
+
+func TestHandlerMiddlewareDecodeError(t *testing.T) {
+	ctx := context.Background()
+	h := new(Handler)
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Fatal("next handler called")
+	})
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "test", []byte("invalid"))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.Middleware(next).ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestVerifiedDeliveryFromContextNotFound(t *testing.T) {
+	_, ok := VerifiedDeliveryFromContext(context.Background())
+	assert.False(t, ok)
+}
@@ -0,0 +1,53 @@
+package githubhook
+
+import (
+	"context"
+	"time"
+)
+
+// Observer observes the processing of requests and deliveries, e.g. to collect metrics.
+//
+// See [Handler.Observer].
+// The github.com/pierrre/githubhook/metrics package provides a Prometheus implementation.
+type Observer interface {
+	// ObserveRequest is called after a request is handled, with its event, response status code and error (nil if it succeeded).
+	// The event is empty if the delivery has not been verified, because the header of an unverified request can contain any value.
+	// Errors can be classified with [ErrInvalidSignature], [ErrPayloadDecode] and [ErrBodyReadAborted].
+	ObserveRequest(ctx context.Context, event string, statusCode int, err error)
+	// ObserveDelivery is called after [Handler.Delivery] (or [Handler.Sink]) returns, with its duration and error.
+	ObserveDelivery(ctx context.Context, md *DeliveryMetadata, duration time.Duration, err error)
+}
+
+// observeRequest observes a request, with the event of the delivery if it has been verified (md is not nil).
+func (h *Handler) observeRequest(ctx context.Context, md *DeliveryMetadata, statusCode int, err error) {
+	recordCompare(ctx, func(res *CompareResult) {
+		res.Verified = md != nil
+		if md != nil {
+			res.Event = md.Event
+		}
+		res.Err = err
+	})
+	recordStatsError(ctx, err)
+	if h.Observer == nil {
+		return
+	}
+	var event string
+	if md != nil {
+		event = md.Event
+	}
+	h.Observer.ObserveRequest(ctx, event, statusCode, err)
+}
+
+// callDelivery calls [Handler.Delivery] (or [Handler.Sink]), and observes it.
+func (h *Handler) callDelivery(ctx context.Context, md *DeliveryMetadata, rawPayload []byte, payload any) error {
+	if h.Costs != nil {
+		defer h.Costs.start(md, CostStageDelivery)()
+	}
+	if h.Observer == nil {
+		return h.callDeliveryHandler(ctx, md, rawPayload, payload)
+	}
+	start := time.Now()
+	err := h.callDeliveryHandler(ctx, md, rawPayload, payload)
+	h.Observer.ObserveDelivery(ctx, md, time.Since(start), err)
+	return err
+}
@@ -0,0 +1,98 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+type testObserver struct {
+	event      string
+	statusCode int
+	err        error
+	deliveries int
+}
+
+func (o *testObserver) ObserveRequest(ctx context.Context, event string, statusCode int, err error) {
+	o.event = event
+	o.statusCode = statusCode
+	o.err = err
+}
+
+func (o *testObserver) ObserveDelivery(ctx context.Context, md *DeliveryMetadata, duration time.Duration, err error) {
+	o.deliveries++
+}
+
+func TestHandlerObserver(t *testing.T) {
+	ctx := context.Background()
+	o := new(testObserver)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+		Observer: o,
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, o.event, "push")
+	assert.Equal(t, o.statusCode, http.StatusOK)
+	assert.NoError(t, o.err)
+	assert.Equal(t, o.deliveries, 1)
+}
+
+func TestHandlerObserverSignatureError(t *testing.T) {
+	ctx := context.Background()
+	o := new(testObserver)
+	h := &Handler{
+		Secret:   "foobar",
+		Observer: o,
+	}
+	s := &Signer{
+		Secret: "invalid",
+	}
+	req, err := s.NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, o.statusCode, http.StatusBadRequest)
+	assert.ErrorIs(t, o.err, ErrInvalidSignature)
+	assert.Equal(t, o.deliveries, 0)
+}
+
+func TestHandlerObserverDecodeError(t *testing.T) {
+	ctx := context.Background()
+	o := new(testObserver)
+	h := &Handler{
+		Observer: o,
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", []byte("invalid"))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, o.statusCode, http.StatusBadRequest)
+	assert.ErrorIs(t, o.err, ErrPayloadDecode)
+}
+
+func TestHandlerObserverDeliveryError(t *testing.T) {
+	ctx := context.Background()
+	o := new(testObserver)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errors.New("error")
+		},
+		Observer: o,
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, o.statusCode, http.StatusInternalServerError)
+	assert.Equal(t, o.deliveries, 1)
+}
@@ -0,0 +1,317 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pierrre/githubhook/events"
+)
+
+// Option configures a [Handler] created with [NewHandler].
+type Option func(h *Handler)
+
+/*
+NewHandler creates a new [Handler], and validates its configuration.
+
+It returns an error if:
+  - no secret is defined, unless [WithoutSecret] is used
+  - options are mutually exclusive or incomplete
+
+Creating a [Handler] with a struct literal is still supported, but it is not validated.
+*/
+func NewHandler(opts ...Option) (*Handler, error) {
+	h := new(Handler)
+	for _, o := range opts {
+		o(h)
+	}
+	err := h.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return h, nil
+}
+
+func (h *Handler) validate() error {
+	var errs []error
+	hasSecret := h.Secret != "" || len(h.Secrets) > 0 || h.SecretProvider != nil || h.SignatureScheme != nil
+	if !hasSecret && !h.withoutSecret {
+		errs = append(errs, errors.New("empty secret: the signature is not verified, use WithoutSecret() to allow it"))
+	}
+	if hasSecret && h.withoutSecret {
+		errs = append(errs, errors.New("secret and WithoutSecret() are mutually exclusive"))
+	}
+	if (h.Secret != "" || len(h.Secrets) > 0) && h.SecretProvider != nil {
+		errs = append(errs, errors.New("secret and secret provider are mutually exclusive"))
+	}
+	if (h.Secret != "" || len(h.Secrets) > 0 || h.SecretProvider != nil) && h.SignatureScheme != nil {
+		errs = append(errs, errors.New("secret and signature scheme are mutually exclusive"))
+	}
+	if h.SignatureScheme != nil && (h.SignatureScheme.Header == "" || h.SignatureScheme.SecretProvider == nil) {
+		errs = append(errs, errors.New("signature scheme without header or secret provider"))
+	}
+	if len(h.TrustedSources) > 0 && !hasSecret {
+		errs = append(errs, errors.New("trusted sources are useless without secret"))
+	}
+	if h.SignaturePolicy != SignaturePolicyAll && h.SignaturePolicy != SignaturePolicyStrongest {
+		errs = append(errs, fmt.Errorf("invalid signature policy: %d", h.SignaturePolicy))
+	}
+	if h.CORS != nil && len(h.CORS.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("CORS without allowed origins"))
+	}
+	if h.CORS != nil && h.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("negative CORS max age"))
+	}
+	if len(h.SoftChecks) > 0 && h.Quarantine == nil {
+		errs = append(errs, errors.New("soft checks without quarantine"))
+	}
+	if h.Delivery != nil && h.Sink != nil {
+		errs = append(errs, errors.New("delivery and sink are mutually exclusive"))
+	}
+	if h.DuplicatePolicy < DuplicateAck || h.DuplicatePolicy > DuplicateAccepted {
+		errs = append(errs, fmt.Errorf("invalid duplicate policy: %d", h.DuplicatePolicy))
+	}
+	if h.DuplicatePolicy != DuplicateAck && h.Dedup == nil {
+		errs = append(errs, errors.New("duplicate policy without dedup"))
+	}
+	for i, l := range h.AsyncLanes {
+		if l.Pool == nil {
+			errs = append(errs, fmt.Errorf("async lane %d without pool", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithSecret sets [Handler.Secret].
+func WithSecret(secret string) Option {
+	return func(h *Handler) {
+		h.Secret = secret
+	}
+}
+
+// WithSecrets appends to [Handler.Secrets].
+func WithSecrets(secrets ...string) Option {
+	return func(h *Handler) {
+		h.Secrets = append(h.Secrets, secrets...)
+	}
+}
+
+// WithSecretProvider sets [Handler.SecretProvider].
+func WithSecretProvider(sp SecretProvider) Option {
+	return func(h *Handler) {
+		h.SecretProvider = sp
+	}
+}
+
+// WithoutSecret explicitly allows a [Handler] without secret.
+// The signature is not verified, so it should only be used for development.
+func WithoutSecret() Option {
+	return func(h *Handler) {
+		h.withoutSecret = true
+	}
+}
+
+// WithDecodePayload sets [Handler.DecodePayload].
+func WithDecodePayload(f func(ctx context.Context, event string, rawPayload []byte) (any, error)) Option {
+	return func(h *Handler) {
+		h.DecodePayload = f
+	}
+}
+
+// WithDelivery sets [Handler.Delivery].
+func WithDelivery(f DeliveryHandler) Option {
+	return func(h *Handler) {
+		h.Delivery = f
+	}
+}
+
+// WithError sets [Handler.Error].
+func WithError(f func(ctx context.Context, err error, req *http.Request)) Option {
+	return func(h *Handler) {
+		h.Error = f
+	}
+}
+
+// WithSecurityHeaders sets [Handler.SecurityHeaders].
+func WithSecurityHeaders() Option {
+	return func(h *Handler) {
+		h.SecurityHeaders = true
+	}
+}
+
+// WithResponseModifier sets [Handler.ResponseModifier].
+func WithResponseModifier(f func(header http.Header, statusCode int)) Option {
+	return func(h *Handler) {
+		h.ResponseModifier = f
+	}
+}
+
+// WithCORS sets [Handler.CORS].
+func WithCORS(c *CORS) Option {
+	return func(h *Handler) {
+		h.CORS = c
+	}
+}
+
+// WithSignaturePolicy sets [Handler.SignaturePolicy].
+func WithSignaturePolicy(p SignaturePolicy) Option {
+	return func(h *Handler) {
+		h.SignaturePolicy = p
+	}
+}
+
+// WithSignatureScheme sets [Handler.SignatureScheme].
+func WithSignatureScheme(s *SignatureScheme) Option {
+	return func(h *Handler) {
+		h.SignatureScheme = s
+	}
+}
+
+// WithTrustedSources appends to [Handler.TrustedSources].
+func WithTrustedSources(tss ...TrustedSource) Option {
+	return func(h *Handler) {
+		h.TrustedSources = append(h.TrustedSources, tss...)
+	}
+}
+
+// WithQuarantine sets [Handler.Quarantine], and appends to [Handler.SoftChecks].
+func WithQuarantine(q *Quarantine, checks ...SoftCheck) Option {
+	return func(h *Handler) {
+		h.Quarantine = q
+		h.SoftChecks = append(h.SoftChecks, checks...)
+	}
+}
+
+// WithLenientHeaders sets [Handler.LenientHeaders].
+func WithLenientHeaders() Option {
+	return func(h *Handler) {
+		h.LenientHeaders = true
+	}
+}
+
+// WithAsync sets [Handler.Async].
+func WithAsync(p *AsyncPool) Option {
+	return func(h *Handler) {
+		h.Async = p
+	}
+}
+
+// WithAsyncLanes appends to [Handler.AsyncLanes].
+func WithAsyncLanes(lanes ...*AsyncLane) Option {
+	return func(h *Handler) {
+		h.AsyncLanes = append(h.AsyncLanes, lanes...)
+	}
+}
+
+// WithDedup sets [Handler.Dedup].
+func WithDedup(s DedupStore) Option {
+	return func(h *Handler) {
+		h.Dedup = s
+	}
+}
+
+// WithDuplicatePolicy sets [Handler.DuplicatePolicy].
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(h *Handler) {
+		h.DuplicatePolicy = p
+	}
+}
+
+// WithMaxBodySize sets [Handler.MaxBodySize].
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.MaxBodySize = n
+	}
+}
+
+// WithPing sets [Handler.Ping].
+func WithPing(f func(ctx context.Context, md *DeliveryMetadata, ping *events.PingEvent) error) Option {
+	return func(h *Handler) {
+		h.Ping = f
+	}
+}
+
+// WithAckPing sets [Handler.AckPing].
+func WithAckPing() Option {
+	return func(h *Handler) {
+		h.AckPing = true
+	}
+}
+
+// WithGenerateDeliveryID sets [Handler.GenerateDeliveryID].
+func WithGenerateDeliveryID(f DeliveryIDGenerator) Option {
+	return func(h *Handler) {
+		h.GenerateDeliveryID = f
+	}
+}
+
+// WithObserver sets [Handler.Observer].
+func WithObserver(o Observer) Option {
+	return func(h *Handler) {
+		h.Observer = o
+	}
+}
+
+// WithSourceAllowlist sets [Handler.SourceAllowlist].
+func WithSourceAllowlist(a *SourceAllowlist) Option {
+	return func(h *Handler) {
+		h.SourceAllowlist = a
+	}
+}
+
+// WithRateLimiters sets [Handler.RateLimiters].
+func WithRateLimiters(limiters ...*RateLimiter) Option {
+	return func(h *Handler) {
+		h.RateLimiters = limiters
+	}
+}
+
+// WithRejectForm sets [Handler.RejectForm].
+func WithRejectForm() Option {
+	return func(h *Handler) {
+		h.RejectForm = true
+	}
+}
+
+// WithConcurrencyLimiter sets [Handler.ConcurrencyLimiter].
+func WithConcurrencyLimiter(l *ConcurrencyLimiter) Option {
+	return func(h *Handler) {
+		h.ConcurrencyLimiter = l
+	}
+}
+
+// WithCosts sets [Handler.Costs].
+func WithCosts(c *Costs) Option {
+	return func(h *Handler) {
+		h.Costs = c
+	}
+}
+
+// WithSink sets [Handler.Sink].
+func WithSink(s Sink) Option {
+	return func(h *Handler) {
+		h.Sink = s
+	}
+}
+
+// WithStore sets [Handler.Store].
+func WithStore(s Store) Option {
+	return func(h *Handler) {
+		h.Store = s
+	}
+}
+
+// WithQuota sets [Handler.Quota].
+func WithQuota(q *Quota) Option {
+	return func(h *Handler) {
+		h.Quota = q
+	}
+}
+
+// WithMaintenance sets [Handler.Maintenance].
+func WithMaintenance(m *Maintenance) Option {
+	return func(h *Handler) {
+		h.Maintenance = m
+	}
+}
@@ -0,0 +1,116 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestNewHandler(t *testing.T) {
+	ctx := context.Background()
+	deliveryCalled := false
+	h, err := NewHandler(
+		WithSecret("foobar"),
+		WithDelivery(func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		}),
+		WithSecurityHeaders(),
+		WithSignaturePolicy(SignaturePolicyStrongest),
+		WithTrustedSources(TrustedHeaderToken("X-Internal-Token", "token")),
+		WithLenientHeaders(),
+	)
+	assert.NoError(t, err)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "foobar", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.True(t, deliveryCalled)
+}
+
+func TestNewHandlerWithoutSecret(t *testing.T) {
+	h, err := NewHandler(
+		WithoutSecret(),
+		WithDecodePayload(func(ctx context.Context, event string, rawPayload []byte) (any, error) {
+			return nil, nil
+		}),
+		WithError(func(ctx context.Context, err error, req *http.Request) {}),
+		WithResponseModifier(func(header http.Header, statusCode int) {}),
+		WithCORS(&CORS{AllowedOrigins: []string{"*"}}),
+		WithQuarantine(new(Quarantine), CheckKnownEvent()),
+	)
+	assert.NoError(t, err)
+	assert.NotZero(t, h)
+}
+
+func TestNewHandlerError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "EmptySecret",
+		},
+		{
+			name: "SecretAndWithoutSecret",
+			opts: []Option{WithSecret("foobar"), WithoutSecret()},
+		},
+		{
+			name: "TrustedSourcesWithoutSecret",
+			opts: []Option{WithoutSecret(), WithTrustedSources(TrustedHeaderToken("X-Internal-Token", "token"))},
+		},
+		{
+			name: "InvalidSignaturePolicy",
+			opts: []Option{WithSecret("foobar"), WithSignaturePolicy(SignaturePolicy(-1))},
+		},
+		{
+			name: "CORSWithoutOrigins",
+			opts: []Option{WithSecret("foobar"), WithCORS(&CORS{MaxAge: -1})},
+		},
+		{
+			name: "SoftChecksWithoutQuarantine",
+			opts: []Option{WithSecret("foobar"), WithQuarantine(nil, CheckKnownEvent())},
+		},
+		{
+			name: "InvalidDuplicatePolicy",
+			opts: []Option{WithSecret("foobar"), WithDedup(NewMemoryDedupStore(10, 0)), WithDuplicatePolicy(DuplicatePolicy(-1))},
+		},
+		{
+			name: "DuplicatePolicyWithoutDedup",
+			opts: []Option{WithSecret("foobar"), WithDuplicatePolicy(DuplicateConflict)},
+		},
+		{
+			name: "SecretAndSignatureScheme",
+			opts: []Option{WithSecret("foobar"), WithSignatureScheme(&SignatureScheme{Header: "X-Internal-Signature", SecretProvider: StaticSecret("internal")})},
+		},
+		{
+			name: "SignatureSchemeWithoutSecretProvider",
+			opts: []Option{WithSignatureScheme(&SignatureScheme{Header: "X-Internal-Signature"})},
+		},
+		{
+			name: "AsyncLaneWithoutPool",
+			opts: []Option{WithSecret("foobar"), WithAsyncLanes(&AsyncLane{Events: []string{"push"}})},
+		},
+		{
+			name: "DeliveryAndSink",
+			opts: []Option{WithSecret("foobar"), WithDelivery(func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+				return nil
+			}), WithSink(SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+				return nil
+			}))},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHandler(tc.opts...)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package otelgithubhook provides [OpenTelemetry] tracing for [githubhook.Handler].
+//
+// [OpenTelemetry]: https://opentelemetry.io
+package otelgithubhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pierrre/githubhook"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/pierrre/githubhook/otelgithubhook"
+
+// Attribute keys.
+const (
+	AttributeEvent      = attribute.Key("github.event")
+	AttributeDeliveryID = attribute.Key("github.delivery_id")
+	AttributeHookID     = attribute.Key("github.hook_id")
+	AttributeRepository = attribute.Key("github.repository")
+	AttributeStatusCode = attribute.Key("http.response.status_code")
+)
+
+// Option configures [Instrument].
+type Option func(c *config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+}
+
+// WithTracerProvider sets the [trace.TracerProvider] (default: the global provider).
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+/*
+Instrument returns a [http.Handler] that creates a span per delivery received by the [githubhook.Handler].
+
+The span is named by the event (e.g. "githubhook push"), and covers the signature verification, the payload decoding and [githubhook.Handler.Delivery].
+The delivery ID, hook ID and repository are recorded as attributes.
+The context of the span is propagated to the callbacks.
+
+It wraps [githubhook.Handler.Delivery], so it must be called after it is defined (e.g. after [githubhook.NewRouter]).
+*/
+func Instrument(h *githubhook.Handler, opts ...Option) http.Handler {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+	if h.Delivery != nil {
+		h.Delivery = wrapDelivery(h.Delivery)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		event := req.Header.Get("X-GitHub-Event")
+		ctx, span := tracer.Start(req.Context(), "githubhook "+event,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				AttributeEvent.String(event),
+				AttributeDeliveryID.String(req.Header.Get("X-GitHub-Delivery")),
+				AttributeHookID.String(req.Header.Get("X-GitHub-Hook-ID")),
+			),
+		)
+		defer span.End()
+		sw := &statusResponseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		h.ServeHTTP(sw, req.WithContext(ctx))
+		span.SetAttributes(AttributeStatusCode.Int(sw.statusCode))
+		if sw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.statusCode))
+		}
+	})
+}
+
+func wrapDelivery(next githubhook.DeliveryHandler) githubhook.DeliveryHandler {
+	return func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+		span := trace.SpanFromContext(ctx)
+		if repo := githubhook.RepositoryFullName(payload); repo != "" {
+			span.SetAttributes(AttributeRepository.String(repo))
+		}
+		err := next(ctx, md, payload)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
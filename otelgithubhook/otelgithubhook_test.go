@@ -0,0 +1,75 @@
+package otelgithubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInstrument(t *testing.T) {
+	ctx := context.Background()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	var spanCtx trace.SpanContext
+	h := &githubhook.Handler{
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			spanCtx = trace.SpanContextFromContext(ctx)
+			return nil
+		},
+	}
+	ih := Instrument(h, WithTracerProvider(tp))
+	req, err := new(githubhook.Signer).NewRequest(ctx, "/", "push", "test", []byte(`{"repository":{"full_name":"octocat/Hello-World"}}`))
+	assert.NoError(t, err)
+	req.Header.Set("X-GitHub-Hook-ID", "123")
+	w := httptest.NewRecorder()
+	ih.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	spans := sr.Ended()
+	assert.SliceLen(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, span.Name(), "githubhook push")
+	assert.Equal(t, span.SpanContext().SpanID(), spanCtx.SpanID())
+	attrs := attribute.NewSet(span.Attributes()...)
+	for k, v := range map[attribute.Key]attribute.Value{
+		AttributeEvent:      attribute.StringValue("push"),
+		AttributeDeliveryID: attribute.StringValue("test"),
+		AttributeHookID:     attribute.StringValue("123"),
+		AttributeRepository: attribute.StringValue("octocat/Hello-World"),
+		AttributeStatusCode: attribute.IntValue(http.StatusOK),
+	} {
+		av, ok := attrs.Value(k)
+		assert.True(t, ok)
+		assert.Equal(t, av.Emit(), v.Emit())
+	}
+}
+
+func TestInstrumentError(t *testing.T) {
+	ctx := context.Background()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	h := &githubhook.Handler{
+		Delivery: func(ctx context.Context, md *githubhook.DeliveryMetadata, payload any) error {
+			return errors.New("error")
+		},
+	}
+	ih := Instrument(h, WithTracerProvider(tp))
+	req, err := new(githubhook.Signer).NewRequest(ctx, "/", "push", "test", []byte(`{}`))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	ih.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+	spans := sr.Ended()
+	assert.SliceLen(t, spans, 1)
+	assert.Equal(t, spans[0].Status().Code, codes.Error)
+	assert.SliceLen(t, spans[0].Events(), 1)
+}
@@ -0,0 +1,43 @@
+package githubhook
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is returned if a callback panics.
+//
+// The panic is recovered, and the request is rejected with a 500 response.
+// [http.ErrAbortHandler] is not recovered.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+// Unwrap returns the panic value if it's an error.
+func (err *PanicError) Unwrap() error {
+	e, _ := err.Value.(error)
+	return e
+}
+
+// recoverPanic recovers a panic, and sets it to the error.
+//
+// It must be deferred.
+func recoverPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if r == http.ErrAbortHandler { //nolint:errorlint // The panic value is compared, like net/http.
+		panic(r)
+	}
+	*err = &PanicError{
+		Value: r,
+		Stack: debug.Stack(),
+	}
+}
@@ -0,0 +1,92 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerPanic(t *testing.T) {
+	ctx := context.Background()
+	var handledErr error
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic("test")
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+	var panicErr *PanicError
+	assert.ErrorAs(t, handledErr, &panicErr)
+	assert.Equal[any](t, panicErr.Value, "test")
+	assert.SliceNotEmpty(t, panicErr.Stack)
+}
+
+func TestHandlerPanicError(t *testing.T) {
+	ctx := context.Background()
+	errPanic := errors.New("error")
+	var handledErr error
+	h := &Handler{
+		DecodePayload: func(ctx context.Context, event string, rawPayload []byte) (any, error) {
+			panic(errPanic)
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+	assert.ErrorIs(t, handledErr, errPanic)
+}
+
+func TestHandlerPanicAbortHandler(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic(http.ErrAbortHandler)
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	assert.Panics(t, func() {
+		h.ServeHTTP(w, req)
+	})
+}
+
+func TestHandlerPanicAsync(t *testing.T) {
+	ctx := context.Background()
+	p := NewAsyncPool(1, 1)
+	var handledErr error
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic("test")
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+		Async: p,
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	err = p.Shutdown(ctx)
+	assert.NoError(t, err)
+	var panicErr *PanicError
+	assert.ErrorAs(t, handledErr, &panicErr)
+}
@@ -0,0 +1,27 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pierrre/githubhook/events"
+)
+
+// handlePing handles a "ping" delivery, with [Handler.Ping] or [Handler.AckPing].
+func (h *Handler) handlePing(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (int, error) {
+	if h.Ping == nil {
+		return http.StatusOK, nil
+	}
+	ping := new(events.PingEvent)
+	err := json.Unmarshal(rawPayload, ping)
+	if err != nil {
+		return 0, newPayloadDecodeError(err)
+	}
+	err = h.Ping(ctx, md, ping)
+	if err != nil {
+		return 0, fmt.Errorf("ping: %w", err)
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,79 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook/events"
+)
+
+var testPingRawPayload = []byte(`{"zen":"Keep it logically awesome.","hook_id":123,"hook":{"id":123,"type":"Repository","active":true,"events":["push"],"config":{"url":"https://example.com/webhook","content_type":"json"}}}`)
+
+func TestHandlerPing(t *testing.T) {
+	ctx := context.Background()
+	var ping *events.PingEvent
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errors.New("unexpected delivery")
+		},
+		Ping: func(ctx context.Context, md *DeliveryMetadata, p *events.PingEvent) error {
+			ping = p
+			return nil
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "ping", "", testPingRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, ping.HookID, 123)
+	assert.Equal(t, ping.Hook.Config.URL, "https://example.com/webhook")
+	assert.DeepEqual(t, ping.Hook.Events, []string{"push"})
+}
+
+func TestHandlerPingError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Ping: func(ctx context.Context, md *DeliveryMetadata, p *events.PingEvent) error {
+			return errors.New("error")
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "ping", "", testPingRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestHandlerPingDecodeError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Ping: func(ctx context.Context, md *DeliveryMetadata, p *events.PingEvent) error {
+			return nil
+		},
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "ping", "", []byte("invalid"))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestHandlerAckPing(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errors.New("unexpected delivery")
+		},
+		AckPing: true,
+	}
+	req, err := new(Signer).NewRequest(ctx, "/", "ping", "", testPingRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+}
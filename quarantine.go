@@ -0,0 +1,194 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SoftCheck checks a verified delivery.
+//
+// It returns a non-empty reason if the delivery is suspicious and must be quarantined.
+type SoftCheck func(event string, rawPayload []byte, req *http.Request) (reason string)
+
+// CheckKnownEvent returns a [SoftCheck] that quarantines unknown events (see [IsKnownEvent]).
+func CheckKnownEvent() SoftCheck {
+	return func(event string, rawPayload []byte, req *http.Request) string {
+		if !IsKnownEvent(event) {
+			return "unknown event: " + event
+		}
+		return ""
+	}
+}
+
+// CheckRemoteAddr returns a [SoftCheck] that quarantines deliveries whose source address is not allowed by the allowlist.
+//
+// The source address is resolved like [Handler.SourceAllowlist], with the trusted proxies (see [SourceAllowlist.SourceAddr]).
+// It allows to review the deliveries from unexpected sources, instead of rejecting them.
+func CheckRemoteAddr(a *SourceAllowlist) SoftCheck {
+	return func(event string, rawPayload []byte, req *http.Request) string {
+		prefixes, err := a.Prefixes.Prefixes(req.Context())
+		if err != nil {
+			return "source allowlist: " + err.Error()
+		}
+		addr, ok := a.SourceAddr(req)
+		if !ok {
+			return "invalid source address: " + req.RemoteAddr
+		}
+		if !containsAddr(prefixes, addr) {
+			return "source address not allowed: " + addr.String()
+		}
+		return ""
+	}
+}
+
+// CheckPayload returns a [SoftCheck] that quarantines deliveries whose payload can't be decoded by the function (e.g. a strict schema validation).
+func CheckPayload(f func(event string, rawPayload []byte) error) SoftCheck {
+	return func(event string, rawPayload []byte, req *http.Request) string {
+		err := f(event, rawPayload)
+		if err != nil {
+			return "invalid payload: " + err.Error()
+		}
+		return ""
+	}
+}
+
+// QuarantinedDelivery is a delivery stored in a [Quarantine].
+type QuarantinedDelivery struct {
+	DeliveryMetadata
+	RawPayload []byte
+	Reason     string
+	Time       time.Time
+}
+
+// ErrQuarantinedDeliveryNotFound is returned by [Quarantine] if the delivery is not found.
+var ErrQuarantinedDeliveryNotFound = errors.New("quarantined delivery not found")
+
+// DefaultQuarantineSize is the default value of [Quarantine.Size].
+const DefaultQuarantineSize = 1000
+
+/*
+Quarantine stores suspicious deliveries for manual review, instead of rejecting or processing them.
+
+Deliveries are quarantined if they are verified, but fail one of the [Handler.SoftChecks].
+They are acknowledged with a 200 response, but [Handler.Delivery] is not called.
+They can be released into the pipeline later with [Quarantine.Release].
+
+Fields:
+  - Size is the maximum number of quarantined deliveries (default: [DefaultQuarantineSize]). When the quarantine is full, the oldest delivery is evicted, and counted as dropped (see [Quarantine.Dropped]). If it's negative, the size is not limited.
+
+The zero value is ready to use.
+*/
+type Quarantine struct {
+	Size int
+
+	mu         sync.Mutex
+	deliveries []*QuarantinedDelivery
+	dropped    int
+}
+
+func (q *Quarantine) getSize() int {
+	if q.Size == 0 {
+		return DefaultQuarantineSize
+	}
+	return q.Size
+}
+
+func (q *Quarantine) add(d *QuarantinedDelivery) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Keep the order of the time, for the deliveries restored by a failed release.
+	i, _ := slices.BinarySearchFunc(q.deliveries, d, func(e *QuarantinedDelivery, d *QuarantinedDelivery) int {
+		if e.Time.After(d.Time) {
+			return 1
+		}
+		return -1
+	})
+	q.deliveries = slices.Insert(q.deliveries, i, d)
+	if size := q.getSize(); size > 0 && len(q.deliveries) > size {
+		n := len(q.deliveries) - size
+		q.deliveries = slices.Delete(q.deliveries, 0, n)
+		q.dropped += n
+	}
+}
+
+// Len returns the number of quarantined deliveries.
+func (q *Quarantine) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.deliveries)
+}
+
+// Dropped returns the number of deliveries evicted because the quarantine was full.
+func (q *Quarantine) Dropped() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// List returns the quarantined deliveries, sorted from the oldest to the newest.
+func (q *Quarantine) List() []*QuarantinedDelivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.deliveries)
+}
+
+// Delete deletes a quarantined delivery, and returns it.
+func (q *Quarantine) Delete(deliveryID string) (*QuarantinedDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.IndexFunc(q.deliveries, func(d *QuarantinedDelivery) bool {
+		return d.DeliveryID == deliveryID
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrQuarantinedDeliveryNotFound, deliveryID)
+	}
+	d := q.deliveries[i]
+	q.deliveries = slices.Delete(q.deliveries, i, i+1)
+	return d, nil
+}
+
+// Release runs a quarantined delivery through the rest of the pipeline of the [Handler], like a live delivery (deduplication, store, payload decoding and delivery), and deletes it if it succeeded.
+//
+// If the delivery fails, it stays in the quarantine, so it can be released again.
+// The released delivery has a [Lineage] with the "quarantine release" reason.
+func (q *Quarantine) Release(ctx context.Context, deliveryID string, h *Handler) error {
+	// The delivery is removed while it's released, so it can't be released concurrently.
+	d, err := q.Delete(deliveryID)
+	if err != nil {
+		return err
+	}
+	md := d.DeliveryMetadata
+	md.Lineage = NextLineage(&d.DeliveryMetadata, "", "quarantine release")
+	_, err = h.Process(ctx, &md, d.RawPayload)
+	if err != nil {
+		q.add(d)
+		return err
+	}
+	return nil
+}
+
+// quarantine returns true if the delivery has been quarantined.
+func (h *Handler) quarantine(md *DeliveryMetadata, rawPayload []byte, in *inbound) bool {
+	if h.Quarantine == nil {
+		return false
+	}
+	for _, check := range h.SoftChecks {
+		reason := check(md.Event, rawPayload, in.request())
+		if reason == "" {
+			continue
+		}
+		h.Quarantine.add(&QuarantinedDelivery{
+			DeliveryMetadata: *md,
+			RawPayload:       rawPayload,
+			Reason:           reason,
+			Time:             time.Now(),
+		})
+		return true
+	}
+	return false
+}
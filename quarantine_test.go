@@ -0,0 +1,238 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerQuarantine(t *testing.T) {
+	ctx := context.Background()
+	deliveryCount := 0
+	var lineage *Lineage
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryCount++
+			lineage = md.Lineage
+			return nil
+		},
+		Quarantine: new(Quarantine),
+		SoftChecks: []SoftCheck{
+			CheckKnownEvent(),
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("X-GitHub-Event", "unknown")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, deliveryCount, 0)
+	ds := h.Quarantine.List()
+	assert.SliceLen(t, ds, 1)
+	assert.Equal(t, ds[0].Reason, "unknown event: unknown")
+	err = h.Quarantine.Release(ctx, ds[0].DeliveryID, h)
+	assert.NoError(t, err)
+	assert.Equal(t, deliveryCount, 1)
+	assert.Equal(t, lineage.OriginalDeliveryID, ds[0].DeliveryID)
+	assert.Equal(t, lineage.Reason, "quarantine release")
+	assert.SliceEmpty(t, h.Quarantine.List())
+}
+
+func TestHandlerQuarantinePass(t *testing.T) {
+	ctx := context.Background()
+	deliveryCount := 0
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryCount++
+			return nil
+		},
+		Quarantine: new(Quarantine),
+		SoftChecks: []SoftCheck{
+			CheckKnownEvent(),
+			CheckRemoteAddr(&SourceAllowlist{
+				Prefixes: StaticPrefixes{netip.MustParsePrefix("127.0.0.0/8")},
+			}),
+			CheckPayload(func(event string, rawPayload []byte) error {
+				return nil
+			}),
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, deliveryCount, 1)
+	assert.SliceEmpty(t, h.Quarantine.List())
+}
+
+func TestSoftChecks(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	req.RemoteAddr = "192.168.1.1:1234"
+	assert.NotZero(t, CheckRemoteAddr(&SourceAllowlist{
+		Prefixes: StaticPrefixes{netip.MustParsePrefix("10.0.0.0/8")},
+	})("push", nil, req))
+	assert.NotZero(t, CheckPayload(func(event string, rawPayload []byte) error {
+		return errors.New("error")
+	})("push", nil, req))
+}
+
+func TestCheckRemoteAddr(t *testing.T) {
+	check := CheckRemoteAddr(&SourceAllowlist{
+		Prefixes:       StaticPrefixes{netip.MustParsePrefix("192.0.2.0/24")},
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		reason       string
+	}{
+		{
+			name:       "Allowed",
+			remoteAddr: "192.0.2.1:1234",
+		},
+		{
+			name:         "AllowedTrustedProxy",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: "192.0.2.1",
+		},
+		{
+			name:         "NotAllowedTrustedProxy",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: "198.51.100.1",
+			reason:       "source address not allowed: 198.51.100.1",
+		},
+		{
+			name:         "NotAllowedUntrustedProxy",
+			remoteAddr:   "198.51.100.1:1234",
+			forwardedFor: "192.0.2.1",
+			reason:       "source address not allowed: 198.51.100.1",
+		},
+		{
+			name:       "Invalid",
+			remoteAddr: "invalid",
+			reason:     "invalid source address: invalid",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			assert.Equal(t, check("push", nil, req), tc.reason)
+		})
+	}
+}
+
+func TestCheckRemoteAddrErrorPrefixes(t *testing.T) {
+	check := CheckRemoteAddr(&SourceAllowlist{
+		Prefixes: testPrefixProviderError{},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	assert.Equal(t, check("push", nil, req), "source allowlist: error")
+}
+
+func TestQuarantineReleaseError(t *testing.T) {
+	ctx := context.Background()
+	deliveryErr := errors.New("error")
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return deliveryErr
+		},
+		Dedup:      NewMemoryDedupStore(10, time.Hour),
+		Quarantine: new(Quarantine),
+	}
+	h.Quarantine.add(&QuarantinedDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			Event:      "push",
+			DeliveryID: "test",
+		},
+		RawPayload: testRawPayload,
+		Time:       time.Now(),
+	})
+	err := h.Quarantine.Release(ctx, "test", h)
+	assert.ErrorIs(t, err, deliveryErr)
+	ds := h.Quarantine.List()
+	assert.SliceLen(t, ds, 1)
+	assert.Equal(t, ds[0].DeliveryID, "test")
+	h.Delivery = func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		return nil
+	}
+	err = h.Quarantine.Release(ctx, "test", h)
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, h.Quarantine.List())
+}
+
+func TestQuarantineReleaseDedup(t *testing.T) {
+	ctx := context.Background()
+	deliveryCount := 0
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryCount++
+			return nil
+		},
+		Dedup:      NewMemoryDedupStore(10, time.Hour),
+		Store:      NewMemoryStore(10),
+		Quarantine: new(Quarantine),
+	}
+	for range 2 {
+		h.Quarantine.add(&QuarantinedDelivery{
+			DeliveryMetadata: DeliveryMetadata{
+				Event:      "push",
+				DeliveryID: "test",
+			},
+			RawPayload: testRawPayload,
+			Time:       time.Now(),
+		})
+		err := h.Quarantine.Release(ctx, "test", h)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, deliveryCount, 1)
+	d, err := h.Store.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusSucceeded)
+}
+
+func TestQuarantineSize(t *testing.T) {
+	q := &Quarantine{
+		Size: 2,
+	}
+	now := time.Now()
+	for i, id := range []string{"1", "2", "3"} {
+		q.add(&QuarantinedDelivery{
+			DeliveryMetadata: DeliveryMetadata{
+				DeliveryID: id,
+			},
+			Time: now.Add(time.Duration(i) * time.Second),
+		})
+	}
+	assert.Equal(t, q.Len(), 2)
+	assert.Equal(t, q.Dropped(), 1)
+	ds := q.List()
+	assert.Equal(t, ds[0].DeliveryID, "2")
+	assert.Equal(t, ds[1].DeliveryID, "3")
+}
+
+func TestQuarantineErrorNotFound(t *testing.T) {
+	ctx := context.Background()
+	q := new(Quarantine)
+	err := q.Release(ctx, "unknown", &Handler{})
+	assert.ErrorIs(t, err, ErrQuarantinedDeliveryNotFound)
+}
@@ -0,0 +1,207 @@
+package githubhook
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned (wrapped in a [RequestError]) if a repository exceeds its [Quota] with [QuotaActionThrottle].
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaAction is the action applied by [Quota] to the deliveries of a repository that exceeds its quota.
+type QuotaAction int
+
+const (
+	// QuotaActionLog logs the deliveries exceeding the quota, and processes them.
+	QuotaActionLog QuotaAction = iota
+	// QuotaActionAlert calls [Quota.Alert] once per repository per window, and processes the deliveries.
+	QuotaActionAlert
+	// QuotaActionThrottle rejects the deliveries exceeding the quota with a 429 response, and a Retry-After header until the end of the window.
+	QuotaActionThrottle
+)
+
+// DefaultQuotaWindow is the default value of [Quota.Window].
+const DefaultQuotaWindow = time.Hour
+
+/*
+Quota accounts the verified deliveries per repository per time window, and applies an action when a repository exceeds its quota, e.g. to bill or police the usage of the tenants of a shared platform.
+
+The repository is the full name of the repository of the payload (see [RepositoryFullName]).
+The deliveries without repository (e.g. organization events) are not accounted.
+The windows are fixed (aligned on the window duration), and the counts are reset at the start of each window.
+
+Fields (all are optional):
+  - Window is the duration of the window (default: [DefaultQuotaWindow]).
+  - Limit is the number of deliveries allowed per repository per window. If it's 0, the deliveries are only accounted.
+  - Limits overrides Limit per repository (e.g. for a tenant with another plan). A negative limit is unlimited.
+  - Action is the action applied to the deliveries exceeding the quota (default: [QuotaActionLog]).
+  - Logger logs the deliveries exceeding the quota with [QuotaActionLog] (default: [slog.Default]).
+  - Alert is called with [QuotaActionAlert], when a repository exceeds its quota.
+
+It implements [http.Handler], and serves the usage of the current window as JSON (e.g. for an admin endpoint).
+See the github.com/pierrre/githubhook/metrics package for the Prometheus metrics.
+
+The zero value is ready to use.
+*/
+type Quota struct {
+	Window time.Duration
+	Limit  int
+	Limits map[string]int
+	Action QuotaAction
+	Logger *slog.Logger
+	Alert  func(ctx context.Context, usage QuotaUsage)
+
+	mu          sync.Mutex
+	windowStart time.Time
+	usage       map[string]*QuotaUsage
+	exceeded    int
+	now         func() time.Time
+}
+
+// QuotaUsage is the usage of a repository in the current window of a [Quota].
+//
+// Count includes the deliveries exceeding the quota (Exceeded).
+// Limit is 0 if the repository is not limited.
+type QuotaUsage struct {
+	Repository string `json:"repository"`
+	Count      int    `json:"count"`
+	Limit      int    `json:"limit"`
+	Exceeded   int    `json:"exceeded"`
+}
+
+// Usage returns the start of the current window, and the usage of the repositories, sorted by repository.
+func (q *Quota) Usage() (windowStart time.Time, usage []QuotaUsage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rotate()
+	usage = make([]QuotaUsage, 0, len(q.usage))
+	for _, u := range q.usage {
+		usage = append(usage, *u)
+	}
+	slices.SortFunc(usage, func(a, b QuotaUsage) int {
+		return cmp.Compare(a.Repository, b.Repository)
+	})
+	return q.windowStart, usage
+}
+
+// Exceeded returns the total number of deliveries that exceeded the quota, in all the windows.
+func (q *Quota) Exceeded() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.exceeded
+}
+
+func (q *Quota) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	windowStart, usage := q.Usage()
+	v := struct {
+		WindowStart time.Time     `json:"window_start"`
+		Window      time.Duration `json:"window"`
+		Usage       []QuotaUsage  `json:"usage"`
+	}{
+		WindowStart: windowStart,
+		Window:      q.getWindow(),
+		Usage:       usage,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (q *Quota) getWindow() time.Duration {
+	if q.Window <= 0 {
+		return DefaultQuotaWindow
+	}
+	return q.Window
+}
+
+func (q *Quota) getLimit(repository string) int {
+	if l, ok := q.Limits[repository]; ok {
+		return max(l, 0)
+	}
+	return q.Limit
+}
+
+// rotate resets the counts if the current window has ended.
+func (q *Quota) rotate() time.Time {
+	now := time.Now()
+	if q.now != nil {
+		now = q.now()
+	}
+	start := now.Truncate(q.getWindow())
+	if !start.Equal(q.windowStart) || q.usage == nil {
+		q.windowStart = start
+		q.usage = make(map[string]*QuotaUsage)
+	}
+	return now
+}
+
+// add accounts a delivery of the repository, and returns its usage, whether it exceeded the quota, and the remaining duration of the window.
+func (q *Quota) add(repository string) (usage QuotaUsage, exceeded bool, remaining time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.rotate()
+	u, ok := q.usage[repository]
+	if !ok {
+		u = &QuotaUsage{
+			Repository: repository,
+			Limit:      q.getLimit(repository),
+		}
+		q.usage[repository] = u
+	}
+	u.Count++
+	exceeded = u.Limit > 0 && u.Count > u.Limit
+	if exceeded {
+		u.Exceeded++
+		q.exceeded++
+	}
+	return *u, exceeded, q.windowStart.Add(q.getWindow()).Sub(now)
+}
+
+func (q *Quota) check(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) error {
+	repository := RepositoryFullName(json.RawMessage(rawPayload))
+	if repository == "" {
+		return nil
+	}
+	usage, exceeded, remaining := q.add(repository)
+	if !exceeded {
+		return nil
+	}
+	switch q.Action {
+	case QuotaActionAlert:
+		if q.Alert != nil && usage.Exceeded == 1 {
+			q.Alert(ctx, usage)
+		}
+	case QuotaActionThrottle:
+		header := make(http.Header)
+		header.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(remaining.Seconds())), 10))
+		return &RequestError{
+			StatusCode: http.StatusTooManyRequests,
+			Message:    "quota exceeded",
+			Err:        fmt.Errorf("%w: %s", ErrQuotaExceeded, repository),
+			Header:     header,
+		}
+	default:
+		logger := q.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.WarnContext(ctx, "Quota exceeded", "repository", repository, "count", usage.Count, "limit", usage.Limit, "event", md.Event, "delivery_id", md.DeliveryID)
+	}
+	return nil
+}
+
+func (h *Handler) checkQuota(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) error {
+	if h.Quota == nil {
+		return nil
+	}
+	return h.Quota.check(ctx, md, rawPayload)
+}
@@ -0,0 +1,168 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerQuota(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)
+	var logs bytes.Buffer
+	var alerts []QuotaUsage
+	for _, tc := range []struct {
+		name       string
+		action     QuotaAction
+		statusCode int
+		check      func(t *testing.T)
+	}{
+		{
+			name:       "Log",
+			action:     QuotaActionLog,
+			statusCode: http.StatusOK,
+			check: func(t *testing.T) {
+				t.Helper()
+				assert.StringContains(t, logs.String(), "repository=octocat/a")
+			},
+		},
+		{
+			name:       "Alert",
+			action:     QuotaActionAlert,
+			statusCode: http.StatusOK,
+			check: func(t *testing.T) {
+				t.Helper()
+				assert.SliceEqual(t, alerts, []QuotaUsage{{Repository: "octocat/a", Count: 2, Limit: 1, Exceeded: 1}})
+			},
+		},
+		{
+			name:       "Throttle",
+			action:     QuotaActionThrottle,
+			statusCode: http.StatusTooManyRequests,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			alerts = nil
+			q := &Quota{
+				Limit:  1,
+				Action: tc.action,
+				Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+				Alert: func(ctx context.Context, usage QuotaUsage) {
+					alerts = append(alerts, usage)
+				},
+				now: func() time.Time {
+					return now
+				},
+			}
+			var handledErr error
+			h := &Handler{
+				Quota: q,
+				Error: func(ctx context.Context, err error, req *http.Request) {
+					handledErr = err
+				},
+			}
+			w := testServeQuota(t, h, "octocat/a")
+			assert.Equal(t, w.Code, http.StatusOK)
+			w = testServeQuota(t, h, "octocat/a")
+			assert.Equal(t, w.Code, tc.statusCode)
+			w = testServeQuota(t, h, "octocat/a")
+			assert.Equal(t, w.Code, tc.statusCode)
+			if tc.action == QuotaActionThrottle {
+				assert.Equal(t, w.Header().Get("Retry-After"), "1800")
+				assert.ErrorIs(t, handledErr, ErrQuotaExceeded)
+			}
+			w = testServeQuota(t, h, "octocat/b")
+			assert.Equal(t, w.Code, http.StatusOK)
+			if tc.check != nil {
+				tc.check(t)
+			}
+			windowStart, usage := q.Usage()
+			assert.Equal(t, windowStart, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			assert.SliceEqual(t, usage, []QuotaUsage{
+				{Repository: "octocat/a", Count: 3, Limit: 1, Exceeded: 2},
+				{Repository: "octocat/b", Count: 1, Limit: 1},
+			})
+			assert.Equal(t, q.Exceeded(), 2)
+		})
+	}
+}
+
+func TestQuotaWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 59, 0, 0, time.UTC)
+	q := &Quota{
+		Limit:  1,
+		Limits: map[string]int{"octocat/unlimited": -1},
+		now: func() time.Time {
+			return now
+		},
+	}
+	_, exceeded, _ := q.add("octocat/a")
+	assert.False(t, exceeded)
+	_, exceeded, remaining := q.add("octocat/a")
+	assert.True(t, exceeded)
+	assert.Equal(t, remaining, time.Minute)
+	for range 3 {
+		_, exceeded, _ = q.add("octocat/unlimited")
+		assert.False(t, exceeded)
+	}
+	now = now.Add(time.Minute)
+	_, exceeded, _ = q.add("octocat/a")
+	assert.False(t, exceeded)
+	_, usage := q.Usage()
+	assert.SliceEqual(t, usage, []QuotaUsage{{Repository: "octocat/a", Count: 1, Limit: 1}})
+	assert.Equal(t, q.Exceeded(), 1)
+}
+
+func TestQuotaNoRepository(t *testing.T) {
+	h := &Handler{
+		Quota: &Quota{
+			Limit:  1,
+			Action: QuotaActionThrottle,
+		},
+	}
+	for range 2 {
+		req, err := new(Signer).NewRequest(context.Background(), "/", "push", "", testRawPayload)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+	_, usage := h.Quota.Usage()
+	assert.SliceEmpty(t, usage)
+}
+
+func TestQuotaServeHTTP(t *testing.T) {
+	q := &Quota{
+		Window: time.Minute,
+		Limit:  10,
+	}
+	q.add("octocat/a")
+	w := httptest.NewRecorder()
+	q.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+	var v struct {
+		Window time.Duration `json:"window"`
+		Usage  []QuotaUsage  `json:"usage"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, v.Window, time.Minute)
+	assert.SliceEqual(t, v.Usage, []QuotaUsage{{Repository: "octocat/a", Count: 1, Limit: 10}})
+}
+
+func testServeQuota(t *testing.T, h *Handler, repository string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := new(Signer).NewRequest(context.Background(), "/", "push", "", []byte(`{"repository":{"full_name":"`+repository+`"}}`))
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
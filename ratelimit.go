@@ -0,0 +1,157 @@
+package githubhook
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned if a delivery exceeds the limit of a [RateLimiter].
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitKey returns the key of a verified delivery for a [RateLimiter].
+//
+// If it returns an empty key, the delivery is not limited.
+type RateLimitKey func(req *http.Request, md *DeliveryMetadata, rawPayload []byte) string
+
+// RateLimitByRemoteAddr returns a [RateLimitKey] that returns the source address of the request.
+//
+// The source address is determined like [SourceAllowlist.SourceAddr]: if the remote address is one of the trustedProxies, the X-Forwarded-For header is used.
+// Behind a proxy, the trusted proxies must be set (e.g. the same as [SourceAllowlist.TrustedProxies]), otherwise all the deliveries share the key of the proxy.
+func RateLimitByRemoteAddr(trustedProxies ...netip.Prefix) RateLimitKey {
+	a := &SourceAllowlist{
+		TrustedProxies: trustedProxies,
+	}
+	return func(req *http.Request, md *DeliveryMetadata, rawPayload []byte) string {
+		addr, ok := a.SourceAddr(req)
+		if !ok {
+			return ""
+		}
+		return addr.String()
+	}
+}
+
+// RateLimitByRepository returns a [RateLimitKey] that returns the full name of the repository of the payload (see [RepositoryFullName]).
+func RateLimitByRepository() RateLimitKey {
+	return func(req *http.Request, md *DeliveryMetadata, rawPayload []byte) string {
+		return RepositoryFullName(json.RawMessage(rawPayload))
+	}
+}
+
+/*
+RateLimiter limits the rate of deliveries per source, with a token bucket per key.
+
+Deliveries exceeding the limit are rejected with a 429 response and a Retry-After header, so floods caused by misconfigured hooks or bulk redeliveries don't reach [Handler.Delivery].
+Only verified deliveries are limited.
+
+It must be created with [NewRateLimiter].
+*/
+type RateLimiter struct {
+	key   RateLimitKey
+	rate  float64
+	burst float64
+	size  int
+
+	mu      sync.Mutex
+	lru     *list.List
+	buckets map[string]*list.Element
+	now     func() time.Time
+}
+
+type rateLimitBucket struct {
+	key    string
+	tokens float64
+	time   time.Time
+}
+
+// NewRateLimiter creates a new [RateLimiter].
+//
+// key returns the key of a delivery, e.g. [RateLimitByRemoteAddr] or [RateLimitByRepository].
+// rate is the number of allowed deliveries per second, and burst the maximum number of deliveries allowed at once.
+// size is the maximum number of tracked keys, the least recently used are evicted.
+func NewRateLimiter(key RateLimitKey, rate float64, burst int, size int) *RateLimiter {
+	return &RateLimiter{
+		key:     key,
+		rate:    rate,
+		burst:   float64(burst),
+		size:    size,
+		lru:     list.New(),
+		buckets: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// Allow returns true if a delivery with the key is allowed, and consumes a token.
+// Otherwise, it returns the duration to wait before the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b := l.getBucket(key, now)
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.time).Seconds()*l.rate)
+	b.time = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *RateLimiter) getBucket(key string, now time.Time) *rateLimitBucket {
+	e, ok := l.buckets[key]
+	if ok {
+		l.lru.MoveToFront(e)
+		return e.Value.(*rateLimitBucket) //nolint:forcetypeassert // The list only contains this type.
+	}
+	b := &rateLimitBucket{
+		key:    key,
+		tokens: l.burst,
+		time:   now,
+	}
+	l.buckets[key] = l.lru.PushFront(b)
+	for l.size > 0 && l.lru.Len() > l.size {
+		e := l.lru.Back()
+		l.lru.Remove(e)
+		delete(l.buckets, e.Value.(*rateLimitBucket).key) //nolint:forcetypeassert // The list only contains this type.
+	}
+	return b
+}
+
+func (l *RateLimiter) check(req *http.Request, md *DeliveryMetadata, rawPayload []byte) error {
+	key := l.key(req, md, rawPayload)
+	if key == "" {
+		return nil
+	}
+	ok, retryAfter := l.Allow(key)
+	if ok {
+		return nil
+	}
+	header := make(http.Header)
+	header.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+	return &RequestError{
+		StatusCode: http.StatusTooManyRequests,
+		Message:    "rate limited",
+		Err:        fmt.Errorf("%w: %s", ErrRateLimited, key),
+		Header:     header,
+	}
+}
+
+func (h *Handler) checkRateLimiters(in *inbound, md *DeliveryMetadata, rawPayload []byte) error {
+	for _, l := range h.RateLimiters {
+		err := l.check(in.request(), md, rawPayload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(RateLimitByRemoteAddr(), 1, 2, 10)
+	l.now = func() time.Time {
+		return now
+	}
+	for range 2 {
+		ok, _ := l.Allow("a")
+		assert.True(t, ok)
+	}
+	ok, retryAfter := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, retryAfter, time.Second)
+	ok, _ = l.Allow("b")
+	assert.True(t, ok)
+	now = now.Add(500 * time.Millisecond)
+	ok, retryAfter = l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, retryAfter, 500*time.Millisecond)
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	l := NewRateLimiter(RateLimitByRemoteAddr(), 0, 1, 1)
+	ok, _ := l.Allow("a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+	ok, _ = l.Allow("b")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestHandlerRateLimiters(t *testing.T) {
+	ctx := context.Background()
+	var handledErr error
+	h := &Handler{
+		RateLimiters: []*RateLimiter{
+			NewRateLimiter(RateLimitByRepository(), 0.5, 1, 10),
+		},
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			handledErr = err
+		},
+	}
+	serve := func(repository string) *httptest.ResponseRecorder {
+		req, err := new(Signer).NewRequest(ctx, "/", "push", "", []byte(`{"repository":{"full_name":"`+repository+`"}}`))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	w := serve("octocat/a")
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = serve("octocat/a")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+	assert.Equal(t, w.Header().Get("Retry-After"), "2")
+	assert.ErrorIs(t, handledErr, ErrRateLimited)
+	w = serve("octocat/b")
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestRateLimitByRemoteAddr(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	assert.Equal(t, RateLimitByRemoteAddr()(req, nil, nil), "1.2.3.4")
+	req.RemoteAddr = "invalid"
+	assert.Equal(t, RateLimitByRemoteAddr()(req, nil, nil), "")
+}
+
+func TestRateLimitByRemoteAddrTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.2")
+	key := RateLimitByRemoteAddr(netip.MustParsePrefix("10.0.0.0/8"))
+	assert.Equal(t, key(req, nil, nil), "1.2.3.4")
+	assert.Equal(t, RateLimitByRemoteAddr()(req, nil, nil), "10.0.0.1") // X-Forwarded-For is ignored without trusted proxies.
+	req.Header.Set("X-Forwarded-For", "invalid")
+	assert.Equal(t, key(req, nil, nil), "")
+}
+
+func TestRateLimitByRepositoryMissing(t *testing.T) {
+	assert.Equal(t, RateLimitByRepository()(nil, nil, []byte(`{}`)), "")
+}
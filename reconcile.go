@@ -0,0 +1,460 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReconcileMaxAge is the default value of [Reconciler.MaxAge].
+const DefaultReconcileMaxAge = 24 * time.Hour
+
+// DefaultReconcileMinAge is the default value of [Reconciler.MinAge].
+const DefaultReconcileMinAge = time.Minute
+
+// DefaultReconcileMaxAttempts is the default value of [Reconciler.MaxAttempts].
+const DefaultReconcileMaxAttempts = 3
+
+// ErrReconcileMaxAttempts is reported to [Reconciler.Error] when a missing delivery is abandoned, because it has reached the maximum number of recovery attempts.
+var ErrReconcileMaxAttempts = errors.New("max reconcile attempts reached")
+
+// ReconcileAction is the action of a [Reconciler] for the missing deliveries.
+type ReconcileAction int
+
+const (
+	// ReconcileReport only reports the missing deliveries.
+	ReconcileReport ReconcileAction = iota
+	// ReconcileFetch fetches the missing deliveries from the API, and runs them through the pipeline of the [Handler]: the events rejected by [Router] are ignored, [Handler.Quarantine] is applied, then [Handler.Process].
+	// The signature is not verified, because the payload is fetched from the authenticated API.
+	// The soft checks receive a request built from the fetched headers, without remote address.
+	ReconcileFetch
+	// ReconcileRedeliver asks GitHub to redeliver the missing deliveries to the webhook.
+	ReconcileRedeliver
+)
+
+/*
+HookDelivery is a delivery listed by the GitHub hook deliveries API.
+
+See https://docs.github.com/en/rest/repos/webhooks#list-deliveries-for-a-repository-webhook.
+*/
+type HookDelivery struct {
+	ID             int64     `json:"id"`
+	GUID           string    `json:"guid"`
+	DeliveredAt    time.Time `json:"delivered_at"`
+	Redelivery     bool      `json:"redelivery"`
+	Status         string    `json:"status"`
+	StatusCode     int       `json:"status_code"`
+	Event          string    `json:"event"`
+	Action         string    `json:"action"`
+	InstallationID int64     `json:"installation_id"`
+	RepositoryID   int64     `json:"repository_id"`
+}
+
+/*
+Reconciler finds the deliveries that were sent by GitHub but never received locally (e.g. during a downtime), with the GitHub hook deliveries API.
+
+It lists the deliveries of the webhook, compares their GUIDs with the received deliveries, and reports the missing ones.
+Depending on Action, the missing deliveries are also fetched and processed, or redelivered by GitHub.
+
+A delivery is received if the webhook answered it with a 2xx or 4xx status (according to the API), or if Received returns true.
+So the deliveries that have been handled but not stored (e.g. quarantined, rejected by [Handler.RateLimiters], [Handler.SourceAllowlist] or [Router], or evicted from a [MemoryStore]) are not missing.
+The deliveries that GitHub couldn't send (e.g. during a downtime), or that failed with a 5xx status, are missing.
+
+Fields:
+  - URL is the URL of the deliveries API of the webhook (required), e.g. "https://api.github.com/repos/OWNER/REPO/hooks/HOOK_ID/deliveries", "https://api.github.com/orgs/ORG/hooks/HOOK_ID/deliveries" or "https://api.github.com/app/hook/deliveries".
+  - Token is the token sent in the Authorization header (optional), e.g. a personal access token. A GitHub App must authenticate with a JWT, set by Client.
+  - Client is the HTTP client (default: [http.DefaultClient]).
+  - Handler is the handler that received the deliveries (required).
+  - Received returns true if a delivery has been received (default: the delivery is in [Handler.Store]).
+  - Action is the action for the missing deliveries (default: [ReconcileReport]).
+  - Redeliverer requests the redeliveries with [ReconcileRedeliver] (optional), with backoff and a cap on attempts. If it's nil, the redeliveries are requested immediately, up to MaxAttempts.
+  - MaxAttempts is the maximum number of recovery attempts per missing delivery, with [ReconcileFetch], or [ReconcileRedeliver] without Redeliverer (default: [DefaultReconcileMaxAttempts]).
+  - MaxAge is the maximum age of the checked deliveries (default: [DefaultReconcileMaxAge]).
+  - MinAge is the minimum age of the checked deliveries (default: [DefaultReconcileMinAge]), so the deliveries in flight are not reported.
+  - Missing is called for each missing delivery (optional).
+  - Error is called if a reconciliation fails in [Reconciler.Run], or if a missing delivery is abandoned (optional).
+
+The redeliveries are checked with their GUID, so a delivery is reported only once, even if it has been attempted several times.
+The recovery attempts are counted per GUID, so [Reconciler.Run] doesn't recover the same delivery forever.
+
+The fields must not be modified after the first reconciliation.
+*/
+type Reconciler struct {
+	URL         string
+	Token       string
+	Client      *http.Client
+	Handler     *Handler
+	Received    func(ctx context.Context, deliveryID string) (bool, error)
+	Action      ReconcileAction
+	Redeliverer *Redeliverer
+	MaxAttempts int
+	MaxAge      time.Duration
+	MinAge      time.Duration
+	Missing     func(ctx context.Context, d *HookDelivery)
+	Error       func(ctx context.Context, err error)
+
+	mu       sync.Mutex
+	attempts map[string]*reconcileAttempts
+	now      func() time.Time
+}
+
+type reconcileAttempts struct {
+	count int
+	first time.Time
+}
+
+// Reconcile checks the deliveries once, and returns the missing ones.
+//
+// With [ReconcileFetch] or [ReconcileRedeliver], it stops at the first delivery that can't be recovered.
+func (r *Reconciler) Reconcile(ctx context.Context) ([]*HookDelivery, error) {
+	now := r.getNow()
+	ds, err := r.api().list(ctx, now.Add(-r.getMaxAge()), now.Add(-r.getMinAge()))
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: %w", err)
+	}
+	r.pruneAttempts(now)
+	var missing []*HookDelivery
+	for _, d := range ds {
+		received, err := r.isReceived(ctx, d)
+		if err != nil {
+			return missing, fmt.Errorf("reconcile: delivery %s: %w", d.GUID, err)
+		}
+		if received {
+			r.forgetAttempts(d.GUID)
+			continue
+		}
+		missing = append(missing, d)
+		if r.Missing != nil {
+			r.Missing(ctx, d)
+		}
+		err = r.recoverDelivery(ctx, d)
+		if err != nil {
+			return missing, fmt.Errorf("reconcile: delivery %s: %w", d.GUID, err)
+		}
+	}
+	return missing, nil
+}
+
+// Run runs [Reconciler.Reconcile] periodically, until the context is canceled.
+//
+// The errors are reported to [Reconciler.Error].
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := r.Reconcile(ctx)
+		if err != nil && ctx.Err() == nil && r.Error != nil {
+			r.Error(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reconciler) isReceived(ctx context.Context, d *HookDelivery) (bool, error) {
+	if d.StatusCode >= 200 && d.StatusCode < 500 {
+		return true, nil
+	}
+	if r.Received != nil {
+		return r.Received(ctx, d.GUID)
+	}
+	if r.Handler.Store == nil {
+		return false, errors.New("store not configured")
+	}
+	_, err := r.Handler.Store.Get(ctx, d.GUID)
+	if errors.Is(err, ErrStoredDeliveryNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("store: %w", err)
+	}
+	return true, nil
+}
+
+func (r *Reconciler) recoverDelivery(ctx context.Context, d *HookDelivery) error {
+	if r.Action == ReconcileReport {
+		return nil
+	}
+	if r.Action == ReconcileRedeliver && r.Redeliverer != nil {
+		r.Redeliverer.schedule(ctx, d.GUID, d.ID)
+		return nil
+	}
+	attempt, abandoned := r.addAttempt(d.GUID)
+	if abandoned && r.Error != nil {
+		r.Error(ctx, fmt.Errorf("reconcile: delivery %s: %w", d.GUID, ErrReconcileMaxAttempts))
+	}
+	if !attempt {
+		return nil
+	}
+	if r.Action == ReconcileFetch {
+		return r.fetch(ctx, d)
+	}
+	return r.api().redeliver(ctx, d.ID)
+}
+
+// addAttempt counts a recovery attempt of a delivery.
+// It returns attempt=false if the maximum number of attempts is reached, and abandoned=true the first time.
+func (r *Reconciler) addAttempt(deliveryID string) (attempt bool, abandoned bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := r.attempts[deliveryID]
+	if a == nil {
+		a = &reconcileAttempts{
+			first: r.getNow(),
+		}
+		if r.attempts == nil {
+			r.attempts = make(map[string]*reconcileAttempts)
+		}
+		r.attempts[deliveryID] = a
+	}
+	maxAttempts := r.getMaxAttempts()
+	if a.count >= maxAttempts {
+		abandoned = a.count == maxAttempts
+		a.count++ // The abandon is reported once.
+		return false, abandoned
+	}
+	a.count++
+	return true, false
+}
+
+func (r *Reconciler) forgetAttempts(deliveryID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, deliveryID)
+}
+
+// pruneAttempts forgets the attempts older than MaxAge, whose deliveries are not listed anymore.
+func (r *Reconciler) pruneAttempts(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	maxAge := r.getMaxAge()
+	for deliveryID, a := range r.attempts {
+		if now.Sub(a.first) > maxAge {
+			delete(r.attempts, deliveryID)
+		}
+	}
+}
+
+// fetch fetches the request of a delivery, and runs it through the pipeline with the "reconcile" lineage.
+func (r *Reconciler) fetch(ctx context.Context, d *HookDelivery) error {
+	header, rawPayload, err := r.api().fetchRequest(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	md := NewDeliveryMetadata(d.Event, d.GUID, header.Get)
+	md.Lineage = NextLineage(md, "", "reconcile")
+	return r.Handler.processFetched(ctx, md, header, rawPayload)
+}
+
+// processFetched runs a delivery fetched from the hook deliveries API through the pipeline of the handler:
+// the events rejected by [Router] are ignored, [Handler.Quarantine] is applied, then [Handler.Process].
+func (h *Handler) processFetched(ctx context.Context, md *DeliveryMetadata, header http.Header, rawPayload []byte) error {
+	if h.acceptEvent != nil && h.acceptEvent(md.Event) != nil {
+		// The original delivery would have been rejected.
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(rawPayload))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header = header
+	if h.quarantine(md, rawPayload, newRequestInbound(req)) {
+		return nil
+	}
+	_, err = h.Process(ctx, md, rawPayload)
+	if err != nil {
+		return fmt.Errorf("process: %w", err)
+	}
+	return nil
+}
+
+func (r *Reconciler) api() *hookDeliveriesAPI {
+	return &hookDeliveriesAPI{
+		url:    r.URL,
+		token:  r.Token,
+		client: r.Client,
+	}
+}
+
+// hookDeliveriesAPI is a client of the GitHub hook deliveries API.
+type hookDeliveriesAPI struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// list lists the deliveries delivered between oldest and newest, with a single entry per GUID (the most recent attempt).
+//
+// The API returns the deliveries from the newest to the oldest, so the pagination stops at the first delivery older than oldest.
+func (api *hookDeliveriesAPI) list(ctx context.Context, oldest time.Time, newest time.Time) ([]*HookDelivery, error) {
+	u, err := url.Parse(api.url)
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("per_page", "100")
+	u.RawQuery = q.Encode()
+	next := u.String()
+	seen := make(map[string]bool)
+	var ds []*HookDelivery
+	for next != "" {
+		var page []*HookDelivery
+		var header http.Header
+		header, err = api.do(ctx, http.MethodGet, next, http.StatusOK, &page)
+		if err != nil {
+			return nil, err
+		}
+		next = nextPageURL(header)
+		for _, d := range page {
+			if d.DeliveredAt.Before(oldest) {
+				next = ""
+				break
+			}
+			if d.DeliveredAt.After(newest) || seen[d.GUID] {
+				continue
+			}
+			seen[d.GUID] = true
+			ds = append(ds, d)
+		}
+	}
+	return ds, nil
+}
+
+// fetchRequest fetches the request of a delivery: its headers and its payload.
+func (api *hookDeliveriesAPI) fetchRequest(ctx context.Context, id int64) (http.Header, []byte, error) {
+	var full struct {
+		Request struct {
+			Headers map[string]string `json:"headers"`
+			Payload json.RawMessage   `json:"payload"`
+		} `json:"request"`
+	}
+	_, err := api.do(ctx, http.MethodGet, api.deliveryURL(id, ""), http.StatusOK, &full)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch: %w", err)
+	}
+	header := make(http.Header, len(full.Request.Headers))
+	for k, v := range full.Request.Headers {
+		header.Set(k, v)
+	}
+	return header, []byte(full.Request.Payload), nil
+}
+
+// redeliver requests GitHub to redeliver a delivery.
+func (api *hookDeliveriesAPI) redeliver(ctx context.Context, id int64) error {
+	_, err := api.do(ctx, http.MethodPost, api.deliveryURL(id, "attempts"), http.StatusAccepted, nil)
+	if err != nil {
+		return fmt.Errorf("redeliver: %w", err)
+	}
+	return nil
+}
+
+func (api *hookDeliveriesAPI) deliveryURL(id int64, suffix string) string {
+	u, _ := url.Parse(api.url) // The URL is validated by list.
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strconv.FormatInt(id, 10)
+	if suffix != "" {
+		u.Path += "/" + suffix
+	}
+	u.RawQuery = ""
+	return u.String()
+}
+
+// do sends a request to the API, and decodes the JSON response in v (if not nil).
+func (api *hookDeliveriesAPI) do(ctx context.Context, method string, u string, statusCode int, v any) (http.Header, error) {
+	return doGitHubAPI(ctx, api.client, api.token, method, u, nil, statusCode, v)
+}
+
+// doGitHubAPI sends a request to the GitHub API, with the JSON encoded body (if not nil), and decodes the JSON response in v (if not nil).
+func doGitHubAPI(ctx context.Context, client *http.Client, token string, method string, u string, body any, statusCode int, v any) (http.Header, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("JSON encode: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != statusCode {
+		return nil, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	if v != nil {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("JSON decode: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// nextPageURL returns the URL of the next page from the Link header, or an empty string.
+func nextPageURL(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		u, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(u), "<>")
+	}
+	return ""
+}
+
+func (r *Reconciler) getMaxAge() time.Duration {
+	if r.MaxAge > 0 {
+		return r.MaxAge
+	}
+	return DefaultReconcileMaxAge
+}
+
+func (r *Reconciler) getMinAge() time.Duration {
+	if r.MinAge > 0 {
+		return r.MinAge
+	}
+	return DefaultReconcileMinAge
+}
+
+func (r *Reconciler) getMaxAttempts() int {
+	if r.MaxAttempts > 0 {
+		return r.MaxAttempts
+	}
+	return DefaultReconcileMaxAttempts
+}
+
+func (r *Reconciler) getNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,401 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+var testReconcileNow = time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+
+type testReconcileAPI struct {
+	*httptest.Server
+	mu        sync.Mutex
+	redeliver []string
+}
+
+// newTestReconcileAPI creates a fake deliveries API, with 2 pages:
+//   - 1: received
+//   - 2: missing, attempted twice
+//   - 3: too recent
+//   - 4: too old
+//   - 5: rejected by the handler (not stored)
+func newTestReconcileAPI(t *testing.T) *testReconcileAPI {
+	t.Helper()
+	api := new(testReconcileAPI)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hooks/1/deliveries", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var ds []*HookDelivery
+		switch req.URL.Query().Get("cursor") {
+		case "":
+			assert.Equal(t, req.URL.Query().Get("per_page"), "100")
+			w.Header().Set("Link", fmt.Sprintf(`<%s/hooks/1/deliveries?cursor=2>; rel="next", <%s/hooks/1/deliveries>; rel="first"`, api.URL, api.URL))
+			ds = []*HookDelivery{
+				{ID: 30, GUID: "guid-3", Event: "push", DeliveredAt: testReconcileNow.Add(-10 * time.Second)},
+				{ID: 22, GUID: "guid-2", Event: "push", DeliveredAt: testReconcileNow.Add(-2 * time.Minute), Redelivery: true},
+			}
+		case "2":
+			ds = []*HookDelivery{
+				{ID: 21, GUID: "guid-2", Event: "push", DeliveredAt: testReconcileNow.Add(-3 * time.Minute), StatusCode: 502},
+				{ID: 10, GUID: "guid-1", Event: "push", DeliveredAt: testReconcileNow.Add(-4 * time.Minute), StatusCode: 200},
+				{ID: 50, GUID: "guid-5", Event: "push", DeliveredAt: testReconcileNow.Add(-5 * time.Minute), StatusCode: 403},
+				{ID: 40, GUID: "guid-4", Event: "push", DeliveredAt: testReconcileNow.Add(-48 * time.Hour)},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(ds)
+	})
+	mux.HandleFunc("GET /hooks/1/deliveries/22", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"id":22,"guid":"guid-2","request":{"headers":{"X-GitHub-Event":"push","X-GitHub-Delivery":"guid-2","X-GitHub-Hook-ID":"1"},"payload":%s}}`, testRawPayload)
+	})
+	mux.HandleFunc("POST /hooks/1/deliveries/{id}/attempts", func(w http.ResponseWriter, req *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		api.redeliver = append(api.redeliver, req.PathValue("id"))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	api.Server = httptest.NewServer(mux)
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (api *testReconcileAPI) getRedeliver() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.redeliver
+}
+
+func newTestReconciler(api *testReconcileAPI, h *Handler) *Reconciler {
+	return &Reconciler{
+		URL:     api.URL + "/hooks/1/deliveries",
+		Token:   "token",
+		Handler: h,
+		now: func() time.Time {
+			return testReconcileNow
+		},
+	}
+}
+
+func newTestReconcileHandler(ctx context.Context, t *testing.T) *Handler {
+	t.Helper()
+	h := &Handler{
+		Secret: "secret",
+		Store:  NewMemoryStore(10),
+	}
+	err := h.Store.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			Event:      "push",
+			DeliveryID: "guid-1",
+		},
+	})
+	assert.NoError(t, err)
+	return h
+}
+
+func TestReconcilerReport(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	r := newTestReconciler(api, newTestReconcileHandler(ctx, t))
+	var reported []string
+	r.Missing = func(ctx context.Context, d *HookDelivery) {
+		reported = append(reported, d.GUID)
+	}
+	missing, err := r.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, missing, 1)
+	assert.Equal(t, missing[0].ID, int64(22))
+	assert.Equal(t, missing[0].GUID, "guid-2")
+	assert.SliceEqual(t, reported, []string{"guid-2"})
+	assert.SliceLen(t, api.getRedeliver(), 0)
+}
+
+func TestReconcilerFetch(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	h := newTestReconcileHandler(ctx, t)
+	var delivered *DeliveryMetadata
+	h.Delivery = func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		delivered = md
+		return nil
+	}
+	r := newTestReconciler(api, h)
+	r.Action = ReconcileFetch
+	missing, err := r.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, missing, 1)
+	assert.NotZero(t, delivered)
+	assert.Equal(t, delivered.DeliveryID, "guid-2")
+	assert.Equal(t, delivered.HookID, "1")
+	assert.DeepEqual(t, delivered.Lineage, &Lineage{
+		OriginalDeliveryID: "guid-2",
+		Generation:         1,
+		Reason:             "reconcile",
+	})
+	sd, err := h.Store.Get(ctx, "guid-2")
+	assert.NoError(t, err)
+	assert.Equal(t, sd.Status, DeliveryStatusSucceeded)
+	missing, err = r.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, missing, 0)
+}
+
+func TestReconcilerRedeliver(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	r := newTestReconciler(api, newTestReconcileHandler(ctx, t))
+	r.Action = ReconcileRedeliver
+	_, err := r.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, api.getRedeliver(), []string{"22"})
+}
+
+func TestReconcilerReceived(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	r := newTestReconciler(api, &Handler{})
+	var checked []string
+	r.Received = func(ctx context.Context, deliveryID string) (bool, error) {
+		checked = append(checked, deliveryID)
+		return false, nil
+	}
+	missing, err := r.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, missing, 1)
+	assert.Equal(t, missing[0].GUID, "guid-2")
+	assert.SliceEqual(t, checked, []string{"guid-2"})
+}
+
+func TestReconcilerFetchQuarantine(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	h := newTestReconcileHandler(ctx, t)
+	deliveryCount := 0
+	h.Delivery = func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		deliveryCount++
+		return nil
+	}
+	h.Quarantine = new(Quarantine)
+	h.SoftChecks = []SoftCheck{
+		CheckRemoteAddr(&SourceAllowlist{
+			Prefixes: StaticPrefixes{netip.MustParsePrefix("192.0.2.0/24")},
+		}),
+	}
+	r := newTestReconciler(api, h)
+	r.Action = ReconcileFetch
+	_, err := r.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, deliveryCount, 0)
+	ds := h.Quarantine.List()
+	assert.SliceLen(t, ds, 1)
+	assert.Equal(t, ds[0].DeliveryID, "guid-2")
+}
+
+func TestReconcilerFetchRouterReject(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	h := newTestReconcileHandler(ctx, t)
+	rt, err := NewRouter(h)
+	assert.NoError(t, err)
+	rt.Unregistered = UnregisteredEventReject
+	r := newTestReconciler(api, h)
+	r.Action = ReconcileFetch
+	_, err = r.Reconcile(ctx)
+	assert.NoError(t, err)
+	_, err = h.Store.Get(ctx, "guid-2")
+	assert.ErrorIs(t, err, ErrStoredDeliveryNotFound)
+}
+
+func TestReconcilerMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	r := newTestReconciler(api, newTestReconcileHandler(ctx, t))
+	r.Action = ReconcileRedeliver
+	r.MaxAttempts = 2
+	var errs []error
+	r.Error = func(ctx context.Context, err error) {
+		errs = append(errs, err)
+	}
+	for range 4 {
+		_, err := r.Reconcile(ctx)
+		assert.NoError(t, err)
+	}
+	assert.SliceEqual(t, api.getRedeliver(), []string{"22", "22"})
+	assert.SliceLen(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrReconcileMaxAttempts)
+	r.now = func() time.Time {
+		return testReconcileNow.Add(DefaultReconcileMaxAge + time.Minute)
+	}
+	r.pruneAttempts(r.getNow())
+	assert.MapLen(t, r.attempts, 0)
+}
+
+func TestReconcilerError(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	for _, tc := range []struct {
+		name      string
+		configure func(r *Reconciler)
+		expected  string
+	}{
+		{
+			name: "URL",
+			configure: func(r *Reconciler) {
+				r.URL = "://invalid"
+			},
+			expected: "parse URL",
+		},
+		{
+			name: "Status",
+			configure: func(r *Reconciler) {
+				r.Token = ""
+			},
+			expected: "unexpected response status 401",
+		},
+		{
+			name: "JSON",
+			configure: func(r *Reconciler) {
+				r.URL = api.URL + "/hooks/1/deliveries/22"
+			},
+			expected: "JSON decode",
+		},
+		{
+			name: "StoreNotConfigured",
+			configure: func(r *Reconciler) {
+				r.Handler = &Handler{}
+			},
+			expected: "store not configured",
+		},
+		{
+			name: "Store",
+			configure: func(r *Reconciler) {
+				r.Handler = &Handler{
+					Store: &testGetErrorStore{},
+				}
+			},
+			expected: "store: error",
+		},
+		{
+			name: "Received",
+			configure: func(r *Reconciler) {
+				r.Received = func(ctx context.Context, deliveryID string) (bool, error) {
+					return false, errors.New("error")
+				}
+			},
+			expected: "error",
+		},
+		{
+			name: "Fetch",
+			configure: func(r *Reconciler) {
+				r.Action = ReconcileFetch
+				r.Handler.Delivery = func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+					return errors.New("error")
+				}
+			},
+			expected: "process",
+		},
+		{
+			name: "Redeliver",
+			configure: func(r *Reconciler) {
+				r.Action = ReconcileRedeliver
+				r.URL = api.URL + "/hooks/1/deliveries?x=1"
+				r.Client = &http.Client{
+					Transport: testRoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+						if req.Method == http.MethodPost {
+							return nil, errors.New("error")
+						}
+						return http.DefaultTransport.RoundTrip(req)
+					}),
+				}
+			},
+			expected: "redeliver",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestReconciler(api, newTestReconcileHandler(ctx, t))
+			tc.configure(r)
+			_, err := r.Reconcile(ctx)
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+type testGetErrorStore struct {
+	Store
+}
+
+func (s *testGetErrorStore) Get(ctx context.Context, deliveryID string) (*StoredDelivery, error) {
+	return nil, errors.New("error")
+}
+
+type testRoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f testRoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestReconcilerRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := newTestReconcileAPI(t)
+	r := newTestReconciler(api, &Handler{})
+	var count int
+	r.Error = func(ctx context.Context, err error) {
+		assert.ErrorContains(t, err, "store not configured")
+		count++
+		if count == 2 {
+			cancel()
+		}
+	}
+	r.Run(ctx, time.Millisecond)
+	assert.Equal(t, count, 2)
+}
+
+func TestReconcilerDefault(t *testing.T) {
+	r := new(Reconciler)
+	assert.Equal(t, r.getMaxAge(), DefaultReconcileMaxAge)
+	assert.Equal(t, r.getMinAge(), DefaultReconcileMinAge)
+	assert.Equal(t, r.getMaxAttempts(), DefaultReconcileMaxAttempts)
+	assert.NotZero(t, r.getNow())
+}
+
+func TestNextPageURL(t *testing.T) {
+	for _, tc := range []struct {
+		link     string
+		expected string
+	}{
+		{
+			link:     "",
+			expected: "",
+		},
+		{
+			link:     `<https://api.github.com/a?cursor=1>; rel="next"`,
+			expected: "https://api.github.com/a?cursor=1",
+		},
+		{
+			link:     `<https://api.github.com/a>; rel="first", <https://api.github.com/a?cursor=2>; rel="next"`,
+			expected: "https://api.github.com/a?cursor=2",
+		},
+		{
+			link:     `<https://api.github.com/a>; rel="prev"`,
+			expected: "",
+		},
+	} {
+		header := make(http.Header)
+		if tc.link != "" {
+			header.Set("Link", tc.link)
+		}
+		assert.Equal(t, nextPageURL(header), tc.expected)
+	}
+}
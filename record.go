@@ -0,0 +1,80 @@
+package githubhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+/*
+Record is a recorded delivery, for offline processing with [Handler.ProcessRecords].
+
+Fields:
+  - Event is the event name.
+  - DeliveryID is the delivery ID (optional). If it's empty, it is generated with [Handler.GenerateDeliveryID].
+  - Headers are additional request headers (optional). If they contain signature headers, they are used instead of signing the payload with the secret of the handler.
+  - Payload is the raw JSON payload.
+  - Lineage is the lineage of a replayed delivery (optional), see [NextLineage].
+  - ReceivedAt is the time when the delivery was received (optional), e.g. to replay the deliveries in their original order and timing.
+*/
+type Record struct {
+	Event      string            `json:"event"`
+	DeliveryID string            `json:"delivery_id"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Payload    json.RawMessage   `json:"payload"`
+	Lineage    *Lineage          `json:"lineage,omitempty"`
+	ReceivedAt time.Time         `json:"received_at"`
+}
+
+// ProcessRecords reads JSONL [Record]s (one per line) and runs them through the full pipeline of the [Handler], without HTTP listener.
+//
+// It is useful for batch processing, and for testing processing logic in CI.
+// It stops at the first record that is not accepted, and returns an error with its line number.
+func (h *Handler) ProcessRecords(ctx context.Context, r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxRecordSize)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		err := h.processRecord(ctx, b)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	err := sc.Err()
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	return nil
+}
+
+// maxRecordSize is the maximum size of a record line.
+// GitHub caps payloads at 25 MB.
+const maxRecordSize = 32 << 20
+
+func (h *Handler) processRecord(ctx context.Context, b []byte) error {
+	var rec Record
+	err := json.Unmarshal(b, &rec)
+	if err != nil {
+		return fmt.Errorf("JSON unmarshal: %w", err)
+	}
+	req, err := h.newSyntheticRequest(ctx, rec.Event, rec.DeliveryID, rec.Payload)
+	if err != nil {
+		return err
+	}
+	if rec.Lineage != nil {
+		rec.Lineage.SetHeader(req.Header)
+	}
+	for k, v := range rec.Headers {
+		req.Header.Set(k, v)
+	}
+	return h.serveSyntheticRequest(req)
+}
@@ -0,0 +1,45 @@
+package githubhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerProcessRecords(t *testing.T) {
+	ctx := context.Background()
+	var deliveryIDs []string
+	h := &Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryIDs = append(deliveryIDs, md.DeliveryID)
+			return nil
+		},
+	}
+	r := strings.NewReader(`{"event":"push","delivery_id":"1","payload":{"foo":"bar"}}
+
+{"event":"issues","delivery_id":"2","payload":{"action":"opened"}}
+`)
+	err := h.ProcessRecords(ctx, r)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, deliveryIDs, []string{"1", "2"})
+}
+
+func TestHandlerProcessRecordsErrorSignature(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+	}
+	r := strings.NewReader(`{"event":"push","delivery_id":"1","headers":{"X-Hub-Signature-256":"sha256=00"},"payload":{}}`)
+	err := h.ProcessRecords(ctx, r)
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestHandlerProcessRecordsErrorJSON(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{}
+	err := h.ProcessRecords(ctx, strings.NewReader("not json"))
+	assert.Error(t, err)
+}
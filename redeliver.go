@@ -0,0 +1,302 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultRedeliverMaxAttempts is the default value of [Redeliverer.MaxAttempts].
+const DefaultRedeliverMaxAttempts = 3
+
+// DefaultRedeliverDelay is the default value of [Redeliverer.Delay].
+const DefaultRedeliverDelay = time.Minute
+
+// DefaultRedeliverMaxDelay is the default value of [Redeliverer.MaxDelay].
+const DefaultRedeliverMaxDelay = time.Hour
+
+var (
+	// ErrRedeliverMaxAttempts is reported to [Redeliverer.Error] if a delivery has reached the maximum number of redelivery attempts.
+	ErrRedeliverMaxAttempts = errors.New("max redelivery attempts reached")
+	// ErrRedeliverNotFound is reported to [Redeliverer.Error] if a delivery is not found by the hook deliveries API.
+	ErrRedeliverNotFound = errors.New("delivery not found")
+)
+
+/*
+Redeliverer requests GitHub to redeliver the failed deliveries, with the redeliver API of the GitHub hook deliveries API (POST .../deliveries/{id}/attempts).
+
+GitHub doesn't retry the failed deliveries automatically.
+The redeliveries are requested with an exponential backoff: the first one after Delay, then the delay is doubled for each attempt, up to MaxDelay.
+The number of attempts is capped per delivery GUID, so a delivery that always fails is not redelivered forever.
+Each redelivery request counts as an attempt, even if it fails.
+
+The failed deliveries are scheduled with [Redeliverer.Schedule], or automatically:
+  - it implements [Observer], so it schedules the deliveries that fail in [Handler.Delivery] (or [Handler.Sink]), see [Handler.Observer]. The deliveries with a [Lineage] are ignored, because they have not been sent by GitHub.
+  - it is used by [Reconciler.Redeliverer], for the missing deliveries.
+
+Fields:
+  - URL is the URL of the deliveries API of the webhook (required), see [Reconciler].
+  - Token is the token sent in the Authorization header (optional).
+  - Client is the HTTP client (default: [http.DefaultClient]).
+  - MaxAttempts is the maximum number of redelivery attempts per delivery (default: [DefaultRedeliverMaxAttempts]).
+  - Delay is the delay before the first attempt (default: [DefaultRedeliverDelay]).
+  - MaxDelay is the maximum delay between attempts (default: [DefaultRedeliverMaxDelay]).
+  - MaxAge is the maximum age of the deliveries searched in the API, and of the attempts counters (default: [DefaultReconcileMaxAge]).
+  - Error is called if a redelivery fails, or is abandoned (optional).
+
+The fields must not be modified after the first delivery is scheduled.
+*/
+type Redeliverer struct {
+	URL         string
+	Token       string
+	Client      *http.Client
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	MaxAge      time.Duration
+	Error       func(ctx context.Context, deliveryID string, err error)
+
+	mu       sync.Mutex
+	closed   bool
+	attempts map[string]*redeliverAttempts
+	wg       sync.WaitGroup
+	now      func() time.Time
+}
+
+type redeliverAttempts struct {
+	count     int
+	first     time.Time
+	pending   *time.Timer
+	abandoned bool
+}
+
+// Schedule schedules the redelivery of a delivery, identified by its GUID (the delivery ID).
+//
+// It returns false if the redelivery is not scheduled: the maximum number of attempts is reached, a redelivery is already pending, or the [Redeliverer] has been shut down.
+// The context is passed to [Redeliverer.Error], without its cancellation.
+func (r *Redeliverer) Schedule(ctx context.Context, deliveryID string) bool {
+	return r.schedule(ctx, deliveryID, 0)
+}
+
+// schedule schedules the redelivery of a delivery.
+// If id is 0, it's searched in the API.
+func (r *Redeliverer) schedule(ctx context.Context, deliveryID string, id int64) bool {
+	ctx = context.WithoutCancel(ctx)
+	scheduled, abandoned := r.scheduleAttempt(ctx, deliveryID, id)
+	if abandoned {
+		r.reportError(ctx, deliveryID, ErrRedeliverMaxAttempts)
+	}
+	return scheduled
+}
+
+// scheduleAttempt schedules the next attempt of a delivery.
+// It returns abandoned=true the first time the maximum number of attempts is reached.
+func (r *Redeliverer) scheduleAttempt(ctx context.Context, deliveryID string, id int64) (scheduled bool, abandoned bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false, false
+	}
+	now := r.getNow()
+	r.prune(now)
+	a := r.attempts[deliveryID]
+	if a == nil {
+		a = &redeliverAttempts{
+			first: now,
+		}
+		if r.attempts == nil {
+			r.attempts = make(map[string]*redeliverAttempts)
+		}
+		r.attempts[deliveryID] = a
+	}
+	if a.pending != nil || a.abandoned {
+		return false, false
+	}
+	if a.count >= r.getMaxAttempts() {
+		a.abandoned = true
+		return false, true
+	}
+	delay := r.delay(a.count)
+	a.count++
+	a.pending = time.AfterFunc(delay, func() {
+		r.attempt(ctx, deliveryID, id)
+	})
+	return true, false
+}
+
+// attempt requests a redelivery, and schedules another attempt if it fails.
+//
+// The attempt is not pending anymore before the request, because the redelivery can fail and be scheduled again (by [Redeliverer.ObserveDelivery]) before the response is received.
+func (r *Redeliverer) attempt(ctx context.Context, deliveryID string, id int64) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	if a := r.attempts[deliveryID]; a != nil {
+		a.pending = nil
+	}
+	r.wg.Add(1)
+	r.mu.Unlock()
+	defer r.wg.Done()
+	err := r.redeliver(ctx, deliveryID, id)
+	if err == nil {
+		return
+	}
+	r.reportError(ctx, deliveryID, err)
+	if !errors.Is(err, ErrRedeliverNotFound) {
+		r.schedule(ctx, deliveryID, id)
+	}
+}
+
+func (r *Redeliverer) redeliver(ctx context.Context, deliveryID string, id int64) error {
+	api := &hookDeliveriesAPI{
+		url:    r.URL,
+		token:  r.Token,
+		client: r.Client,
+	}
+	if id == 0 {
+		now := r.getNow()
+		ds, err := api.list(ctx, now.Add(-r.getMaxAge()), now)
+		if err != nil {
+			return fmt.Errorf("redeliver: %w", err)
+		}
+		for _, d := range ds {
+			if d.GUID == deliveryID {
+				id = d.ID
+				break
+			}
+		}
+		if id == 0 {
+			return fmt.Errorf("redeliver: %w", ErrRedeliverNotFound)
+		}
+	}
+	return api.redeliver(ctx, id)
+}
+
+// Forget forgets the attempts of a delivery, and cancels its pending redelivery.
+//
+// It's called automatically when a delivery succeeds, with [Observer].
+func (r *Redeliverer) Forget(deliveryID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.forget(deliveryID)
+}
+
+func (r *Redeliverer) forget(deliveryID string) {
+	a := r.attempts[deliveryID]
+	if a == nil {
+		return
+	}
+	if a.pending != nil {
+		a.pending.Stop()
+	}
+	delete(r.attempts, deliveryID)
+}
+
+// prune forgets the attempts older than MaxAge, which can't be redelivered anymore.
+func (r *Redeliverer) prune(now time.Time) {
+	maxAge := r.getMaxAge()
+	for deliveryID, a := range r.attempts {
+		if a.pending == nil && now.Sub(a.first) > maxAge {
+			delete(r.attempts, deliveryID)
+		}
+	}
+}
+
+// delay returns the delay before an attempt, after count previous attempts.
+func (r *Redeliverer) delay(count int) time.Duration {
+	d := r.getDelay()
+	maxDelay := r.getMaxDelay()
+	for range count {
+		d *= 2
+		if d >= maxDelay {
+			return maxDelay
+		}
+	}
+	return min(d, maxDelay)
+}
+
+// ObserveRequest implements [Observer].
+func (r *Redeliverer) ObserveRequest(ctx context.Context, event string, statusCode int, err error) {}
+
+// ObserveDelivery implements [Observer].
+//
+// It schedules the redelivery of the failed deliveries, and forgets the attempts of the succeeded ones.
+func (r *Redeliverer) ObserveDelivery(ctx context.Context, md *DeliveryMetadata, duration time.Duration, err error) {
+	if md.Lineage != nil {
+		return
+	}
+	if err == nil {
+		r.Forget(md.DeliveryID)
+		return
+	}
+	r.Schedule(ctx, md.DeliveryID)
+}
+
+// Shutdown cancels the pending redeliveries, and waits until the redeliveries in progress are requested.
+//
+// It returns the context error if the context is done before.
+func (r *Redeliverer) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	for deliveryID := range r.attempts {
+		r.forget(deliveryID)
+	}
+	r.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("redeliverer shutdown: %w", ctx.Err())
+	}
+}
+
+func (r *Redeliverer) reportError(ctx context.Context, deliveryID string, err error) {
+	if r.Error != nil {
+		r.Error(ctx, deliveryID, err)
+	}
+}
+
+func (r *Redeliverer) getMaxAttempts() int {
+	if r.MaxAttempts > 0 {
+		return r.MaxAttempts
+	}
+	return DefaultRedeliverMaxAttempts
+}
+
+func (r *Redeliverer) getDelay() time.Duration {
+	if r.Delay > 0 {
+		return r.Delay
+	}
+	return DefaultRedeliverDelay
+}
+
+func (r *Redeliverer) getMaxDelay() time.Duration {
+	if r.MaxDelay > 0 {
+		return r.MaxDelay
+	}
+	return DefaultRedeliverMaxDelay
+}
+
+func (r *Redeliverer) getMaxAge() time.Duration {
+	if r.MaxAge > 0 {
+		return r.MaxAge
+	}
+	return DefaultReconcileMaxAge
+}
+
+func (r *Redeliverer) getNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
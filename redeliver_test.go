@@ -0,0 +1,292 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+type testRedeliverErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (e *testRedeliverErrors) add(ctx context.Context, deliveryID string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errs = append(e.errs, err)
+}
+
+func (e *testRedeliverErrors) get() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.errs
+}
+
+func newTestRedeliverer(api *testReconcileAPI, errs *testRedeliverErrors) *Redeliverer {
+	return &Redeliverer{
+		URL:   api.URL + "/hooks/1/deliveries",
+		Token: "token",
+		Delay: time.Millisecond,
+		Error: errs.add,
+		now: func() time.Time {
+			return testReconcileNow
+		},
+	}
+}
+
+func testWaitFor(t *testing.T, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRedeliverer(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	ok := r.Schedule(ctx, "guid-2")
+	assert.True(t, ok)
+	ok = r.Schedule(ctx, "guid-2")
+	assert.False(t, ok) // Pending.
+	testWaitFor(t, func() bool {
+		return len(api.getRedeliver()) == 1
+	})
+	assert.SliceEqual(t, api.getRedeliver(), []string{"22"})
+	err := r.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, errs.get(), 0)
+}
+
+func TestRedelivererScheduleDuringRequest(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	r.Delay = time.Hour
+	var scheduled []bool
+	r.Client = &http.Client{
+		Transport: testRoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				// The redelivery fails before the response of the API.
+				scheduled = append(scheduled, r.Schedule(ctx, "guid-2"))
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	r.attempts = map[string]*redeliverAttempts{
+		"guid-2": {
+			count:   1,
+			first:   testReconcileNow,
+			pending: time.NewTimer(0), // Fired.
+		},
+	}
+	r.attempt(ctx, "guid-2", 22)
+	assert.SliceEqual(t, scheduled, []bool{true})
+	assert.SliceEqual(t, api.getRedeliver(), []string{"22"})
+	err := r.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, errs.get(), 0)
+}
+
+func TestRedelivererMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	var mu sync.Mutex
+	var posts int
+	r.Client = &http.Client{
+		Transport: testRoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPost {
+				return http.DefaultTransport.RoundTrip(req)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			posts++
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	r.MaxAttempts = 2
+	ok := r.Schedule(ctx, "guid-2")
+	assert.True(t, ok)
+	testWaitFor(t, func() bool {
+		return len(errs.get()) == 3
+	})
+	es := errs.get()
+	assert.ErrorContains(t, es[0], "unexpected response status 500")
+	assert.ErrorContains(t, es[1], "unexpected response status 500")
+	assert.ErrorIs(t, es[2], ErrRedeliverMaxAttempts)
+	mu.Lock()
+	assert.Equal(t, posts, 2)
+	mu.Unlock()
+	ok = r.Schedule(ctx, "guid-2")
+	assert.False(t, ok)
+	assert.SliceLen(t, errs.get(), 3) // Reported once.
+}
+
+func TestRedelivererNotFound(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	r.Schedule(ctx, "unknown")
+	testWaitFor(t, func() bool {
+		return len(errs.get()) == 1
+	})
+	assert.ErrorIs(t, errs.get()[0], ErrRedeliverNotFound)
+	err := r.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.SliceLen(t, errs.get(), 1) // Not retried.
+}
+
+func TestRedelivererListError(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	r.Token = ""
+	r.MaxAttempts = 1
+	r.Schedule(ctx, "guid-2")
+	testWaitFor(t, func() bool {
+		return len(errs.get()) == 2
+	})
+	assert.ErrorContains(t, errs.get()[0], "unexpected response status 401")
+	assert.ErrorIs(t, errs.get()[1], ErrRedeliverMaxAttempts)
+}
+
+func TestRedelivererObserver(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	r.Delay = time.Hour
+	var _ Observer = r
+	r.ObserveRequest(ctx, "push", http.StatusOK, nil)
+	r.ObserveDelivery(ctx, &DeliveryMetadata{DeliveryID: "guid-2"}, time.Second, errors.New("error"))
+	assert.MapLen(t, r.attempts, 1)
+	r.ObserveDelivery(ctx, &DeliveryMetadata{DeliveryID: "guid-2"}, time.Second, nil)
+	assert.MapLen(t, r.attempts, 0)
+	r.ObserveDelivery(ctx, &DeliveryMetadata{DeliveryID: "guid-2", Lineage: &Lineage{}}, time.Second, errors.New("error"))
+	assert.MapLen(t, r.attempts, 0)
+}
+
+func TestRedelivererHandler(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	h := &Handler{
+		Secret: "secret",
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errors.New("error")
+		},
+		Observer: r,
+	}
+	md := NewDeliveryMetadata("push", "guid-2", func(name string) string { return "" })
+	_, err := h.Process(ctx, md, testRawPayload)
+	assert.Error(t, err)
+	testWaitFor(t, func() bool {
+		return len(api.getRedeliver()) == 1
+	})
+}
+
+func TestRedelivererShutdown(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	r.Delay = time.Hour
+	ok := r.Schedule(ctx, "guid-2")
+	assert.True(t, ok)
+	err := r.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.MapLen(t, r.attempts, 0)
+	ok = r.Schedule(ctx, "guid-2")
+	assert.False(t, ok)
+	r.attempt(ctx, "guid-2", 22) // Closed.
+	assert.SliceLen(t, api.getRedeliver(), 0)
+}
+
+func TestRedelivererShutdownTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := new(Redeliverer)
+	r.wg.Add(1)
+	defer r.wg.Done()
+	err := r.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRedelivererPrune(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestRedeliverer(api, errs)
+	now := testReconcileNow
+	r.now = func() time.Time {
+		return now
+	}
+	r.MaxAttempts = 1
+	r.Schedule(ctx, "guid-2")
+	testWaitFor(t, func() bool {
+		return len(api.getRedeliver()) == 1
+	})
+	testWaitFor(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.attempts["guid-2"].pending == nil
+	})
+	ok := r.Schedule(ctx, "guid-2")
+	assert.False(t, ok)
+	now = now.Add(DefaultReconcileMaxAge + time.Second)
+	ok = r.Schedule(ctx, "guid-2")
+	assert.True(t, ok)
+}
+
+func TestRedelivererDelay(t *testing.T) {
+	r := &Redeliverer{
+		Delay:    time.Second,
+		MaxDelay: 5 * time.Second,
+	}
+	for count, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		assert.Equal(t, r.delay(count), expected)
+	}
+	r = new(Redeliverer)
+	assert.Equal(t, r.delay(0), DefaultRedeliverDelay)
+	assert.Equal(t, r.delay(10), DefaultRedeliverMaxDelay)
+	assert.Equal(t, r.getMaxAttempts(), DefaultRedeliverMaxAttempts)
+	assert.Equal(t, r.getMaxAge(), DefaultReconcileMaxAge)
+	assert.NotZero(t, r.getNow())
+}
+
+func TestReconcilerRedeliverer(t *testing.T) {
+	ctx := context.Background()
+	api := newTestReconcileAPI(t)
+	errs := new(testRedeliverErrors)
+	r := newTestReconciler(api, newTestReconcileHandler(ctx, t))
+	r.Action = ReconcileRedeliver
+	r.Redeliverer = newTestRedeliverer(api, errs)
+	_, err := r.Reconcile(ctx)
+	assert.NoError(t, err)
+	testWaitFor(t, func() bool {
+		return len(api.getRedeliver()) == 1
+	})
+	assert.SliceEqual(t, api.getRedeliver(), []string{"22"})
+}
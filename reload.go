@@ -0,0 +1,55 @@
+package githubhook
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+/*
+ReloadableHandler is a [http.Handler] that serves the requests with a [Handler] that can be replaced atomically at runtime, e.g. to reload the secrets, the event filters or the sinks from a configuration file.
+
+The configuration of a [Handler] is read from its fields during the whole request, so they must not be modified while it's serving.
+Instead, a new [Handler] is created with the new configuration, and replaces the current one with [ReloadableHandler.Reload].
+The requests in progress keep using the handler they started with, so the in-flight deliveries are not dropped.
+
+The stateful components (e.g. [Handler.Async], [Handler.Dedup], [Handler.Store], [Handler.Quarantine]) should be shared by the new handler, so their state is preserved.
+The previous handler returned by [ReloadableHandler.Reload] should be closed with [Handler.Close], which waits for its deliveries in progress and closes its sink (not the shared components).
+
+It must be created with [NewReloadableHandler].
+*/
+type ReloadableHandler struct {
+	handler atomic.Pointer[Handler]
+}
+
+// NewReloadableHandler creates a new [ReloadableHandler] with an initial [Handler].
+func NewReloadableHandler(h *Handler) *ReloadableHandler {
+	r := new(ReloadableHandler)
+	r.handler.Store(h)
+	return r
+}
+
+// Handler returns the current [Handler].
+func (r *ReloadableHandler) Handler() *Handler {
+	return r.handler.Load()
+}
+
+// Reload replaces the current [Handler], and returns the previous one.
+//
+// The new requests are served by the new handler.
+// The previous handler should be closed with [Handler.Close].
+func (r *ReloadableHandler) Reload(h *Handler) *Handler {
+	return r.handler.Swap(h)
+}
+
+func (r *ReloadableHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Handler().ServeHTTP(w, req)
+}
+
+// Middleware returns an [http.Handler] that verifies the deliveries with the current [Handler], and calls next with the verified delivery.
+//
+// See [Handler.Middleware].
+func (r *ReloadableHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Handler().Middleware(next).ServeHTTP(w, req)
+	})
+}
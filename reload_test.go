@@ -0,0 +1,91 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestReloadableHandler(t *testing.T) {
+	ctx := context.Background()
+	h1 := &Handler{
+		Secret: "secret1",
+	}
+	r := NewReloadableHandler(h1)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	resp, err := http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "secret1", testRawPayload))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	old := r.Reload(&Handler{
+		Secret: "secret2",
+	})
+	assert.Equal(t, old, h1)
+	assert.Equal(t, r.Handler().Secret, "secret2")
+	resp, err = http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "secret1", testRawPayload))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+	resp, err = http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "secret2", testRawPayload))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+}
+
+func TestReloadableHandlerInFlight(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	r := NewReloadableHandler(&Handler{
+		Secret: "secret1",
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			close(started)
+			<-unblock
+			return nil
+		},
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	done := make(chan *http.Response)
+	go func() {
+		resp, err := http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "secret1", testRawPayload))
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		done <- resp
+	}()
+	<-started
+	r.Reload(&Handler{
+		Secret: "secret2",
+	})
+	close(unblock)
+	testExpectResponseStatusOK(t, <-done)
+}
+
+func TestReloadableHandlerMiddleware(t *testing.T) {
+	ctx := context.Background()
+	r := NewReloadableHandler(&Handler{
+		Secret: "secret1",
+	})
+	var called bool
+	srv := httptest.NewServer(r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, called = VerifiedDeliveryFromContext(req.Context())
+	})))
+	defer srv.Close()
+	r.Reload(&Handler{
+		Secret: "secret2",
+	})
+	resp, err := http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "secret1", testRawPayload))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+	assert.False(t, called)
+	resp, err = http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "secret2", testRawPayload))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	assert.True(t, called)
+}
@@ -0,0 +1,136 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/pierrre/githubhook/events"
+)
+
+/*
+RepositoryRenames tracks the renames and transfers of repositories, from "repository" deliveries.
+
+It maintains a mapping of old to new repository full names, so configurations keyed by repository name keep working after a rename (see [RepositoryIs]).
+[RepositoryRenames.Handle] must receive the deliveries, e.g. with [Tee].
+It only supports payloads decoded as *[events.RepositoryEvent] (the default).
+Names are case-insensitive, like on GitHub.
+
+The zero value is ready to use.
+*/
+type RepositoryRenames struct {
+	mu    sync.RWMutex
+	names map[string]string
+}
+
+// Handle records the rename or transfer of a "repository" delivery.
+//
+// It implements [DeliveryHandler].
+func (r *RepositoryRenames) Handle(ctx context.Context, md *DeliveryMetadata, payload any) error {
+	ev, ok := payload.(*events.RepositoryEvent)
+	if !ok || ev.Repository == nil || ev.Changes == nil {
+		return nil
+	}
+	newName := ev.Repository.FullName
+	owner, name, ok := strings.Cut(newName, "/")
+	if !ok {
+		return nil
+	}
+	switch ev.Action {
+	case "renamed":
+		if c := ev.Changes.Repository; c != nil && c.Name != nil && c.Name.From != "" {
+			r.Add(owner+"/"+c.Name.From, newName)
+		}
+	case "transferred":
+		if c := ev.Changes.Owner; c != nil {
+			switch {
+			case c.From.User != nil && c.From.User.Login != "":
+				r.Add(c.From.User.Login+"/"+name, newName)
+			case c.From.Organization != nil && c.From.Organization.Login != "":
+				r.Add(c.From.Organization.Login+"/"+name, newName)
+			}
+		}
+	}
+	return nil
+}
+
+// Add records a rename.
+func (r *RepositoryRenames) Add(oldName string, newName string) {
+	oldName = strings.ToLower(oldName)
+	newName = strings.ToLower(newName)
+	if oldName == newName {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names == nil {
+		r.names = make(map[string]string)
+	}
+	r.names[oldName] = newName
+	// The new name is now in use, so it's no longer an old name.
+	delete(r.names, newName)
+}
+
+// Resolve returns the current name (lowercase) of a repository, following successive renames.
+func (r *RepositoryRenames) Resolve(name string) string {
+	name = strings.ToLower(name)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for range len(r.names) {
+		newName, ok := r.names[name]
+		if !ok {
+			break
+		}
+		name = newName
+	}
+	return name
+}
+
+// RepositoryIs returns a [DeliveryPredicate] that matches deliveries of the given repositories (full names).
+//
+// The names are resolved with the renames, so they match after a rename or a transfer.
+// renames is optional.
+func RepositoryIs(renames *RepositoryRenames, names ...string) DeliveryPredicate {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) bool {
+		repo := RepositoryFullName(payload)
+		if repo == "" {
+			return false
+		}
+		for _, name := range names {
+			if renames != nil {
+				name = renames.Resolve(name)
+			}
+			if strings.EqualFold(name, repo) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RepositoryFullName returns the repository full name of a decoded payload, or an empty string.
+//
+// It supports the types of the events package, generic map[string]any payloads, and raw JSON payloads ([json.RawMessage]).
+func RepositoryFullName(payload any) string {
+	switch p := payload.(type) {
+	case json.RawMessage:
+		var v struct {
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		}
+		_ = json.Unmarshal(p, &v)
+		return v.Repository.FullName
+	case interface{ GetCommon() *events.Common }:
+		if c := p.GetCommon(); c.Repository != nil {
+			return c.Repository.FullName
+		}
+	case map[string]any:
+		if repo, ok := p["repository"].(map[string]any); ok {
+			name, _ := repo["full_name"].(string)
+			return name
+		}
+	}
+	return ""
+}
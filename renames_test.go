@@ -0,0 +1,96 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestRepositoryRenames(t *testing.T) {
+	ctx := context.Background()
+	r := new(RepositoryRenames)
+	for _, tc := range []struct {
+		event      string
+		rawPayload string
+	}{
+		{
+			event:      "repository",
+			rawPayload: `{"action":"renamed","changes":{"repository":{"name":{"from":"old"}}},"repository":{"full_name":"octocat/new"}}`,
+		},
+		{
+			event:      "repository",
+			rawPayload: `{"action":"transferred","changes":{"owner":{"from":{"user":{"login":"octocat"}}}},"repository":{"full_name":"github/new"}}`,
+		},
+		{
+			event:      "repository",
+			rawPayload: `{"action":"created","repository":{"full_name":"octocat/other"}}`,
+		},
+	} {
+		payload, err := events.Decode(tc.event, []byte(tc.rawPayload))
+		assert.NoError(t, err)
+		err = r.Handle(ctx, &DeliveryMetadata{Event: tc.event}, payload)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, r.Resolve("Octocat/Old"), "github/new")
+	assert.Equal(t, r.Resolve("octocat/new"), "github/new")
+	assert.Equal(t, r.Resolve("octocat/other"), "octocat/other")
+}
+
+func TestRepositoryRenamesCycle(t *testing.T) {
+	r := new(RepositoryRenames)
+	r.Add("octocat/a", "octocat/b")
+	r.Add("octocat/b", "octocat/a")
+	assert.Equal(t, r.Resolve("octocat/a"), "octocat/a")
+	assert.Equal(t, r.Resolve("octocat/b"), "octocat/a")
+}
+
+func TestRepositoryIs(t *testing.T) {
+	ctx := context.Background()
+	r := new(RepositoryRenames)
+	r.Add("octocat/old", "octocat/new")
+	p := RepositoryIs(r, "octocat/old")
+	md := &DeliveryMetadata{Event: "push"}
+	assert.True(t, p(ctx, md, map[string]any{"repository": map[string]any{"full_name": "octocat/New"}}))
+	assert.False(t, p(ctx, md, &events.PushEvent{Common: events.Common{Repository: &events.Repository{FullName: "octocat/other"}}}))
+	assert.False(t, p(ctx, md, nil))
+	assert.True(t, RepositoryIs(nil, "octocat/new")(ctx, md, &events.PushEvent{Common: events.Common{Repository: &events.Repository{FullName: "octocat/new"}}}))
+}
+
+func TestRepositoryFullName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		payload  any
+		expected string
+	}{
+		{
+			name: "Event",
+			payload: &events.PushEvent{
+				Common: events.Common{
+					Repository: &events.Repository{FullName: "octocat/hello"},
+				},
+			},
+			expected: "octocat/hello",
+		},
+		{
+			name:     "Map",
+			payload:  map[string]any{"repository": map[string]any{"full_name": "octocat/hello"}},
+			expected: "octocat/hello",
+		},
+		{
+			name:     "Raw",
+			payload:  json.RawMessage(`{"repository":{"full_name":"octocat/hello"}}`),
+			expected: "octocat/hello",
+		},
+		{
+			name:    "Unknown",
+			payload: "unknown",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, RepositoryFullName(tc.payload), tc.expected)
+		})
+	}
+}
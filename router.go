@@ -0,0 +1,162 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"text/template"
+)
+
+// UnregisteredEventPolicy defines how a [Router] handles deliveries of events without registered handler.
+type UnregisteredEventPolicy int
+
+const (
+	// UnregisteredEventIgnore accepts the delivery, and ignores it.
+	UnregisteredEventIgnore UnregisteredEventPolicy = iota
+	// UnregisteredEventReject rejects the delivery with a 400 response.
+	UnregisteredEventReject
+)
+
+/*
+Router dispatches deliveries to [DeliveryHandler]s registered per event.
+
+It wraps a [Handler], and must be created with [NewRouter].
+Unregistered is the policy for events without registered handler (default: [UnregisteredEventIgnore]).
+The response of each route can be overridden with [Router.Respond].
+*/
+type Router struct {
+	Unregistered UnregisteredEventPolicy
+
+	handler   *Handler
+	mu        sync.RWMutex
+	handlers  map[string][]DeliveryHandler
+	responses map[string]*RouteResponse
+}
+
+/*
+RouteResponse overrides the response of the deliveries of an event, see [Router.Respond].
+
+Fields (all are optional):
+  - StatusCode is the status of the successful responses, instead of 200 (or 202 with [Handler.Async]).
+  - Body is the template of the body of the successful responses, executed with the [DeliveryMetadata] (e.g. "processed {{.DeliveryID}}"). If it fails, the response has no body, and the error is reported to [Handler.Error].
+  - ContentType is the content type of Body (default: "text/plain; charset=utf-8").
+  - SanitizeErrors replaces the message of the error responses with the status text (e.g. "Internal Server Error"), so the details of the errors are not exposed.
+
+It only applies to the verified deliveries of the event: the requests rejected before (e.g. with an invalid signature), and the duplicates (see [Handler.DuplicatePolicy]), get the response of [Handler].
+*/
+type RouteResponse struct {
+	StatusCode     int
+	Body           *template.Template
+	ContentType    string
+	SanitizeErrors bool
+}
+
+// NewRouter creates a new [Router] for a [Handler].
+//
+// It sets [Handler.Delivery].
+// It returns an error if the handler already has a Delivery or a [Handler.Sink], because they would be replaced or bypassed by the router.
+func NewRouter(h *Handler) (*Router, error) {
+	if h.Delivery != nil || h.Sink != nil {
+		return nil, errors.New("router: the handler already has a Delivery or a Sink")
+	}
+	r := &Router{
+		handler:   h,
+		handlers:  make(map[string][]DeliveryHandler),
+		responses: make(map[string]*RouteResponse),
+	}
+	h.Delivery = r.dispatch
+	h.acceptEvent = r.acceptEvent
+	h.routeResponse = r.getResponse
+	return r, nil
+}
+
+// On registers a [DeliveryHandler] for an event.
+//
+// Multiple handlers can be registered for the same event, they are called in registration order.
+func (r *Router) On(event string, h DeliveryHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[event] = append(r.handlers[event], h)
+}
+
+// Respond overrides the response of the deliveries of an event.
+//
+// A nil response restores the response of [Handler].
+func (r *Router) Respond(event string, resp *RouteResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if resp == nil {
+		delete(r.responses, event)
+		return
+	}
+	r.responses[event] = resp
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+func (r *Router) getHandlers(event string) []DeliveryHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handlers[event]
+}
+
+func (r *Router) getResponse(event string) *RouteResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.responses[event]
+}
+
+func (r *Router) acceptEvent(event string) error {
+	if r.Unregistered == UnregisteredEventReject && len(r.getHandlers(event)) == 0 {
+		return &RequestError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "unregistered event: " + event,
+		}
+	}
+	return nil
+}
+
+func (r *Router) dispatch(ctx context.Context, md *DeliveryMetadata, payload any) error {
+	return Tee(r.getHandlers(md.Event)...)(ctx, md, payload)
+}
+
+// getRouteResponse returns the [RouteResponse] of a verified delivery, or nil.
+func (h *Handler) getRouteResponse(md *DeliveryMetadata) *RouteResponse {
+	if md == nil || h.routeResponse == nil {
+		return nil
+	}
+	return h.routeResponse(md.Event)
+}
+
+// writeRouteResponse writes the successful response of a delivery, overridden by a [RouteResponse], and returns its status code.
+func (h *Handler) writeRouteResponse(w http.ResponseWriter, in *inbound, md *DeliveryMetadata, statusCode int, rr *RouteResponse) int {
+	if rr.StatusCode != 0 {
+		statusCode = rr.StatusCode
+	}
+	var body bytes.Buffer
+	if rr.Body != nil {
+		err := rr.Body.Execute(&body, md)
+		if err != nil {
+			body.Reset()
+			if h.Error != nil {
+				h.Error(in.ctx, fmt.Errorf("route response body: %w", err), in.request())
+			}
+		}
+	}
+	h.writeResponseHeader(w, statusCode)
+	if body.Len() > 0 {
+		contentType := rr.ContentType
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(statusCode)
+	_, _ = body.WriteTo(w)
+	return statusCode
+}
@@ -0,0 +1,166 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/pierrre/assert"
+)
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewRouter(&Handler{})
+	assert.NoError(t, err)
+	count := 0
+	r.On("push", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		count++
+		return nil
+	})
+	r.On("push", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		count++
+		return nil
+	})
+	r.On("issues", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		t.Fatal("unexpected call")
+		return nil
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, count, 2)
+}
+
+func TestRouterUnregisteredIgnore(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewRouter(&Handler{})
+	assert.NoError(t, err)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+}
+
+func TestRouterUnregisteredReject(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewRouter(&Handler{})
+	assert.NoError(t, err)
+	r.Unregistered = UnregisteredEventReject
+	r.On("issues", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		return nil
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestNewRouterError(t *testing.T) {
+	_, err := NewRouter(&Handler{
+		Sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+			return nil
+		}),
+	})
+	assert.Error(t, err)
+	_, err = NewRouter(&Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestRouterRespond(t *testing.T) {
+	ctx := context.Background()
+	var errs []error
+	r, err := NewRouter(&Handler{
+		Error: func(ctx context.Context, err error, req *http.Request) {
+			errs = append(errs, err)
+		},
+	})
+	assert.NoError(t, err)
+	r.On("push", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		return nil
+	})
+	r.On("issues", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		return errors.New("internal details")
+	})
+	r.On("release", func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		return nil
+	})
+	r.Respond("push", &RouteResponse{
+		StatusCode:  http.StatusAccepted,
+		Body:        template.Must(template.New("").Parse(`{"delivery":"{{.DeliveryID}}"}`)),
+		ContentType: "application/json",
+	})
+	r.Respond("issues", &RouteResponse{
+		SanitizeErrors: true,
+	})
+	r.Respond("release", &RouteResponse{
+		Body: template.Must(template.New("").Parse(`{{.Invalid}}`)),
+	})
+	for _, tc := range []struct {
+		event               string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			event:               "push",
+			expectedStatus:      http.StatusAccepted,
+			expectedContentType: "application/json",
+			expectedBody:        `{"delivery":"test"}`,
+		},
+		{
+			event:               "issues",
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "Internal Server Error\n",
+		},
+		{
+			event:          "release",
+			expectedStatus: http.StatusOK,
+		},
+	} {
+		t.Run(tc.event, func(t *testing.T) {
+			req, err := new(Signer).NewRequest(ctx, "/", tc.event, "test", testRawPayload)
+			assert.NoError(t, err)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, tc.expectedStatus)
+			assert.Equal(t, w.Header().Get("Content-Type"), tc.expectedContentType)
+			b, err := io.ReadAll(w.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, string(b), tc.expectedBody)
+		})
+	}
+	assert.SliceLen(t, errs, 2)
+	assert.ErrorContains(t, errs[1], "route response body")
+	r.Respond("push", nil)
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "test2", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "")
+}
@@ -0,0 +1,129 @@
+package githubhook
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pierrre/githubhook/events"
+)
+
+/*
+SchemaDrift detects the drift between the received payloads and the payload types of the [events] package, so the changes of the GitHub payloads are known before something breaks.
+
+It counts, per event, the unknown fields (in the payload but not in the type) and the missing fields (expected by the type but not in the payload), see [events.CompareFields].
+The payloads are compared by [SchemaDrift.DecodePayload], which must be set as [Handler.DecodePayload].
+The events without payload type are ignored.
+
+Fields (all are optional):
+  - Report is called periodically by [SchemaDrift.Run] with the current drift, if there is any.
+
+The zero value is ready to use.
+*/
+type SchemaDrift struct {
+	Report func(ctx context.Context, drift []*EventSchemaDrift)
+
+	mu     sync.Mutex
+	events map[string]*EventSchemaDrift
+}
+
+/*
+EventSchemaDrift is the drift of an event detected by [SchemaDrift].
+
+Fields:
+  - Event is the event name.
+  - Deliveries is the number of compared payloads.
+  - Unknown is the number of payloads that contain each unknown field, by path (e.g. "pull_request.head.sha").
+  - Missing is the number of payloads that don't contain each missing field, by path.
+*/
+type EventSchemaDrift struct {
+	Event      string         `json:"event"`
+	Deliveries int            `json:"deliveries"`
+	Unknown    map[string]int `json:"unknown,omitempty"`
+	Missing    map[string]int `json:"missing,omitempty"`
+}
+
+// DecodePayload compares the payload with [SchemaDrift.Record], and decodes it with [events.Decode].
+//
+// It has the signature of [Handler.DecodePayload].
+func (s *SchemaDrift) DecodePayload(ctx context.Context, event string, rawPayload []byte) (any, error) {
+	s.Record(event, rawPayload)
+	return events.Decode(event, rawPayload) //nolint:wrapcheck // The error is wrapped by Handler.
+}
+
+// Record compares a payload with the payload type of its event.
+//
+// The invalid payloads are ignored.
+func (s *SchemaDrift) Record(event string, rawPayload []byte) {
+	d, err := events.CompareFields(event, rawPayload)
+	if err != nil || d == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ed := s.events[event]
+	if ed == nil {
+		ed = &EventSchemaDrift{
+			Event:   event,
+			Unknown: make(map[string]int),
+			Missing: make(map[string]int),
+		}
+		if s.events == nil {
+			s.events = make(map[string]*EventSchemaDrift)
+		}
+		s.events[event] = ed
+	}
+	ed.Deliveries++
+	for _, p := range d.Unknown {
+		ed.Unknown[p]++
+	}
+	for _, p := range d.Missing {
+		ed.Missing[p]++
+	}
+}
+
+// Drift returns the drift of the events that have unknown or missing fields, sorted by event.
+func (s *SchemaDrift) Drift() []*EventSchemaDrift {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var drift []*EventSchemaDrift
+	for _, event := range slices.Sorted(maps.Keys(s.events)) {
+		ed := s.events[event]
+		if len(ed.Unknown) == 0 && len(ed.Missing) == 0 {
+			continue
+		}
+		drift = append(drift, &EventSchemaDrift{
+			Event:      ed.Event,
+			Deliveries: ed.Deliveries,
+			Unknown:    maps.Clone(ed.Unknown),
+			Missing:    maps.Clone(ed.Missing),
+		})
+	}
+	return drift
+}
+
+// Reset forgets the recorded payloads, e.g. after the payload types have been updated.
+func (s *SchemaDrift) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+}
+
+// Run calls [SchemaDrift.Report] periodically with the current drift, until the context is canceled.
+func (s *SchemaDrift) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		drift := s.Drift()
+		if len(drift) > 0 && s.Report != nil {
+			s.Report(ctx, drift)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	ctx := context.Background()
+	s := new(SchemaDrift)
+	h := &Handler{
+		DecodePayload: s.DecodePayload,
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			_, _ = assert.Type[*events.PushEvent](t, payload)
+			return nil
+		},
+	}
+	for _, rawPayload := range []string{
+		`{"ref": "refs/heads/main", "new_field": 1}`,
+		`{"ref": "refs/heads/main", "new_field": 2, "other_field": 3}`,
+	} {
+		req, err := new(Signer).NewRequest(ctx, "/", "push", "test", []byte(rawPayload))
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+	s.Record("unknown", []byte(`{"field": 1}`))
+	s.Record("push", []byte(`invalid`))
+	drift := s.Drift()
+	assert.SliceLen(t, drift, 1)
+	d := drift[0]
+	assert.Equal(t, d.Event, "push")
+	assert.Equal(t, d.Deliveries, 2)
+	assert.MapEqual(t, d.Unknown, map[string]int{"new_field": 2, "other_field": 1})
+	assert.Equal(t, d.Missing["before"], 2)
+	s.Reset()
+	assert.SliceEmpty(t, s.Drift())
+}
+
+func TestSchemaDriftNoDrift(t *testing.T) {
+	s := new(SchemaDrift)
+	s.Record("ping", []byte(`{"zen": "zen", "hook_id": 1}`))
+	assert.SliceEmpty(t, s.Drift())
+}
+
+func TestSchemaDriftRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reported := make(chan []*EventSchemaDrift, 1)
+	s := &SchemaDrift{
+		Report: func(ctx context.Context, drift []*EventSchemaDrift) {
+			select {
+			case reported <- drift:
+			default:
+			}
+		},
+	}
+	s.Record("push", []byte(`{"new_field": 1}`))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx, time.Millisecond)
+	}()
+	drift := <-reported
+	assert.SliceLen(t, drift, 1)
+	cancel()
+	<-done
+}
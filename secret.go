@@ -0,0 +1,128 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNoSecret is returned (wrapped in a [RequestError] with a 403 status) if [Handler.SecretProvider] or [Handler.Secrets] provide no secret for a request.
+//
+// The request is rejected, instead of being accepted without signature verification.
+var ErrNoSecret = errors.New("no secret")
+
+// SecretProvider provides the secret used to verify the signature of a request.
+//
+// It allows to fetch secrets per request, e.g. from a database, a per-tenant configuration, or an external secret manager.
+// If it returns an empty secret, the request is rejected with a 403 response (see [ErrNoSecret]), e.g. for an unknown tenant.
+// If it returns a [RequestError], it is used for the response, otherwise the response is a 500 error.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, req *http.Request, event string) ([]byte, error)
+}
+
+// MultiSecretProvider is a [SecretProvider] that can provide several candidate secrets.
+//
+// A signature is valid if it matches any of them.
+// It is useful for zero-downtime secret rotation.
+type MultiSecretProvider interface {
+	SecretProvider
+	GetSecrets(ctx context.Context, req *http.Request, event string) ([][]byte, error)
+}
+
+// SecretProviderFunc is a [SecretProvider] function.
+type SecretProviderFunc func(ctx context.Context, req *http.Request, event string) ([]byte, error)
+
+// GetSecret implements [SecretProvider].
+func (f SecretProviderFunc) GetSecret(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+	return f(ctx, req, event)
+}
+
+// StaticSecret is a [SecretProvider] that always returns the same secret.
+//
+// It is the default provider for [Handler.Secret].
+type StaticSecret string
+
+// GetSecret implements [SecretProvider].
+func (s StaticSecret) GetSecret(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+	return []byte(s), nil
+}
+
+// StaticSecrets is a [MultiSecretProvider] that always returns the same secrets.
+//
+// It is the default provider for [Handler.Secrets].
+// [StaticSecrets.GetSecret] returns the first secret.
+type StaticSecrets []string
+
+// GetSecret implements [SecretProvider].
+func (s StaticSecrets) GetSecret(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return []byte(s[0]), nil
+}
+
+// GetSecrets implements [MultiSecretProvider].
+func (s StaticSecrets) GetSecrets(ctx context.Context, req *http.Request, event string) ([][]byte, error) {
+	secrets := make([][]byte, 0, len(s))
+	for _, secret := range s {
+		if secret != "" {
+			secrets = append(secrets, []byte(secret))
+		}
+	}
+	return secrets, nil
+}
+
+func (h *Handler) getSecretProvider() SecretProvider {
+	if h.SignatureScheme != nil {
+		return h.SignatureScheme.SecretProvider
+	}
+	if h.SecretProvider != nil {
+		return h.SecretProvider
+	}
+	if len(h.Secrets) > 0 {
+		return append(StaticSecrets{h.Secret}, h.Secrets...)
+	}
+	return StaticSecret(h.Secret)
+}
+
+// getSecret returns the secret used to sign synthetic requests.
+func (h *Handler) getSecret(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+	sp := h.getSecretProvider()
+	if msp, ok := sp.(MultiSecretProvider); ok {
+		secrets, err := h.getSecrets(ctx, req, event, msp)
+		if err != nil || len(secrets) == 0 {
+			return nil, err
+		}
+		return secrets[0], nil
+	}
+	secret, err := sp.GetSecret(ctx, req, event)
+	if err != nil {
+		return nil, fmt.Errorf("get secret: %w", err)
+	}
+	return secret, nil
+}
+
+// getCandidateSecrets returns the secrets accepted for a request.
+func (h *Handler) getCandidateSecrets(ctx context.Context, req *http.Request, event string) ([][]byte, error) {
+	sp := h.getSecretProvider()
+	if msp, ok := sp.(MultiSecretProvider); ok {
+		return h.getSecrets(ctx, req, event, msp)
+	}
+	secret, err := sp.GetSecret(ctx, req, event)
+	if err != nil {
+		return nil, fmt.Errorf("get secret: %w", err)
+	}
+	if len(secret) == 0 {
+		return nil, nil
+	}
+	return [][]byte{secret}, nil
+}
+
+func (h *Handler) getSecrets(ctx context.Context, req *http.Request, event string, msp MultiSecretProvider) ([][]byte, error) {
+	secrets, err := msp.GetSecrets(ctx, req, event)
+	if err != nil {
+		return nil, fmt.Errorf("get secrets: %w", err)
+	}
+	return secrets, nil
+}
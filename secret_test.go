@@ -0,0 +1,151 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerSecretProvider(t *testing.T) {
+	ctx := context.Background()
+	var providerEvent string
+	h := &Handler{
+		SecretProvider: SecretProviderFunc(func(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+			providerEvent = event
+			return []byte("foobar"), nil
+		}),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "foobar", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, providerEvent, "push")
+}
+
+func TestHandlerSecretProviderErrorSignature(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		SecretProvider: StaticSecret("foobar"),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "wrong", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestHandlerSecretProviderEmpty(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name    string
+		handler *Handler
+	}{
+		{
+			name: "Provider",
+			handler: &Handler{
+				SecretProvider: StaticSecret(""),
+			},
+		},
+		{
+			name: "Secrets",
+			handler: &Handler{
+				Secrets: []string{""},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var handledErr error
+			tc.handler.Error = func(ctx context.Context, err error, req *http.Request) {
+				handledErr = err
+			}
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+			req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			testExpectResponseStatus(t, resp, http.StatusForbidden)
+			assert.ErrorIs(t, handledErr, ErrNoSecret)
+		})
+	}
+}
+
+func TestHandlerSecretProviderError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		SecretProvider: SecretProviderFunc(func(ctx context.Context, req *http.Request, event string) ([]byte, error) {
+			return nil, errors.New("error")
+		}),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "foobar", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusInternalServerError)
+}
+
+func TestNewHandlerSecretProvider(t *testing.T) {
+	_, err := NewHandler(WithSecretProvider(StaticSecret("foobar")))
+	assert.NoError(t, err)
+	_, err = NewHandler(WithSecret("foobar"), WithSecretProvider(StaticSecret("foobar")))
+	assert.Error(t, err)
+}
+
+func TestHandlerSecrets(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret:  "new",
+		Secrets: []string{"old"},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	for _, secret := range []string{"new", "old"} {
+		req := testNewJSONRequest(ctx, t, srv, secret, testRawPayload)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		testExpectResponseStatusOK(t, resp)
+	}
+	req := testNewJSONRequest(ctx, t, srv, "wrong", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestHandlerSecretsSelfTest(t *testing.T) {
+	ctx := context.Background()
+	h, err := NewHandler(WithSecrets("old", "new"))
+	assert.NoError(t, err)
+	err = h.SelfTest(ctx)
+	assert.NoError(t, err)
+}
+
+func TestStaticSecrets(t *testing.T) {
+	ctx := context.Background()
+	secret, err := StaticSecrets{}.GetSecret(ctx, nil, "push")
+	assert.NoError(t, err)
+	assert.SliceEmpty(t, secret)
+	secret, err = StaticSecrets{"a", "b"}.GetSecret(ctx, nil, "push")
+	assert.NoError(t, err)
+	assert.Equal(t, string(secret), "a")
+}
@@ -0,0 +1,78 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+var selfTestRawPayload = []byte(`{"zen":"githubhook self-test"}`)
+
+// SelfTest validates the configuration of the [Handler] end to end, before traffic arrives.
+//
+// It sends a synthetic signed "ping" delivery through the full pipeline, so [Handler.Delivery] (or [Handler.Ping]) is called with it.
+// It returns an error if the delivery is not accepted.
+func (h *Handler) SelfTest(ctx context.Context) error {
+	var deliveryID string
+	if h.GenerateDeliveryID == nil {
+		var err error
+		deliveryID, err = newSelfTestDeliveryID()
+		if err != nil {
+			return err
+		}
+	}
+	req, err := h.newSyntheticRequest(ctx, "ping", deliveryID, selfTestRawPayload)
+	if err != nil {
+		return fmt.Errorf("self-test: %w", err)
+	}
+	err = h.serveSyntheticRequest(req)
+	if err != nil {
+		return fmt.Errorf("self-test: %w", err)
+	}
+	return nil
+}
+
+func newSelfTestDeliveryID() (string, error) {
+	id, err := NewDeliveryID()
+	if err != nil {
+		return "", fmt.Errorf("self-test: %w", err)
+	}
+	return "self-test-" + id, nil
+}
+
+// newSyntheticRequest creates a delivery request, signed with the secret of the handler.
+// If deliveryID is empty, it is generated with [Handler.GenerateDeliveryID].
+func (h *Handler) newSyntheticRequest(ctx context.Context, event string, deliveryID string, rawPayload []byte) (*http.Request, error) {
+	s := &Signer{
+		Scheme:             h.SignatureScheme,
+		GenerateDeliveryID: h.GenerateDeliveryID,
+	}
+	req, err := s.NewRequest(ctx, "/", event, deliveryID, rawPayload)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := h.getSecret(ctx, req, event)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) > 0 {
+		s.Secret = string(secret)
+		req.Header, err = s.Header(event, req.Header.Get("X-GitHub-Delivery"), rawPayload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// serveSyntheticRequest serves a request without HTTP listener, and returns an error if the response status is not 200 or 202.
+func (h *Handler) serveSyntheticRequest(req *http.Request) error {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK && w.Code != http.StatusAccepted {
+		return fmt.Errorf("unexpected response status %d: %s", w.Code, bytes.TrimSpace(w.Body.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerSelfTest(t *testing.T) {
+	ctx := context.Background()
+	var deliveryEvent string
+	h := &Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryEvent = md.Event
+			return nil
+		},
+	}
+	err := h.SelfTest(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, deliveryEvent, "ping")
+}
+
+func TestHandlerSelfTestError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		DecodePayload: func(ctx context.Context, event string, rawPayload []byte) (any, error) {
+			return nil, errors.New("error")
+		},
+	}
+	err := h.SelfTest(ctx)
+	assert.Error(t, err)
+}
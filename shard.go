@@ -0,0 +1,61 @@
+package githubhook
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/pierrre/githubhook/events"
+)
+
+// ShardKey returns a stable sharding key for a decoded payload.
+//
+// It is the repository full name if available, otherwise the installation ID, otherwise an empty string.
+// It supports the types of the events package, and generic map[string]any payloads.
+func ShardKey(payload any) string {
+	switch p := payload.(type) {
+	case interface{ GetCommon() *events.Common }:
+		c := p.GetCommon()
+		if c.Repository != nil && c.Repository.FullName != "" {
+			return c.Repository.FullName
+		}
+		if c.Installation != nil && c.Installation.ID != 0 {
+			return strconv.FormatInt(c.Installation.ID, 10)
+		}
+	case map[string]any:
+		if repo, ok := p["repository"].(map[string]any); ok {
+			if name, ok := repo["full_name"].(string); ok && name != "" {
+				return name
+			}
+		}
+		if inst, ok := p["installation"].(map[string]any); ok {
+			if id, ok := inst["id"].(float64); ok && id != 0 {
+				return strconv.FormatInt(int64(id), 10)
+			}
+		}
+	}
+	return ""
+}
+
+// Shard returns the partition of a key, in [0, n).
+//
+// It uses the FNV-1a hash, so it is stable across processes and versions.
+func Shard(key string, n int) int {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum64() % uint64(n)) //nolint:gosec // The result is lower than n.
+}
+
+// ShardRouter returns a [DeliveryHandler] that dispatches each delivery to one of the partitions, with [ShardKey] and [Shard].
+//
+// Deliveries of the same repository are always dispatched to the same partition.
+// It panics if there is no partition.
+func ShardRouter(partitions ...DeliveryHandler) DeliveryHandler {
+	if len(partitions) == 0 {
+		panic("no partition")
+	}
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		i := Shard(ShardKey(payload), len(partitions))
+		return partitions[i](ctx, md, payload)
+	}
+}
@@ -0,0 +1,76 @@
+package githubhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pierrre/assert"
+	"github.com/pierrre/githubhook/events"
+)
+
+func TestShardKey(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		payload  any
+		expected string
+	}{
+		{
+			name: "TypedRepository",
+			payload: &events.PushEvent{Common: events.Common{
+				Repository: &events.Repository{FullName: "octocat/Hello-World"},
+			}},
+			expected: "octocat/Hello-World",
+		},
+		{
+			name: "TypedInstallation",
+			payload: &events.PushEvent{Common: events.Common{
+				Installation: &events.Installation{ID: 123},
+			}},
+			expected: "123",
+		},
+		{
+			name:     "MapRepository",
+			payload:  map[string]any{"repository": map[string]any{"full_name": "octocat/Hello-World"}},
+			expected: "octocat/Hello-World",
+		},
+		{
+			name:     "MapInstallation",
+			payload:  map[string]any{"installation": map[string]any{"id": float64(123)}},
+			expected: "123",
+		},
+		{
+			name:     "Unknown",
+			payload:  "unknown",
+			expected: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, ShardKey(tc.payload), tc.expected)
+		})
+	}
+}
+
+func TestShard(t *testing.T) {
+	assert.Equal(t, Shard("octocat/Hello-World", 10), Shard("octocat/Hello-World", 10))
+	for _, key := range []string{"", "a", "b", "octocat/Hello-World"} {
+		s := Shard(key, 3)
+		assert.True(t, s >= 0 && s < 3)
+	}
+}
+
+func TestShardRouter(t *testing.T) {
+	ctx := context.Background()
+	counts := make([]int, 4)
+	partitions := make([]DeliveryHandler, len(counts))
+	for i := range partitions {
+		partitions[i] = func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			counts[i]++
+			return nil
+		}
+	}
+	h := ShardRouter(partitions...)
+	payload := map[string]any{"repository": map[string]any{"full_name": "octocat/Hello-World"}}
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push"}, payload))
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push"}, payload))
+	assert.Equal(t, counts[Shard("octocat/Hello-World", len(counts))], 2)
+}
@@ -0,0 +1,45 @@
+package githubhook
+
+import (
+	"crypto/sha256"
+	"hash"
+)
+
+/*
+SignatureScheme is an internal HMAC signature scheme, that replaces GitHub's signature schemes.
+
+It allows to offload the verification of GitHub's signature to a trusted proxy, in a layered ingestion architecture: the proxy verifies GitHub's signature, and re-signs the delivery with the internal scheme (see [Signer.Scheme]), so the handler only verifies the internal signature.
+The signature is the hex encoded HMAC of the raw payload, prefixed with Prefix, in the Header header.
+
+Fields:
+  - Header is the header of the signature (required), e.g. "X-Internal-Signature".
+  - Prefix is the prefix of the signature (optional), e.g. "sha256=".
+  - Hash is the hash function of the HMAC (default: SHA-256).
+  - SecretProvider provides the internal secret (required). It can be a [MultiSecretProvider], e.g. during secret rotation.
+*/
+type SignatureScheme struct {
+	Header         string
+	Prefix         string
+	Hash           func() hash.Hash
+	SecretProvider SecretProvider
+}
+
+func (s *SignatureScheme) scheme() signatureScheme {
+	h := s.Hash
+	if h == nil {
+		h = sha256.New
+	}
+	return signatureScheme{
+		header: s.Header,
+		prefix: s.Prefix,
+		hash:   h,
+	}
+}
+
+// getSignatureSchemes returns the schemes of the signatures verified by the handler: [Handler.SignatureScheme], or GitHub's schemes.
+func (h *Handler) getSignatureSchemes() []signatureScheme {
+	if h.SignatureScheme != nil {
+		return []signatureScheme{h.SignatureScheme.scheme()}
+	}
+	return signatureSchemes
+}
@@ -0,0 +1,99 @@
+package githubhook
+
+import (
+	"context"
+	"crypto/sha512"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerSignatureScheme(t *testing.T) {
+	ctx := context.Background()
+	scheme := &SignatureScheme{
+		Header:         "X-Internal-Signature",
+		Prefix:         "sha512=",
+		Hash:           sha512.New,
+		SecretProvider: StaticSecrets{"internal", "old"},
+	}
+	h, err := NewHandler(WithSignatureScheme(scheme))
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		name       string
+		signer     *Signer
+		statusCode int
+	}{
+		{
+			name:       "Valid",
+			signer:     &Signer{Secret: "internal", Scheme: scheme},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Rotated",
+			signer:     &Signer{Secret: "old", Scheme: scheme},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Invalid",
+			signer:     &Signer{Secret: "invalid", Scheme: scheme},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "GitHubSignature",
+			signer:     &Signer{Secret: "internal"},
+			statusCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := tc.signer.NewRequest(ctx, "/", "push", "", testRawPayload)
+			assert.NoError(t, err)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, w.Code, tc.statusCode)
+		})
+	}
+}
+
+func TestHandlerSignatureSchemeMissingHeader(t *testing.T) {
+	h := &Handler{
+		SignatureScheme: &SignatureScheme{
+			Header:         "X-Internal-Signature",
+			SecretProvider: StaticSecret("internal"),
+		},
+	}
+	req, err := new(Signer).NewRequest(context.Background(), "/", "push", "", testRawPayload)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.StringContains(t, w.Body.String(), "missing header: X-Internal-Signature")
+}
+
+func TestHandlerSignatureSchemeSelfTest(t *testing.T) {
+	h, err := NewHandler(WithSignatureScheme(&SignatureScheme{
+		Header:         "X-Internal-Signature",
+		SecretProvider: StaticSecret("internal"),
+	}))
+	assert.NoError(t, err)
+	err = h.SelfTest(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestSignerScheme(t *testing.T) {
+	s := &Signer{
+		Secret: "secret",
+		Scheme: &SignatureScheme{
+			Header: "X-Internal-Signature",
+			Prefix: "sha256=",
+		},
+	}
+	header, err := s.Header("push", "1", testRawPayload)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	testSignRequestSHA256(req, "secret", testRawPayload)
+	assert.Equal(t, header.Get("X-Internal-Signature"), req.Header.Get("X-Hub-Signature-256"))
+	assert.Equal(t, header.Get("X-Hub-Signature-256"), "")
+	assert.Equal(t, header.Get("X-Hub-Signature"), "")
+}
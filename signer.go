@@ -0,0 +1,102 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// DefaultSignerUserAgent is the default value of [Signer.UserAgent].
+const DefaultSignerUserAgent = "GitHub-Hookshot/githubhook"
+
+/*
+Signer creates GitHub-compatible delivery headers, signed with a secret.
+
+It can be used to forward deliveries, or to send test deliveries.
+
+Fields (all are optional):
+  - Secret is the secret used to sign the payload. If it's empty, the payload is not signed.
+  - DisableSHA1 disables the SHA-1 signature (X-Hub-Signature), only the SHA-256 signature (X-Hub-Signature-256) is set.
+  - Scheme signs the payload with an internal scheme, instead of GitHub's schemes, e.g. in a trusted proxy that re-signs the deliveries (see [Handler.SignatureScheme]). Its SecretProvider is not used: the payload is signed with Secret.
+  - UserAgent is the User-Agent header (default: [DefaultSignerUserAgent]).
+  - GenerateDeliveryID generates the delivery ID if it's not provided (default: [RandomDeliveryID]).
+  - Lineage sets the lineage headers, for deliveries regenerated from another one (see [NextLineage]).
+*/
+type Signer struct {
+	Secret             string
+	DisableSHA1        bool
+	Scheme             *SignatureScheme
+	UserAgent          string
+	GenerateDeliveryID DeliveryIDGenerator
+	Lineage            *Lineage
+}
+
+// Header returns the headers of a delivery.
+//
+// If deliveryID is empty, a new one is generated with [Signer.GenerateDeliveryID].
+func (s *Signer) Header(event string, deliveryID string, rawPayload []byte) (http.Header, error) {
+	if deliveryID == "" {
+		generate := s.GenerateDeliveryID
+		if generate == nil {
+			generate = RandomDeliveryID
+		}
+		var err error
+		deliveryID, err = generate(event, rawPayload)
+		if err != nil {
+			return nil, fmt.Errorf("generate delivery ID: %w", err)
+		}
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("X-GitHub-Event", event)
+	header.Set("X-GitHub-Delivery", deliveryID)
+	userAgent := s.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultSignerUserAgent
+	}
+	header.Set("User-Agent", userAgent)
+	if s.Lineage != nil {
+		s.Lineage.SetHeader(header)
+	}
+	if s.Secret != "" {
+		s.sign(header, rawPayload)
+	}
+	return header, nil
+}
+
+// NewRequest returns a new delivery request, with the headers returned by [Signer.Header].
+func (s *Signer) NewRequest(ctx context.Context, url string, event string, deliveryID string, rawPayload []byte) (*http.Request, error) {
+	header, err := s.Header(event, deliveryID, rawPayload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(rawPayload))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header = header
+	return req, nil
+}
+
+func (s *Signer) sign(header http.Header, rawPayload []byte) {
+	if s.Scheme != nil {
+		scheme := s.Scheme.scheme()
+		header.Set(scheme.header, signPayload(scheme, []byte(s.Secret), rawPayload))
+		return
+	}
+	for _, scheme := range signatureSchemes {
+		if s.DisableSHA1 && scheme.prefix == "sha1=" {
+			continue
+		}
+		header.Set(scheme.header, signPayload(scheme, []byte(s.Secret), rawPayload))
+	}
+}
+
+func signPayload(scheme signatureScheme, secret []byte, rawPayload []byte) string {
+	mac := hmac.New(scheme.hash, secret)
+	_, _ = mac.Write(rawPayload)
+	return scheme.prefix + hex.EncodeToString(mac.Sum(nil))
+}
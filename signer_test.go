@@ -0,0 +1,62 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestSigner(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		Secret: "foobar",
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	s := &Signer{
+		Secret: "foobar",
+	}
+	req, err := s.NewRequest(ctx, srv.URL, "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	assert.NotZero(t, req.Header.Get("X-Hub-Signature-256"))
+	assert.NotZero(t, req.Header.Get("X-Hub-Signature"))
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+	assert.Equal(t, md.Event, "push")
+	assert.Equal(t, md.DeliveryID, "test")
+	assert.Equal(t, md.UserAgent, DefaultSignerUserAgent)
+}
+
+func TestSignerDisableSHA1(t *testing.T) {
+	s := &Signer{
+		Secret:      "foobar",
+		DisableSHA1: true,
+		UserAgent:   "test",
+	}
+	header, err := s.Header("push", "test", testRawPayload)
+	assert.NoError(t, err)
+	assert.NotZero(t, header.Get("X-Hub-Signature-256"))
+	assert.Zero(t, header.Get("X-Hub-Signature"))
+	assert.Equal(t, header.Get("User-Agent"), "test")
+}
+
+func TestSignerWithoutSecret(t *testing.T) {
+	s := &Signer{}
+	header, err := s.Header("push", "", testRawPayload)
+	assert.NoError(t, err)
+	assert.Zero(t, header.Get("X-Hub-Signature-256"))
+	assert.Zero(t, header.Get("X-Hub-Signature"))
+	assert.NotZero(t, header.Get("X-GitHub-Delivery"))
+}
@@ -0,0 +1,340 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink sends verified deliveries somewhere, e.g. a message broker or a downstream endpoint.
+//
+// It's an alternative to [DeliveryHandler] for publishers and forwarders, which need the raw payload.
+// See [Handler.Sink].
+type Sink interface {
+	Send(ctx context.Context, d *VerifiedDelivery) error
+}
+
+// SinkFunc is a [Sink] function.
+type SinkFunc func(ctx context.Context, d *VerifiedDelivery) error
+
+// Send implements [Sink].
+func (f SinkFunc) Send(ctx context.Context, d *VerifiedDelivery) error {
+	return f(ctx, d)
+}
+
+// SinkStarter is implemented by the sinks that can be started before the deliveries are received, see [StartSink].
+//
+// Start pre-establishes the connections (broker, HTTP...) and validates the configuration and the credentials, so the first delivery doesn't pay the cold start, and a misconfiguration is detected before the traffic is accepted.
+// It must not send a delivery.
+type SinkStarter interface {
+	Start(ctx context.Context) error
+}
+
+// StartSink starts the sink if it implements [SinkStarter], or does nothing.
+//
+// The sinks returned by [Fanout], [Fallback], [Filtered] and [Transformed] start their sinks.
+func StartSink(ctx context.Context, s Sink) error {
+	st, ok := s.(SinkStarter)
+	if !ok {
+		return nil
+	}
+	return startSink(ctx, st)
+}
+
+// startSink starts a sink, and recovers a panic.
+func startSink(ctx context.Context, st SinkStarter) (err error) {
+	defer recoverPanic(&err)
+	return st.Start(ctx)
+}
+
+// Start starts [Handler.Sink] with [StartSink], so it must be called before the handler receives the deliveries.
+//
+// It does nothing if [Handler.Sink] is not defined.
+func (h *Handler) Start(ctx context.Context) error {
+	if h.Sink == nil {
+		return nil
+	}
+	err := StartSink(ctx, h.Sink)
+	if err != nil {
+		return fmt.Errorf("start sink: %w", err)
+	}
+	return nil
+}
+
+// SinkCloser is implemented by the sinks that release resources (connections, workers...) when they are not used anymore, see [CloseSink].
+//
+// Close is called after the last delivery has been sent, e.g. when the [Handler] is replaced by [ReloadableHandler.Reload].
+// It must not close the components that are shared with other sinks (e.g. a client owned by the caller).
+type SinkCloser interface {
+	Close(ctx context.Context) error
+}
+
+// CloseSink closes the sink if it implements [SinkCloser], or does nothing.
+//
+// The sinks returned by [Fanout], [Fallback], [Filtered] and [Transformed] close their sinks.
+func CloseSink(ctx context.Context, s Sink) error {
+	cl, ok := s.(SinkCloser)
+	if !ok {
+		return nil
+	}
+	return closeSink(ctx, cl)
+}
+
+// closeSink closes a sink, and recovers a panic.
+func closeSink(ctx context.Context, cl SinkCloser) (err error) {
+	defer recoverPanic(&err)
+	return cl.Close(ctx)
+}
+
+/*
+Close waits until the requests and the asynchronous deliveries in progress of the handler are done, then closes [Handler.Sink] with [CloseSink].
+
+It must be called when the handler is not used anymore, e.g. on the handler returned by [ReloadableHandler.Reload], or at shutdown after the HTTP server.
+It returns the context error if the context is done before the deliveries in progress (the sink is not closed).
+The deliveries abandoned by the shutdown of [Handler.Async] are not waited for.
+
+The stateful components (e.g. [Handler.Async], [Handler.Dedup], [Handler.Store], [Handler.Maintenance]) are not closed, because they are usually shared by the next handler: they must be closed by their owner.
+The deliveries held by [Handler.Maintenance] are resumed with the handler that received them, so the maintenance mode should be disabled before.
+*/
+func (h *Handler) Close(ctx context.Context) error {
+	err := h.waitInflight(ctx)
+	if err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	if h.Sink == nil {
+		return nil
+	}
+	err = CloseSink(ctx, h.Sink)
+	if err != nil {
+		return fmt.Errorf("close sink: %w", err)
+	}
+	return nil
+}
+
+// closePollInterval is the interval of [Handler.Close] to check the deliveries in progress.
+const closePollInterval = 10 * time.Millisecond
+
+// waitInflight waits until there are no requests and asynchronous deliveries in progress, or returns the context error.
+func (h *Handler) waitInflight(ctx context.Context) error {
+	if atomic.LoadInt32(&h.inflight) == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(closePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if atomic.LoadInt32(&h.inflight) == 0 {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck // The error is wrapped by the caller.
+		}
+	}
+}
+
+func (h *Handler) addInflight() {
+	atomic.AddInt32(&h.inflight, 1)
+}
+
+func (h *Handler) doneInflight() {
+	atomic.AddInt32(&h.inflight, -1)
+}
+
+// Fanout returns a [Sink] that sends the deliveries to all sinks concurrently.
+//
+// All sinks are called, even if one of them returns an error, and the errors are joined.
+// It implements [SinkStarter] and [SinkCloser]: the sinks are started and closed concurrently.
+func Fanout(sinks ...Sink) Sink {
+	return fanoutSink(sinks)
+}
+
+type fanoutSink []Sink
+
+func (sinks fanoutSink) Send(ctx context.Context, d *VerifiedDelivery) error {
+	return sinks.all(func(s Sink) error {
+		return sendSink(ctx, s, d)
+	})
+}
+
+func (sinks fanoutSink) Start(ctx context.Context) error {
+	return sinks.all(func(s Sink) error {
+		return StartSink(ctx, s)
+	})
+}
+
+func (sinks fanoutSink) Close(ctx context.Context) error {
+	return sinks.all(func(s Sink) error {
+		return CloseSink(ctx, s)
+	})
+}
+
+// all calls f for all sinks concurrently, and joins the errors.
+func (sinks fanoutSink) all(f func(s Sink) error) error {
+	errs := make([]error, len(sinks))
+	var wg sync.WaitGroup
+	for i, s := range sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = f(s)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Fallback returns a [Sink] that sends the deliveries to the primary sink, or to the secondary sink if the primary sink returns an error.
+//
+// If both return an error, the errors are joined.
+// It implements [SinkStarter]: both sinks are started, because the secondary sink must be ready when the primary sink fails.
+// It implements [SinkCloser]: both sinks are closed.
+func Fallback(primary Sink, secondary Sink) Sink {
+	return &fallbackSink{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+type fallbackSink struct {
+	primary   Sink
+	secondary Sink
+}
+
+func (s *fallbackSink) Send(ctx context.Context, d *VerifiedDelivery) error {
+	err := s.primary.Send(ctx, d)
+	if err == nil {
+		return nil
+	}
+	secondaryErr := s.secondary.Send(ctx, d)
+	if secondaryErr == nil {
+		return nil
+	}
+	return errors.Join(err, secondaryErr)
+}
+
+func (s *fallbackSink) Start(ctx context.Context) error {
+	return fanoutSink{s.primary, s.secondary}.Start(ctx)
+}
+
+func (s *fallbackSink) Close(ctx context.Context) error {
+	return fanoutSink{s.primary, s.secondary}.Close(ctx)
+}
+
+// Filtered returns a [Sink] that sends the deliveries to the sink only if the predicate matches.
+//
+// It implements [SinkStarter] and [SinkCloser].
+func Filtered(sink Sink, predicate DeliveryPredicate) Sink {
+	return &filteredSink{
+		sink:      sink,
+		predicate: predicate,
+	}
+}
+
+type filteredSink struct {
+	sink      Sink
+	predicate DeliveryPredicate
+}
+
+func (s *filteredSink) Send(ctx context.Context, d *VerifiedDelivery) error {
+	if s.predicate(ctx, &d.DeliveryMetadata, d.Payload) {
+		return s.sink.Send(ctx, d)
+	}
+	return nil
+}
+
+func (s *filteredSink) Start(ctx context.Context) error {
+	return StartSink(ctx, s.sink)
+}
+
+func (s *filteredSink) Close(ctx context.Context) error {
+	return CloseSink(ctx, s.sink)
+}
+
+// sendSink sends a delivery to a sink, and recovers a panic.
+func sendSink(ctx context.Context, s Sink, d *VerifiedDelivery) (err error) {
+	defer recoverPanic(&err)
+	return s.Send(ctx, d)
+}
+
+/*
+SinkHTTPHandler returns a [http.Handler] that sends the verified delivery of the request to a sink.
+
+It must be wrapped by [Handler.Middleware], which verifies the deliveries.
+The response status is 200 if the delivery is sent, 502 otherwise (500 if the delivery is not verified, or if the sink panics).
+The error function is called if the delivery can't be sent (optional).
+*/
+func SinkHTTPHandler(s Sink, errorFunc func(ctx context.Context, err error, req *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handleError := func(statusCode int, err error) {
+			http.Error(w, http.StatusText(statusCode), statusCode)
+			if errorFunc != nil {
+				errorFunc(req.Context(), err, req)
+			}
+		}
+		d, ok := VerifiedDeliveryFromContext(req.Context())
+		if !ok {
+			handleError(http.StatusInternalServerError, errors.New("missing verified delivery: the sink must be wrapped by githubhook.Handler.Middleware"))
+			return
+		}
+		err := sendSink(req.Context(), s, d)
+		if err != nil {
+			statusCode := http.StatusBadGateway
+			var panicErr *PanicError
+			if errors.As(err, &panicErr) {
+				statusCode = http.StatusInternalServerError
+			}
+			handleError(statusCode, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// SinkDeliveryHandler returns a [DeliveryHandler] that sends the deliveries to a sink.
+//
+// The raw payload is not available, so it's rebuilt from the decoded payload:
+// if the payload is a [json.RawMessage] or a []byte, it's used as is.
+// Otherwise, it's encoded to JSON, so the fields unknown by the [events] package are lost.
+// [Handler.Sink] or [SinkHTTPHandler] should be preferred, because they send the raw payload.
+//
+// [events]: https://pkg.go.dev/github.com/pierrre/githubhook/events
+func SinkDeliveryHandler(s Sink) DeliveryHandler {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		var rawPayload []byte
+		switch v := payload.(type) {
+		case json.RawMessage:
+			rawPayload = v
+		case []byte:
+			rawPayload = v
+		default:
+			var err error
+			rawPayload, err = json.Marshal(payload)
+			if err != nil {
+				return fmt.Errorf("JSON encode: %w", err)
+			}
+		}
+		return s.Send(ctx, &VerifiedDelivery{
+			DeliveryMetadata: *md,
+			RawPayload:       rawPayload,
+			Payload:          payload,
+		})
+	}
+}
+
+// callDeliveryHandler calls [Handler.Sink] if it's defined, or [Handler.Delivery].
+func (h *Handler) callDeliveryHandler(ctx context.Context, md *DeliveryMetadata, rawPayload []byte, payload any) error {
+	if h.Sink != nil {
+		return h.Sink.Send(ctx, &VerifiedDelivery{
+			DeliveryMetadata: *md,
+			RawPayload:       rawPayload,
+			Payload:          payload,
+		})
+	}
+	return h.Delivery(ctx, md, payload)
+}
@@ -0,0 +1,396 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerSink(t *testing.T) {
+	ctx := context.Background()
+	var sent *VerifiedDelivery
+	h := &Handler{
+		Sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+			sent = d
+			return nil
+		}),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	assert.NotZero(t, sent)
+	assert.Equal(t, sent.Event, "push")
+	assert.Equal(t, string(sent.RawPayload), string(testRawPayload))
+	assert.NotZero(t, sent.Payload)
+}
+
+func TestHandlerSinkError(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+			return errors.New("error")
+		}),
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusInternalServerError)
+}
+
+func testCountSink(n *atomic.Int64, err error) Sink {
+	return SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+		n.Add(1)
+		return err
+	})
+}
+
+func TestFanout(t *testing.T) {
+	var n1, n2 atomic.Int64
+	s := Fanout(testCountSink(&n1, nil), testCountSink(&n2, errors.New("error")))
+	err := s.Send(context.Background(), &VerifiedDelivery{})
+	assert.Error(t, err)
+	assert.Equal(t, n1.Load(), int64(1))
+	assert.Equal(t, n2.Load(), int64(1))
+}
+
+func TestFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		primaryErr        error
+		secondaryErr      error
+		expectedSecondary int64
+		expectedErr       bool
+	}{
+		{
+			name: "Primary",
+		},
+		{
+			name:              "Secondary",
+			primaryErr:        errors.New("error"),
+			expectedSecondary: 1,
+		},
+		{
+			name:              "Error",
+			primaryErr:        errors.New("error"),
+			secondaryErr:      errors.New("error"),
+			expectedSecondary: 1,
+			expectedErr:       true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var primary, secondary atomic.Int64
+			s := Fallback(testCountSink(&primary, tc.primaryErr), testCountSink(&secondary, tc.secondaryErr))
+			err := s.Send(context.Background(), &VerifiedDelivery{})
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, primary.Load(), int64(1))
+			assert.Equal(t, secondary.Load(), tc.expectedSecondary)
+		})
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	var n atomic.Int64
+	s := Filtered(testCountSink(&n, nil), EventIs("push"))
+	err := s.Send(context.Background(), &VerifiedDelivery{DeliveryMetadata: DeliveryMetadata{Event: "push"}})
+	assert.NoError(t, err)
+	err = s.Send(context.Background(), &VerifiedDelivery{DeliveryMetadata: DeliveryMetadata{Event: "issues"}})
+	assert.NoError(t, err)
+	assert.Equal(t, n.Load(), int64(1))
+}
+
+func TestFanoutPanic(t *testing.T) {
+	var n atomic.Int64
+	s := Fanout(testCountSink(&n, nil), SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+		panic("test")
+	}))
+	err := s.Send(context.Background(), &VerifiedDelivery{})
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, n.Load(), int64(1))
+}
+
+type testStartSink struct {
+	Sink
+	started *atomic.Int64
+	err     error
+}
+
+func (s *testStartSink) Start(ctx context.Context) error {
+	s.started.Add(1)
+	return s.err
+}
+
+func TestStartSink(t *testing.T) {
+	var n, started atomic.Int64
+	s := Fanout(
+		Filtered(&testStartSink{Sink: testCountSink(&n, nil), started: &started}, EventIs("push")),
+		Fallback(&testStartSink{Sink: testCountSink(&n, nil), started: &started}, Transformed(&testStartSink{Sink: testCountSink(&n, nil), started: &started}, SelectFields("ref"))),
+		testCountSink(&n, nil),
+	)
+	err := StartSink(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Equal(t, started.Load(), int64(3))
+	assert.Equal(t, n.Load(), int64(0))
+}
+
+func TestStartSinkError(t *testing.T) {
+	var started atomic.Int64
+	s := Fanout(
+		&testStartSink{Sink: SinkFunc(nil), started: &started, err: errors.New("error")},
+		&testStartSink{Sink: SinkFunc(nil), started: &started},
+	)
+	err := StartSink(context.Background(), s)
+	assert.ErrorContains(t, err, "error")
+	assert.Equal(t, started.Load(), int64(2))
+}
+
+func TestStartSinkNotStarter(t *testing.T) {
+	err := StartSink(context.Background(), SinkFunc(nil))
+	assert.NoError(t, err)
+}
+
+func TestHandlerStart(t *testing.T) {
+	var started atomic.Int64
+	h := &Handler{
+		Sink: &testStartSink{Sink: SinkFunc(nil), started: &started, err: errors.New("error")},
+	}
+	err := h.Start(context.Background())
+	assert.ErrorContains(t, err, "start sink: error")
+	assert.Equal(t, started.Load(), int64(1))
+	h = &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+	}
+	err = h.Start(context.Background())
+	assert.NoError(t, err)
+}
+
+type testCloseSink struct {
+	Sink
+	closed *atomic.Int64
+	err    error
+}
+
+func (s *testCloseSink) Close(ctx context.Context) error {
+	s.closed.Add(1)
+	return s.err
+}
+
+func TestCloseSink(t *testing.T) {
+	var closed atomic.Int64
+	s := Fanout(
+		Filtered(&testCloseSink{Sink: SinkFunc(nil), closed: &closed}, EventIs("push")),
+		Fallback(&testCloseSink{Sink: SinkFunc(nil), closed: &closed}, Transformed(&testCloseSink{Sink: SinkFunc(nil), closed: &closed}, SelectFields("ref"))),
+		&testCloseSink{Sink: SinkFunc(nil), closed: &closed, err: errors.New("error")},
+		SinkFunc(nil),
+	)
+	err := CloseSink(context.Background(), s)
+	assert.ErrorContains(t, err, "error")
+	assert.Equal(t, closed.Load(), int64(4))
+}
+
+func TestCloseSinkNotCloser(t *testing.T) {
+	err := CloseSink(context.Background(), SinkFunc(nil))
+	assert.NoError(t, err)
+}
+
+func TestHandlerClose(t *testing.T) {
+	ctx := context.Background()
+	var closed atomic.Int64
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	h := &Handler{
+		Sink: &testCloseSink{
+			Sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+				close(started)
+				<-unblock
+				return nil
+			}),
+			closed: &closed,
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := http.DefaultClient.Do(testNewJSONRequest(ctx, t, srv, "", testRawPayload))
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		testExpectResponseStatusOK(t, resp)
+	}()
+	<-started
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err := h.Close(closeCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, closed.Load(), int64(0))
+	close(unblock)
+	<-done
+	err = h.Close(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, closed.Load(), int64(1))
+}
+
+func TestHandlerCloseAsync(t *testing.T) {
+	ctx := context.Background()
+	var closed atomic.Int64
+	unblock := make(chan struct{})
+	p := NewAsyncPool(1, 1)
+	defer func() {
+		_ = p.Shutdown(ctx)
+	}()
+	h := &Handler{
+		Async: p,
+		Sink: &testCloseSink{
+			Sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+				<-unblock
+				return nil
+			}),
+			closed: &closed,
+		},
+	}
+	statusCode, err := h.Process(ctx, &DeliveryMetadata{Event: "push", DeliveryID: "1"}, testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, statusCode, http.StatusAccepted)
+	closeErr := make(chan error)
+	go func() {
+		closeErr <- h.Close(ctx)
+	}()
+	time.Sleep(2 * closePollInterval)
+	assert.Equal(t, closed.Load(), int64(0))
+	close(unblock)
+	err = <-closeErr
+	assert.NoError(t, err)
+	assert.Equal(t, closed.Load(), int64(1))
+}
+
+func TestHandlerCloseError(t *testing.T) {
+	var closed atomic.Int64
+	h := &Handler{
+		Sink: &testCloseSink{Sink: SinkFunc(nil), closed: &closed, err: errors.New("error")},
+	}
+	err := h.Close(context.Background())
+	assert.ErrorContains(t, err, "close sink: error")
+	h = &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+	}
+	err = h.Close(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestSinkHTTPHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		sink       Sink
+		statusCode int
+	}{
+		{
+			name: "OK",
+			sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+				return nil
+			}),
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "Error",
+			sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+				return errors.New("error")
+			}),
+			statusCode: http.StatusBadGateway,
+		},
+		{
+			name: "Panic",
+			sink: SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+				panic("test")
+			}),
+			statusCode: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			var handledErr error
+			h := new(Handler)
+			srv := httptest.NewServer(h.Middleware(SinkHTTPHandler(tc.sink, func(ctx context.Context, err error, req *http.Request) {
+				handledErr = err
+			})))
+			defer srv.Close()
+			req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			_ = resp.Body.Close()
+			testExpectResponseStatus(t, resp, tc.statusCode)
+			if tc.statusCode != http.StatusOK {
+				assert.Error(t, handledErr)
+			}
+		})
+	}
+}
+
+func TestSinkHTTPHandlerNotVerified(t *testing.T) {
+	w := httptest.NewRecorder()
+	SinkHTTPHandler(SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+		return nil
+	}), nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestSinkDeliveryHandler(t *testing.T) {
+	var sent *VerifiedDelivery
+	dh := SinkDeliveryHandler(SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+		sent = d
+		return nil
+	}))
+	md := &DeliveryMetadata{Event: "push"}
+	for _, tc := range []struct {
+		name     string
+		payload  any
+		expected string
+	}{
+		{
+			name:     "RawMessage",
+			payload:  json.RawMessage(`{"a":1}`),
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "Bytes",
+			payload:  []byte(`{"a":1}`),
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "Encode",
+			payload:  map[string]int{"a": 1},
+			expected: `{"a":1}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := dh(context.Background(), md, tc.payload)
+			assert.NoError(t, err)
+			assert.Equal(t, sent.Event, "push")
+			assert.Equal(t, string(sent.RawPayload), tc.expected)
+		})
+	}
+	err := dh(context.Background(), md, make(chan int))
+	assert.ErrorContains(t, err, "JSON encode")
+}
@@ -0,0 +1,99 @@
+package githubhook
+
+import (
+	"context"
+)
+
+/*
+SinkTrace is a set of hooks to trace the operations of the sinks, like [net/http/httptrace.ClientTrace] for the HTTP clients.
+It allows to attach low-level instrumentation to the forwarding paths, e.g. to diagnose the latency of a downstream.
+
+It's attached to the context with [WithSinkTrace], and the sinks call it with [TracePublish] (and [ContextSinkTrace] for the connections).
+It's supported by the sinks of the forward, githubhooknats, githubhookkafka, githubhookaws and githubhookpubsub packages.
+
+Fields (all are optional):
+  - Connect is called before a connection to the target is acquired, by the sinks that connect for each delivery (e.g. HTTP forwarding).
+  - Connected is called when the connection is acquired, or failed.
+  - Publish is called before the delivery is published to the target.
+  - Ack is called when the target acknowledges the delivery, or rejects it. For the asynchronous sinks (e.g. a Kafka async producer), it's called when the delivery is queued.
+
+The target identifies the downstream of the sink: a URL, a subject, a topic, a queue...
+The hooks may be called concurrently, e.g. by [Fanout].
+*/
+type SinkTrace struct {
+	Connect   func(target string)
+	Connected func(target string, err error)
+	Publish   func(target string, deliveryID string)
+	Ack       func(target string, deliveryID string, err error)
+}
+
+type sinkTraceContextKey struct{}
+
+// WithSinkTrace returns a context with the trace.
+//
+// If the context already has a trace, the hooks of the new trace are called first, then the hooks of the previous one.
+func WithSinkTrace(ctx context.Context, trace *SinkTrace) context.Context {
+	if old := ContextSinkTrace(ctx); old != nil {
+		trace = trace.compose(old)
+	}
+	return context.WithValue(ctx, sinkTraceContextKey{}, trace)
+}
+
+// ContextSinkTrace returns the trace of the context, or nil.
+func ContextSinkTrace(ctx context.Context) *SinkTrace {
+	trace, _ := ctx.Value(sinkTraceContextKey{}).(*SinkTrace)
+	return trace
+}
+
+// compose returns a trace that calls the hooks of t, then the hooks of old.
+func (t *SinkTrace) compose(old *SinkTrace) *SinkTrace {
+	return &SinkTrace{
+		Connect: func(target string) {
+			if t.Connect != nil {
+				t.Connect(target)
+			}
+			if old.Connect != nil {
+				old.Connect(target)
+			}
+		},
+		Connected: func(target string, err error) {
+			if t.Connected != nil {
+				t.Connected(target, err)
+			}
+			if old.Connected != nil {
+				old.Connected(target, err)
+			}
+		},
+		Publish: func(target string, deliveryID string) {
+			if t.Publish != nil {
+				t.Publish(target, deliveryID)
+			}
+			if old.Publish != nil {
+				old.Publish(target, deliveryID)
+			}
+		},
+		Ack: func(target string, deliveryID string, err error) {
+			if t.Ack != nil {
+				t.Ack(target, deliveryID, err)
+			}
+			if old.Ack != nil {
+				old.Ack(target, deliveryID, err)
+			}
+		},
+	}
+}
+
+// TracePublish calls publish, between the [SinkTrace.Publish] and [SinkTrace.Ack] hooks of the trace of the context.
+//
+// It's used by the [Sink] implementations.
+func TracePublish(ctx context.Context, target string, deliveryID string, publish func() error) error {
+	trace := ContextSinkTrace(ctx)
+	if trace != nil && trace.Publish != nil {
+		trace.Publish(target, deliveryID)
+	}
+	err := publish()
+	if trace != nil && trace.Ack != nil {
+		trace.Ack(target, deliveryID, err)
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestSinkTrace(t *testing.T) {
+	var calls []string
+	ctx := WithSinkTrace(context.Background(), &SinkTrace{
+		Publish: func(target string, deliveryID string) {
+			calls = append(calls, "old publish "+target+" "+deliveryID)
+		},
+		Ack: func(target string, deliveryID string, err error) {
+			calls = append(calls, "old ack "+err.Error())
+		},
+	})
+	ctx = WithSinkTrace(ctx, &SinkTrace{
+		Connect: func(target string) {
+			calls = append(calls, "connect "+target)
+		},
+		Publish: func(target string, deliveryID string) {
+			calls = append(calls, "new publish "+target+" "+deliveryID)
+		},
+	})
+	trace := ContextSinkTrace(ctx)
+	trace.Connect("target")
+	trace.Connected("target", nil)
+	err := TracePublish(ctx, "target", "1", func() error {
+		calls = append(calls, "publish")
+		return errors.New("error")
+	})
+	assert.Error(t, err)
+	assert.SliceEqual(t, calls, []string{
+		"connect target",
+		"new publish target 1",
+		"old publish target 1",
+		"publish",
+		"old ack error",
+	})
+}
+
+func TestTracePublishWithoutTrace(t *testing.T) {
+	assert.Zero(t, ContextSinkTrace(context.Background()))
+	called := false
+	err := TracePublish(context.Background(), "target", "1", func() error {
+		called = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
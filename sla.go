@@ -0,0 +1,149 @@
+package githubhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	slaBuckets = 60
+	// minSLAWindow is the minimum window of a [SLA], so each bucket covers at least 1 second.
+	minSLAWindow = slaBuckets * time.Second
+)
+
+/*
+SLA tracks the compliance of deliveries with a service level objective (e.g. 95% of deliveries processed within 5s), over a rolling window.
+
+A delivery is compliant if it's processed successfully within the target duration after it was received ([DeliveryMetadata.ReceivedAt]).
+It includes the time spent in the queue of [Handler.Async].
+The SLA is breached if the ratio of compliant deliveries in the window is lower than the objective.
+
+Fields (all are optional):
+  - MinDeliveries is the minimum number of deliveries in the window before the SLA can be breached.
+  - Breach is called when the SLA becomes breached.
+  - Restore is called when the SLA is no longer breached.
+
+It must be created with [NewSLA], and receive the deliveries with [SLA.Middleware].
+The report can be exported to Prometheus with the metrics package (SLACollector).
+*/
+type SLA struct {
+	MinDeliveries int
+	Breach        func(ctx context.Context, r SLAReport)
+	Restore       func(ctx context.Context, r SLAReport)
+
+	objective float64
+	target    time.Duration
+	window    time.Duration
+
+	mu       sync.Mutex
+	buckets  [slaBuckets]slaBucket
+	breached bool
+	now      func() time.Time
+}
+
+type slaBucket struct {
+	index     int64
+	total     int
+	compliant int
+}
+
+// NewSLA creates a new [SLA].
+//
+// objective is the minimum ratio of compliant deliveries (e.g. 0.95), target the maximum processing duration, and window the duration of the rolling window (minimum: 1 minute).
+func NewSLA(objective float64, target time.Duration, window time.Duration) *SLA {
+	return &SLA{
+		objective: objective,
+		target:    target,
+		window:    max(window, minSLAWindow),
+		now:       time.Now,
+	}
+}
+
+// Middleware returns a [DeliveryMiddleware] that records the deliveries.
+func (s *SLA) Middleware(next DeliveryHandler) DeliveryHandler {
+	return func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		err := next(ctx, md, payload)
+		start := md.ReceivedAt
+		if start.IsZero() {
+			start = s.now()
+		}
+		s.Record(ctx, s.now().Sub(start), err == nil)
+		return err
+	}
+}
+
+// Record records a delivery, with its processing duration and its result.
+func (s *SLA) Record(ctx context.Context, duration time.Duration, success bool) {
+	s.mu.Lock()
+	b := s.getBucket(s.now())
+	b.total++
+	if success && duration <= s.target {
+		b.compliant++
+	}
+	r := s.report()
+	changed := r.Breached != s.breached
+	s.breached = r.Breached
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+	if r.Breached && s.Breach != nil {
+		s.Breach(ctx, r)
+	}
+	if !r.Breached && s.Restore != nil {
+		s.Restore(ctx, r)
+	}
+}
+
+// Report returns the current compliance report.
+func (s *SLA) Report() SLAReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report()
+}
+
+func (s *SLA) bucketDuration() time.Duration {
+	return s.window / slaBuckets
+}
+
+func (s *SLA) getBucket(now time.Time) *slaBucket {
+	index := now.UnixNano() / int64(s.bucketDuration())
+	b := &s.buckets[index%slaBuckets]
+	if b.index != index {
+		*b = slaBucket{
+			index: index,
+		}
+	}
+	return b
+}
+
+func (s *SLA) report() SLAReport {
+	r := SLAReport{
+		Objective: s.objective,
+		Target:    s.target,
+	}
+	index := s.now().UnixNano() / int64(s.bucketDuration())
+	for _, b := range s.buckets {
+		if b.index > index-slaBuckets && b.index <= index {
+			r.Total += b.total
+			r.Compliant += b.compliant
+		}
+	}
+	r.Compliance = 1
+	if r.Total > 0 {
+		r.Compliance = float64(r.Compliant) / float64(r.Total)
+	}
+	r.Breached = r.Total > 0 && r.Total >= s.MinDeliveries && r.Compliance < s.objective
+	return r
+}
+
+// SLAReport is the compliance report of a [SLA], for the rolling window.
+type SLAReport struct {
+	Objective  float64       `json:"objective"`
+	Target     time.Duration `json:"target"`
+	Total      int           `json:"total"`
+	Compliant  int           `json:"compliant"`
+	Compliance float64       `json:"compliance"`
+	Breached   bool          `json:"breached"`
+}
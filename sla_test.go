@@ -0,0 +1,77 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestSLA(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSLA(0.9, time.Second, time.Minute)
+	s.now = func() time.Time {
+		return now
+	}
+	var breaches, restores int
+	s.Breach = func(ctx context.Context, r SLAReport) {
+		breaches++
+	}
+	s.Restore = func(ctx context.Context, r SLAReport) {
+		restores++
+	}
+	for range 9 {
+		s.Record(ctx, 100*time.Millisecond, true)
+	}
+	s.Record(ctx, 2*time.Second, true)
+	r := s.Report()
+	assert.Equal(t, r.Total, 10)
+	assert.Equal(t, r.Compliant, 9)
+	assert.False(t, r.Breached)
+	s.Record(ctx, 100*time.Millisecond, false)
+	r = s.Report()
+	assert.True(t, r.Breached)
+	assert.Equal(t, breaches, 1)
+	now = now.Add(2 * time.Minute)
+	s.Record(ctx, 100*time.Millisecond, true)
+	r = s.Report()
+	assert.Equal(t, r.Total, 1)
+	assert.False(t, r.Breached)
+	assert.Equal(t, restores, 1)
+}
+
+func TestSLAMinDeliveries(t *testing.T) {
+	ctx := context.Background()
+	s := NewSLA(0.9, time.Second, time.Minute)
+	s.MinDeliveries = 2
+	s.Record(ctx, 2*time.Second, true)
+	assert.False(t, s.Report().Breached)
+	s.Record(ctx, 2*time.Second, true)
+	assert.True(t, s.Report().Breached)
+}
+
+func TestSLAMiddleware(t *testing.T) {
+	ctx := context.Background()
+	s := NewSLA(0.9, time.Second, time.Minute)
+	h := Chain(s.Middleware)(func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+		if md.Event == "issues" {
+			return errors.New("error")
+		}
+		return nil
+	})
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push", ReceivedAt: time.Now()}, nil))
+	assert.NoError(t, h(ctx, &DeliveryMetadata{Event: "push", ReceivedAt: time.Now().Add(-time.Minute)}, nil))
+	assert.Error(t, h(ctx, &DeliveryMetadata{Event: "issues", ReceivedAt: time.Now()}, nil))
+	r := s.Report()
+	assert.Equal(t, r.Total, 3)
+	assert.Equal(t, r.Compliant, 1)
+}
+
+func TestNewSLAMinWindow(t *testing.T) {
+	s := NewSLA(0.9, time.Second, time.Millisecond)
+	assert.Equal(t, s.window, minSLAWindow)
+	assert.Equal(t, s.bucketDuration(), time.Second)
+}
@@ -0,0 +1,62 @@
+package githubhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ValidateSignature validates the signature of a payload, without [Handler].
+//
+// signatureHeader is the value of the X-Hub-Signature-256 ("sha256=...") or X-Hub-Signature ("sha1=...") header.
+// It returns an error wrapping [ErrInvalidSignature] if the signature is missing, malformed, or doesn't match the secret.
+func ValidateSignature(payload []byte, signatureHeader string, secret []byte) error {
+	if signatureHeader == "" {
+		return fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+	for _, scheme := range signatureSchemes {
+		if !strings.HasPrefix(signatureHeader, scheme.prefix) {
+			continue
+		}
+		err := checkSignaturePayload([][]byte{secret}, payload, signatureHeader, scheme)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown signature scheme", ErrInvalidSignature)
+}
+
+// ParsePayload extracts a delivery from a request, without [Handler].
+//
+// It checks the method and the headers, and reads the body (JSON or form encoded), limited to [DefaultMaxBodySize].
+// It doesn't verify the signature, see [ValidateSignature].
+// The returned error is a [*RequestError], that contains the suggested response status code.
+func ParsePayload(req *http.Request) (event string, deliveryID string, raw []byte, err error) {
+	return new(Handler).parseRequest(newRequestInbound(req))
+}
+
+// VerifySignature verifies the signature of a delivery with the secrets of the handler, without [http.Request].
+//
+// The header function returns the value of a request header.
+// It allows to reuse the verification in an adapter for another HTTP server.
+// [Handler.SecretProvider] is called with a nil request, and [Handler.TrustedSources] are ignored.
+// An invalid signature is returned as a [*RequestError] wrapping [ErrInvalidSignature].
+func (h *Handler) VerifySignature(ctx context.Context, event string, rawPayload []byte, header func(name string) string) error {
+	_, err := h.verifySignature(ctx, nil, event, rawPayload, header)
+	return err
+}
+
+// Process runs a verified delivery through the rest of the pipeline (deduplication, payload decoding and delivery), without [http.Request].
+//
+// It allows to reuse the pipeline in an adapter for another HTTP server, after [Handler.VerifySignature].
+// It returns the response status code: 200 if the delivery has been processed, 202 if it has been queued in [Handler.Async], or the status code of [Handler.DuplicatePolicy] for a duplicate.
+// [Handler.Quarantine] is not applied.
+func (h *Handler) Process(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (statusCode int, err error) {
+	defer recoverPanic(&err)
+	h.addInflight()
+	defer h.doneInflight()
+	statusCode, _, err = h.process(ctx, md, rawPayload)
+	return statusCode, err
+}
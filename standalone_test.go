@@ -0,0 +1,116 @@
+package githubhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestValidateSignature(t *testing.T) {
+	s := &Signer{
+		Secret: "foobar",
+	}
+	header, err := s.Header("push", "test", testRawPayload)
+	assert.NoError(t, err)
+	for _, name := range []string{"X-Hub-Signature-256", "X-Hub-Signature"} {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateSignature(testRawPayload, header.Get(name), []byte("foobar"))
+			assert.NoError(t, err)
+			err = ValidateSignature(testRawPayload, header.Get(name), []byte("invalid"))
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestValidateSignatureError(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		signature string
+	}{
+		{
+			name: "Missing",
+		},
+		{
+			name:      "UnknownScheme",
+			signature: "md5=00",
+		},
+		{
+			name:      "InvalidHex",
+			signature: "sha256=zz",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSignature(testRawPayload, tc.signature, []byte("foobar"))
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	ctx := context.Background()
+	req, err := new(Signer).NewRequest(ctx, "/", "push", "test", testRawPayload)
+	assert.NoError(t, err)
+	event, deliveryID, raw, err := ParsePayload(req)
+	assert.NoError(t, err)
+	assert.Equal(t, event, "push")
+	assert.Equal(t, deliveryID, "test")
+	assert.BytesEqual(t, raw, testRawPayload)
+}
+
+func TestParsePayloadError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	_, _, _, err := ParsePayload(req)
+	var reqErr *RequestError
+	assert.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, reqErr.StatusCode, http.StatusBadRequest)
+}
+
+func TestHandlerVerifySignature(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secrets: []string{"old", "new"},
+	}
+	header, err := (&Signer{Secret: "new"}).Header("push", "test", testRawPayload)
+	assert.NoError(t, err)
+	err = h.VerifySignature(ctx, "push", testRawPayload, header.Get)
+	assert.NoError(t, err)
+	header, err = (&Signer{Secret: "invalid"}).Header("push", "test", testRawPayload)
+	assert.NoError(t, err)
+	err = h.VerifySignature(ctx, "push", testRawPayload, header.Get)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestHandlerProcess(t *testing.T) {
+	ctx := context.Background()
+	var md *DeliveryMetadata
+	h := &Handler{
+		Delivery: func(ctx context.Context, m *DeliveryMetadata, payload any) error {
+			md = m
+			return nil
+		},
+	}
+	header := http.Header{}
+	header.Set("X-GitHub-Hook-ID", "123")
+	statusCode, err := h.Process(ctx, NewDeliveryMetadata("push", "test", header.Get), testRawPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, statusCode, http.StatusOK)
+	assert.Equal(t, md.DeliveryID, "test")
+	assert.Equal(t, md.HookID, "123")
+}
+
+func TestHandlerProcessPanic(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic("test")
+		},
+	}
+	_, err := h.Process(ctx, NewDeliveryMetadata("push", "test", http.Header{}.Get), testRawPayload)
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+}
@@ -0,0 +1,274 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var statsDurationBounds = []time.Duration{
+	1 * time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	60 * time.Second,
+}
+
+const (
+	statsMinutes = 60
+	statsHours   = 24
+)
+
+/*
+Stats aggregates per-minute and per-hour rollups of the requests served by a [http.Handler] (usually a [Handler]).
+
+It keeps the last 60 minutes and the last 24 hours.
+It must be created with [NewStats].
+
+"It implements [http.Handler], and serves the rollups as JSON (e.g. for an admin endpoint).
+If Quota is set, the usage of the repositories in the current window of the [Quota] is also served.
+*/
+type Stats struct {
+	Quota *Quota
+
+	mu      sync.Mutex
+	minutes *statsSeries
+	hours   *statsSeries
+	now     func() time.Time
+}
+
+// NewStats creates a new [Stats].
+func NewStats() *Stats {
+	return &Stats{
+		minutes: newStatsSeries(time.Minute, statsMinutes),
+		hours:   newStatsSeries(time.Hour, statsHours),
+		now:     time.Now,
+	}
+}
+
+// Handler returns a [http.Handler] that records the requests served by the given [http.Handler].
+func (s *Stats) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := s.now()
+		sw := &statusResponseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		rec := new(statsRecorder)
+		req = req.WithContext(context.WithValue(req.Context(), statsRecorderContextKey{}, rec))
+		h.ServeHTTP(sw, req)
+		s.RecordError(sw.statusCode, s.now().Sub(start), rec.err)
+	})
+}
+
+// Record records a request with its response status code and duration.
+//
+// Status codes >= 400 are considered as errors.
+// Status code 408 is also counted as a timeout, in order to distinguish network problems from application problems.
+func (s *Stats) Record(statusCode int, duration time.Duration) {
+	s.RecordError(statusCode, duration, nil)
+}
+
+// RecordError is like [Stats.Record], with the error of the request (nil if it succeeded).
+//
+// The errors wrapping [ErrBodyReadAborted] are also counted as aborted, in order to distinguish the clients that disconnect from the invalid requests (both have a 400 status code).
+// [Stats.Handler] records the error returned by a [Handler].
+func (s *Stats) RecordError(statusCode int, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	aborted := errors.Is(err, ErrBodyReadAborted)
+	s.minutes.add(now, duration, statusCode, aborted)
+	s.hours.add(now, duration, statusCode, aborted)
+}
+
+// statsRecorder records the error of a request served by [Stats.Handler].
+type statsRecorder struct {
+	err error
+}
+
+type statsRecorderContextKey struct{}
+
+// recordStatsError records the error of the request, if it's served by [Stats.Handler].
+func recordStatsError(ctx context.Context, err error) {
+	rec, ok := ctx.Value(statsRecorderContextKey{}).(*statsRecorder)
+	if ok {
+		rec.err = err
+	}
+}
+
+// Minutes returns the per-minute rollups, sorted from the oldest to the newest.
+func (s *Stats) Minutes() []StatsRollup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.minutes.rollups(s.now())
+}
+
+// Hours returns the per-hour rollups, sorted from the oldest to the newest.
+func (s *Stats) Hours() []StatsRollup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hours.rollups(s.now())
+}
+
+func (s *Stats) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	v := struct {
+		Minutes []StatsRollup `json:"minutes"`
+		Hours   []StatsRollup `json:"hours"`
+		Quota   []QuotaUsage  `json:"quota,omitempty"`
+	}{
+		Minutes: s.Minutes(),
+		Hours:   s.Hours(),
+	}
+	if s.Quota != nil {
+		_, v.Quota = s.Quota.Usage()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// StatsRollup is the rollup of the requests for a period.
+//
+// Aborted is the number of requests aborted by the client while the body was read (see [Stats.RecordError]).
+// P95Duration is approximated with a histogram.
+type StatsRollup struct {
+	Start       time.Time     `json:"start"`
+	Count       int           `json:"count"`
+	Errors      int           `json:"errors"`
+	Timeouts    int           `json:"timeouts"`
+	Aborted     int           `json:"aborted"`
+	ErrorRate   float64       `json:"error_rate"`
+	P95Duration time.Duration `json:"p95_duration"`
+}
+
+type statsSeries struct {
+	period  time.Duration
+	size    int
+	buckets []*statsBucket
+}
+
+func newStatsSeries(period time.Duration, size int) *statsSeries {
+	return &statsSeries{
+		period: period,
+		size:   size,
+	}
+}
+
+func (ss *statsSeries) add(now time.Time, duration time.Duration, statusCode int, aborted bool) {
+	start := now.Truncate(ss.period)
+	var b *statsBucket
+	if len(ss.buckets) > 0 && ss.buckets[len(ss.buckets)-1].start.Equal(start) {
+		b = ss.buckets[len(ss.buckets)-1]
+	} else {
+		b = &statsBucket{
+			start:     start,
+			durations: make([]int, len(statsDurationBounds)+1),
+		}
+		ss.buckets = append(ss.buckets, b)
+	}
+	ss.expire(now)
+	b.add(duration, statusCode, aborted)
+}
+
+func (ss *statsSeries) expire(now time.Time) {
+	minStart := now.Truncate(ss.period).Add(-time.Duration(ss.size-1) * ss.period)
+	i := 0
+	for i < len(ss.buckets) && ss.buckets[i].start.Before(minStart) {
+		i++
+	}
+	ss.buckets = ss.buckets[i:]
+}
+
+func (ss *statsSeries) rollups(now time.Time) []StatsRollup {
+	ss.expire(now)
+	rs := make([]StatsRollup, 0, len(ss.buckets))
+	for _, b := range ss.buckets {
+		rs = append(rs, b.rollup())
+	}
+	return rs
+}
+
+type statsBucket struct {
+	start     time.Time
+	count     int
+	errors    int
+	timeouts  int
+	aborted   int
+	durations []int
+}
+
+func (b *statsBucket) add(duration time.Duration, statusCode int, aborted bool) {
+	b.count++
+	if statusCode >= http.StatusBadRequest {
+		b.errors++
+	}
+	if statusCode == http.StatusRequestTimeout {
+		b.timeouts++
+	}
+	if aborted {
+		b.aborted++
+	}
+	i := 0
+	for i < len(statsDurationBounds) && duration > statsDurationBounds[i] {
+		i++
+	}
+	b.durations[i]++
+}
+
+func (b *statsBucket) rollup() StatsRollup {
+	r := StatsRollup{
+		Start:    b.start,
+		Count:    b.count,
+		Errors:   b.errors,
+		Timeouts: b.timeouts,
+		Aborted:  b.aborted,
+	}
+	if b.count > 0 {
+		r.ErrorRate = float64(b.errors) / float64(b.count)
+		r.P95Duration = b.percentile(0.95)
+	}
+	return r
+}
+
+func (b *statsBucket) percentile(p float64) time.Duration {
+	target := int(float64(b.count)*p + 0.5)
+	n := 0
+	for i, c := range b.durations {
+		n += c
+		if n >= target && i < len(statsDurationBounds) {
+			return statsDurationBounds[i]
+		}
+	}
+	return statsDurationBounds[len(statsDurationBounds)-1]
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
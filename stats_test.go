@@ -0,0 +1,118 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	s := NewStats()
+	srv := httptest.NewServer(s.Handler(&Handler{}))
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, http.NoBody)
+	assert.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusMethodNotAllowed)
+	minutes := s.Minutes()
+	assert.SliceLen(t, minutes, 1)
+	assert.Equal(t, minutes[0].Count, 2)
+	assert.Equal(t, minutes[0].Errors, 1)
+	assert.Equal(t, minutes[0].ErrorRate, 0.5)
+	hours := s.Hours()
+	assert.SliceLen(t, hours, 1)
+	assert.Equal(t, hours[0].Count, 2)
+}
+
+func TestStatsRollups(t *testing.T) {
+	s := NewStats()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		return now
+	}
+	for range 99 {
+		s.Record(http.StatusOK, 3*time.Millisecond)
+	}
+	s.Record(http.StatusInternalServerError, 3*time.Second)
+	s.Record(http.StatusRequestTimeout, 10*time.Second)
+	s.RecordError(http.StatusBadRequest, time.Second, fmt.Errorf("%w: %w", ErrBodyReadAborted, io.ErrUnexpectedEOF))
+	now = now.Add(time.Minute)
+	s.Record(http.StatusOK, 10*time.Millisecond)
+	minutes := s.Minutes()
+	assert.SliceLen(t, minutes, 2)
+	assert.Equal(t, minutes[0].Count, 102)
+	assert.Equal(t, minutes[0].Errors, 3)
+	assert.Equal(t, minutes[0].Timeouts, 1)
+	assert.Equal(t, minutes[0].Aborted, 1)
+	assert.Equal(t, minutes[0].P95Duration, 5*time.Millisecond)
+	assert.Equal(t, minutes[1].Count, 1)
+	assert.Equal(t, minutes[1].P95Duration, 10*time.Millisecond)
+	now = now.Add(2 * time.Hour)
+	assert.SliceEmpty(t, s.Minutes())
+	assert.SliceLen(t, s.Hours(), 1)
+}
+
+func TestStatsAborted(t *testing.T) {
+	s := NewStats()
+	h := s.Handler(&Handler{})
+	req := httptest.NewRequest(http.MethodPost, "/", &testErrorReader{err: io.ErrUnexpectedEOF})
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	req = httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	minutes := s.Minutes()
+	assert.SliceLen(t, minutes, 1)
+	assert.Equal(t, minutes[0].Errors, 2)
+	assert.Equal(t, minutes[0].Aborted, 1)
+}
+
+func TestStatsServeHTTP(t *testing.T) {
+	s := NewStats()
+	s.Record(http.StatusOK, time.Millisecond)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+	var v struct {
+		Minutes []StatsRollup `json:"minutes"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.SliceLen(t, v.Minutes, 1)
+}
+
+func TestStatsServeHTTPQuota(t *testing.T) {
+	s := NewStats()
+	s.Quota = new(Quota)
+	s.Quota.add("octocat/a")
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	s.ServeHTTP(w, req)
+	var v struct {
+		Quota []QuotaUsage `json:"quota"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	assert.NoError(t, err)
+	assert.SliceEqual(t, v.Quota, []QuotaUsage{{Repository: "octocat/a", Count: 1}})
+}
@@ -0,0 +1,309 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DeliveryStatus is the processing status of a [StoredDelivery].
+type DeliveryStatus string
+
+// Delivery statuses.
+const (
+	// DeliveryStatusPending is the status of a delivery that is received, but not processed yet (e.g. queued in [Handler.Async]).
+	DeliveryStatusPending DeliveryStatus = "pending"
+	// DeliveryStatusSucceeded is the status of a delivery that is processed successfully.
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	// DeliveryStatusFailed is the status of a delivery whose processing failed.
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+/*
+StoredDelivery is a delivery stored in a [Store].
+
+Fields:
+  - DeliveryMetadata is the metadata of the delivery. The delivery ID identifies the delivery in the store.
+  - Repository is the full name of the repository of the payload (see [RepositoryFullName]), or empty.
+  - RawPayload is the raw JSON payload. It must not be modified.
+  - Status is the processing status.
+  - Error is the error message if the processing failed.
+  - UpdatedAt is the time of the last status update.
+*/
+type StoredDelivery struct {
+	DeliveryMetadata
+	Repository string
+	RawPayload []byte
+	Status     DeliveryStatus
+	Error      string
+	UpdatedAt  time.Time
+}
+
+// StoreFilter filters the deliveries returned by [Store.List].
+//
+// Empty fields match all deliveries.
+type StoreFilter struct {
+	// Event matches the event name.
+	Event string
+	// Repository matches the repository full name.
+	Repository string
+	// Status matches the processing status.
+	Status DeliveryStatus
+	// Since matches the deliveries received at or after this time.
+	Since time.Time
+	// Until matches the deliveries received before this time.
+	Until time.Time
+	// Limit is the maximum number of returned deliveries.
+	Limit int
+	// Newest sorts the deliveries from the newest to the oldest received, so Limit keeps the newest deliveries.
+	Newest bool
+}
+
+// Match returns true if the delivery matches the filter (except Limit and Newest).
+func (f *StoreFilter) Match(d *StoredDelivery) bool {
+	return (f.Event == "" || d.Event == f.Event) &&
+		(f.Repository == "" || d.Repository == f.Repository) &&
+		(f.Status == "" || d.Status == f.Status) &&
+		(f.Since.IsZero() || !d.ReceivedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || d.ReceivedAt.Before(f.Until))
+}
+
+// ErrStoredDeliveryNotFound is returned by [Store] if the delivery is not found.
+var ErrStoredDeliveryNotFound = errors.New("stored delivery not found")
+
+// Store persists the raw deliveries, with their metadata and processing status.
+//
+// It's the foundation for replay, audit and admin tooling.
+// See [Handler.Store].
+type Store interface {
+	// Save creates or replaces a delivery, identified by its delivery ID.
+	Save(ctx context.Context, d *StoredDelivery) error
+	// Get returns a delivery, or an error wrapping [ErrStoredDeliveryNotFound].
+	Get(ctx context.Context, deliveryID string) (*StoredDelivery, error)
+	// List returns the deliveries matching the filter, sorted from the oldest to the newest received (or the reverse, see [StoreFilter.Newest]).
+	List(ctx context.Context, filter StoreFilter) ([]*StoredDelivery, error)
+	// Delete deletes a delivery, or returns an error wrapping [ErrStoredDeliveryNotFound].
+	Delete(ctx context.Context, deliveryID string) error
+}
+
+// PayloadDigest returns the content address of a raw payload: the SHA-256 hash of its bytes.
+//
+// The stores use it to store each distinct payload once, separately from the metadata of the deliveries.
+// So the redeliveries and the duplicate events don't store the same payload twice.
+func PayloadDigest(rawPayload []byte) [sha256.Size]byte {
+	return sha256.Sum256(rawPayload)
+}
+
+// storeDelivery saves a verified delivery with the pending status.
+func (h *Handler) storeDelivery(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) error {
+	if h.Store == nil {
+		return nil
+	}
+	err := h.Store.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: *md,
+		Repository:       RepositoryFullName(json.RawMessage(rawPayload)),
+		RawPayload:       rawPayload,
+		Status:           DeliveryStatusPending,
+		UpdatedAt:        time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	return nil
+}
+
+// updateStoredDelivery updates the status of a stored delivery, after it's processed.
+//
+// The delivery is already processed, so an error is reported to [Handler.Error] instead of being returned.
+func (h *Handler) updateStoredDelivery(ctx context.Context, md *DeliveryMetadata, rawPayload []byte, deliveryErr error) {
+	if h.Store == nil {
+		return
+	}
+	d := &StoredDelivery{
+		DeliveryMetadata: *md,
+		Repository:       RepositoryFullName(json.RawMessage(rawPayload)),
+		RawPayload:       rawPayload,
+		Status:           DeliveryStatusSucceeded,
+		UpdatedAt:        time.Now(),
+	}
+	if deliveryErr != nil {
+		d.Status = DeliveryStatusFailed
+		d.Error = deliveryErr.Error()
+	}
+	err := h.Store.Save(ctx, d)
+	if err != nil && h.Error != nil {
+		h.Error(ctx, fmt.Errorf("store: %w", err), nil)
+	}
+}
+
+/*
+MemoryStore is an in-memory [Store].
+
+It keeps a bounded number of deliveries (the oldest saved are evicted).
+The payloads are content-addressed (see [PayloadDigest]): the deliveries with the same payload share it, and it's released with the last of them.
+It must be created with [NewMemoryStore].
+*/
+type MemoryStore struct {
+	size int
+
+	mu         sync.Mutex
+	deliveries map[string]*StoredDelivery
+	ids        []string
+	payloads   map[[sha256.Size]byte]*memoryPayload
+}
+
+// memoryPayload is a payload shared by the deliveries of a [MemoryStore].
+type memoryPayload struct {
+	rawPayload []byte
+	refs       int
+}
+
+// NewMemoryStore creates a new [MemoryStore].
+//
+// size is the maximum number of deliveries (minimum 1).
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{
+		size:       max(size, 1),
+		deliveries: make(map[string]*StoredDelivery),
+		payloads:   make(map[[sha256.Size]byte]*memoryPayload),
+	}
+}
+
+// Save implements [Store].
+//
+// The raw payload is copied (once per distinct payload), so the caller can reuse it.
+func (s *MemoryStore) Save(ctx context.Context, d *StoredDelivery) error {
+	c := *d
+	c.Annotations = d.Annotations.Clone()
+	d = &c
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.RawPayload = s.acquirePayload(d.RawPayload)
+	if old, ok := s.deliveries[d.DeliveryID]; ok {
+		s.releasePayload(old.RawPayload)
+	} else {
+		s.ids = append(s.ids, d.DeliveryID)
+	}
+	s.deliveries[d.DeliveryID] = d
+	for len(s.ids) > s.size {
+		s.releasePayload(s.deliveries[s.ids[0]].RawPayload)
+		delete(s.deliveries, s.ids[0])
+		s.ids = slices.Delete(s.ids, 0, 1)
+	}
+	return nil
+}
+
+// acquirePayload returns the shared copy of a payload, and increments its references.
+func (s *MemoryStore) acquirePayload(rawPayload []byte) []byte {
+	digest := PayloadDigest(rawPayload)
+	p, ok := s.payloads[digest]
+	if !ok {
+		p = &memoryPayload{
+			rawPayload: bytes.Clone(rawPayload),
+		}
+		s.payloads[digest] = p
+	}
+	p.refs++
+	return p.rawPayload
+}
+
+// releasePayload decrements the references of a payload, and deletes it if it's not referenced anymore.
+func (s *MemoryStore) releasePayload(rawPayload []byte) {
+	digest := PayloadDigest(rawPayload)
+	p, ok := s.payloads[digest]
+	if !ok {
+		return
+	}
+	p.refs--
+	if p.refs <= 0 {
+		delete(s.payloads, digest)
+	}
+}
+
+// Get implements [Store].
+func (s *MemoryStore) Get(ctx context.Context, deliveryID string) (*StoredDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[deliveryID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStoredDeliveryNotFound, deliveryID)
+	}
+	c := *d
+	c.Annotations = d.Annotations.Clone()
+	return &c, nil
+}
+
+// List implements [Store].
+func (s *MemoryStore) List(ctx context.Context, filter StoreFilter) ([]*StoredDelivery, error) {
+	s.mu.Lock()
+	var ds []*StoredDelivery
+	for _, id := range s.ids {
+		d := s.deliveries[id]
+		if filter.Match(d) {
+			c := *d
+			c.Annotations = d.Annotations.Clone()
+			ds = append(ds, &c)
+		}
+	}
+	s.mu.Unlock()
+	slices.SortStableFunc(ds, func(a, b *StoredDelivery) int {
+		if filter.Newest {
+			return b.ReceivedAt.Compare(a.ReceivedAt)
+		}
+		return a.ReceivedAt.Compare(b.ReceivedAt)
+	})
+	if filter.Limit > 0 && len(ds) > filter.Limit {
+		ds = ds[:filter.Limit]
+	}
+	return ds, nil
+}
+
+// Delete implements [Store].
+func (s *MemoryStore) Delete(ctx context.Context, deliveryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deliveries[deliveryID]; !ok {
+		return fmt.Errorf("%w: %s", ErrStoredDeliveryNotFound, deliveryID)
+	}
+	s.releasePayload(s.deliveries[deliveryID].RawPayload)
+	delete(s.deliveries, deliveryID)
+	s.ids = slices.DeleteFunc(s.ids, func(id string) bool {
+		return id == deliveryID
+	})
+	return nil
+}
+
+// Redeliver runs a stored delivery through the rest of the pipeline of the [Handler] (payload decoding and delivery), and updates its status in [Handler.Store].
+//
+// The redelivered delivery has a [Lineage] with the operator and the "redeliver" reason.
+// It returns the response status code: 200 if the delivery has been processed, or 202 if it has been queued in [Handler.Async].
+func (h *Handler) Redeliver(ctx context.Context, deliveryID string, operator string) (statusCode int, err error) {
+	if h.Store == nil {
+		return 0, errors.New("store not configured")
+	}
+	h.addInflight()
+	defer h.doneInflight()
+	d, err := h.Store.Get(ctx, deliveryID)
+	if err != nil {
+		return 0, fmt.Errorf("store: %w", err)
+	}
+	md := d.DeliveryMetadata
+	md.Lineage = NextLineage(&d.DeliveryMetadata, operator, "redeliver")
+	statusCode, err = h.redeliver(ctx, &md, d.RawPayload)
+	if statusCode != http.StatusAccepted {
+		h.updateStoredDelivery(ctx, &md, d.RawPayload, err)
+	}
+	return statusCode, err
+}
+
+func (h *Handler) redeliver(ctx context.Context, md *DeliveryMetadata, rawPayload []byte) (statusCode int, err error) {
+	defer recoverPanic(&err)
+	return h.deliver(ctx, md, rawPayload)
+}
@@ -0,0 +1,356 @@
+package githubhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			d, err := s.Get(ctx, md.DeliveryID)
+			assert.NoError(t, err)
+			assert.Equal(t, d.Status, DeliveryStatusPending)
+			return nil
+		},
+		Store: s,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", []byte(`{"repository":{"full_name":"pierrre/githubhook"}}`))
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatusOK(t, resp)
+	d, err := s.Get(ctx, req.Header.Get("X-GitHub-Delivery"))
+	assert.NoError(t, err)
+	assert.Equal(t, d.Event, "push")
+	assert.Equal(t, d.Repository, "pierrre/githubhook")
+	assert.Equal(t, d.Status, DeliveryStatusSucceeded)
+	assert.Zero(t, d.Error)
+}
+
+func TestHandlerStoreDeliveryError(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return errors.New("error")
+		},
+		Store: s,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusInternalServerError)
+	d, err := s.Get(ctx, req.Header.Get("X-GitHub-Delivery"))
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusFailed)
+	assert.NotZero(t, d.Error)
+}
+
+func TestHandlerStoreAsync(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	p := NewAsyncPool(1, 10)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			return nil
+		},
+		Async: p,
+		Store: s,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusAccepted)
+	err = p.Shutdown(ctx)
+	assert.NoError(t, err)
+	d, err := s.Get(ctx, req.Header.Get("X-GitHub-Delivery"))
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusSucceeded)
+}
+
+type testErrorStore struct {
+	Store
+}
+
+func (s *testErrorStore) Save(ctx context.Context, d *StoredDelivery) error {
+	return errors.New("error")
+}
+
+func (s *testErrorStore) List(ctx context.Context, filter StoreFilter) ([]*StoredDelivery, error) {
+	return nil, errors.New("error")
+}
+
+func TestHandlerStoreError(t *testing.T) {
+	ctx := context.Background()
+	deliveryCalled := false
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			deliveryCalled = true
+			return nil
+		},
+		Store: &testErrorStore{},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	testExpectResponseStatus(t, resp, http.StatusInternalServerError)
+	assert.False(t, deliveryCalled)
+}
+
+func TestHandlerRedeliver(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	err := s.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			Event:      "push",
+			DeliveryID: "test",
+		},
+		RawPayload: testRawPayload,
+		Status:     DeliveryStatusFailed,
+		Error:      "error",
+	})
+	assert.NoError(t, err)
+	var lineage *Lineage
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			lineage = md.Lineage
+			return nil
+		},
+		Store: s,
+	}
+	statusCode, err := h.Redeliver(ctx, "test", "operator")
+	assert.NoError(t, err)
+	assert.Equal(t, statusCode, http.StatusOK)
+	assert.DeepEqual(t, lineage, &Lineage{
+		OriginalDeliveryID: "test",
+		Generation:         1,
+		Operator:           "operator",
+		Reason:             "redeliver",
+	})
+	d, err := s.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusSucceeded)
+	assert.Zero(t, d.Error)
+	assert.DeepEqual(t, d.Lineage, lineage)
+}
+
+func TestHandlerRedeliverDeliveryError(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	err := s.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			Event:      "push",
+			DeliveryID: "test",
+		},
+		RawPayload: testRawPayload,
+	})
+	assert.NoError(t, err)
+	h := &Handler{
+		Delivery: func(ctx context.Context, md *DeliveryMetadata, payload any) error {
+			panic("error")
+		},
+		Store: s,
+	}
+	_, err = h.Redeliver(ctx, "test", "")
+	assert.ErrorAs(t, err, new(*PanicError))
+	d, err := s.Get(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Status, DeliveryStatusFailed)
+}
+
+func TestHandlerRedeliverNotFound(t *testing.T) {
+	h := &Handler{
+		Store: NewMemoryStore(10),
+	}
+	_, err := h.Redeliver(context.Background(), "test", "")
+	assert.ErrorIs(t, err, ErrStoredDeliveryNotFound)
+}
+
+func TestHandlerRedeliverNoStore(t *testing.T) {
+	_, err := new(Handler).Redeliver(context.Background(), "test", "")
+	assert.Error(t, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"1", "2", "3"} {
+		err := s.Save(ctx, &StoredDelivery{
+			DeliveryMetadata: DeliveryMetadata{
+				Event:      "push",
+				DeliveryID: id,
+				ReceivedAt: now.Add(time.Duration(i) * time.Minute),
+			},
+			Status: DeliveryStatusPending,
+		})
+		assert.NoError(t, err)
+	}
+	_, err := s.Get(ctx, "1")
+	assert.ErrorIs(t, err, ErrStoredDeliveryNotFound)
+	d, err := s.Get(ctx, "2")
+	assert.NoError(t, err)
+	d.Status = DeliveryStatusSucceeded
+	err = s.Save(ctx, d)
+	assert.NoError(t, err)
+	ds, err := s.List(ctx, StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 2)
+	assert.Equal(t, ds[0].DeliveryID, "2")
+	assert.Equal(t, ds[1].DeliveryID, "3")
+	ds, err = s.List(ctx, StoreFilter{Status: DeliveryStatusSucceeded})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 1)
+	ds, err = s.List(ctx, StoreFilter{Limit: 1})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 1)
+	assert.Equal(t, ds[0].DeliveryID, "2")
+	ds, err = s.List(ctx, StoreFilter{Limit: 1, Newest: true})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 1)
+	assert.Equal(t, ds[0].DeliveryID, "3")
+	err = s.Delete(ctx, "2")
+	assert.NoError(t, err)
+	err = s.Delete(ctx, "2")
+	assert.ErrorIs(t, err, ErrStoredDeliveryNotFound)
+	ds, err = s.List(ctx, StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 1)
+}
+
+func TestMemoryStoreSaveCopy(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(1)
+	rawPayload := []byte(`{}`)
+	err := s.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			DeliveryID: "1",
+		},
+		RawPayload: rawPayload,
+	})
+	assert.NoError(t, err)
+	rawPayload[0] = 'x'
+	d, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, string(d.RawPayload), `{}`)
+}
+
+func TestMemoryStoreAnnotationsCopy(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(1)
+	annotations := new(Annotations)
+	annotations.Set("rule", "deploy")
+	err := s.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			DeliveryID:  "1",
+			Annotations: annotations,
+		},
+	})
+	assert.NoError(t, err)
+	annotations.Set("rule", "modified")
+	d, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	d.Annotations.Set("rule", "get")
+	ds, err := s.List(ctx, StoreFilter{})
+	assert.NoError(t, err)
+	assert.SliceLen(t, ds, 1)
+	ds[0].Annotations.Set("rule", "list")
+	ds, err = s.List(ctx, StoreFilter{})
+	assert.NoError(t, err)
+	v, _ := ds[0].Annotations.Get("rule")
+	assert.Equal(t, v, "deploy")
+}
+
+func TestMemoryStorePayloads(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(3)
+	for _, id := range []string{"1", "2", "3"} {
+		err := s.Save(ctx, &StoredDelivery{
+			DeliveryMetadata: DeliveryMetadata{
+				DeliveryID: id,
+			},
+			RawPayload: []byte(`{"ref":"refs/heads/main"}`),
+		})
+		assert.NoError(t, err)
+	}
+	assert.MapLen(t, s.payloads, 1)
+	d1, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	d2, err := s.Get(ctx, "2")
+	assert.NoError(t, err)
+	assert.True(t, &d1.RawPayload[0] == &d2.RawPayload[0])
+	err = s.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			DeliveryID: "3",
+		},
+		RawPayload: []byte(`{}`),
+	})
+	assert.NoError(t, err)
+	assert.MapLen(t, s.payloads, 2)
+	for _, id := range []string{"1", "2"} {
+		err = s.Delete(ctx, id)
+		assert.NoError(t, err)
+	}
+	assert.MapLen(t, s.payloads, 1)
+	err = s.Save(ctx, &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			DeliveryID: "4",
+		},
+		RawPayload: []byte(`{}`),
+	})
+	assert.NoError(t, err)
+	assert.MapLen(t, s.payloads, 1)
+	assert.Equal(t, s.payloads[PayloadDigest([]byte(`{}`))].refs, 2)
+}
+
+func TestStoreFilterMatch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := &StoredDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			Event:      "push",
+			ReceivedAt: now,
+		},
+		Repository: "pierrre/githubhook",
+		Status:     DeliveryStatusFailed,
+	}
+	for _, tc := range []struct {
+		name     string
+		filter   StoreFilter
+		expected bool
+	}{
+		{name: "Empty", filter: StoreFilter{}, expected: true},
+		{name: "Event", filter: StoreFilter{Event: "push"}, expected: true},
+		{name: "OtherEvent", filter: StoreFilter{Event: "issues"}, expected: false},
+		{name: "Repository", filter: StoreFilter{Repository: "pierrre/githubhook"}, expected: true},
+		{name: "OtherRepository", filter: StoreFilter{Repository: "pierrre/other"}, expected: false},
+		{name: "OtherStatus", filter: StoreFilter{Status: DeliveryStatusSucceeded}, expected: false},
+		{name: "Since", filter: StoreFilter{Since: now}, expected: true},
+		{name: "SinceAfter", filter: StoreFilter{Since: now.Add(time.Second)}, expected: false},
+		{name: "Until", filter: StoreFilter{Until: now}, expected: false},
+		{name: "UntilAfter", filter: StoreFilter{Until: now.Add(time.Second)}, expected: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.filter.Match(d), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package githubhook
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+/*
+TrafficReport is the report of the events, actions and repositories that a deployment actually receives, with their volume and an example payload.
+
+It's generated from the stored or archived deliveries, and is useful for pruning the events of the webhook subscriptions and the filters.
+The deliveries are added with [TrafficReport.Add] or [TrafficReport.AddStore], and the report is written with [TrafficReport.WriteMarkdown] or encoded in JSON.
+
+Fields:
+  - Deliveries is the number of deliveries.
+  - Since and Until are the received times of the oldest and the newest deliveries (zero if they are unknown).
+  - Events are the received events, sorted by volume (the most received first).
+
+The zero value is ready to use.
+*/
+type TrafficReport struct {
+	Deliveries int             `json:"deliveries"`
+	Since      time.Time       `json:"since"`
+	Until      time.Time       `json:"until"`
+	Events     []*TrafficEvent `json:"events"`
+}
+
+/*
+TrafficEvent is the traffic of an event in a [TrafficReport].
+
+Fields:
+  - Event is the event name.
+  - Known is true if the event is in the catalog (see [IsKnownEvent]).
+  - Deliveries is the number of deliveries.
+  - Actions is the number of deliveries by action (empty if the event has no action).
+  - Repositories is the number of deliveries by repository full name (empty for the events without repository).
+  - Example is the payload of the most recent delivery.
+*/
+type TrafficEvent struct {
+	Event        string          `json:"event"`
+	Known        bool            `json:"known"`
+	Deliveries   int             `json:"deliveries"`
+	Actions      map[string]int  `json:"actions,omitempty"`
+	Repositories map[string]int  `json:"repositories,omitempty"`
+	Example      json.RawMessage `json:"example,omitempty"`
+
+	exampleReceivedAt time.Time
+}
+
+// Add adds a delivery to the report.
+//
+// receivedAt is optional.
+func (r *TrafficReport) Add(event string, receivedAt time.Time, rawPayload []byte) {
+	r.Deliveries++
+	if !receivedAt.IsZero() {
+		if r.Since.IsZero() || receivedAt.Before(r.Since) {
+			r.Since = receivedAt
+		}
+		if receivedAt.After(r.Until) {
+			r.Until = receivedAt
+		}
+	}
+	te := r.event(event)
+	te.Deliveries++
+	var v struct {
+		Action string `json:"action"`
+	}
+	_ = json.Unmarshal(rawPayload, &v)
+	if v.Action != "" {
+		te.Actions[v.Action]++
+	}
+	if repo := RepositoryFullName(json.RawMessage(rawPayload)); repo != "" {
+		te.Repositories[repo]++
+	}
+	if te.Example == nil || !receivedAt.Before(te.exampleReceivedAt) {
+		te.Example = bytes.Clone(rawPayload)
+		te.exampleReceivedAt = receivedAt
+	}
+	slices.SortStableFunc(r.Events, func(a, b *TrafficEvent) int {
+		return cmp.Or(cmp.Compare(b.Deliveries, a.Deliveries), strings.Compare(a.Event, b.Event))
+	})
+}
+
+func (r *TrafficReport) event(event string) *TrafficEvent {
+	for _, te := range r.Events {
+		if te.Event == event {
+			return te
+		}
+	}
+	te := &TrafficEvent{
+		Event:        event,
+		Known:        IsKnownEvent(event),
+		Actions:      make(map[string]int),
+		Repositories: make(map[string]int),
+	}
+	r.Events = append(r.Events, te)
+	return te
+}
+
+// AddStore adds the deliveries of a [Store] that match the filter to the report.
+func (r *TrafficReport) AddStore(ctx context.Context, s Store, filter StoreFilter) error {
+	ds, err := s.List(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("traffic report: %w", err)
+	}
+	for _, d := range ds {
+		r.Add(d.Event, d.ReceivedAt, d.RawPayload)
+	}
+	return nil
+}
+
+// WriteMarkdown writes the report in Markdown.
+func (r *TrafficReport) WriteMarkdown(w io.Writer) error {
+	b := new(bytes.Buffer)
+	b.WriteString("# Webhook traffic\n\n")
+	fmt.Fprintf(b, "%d deliveries", r.Deliveries)
+	if !r.Since.IsZero() {
+		fmt.Fprintf(b, ", from %s to %s", r.Since.UTC().Format(time.RFC3339), r.Until.UTC().Format(time.RFC3339))
+	}
+	b.WriteString(".\n")
+	if len(r.Events) > 0 {
+		b.WriteString("\n| Event | Deliveries | Actions | Repositories |\n| --- | ---: | ---: | ---: |\n")
+		for _, te := range r.Events {
+			fmt.Fprintf(b, "| [%s](#%s) | %d | %d | %d |\n", te.Event, te.Event, te.Deliveries, len(te.Actions), len(te.Repositories))
+		}
+	}
+	for _, te := range r.Events {
+		te.writeMarkdown(b)
+	}
+	_, err := w.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("traffic report: %w", err)
+	}
+	return nil
+}
+
+func (te *TrafficEvent) writeMarkdown(b *bytes.Buffer) {
+	fmt.Fprintf(b, "\n## %s\n\n%d deliveries", te.Event, te.Deliveries)
+	if !te.Known {
+		b.WriteString(" (unknown event)")
+	}
+	b.WriteString(".\n")
+	writeMarkdownCounts(b, "Actions", te.Actions)
+	writeMarkdownCounts(b, "Repositories", te.Repositories)
+	if te.Example != nil {
+		example := new(bytes.Buffer)
+		err := json.Indent(example, te.Example, "", "  ")
+		if err != nil {
+			example.Reset()
+			example.Write(te.Example)
+		}
+		fmt.Fprintf(b, "\nExample payload:\n\n```json\n%s\n```\n", example)
+	}
+}
+
+// writeMarkdownCounts writes the counts, sorted by count (the largest first).
+func writeMarkdownCounts(b *bytes.Buffer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s:\n\n", title)
+	keys := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	for _, k := range keys {
+		fmt.Fprintf(b, "- %s: %d\n", k, counts[k])
+	}
+}
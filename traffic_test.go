@@ -0,0 +1,73 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pierrre/assert"
+)
+
+func TestTrafficReport(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore(10)
+	for i, d := range []struct {
+		event   string
+		payload string
+	}{
+		{"pull_request", `{"action":"opened","number":1,"repository":{"full_name":"a/a"}}`},
+		{"push", `{"ref":"refs/heads/main","repository":{"full_name":"a/a"}}`},
+		{"pull_request", `{"action":"closed","number":1,"repository":{"full_name":"a/a"}}`},
+		{"pull_request", `{"action":"opened","number":2,"repository":{"full_name":"b/b"}}`},
+		{"custom", `{}`},
+	} {
+		err := s.Save(ctx, &StoredDelivery{
+			DeliveryMetadata: DeliveryMetadata{
+				Event:      d.event,
+				DeliveryID: string(rune('a' + i)),
+				ReceivedAt: t0.Add(time.Duration(i) * time.Hour),
+			},
+			RawPayload: []byte(d.payload),
+		})
+		assert.NoError(t, err)
+	}
+	r := new(TrafficReport)
+	err := r.AddStore(ctx, s, StoreFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, r.Deliveries, 5)
+	assert.Equal(t, r.Since, t0)
+	assert.Equal(t, r.Until, t0.Add(4*time.Hour))
+	assert.SliceLen(t, r.Events, 3)
+	pr := r.Events[0]
+	assert.Equal(t, pr.Event, "pull_request")
+	assert.True(t, pr.Known)
+	assert.Equal(t, pr.Deliveries, 3)
+	assert.MapEqual(t, pr.Actions, map[string]int{"opened": 2, "closed": 1})
+	assert.MapEqual(t, pr.Repositories, map[string]int{"a/a": 2, "b/b": 1})
+	assert.Equal(t, string(pr.Example), `{"action":"opened","number":2,"repository":{"full_name":"b/b"}}`)
+	assert.Equal(t, r.Events[1].Event, "custom")
+	assert.False(t, r.Events[1].Known)
+	assert.MapEmpty(t, r.Events[1].Repositories)
+	assert.Equal(t, r.Events[2].Event, "push")
+	buf := new(bytes.Buffer)
+	err = r.WriteMarkdown(buf)
+	assert.NoError(t, err)
+	md := buf.String()
+	assert.StringContains(t, md, "5 deliveries, from 2024-01-01T00:00:00Z to 2024-01-01T04:00:00Z.")
+	assert.StringContains(t, md, "| [pull_request](#pull_request) | 3 | 2 | 2 |")
+	assert.StringContains(t, md, "## custom\n\n1 deliveries (unknown event).")
+	assert.StringContains(t, md, "Actions:\n\n- opened: 2\n- closed: 1\n")
+	assert.StringContains(t, md, "```json\n{\n  \"action\": \"opened\",")
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.StringContains(t, string(b), `"event":"pull_request","known":true,"deliveries":3`)
+}
+
+func TestTrafficReportStoreError(t *testing.T) {
+	r := new(TrafficReport)
+	err := r.AddStore(context.Background(), &testErrorStore{}, StoreFilter{})
+	assert.Error(t, err)
+}
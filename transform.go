@@ -0,0 +1,164 @@
+package githubhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// PayloadTransform transforms the raw JSON payload of a delivery, and returns the new raw JSON payload.
+//
+// See [Transformed].
+type PayloadTransform func(ctx context.Context, d *VerifiedDelivery) ([]byte, error)
+
+// Transformed returns a [Sink] that sends the deliveries to the sink, with the payload transformed by the transform.
+//
+// It allows each sink to receive its own representation of the deliveries, e.g. a slim push event for a notification topic, reducing the bandwidth and the parsing costs of the downstream.
+// The delivery received by the sink is a copy, whose RawPayload is the transformed payload, and Payload is the transformed payload as a [json.RawMessage].
+// The metadata are unchanged, so the sinks that sign the payload (e.g. forwarding) sign the transformed payload.
+// It implements [SinkStarter] and [SinkCloser].
+func Transformed(sink Sink, transform PayloadTransform) Sink {
+	return &transformedSink{
+		sink:      sink,
+		transform: transform,
+	}
+}
+
+type transformedSink struct {
+	sink      Sink
+	transform PayloadTransform
+}
+
+func (s *transformedSink) Send(ctx context.Context, d *VerifiedDelivery) error {
+	rawPayload, err := s.transform(ctx, d)
+	if err != nil {
+		return fmt.Errorf("transform payload: %w", err)
+	}
+	return s.sink.Send(ctx, &VerifiedDelivery{
+		DeliveryMetadata: d.DeliveryMetadata,
+		RawPayload:       rawPayload,
+		Payload:          json.RawMessage(rawPayload),
+	})
+}
+
+func (s *transformedSink) Start(ctx context.Context) error {
+	return StartSink(ctx, s.sink)
+}
+
+func (s *transformedSink) Close(ctx context.Context) error {
+	return CloseSink(ctx, s.sink)
+}
+
+// SelectFields returns a [PayloadTransform] that keeps only the fields of the payload, identified by their dot-separated path (e.g. "repository.full_name").
+//
+// The structure of the selected fields is kept, and the missing fields are ignored:
+// with "repository.full_name", "ref" and "after", a push event becomes {"after":"...","ref":"...","repository":{"full_name":"..."}}.
+func SelectFields(paths ...string) PayloadTransform {
+	return func(ctx context.Context, d *VerifiedDelivery) ([]byte, error) {
+		src, err := decodeTransformPayload(d.RawPayload)
+		if err != nil {
+			return nil, err
+		}
+		dst := make(map[string]any)
+		for _, p := range paths {
+			selectField(dst, src, strings.Split(p, "."))
+		}
+		b, err := json.Marshal(dst)
+		if err != nil {
+			return nil, fmt.Errorf("JSON encode: %w", err)
+		}
+		return b, nil
+	}
+}
+
+func selectField(dst map[string]any, src map[string]any, path []string) {
+	v, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = v
+		return
+	}
+	srcChild, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	dstChild, ok := dst[path[0]].(map[string]any)
+	if !ok {
+		dstChild = make(map[string]any)
+	}
+	selectField(dstChild, srcChild, path[1:])
+	if len(dstChild) > 0 {
+		dst[path[0]] = dstChild
+	}
+}
+
+/*
+TemplateTransform returns a [PayloadTransform] that renders a [text/template], whose output is the new JSON payload.
+
+The data of the template has the fields:
+  - Event is the event name.
+  - DeliveryID is the delivery ID.
+  - Payload is the JSON payload, decoded to generic values (map[string]any, []any, [json.Number]...).
+
+The "json" function encodes a value to JSON, so the values are correctly quoted and escaped:
+
+	{"repository": {{json .Payload.repository.full_name}}, "ref": {{json .Payload.ref}}, "sha": {{json .Payload.after}}}
+
+It returns an error if the template can't be parsed.
+The transformation returns an error if the output is not valid JSON.
+*/
+func TemplateTransform(text string) (PayloadTransform, error) {
+	tmpl, err := template.New("transform").Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("JSON encode: %w", err)
+			}
+			return string(b), nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return func(ctx context.Context, d *VerifiedDelivery) ([]byte, error) {
+		payload, err := decodeTransformPayload(d.RawPayload)
+		if err != nil {
+			return nil, err
+		}
+		buf := new(bytes.Buffer)
+		err = tmpl.Execute(buf, struct {
+			Event      string
+			DeliveryID string
+			Payload    map[string]any
+		}{
+			Event:      d.Event,
+			DeliveryID: d.DeliveryID,
+			Payload:    payload,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, errors.New("template: invalid JSON output")
+		}
+		return buf.Bytes(), nil
+	}, nil
+}
+
+// decodeTransformPayload decodes a raw JSON object, with the numbers as [json.Number], so they are encoded unchanged.
+func decodeTransformPayload(rawPayload []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(rawPayload))
+	dec.UseNumber()
+	var v map[string]any
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("JSON decode: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,77 @@
+package githubhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+var testTransformRawPayload = []byte(`{"ref":"refs/heads/main","after":"abc123","size":12345678901234567890,"repository":{"full_name":"pierrre/githubhook","private":false},"commits":[{"id":"abc123"}]}`)
+
+func testTransformDelivery() *VerifiedDelivery {
+	return &VerifiedDelivery{
+		DeliveryMetadata: DeliveryMetadata{
+			Event:      "push",
+			DeliveryID: "1",
+		},
+		RawPayload: testTransformRawPayload,
+	}
+}
+
+func TestTransformed(t *testing.T) {
+	ctx := context.Background()
+	var received *VerifiedDelivery
+	s := Transformed(SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+		received = d
+		return nil
+	}), SelectFields("repository.full_name", "ref", "after", "size", "missing", "ref.invalid"))
+	d := testTransformDelivery()
+	err := s.Send(ctx, d)
+	assert.NoError(t, err)
+	assert.Equal(t, string(received.RawPayload), `{"after":"abc123","ref":"refs/heads/main","repository":{"full_name":"pierrre/githubhook"},"size":12345678901234567890}`)
+	assert.Equal(t, string(received.Payload.(json.RawMessage)), string(received.RawPayload))
+	assert.Equal(t, received.DeliveryID, "1")
+	assert.Equal(t, string(d.RawPayload), string(testTransformRawPayload))
+}
+
+func TestTransformedError(t *testing.T) {
+	s := Transformed(SinkFunc(func(ctx context.Context, d *VerifiedDelivery) error {
+		t.Fatal("sink called")
+		return nil
+	}), func(ctx context.Context, d *VerifiedDelivery) ([]byte, error) {
+		return nil, errors.New("error")
+	})
+	err := s.Send(context.Background(), testTransformDelivery())
+	assert.Error(t, err)
+}
+
+func TestSelectFieldsInvalidJSON(t *testing.T) {
+	_, err := SelectFields("ref")(context.Background(), &VerifiedDelivery{RawPayload: []byte("invalid")})
+	assert.Error(t, err)
+}
+
+func TestTemplateTransform(t *testing.T) {
+	transform, err := TemplateTransform(`{"event": {{json .Event}}, "repository": {{json .Payload.repository.full_name}}, "sha": {{json .Payload.after}}, "commits": {{len .Payload.commits}}, "missing": {{json .Payload.missing}}}`)
+	assert.NoError(t, err)
+	b, err := transform(context.Background(), testTransformDelivery())
+	assert.NoError(t, err)
+	assert.Equal(t, string(b), `{"event": "push", "repository": "pierrre/githubhook", "sha": "abc123", "commits": 1, "missing": null}`)
+}
+
+func TestTemplateTransformError(t *testing.T) {
+	_, err := TemplateTransform(`{{`)
+	assert.Error(t, err)
+	transform, err := TemplateTransform(`{"ref": {{.Payload.ref}}}`)
+	assert.NoError(t, err)
+	_, err = transform(context.Background(), testTransformDelivery())
+	assert.Error(t, err)
+	transform, err = TemplateTransform(`{{index .Payload.commits 10}}`)
+	assert.NoError(t, err)
+	_, err = transform(context.Background(), testTransformDelivery())
+	assert.Error(t, err)
+	_, err = transform(context.Background(), &VerifiedDelivery{RawPayload: []byte("invalid")})
+	assert.Error(t, err)
+}
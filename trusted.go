@@ -0,0 +1,97 @@
+package githubhook
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+)
+
+// TrustedSource identifies requests coming from a trusted source.
+//
+// The signature verification is skipped for these requests.
+// It is useful to replay archived deliveries or to inject synthetic deliveries from internal tooling.
+type TrustedSource interface {
+	IsTrusted(req *http.Request) bool
+}
+
+// TrustedSourceFunc is a [TrustedSource] function.
+type TrustedSourceFunc func(req *http.Request) bool
+
+// IsTrusted implements [TrustedSource].
+func (f TrustedSourceFunc) IsTrusted(req *http.Request) bool {
+	return f(req)
+}
+
+// TrustedCIDRs returns a [TrustedSource] that trusts requests whose remote address is contained in one of the prefixes.
+//
+// The remote address is read from [http.Request.RemoteAddr], so proxy headers are not taken into account.
+func TrustedCIDRs(prefixes ...netip.Prefix) TrustedSource {
+	return TrustedSourceFunc(func(req *http.Request) bool {
+		addr, ok := getRemoteAddr(req)
+		if !ok {
+			return false
+		}
+		return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool {
+			return p.Contains(addr)
+		})
+	})
+}
+
+func getRemoteAddr(req *http.Request) (netip.Addr, bool) {
+	return parseRemoteAddr(req.RemoteAddr)
+}
+
+func parseRemoteAddr(remoteAddr string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// TrustedHeaderToken returns a [TrustedSource] that trusts requests containing a header with the given token.
+//
+// The token is compared in constant time.
+func TrustedHeaderToken(name string, token string) TrustedSource {
+	return TrustedSourceFunc(func(req *http.Request) bool {
+		v := req.Header.Get(name)
+		return token != "" && subtle.ConstantTimeCompare([]byte(v), []byte(token)) == 1
+	})
+}
+
+// TrustedClientCertificateSANs returns a [TrustedSource] that trusts requests with a verified TLS client certificate (mTLS) containing one of the given DNS or URI subject alternative names.
+func TrustedClientCertificateSANs(names ...string) TrustedSource {
+	return TrustedSourceFunc(func(req *http.Request) bool {
+		if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+			return false
+		}
+		cert := req.TLS.VerifiedChains[0][0]
+		for _, n := range cert.DNSNames {
+			if slices.Contains(names, n) {
+				return true
+			}
+		}
+		for _, u := range cert.URIs {
+			if slices.Contains(names, u.String()) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (h *Handler) isTrustedSource(in *inbound) bool {
+	if len(h.TrustedSources) == 0 {
+		return false
+	}
+	req := in.request()
+	return slices.ContainsFunc(h.TrustedSources, func(ts TrustedSource) bool {
+		return ts.IsTrusted(req)
+	})
+}
@@ -0,0 +1,79 @@
+package githubhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/pierrre/assert"
+)
+
+func TestHandlerTrustedSource(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+		TrustedSources: []TrustedSource{
+			TrustedHeaderToken("X-Internal-Token", "token"),
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("X-Internal-Token", "token")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatusOK(t, resp)
+}
+
+func TestHandlerTrustedSourceNotTrusted(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{
+		Secret: "foobar",
+		TrustedSources: []TrustedSource{
+			TrustedHeaderToken("X-Internal-Token", "token"),
+		},
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req := testNewJSONRequest(ctx, t, srv, "", testRawPayload)
+	req.Header.Set("X-Internal-Token", "wrong")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	testExpectResponseStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestTrustedCIDRs(t *testing.T) {
+	ts := TrustedCIDRs(netip.MustParsePrefix("10.0.0.0/8"))
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	req.RemoteAddr = "10.1.2.3:1234"
+	assert.True(t, ts.IsTrusted(req))
+	req.RemoteAddr = "192.168.1.1:1234"
+	assert.False(t, ts.IsTrusted(req))
+	req.RemoteAddr = "invalid"
+	assert.False(t, ts.IsTrusted(req))
+}
+
+func TestTrustedClientCertificateSANs(t *testing.T) {
+	ts := TrustedClientCertificateSANs("replayer.internal", "spiffe://internal/replayer")
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	assert.False(t, ts.IsTrusted(req))
+	req.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{DNSNames: []string{"replayer.internal"}}}},
+	}
+	assert.True(t, ts.IsTrusted(req))
+	req.TLS.VerifiedChains[0][0] = &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "internal", Path: "/replayer"}}}
+	assert.True(t, ts.IsTrusted(req))
+	req.TLS.VerifiedChains[0][0] = &x509.Certificate{DNSNames: []string{"other.internal"}}
+	assert.False(t, ts.IsTrusted(req))
+}